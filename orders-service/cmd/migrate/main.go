@@ -0,0 +1,58 @@
+// Command migrate upgrades the documents of the Firestore backend to the
+// current storage layout. It is safe to run more than once.
+package main
+
+import (
+	"context"
+	"log"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
+)
+
+func main() {
+	ctx := context.Background()
+
+	firebase := db.NewFirebaseService()
+	firestoreClient, err := firebase.GetApp().Firestore(ctx)
+	if err != nil {
+		log.Fatalf("failed to create firestore client: %v", err)
+	}
+	defer firestoreClient.Close()
+
+	firestoreService := db.NewFirestoreService(firestoreClient)
+
+	n, err := db.MigrateOrderItems(ctx, firestoreService)
+	if err != nil {
+		log.Fatalf("failed to migrate order items: %v", err)
+	}
+
+	log.Printf("Migrated order items of %d orders", n)
+
+	n, err = db.MigrateMoney(ctx, firestoreService)
+	if err != nil {
+		log.Fatalf("failed to migrate amounts to minor units: %v", err)
+	}
+
+	log.Printf("Migrated amounts of %d products and orders", n)
+
+	n, err = db.MigrateOrderTotals(ctx, firestoreService)
+	if err != nil {
+		log.Fatalf("failed to migrate order totals: %v", err)
+	}
+
+	log.Printf("Migrated order totals of %d orders", n)
+
+	n, err = db.MigrateStockReservations(ctx, firestoreService)
+	if err != nil {
+		log.Fatalf("failed to migrate stock reservations: %v", err)
+	}
+
+	log.Printf("Migrated stock reservations of %d orders", n)
+
+	n, err = db.MigratePromotionRedemptions(ctx, firestoreService)
+	if err != nil {
+		log.Fatalf("failed to migrate promotion redemptions: %v", err)
+	}
+
+	log.Printf("Released promotion redemptions of %d orders", n)
+}
@@ -4,21 +4,72 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/Mik3y-F/order-management-system/orders/internal/checkout"
 	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
 	"github.com/Mik3y-F/order-management-system/orders/internal/handlers"
+	"github.com/Mik3y-F/order-management-system/orders/internal/inventory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/orders/internal/promotions"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/tax"
+	"github.com/Mik3y-F/order-management-system/orders/internal/watch"
+	"github.com/Mik3y-F/order-management-system/orders/internal/webhooks"
 	payments "github.com/Mik3y-F/order-management-system/payments/pkg/client"
+	"github.com/Mik3y-F/order-management-system/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	idempotency_memory "github.com/Mik3y-F/order-management-system/pkg/idempotency/memory"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
 )
 
 const (
-	BIND_ADDRESS = "BIND_ADDRESS"
-	PORT         = "PORT"
+	BIND_ADDRESS    = "BIND_ADDRESS"
+	PORT            = "PORT"
+	STORAGE_BACKEND = "STORAGE_BACKEND"
 
-	DEFAULT_BIND_ADDRESS = "localhost"
-	DEFAULT_PORT         = "50051"
+	// TAX_RATES lists the tax rates of product categories, e.g.
+	// "default=16,food=0,medical=exempt". TAX_INCLUSIVE tells whether
+	// product prices include the tax.
+	TAX_RATES     = "TAX_RATES"
+	TAX_INCLUSIVE = "TAX_INCLUSIVE"
+
+	// IDEMPOTENCY_TTL is how long responses to requests with an idempotency
+	// key are replayed, as a Go duration, e.g. "24h".
+	IDEMPOTENCY_TTL = "IDEMPOTENCY_TTL"
+
+	// EVENTS_FILE is the file that order events are appended to as JSON
+	// lines. Without it, events are only logged.
+	EVENTS_FILE = "EVENTS_FILE"
+
+	DEFAULT_BIND_ADDRESS    = "localhost"
+	DEFAULT_PORT            = "50051"
+	DEFAULT_STORAGE_BACKEND = STORAGE_BACKEND_FIRESTORE
+	DEFAULT_TAX_RATES       = tax.DefaultRates
+	DEFAULT_TAX_INCLUSIVE   = true
+	DEFAULT_IDEMPOTENCY_TTL = idempotency.DefaultTTL
+
+	// Supported values for STORAGE_BACKEND.
+	STORAGE_BACKEND_FIRESTORE = "firestore"
+	STORAGE_BACKEND_MEMORY    = "memory"
+	STORAGE_BACKEND_POSTGRES  = "postgres"
 )
 
+// repositories groups the storage implementations used by the server.
+type repositories struct {
+	product   repository.ProductRepository
+	customer  repository.CustomerRepository
+	order     repository.OrderRepository
+	promotion repository.PromotionRepository
+
+	idempotency  idempotency.Store
+	checkoutSaga repository.CheckoutSagaRepository
+	outbox       repository.OutboxRepository
+	webhook      repository.WebhookRepository
+}
+
 func main() {
 
 	ctx := context.Background()
@@ -35,20 +86,51 @@ func main() {
 		port = DEFAULT_PORT
 	}
 
-	s := handlers.NewGRPCServer()
+	storageBackend := os.Getenv(STORAGE_BACKEND)
+	if storageBackend == "" {
+		storageBackend = DEFAULT_STORAGE_BACKEND
+	}
+
+	taxRates := os.Getenv(TAX_RATES)
+	if taxRates == "" {
+		taxRates = DEFAULT_TAX_RATES
+	}
+
+	taxInclusive := DEFAULT_TAX_INCLUSIVE
+	if v := os.Getenv(TAX_INCLUSIVE); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("invalid %s: %v", TAX_INCLUSIVE, err)
+		}
+		taxInclusive = b
+	}
 
-	firebase := db.NewFirebaseService()
-	firestoreClient, err := firebase.GetApp().Firestore(ctx)
+	taxTable, err := tax.ParseTable(taxRates, taxInclusive)
 	if err != nil {
-		log.Fatalf("failed to create firestore client: %v", err)
+		log.Fatalf("invalid %s: %v", TAX_RATES, err)
+	}
+
+	idempotencyTTL := DEFAULT_IDEMPOTENCY_TTL
+	if v := os.Getenv(IDEMPOTENCY_TTL); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid %s: %q", IDEMPOTENCY_TTL, v)
+		}
+		idempotencyTTL = d
 	}
-	defer firestoreClient.Close()
 
-	firestoreService := db.NewFirestoreService(firestoreClient)
+	s := handlers.NewGRPCServer()
+
+	repos, closeRepos := newRepositories(ctx, storageBackend)
+	defer closeRepos()
+
+	// Every status change, whether from checkout or from the payments service,
+	// goes through the inventory so that product stock follows the order.
+	repos.order = inventory.NewOrderRepository(repos.order, repos.product)
 
-	ProductRepository := db.NewProductService(firestoreService)
-	customerRepository := db.NewCustomerService(firestoreService)
-	orderRepository := db.NewOrderRepository(firestoreService)
+	// Orders stop counting towards the limits of their promotion once they
+	// fail, are cancelled or are deleted.
+	repos.order = promotions.NewOrderRepository(repos.order, repos.promotion)
 
 	// Setup payments service client
 	conn, err := payments.ConnectToPaymentService("localhost:50051")
@@ -58,14 +140,137 @@ func main() {
 	paymentsClient := payments.NewGrpcPaymentsClient(conn)
 
 	checkoutService := checkout.NewCheckoutService(
-		ProductRepository, customerRepository, orderRepository, paymentsClient)
+		repos.product, repos.customer, repos.order, repos.checkoutSaga, paymentsClient)
+
+	// Checkouts interrupted by the last shutdown are finished before new
+	// requests come in.
+	if err := checkoutService.Resume(ctx); err != nil {
+		log.Fatalf("failed to resume checkouts: %v", err)
+	}
+
+	publisher, closePublisher := newPublisher(os.Getenv(EVENTS_FILE))
+	defer closePublisher()
+
+	// Order events are streamed to the clients watching the orders, and posted
+	// to the webhook subscriptions of merchants.
+	watchBroker := watch.NewBroker()
+	publisher.Subscribe(watchBroker.HandleEvent)
+
+	webhookService := webhooks.NewWebhookService(repos.webhook)
+	publisher.Subscribe(webhookService.HandleEvent)
+	go webhookService.Run(ctx)
+
+	// Events recorded before the last shutdown are published along with new
+	// ones.
+	go outbox.NewRelay(repos.outbox, publisher).Run(ctx)
 
-	s.ProductRepository = ProductRepository
-	s.CustomerRepository = customerRepository
-	s.OrderRepository = orderRepository
+	s.ProductRepository = repos.product
+	s.CustomerRepository = repos.customer
+	s.OrderRepository = repos.order
+	s.PromotionRepository = repos.promotion
+	s.WebhookRepository = repos.webhook
+	s.TaxRates = taxTable
+	s.IdempotencyStore = repos.idempotency
+	s.IdempotencyTTL = idempotencyTTL
 	s.CheckoutService = checkoutService
+	s.PromotionService = promotions.NewPromotionService(repos.promotion, repos.order)
+	s.WebhookService = webhookService
+	s.Watch = watchBroker
 
 	if err := s.Run(ctx, bindAddress, port); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
+
+// newRepositories sets up the repositories for the given storage backend. The
+// returned function releases any resources held by the backend.
+func newRepositories(ctx context.Context, backend string) (*repositories, func()) {
+	log.Printf("Using %s storage backend", backend)
+
+	switch backend {
+	case STORAGE_BACKEND_FIRESTORE:
+		firebase := db.NewFirebaseService()
+		firestoreClient, err := firebase.GetApp().Firestore(ctx)
+		if err != nil {
+			log.Fatalf("failed to create firestore client: %v", err)
+		}
+
+		firestoreService := db.NewFirestoreService(firestoreClient)
+
+		return &repositories{
+			product:   db.NewProductService(firestoreService),
+			customer:  db.NewCustomerService(firestoreService),
+			order:     db.NewOrderRepository(firestoreService),
+			promotion: db.NewPromotionRepository(firestoreService),
+
+			idempotency:  db.NewIdempotencyStore(firestoreService),
+			checkoutSaga: db.NewCheckoutSagaRepository(firestoreService),
+			outbox:       db.NewOutboxRepository(firestoreService),
+			webhook:      db.NewWebhookRepository(firestoreService),
+		}, func() { firestoreClient.Close() }
+
+	case STORAGE_BACKEND_MEMORY:
+		orders := memory.NewOrderRepository()
+
+		return &repositories{
+			product:   memory.NewProductRepository(),
+			customer:  memory.NewCustomerRepository(),
+			order:     orders,
+			promotion: memory.NewPromotionRepository(),
+
+			idempotency:  idempotency_memory.NewStore(),
+			checkoutSaga: memory.NewCheckoutSagaRepository(),
+			outbox:       orders.Outbox(),
+			webhook:      memory.NewWebhookRepository(),
+		}, func() {}
+
+	case STORAGE_BACKEND_POSTGRES:
+		postgresDB := postgres.NewDB(pkg.MustGetEnv(postgres.POSTGRES_DSN))
+		if err := postgresDB.Open(); err != nil {
+			log.Fatalf("failed to open postgres database: %v", err)
+		}
+
+		return &repositories{
+			product:   postgres.NewProductRepository(postgresDB),
+			customer:  postgres.NewCustomerRepository(postgresDB),
+			order:     postgres.NewOrderRepository(postgresDB),
+			promotion: postgres.NewPromotionRepository(postgresDB),
+
+			idempotency:  postgres.NewIdempotencyStore(postgresDB),
+			checkoutSaga: postgres.NewCheckoutSagaRepository(postgresDB),
+			outbox:       postgres.NewOutboxRepository(postgresDB),
+			webhook:      postgres.NewWebhookRepository(postgresDB),
+		}, func() { postgresDB.Close() }
+
+	default:
+		log.Fatalf("unsupported storage backend %q", backend)
+		return nil, nil
+	}
+}
+
+// newPublisher returns the publisher of order events, which writes them to a
+// file if path is set, and to the log otherwise. Other in-process consumers
+// can subscribe to it. The returned function closes the publisher.
+func newPublisher(path string) (*outbox.InProcessPublisher, func()) {
+	publisher := outbox.NewInProcessPublisher()
+
+	if path != "" {
+		log.Printf("Publishing events to %s", path)
+
+		filePublisher, err := outbox.NewFilePublisher(path)
+		if err != nil {
+			log.Fatalf("failed to create events publisher: %v", err)
+		}
+
+		publisher.Subscribe(filePublisher.Publish)
+
+		return publisher, func() { filePublisher.Close() }
+	}
+
+	publisher.Subscribe(func(ctx context.Context, event *outbox.Event) error {
+		log.Printf("[events] %s %s %s", event.Type, event.AggregateType, event.AggregateId)
+		return nil
+	})
+
+	return publisher, func() {}
+}
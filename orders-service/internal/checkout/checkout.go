@@ -2,6 +2,8 @@ package checkout
 
 import (
 	"context"
+	"errors"
+	"log"
 
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
 	"github.com/Mik3y-F/order-management-system/orders/internal/service"
@@ -9,12 +11,16 @@ import (
 
 	"github.com/Mik3y-F/order-management-system/payments/pkg/client"
 	"github.com/Mik3y-F/order-management-system/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type CheckoutService struct {
 	productRepository  repository.ProductRepository
 	customerRepository repository.CustomerRepository
 	orderRepository    repository.OrderRepository
+	sagaRepository     repository.CheckoutSagaRepository
 
 	paymentsClient client.PaymentsClient
 }
@@ -23,12 +29,14 @@ func NewCheckoutService(
 	productRepository repository.ProductRepository,
 	customerRepository repository.CustomerRepository,
 	orderRepository repository.OrderRepository,
+	sagaRepository repository.CheckoutSagaRepository,
 	paymentsClient client.PaymentsClient) *CheckoutService {
 
 	return &CheckoutService{
 		orderRepository:    orderRepository,
 		productRepository:  productRepository,
 		customerRepository: customerRepository,
+		sagaRepository:     sagaRepository,
 		paymentsClient:     paymentsClient,
 	}
 }
@@ -46,95 +54,366 @@ func (s *CheckoutService) CheckPreconditions() {
 		panic("orderRepository is required")
 	}
 
+	if s.sagaRepository == nil {
+		panic("sagaRepository is required")
+	}
+
 	if s.paymentsClient == nil {
 		panic("paymentsClient is required")
 	}
 }
 
-func (s *CheckoutService) GetOrderCost(ctx context.Context, orderId string) (uint, error) {
+// GetOrderCost returns the total stored with the order, which is based on the
+// product prices at the time its items were added and is net of the discount
+// of the promotion applied to it.
+func (s *CheckoutService) GetOrderCost(ctx context.Context, orderId string) (money.Money, error) {
 	s.CheckPreconditions()
 
 	order, err := s.orderRepository.GetOrder(ctx, orderId)
 	if err != nil {
-		return 0, err
+		return money.Money{}, err
 	}
 
-	var cost uint
-	for _, item := range order.Items {
-		product, err := s.productRepository.GetProduct(ctx, item.ProductId)
+	return order.Total, nil
+}
+
+// ProcessCheckout runs the checkout of an order as a saga: it reserves the
+// order's items, requests the payment from the customer and confirms that the
+// payments service took the order over. It fails with OUT_OF_STOCK_ERROR if a
+// product does not have enough units left. If the payments service rejects
+// the payment the order is marked failed, which releases its stock. If it
+// cannot be reached, the checkout is left running and checking the order out
+// again resumes it, requesting the payment with the same idempotency key.
+func (s *CheckoutService) ProcessCheckout(ctx context.Context, orderId string) (*service.Order, error) {
+	s.CheckPreconditions()
+
+	saga, err := s.unfinishedSaga(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if saga != nil {
+		err = s.resumeSaga(ctx, saga)
+	} else {
+		saga, err = s.sagaRepository.CreateCheckoutSaga(ctx, &repository.CheckoutSaga{OrderId: orderId})
 		if err != nil {
-			return 0, service.Errorf(service.INTERNAL_ERROR, "failed to get product: %v", err)
+			return nil, err
 		}
 
-		cost += product.Price * item.Quantity
+		err = s.runSaga(ctx, saga)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepository.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get order: %v", err)
 	}
 
-	return cost, nil
+	return order.ServiceOrder(), nil
 }
 
-func (s *CheckoutService) ProcessCheckout(ctx context.Context, orderId string) (*service.Order, error) {
+// Resume finishes the checkouts that were interrupted, e.g. because the
+// service stopped halfway through. Running sagas continue from the step they
+// were at, and compensating ones are compensated. It is meant to be called on
+// startup, before the service takes requests. Sagas that cannot be finished
+// are logged and left for the next start.
+func (s *CheckoutService) Resume(ctx context.Context) error {
 	s.CheckPreconditions()
 
-	order, err := s.orderRepository.UpdateOrderStatus(ctx, orderId, orders_pkg.OrderStatusProcessing)
+	sagas, err := s.sagaRepository.ListUnfinishedCheckoutSagas(ctx)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update order status: %v", err)
+		return err
 	}
 
-	customer, err := s.customerRepository.GetCustomer(ctx, order.CustomerId)
-	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get customer: %v", err)
+	for _, saga := range sagas {
+		log.Printf("[checkout] resuming checkout %s of order %s at %s (%s)",
+			saga.Id, saga.OrderId, saga.Step, saga.Status)
+
+		if err := s.resumeSaga(ctx, saga); err != nil {
+			log.Printf("[checkout] resumed checkout %s of order %s failed: %v", saga.Id, saga.OrderId, err)
+		}
 	}
 
-	phoneNo, err := pkg.StringToUint(customer.Phone)
+	return nil
+}
+
+// unfinishedSaga returns the running saga of the order, or nil if it has
+// none, e.g. because its payment could not be requested yet.
+func (s *CheckoutService) unfinishedSaga(ctx context.Context, orderId string) (*repository.CheckoutSaga, error) {
+	sagas, err := s.sagaRepository.ListUnfinishedCheckoutSagas(ctx)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to convert phone number to uint: %v", err)
+		return nil, err
 	}
 
-	cost, err := s.GetOrderCost(ctx, orderId)
-	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get order cost: %v", err)
+	for _, saga := range sagas {
+		if saga.OrderId == orderId && saga.Status == repository.CheckoutSagaStatusRunning {
+			return saga, nil
+		}
 	}
-	_, err = s.paymentsClient.ProcessMpesaPayment(ctx, &client.ProcessMpesaPaymentRequest{
-		OrderId:     orderId,
-		Amount:      uint32(cost),
-		CustomerId:  order.CustomerId,
-		PhoneNumber: uint64(phoneNo),
+
+	return nil, nil
+}
+
+// checkoutKey identifies the saga to the services it calls: it is the
+// reservation of the order's items and the idempotency key of its payment.
+func checkoutKey(saga *repository.CheckoutSaga) string {
+	return "checkout:" + saga.Id
+}
+
+// holdsReservation reports whether the order moved to processing with the
+// reservation of the saga.
+func holdsReservation(order *repository.Order, saga *repository.CheckoutSaga) bool {
+	return order.OrderStatus == orders_pkg.OrderStatusProcessing && order.StockReservation == checkoutKey(saga)
+}
+
+func (s *CheckoutService) resumeSaga(ctx context.Context, saga *repository.CheckoutSaga) error {
+	// The items may have been reserved without the saga recording it.
+	if saga.Status == repository.CheckoutSagaStatusRunning && saga.Step == repository.CheckoutStepReserve {
+		order, err := s.orderRepository.GetOrder(ctx, saga.OrderId)
+		if err == nil && holdsReservation(order, saga) {
+			saga, err = s.updateSaga(ctx, saga, repository.CheckoutStepCharge, repository.CheckoutSagaStatusRunning, "")
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.runSaga(ctx, saga)
+}
+
+// runSaga runs the steps of the saga from the one it is at and stores its
+// progress after each of them. If reserving the items fails or the payment is
+// rejected, the saga is compensated and the error of the step returned.
+// Steps may run again if the service stops before their outcome is stored,
+// so they must be safe to repeat.
+func (s *CheckoutService) runSaga(ctx context.Context, saga *repository.CheckoutSaga) error {
+	var err error
+
+	for saga.Status == repository.CheckoutSagaStatusRunning {
+		if stepErr := s.runStep(ctx, saga); stepErr != nil {
+			// Once the payment may have been requested the customer may pay
+			// at any moment, so a request that may have reached the payments
+			// service and the confirmation are retried instead.
+			if saga.Step == repository.CheckoutStepConfirm ||
+				(saga.Step == repository.CheckoutStepCharge && !rejected(stepErr)) {
+				return stepErr
+			}
+
+			saga, err = s.updateSaga(ctx, saga,
+				saga.Step, repository.CheckoutSagaStatusCompensating, service.ErrorMessage(stepErr))
+			if err != nil {
+				log.Printf("[checkout] failed to start compensating checkout of order %s: %v", saga.OrderId, err)
+				return stepErr
+			}
+
+			if err := s.compensate(ctx, saga); err != nil {
+				log.Printf("[checkout] failed to compensate checkout %s of order %s: %v", saga.Id, saga.OrderId, err)
+			}
+
+			return stepErr
+		}
+
+		step, status := nextCheckoutStep(saga.Step)
+		if saga, err = s.updateSaga(ctx, saga, step, status, ""); err != nil {
+			return err
+		}
+	}
+
+	if saga.Status == repository.CheckoutSagaStatusCompensating {
+		return s.compensate(ctx, saga)
+	}
+
+	return nil
+}
+
+// rejected reports whether err means that a step was refused and retrying it
+// cannot succeed, e.g. a payment that the payments service rejected, rather
+// than that its outcome is unknown, e.g. because the request timed out.
+func rejected(err error) bool {
+	switch service.ErrorCode(err) {
+	case service.INVALID_ERROR, service.FAILED_PRECONDITION_ERROR, service.NOT_FOUND_ERROR:
+		return true
+	default:
+		return false
+	}
+}
+
+// nextCheckoutStep returns the step after step, or step itself together with
+// the completed status if it is the last one.
+func nextCheckoutStep(step repository.CheckoutStep) (repository.CheckoutStep, repository.CheckoutSagaStatus) {
+	for i, known := range repository.CheckoutSteps[:len(repository.CheckoutSteps)-1] {
+		if step == known {
+			return repository.CheckoutSteps[i+1], repository.CheckoutSagaStatusRunning
+		}
+	}
+
+	return step, repository.CheckoutSagaStatusCompleted
+}
+
+func (s *CheckoutService) runStep(ctx context.Context, saga *repository.CheckoutSaga) error {
+	switch saga.Step {
+	case repository.CheckoutStepReserve:
+		// Only new and failed orders can be checked out; the repository
+		// rejects anything else with a FAILED_PRECONDITION error that the
+		// caller should see. The items are reserved under the key of the
+		// saga, so reserving them again after a restart replaces the
+		// reservation instead of adding to it.
+		_, err := s.orderRepository.UpdateOrderStatus(ctx, saga.OrderId, &repository.OrderStatusUpdate{
+			Status:           orders_pkg.OrderStatusProcessing,
+			StockReservation: checkoutKey(saga),
+			Actor:            "checkout",
+			Reason:           "checkout started",
+		})
+		return err
+
+	case repository.CheckoutStepCharge:
+		order, err := s.orderRepository.GetOrder(ctx, saga.OrderId)
+		if err != nil {
+			return err
+		}
+
+		switch order.OrderStatus {
+		case orders_pkg.OrderStatusProcessing:
+			return s.requestPayment(ctx, order, checkoutKey(saga))
+
+		case orders_pkg.OrderStatusPending, orders_pkg.OrderStatusPaid:
+			// The payment was requested before the service stopped.
+			return nil
+
+		default:
+			return service.Errorf(service.FAILED_PRECONDITION_ERROR,
+				"order is %s and can no longer be paid for", order.OrderStatus)
+		}
+
+	case repository.CheckoutStepConfirm:
+		order, err := s.orderRepository.GetOrder(ctx, saga.OrderId)
+		if err != nil {
+			return err
+		}
+
+		// The payments service moves the order on once the payment is
+		// requested; it is only moved here if that did not happen.
+		if order.OrderStatus != orders_pkg.OrderStatusProcessing {
+			return nil
+		}
+
+		_, err = s.orderRepository.UpdateOrderStatus(ctx, saga.OrderId, &repository.OrderStatusUpdate{
+			Status:  orders_pkg.OrderStatusPending,
+			Version: &order.Version,
+			Actor:   "checkout",
+			Reason:  "payment requested",
+		})
+		return err
+
+	default:
+		return service.Errorf(service.INTERNAL_ERROR, "unknown checkout step %q", saga.Step)
+	}
+}
+
+// compensate undoes the steps that ran before the one the saga failed at and
+// marks the saga compensated. Marking the order failed releases its items;
+// the items of an order that never took the reservation of the saga are
+// released directly.
+func (s *CheckoutService) compensate(ctx context.Context, saga *repository.CheckoutSaga) error {
+	failOrder := saga.Step != repository.CheckoutStepReserve
+
+	if !failOrder {
+		// The items may have been reserved for the saga, with or without the
+		// order moving to processing before the service stopped.
+		order, err := s.orderRepository.GetOrder(ctx, saga.OrderId)
+		if err != nil && service.ErrorCode(err) != service.NOT_FOUND_ERROR {
+			return err
+		}
+
+		failOrder = err == nil && holdsReservation(order, saga)
+		if !failOrder {
+			if err := s.productRepository.ReleaseStock(ctx, checkoutKey(saga)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if failOrder {
+		// The payments service may already have moved the order to pending,
+		// so the update is not pinned to a version. Orders that cannot fail
+		// any more, e.g. because they are cancelled or paid, hold no stock.
+		_, err := s.orderRepository.UpdateOrderStatus(ctx, saga.OrderId, &repository.OrderStatusUpdate{
+			Status: orders_pkg.OrderStatusFailed,
+			Actor:  "checkout",
+			Reason: saga.Error,
+		})
+		if err != nil && service.ErrorCode(err) != service.FAILED_PRECONDITION_ERROR {
+			return err
+		}
+	}
+
+	_, err := s.updateSaga(ctx, saga, saga.Step, repository.CheckoutSagaStatusCompensated, saga.Error)
+	return err
+}
+
+func (s *CheckoutService) updateSaga(ctx context.Context, saga *repository.CheckoutSaga,
+	step repository.CheckoutStep, status repository.CheckoutSagaStatus, reason string) (*repository.CheckoutSaga, error) {
+
+	return s.sagaRepository.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+		Step:    step,
+		Status:  status,
+		Error:   reason,
+		Version: saga.Version,
 	})
+}
+
+// requestPayment asks the payments service to charge the customer for the
+// order. The idempotency key makes retries of the request safe. Errors keep
+// the code of the payments service, so that rejections can be told from
+// requests that may still have gone through.
+func (s *CheckoutService) requestPayment(ctx context.Context, order *repository.Order, idempotencyKey string) error {
+	customer, err := s.customerRepository.GetCustomer(ctx, order.CustomerId)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to process payment: %v", err)
+		return service.Errorf(service.ErrorCode(err), "failed to get customer: %s", service.ErrorMessage(err))
 	}
 
-	order, err = s.orderRepository.GetOrder(ctx, orderId)
+	phoneNo, err := pkg.StringToUint(customer.Phone)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get order: %v", err)
+		return service.Errorf(service.INVALID_ERROR, "invalid customer phone number %q: %v", customer.Phone, err)
+	}
+
+	// Repositories reject changes to the items and promotion of orders that
+	// are being paid for, so the total is the one the customer saw.
+	_, err = s.paymentsClient.ProcessMpesaPayment(ctx, &client.ProcessMpesaPaymentRequest{
+		OrderId:        order.Id,
+		Amount:         &client.Money{Amount: order.Total.Amount, Currency: order.Total.Currency},
+		CustomerId:     order.CustomerId,
+		PhoneNumber:    uint64(phoneNo),
+		IdempotencyKey: idempotencyKey,
+	})
+	if err != nil {
+		return paymentError(err)
 	}
 
-	return s.unmarshallRepositoryOrder(order), nil
+	return nil
 }
 
-func (s *CheckoutService) unmarshallOrderItem(item *repository.OrderItem) *service.OrderItem {
-	return &service.OrderItem{
-		Id:        item.Id,
-		ProductId: item.ProductId,
-		Quantity:  item.Quantity,
-		CreatedAt: item.CreatedAt,
-		UpdatedAt: item.UpdatedAt,
+// paymentError converts an error of the payments service to an application
+// error with the same meaning.
+func paymentError(err error) error {
+	var appErr *service.Error
+	if errors.As(err, &appErr) {
+		return err
 	}
-}
 
-func (s *CheckoutService) unmarshallRepositoryOrder(order *repository.Order) *service.Order {
+	st := status.Convert(err)
 
-	orderItems := make([]*service.OrderItem, len(order.Items))
-	for i, item := range order.Items {
-		orderItems[i] = s.unmarshallOrderItem(item)
+	code := service.INTERNAL_ERROR
+	switch st.Code() {
+	case codes.InvalidArgument:
+		code = service.INVALID_ERROR
+	case codes.FailedPrecondition:
+		code = service.FAILED_PRECONDITION_ERROR
+	case codes.Aborted:
+		code = service.CONFLICT_ERROR
 	}
 
-	return &service.Order{
-		Id:          order.Id,
-		CustomerId:  order.CustomerId,
-		Items:       orderItems,
-		OrderStatus: order.OrderStatus,
-		CreatedAt:   order.CreatedAt,
-		UpdatedAt:   order.UpdatedAt,
-	}
+	return service.Errorf(code, "failed to process payment: %s", st.Message())
 }
@@ -0,0 +1,425 @@
+package checkout_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/checkout"
+	"github.com/Mik3y-F/order-management-system/orders/internal/inventory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/payments/pkg/client"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// paymentsClient records the payments it is asked for and fails them with err.
+type paymentsClient struct {
+	requests []*client.ProcessMpesaPaymentRequest
+	err      error
+}
+
+func (c *paymentsClient) HealthCheck(
+	ctx context.Context, req *client.HealthCheckRequest) (*client.HealthCheckResponse, error) {
+	return &client.HealthCheckResponse{}, nil
+}
+
+func (c *paymentsClient) ProcessMpesaPayment(
+	ctx context.Context, req *client.ProcessMpesaPaymentRequest) (*client.ProcessMpesaPaymentResponse, error) {
+
+	c.requests = append(c.requests, req)
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return &client.ProcessMpesaPaymentResponse{}, nil
+}
+
+func (c *paymentsClient) GetPayment(
+	ctx context.Context, req *client.GetPaymentRequest) (*client.GetPaymentResponse, error) {
+	return &client.GetPaymentResponse{}, nil
+}
+
+func (c *paymentsClient) RefundPayment(
+	ctx context.Context, req *client.RefundPaymentRequest) (*client.RefundPaymentResponse, error) {
+	return &client.RefundPaymentResponse{}, nil
+}
+
+type fixture struct {
+	checkout *checkout.CheckoutService
+	products repository.ProductRepository
+	orders   repository.OrderRepository
+	sagas    repository.CheckoutSagaRepository
+	payments *paymentsClient
+
+	product *repository.Product
+	order   *repository.Order
+}
+
+// setup returns a checkout service backed by memory repositories, with one
+// order for 2 units of a product that has stock units left.
+func setup(t *testing.T, stock uint) *fixture {
+	t.Helper()
+
+	ctx := context.Background()
+	f := &fixture{
+		products: memory.NewProductRepository(),
+		sagas:    memory.NewCheckoutSagaRepository(),
+		payments: &paymentsClient{},
+	}
+	f.orders = inventory.NewOrderRepository(memory.NewOrderRepository(), f.products)
+
+	customers := memory.NewCustomerRepository()
+	customer, err := customers.CreateCustomer(ctx, &repository.Customer{
+		FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", Phone: "254700000000",
+	})
+	if err != nil {
+		t.Fatalf("failed to create customer: %v", err)
+	}
+
+	f.product, err = f.products.CreateProduct(ctx, &repository.Product{
+		Name: "Test Product", Price: money.New(100, "KES"), Stock: pkg.UintPtr(stock),
+	})
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	f.order, err = f.orders.CreateOrder(ctx, &repository.Order{
+		CustomerId: customer.Id,
+		Items:      []*repository.OrderItem{{ProductId: f.product.Id, Quantity: 2, UnitPrice: f.product.Price}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	f.checkout = checkout.NewCheckoutService(f.products, customers, f.orders, f.sagas, f.payments)
+
+	return f
+}
+
+func (f *fixture) wantOrder(t *testing.T, status pkg.OrderStatus, stock, reserved uint) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	order, err := f.orders.GetOrder(ctx, f.order.Id)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	if order.OrderStatus != status {
+		t.Errorf("order status = %s, want %s", order.OrderStatus, status)
+	}
+
+	product, err := f.products.GetProduct(ctx, f.product.Id)
+	if err != nil {
+		t.Fatalf("failed to get product: %v", err)
+	}
+	if product.Stock == nil {
+		t.Fatalf("stock is untracked, want %d", stock)
+	}
+	if *product.Stock != stock || product.Reserved != reserved {
+		t.Errorf("stock = %d, reserved = %d, want %d and %d", *product.Stock, product.Reserved, stock, reserved)
+	}
+}
+
+// wantSaga returns the saga with the id after checking that it is in status.
+func (f *fixture) wantSaga(t *testing.T, id string, status repository.CheckoutSagaStatus) *repository.CheckoutSaga {
+	t.Helper()
+
+	saga, err := f.sagas.GetCheckoutSaga(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get checkout saga: %v", err)
+	}
+	if saga.Status != status {
+		t.Errorf("checkout saga status = %s, want %s", saga.Status, status)
+	}
+
+	return saga
+}
+
+// startSaga creates a saga for the fixture's order and moves it to step.
+func (f *fixture) startSaga(
+	t *testing.T, step repository.CheckoutStep, status repository.CheckoutSagaStatus) *repository.CheckoutSaga {
+
+	t.Helper()
+
+	ctx := context.Background()
+
+	saga, err := f.sagas.CreateCheckoutSaga(ctx, &repository.CheckoutSaga{OrderId: f.order.Id})
+	if err != nil {
+		t.Fatalf("failed to create checkout saga: %v", err)
+	}
+
+	saga, err = f.sagas.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+		Step: step, Status: status, Error: "interrupted", Version: saga.Version,
+	})
+	if err != nil {
+		t.Fatalf("failed to update checkout saga: %v", err)
+	}
+
+	return saga
+}
+
+// reserve moves the fixture's order to processing as the reserve step of the
+// saga does.
+func (f *fixture) reserve(t *testing.T, saga *repository.CheckoutSaga) {
+	t.Helper()
+
+	_, err := f.orders.UpdateOrderStatus(context.Background(), f.order.Id, &repository.OrderStatusUpdate{
+		Status: pkg.OrderStatusProcessing, StockReservation: "checkout:" + saga.Id,
+	})
+	if err != nil {
+		t.Fatalf("failed to reserve order: %v", err)
+	}
+}
+
+// wantFinished fails the test if a checkout saga is left unfinished.
+func (f *fixture) wantFinished(t *testing.T) {
+	t.Helper()
+
+	sagas, err := f.sagas.ListUnfinishedCheckoutSagas(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list checkout sagas: %v", err)
+	}
+	if len(sagas) != 0 {
+		t.Errorf("%d checkout sagas are unfinished, want none", len(sagas))
+	}
+}
+
+func TestCheckoutService_ProcessCheckout(t *testing.T) {
+	f := setup(t, 5)
+
+	order, err := f.checkout.ProcessCheckout(context.Background(), f.order.Id)
+	if err != nil {
+		t.Fatalf("ProcessCheckout() error = %v", err)
+	}
+	if order.OrderStatus != pkg.OrderStatusPending {
+		t.Errorf("ProcessCheckout() status = %s, want %s", order.OrderStatus, pkg.OrderStatusPending)
+	}
+
+	f.wantOrder(t, pkg.OrderStatusPending, 3, 2)
+	f.wantFinished(t)
+
+	if len(f.payments.requests) != 1 {
+		t.Fatalf("ProcessCheckout() requested %d payments, want 1", len(f.payments.requests))
+	}
+
+	req := f.payments.requests[0]
+	if req.OrderId != f.order.Id || req.Amount.Amount != 200 || req.PhoneNumber != 254700000000 {
+		t.Errorf("ProcessCheckout() requested payment %v", req)
+	}
+
+	// Payments are requested with the id of the saga as idempotency key.
+	f.wantSaga(t, req.IdempotencyKey[len("checkout:"):], repository.CheckoutSagaStatusCompleted)
+}
+
+func TestCheckoutService_ProcessCheckout_Promotion(t *testing.T) {
+	ctx := context.Background()
+	f := setup(t, 5)
+
+	// 10% off KES 155.00 is KES 15.50, which is rounded down to whole
+	// shillings so that M-Pesa can charge the total.
+	order, err := f.orders.CreateOrder(ctx, &repository.Order{
+		CustomerId: f.order.CustomerId,
+		Items:      []*repository.OrderItem{{ProductId: f.product.Id, Quantity: 2, UnitPrice: money.New(7750, "KES")}},
+		Promotion: &repository.AppliedPromotion{
+			PromotionId: "promotion-1",
+			Code:        "TEN",
+			Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	f.order = order
+
+	if _, err := f.checkout.ProcessCheckout(ctx, order.Id); err != nil {
+		t.Fatalf("ProcessCheckout() error = %v", err)
+	}
+
+	if len(f.payments.requests) != 1 {
+		t.Fatalf("ProcessCheckout() requested %d payments, want 1", len(f.payments.requests))
+	}
+
+	amount := f.payments.requests[0].Amount
+	if shillings, err := money.New(amount.Amount, amount.Currency).MajorUnits(); err != nil || shillings != 140 {
+		t.Errorf("ProcessCheckout() requested a payment of %v, want KES 140.00", amount)
+	}
+}
+
+func TestCheckoutService_ProcessCheckout_PaymentFailed(t *testing.T) {
+	f := setup(t, 5)
+	f.payments.err = status.Error(codes.FailedPrecondition, "payment rejected")
+
+	_, err := f.checkout.ProcessCheckout(context.Background(), f.order.Id)
+	if service.ErrorCode(err) != service.FAILED_PRECONDITION_ERROR {
+		t.Fatalf("ProcessCheckout() error = %v, want code %q", err, service.FAILED_PRECONDITION_ERROR)
+	}
+
+	// The order is failed, which releases its items.
+	f.wantOrder(t, pkg.OrderStatusFailed, 5, 0)
+	f.wantFinished(t)
+
+	saga := f.wantSaga(t, f.payments.requests[0].IdempotencyKey[len("checkout:"):],
+		repository.CheckoutSagaStatusCompensated)
+	if saga.Step != repository.CheckoutStepCharge || saga.Error == "" {
+		t.Errorf("checkout saga = %+v, want it compensated at the charge step with an error", saga)
+	}
+
+	// Failed orders can be checked out again.
+	f.payments.err = nil
+	if _, err := f.checkout.ProcessCheckout(context.Background(), f.order.Id); err != nil {
+		t.Fatalf("ProcessCheckout() error = %v", err)
+	}
+	f.wantOrder(t, pkg.OrderStatusPending, 3, 2)
+
+	if key := f.payments.requests[1].IdempotencyKey; key == f.payments.requests[0].IdempotencyKey {
+		t.Errorf("ProcessCheckout() reused idempotency key %q for a new checkout", key)
+	}
+}
+
+func TestCheckoutService_ProcessCheckout_PaymentUnavailable(t *testing.T) {
+	f := setup(t, 5)
+	f.payments.err = status.Error(codes.Unavailable, "payments unavailable")
+
+	_, err := f.checkout.ProcessCheckout(context.Background(), f.order.Id)
+	if service.ErrorCode(err) != service.INTERNAL_ERROR {
+		t.Fatalf("ProcessCheckout() error = %v, want code %q", err, service.INTERNAL_ERROR)
+	}
+
+	// The payment may have been requested, so the order keeps its items and
+	// the saga waits to request it again.
+	f.wantOrder(t, pkg.OrderStatusProcessing, 3, 2)
+
+	key := f.payments.requests[0].IdempotencyKey
+	saga := f.wantSaga(t, key[len("checkout:"):], repository.CheckoutSagaStatusRunning)
+	if saga.Step != repository.CheckoutStepCharge {
+		t.Errorf("checkout saga step = %q, want %q", saga.Step, repository.CheckoutStepCharge)
+	}
+
+	// Checking the order out again resumes the saga with the same key.
+	f.payments.err = nil
+	if _, err := f.checkout.ProcessCheckout(context.Background(), f.order.Id); err != nil {
+		t.Fatalf("ProcessCheckout() error = %v", err)
+	}
+	f.wantOrder(t, pkg.OrderStatusPending, 3, 2)
+	f.wantFinished(t)
+
+	if len(f.payments.requests) != 2 || f.payments.requests[1].IdempotencyKey != key {
+		t.Errorf("ProcessCheckout() requested payments %v, want a retry with key %q", f.payments.requests, key)
+	}
+}
+
+func TestCheckoutService_ProcessCheckout_OutOfStock(t *testing.T) {
+	f := setup(t, 1)
+
+	_, err := f.checkout.ProcessCheckout(context.Background(), f.order.Id)
+	if service.ErrorCode(err) != service.OUT_OF_STOCK_ERROR {
+		t.Fatalf("ProcessCheckout() error = %v, want code %q", err, service.OUT_OF_STOCK_ERROR)
+	}
+
+	f.wantOrder(t, pkg.OrderStatusNew, 1, 0)
+	f.wantFinished(t)
+
+	if len(f.payments.requests) != 0 {
+		t.Errorf("ProcessCheckout() requested %d payments, want none", len(f.payments.requests))
+	}
+}
+
+func TestCheckoutService_Resume(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    repository.CheckoutStep
+		status  repository.CheckoutSagaStatus
+		reserve bool
+
+		// reserveStock reserves the items for the saga without moving the
+		// order on, as if the service stopped in between.
+		reserveStock bool
+
+		wantStatus   repository.CheckoutSagaStatus
+		wantOrder    pkg.OrderStatus
+		wantStock    uint
+		wantReserved uint
+		wantPayments int
+	}{
+		{
+			name: "Not Reserved",
+			step: repository.CheckoutStepReserve, status: repository.CheckoutSagaStatusRunning,
+			wantStatus: repository.CheckoutSagaStatusCompleted, wantOrder: pkg.OrderStatusPending,
+			wantStock: 3, wantReserved: 2, wantPayments: 1,
+		},
+		{
+			name: "Reserved But Not Recorded",
+			step: repository.CheckoutStepReserve, status: repository.CheckoutSagaStatusRunning, reserve: true,
+			wantStatus: repository.CheckoutSagaStatusCompleted, wantOrder: pkg.OrderStatusPending,
+			wantStock: 3, wantReserved: 2, wantPayments: 1,
+		},
+		{
+			name: "Stock Reserved But Not Order",
+			step: repository.CheckoutStepReserve, status: repository.CheckoutSagaStatusRunning, reserveStock: true,
+			wantStatus: repository.CheckoutSagaStatusCompleted, wantOrder: pkg.OrderStatusPending,
+			wantStock: 3, wantReserved: 2, wantPayments: 1,
+		},
+		{
+			name: "Stock Reserved But Compensating",
+			step: repository.CheckoutStepReserve, status: repository.CheckoutSagaStatusCompensating, reserveStock: true,
+			wantStatus: repository.CheckoutSagaStatusCompensated, wantOrder: pkg.OrderStatusNew,
+			wantStock: 5, wantReserved: 0, wantPayments: 0,
+		},
+		{
+			name: "Charging",
+			step: repository.CheckoutStepCharge, status: repository.CheckoutSagaStatusRunning, reserve: true,
+			wantStatus: repository.CheckoutSagaStatusCompleted, wantOrder: pkg.OrderStatusPending,
+			wantStock: 3, wantReserved: 2, wantPayments: 1,
+		},
+		{
+			name: "Confirming",
+			step: repository.CheckoutStepConfirm, status: repository.CheckoutSagaStatusRunning, reserve: true,
+			wantStatus: repository.CheckoutSagaStatusCompleted, wantOrder: pkg.OrderStatusPending,
+			wantStock: 3, wantReserved: 2, wantPayments: 0,
+		},
+		{
+			name: "Compensating",
+			step: repository.CheckoutStepCharge, status: repository.CheckoutSagaStatusCompensating, reserve: true,
+			wantStatus: repository.CheckoutSagaStatusCompensated, wantOrder: pkg.OrderStatusFailed,
+			wantStock: 5, wantReserved: 0, wantPayments: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := setup(t, 5)
+
+			saga := f.startSaga(t, tt.step, tt.status)
+			if tt.reserve {
+				f.reserve(t, saga)
+			}
+			if tt.reserveStock {
+				err := f.products.ReserveStock(context.Background(), "checkout:"+saga.Id,
+					repository.OrderStockItems(f.order.Items))
+				if err != nil {
+					t.Fatalf("failed to reserve stock: %v", err)
+				}
+			}
+
+			if err := f.checkout.Resume(context.Background()); err != nil {
+				t.Fatalf("Resume() error = %v", err)
+			}
+
+			f.wantSaga(t, saga.Id, tt.wantStatus)
+			f.wantOrder(t, tt.wantOrder, tt.wantStock, tt.wantReserved)
+			f.wantFinished(t)
+
+			if len(f.payments.requests) != tt.wantPayments {
+				t.Fatalf("Resume() requested %d payments, want %d", len(f.payments.requests), tt.wantPayments)
+			}
+			if tt.wantPayments > 0 && f.payments.requests[0].IdempotencyKey != "checkout:"+saga.Id {
+				t.Errorf("Resume() requested payment with idempotency key %q, want %q",
+					f.payments.requests[0].IdempotencyKey, "checkout:"+saga.Id)
+			}
+		})
+	}
+}
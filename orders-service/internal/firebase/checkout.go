@@ -0,0 +1,192 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ repository.CheckoutSagaRepository = (*CheckoutSagaRepository)(nil)
+
+type CheckoutSagaRepository struct {
+	db *FirestoreService
+}
+
+func NewCheckoutSagaRepository(db *FirestoreService) *CheckoutSagaRepository {
+	return &CheckoutSagaRepository{
+		db: db,
+	}
+}
+
+func (r *CheckoutSagaRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *CheckoutSagaRepository) sagaCollection() *firestore.CollectionRef {
+	r.CheckPreconditions()
+
+	return r.db.client.Collection("checkout_sagas")
+}
+
+func (r *CheckoutSagaRepository) CreateCheckoutSaga(
+	ctx context.Context, saga *repository.CheckoutSaga) (*repository.CheckoutSaga, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := time.Now().Format(time.RFC3339)
+
+	saga.CreatedAt = currentTime
+	saga.UpdatedAt = currentTime
+	saga.Step = repository.CheckoutStepReserve
+	saga.Status = repository.CheckoutSagaStatusRunning
+	saga.Error = ""
+	saga.Version = 1
+
+	if err := saga.Validate(); err != nil {
+		return nil, err
+	}
+
+	docRef, _, err := r.sagaCollection().Add(ctx, r.marshallCheckoutSaga(saga))
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to create checkout saga: %v", err)
+	}
+
+	saga.Id = docRef.ID
+
+	return saga, nil
+}
+
+func (r *CheckoutSagaRepository) GetCheckoutSaga(ctx context.Context, id string) (*repository.CheckoutSaga, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	doc, err := r.sagaCollection().Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "checkout saga not found")
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get checkout saga: %v", err)
+	}
+
+	return r.unmarshallCheckoutSagaDoc(doc)
+}
+
+func (r *CheckoutSagaRepository) UpdateCheckoutSaga(
+	ctx context.Context, id string, update *repository.CheckoutSagaUpdate) (*repository.CheckoutSaga, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	docRef := r.sagaCollection().Doc(id)
+
+	var saga *repository.CheckoutSaga
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "checkout saga not found")
+		} else if err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to get checkout saga: %v", err)
+		}
+
+		if saga, err = r.unmarshallCheckoutSagaDoc(doc); err != nil {
+			return err
+		}
+
+		if err := saga.ApplyUpdate(update); err != nil {
+			return err
+		}
+
+		saga.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		return tx.Set(docRef, r.marshallCheckoutSaga(saga))
+	})
+
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return nil, err
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update checkout saga: %v", err)
+	}
+
+	return saga, nil
+}
+
+// ListUnfinishedCheckoutSagas sorts the sagas itself, as ordering the query
+// by another field than it filters on would need a composite index.
+func (r *CheckoutSagaRepository) ListUnfinishedCheckoutSagas(ctx context.Context) ([]*repository.CheckoutSaga, error) {
+	r.CheckPreconditions()
+
+	docs, err := r.sagaCollection().Where("status", "in", []string{
+		string(repository.CheckoutSagaStatusRunning),
+		string(repository.CheckoutSagaStatusCompensating),
+	}).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to list checkout sagas: %v", err)
+	}
+
+	sagas := make([]*repository.CheckoutSaga, 0, len(docs))
+	for _, doc := range docs {
+		saga, err := r.unmarshallCheckoutSagaDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		sagas = append(sagas, saga)
+	}
+
+	sort.Slice(sagas, func(i, j int) bool {
+		if sagas[i].CreatedAt != sagas[j].CreatedAt {
+			return sagas[i].CreatedAt < sagas[j].CreatedAt
+		}
+
+		return sagas[i].Id < sagas[j].Id
+	})
+
+	return sagas, nil
+}
+
+func (r *CheckoutSagaRepository) marshallCheckoutSaga(saga *repository.CheckoutSaga) *CheckoutSagaModel {
+	return &CheckoutSagaModel{
+		OrderId:   saga.OrderId,
+		Step:      string(saga.Step),
+		Status:    string(saga.Status),
+		Error:     saga.Error,
+		Version:   int(saga.Version),
+		CreatedAt: saga.CreatedAt,
+		UpdatedAt: saga.UpdatedAt,
+	}
+}
+
+func (r *CheckoutSagaRepository) unmarshallCheckoutSagaDoc(
+	doc *firestore.DocumentSnapshot) (*repository.CheckoutSaga, error) {
+
+	var model CheckoutSagaModel
+	if err := doc.DataTo(&model); err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall checkout saga: %v", err)
+	}
+
+	return &repository.CheckoutSaga{
+		Id:        doc.Ref.ID,
+		OrderId:   model.OrderId,
+		Step:      repository.CheckoutStep(model.Step),
+		Status:    repository.CheckoutSagaStatus(model.Status),
+		Error:     model.Error,
+		Version:   uint(model.Version),
+		CreatedAt: model.CreatedAt,
+		UpdatedAt: model.UpdatedAt,
+	}, nil
+}
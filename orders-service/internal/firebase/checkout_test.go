@@ -0,0 +1,25 @@
+package firebase_test
+
+import (
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestCheckoutSagaRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("CheckoutSagaRepository.CheckPreconditions() did not panic without a DB")
+		}
+	}()
+
+	db.NewCheckoutSagaRepository(nil).CheckPreconditions()
+}
+
+func TestCheckoutSagaRepository(t *testing.T) {
+	repositorytest.TestCheckoutSagaRepository(t, func(t *testing.T) repository.CheckoutSagaRepository {
+		return db.NewCheckoutSagaRepository(MustOpenFirestore(t))
+	})
+}
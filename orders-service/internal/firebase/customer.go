@@ -86,12 +86,30 @@ func (s *CustomerRepository) GetCustomer(ctx context.Context, id string) (*repos
 	return customer, nil
 }
 
-func (s *CustomerRepository) ListCustomers(ctx context.Context) ([]*repository.Customer, error) {
+// customerCursors convert the page token values of the customer sort fields.
+var customerCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"last_name":  textCursor,
+}
+
+func (s *CustomerRepository) ListCustomers(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Customer, string, error) {
+
 	s.CheckPreconditions()
 
-	iter := s.customerCollection().Documents(ctx)
+	page, err := repository.ParsePage(opts, repository.CustomerSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
 
-	var customers []*repository.Customer
+	q, err := pageQuery(s.customerCollection().Query, page, customerCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	iter := q.Documents(ctx)
+
+	customers := make([]*repository.Customer, 0)
 
 	for {
 		docRef, err := iter.Next()
@@ -99,12 +117,12 @@ func (s *CustomerRepository) ListCustomers(ctx context.Context) ([]*repository.C
 			break
 		}
 		if err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to iterate customers: %v", err)
+			return nil, "", service.Errorf(service.INTERNAL_ERROR, "failed to iterate customers: %v", err)
 		}
 
 		customerModel := &CustomerModel{}
 		if err := docRef.DataTo(customerModel); err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall customer: %v", err)
+			return nil, "", service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall customer: %v", err)
 		}
 
 		customer := s.unmarshallCustomer(customerModel)
@@ -113,7 +131,12 @@ func (s *CustomerRepository) ListCustomers(ctx context.Context) ([]*repository.C
 		customers = append(customers, customer)
 	}
 
-	return customers, nil
+	customers, next := repository.TrimPage(page, customers,
+		func(c *repository.Customer) string { return c.SortValue(page.Ordering.Field) },
+		func(c *repository.Customer) string { return c.Id },
+	)
+
+	return customers, next, nil
 }
 
 func (s *CustomerRepository) UpdateCustomer(
@@ -168,9 +191,14 @@ func (s *CustomerRepository) DeleteCustomer(ctx context.Context, id string) erro
 		return service.Errorf(service.INVALID_ERROR, "id is required")
 	}
 
-	_, err := s.customerCollection().Doc(id).Delete(ctx)
+	_, err := s.customerCollection().Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return service.Errorf(service.NOT_FOUND_ERROR, "customer not found")
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to delete customer: %v", err)
+	}
 
-	return err
+	return nil
 }
 
 func (s *CustomerRepository) marshallCustomer(customer *repository.Customer) *CustomerModel {
@@ -9,11 +9,14 @@ import (
 
 	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 )
 
 func deleteTestCustomer(t *testing.T, ctx context.Context, cs repository.CustomerRepository, id string) {
 	err := cs.DeleteCustomer(ctx, id)
-	if err != nil {
+	// Tests that delete the record themselves leave nothing to clean up.
+	if err != nil && service.ErrorCode(err) != service.NOT_FOUND_ERROR {
 		t.Fatalf("failed to delete product: %v", err)
 	}
 }
@@ -252,7 +255,7 @@ func TestCustomerService_ListCustomers(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := customerRepository.ListCustomers(tt.args.ctx)
+			got, _, err := customerRepository.ListCustomers(tt.args.ctx, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CustomerRepository.ListCustomers() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -422,3 +425,9 @@ func TestCustomerService_DeleteProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomerRepository(t *testing.T) {
+	repositorytest.TestCustomerRepository(t, func(t *testing.T) repository.CustomerRepository {
+		return db.NewCustomerService(MustOpenFirestore(t))
+	})
+}
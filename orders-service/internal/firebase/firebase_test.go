@@ -1,6 +1,7 @@
 package firebase_test
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
@@ -9,6 +10,8 @@ import (
 	"sync"
 	"syscall"
 	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
 )
 
 const FirestoreEmulatorHost = "FIRESTORE_EMULATOR_HOST"
@@ -85,3 +88,19 @@ func killCommand(cmd *exec.Cmd, result int) {
 	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
 	os.Exit(result)
 }
+
+// MustOpenFirestore connects to the emulator and closes the connection when
+// the test completes.
+func MustOpenFirestore(tb testing.TB) *db.FirestoreService {
+	tb.Helper()
+
+	ctx := context.Background()
+
+	firestoreClient, err := db.NewFirebaseService().GetApp().Firestore(ctx)
+	if err != nil {
+		tb.Fatalf("failed to create firestore client: %v", err)
+	}
+	tb.Cleanup(func() { firestoreClient.Close() })
+
+	return db.NewFirestoreService(firestoreClient)
+}
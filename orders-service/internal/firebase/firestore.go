@@ -1,7 +1,12 @@
 package firebase
 
 import (
+	"strconv"
+	"time"
+
 	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 )
 
 type FirestoreService struct {
@@ -13,3 +18,49 @@ func NewFirestoreService(client *firestore.Client) *FirestoreService {
 		client: client,
 	}
 }
+
+// pageQuery orders q as the page requests and starts it after the cursor of
+// the page; cursor converts the cursor value to the stored value of the
+// ordering field. One document more than the page size is fetched, see
+// repository.TrimPage.
+func pageQuery(q firestore.Query, page *repository.Page, cursor func(value string) (any, error)) (firestore.Query, error) {
+	dir := firestore.Asc
+	if page.Ordering.Desc {
+		dir = firestore.Desc
+	}
+
+	q = q.OrderBy(page.Ordering.Field, dir).OrderBy(firestore.DocumentID, dir)
+
+	if page.Cursor != nil {
+		value, err := cursor(page.Cursor.Value)
+		if err != nil {
+			return q, service.Errorf(service.INVALID_ERROR, "invalid page_token")
+		}
+
+		q = q.StartAfter(value, page.Cursor.Id)
+	}
+
+	return q.Limit(page.Size + 1), nil
+}
+
+// timeCursor converts the cursor value of a timestamp field to the format the
+// timestamps are stored in.
+func timeCursor(value string) (any, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.Local().Format(time.RFC3339), nil
+}
+
+// uintCursor parses the cursor value of an unsigned integer field.
+func uintCursor(value string) (any, error) {
+	n, err := strconv.ParseUint(value, 10, 63)
+	return int64(n), err
+}
+
+// textCursor parses the cursor value of a string field.
+func textCursor(value string) (any, error) {
+	return value, nil
+}
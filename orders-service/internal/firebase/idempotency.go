@@ -0,0 +1,12 @@
+package firebase
+
+import (
+	idempotency_firebase "github.com/Mik3y-F/order-management-system/pkg/idempotency/firebase"
+)
+
+// NewIdempotencyStore returns a store of idempotency keys in the
+// idempotency_keys collection. Set a TTL policy on its expires_at field to
+// delete expired keys.
+func NewIdempotencyStore(db *FirestoreService) *idempotency_firebase.Store {
+	return idempotency_firebase.NewStore(db.client, "idempotency_keys")
+}
@@ -0,0 +1,15 @@
+package firebase_test
+
+import (
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/idempotencytest"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	idempotencytest.TestStore(t, func(t *testing.T) idempotency.Store {
+		return db.NewIdempotencyStore(MustOpenFirestore(t))
+	})
+}
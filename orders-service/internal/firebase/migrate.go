@@ -0,0 +1,485 @@
+package firebase
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	orderPkg "github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MigrateOrderItems moves order items into the items array of their order
+// document, which is the only place they are stored now.
+//
+// Older versions wrote every item twice: embedded in the order without an id,
+// and as a document in the order's "items" subcollection. The subcollection
+// was what the service read back, so it takes precedence; it is deleted once
+// its items have been embedded. Embedded items that never got an id are given
+// one. Orders that are already migrated are left untouched, so the migration
+// can safely be run more than once. It returns the number of orders changed.
+func MigrateOrderItems(ctx context.Context, db *FirestoreService) (int, error) {
+	r := NewOrderRepository(db)
+
+	iter := r.orderCollection().DocumentRefs(ctx)
+
+	migrated := 0
+	for {
+		orderRef, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to iterate orders: %v", err)
+		}
+
+		changed, err := r.migrateOrderItems(ctx, orderRef)
+		if err != nil {
+			return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to migrate order %s: %v", orderRef.ID, err)
+		}
+
+		if changed {
+			migrated++
+		}
+	}
+
+	return migrated, nil
+}
+
+func (r *OrderRepository) migrateOrderItems(ctx context.Context, orderRef *firestore.DocumentRef) (bool, error) {
+	changed := false
+
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		changed = false
+
+		doc, err := tx.Get(orderRef)
+		if err != nil {
+			return err
+		}
+
+		orderModel := &OrderModel{}
+		if err := doc.DataTo(orderModel); err != nil {
+			return err
+		}
+
+		itemDocs, err := tx.Documents(orderRef.Collection("items")).GetAll()
+		if err != nil {
+			return err
+		}
+
+		items := orderModel.Items
+		if len(itemDocs) > 0 {
+			items = make([]*OrderItemModel, 0, len(itemDocs))
+			for _, itemDoc := range itemDocs {
+				item := &OrderItemModel{}
+				if err := itemDoc.DataTo(item); err != nil {
+					return err
+				}
+				item.Id = itemDoc.Ref.ID
+
+				items = append(items, item)
+			}
+
+			// Subcollection documents come back in id order, which is random.
+			sort.SliceStable(items, func(i, j int) bool {
+				return items[i].CreatedAt < items[j].CreatedAt
+			})
+
+			changed = true
+		}
+
+		for _, item := range items {
+			if item.Id == "" {
+				item.Id = r.newOrderItemID()
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+
+		if err := tx.Update(orderRef, []firestore.Update{{Path: "items", Value: items}}); err != nil {
+			return err
+		}
+
+		for _, itemDoc := range itemDocs {
+			if err := tx.Delete(itemDoc.Ref); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return changed, err
+}
+
+// MigrateOrderTotals prices the items of orders written before items kept the
+// price of their product, and stores the order totals. Items without a unit
+// price are priced at the current price of their product; items whose product
+// no longer exists keep a price of zero. Orders whose totals are already up to
+// date are left untouched. It returns the number of orders changed.
+func MigrateOrderTotals(ctx context.Context, db *FirestoreService) (int, error) {
+	r := NewOrderRepository(db)
+	products := NewProductService(db)
+
+	iter := r.orderCollection().DocumentRefs(ctx)
+
+	migrated := 0
+	for {
+		orderRef, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to iterate orders: %v", err)
+		}
+
+		changed, err := r.migrateOrderTotals(ctx, products, orderRef)
+		if err != nil {
+			return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to migrate order %s: %v", orderRef.ID, err)
+		}
+
+		if changed {
+			migrated++
+		}
+	}
+
+	return migrated, nil
+}
+
+func (r *OrderRepository) migrateOrderTotals(
+	ctx context.Context, products *ProductRepository, orderRef *firestore.DocumentRef) (bool, error) {
+
+	changed := false
+
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(orderRef)
+		if err != nil {
+			return err
+		}
+
+		order, err := r.unmarshallOrderDoc(doc)
+		if err != nil {
+			return err
+		}
+
+		before := *r.marshallOrder(order)
+
+		for _, item := range order.Items {
+			if !item.UnitPrice.IsZero() {
+				continue
+			}
+
+			productDoc, err := tx.Get(products.productCollection().Doc(item.ProductId))
+			if status.Code(err) == codes.NotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			product, err := products.unmarshallProductDoc(productDoc)
+			if err != nil {
+				return err
+			}
+
+			item.UnitPrice = product.Price
+		}
+
+		if err := order.UpdateTotals(); err != nil {
+			return err
+		}
+
+		after := r.marshallOrder(order)
+		changed = !reflect.DeepEqual(&before, after)
+		if !changed {
+			return nil
+		}
+
+		return tx.Set(orderRef, after)
+	})
+
+	return changed, err
+}
+
+// migratedCurrency is the currency of the amounts written before amounts were
+// stored in minor units with their currency: they were whole shillings.
+const migratedCurrency = "KES"
+
+// MigrateMoney converts the prices of products and the amounts of orders from
+// whole shillings to the minor units of their currency, and stores the
+// currency next to them. Documents that already have a currency field are
+// left untouched. It returns the number of documents changed.
+//
+// It must run before MigrateOrderTotals, which prices items at the current
+// price of their product.
+func MigrateMoney(ctx context.Context, db *FirestoreService) (int, error) {
+	products := NewProductService(db)
+	orders := NewOrderRepository(db)
+
+	migrated := 0
+	for _, collection := range []struct {
+		ref     *firestore.CollectionRef
+		migrate func(tx *firestore.Transaction, doc *firestore.DocumentSnapshot) error
+	}{
+		{products.productCollection(), migrateProductMoney},
+		{orders.orderCollection(), migrateOrderMoney},
+	} {
+		iter := collection.ref.DocumentRefs(ctx)
+		for {
+			ref, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to iterate %s: %v", collection.ref.ID, err)
+			}
+
+			changed := false
+			err = db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+				changed = false
+
+				doc, err := tx.Get(ref)
+				if err != nil {
+					return err
+				}
+
+				if _, ok := doc.Data()["currency"]; ok {
+					return nil
+				}
+
+				changed = true
+
+				return collection.migrate(tx, doc)
+			})
+			if err != nil {
+				return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to migrate %s: %v", ref.Path, err)
+			}
+
+			if changed {
+				migrated++
+			}
+		}
+	}
+
+	return migrated, nil
+}
+
+func migrateProductMoney(tx *firestore.Transaction, doc *firestore.DocumentSnapshot) error {
+	var product ProductModel
+	if err := doc.DataTo(&product); err != nil {
+		return err
+	}
+
+	price, err := toMinorUnits(product.Price)
+	if err != nil {
+		return err
+	}
+
+	return tx.Update(doc.Ref, []firestore.Update{
+		{Path: "price", Value: price},
+		{Path: "currency", Value: migratedCurrency},
+	})
+}
+
+func migrateOrderMoney(tx *firestore.Transaction, doc *firestore.DocumentSnapshot) error {
+	var order OrderModel
+	if err := doc.DataTo(&order); err != nil {
+		return err
+	}
+
+	// Zero amounts are left without a currency, like the zero money.Money.
+	for _, item := range order.Items {
+		if item.UnitPrice == 0 {
+			continue
+		}
+
+		item.Currency = migratedCurrency
+		for _, amount := range []*int64{&item.UnitPrice, &item.LineTotal} {
+			if err := convertToMinorUnits(amount); err != nil {
+				return err
+			}
+		}
+	}
+
+	if order.Subtotal != 0 || order.Tax != 0 || order.Discount != 0 {
+		order.Currency = migratedCurrency
+		for _, amount := range []*int64{&order.Subtotal, &order.Tax, &order.Discount, &order.Total} {
+			if err := convertToMinorUnits(amount); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Set(doc.Ref, &order)
+}
+
+// convertToMinorUnits converts an amount of whole shillings to cents.
+func convertToMinorUnits(amount *int64) error {
+	converted, err := toMinorUnits(*amount)
+	if err != nil {
+		return err
+	}
+
+	*amount = converted
+
+	return nil
+}
+
+func toMinorUnits(amount int64) (int64, error) {
+	converted, err := money.New(amount, migratedCurrency).Mul(100)
+	return converted.Amount, err
+}
+
+// MigrateStockReservations records the stock held by orders that were checked
+// out before reservations were: each gets a reservation under its own id with
+// the units of the products whose stock is tracked now. Orders that have a
+// reservation already are left untouched. It returns the number of orders
+// changed.
+func MigrateStockReservations(ctx context.Context, db *FirestoreService) (int, error) {
+	r := NewOrderRepository(db)
+	products := NewProductService(db)
+
+	iter := r.orderCollection().Where("order_status", "in", []string{
+		string(orderPkg.OrderStatusProcessing), string(orderPkg.OrderStatusPending),
+	}).Documents(ctx)
+	defer iter.Stop()
+
+	migrated := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to iterate orders: %v", err)
+		}
+
+		changed, err := r.migrateStockReservation(ctx, products, doc.Ref)
+		if err != nil {
+			return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to migrate order %s: %v", doc.Ref.ID, err)
+		}
+
+		if changed {
+			migrated++
+		}
+	}
+
+	return migrated, nil
+}
+
+func (r *OrderRepository) migrateStockReservation(
+	ctx context.Context, products *ProductRepository, orderRef *firestore.DocumentRef) (bool, error) {
+
+	changed := false
+
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		changed = false
+
+		doc, err := tx.Get(orderRef)
+		if err != nil {
+			return err
+		}
+
+		order, err := r.unmarshallOrderDoc(doc)
+		if err != nil {
+			return err
+		}
+
+		if order.StockReservation != "" || len(order.Items) == 0 {
+			return nil
+		}
+
+		items, err := repository.MergeStockItems(repository.OrderStockItems(order.Items))
+		if err != nil {
+			return err
+		}
+
+		tracked, err := products.getProducts(tx, repository.StockProductIds(items))
+		if err != nil {
+			return err
+		}
+
+		reserved := make([]repository.StockItem, 0, len(items))
+		for _, item := range items {
+			if product, ok := tracked[item.ProductId]; ok && product.Stock != nil {
+				reserved = append(reserved, item)
+			}
+		}
+
+		changed = true
+
+		if err := tx.Set(products.reservationDoc(order.Id), marshallStockReservation(reserved)); err != nil {
+			return err
+		}
+
+		return tx.Update(orderRef, []firestore.Update{{Path: "stock_reservation", Value: order.Id}})
+	})
+
+	return changed, err
+}
+
+// MigratePromotionRedemptions releases the redemptions of orders that failed,
+// were cancelled or were deleted before such orders stopped counting towards
+// the limits of their promotion. It returns the number of redemptions
+// released.
+func MigratePromotionRedemptions(ctx context.Context, db *FirestoreService) (int, error) {
+	r := NewPromotionRepository(db)
+	orders := NewOrderRepository(db)
+
+	promotionRefs, err := r.promotionCollection().DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return 0, service.Errorf(service.INTERNAL_ERROR, "failed to list promotions: %v", err)
+	}
+
+	migrated := 0
+	for _, promotionRef := range promotionRefs {
+		redemptionRefs, err := r.redemptionCollection(promotionRef.ID).DocumentRefs(ctx).GetAll()
+		if err != nil {
+			return migrated, service.Errorf(service.INTERNAL_ERROR, "failed to list promotion redemptions: %v", err)
+		}
+
+		for _, redemptionRef := range redemptionRefs {
+			released, err := orders.migratePromotionRedemption(ctx, r, promotionRef.ID, redemptionRef.ID)
+			if err != nil {
+				return migrated, service.Errorf(service.INTERNAL_ERROR,
+					"failed to migrate redemption of promotion %s by order %s: %v", promotionRef.ID, redemptionRef.ID, err)
+			}
+
+			if released {
+				migrated++
+			}
+		}
+	}
+
+	return migrated, nil
+}
+
+func (r *OrderRepository) migratePromotionRedemption(
+	ctx context.Context, promotions *PromotionRepository, promotionId string, orderId string) (bool, error) {
+
+	doc, err := r.orderCollection().Doc(orderId).Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return false, err
+	}
+
+	if err == nil {
+		order, err := r.unmarshallOrderDoc(doc)
+		if err != nil {
+			return false, err
+		}
+
+		if order.OrderStatus != orderPkg.OrderStatusFailed && order.OrderStatus != orderPkg.OrderStatusCancelled {
+			return false, nil
+		}
+	}
+
+	return promotions.releasePromotion(ctx, promotionId, orderId)
+}
@@ -0,0 +1,92 @@
+package firebase_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+)
+
+func TestMigrateOrderItems(t *testing.T) {
+	ctx := context.Background()
+
+	firestoreClient, err := db.NewFirebaseService().GetApp().Firestore(ctx)
+	if err != nil {
+		t.Fatalf("failed to create firestore client: %v", err)
+	}
+	defer firestoreClient.Close()
+
+	firestoreService := db.NewFirestoreService(firestoreClient)
+	orderRepository := db.NewOrderRepository(firestoreService)
+
+	// Write an order the way older versions did: items embedded without ids and
+	// duplicated in the items subcollection.
+	createdAt := time.Now().Format(time.RFC3339)
+	legacyItem := map[string]interface{}{
+		"product_id": "product-1",
+		"quantity":   2,
+		"created_at": createdAt,
+		"updated_at": createdAt,
+	}
+
+	orderRef, _, err := firestoreClient.Collection("orders").Add(ctx, map[string]interface{}{
+		"customer_id":  "customer-1",
+		"items":        []interface{}{legacyItem},
+		"order_status": "new",
+		"created_at":   createdAt,
+		"updated_at":   createdAt,
+	})
+	if err != nil {
+		t.Fatalf("failed to create legacy order: %v", err)
+	}
+	defer deleteTestOrder(t, ctx, orderRepository, orderRef.ID)
+
+	itemRef, _, err := orderRef.Collection("items").Add(ctx, legacyItem)
+	if err != nil {
+		t.Fatalf("failed to create legacy order item: %v", err)
+	}
+
+	if _, err := db.MigrateOrderItems(ctx, firestoreService); err != nil {
+		t.Fatalf("MigrateOrderItems() error = %v", err)
+	}
+
+	got, err := orderRepository.GetOrder(ctx, orderRef.ID)
+	if err != nil {
+		t.Fatalf("OrderRepository.GetOrder() error = %v", err)
+	}
+
+	want := []*repository.OrderItem{{
+		Id:        itemRef.ID,
+		ProductId: "product-1",
+		Quantity:  2,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}}
+	if !reflect.DeepEqual(got.Items, want) {
+		t.Errorf("OrderRepository.GetOrder() items = %+v, want %+v", got.Items, want)
+	}
+
+	remaining, err := orderRef.Collection("items").Documents(ctx).GetAll()
+	if err != nil {
+		t.Fatalf("failed to list legacy order items: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("items subcollection has %d documents, want none", len(remaining))
+	}
+
+	// Migrated orders are left untouched.
+	if _, err := db.MigrateOrderItems(ctx, firestoreService); err != nil {
+		t.Fatalf("MigrateOrderItems() error = %v", err)
+	}
+
+	again, err := orderRepository.GetOrder(ctx, orderRef.ID)
+	if err != nil {
+		t.Fatalf("OrderRepository.GetOrder() error = %v", err)
+	}
+	if !reflect.DeepEqual(again, got) {
+		t.Errorf("OrderRepository.GetOrder() = %+v after a second migration, want %+v", again, got)
+	}
+}
@@ -1,13 +1,30 @@
 package firebase
 
+import "time"
+
 type ProductModel struct {
 	Name        string `firestore:"name"`
 	Description string `firestore:"description"`
-	Price       int    `firestore:"price"`
+	Price       int64  `firestore:"price"`
+	Currency    string `firestore:"currency"`
+	Category    string `firestore:"category"`
+	Stock       *int   `firestore:"stock"` // missing or null when the stock is not tracked
+	Reserved    int    `firestore:"reserved"`
 	CreatedAt   string `firestore:"created_at"`
 	UpdatedAt   string `firestore:"updated_at"`
 }
 
+// StockReservationModel is stored in the stock_reservations collection, with
+// the escaped reservation id as its document id.
+type StockReservationModel struct {
+	Items []*StockItemModel `firestore:"items"`
+}
+
+type StockItemModel struct {
+	ProductId string `firestore:"product_id"`
+	Quantity  int    `firestore:"quantity"`
+}
+
 type CustomerModel struct {
 	FirstName string `firestore:"first_name"`
 	LastName  string `firestore:"last_name"`
@@ -18,16 +35,129 @@ type CustomerModel struct {
 }
 
 type OrderModel struct {
-	CustomerId  string            `firestore:"customer_id"`
-	Items       []*OrderItemModel `firestore:"items"`
-	OrderStatus string            `firestore:"order_status"`
-	CreatedAt   string            `firestore:"created_at"`
-	UpdatedAt   string            `firestore:"updated_at"`
+	CustomerId       string              `firestore:"customer_id"`
+	Items            []*OrderItemModel   `firestore:"items"`
+	OrderStatus      string              `firestore:"order_status"`
+	Version          int                 `firestore:"version"`
+	Currency         string              `firestore:"currency"`
+	Subtotal         int64               `firestore:"subtotal"`
+	Tax              int64               `firestore:"tax"`
+	Discount         int64               `firestore:"discount"`
+	Total            int64               `firestore:"total"`
+	Promotion        *PromotionRuleModel `firestore:"promotion"`
+	StockReservation string              `firestore:"stock_reservation"`
+	CreatedAt        string              `firestore:"created_at"`
+	UpdatedAt        string              `firestore:"updated_at"`
 }
 
+// OrderItemModel is embedded in the items array of its order document.
 type OrderItemModel struct {
+	Id        string `firestore:"id"`
 	ProductId string `firestore:"product_id"`
 	Quantity  int    `firestore:"quantity"`
+	Currency  string `firestore:"currency"`
+	UnitPrice int64  `firestore:"unit_price"`
+	LineTotal int64  `firestore:"line_total"`
+
+	TaxCategory  string `firestore:"tax_category"`
+	TaxRate      int    `firestore:"tax_rate"`
+	TaxExempt    bool   `firestore:"tax_exempt"`
+	TaxInclusive bool   `firestore:"tax_inclusive"`
+	Tax          int64  `firestore:"tax"`
+
 	CreatedAt string `firestore:"created_at"`
 	UpdatedAt string `firestore:"updated_at"`
 }
+
+// OrderStatusChangeModel is stored in the status_history subcollection of its
+// order document.
+type OrderStatusChangeModel struct {
+	FromStatus string `firestore:"from_status"`
+	ToStatus   string `firestore:"to_status"`
+	Actor      string `firestore:"actor"`
+	Reason     string `firestore:"reason"`
+	Version    int    `firestore:"version"`
+	CreatedAt  string `firestore:"created_at"`
+}
+
+// PromotionRuleModel is the rule of a promotion document, and the copy of it
+// embedded in the orders the promotion is applied to. PromotionId and Code are
+// only set in orders.
+type PromotionRuleModel struct {
+	PromotionId string `firestore:"promotion_id,omitempty"`
+	Code        string `firestore:"code,omitempty"`
+	Type        string `firestore:"type"`
+	Percentage  int    `firestore:"percentage"`
+	Currency    string `firestore:"currency"`
+	AmountOff   int64  `firestore:"amount_off"`
+	ProductId   string `firestore:"product_id"`
+	BuyQuantity int    `firestore:"buy_quantity"`
+	GetQuantity int    `firestore:"get_quantity"`
+}
+
+type PromotionModel struct {
+	Code             string             `firestore:"code"`
+	Description      string             `firestore:"description"`
+	Rule             PromotionRuleModel `firestore:"rule"`
+	StartsAt         string             `firestore:"starts_at"`
+	EndsAt           string             `firestore:"ends_at"`
+	UsageLimit       int                `firestore:"usage_limit"`
+	PerCustomerLimit int                `firestore:"per_customer_limit"`
+	Redemptions      int                `firestore:"redemptions"`
+	CreatedAt        string             `firestore:"created_at"`
+	UpdatedAt        string             `firestore:"updated_at"`
+}
+
+// PromotionRedemptionModel is stored in the redemptions subcollection of its
+// promotion document, with the id of the order as its document id.
+type PromotionRedemptionModel struct {
+	CustomerId string `firestore:"customer_id"`
+	CreatedAt  string `firestore:"created_at"`
+}
+
+// CheckoutSagaModel is stored in the checkout_sagas collection.
+type CheckoutSagaModel struct {
+	OrderId   string `firestore:"order_id"`
+	Step      string `firestore:"step"`
+	Status    string `firestore:"status"`
+	Error     string `firestore:"error"`
+	Version   int    `firestore:"version"`
+	CreatedAt string `firestore:"created_at"`
+	UpdatedAt string `firestore:"updated_at"`
+}
+
+// OutboxEventModel is stored in the outbox_events collection. CreatedAt is a
+// timestamp, whose sub-second precision keeps events in the order they were
+// recorded.
+type OutboxEventModel struct {
+	Type          string    `firestore:"type"`
+	AggregateType string    `firestore:"aggregate_type"`
+	AggregateId   string    `firestore:"aggregate_id"`
+	Payload       string    `firestore:"payload"`
+	CreatedAt     time.Time `firestore:"created_at"`
+}
+
+type WebhookSubscriptionModel struct {
+	Url        string   `firestore:"url"`
+	Secret     string   `firestore:"secret"`
+	EventTypes []string `firestore:"event_types"`
+	CreatedAt  string   `firestore:"created_at"`
+	UpdatedAt  string   `firestore:"updated_at"`
+}
+
+// WebhookDeliveryModel is stored in the webhook_deliveries collection. The
+// pair of SubscriptionId and EventId is unique, which CreateWebhookDelivery
+// checks in a transaction.
+type WebhookDeliveryModel struct {
+	SubscriptionId   string `firestore:"subscription_id"`
+	EventId          string `firestore:"event_id"`
+	EventType        string `firestore:"event_type"`
+	Payload          []byte `firestore:"payload"`
+	Status           string `firestore:"status"`
+	Attempts         int    `firestore:"attempts"`
+	LastResponseCode int    `firestore:"last_response_code"`
+	LastError        string `firestore:"last_error"`
+	NextAttemptAt    string `firestore:"next_attempt_at"`
+	CreatedAt        string `firestore:"created_at"`
+	UpdatedAt        string `firestore:"updated_at"`
+}
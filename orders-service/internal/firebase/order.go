@@ -2,12 +2,14 @@ package firebase
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
 	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 	orderPkg "github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -37,36 +39,65 @@ func (r *OrderRepository) orderCollection() *firestore.CollectionRef {
 	return r.db.client.Collection("orders")
 }
 
+func (r *OrderRepository) historyCollection(orderId string) *firestore.CollectionRef {
+	return r.orderCollection().Doc(orderId).Collection("status_history")
+}
+
+// CreateOrder stores the order with its items embedded in a single document,
+// so that the order and its items are always written together.
 func (r *OrderRepository) CreateOrder(ctx context.Context, order *repository.Order) (*repository.Order, error) {
 	r.CheckPreconditions()
 
 	// Set CreatedAt and UpdatedAt to the current time
-	currentTime := time.Now()
+	currentTime := time.Now().Format(time.RFC3339)
 
-	order.CreatedAt = currentTime.Format(time.RFC3339)
-	order.UpdatedAt = currentTime.Format(time.RFC3339)
+	order.CreatedAt = currentTime
+	order.UpdatedAt = currentTime
 
 	order.OrderStatus = orderPkg.OrderStatusNew
+	order.Version = 1
+
+	for _, item := range order.Items {
+		item.CreatedAt = currentTime
+		item.UpdatedAt = currentTime
+	}
 
 	err := order.Validate()
 	if err != nil {
 		return nil, service.Errorf(service.INVALID_ERROR, "invalid order details provided: %v", err)
 	}
 
-	orderModel := r.marshallOrder(order)
+	for _, item := range order.Items {
+		item.Id = r.newOrderItemID()
+	}
 
-	docRef, _, err := r.orderCollection().Add(ctx, orderModel)
+	if err := order.UpdateTotals(); err != nil {
+		return nil, err
+	}
+
+	docRef := r.orderCollection().NewDoc()
+
+	created := *order
+	created.Id = docRef.ID
+
+	event, err := repository.NewOrderCreatedEvent(&created)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to create order: %v", err)
+		return nil, err
 	}
 
-	order.Id = docRef.ID
+	err = r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Create(docRef, r.marshallOrder(order)); err != nil {
+			return err
+		}
 
-	_, err = r.CreateOrderItems(ctx, order.Id, order.Items)
+		return createOutboxEvent(tx, r.db, event)
+	})
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to create order items: %v", err)
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to create order: %v", err)
 	}
 
+	order.Id = docRef.ID
+
 	return order, nil
 }
 
@@ -77,36 +108,58 @@ func (r *OrderRepository) GetOrder(ctx context.Context, id string) (*repository.
 		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
 	}
 
-	docRef, err := r.orderCollection().Doc(id).Get(ctx)
+	doc, err := r.orderCollection().Doc(id).Get(ctx)
 	if status.Code(err) == codes.NotFound {
 		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order not found")
 	} else if err != nil {
 		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get order: %v", err)
 	}
 
-	orderModel := &OrderModel{}
-	if err := docRef.DataTo(orderModel); err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall order: %v", err)
-	}
+	return r.unmarshallOrderDoc(doc)
+}
 
-	order := r.unmarshallOrder(orderModel)
+// orderCursors convert the page token values of the order sort fields.
+var orderCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"updated_at": timeCursor,
+}
 
-	order.Id = id
+func (r *OrderRepository) ListOrders(
+	ctx context.Context, filter *repository.OrderFilter, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+
+	r.CheckPreconditions()
 
-	orderItems, err := r.ListOrderItems(ctx, id)
+	page, err := repository.ParsePage(opts, repository.OrderSortFields...)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get order items: %v", err)
+		return nil, "", err
 	}
 
-	order.Items = orderItems
+	q := r.orderCollection().Query
+	if filter != nil {
+		if err := filter.Validate(); err != nil {
+			return nil, "", err
+		}
 
-	return order, nil
-}
+		if filter.CustomerId != "" {
+			q = q.Where("customer_id", "==", filter.CustomerId)
+		}
+		if filter.Status != "" {
+			q = q.Where("order_status", "==", string(filter.Status))
+		}
+		if !filter.CreatedFrom.IsZero() {
+			q = q.Where("created_at", ">=", filter.CreatedFrom.Local().Format(time.RFC3339))
+		}
+		if !filter.CreatedUntil.IsZero() {
+			q = q.Where("created_at", "<", filter.CreatedUntil.Local().Format(time.RFC3339))
+		}
+	}
 
-func (r *OrderRepository) ListOrders(ctx context.Context) ([]*repository.Order, error) {
-	r.CheckPreconditions()
+	q, err = pageQuery(q, page, orderCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
 
-	iter := r.orderCollection().Documents(ctx)
+	iter := q.Documents(ctx)
 
 	orders := make([]*repository.Order, 0)
 
@@ -116,97 +169,195 @@ func (r *OrderRepository) ListOrders(ctx context.Context) ([]*repository.Order,
 			break
 		}
 		if err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to iterate orders: %v", err)
+			return nil, "", service.Errorf(service.INTERNAL_ERROR, "failed to iterate orders: %v", err)
 		}
 
-		orderModel := &OrderModel{}
-		if err := doc.DataTo(orderModel); err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall order: %v", err)
+		order, err := r.unmarshallOrderDoc(doc)
+		if err != nil {
+			return nil, "", err
 		}
 
-		order := r.unmarshallOrder(orderModel)
+		orders = append(orders, order)
+	}
 
-		order.Id = doc.Ref.ID
+	orders, next := repository.TrimPage(page, orders,
+		func(o *repository.Order) string { return o.SortValue(page.Ordering.Field) },
+		func(o *repository.Order) string { return o.Id },
+	)
 
-		orderItems, err := r.ListOrderItems(ctx, order.Id)
-		if err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get order items: %v", err)
-		}
+	return orders, next, nil
+}
 
-		order.Items = orderItems
+func (r *OrderRepository) ListOrdersByCustomer(
+	ctx context.Context, customerId string, opts *repository.ListOptions) ([]*repository.Order, string, error) {
 
-		orders = append(orders, order)
+	filter, opts, err := repository.CustomerOrders(customerId, opts)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return orders, nil
+	return r.ListOrders(ctx, filter, opts)
 }
 
 func (r *OrderRepository) UpdateOrderStatus(
-	ctx context.Context, orderId string, status orderPkg.OrderStatus) (*repository.Order, error) {
+	ctx context.Context, orderId string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
 	r.CheckPreconditions()
 
-	order, err := r.GetOrder(ctx, orderId)
-	if err != nil {
-		return nil, err
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
 	}
 
-	order.OrderStatus = status
-	order.UpdatedAt = time.Now().Format(time.RFC3339)
+	return r.updateOrder(ctx, orderId, func(tx *firestore.Transaction, order *repository.Order) error {
+		change, err := order.ApplyStatusUpdate(update)
+		if err != nil {
+			return err
+		}
 
-	orderModel := r.marshallOrder(order)
-	_, err = r.orderCollection().Doc(orderId).Set(ctx, orderModel)
-	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update order status: %v", err)
-	}
+		order.UpdatedAt = time.Now().Format(time.RFC3339)
+		change.CreatedAt = order.UpdatedAt
+
+		historyRef := r.historyCollection(orderId).NewDoc()
+		change.Id = historyRef.ID
+
+		if err := tx.Create(historyRef, r.marshallOrderStatusChange(change)); err != nil {
+			return err
+		}
 
-	return r.GetOrder(ctx, orderId)
+		event, err := repository.NewOrderStatusEvent(order, change)
+		if err != nil {
+			return err
+		}
+
+		return createOutboxEvent(tx, r.db, event)
+	})
 }
 
 func (r *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
 	r.CheckPreconditions()
 
-	_, err := r.orderCollection().Doc(id).Delete(ctx)
-	if err != nil {
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	_, err := r.orderCollection().Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	} else if err != nil {
 		return service.Errorf(service.INTERNAL_ERROR, "failed to delete order: %v", err)
 	}
 
+	// Firestore does not delete subcollections together with their parent.
+	docRefs, err := r.historyCollection(id).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to list order status history: %v", err)
+	}
+
+	for _, docRef := range docRefs {
+		if _, err := docRef.Delete(ctx); err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to delete order status history: %v", err)
+		}
+	}
+
 	return nil
 }
 
-func (r *OrderRepository) orderItemCollection(orderId string) *firestore.CollectionRef {
+func (r *OrderRepository) GetOrderHistory(
+	ctx context.Context, orderId string) ([]*repository.OrderStatusChange, error) {
+
 	r.CheckPreconditions()
 
-	return r.orderCollection().Doc(orderId).Collection("items")
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	if _, err := r.GetOrder(ctx, orderId); err != nil {
+		return nil, err
+	}
+
+	docs, err := r.historyCollection(orderId).OrderBy("version", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to list order status history: %v", err)
+	}
+
+	history := make([]*repository.OrderStatusChange, 0, len(docs))
+	for _, doc := range docs {
+		changeModel := &OrderStatusChangeModel{}
+		if err := doc.DataTo(changeModel); err != nil {
+			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall order status change: %v", err)
+		}
+
+		change := r.unmarshallOrderStatusChange(changeModel)
+		change.Id = doc.Ref.ID
+		change.OrderId = orderId
+
+		history = append(history, change)
+	}
+
+	return history, nil
 }
 
-func (r *OrderRepository) CreateOrderItem(
-	ctx context.Context, orderId string, orderItem *repository.OrderItem) (*repository.OrderItem, error) {
+// updateOrder reads the order, applies fn and writes the result back in a
+// single transaction. Firestore retries the transaction if the order changes
+// in the meantime, so fn must not have side effects outside of the order and
+// writes it adds to tx.
+func (r *OrderRepository) updateOrder(
+	ctx context.Context, orderId string, fn func(tx *firestore.Transaction, order *repository.Order) error,
+) (*repository.Order, error) {
 
-	r.CheckPreconditions()
+	var order *repository.Order
 
-	// Set CreatedAt and UpdatedAt to the current time
-	currentTime := time.Now()
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docRef := r.orderCollection().Doc(orderId)
 
-	orderItem.CreatedAt = currentTime.Format(time.RFC3339)
-	orderItem.UpdatedAt = currentTime.Format(time.RFC3339)
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+		} else if err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to get order: %v", err)
+		}
 
-	err := orderItem.Validate()
-	if err != nil {
-		return nil, service.Errorf(service.INVALID_ERROR, "invalid order item details provided: %v", err)
+		order, err = r.unmarshallOrderDoc(doc)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(tx, order); err != nil {
+			return err
+		}
+
+		return tx.Set(docRef, r.marshallOrder(order))
+	})
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return nil, err
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update order: %v", err)
 	}
 
-	orderItemModel := r.marshallOrderItem(orderItem)
+	return order, nil
+}
+
+// newOrderItemID returns a new unique id for an order item. Items are embedded
+// in their order document, so the id is only unique within the collection's
+// id space and never refers to a document of its own.
+func (r *OrderRepository) newOrderItemID() string {
+	return r.orderCollection().NewDoc().ID
+}
+
+func (r *OrderRepository) CreateOrderItem(
+	ctx context.Context, orderId string, orderItem *repository.OrderItem) (*repository.OrderItem, error) {
+
+	r.CheckPreconditions()
 
-	docRef, _, err := r.orderItemCollection(orderId).Add(ctx, orderItemModel)
+	items, err := r.CreateOrderItems(ctx, orderId, []*repository.OrderItem{orderItem})
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to create order item: %v", err)
+		return nil, err
 	}
 
-	orderItem.Id = docRef.ID
-
-	return orderItem, nil
+	return items[0], nil
 }
 
+// CreateOrderItems appends the items to the order in a single transaction.
 func (r *OrderRepository) CreateOrderItems(
 	ctx context.Context, orderId string, orderItems []*repository.OrderItem) ([]*repository.OrderItem, error) {
 
@@ -216,13 +367,10 @@ func (r *OrderRepository) CreateOrderItems(
 		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
 	}
 
-	bulkWriter := r.db.client.BulkWriter(ctx)
-
+	// Set CreatedAt and UpdatedAt to the current time
 	currentTime := time.Now().Format(time.RFC3339)
-	var createdOrderItems []*repository.OrderItem
 
 	for _, orderItem := range orderItems {
-		// Set CreatedAt and UpdatedAt to the current time
 		orderItem.CreatedAt = currentTime
 		orderItem.UpdatedAt = currentTime
 
@@ -230,22 +378,25 @@ func (r *OrderRepository) CreateOrderItems(
 		if err != nil {
 			return nil, service.Errorf(service.INVALID_ERROR, "invalid order item details provided: %v", err)
 		}
+	}
 
-		orderItemModel := r.marshallOrderItem(orderItem)
-		docRef := r.orderItemCollection(orderId).NewDoc() // Create a new document reference.
-
-		orderItem.Id = docRef.ID
-		createdOrderItems = append(createdOrderItems, orderItem)
+	_, err := r.updateOrder(ctx, orderId, func(_ *firestore.Transaction, order *repository.Order) error {
+		if err := order.PrepareChange("items"); err != nil {
+			return err
+		}
 
-		_, err = bulkWriter.Create(docRef, orderItemModel)
-		if err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to create order item: %v", err)
+		for _, orderItem := range orderItems {
+			orderItem.Id = r.newOrderItemID()
+			order.Items = append(order.Items, orderItem)
 		}
-	}
 
-	bulkWriter.Flush()
+		return order.UpdateTotals()
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return createdOrderItems, nil
+	return orderItems, nil
 }
 
 func (r *OrderRepository) GetOrderItem(
@@ -259,23 +410,17 @@ func (r *OrderRepository) GetOrderItem(
 		return nil, service.Errorf(service.INVALID_ERROR, "order item id is required")
 	}
 
-	docRef, err := r.orderItemCollection(orderId).Doc(orderItemId).Get(ctx)
-	if status.Code(err) == codes.NotFound {
-		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
-	} else if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get order item: %v", err)
+	order, err := r.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
 	}
 
-	orderItemModel := &OrderItemModel{}
-	if err := docRef.DataTo(orderItemModel); err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall order item: %v", err)
+	i := findOrderItem(order, orderItemId)
+	if i < 0 {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
 	}
 
-	orderItem := r.unmarshallOrderItem(orderItemModel)
-
-	orderItem.Id = docRef.Ref.ID
-
-	return orderItem, nil
+	return order.Items[i], nil
 }
 
 func (r *OrderRepository) ListOrderItems(ctx context.Context, orderId string) ([]*repository.OrderItem, error) {
@@ -285,32 +430,12 @@ func (r *OrderRepository) ListOrderItems(ctx context.Context, orderId string) ([
 		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
 	}
 
-	iter := r.orderItemCollection(orderId).Documents(ctx)
-
-	orderItems := make([]*repository.OrderItem, 0)
-
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		orderItemModel := &OrderItemModel{}
-		if err := doc.DataTo(orderItemModel); err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall order item: %v", err)
-		}
-
-		orderItem := r.unmarshallOrderItem(orderItemModel)
-
-		orderItem.Id = doc.Ref.ID
-
-		orderItems = append(orderItems, orderItem)
+	order, err := r.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
 	}
 
-	return orderItems, nil
+	return order.Items, nil
 }
 
 func (r *OrderRepository) UpdateOrderItem(
@@ -324,25 +449,39 @@ func (r *OrderRepository) UpdateOrderItem(
 		return nil, service.Errorf(service.INVALID_ERROR, "order item id is required")
 	}
 
-	orderItem, err := r.GetOrderItem(ctx, orderId, orderItemId)
-	if err != nil {
-		return nil, err
-	}
+	var orderItem *repository.OrderItem
 
-	if v := update.Quantity; v != nil {
-		orderItem.Quantity = *v
-	}
+	_, err := r.updateOrder(ctx, orderId, func(_ *firestore.Transaction, order *repository.Order) error {
+		i := findOrderItem(order, orderItemId)
+		if i < 0 {
+			return service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+		}
+
+		if err := order.PrepareChange("items"); err != nil {
+			return err
+		}
+
+		orderItem = order.Items[i]
+
+		if v := update.Quantity; v != nil {
+			orderItem.Quantity = *v
+		}
 
-	// Set UpdatedAt to the current time
-	orderItem.UpdatedAt = time.Now().Format(time.RFC3339)
+		err := orderItem.Validate()
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid order item details provided: %v", err)
+		}
+
+		// Set UpdatedAt to the current time
+		orderItem.UpdatedAt = time.Now().Format(time.RFC3339)
 
-	orderItemModel := r.marshallOrderItem(orderItem)
-	_, err = r.orderItemCollection(orderId).Doc(orderItemId).Set(ctx, orderItemModel)
+		return order.UpdateTotals()
+	})
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update order item: %v", err)
+		return nil, err
 	}
 
-	return r.GetOrderItem(ctx, orderId, orderItemId)
+	return orderItem, nil
 }
 
 func (r *OrderRepository) DeleteOrderItem(ctx context.Context, orderId string, orderItemId string) error {
@@ -354,31 +493,158 @@ func (r *OrderRepository) DeleteOrderItem(ctx context.Context, orderId string, o
 		return service.Errorf(service.INVALID_ERROR, "order item id is required")
 	}
 
-	_, err := r.orderItemCollection(orderId).Doc(orderItemId).Delete(ctx)
-	if err != nil {
-		return service.Errorf(service.INTERNAL_ERROR, "failed to delete order item: %v", err)
+	_, err := r.updateOrder(ctx, orderId, func(_ *firestore.Transaction, order *repository.Order) error {
+		i := findOrderItem(order, orderItemId)
+		if i < 0 {
+			return service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+		}
+
+		if err := order.PrepareChange("items"); err != nil {
+			return err
+		}
+
+		order.Items = append(order.Items[:i], order.Items[i+1:]...)
+		return order.UpdateTotals()
+	})
+
+	return err
+}
+
+func (r *OrderRepository) ApplyOrderPromotion(
+	ctx context.Context, orderId string, promotion *repository.AppliedPromotion, replaces string) (*repository.Order, error) {
+
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
 	}
 
-	return nil
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+
+	return r.updateOrder(ctx, orderId, func(_ *firestore.Transaction, order *repository.Order) error {
+		if err := order.CheckReplacedPromotion(replaces); err != nil {
+			return err
+		}
+
+		if err := order.PrepareChange("promotion"); err != nil {
+			return err
+		}
+
+		order.Promotion = promotion
+
+		if err := order.UpdateTotals(); err != nil {
+			return err
+		}
+
+		order.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		return nil
+	})
+}
+
+// findOrderItem returns the index of the item in the order, or -1.
+func findOrderItem(order *repository.Order, orderItemId string) int {
+	for i, item := range order.Items {
+		if item.Id == orderItemId {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (r *OrderRepository) unmarshallOrderDoc(doc *firestore.DocumentSnapshot) (*repository.Order, error) {
+	orderModel := &OrderModel{}
+	if err := doc.DataTo(orderModel); err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall order: %v", err)
+	}
+
+	order := r.unmarshallOrder(orderModel)
+	order.Id = doc.Ref.ID
+
+	return order, nil
 }
 
 func (r *OrderRepository) marshallOrder(order *repository.Order) *OrderModel {
 	return &OrderModel{
-		CustomerId:  order.CustomerId,
-		Items:       r.marshallOrderItems(order.Items),
-		OrderStatus: string(order.OrderStatus),
-		CreatedAt:   order.CreatedAt,
-		UpdatedAt:   order.UpdatedAt,
+		CustomerId:       order.CustomerId,
+		Items:            r.marshallOrderItems(order.Items),
+		OrderStatus:      string(order.OrderStatus),
+		Version:          int(order.Version),
+		Currency:         order.Total.Currency,
+		Subtotal:         order.Subtotal.Amount,
+		Tax:              order.Tax.Amount,
+		Discount:         order.Discount.Amount,
+		Total:            order.Total.Amount,
+		Promotion:        marshallAppliedPromotion(order.Promotion),
+		StockReservation: order.StockReservation,
+		CreatedAt:        order.CreatedAt,
+		UpdatedAt:        order.UpdatedAt,
 	}
 }
 
 func (r *OrderRepository) unmarshallOrder(order *OrderModel) *repository.Order {
 	return &repository.Order{
-		CustomerId:  order.CustomerId,
-		Items:       r.unmarshallOrderItems(order.Items),
-		OrderStatus: orderPkg.OrderStatus(order.OrderStatus),
-		CreatedAt:   order.CreatedAt,
-		UpdatedAt:   order.UpdatedAt,
+		CustomerId:       order.CustomerId,
+		Items:            r.unmarshallOrderItems(order.Items),
+		OrderStatus:      orderPkg.OrderStatus(order.OrderStatus),
+		Version:          uint(order.Version),
+		Subtotal:         money.New(order.Subtotal, order.Currency),
+		Tax:              money.New(order.Tax, order.Currency),
+		Discount:         money.New(order.Discount, order.Currency),
+		Total:            money.New(order.Total, order.Currency),
+		Promotion:        unmarshallAppliedPromotion(order.Promotion),
+		StockReservation: order.StockReservation,
+		CreatedAt:        order.CreatedAt,
+		UpdatedAt:        order.UpdatedAt,
+	}
+}
+
+func marshallAppliedPromotion(promotion *repository.AppliedPromotion) *PromotionRuleModel {
+	if promotion == nil {
+		return nil
+	}
+
+	rule := marshallPromotionRule(&promotion.Rule)
+	rule.PromotionId = promotion.PromotionId
+	rule.Code = promotion.Code
+
+	return &rule
+}
+
+func unmarshallAppliedPromotion(rule *PromotionRuleModel) *repository.AppliedPromotion {
+	if rule == nil {
+		return nil
+	}
+
+	return &repository.AppliedPromotion{
+		PromotionId: rule.PromotionId,
+		Code:        rule.Code,
+		Rule:        unmarshallPromotionRule(rule),
+	}
+}
+
+func (r *OrderRepository) marshallOrderStatusChange(change *repository.OrderStatusChange) *OrderStatusChangeModel {
+	return &OrderStatusChangeModel{
+		FromStatus: string(change.FromStatus),
+		ToStatus:   string(change.ToStatus),
+		Actor:      change.Actor,
+		Reason:     change.Reason,
+		Version:    int(change.Version),
+		CreatedAt:  change.CreatedAt,
+	}
+}
+
+func (r *OrderRepository) unmarshallOrderStatusChange(change *OrderStatusChangeModel) *repository.OrderStatusChange {
+	return &repository.OrderStatusChange{
+		FromStatus: orderPkg.OrderStatus(change.FromStatus),
+		ToStatus:   orderPkg.OrderStatus(change.ToStatus),
+		Actor:      change.Actor,
+		Reason:     change.Reason,
+		Version:    uint(change.Version),
+		CreatedAt:  change.CreatedAt,
 	}
 }
 
@@ -404,8 +670,19 @@ func (r *OrderRepository) unmarshallOrderItems(items []*OrderItemModel) []*repos
 
 func (r *OrderRepository) marshallOrderItem(item *repository.OrderItem) *OrderItemModel {
 	return &OrderItemModel{
+		Id:        item.Id,
 		ProductId: item.ProductId,
 		Quantity:  int(item.Quantity),
+		Currency:  item.UnitPrice.Currency,
+		UnitPrice: item.UnitPrice.Amount,
+		LineTotal: item.LineTotal.Amount,
+
+		TaxCategory:  item.TaxRate.Category,
+		TaxRate:      int(item.TaxRate.Rate),
+		TaxExempt:    item.TaxRate.Exempt,
+		TaxInclusive: item.TaxRate.Inclusive,
+		Tax:          item.Tax.Amount,
+
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
 	}
@@ -413,8 +690,18 @@ func (r *OrderRepository) marshallOrderItem(item *repository.OrderItem) *OrderIt
 
 func (r *OrderRepository) unmarshallOrderItem(item *OrderItemModel) *repository.OrderItem {
 	return &repository.OrderItem{
+		Id:        item.Id,
 		ProductId: item.ProductId,
 		Quantity:  uint(item.Quantity),
+		UnitPrice: money.New(item.UnitPrice, item.Currency),
+		LineTotal: money.New(item.LineTotal, item.Currency),
+		TaxRate: repository.TaxRate{
+			Category:  item.TaxCategory,
+			Rate:      uint(item.TaxRate),
+			Exempt:    item.TaxExempt,
+			Inclusive: item.TaxInclusive,
+		},
+		Tax:       money.New(item.Tax, item.Currency),
 		CreatedAt: item.CreatedAt,
 		UpdatedAt: item.UpdatedAt,
 	}
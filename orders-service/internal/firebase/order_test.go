@@ -9,12 +9,15 @@ import (
 
 	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 	"github.com/Mik3y-F/order-management-system/orders/pkg"
 )
 
 func deleteTestOrder(t *testing.T, ctx context.Context, orderRepository repository.OrderRepository, id string) {
 	err := orderRepository.DeleteOrder(ctx, id)
-	if err != nil {
+	// Tests that delete the record themselves leave nothing to clean up.
+	if err != nil && service.ErrorCode(err) != service.NOT_FOUND_ERROR {
 		t.Fatalf("failed to delete order: %v", err)
 	}
 }
@@ -351,7 +354,7 @@ func TestOrderService_ListOrders(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := orderRepository.ListOrders(tt.args.ctx)
+			got, _, err := orderRepository.ListOrders(tt.args.ctx, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("OrderRepository.ListOrders() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1084,12 +1087,13 @@ func TestOrderService_UpdateOrderStatus(t *testing.T) {
 			args: args{
 				ctx:     ctx,
 				orderId: testOrder.Id,
-				status:  pkg.OrderStatusPaid,
+				status:  pkg.OrderStatusProcessing,
 			},
 			want: &repository.Order{
 				Id:          testOrder.Id,
 				CustomerId:  "customer-1",
-				OrderStatus: pkg.OrderStatusPaid,
+				OrderStatus: pkg.OrderStatusProcessing,
+				Version:     2,
 				Items: []*repository.OrderItem{
 					{
 						Id:        testOrder.Items[0].Id,
@@ -1107,7 +1111,9 @@ func TestOrderService_UpdateOrderStatus(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
-			got, err := orderRepository.UpdateOrderStatus(tt.args.ctx, tt.args.orderId, tt.args.status)
+			got, err := orderRepository.UpdateOrderStatus(tt.args.ctx, tt.args.orderId, &repository.OrderStatusUpdate{
+				Status: tt.args.status,
+			})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("OrderRepository.UpdateOrderStatus() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1153,3 +1159,9 @@ func TestOrderService_UpdateOrderStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestOrderRepository(t *testing.T) {
+	repositorytest.TestOrderRepository(t, func(t *testing.T) repository.OrderRepository {
+		return db.NewOrderRepository(MustOpenFirestore(t))
+	})
+}
@@ -0,0 +1,91 @@
+package firebase
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+var _ repository.OutboxRepository = (*OutboxRepository)(nil)
+
+type OutboxRepository struct {
+	db *FirestoreService
+}
+
+func NewOutboxRepository(db *FirestoreService) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+func (r *OutboxRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func outboxCollection(db *FirestoreService) *firestore.CollectionRef {
+	return db.client.Collection("outbox_events")
+}
+
+func (r *OutboxRepository) ListOutboxEvents(ctx context.Context, limit int) ([]*outbox.Event, error) {
+	r.CheckPreconditions()
+
+	docs, err := outboxCollection(r.db).OrderBy("created_at", firestore.Asc).Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to list outbox events: %v", err)
+	}
+
+	events := make([]*outbox.Event, 0, len(docs))
+	for _, doc := range docs {
+		var model OutboxEventModel
+		if err := doc.DataTo(&model); err != nil {
+			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall outbox event: %v", err)
+		}
+
+		events = append(events, &outbox.Event{
+			Id:            doc.Ref.ID,
+			Type:          model.Type,
+			AggregateType: model.AggregateType,
+			AggregateId:   model.AggregateId,
+			Payload:       []byte(model.Payload),
+			CreatedAt:     model.CreatedAt.Local().Format(time.RFC3339),
+		})
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	r.CheckPreconditions()
+
+	for _, id := range ids {
+		if _, err := outboxCollection(r.db).Doc(id).Delete(ctx); err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to delete outbox event: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// createOutboxEvent records the event in the transaction of the change that
+// caused it.
+func createOutboxEvent(tx *firestore.Transaction, db *FirestoreService, event *outbox.Event) error {
+	t := time.Now()
+
+	docRef := outboxCollection(db).NewDoc()
+	event.Id = docRef.ID
+	event.CreatedAt = t.Format(time.RFC3339)
+
+	return tx.Create(docRef, &OutboxEventModel{
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateId:   event.AggregateId,
+		Payload:       string(event.Payload),
+		CreatedAt:     t,
+	})
+}
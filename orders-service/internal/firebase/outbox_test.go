@@ -0,0 +1,26 @@
+package firebase_test
+
+import (
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestOutboxRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("OutboxRepository.CheckPreconditions() did not panic without a DB")
+		}
+	}()
+
+	db.NewOutboxRepository(nil).CheckPreconditions()
+}
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.OrderRepository, repository.OutboxRepository) {
+		firestore := MustOpenFirestore(t)
+		return db.NewOrderRepository(firestore), db.NewOutboxRepository(firestore)
+	})
+}
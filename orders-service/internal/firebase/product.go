@@ -2,11 +2,15 @@ package firebase
 
 import (
 	"context"
+	"errors"
+	"net/url"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
 	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -36,6 +40,12 @@ func (r *ProductRepository) productCollection() *firestore.CollectionRef {
 	return r.db.client.Collection("products")
 }
 
+func (r *ProductRepository) reservationDoc(id string) *firestore.DocumentRef {
+	r.CheckPreconditions()
+
+	return r.db.client.Collection("stock_reservations").Doc(url.PathEscape(id))
+}
+
 func (r *ProductRepository) CreateProduct(ctx context.Context, product *repository.Product) (*repository.Product, error) {
 	r.CheckPreconditions()
 
@@ -50,6 +60,7 @@ func (r *ProductRepository) CreateProduct(ctx context.Context, product *reposito
 		return nil, service.Errorf(service.INVALID_ERROR, "invalid product provided: %v", err)
 	}
 
+	product.Reserved = 0
 	productModel := r.marshallProduct(product)
 
 	docRef, _, writeErr := r.productCollection().Add(ctx, productModel)
@@ -88,12 +99,51 @@ func (r *ProductRepository) GetProduct(ctx context.Context, id string) (*reposit
 	return product, nil
 }
 
-func (r *ProductRepository) ListProducts(ctx context.Context) ([]*repository.Product, error) {
+// productCursors convert the page token values of the product sort fields.
+var productCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"name":       textCursor,
+	"price":      uintCursor,
+}
+
+func (r *ProductRepository) ListProducts(
+	ctx context.Context, filter *repository.ProductFilter, opts *repository.ListOptions) ([]*repository.Product, string, error) {
+
 	r.CheckPreconditions()
 
-	iter := r.productCollection().Documents(ctx)
+	page, err := repository.ParsePage(opts, repository.ProductSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	q := r.productCollection().Query
+	if filter != nil {
+		if err := filter.Validate(); err != nil {
+			return nil, "", err
+		}
+
+		if filter.NamePrefix != "" {
+			q = q.Where("name", ">=", filter.NamePrefix).Where("name", "<", filter.NamePrefix+"\uf8ff")
+		}
+		if filter.Currency != "" {
+			q = q.Where("currency", "==", filter.Currency)
+		}
+		if filter.MinPrice != nil {
+			q = q.Where("price", ">=", *filter.MinPrice)
+		}
+		if filter.MaxPrice != nil {
+			q = q.Where("price", "<=", *filter.MaxPrice)
+		}
+	}
+
+	q, err = pageQuery(q, page, productCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	iter := q.Documents(ctx)
 
-	var products []*repository.Product
+	products := make([]*repository.Product, 0)
 
 	for {
 		docRef, err := iter.Next()
@@ -101,12 +151,12 @@ func (r *ProductRepository) ListProducts(ctx context.Context) ([]*repository.Pro
 			break
 		}
 		if err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to iterate products: %v", err)
+			return nil, "", service.Errorf(service.INTERNAL_ERROR, "failed to iterate products: %v", err)
 		}
 
 		productModel := &ProductModel{}
 		if err := docRef.DataTo(productModel); err != nil {
-			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall product: %v", err)
+			return nil, "", service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall product: %v", err)
 		}
 
 		product := r.unmarshallProduct(productModel)
@@ -115,64 +165,285 @@ func (r *ProductRepository) ListProducts(ctx context.Context) ([]*repository.Pro
 		products = append(products, product)
 	}
 
-	return products, nil
+	products, next := repository.TrimPage(page, products,
+		func(p *repository.Product) string { return p.SortValue(page.Ordering.Field) },
+		func(p *repository.Product) string { return p.Id },
+	)
+
+	return products, next, nil
 }
 
 func (r *ProductRepository) UpdateProduct(ctx context.Context, id string, update *repository.ProductUpdate,
 ) (*repository.Product, error) {
 	r.CheckPreconditions()
 
-	product, getErr := r.GetProduct(ctx, id)
-	if getErr != nil {
-		return nil, getErr
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
 	}
 
-	if p := update.Name; p != nil {
-		product.Name = *p
-	}
+	var product *repository.Product
+
+	// The update runs in a transaction so that it cannot overwrite a
+	// concurrent stock adjustment.
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docRef := r.productCollection().Doc(id)
+
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "product not found")
+		} else if err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to get product: %v", err)
+		}
+
+		product, err = r.unmarshallProductDoc(doc)
+		if err != nil {
+			return err
+		}
+
+		if p := update.Name; p != nil {
+			product.Name = *p
+		}
+
+		if p := update.Description; p != nil {
+			product.Description = *p
+		}
 
-	if p := update.Description; p != nil {
-		product.Description = *p
+		if p := update.Price; p != nil {
+			product.Price = *p
+		}
+
+		if p := update.Category; p != nil {
+			product.Category = *p
+		}
+
+		if p := update.Stock; p != nil {
+			product.Stock = pkg.UintPtr(*p)
+		}
+
+		err = product.Validate()
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid product details provided: %v", err)
+		}
+
+		product.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		return tx.Set(docRef, r.marshallProduct(product))
+	})
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return nil, err
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update product: %v", err)
 	}
 
-	if p := update.Price; p != nil {
-		product.Price = *p
+	return product, nil
+}
+
+func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
 	}
 
-	err := product.Validate()
-	if err != nil {
-		return nil, service.Errorf(service.INVALID_ERROR, "invalid product details provided: %v", err)
+	_, err := r.productCollection().Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return service.Errorf(service.NOT_FOUND_ERROR, "product not found")
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to delete product: %v", err)
 	}
 
-	timeNow := time.Now()
-	product.UpdatedAt = timeNow.Format(time.RFC3339)
+	return nil
+}
 
-	productModel := r.marshallProduct(product)
+func (r *ProductRepository) ReserveStock(ctx context.Context, reservationId string, items []repository.StockItem) error {
+	r.CheckPreconditions()
+
+	if reservationId == "" {
+		return service.Errorf(service.INVALID_ERROR, "reservation id is required")
+	}
 
-	_, err = r.productCollection().Doc(id).Set(ctx, productModel)
+	items, err := repository.MergeStockItems(items)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update product: %v", err)
+		return err
 	}
 
-	return product, nil
+	return r.adjustStock(ctx, reservationId, func(tx *firestore.Transaction, replaced []repository.StockItem) error {
+		products, err := r.getProducts(tx, repository.StockProductIds(replaced, items))
+		if err != nil {
+			return err
+		}
+
+		reserved, err := repository.ReplaceStockReservation(products, replaced, items)
+		if err != nil {
+			return err
+		}
+
+		// Firestore transactions must do all their reads before any writes.
+		if err := r.setProducts(tx, products); err != nil {
+			return err
+		}
+
+		return tx.Set(r.reservationDoc(reservationId), marshallStockReservation(reserved))
+	})
 }
 
-func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
+func (r *ProductRepository) ReleaseStock(ctx context.Context, reservationId string) error {
+	return r.settleStock(ctx, reservationId, (*repository.Product).Release)
+}
+
+func (r *ProductRepository) CommitStock(ctx context.Context, reservationId string) error {
+	return r.settleStock(ctx, reservationId, (*repository.Product).Commit)
+}
+
+// settleStock applies adjust to the products of the reservation and deletes
+// it in one transaction.
+func (r *ProductRepository) settleStock(
+	ctx context.Context, reservationId string, adjust repository.StockAdjustment) error {
+
 	r.CheckPreconditions()
 
-	_, err := r.productCollection().Doc(id).Delete(ctx)
+	if reservationId == "" {
+		return service.Errorf(service.INVALID_ERROR, "reservation id is required")
+	}
+
+	return r.adjustStock(ctx, reservationId, func(tx *firestore.Transaction, items []repository.StockItem) error {
+		products, err := r.getProducts(tx, repository.StockProductIds(items))
+		if err != nil {
+			return err
+		}
+
+		if err := repository.SettleStockReservation(products, items, adjust); err != nil {
+			return err
+		}
+
+		if err := r.setProducts(tx, products); err != nil {
+			return err
+		}
+
+		return tx.Delete(r.reservationDoc(reservationId))
+	})
+}
+
+// adjustStock reads the reservation in a transaction and runs fn with its
+// items, none if it does not exist, so that the adjustments of the products
+// and the reservation are all or nothing.
+func (r *ProductRepository) adjustStock(ctx context.Context, reservationId string,
+	fn func(tx *firestore.Transaction, items []repository.StockItem) error) error {
+
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(r.reservationDoc(reservationId))
+		if err != nil && status.Code(err) != codes.NotFound {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to get stock reservation: %v", err)
+		}
+
+		var items []repository.StockItem
+		if doc != nil && doc.Exists() {
+			reservation := &StockReservationModel{}
+			if err := doc.DataTo(reservation); err != nil {
+				return service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall stock reservation: %v", err)
+			}
+
+			items = unmarshallStockReservation(reservation)
+		}
+
+		return fn(tx, items)
+	})
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return err
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to adjust product stock: %v", err)
+	}
+
+	return nil
+}
+
+// getProducts reads the products with the given ids that exist in the
+// transaction.
+func (r *ProductRepository) getProducts(
+	tx *firestore.Transaction, ids []string) (map[string]*repository.Product, error) {
+
+	docRefs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		docRefs[i] = r.productCollection().Doc(id)
+	}
+
+	docs, err := tx.GetAll(docRefs)
 	if err != nil {
-		return service.Errorf(service.INTERNAL_ERROR, "failed to delete product: %v", err)
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get products: %v", err)
 	}
 
-	return err
+	products := make(map[string]*repository.Product, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+
+		product, err := r.unmarshallProductDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		products[product.Id] = product
+	}
+
+	return products, nil
+}
+
+// setProducts writes the products back after a stock adjustment.
+func (r *ProductRepository) setProducts(tx *firestore.Transaction, products map[string]*repository.Product) error {
+	updatedAt := time.Now().Format(time.RFC3339)
+
+	for _, product := range products {
+		product.UpdatedAt = updatedAt
+		if err := tx.Set(r.productCollection().Doc(product.Id), r.marshallProduct(product)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func marshallStockReservation(items []repository.StockItem) *StockReservationModel {
+	reservation := &StockReservationModel{Items: make([]*StockItemModel, len(items))}
+	for i, item := range items {
+		reservation.Items[i] = &StockItemModel{ProductId: item.ProductId, Quantity: int(item.Quantity)}
+	}
+
+	return reservation
+}
+
+func unmarshallStockReservation(reservation *StockReservationModel) []repository.StockItem {
+	items := make([]repository.StockItem, len(reservation.Items))
+	for i, item := range reservation.Items {
+		items[i] = repository.StockItem{ProductId: item.ProductId, Quantity: uint(item.Quantity)}
+	}
+
+	return items
+}
+
+func (r *ProductRepository) unmarshallProductDoc(doc *firestore.DocumentSnapshot) (*repository.Product, error) {
+	productModel := &ProductModel{}
+	if err := doc.DataTo(productModel); err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall product: %v", err)
+	}
+
+	product := r.unmarshallProduct(productModel)
+	product.Id = doc.Ref.ID
+
+	return product, nil
 }
 
 func (r *ProductRepository) marshallProduct(product *repository.Product) *ProductModel {
 	return &ProductModel{
 		Name:        product.Name,
 		Description: product.Description,
-		Price:       int(product.Price),
+		Price:       product.Price.Amount,
+		Currency:    product.Price.Currency,
+		Category:    product.Category,
+		Stock:       marshallStock(product.Stock),
+		Reserved:    int(product.Reserved),
 		CreatedAt:   product.CreatedAt,
 		UpdatedAt:   product.UpdatedAt,
 	}
@@ -182,8 +453,30 @@ func (r *ProductRepository) unmarshallProduct(productModel *ProductModel) *repos
 	return &repository.Product{
 		Name:        productModel.Name,
 		Description: productModel.Description,
-		Price:       uint(productModel.Price),
+		Price:       money.New(productModel.Price, productModel.Currency),
+		Category:    productModel.Category,
+		Stock:       unmarshallStock(productModel.Stock),
+		Reserved:    uint(productModel.Reserved),
 		CreatedAt:   productModel.CreatedAt,
 		UpdatedAt:   productModel.UpdatedAt,
 	}
 }
+
+// marshallStock and unmarshallStock convert the stock of a product, nil for
+// products whose stock is not tracked.
+func marshallStock(stock *uint) *int {
+	if stock == nil {
+		return nil
+	}
+
+	v := int(*stock)
+	return &v
+}
+
+func unmarshallStock(stock *int) *uint {
+	if stock == nil {
+		return nil
+	}
+
+	return pkg.UintPtr(uint(*stock))
+}
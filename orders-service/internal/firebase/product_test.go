@@ -8,12 +8,16 @@ import (
 
 	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 )
 
 func deleteTestProduct(t *testing.T, ctx context.Context, productRepository repository.ProductRepository, id string) {
 	err := productRepository.DeleteProduct(ctx, id)
-	if err != nil {
+	// Tests that delete the record themselves leave nothing to clean up.
+	if err != nil && service.ErrorCode(err) != service.NOT_FOUND_ERROR {
 		t.Fatalf("failed to delete product: %v", err)
 	}
 }
@@ -80,13 +84,13 @@ func TestProductRepository_CreateProduct(t *testing.T) {
 				product: &repository.Product{
 					Name:        "Test Product",
 					Description: "Test Description",
-					Price:       100,
+					Price:       money.New(100, "KES"),
 				},
 			},
 			want: &repository.Product{
 				Name:        "Test Product",
 				Description: "Test Description",
-				Price:       100,
+				Price:       money.New(100, "KES"),
 				CreatedAt:   time.Now().Format(time.RFC3339),
 				UpdatedAt:   time.Now().Format(time.RFC3339),
 			},
@@ -99,7 +103,7 @@ func TestProductRepository_CreateProduct(t *testing.T) {
 				product: &repository.Product{
 					Name:        "",
 					Description: "Test Description",
-					Price:       100,
+					Price:       money.New(100, "KES"),
 				},
 			},
 			want:    nil,
@@ -151,7 +155,7 @@ func TestProductRepository_GetProduct(t *testing.T) {
 	p, err := productRepository.CreateProduct(ctx, &repository.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100,
+		Price:       money.New(100, "KES"),
 	})
 	if err != nil {
 		t.Fatalf("failed to create product: %v", err)
@@ -219,7 +223,7 @@ func TestProductRepository_ListProducts(t *testing.T) {
 	p, err := productRepository.CreateProduct(ctx, &repository.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100,
+		Price:       money.New(100, "KES"),
 	})
 	if err != nil {
 		t.Fatalf("failed to create product: %v", err)
@@ -248,7 +252,7 @@ func TestProductRepository_ListProducts(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := productRepository.ListProducts(tt.args.ctx)
+			got, _, err := productRepository.ListProducts(tt.args.ctx, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ProductRepository.ListProducts() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -277,7 +281,7 @@ func TestProductRepository_UpdateProduct(t *testing.T) {
 	p, err := productRepository.CreateProduct(ctx, &repository.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100,
+		Price:       money.New(100, "KES"),
 	})
 	if err != nil {
 		t.Fatalf("failed to create product: %v", err)
@@ -304,14 +308,14 @@ func TestProductRepository_UpdateProduct(t *testing.T) {
 				update: &repository.ProductUpdate{
 					Name:        pkg.StringPtr("Updated Test Product"),
 					Description: pkg.StringPtr("Updated Test Description"),
-					Price:       pkg.UintPtr(200),
+					Price:       &money.Money{Amount: 200, Currency: "KES"},
 				},
 			},
 			want: &repository.Product{
 				Id:          p.Id,
 				Name:        "Updated Test Product",
 				Description: "Updated Test Description",
-				Price:       200,
+				Price:       money.New(200, "KES"),
 				CreatedAt:   p.CreatedAt,
 				UpdatedAt:   time.Now().Format(time.RFC3339),
 			},
@@ -325,7 +329,7 @@ func TestProductRepository_UpdateProduct(t *testing.T) {
 				update: &repository.ProductUpdate{
 					Name:        pkg.StringPtr(""),
 					Description: pkg.StringPtr("Updated Test Description"),
-					Price:       pkg.UintPtr(200),
+					Price:       &money.Money{Amount: 200, Currency: "KES"},
 				},
 			},
 			want:    nil,
@@ -363,7 +367,7 @@ func TestProductRepository_DeleteProduct(t *testing.T) {
 	p, err := productRepository.CreateProduct(ctx, &repository.Product{
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100,
+		Price:       money.New(100, "KES"),
 	})
 	if err != nil {
 		t.Fatalf("failed to create product: %v", err)
@@ -404,3 +408,9 @@ func TestProductRepository_DeleteProduct(t *testing.T) {
 		})
 	}
 }
+
+func TestProductRepository(t *testing.T) {
+	repositorytest.TestProductRepository(t, func(t *testing.T) repository.ProductRepository {
+		return db.NewProductService(MustOpenFirestore(t))
+	})
+}
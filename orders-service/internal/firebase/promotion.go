@@ -0,0 +1,435 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ repository.PromotionRepository = (*PromotionRepository)(nil)
+
+type PromotionRepository struct {
+	db *FirestoreService
+}
+
+func NewPromotionRepository(db *FirestoreService) *PromotionRepository {
+	return &PromotionRepository{
+		db: db,
+	}
+}
+
+func (r *PromotionRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *PromotionRepository) promotionCollection() *firestore.CollectionRef {
+	r.CheckPreconditions()
+
+	return r.db.client.Collection("promotions")
+}
+
+func (r *PromotionRepository) redemptionCollection(promotionId string) *firestore.CollectionRef {
+	return r.promotionCollection().Doc(promotionId).Collection("redemptions")
+}
+
+// CreatePromotion checks that the code is not taken in the same transaction
+// that stores the promotion, as Firestore has no unique constraints.
+func (r *PromotionRepository) CreatePromotion(
+	ctx context.Context, promotion *repository.Promotion) (*repository.Promotion, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := time.Now().Format(time.RFC3339)
+	promotion.CreatedAt = currentTime
+	promotion.UpdatedAt = currentTime
+	promotion.Redemptions = 0
+
+	err := promotion.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid promotion provided: %v", err)
+	}
+
+	docRef := r.promotionCollection().NewDoc()
+
+	err = r.runTransaction(ctx, func(tx *firestore.Transaction) error {
+		docs, err := tx.Documents(r.promotionCollection().Where("code", "==", promotion.Code).Limit(1)).GetAll()
+		if err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to get promotion: %v", err)
+		} else if len(docs) > 0 {
+			return service.Errorf(service.ALREADY_EXISTS_ERROR, "promotion already exists")
+		}
+
+		return tx.Create(docRef, r.marshallPromotion(promotion))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	promotion.Id = docRef.ID
+
+	return promotion, nil
+}
+
+func (r *PromotionRepository) GetPromotion(ctx context.Context, id string) (*repository.Promotion, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	doc, err := r.promotionCollection().Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get promotion: %v", err)
+	}
+
+	return r.unmarshallPromotionDoc(doc)
+}
+
+func (r *PromotionRepository) GetPromotionByCode(ctx context.Context, code string) (*repository.Promotion, error) {
+	r.CheckPreconditions()
+
+	if code == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "code is required")
+	}
+
+	docs, err := r.promotionCollection().Where("code", "==", code).Limit(1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get promotion: %v", err)
+	} else if len(docs) == 0 {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	}
+
+	return r.unmarshallPromotionDoc(docs[0])
+}
+
+// promotionCursors convert the page token values of the promotion sort fields.
+var promotionCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"code":       textCursor,
+}
+
+func (r *PromotionRepository) ListPromotions(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Promotion, string, error) {
+
+	r.CheckPreconditions()
+
+	page, err := repository.ParsePage(opts, repository.PromotionSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	q, err := pageQuery(r.promotionCollection().Query, page, promotionCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	iter := q.Documents(ctx)
+
+	promotions := make([]*repository.Promotion, 0)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, "", service.Errorf(service.INTERNAL_ERROR, "failed to iterate promotions: %v", err)
+		}
+
+		promotion, err := r.unmarshallPromotionDoc(doc)
+		if err != nil {
+			return nil, "", err
+		}
+
+		promotions = append(promotions, promotion)
+	}
+
+	promotions, next := repository.TrimPage(page, promotions,
+		func(p *repository.Promotion) string { return p.SortValue(page.Ordering.Field) },
+		func(p *repository.Promotion) string { return p.Id },
+	)
+
+	return promotions, next, nil
+}
+
+// UpdatePromotion runs in a transaction, so that it does not overwrite the
+// redemption count of concurrent redemptions.
+func (r *PromotionRepository) UpdatePromotion(
+	ctx context.Context, id string, update *repository.PromotionUpdate) (*repository.Promotion, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	var promotion *repository.Promotion
+
+	err := r.runTransaction(ctx, func(tx *firestore.Transaction) (err error) {
+		docRef := r.promotionCollection().Doc(id)
+		if promotion, err = r.getPromotion(tx, docRef); err != nil {
+			return err
+		}
+
+		if v := update.Description; v != nil {
+			promotion.Description = *v
+		}
+
+		if v := update.StartsAt; v != nil {
+			promotion.StartsAt = *v
+		}
+
+		if v := update.EndsAt; v != nil {
+			promotion.EndsAt = *v
+		}
+
+		if v := update.UsageLimit; v != nil {
+			promotion.UsageLimit = *v
+		}
+
+		if v := update.PerCustomerLimit; v != nil {
+			promotion.PerCustomerLimit = *v
+		}
+
+		promotion.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		err = promotion.Validate()
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid promotion details provided: %v", err)
+		}
+
+		return tx.Set(docRef, r.marshallPromotion(promotion))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func (r *PromotionRepository) DeletePromotion(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	_, err := r.promotionCollection().Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to delete promotion: %v", err)
+	}
+
+	// Firestore does not delete subcollections together with their parent.
+	docRefs, err := r.redemptionCollection(id).DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to list promotion redemptions: %v", err)
+	}
+
+	for _, docRef := range docRefs {
+		if _, err := docRef.Delete(ctx); err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to delete promotion redemptions: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// RedeemPromotion counts the redemptions of the customer and stores the new
+// one in a single transaction, which Firestore retries if the promotion
+// changes in the meantime.
+func (r *PromotionRepository) RedeemPromotion(ctx context.Context, redemption *repository.PromotionRedemption) error {
+	r.CheckPreconditions()
+
+	if err := redemption.Validate(); err != nil {
+		return err
+	}
+
+	return r.runTransaction(ctx, func(tx *firestore.Transaction) error {
+		docRef := r.promotionCollection().Doc(redemption.PromotionId)
+		promotion, err := r.getPromotion(tx, docRef)
+		if err != nil {
+			return err
+		}
+
+		redemptionRef := r.redemptionCollection(redemption.PromotionId).Doc(redemption.OrderId)
+		if _, err := tx.Get(redemptionRef); err == nil {
+			return nil
+		} else if status.Code(err) != codes.NotFound {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to get promotion redemption: %v", err)
+		}
+
+		customerDocs, err := tx.Documents(
+			r.redemptionCollection(redemption.PromotionId).Where("customer_id", "==", redemption.CustomerId),
+		).GetAll()
+		if err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to list promotion redemptions: %v", err)
+		}
+
+		if err := promotion.CheckRedemption(uint(len(customerDocs))); err != nil {
+			return err
+		}
+
+		redemption.CreatedAt = time.Now().Format(time.RFC3339)
+
+		err = tx.Create(redemptionRef, &PromotionRedemptionModel{
+			CustomerId: redemption.CustomerId,
+			CreatedAt:  redemption.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.Update(docRef, []firestore.Update{{Path: "redemptions", Value: firestore.Increment(1)}})
+	})
+}
+
+func (r *PromotionRepository) ReleasePromotion(ctx context.Context, promotionId string, orderId string) error {
+	r.CheckPreconditions()
+
+	if promotionId == "" {
+		return service.Errorf(service.INVALID_ERROR, "promotion id is required")
+	} else if orderId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	_, err := r.releasePromotion(ctx, promotionId, orderId)
+	return err
+}
+
+// releasePromotion removes the redemption of the promotion by the order and
+// reports whether there was one.
+func (r *PromotionRepository) releasePromotion(ctx context.Context, promotionId string, orderId string) (bool, error) {
+	released := false
+
+	err := r.runTransaction(ctx, func(tx *firestore.Transaction) error {
+		released = false
+
+		redemptionRef := r.redemptionCollection(promotionId).Doc(orderId)
+		if _, err := tx.Get(redemptionRef); status.Code(err) == codes.NotFound {
+			return nil
+		} else if err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to get promotion redemption: %v", err)
+		}
+
+		released = true
+
+		if err := tx.Delete(redemptionRef); err != nil {
+			return err
+		}
+
+		return tx.Update(r.promotionCollection().Doc(promotionId),
+			[]firestore.Update{{Path: "redemptions", Value: firestore.Increment(-1)}})
+	})
+
+	return released, err
+}
+
+// runTransaction runs fn in a transaction, passing application errors through
+// and wrapping any other failure.
+func (r *PromotionRepository) runTransaction(ctx context.Context, fn func(tx *firestore.Transaction) error) error {
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(tx)
+	})
+
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return err
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to update promotion: %v", err)
+	}
+
+	return nil
+}
+
+// getPromotion reads a promotion as part of the transaction.
+func (r *PromotionRepository) getPromotion(
+	tx *firestore.Transaction, docRef *firestore.DocumentRef) (*repository.Promotion, error) {
+
+	doc, err := tx.Get(docRef)
+	if status.Code(err) == codes.NotFound {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get promotion: %v", err)
+	}
+
+	return r.unmarshallPromotionDoc(doc)
+}
+
+func (r *PromotionRepository) unmarshallPromotionDoc(doc *firestore.DocumentSnapshot) (*repository.Promotion, error) {
+	promotionModel := &PromotionModel{}
+	if err := doc.DataTo(promotionModel); err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall promotion: %v", err)
+	}
+
+	promotion := r.unmarshallPromotion(promotionModel)
+	promotion.Id = doc.Ref.ID
+
+	return promotion, nil
+}
+
+func (r *PromotionRepository) marshallPromotion(promotion *repository.Promotion) *PromotionModel {
+	return &PromotionModel{
+		Code:             promotion.Code,
+		Description:      promotion.Description,
+		Rule:             marshallPromotionRule(&promotion.Rule),
+		StartsAt:         promotion.StartsAt,
+		EndsAt:           promotion.EndsAt,
+		UsageLimit:       int(promotion.UsageLimit),
+		PerCustomerLimit: int(promotion.PerCustomerLimit),
+		Redemptions:      int(promotion.Redemptions),
+		CreatedAt:        promotion.CreatedAt,
+		UpdatedAt:        promotion.UpdatedAt,
+	}
+}
+
+func (r *PromotionRepository) unmarshallPromotion(promotion *PromotionModel) *repository.Promotion {
+	return &repository.Promotion{
+		Code:             promotion.Code,
+		Description:      promotion.Description,
+		Rule:             unmarshallPromotionRule(&promotion.Rule),
+		StartsAt:         promotion.StartsAt,
+		EndsAt:           promotion.EndsAt,
+		UsageLimit:       uint(promotion.UsageLimit),
+		PerCustomerLimit: uint(promotion.PerCustomerLimit),
+		Redemptions:      uint(promotion.Redemptions),
+		CreatedAt:        promotion.CreatedAt,
+		UpdatedAt:        promotion.UpdatedAt,
+	}
+}
+
+func marshallPromotionRule(rule *repository.PromotionRule) PromotionRuleModel {
+	return PromotionRuleModel{
+		Type:        string(rule.Type),
+		Percentage:  int(rule.Percentage),
+		Currency:    rule.AmountOff.Currency,
+		AmountOff:   rule.AmountOff.Amount,
+		ProductId:   rule.ProductId,
+		BuyQuantity: int(rule.BuyQuantity),
+		GetQuantity: int(rule.GetQuantity),
+	}
+}
+
+func unmarshallPromotionRule(rule *PromotionRuleModel) repository.PromotionRule {
+	return repository.PromotionRule{
+		Type:        repository.PromotionType(rule.Type),
+		Percentage:  uint(rule.Percentage),
+		AmountOff:   money.New(rule.AmountOff, rule.Currency),
+		ProductId:   rule.ProductId,
+		BuyQuantity: uint(rule.BuyQuantity),
+		GetQuantity: uint(rule.GetQuantity),
+	}
+}
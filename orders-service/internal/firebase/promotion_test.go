@@ -0,0 +1,25 @@
+package firebase_test
+
+import (
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestPromotionRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("PromotionRepository.CheckPreconditions() did not panic without a DB")
+		}
+	}()
+
+	db.NewPromotionRepository(nil).CheckPreconditions()
+}
+
+func TestPromotionRepository(t *testing.T) {
+	repositorytest.TestPromotionRepository(t, func(t *testing.T) repository.PromotionRepository {
+		return db.NewPromotionRepository(MustOpenFirestore(t))
+	})
+}
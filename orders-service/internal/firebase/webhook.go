@@ -0,0 +1,392 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ repository.WebhookRepository = (*WebhookRepository)(nil)
+
+type WebhookRepository struct {
+	db *FirestoreService
+}
+
+func NewWebhookRepository(db *FirestoreService) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (r *WebhookRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *WebhookRepository) subscriptionCollection() *firestore.CollectionRef {
+	r.CheckPreconditions()
+
+	return r.db.client.Collection("webhook_subscriptions")
+}
+
+func (r *WebhookRepository) deliveryCollection() *firestore.CollectionRef {
+	r.CheckPreconditions()
+
+	return r.db.client.Collection("webhook_deliveries")
+}
+
+func (r *WebhookRepository) CreateWebhookSubscription(
+	ctx context.Context, subscription *repository.WebhookSubscription) (*repository.WebhookSubscription, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := time.Now().Format(time.RFC3339)
+	subscription.CreatedAt = currentTime
+	subscription.UpdatedAt = currentTime
+
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+
+	docRef, _, err := r.subscriptionCollection().Add(ctx, &WebhookSubscriptionModel{
+		Url:        subscription.Url,
+		Secret:     subscription.Secret,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+		UpdatedAt:  subscription.UpdatedAt,
+	})
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to create webhook subscription: %v", err)
+	}
+
+	subscription.Id = docRef.ID
+
+	return subscription, nil
+}
+
+func (r *WebhookRepository) GetWebhookSubscription(
+	ctx context.Context, id string) (*repository.WebhookSubscription, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	doc, err := r.subscriptionCollection().Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "webhook subscription not found")
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get webhook subscription: %v", err)
+	}
+
+	return r.unmarshallSubscriptionDoc(doc)
+}
+
+func (r *WebhookRepository) ListWebhookSubscriptions(ctx context.Context) ([]*repository.WebhookSubscription, error) {
+	r.CheckPreconditions()
+
+	docs, err := r.subscriptionCollection().
+		OrderBy("created_at", firestore.Asc).OrderBy(firestore.DocumentID, firestore.Asc).
+		Documents(ctx).GetAll()
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to list webhook subscriptions: %v", err)
+	}
+
+	subscriptions := make([]*repository.WebhookSubscription, 0, len(docs))
+	for _, doc := range docs {
+		subscription, err := r.unmarshallSubscriptionDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	docRef := r.subscriptionCollection().Doc(id)
+
+	return r.runTransaction(ctx, "delete webhook subscription", func(tx *firestore.Transaction) error {
+		if _, err := tx.Get(docRef); status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "webhook subscription not found")
+		} else if err != nil {
+			return err
+		}
+
+		return tx.Delete(docRef)
+	})
+}
+
+// CreateWebhookDelivery checks that the event is not delivered to the
+// subscription yet in the same transaction that stores the delivery, as
+// Firestore has no unique constraints.
+func (r *WebhookRepository) CreateWebhookDelivery(
+	ctx context.Context, delivery *repository.WebhookDelivery) (*repository.WebhookDelivery, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := time.Now().Format(time.RFC3339)
+	delivery.CreatedAt = currentTime
+	delivery.UpdatedAt = currentTime
+
+	if err := delivery.Validate(); err != nil {
+		return nil, err
+	}
+
+	docRef := r.deliveryCollection().NewDoc()
+
+	err := r.runTransaction(ctx, "create webhook delivery", func(tx *firestore.Transaction) error {
+		docs, err := tx.Documents(r.deliveryCollection().
+			Where("subscription_id", "==", delivery.SubscriptionId).
+			Where("event_id", "==", delivery.EventId).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		} else if len(docs) > 0 {
+			return service.Errorf(service.ALREADY_EXISTS_ERROR, "webhook delivery already exists")
+		}
+
+		return tx.Create(docRef, r.marshallDelivery(delivery))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Id = docRef.ID
+
+	return delivery, nil
+}
+
+func (r *WebhookRepository) GetWebhookDelivery(ctx context.Context, id string) (*repository.WebhookDelivery, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	doc, err := r.deliveryCollection().Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "webhook delivery not found")
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get webhook delivery: %v", err)
+	}
+
+	return r.unmarshallDeliveryDoc(doc)
+}
+
+// webhookDeliveryCursors convert the page token values of the delivery sort
+// fields.
+var webhookDeliveryCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"updated_at": timeCursor,
+}
+
+func (r *WebhookRepository) ListWebhookDeliveries(ctx context.Context, filter *repository.WebhookDeliveryFilter,
+	opts *repository.ListOptions) ([]*repository.WebhookDelivery, string, error) {
+
+	r.CheckPreconditions()
+
+	page, err := repository.ParsePage(opts, repository.WebhookDeliverySortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	q := r.deliveryCollection().Query
+	if filter != nil {
+		if err := filter.Validate(); err != nil {
+			return nil, "", err
+		}
+
+		if filter.SubscriptionId != "" {
+			q = q.Where("subscription_id", "==", filter.SubscriptionId)
+		}
+		if filter.Status != "" {
+			q = q.Where("status", "==", string(filter.Status))
+		}
+	}
+
+	q, err = pageQuery(q, page, webhookDeliveryCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	deliveries, err := r.queryDeliveries(ctx, q)
+	if err != nil {
+		return nil, "", err
+	}
+
+	deliveries, next := repository.TrimPage(page, deliveries,
+		func(d *repository.WebhookDelivery) string { return d.SortValue(page.Ordering.Field) },
+		func(d *repository.WebhookDelivery) string { return d.Id },
+	)
+
+	return deliveries, next, nil
+}
+
+func (r *WebhookRepository) ListDueWebhookDeliveries(
+	ctx context.Context, at time.Time, limit int) ([]*repository.WebhookDelivery, error) {
+
+	r.CheckPreconditions()
+
+	return r.queryDeliveries(ctx, r.deliveryCollection().
+		Where("status", "==", string(repository.WebhookDeliveryStatusPending)).
+		Where("next_attempt_at", "<=", at.Local().Format(time.RFC3339)).
+		OrderBy("next_attempt_at", firestore.Asc).OrderBy(firestore.DocumentID, firestore.Asc).
+		Limit(limit))
+}
+
+func (r *WebhookRepository) UpdateWebhookDelivery(ctx context.Context, id string,
+	update *repository.WebhookDeliveryUpdate) (*repository.WebhookDelivery, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	docRef := r.deliveryCollection().Doc(id)
+
+	var delivery *repository.WebhookDelivery
+	err := r.runTransaction(ctx, "update webhook delivery", func(tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "webhook delivery not found")
+		} else if err != nil {
+			return err
+		}
+
+		if delivery, err = r.unmarshallDeliveryDoc(doc); err != nil {
+			return err
+		}
+
+		if err := delivery.ApplyUpdate(update); err != nil {
+			return err
+		}
+
+		delivery.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		return tx.Set(docRef, r.marshallDelivery(delivery))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (r *WebhookRepository) queryDeliveries(
+	ctx context.Context, q firestore.Query) ([]*repository.WebhookDelivery, error) {
+
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to list webhook deliveries: %v", err)
+	}
+
+	deliveries := make([]*repository.WebhookDelivery, 0, len(docs))
+	for _, doc := range docs {
+		delivery, err := r.unmarshallDeliveryDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// runTransaction runs fn in a transaction, passing through application errors
+// and wrapping any other failure.
+func (r *WebhookRepository) runTransaction(
+	ctx context.Context, action string, fn func(tx *firestore.Transaction) error) error {
+
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(tx)
+	})
+
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return err
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to %s: %v", action, err)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) unmarshallSubscriptionDoc(
+	doc *firestore.DocumentSnapshot) (*repository.WebhookSubscription, error) {
+
+	var model WebhookSubscriptionModel
+	if err := doc.DataTo(&model); err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall webhook subscription: %v", err)
+	}
+
+	return &repository.WebhookSubscription{
+		Id:         doc.Ref.ID,
+		Url:        model.Url,
+		Secret:     model.Secret,
+		EventTypes: model.EventTypes,
+		CreatedAt:  model.CreatedAt,
+		UpdatedAt:  model.UpdatedAt,
+	}, nil
+}
+
+func (r *WebhookRepository) marshallDelivery(delivery *repository.WebhookDelivery) *WebhookDeliveryModel {
+	// Timestamps are validated by the caller, and stored in local time so that
+	// they compare as strings.
+	nextAttemptAt, _ := time.Parse(time.RFC3339, delivery.NextAttemptAt)
+
+	return &WebhookDeliveryModel{
+		SubscriptionId:   delivery.SubscriptionId,
+		EventId:          delivery.EventId,
+		EventType:        delivery.EventType,
+		Payload:          delivery.Payload,
+		Status:           string(delivery.Status),
+		Attempts:         int(delivery.Attempts),
+		LastResponseCode: delivery.LastResponseCode,
+		LastError:        delivery.LastError,
+		NextAttemptAt:    nextAttemptAt.Local().Format(time.RFC3339),
+		CreatedAt:        delivery.CreatedAt,
+		UpdatedAt:        delivery.UpdatedAt,
+	}
+}
+
+func (r *WebhookRepository) unmarshallDeliveryDoc(
+	doc *firestore.DocumentSnapshot) (*repository.WebhookDelivery, error) {
+
+	var model WebhookDeliveryModel
+	if err := doc.DataTo(&model); err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to unmarshall webhook delivery: %v", err)
+	}
+
+	return &repository.WebhookDelivery{
+		Id:               doc.Ref.ID,
+		SubscriptionId:   model.SubscriptionId,
+		EventId:          model.EventId,
+		EventType:        model.EventType,
+		Payload:          model.Payload,
+		Status:           repository.WebhookDeliveryStatus(model.Status),
+		Attempts:         uint(model.Attempts),
+		LastResponseCode: model.LastResponseCode,
+		LastError:        model.LastError,
+		NextAttemptAt:    model.NextAttemptAt,
+		CreatedAt:        model.CreatedAt,
+		UpdatedAt:        model.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,25 @@
+package firebase_test
+
+import (
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/orders/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestWebhookRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("WebhookRepository.CheckPreconditions() did not panic without a DB")
+		}
+	}()
+
+	db.NewWebhookRepository(nil).CheckPreconditions()
+}
+
+func TestWebhookRepository(t *testing.T) {
+	repositorytest.TestWebhookRepository(t, func(t *testing.T) repository.WebhookRepository {
+		return db.NewWebhookRepository(MustOpenFirestore(t))
+	})
+}
@@ -5,19 +5,64 @@ import (
 	"fmt"
 
 	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 )
 
 func (s *GRPCServer) ProcessCheckout(
 	ctx context.Context, in *pb.ProcessCheckoutRequest) (*pb.ProcessCheckoutResponse, error) {
 
-	o, err := s.CheckoutService.ProcessCheckout(ctx, in.GetOrderId())
+	out, err := idempotent(ctx, s, "ProcessCheckout", in, s.processCheckout)
 	if err != nil {
 		return nil, Error(fmt.Errorf("failed to process checkout: %w", err))
 	}
 
+	return out, nil
+}
+
+func (s *GRPCServer) processCheckout(
+	ctx context.Context, in *pb.ProcessCheckoutRequest) (*pb.ProcessCheckoutResponse, error) {
+
+	o, err := s.CheckoutService.ProcessCheckout(ctx, in.GetOrderId())
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := getGRPCOrderStatus(o.OrderStatus)
+	if err != nil {
+		return nil, err
+	}
+
 	return &pb.ProcessCheckoutResponse{
 		OrderId:    o.Id,
 		CustomerId: o.CustomerId,
-		Status:     getGRPCOrderStatus(o.OrderStatus),
+		Status:     status,
+		OrderItems: getGRPCCheckoutItems(o.Items),
+		Subtotal:   getGRPCMoney(o.Subtotal),
+		Tax:        getGRPCMoney(o.Tax),
+		Discount:   getGRPCMoney(o.Discount),
+		Total:      getGRPCMoney(o.Total),
 	}, nil
 }
+
+func getGRPCCheckoutItems(items []*service.OrderItem) []*pb.OrderItem {
+	var orderItems []*pb.OrderItem
+	for _, item := range items {
+		orderItems = append(orderItems, &pb.OrderItem{
+			Id:        item.Id,
+			ProductId: item.ProductId,
+			Quantity:  uint32(item.Quantity),
+			UnitPrice: getGRPCMoney(item.UnitPrice),
+			LineTotal: getGRPCMoney(item.LineTotal),
+			TaxRate: getGRPCTaxRate(repository.TaxRate{
+				Category:  item.TaxCategory,
+				Rate:      item.TaxRate,
+				Exempt:    item.TaxExempt,
+				Inclusive: item.TaxInclusive,
+			}),
+			Tax: getGRPCMoney(item.Tax),
+		})
+	}
+
+	return orderItems
+}
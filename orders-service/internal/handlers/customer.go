@@ -54,9 +54,13 @@ func (s *GRPCServer) ListCustomers(
 
 	log.Printf("Received: %v", in)
 
-	customers, err := s.CustomerRepository.ListCustomers(ctx)
+	customers, next, err := s.CustomerRepository.ListCustomers(ctx, &repository.ListOptions{
+		PageSize:  int(in.GetPageSize()),
+		PageToken: in.GetPageToken(),
+		OrderBy:   in.GetOrderBy(),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list customers: %w", err)
+		return nil, Error(fmt.Errorf("failed to list customers: %w", err))
 	}
 
 	var responseCustomers []*pb.Customer
@@ -71,7 +75,8 @@ func (s *GRPCServer) ListCustomers(
 	}
 
 	return &pb.ListCustomersResponse{
-		Customers: responseCustomers,
+		Customers:     responseCustomers,
+		NextPageToken: next,
 	}, nil
 }
 
@@ -145,7 +145,16 @@ func TestGRPCServer_GetCustomer(t *testing.T) {
 	}
 }
 
-func mockListCustomersFunc(ctx context.Context) ([]*repository.Customer, error) {
+func mockListCustomersFunc(ctx context.Context, opts *repository.ListOptions) ([]*repository.Customer, string, error) {
+	if opts.OrderBy == "email" {
+		return nil, "", errors.New("intentional error")
+	}
+
+	var next string
+	if opts.PageSize == 1 {
+		next = "next-page"
+	}
+
 	return []*repository.Customer{
 		{
 			Id:        "1",
@@ -153,7 +162,7 @@ func mockListCustomersFunc(ctx context.Context) ([]*repository.Customer, error)
 			LastName:  "Doe",
 			Email:     "john.doe@example.com",
 		},
-	}, nil
+	}, next, nil
 }
 
 func TestGRPCServer_ListCustomers(t *testing.T) {
@@ -189,6 +198,33 @@ func TestGRPCServer_ListCustomers(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "List Customers Success - Next Page",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListCustomersRequest{PageSize: 1},
+			},
+			want: &pb.ListCustomersResponse{
+				Customers: []*pb.Customer{
+					{
+						Id:        "1",
+						FirstName: "John",
+						LastName:  "Doe",
+						Email:     "john.doe@example.com",
+					},
+				},
+				NextPageToken: "next-page",
+			},
+		},
+		{
+			name: "List Customers Error",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListCustomersRequest{OrderBy: "email"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
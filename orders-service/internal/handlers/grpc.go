@@ -33,6 +33,12 @@ func GRPCErrorStatusCode(err error) error {
 		return status.Error(codes.NotFound, service.ErrorMessage(err))
 	case service.ALREADY_EXISTS_ERROR:
 		return status.Error(codes.AlreadyExists, service.ErrorMessage(err))
+	case service.CONFLICT_ERROR:
+		return status.Error(codes.Aborted, service.ErrorMessage(err))
+	case service.FAILED_PRECONDITION_ERROR, service.OUT_OF_STOCK_ERROR:
+		return status.Error(codes.FailedPrecondition, service.ErrorMessage(err))
+	case service.NOT_IMPLEMENTED_ERROR:
+		return status.Error(codes.Unimplemented, service.ErrorMessage(err))
 	case service.INTERNAL_ERROR:
 		return status.Error(codes.Internal, service.ErrorMessage(err))
 	default:
@@ -0,0 +1,36 @@
+package handlers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/handlers"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCErrorStatusCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{"Invalid", service.Errorf(service.INVALID_ERROR, "invalid"), codes.InvalidArgument},
+		{"Not Found", service.Errorf(service.NOT_FOUND_ERROR, "not found"), codes.NotFound},
+		{"Already Exists", service.Errorf(service.ALREADY_EXISTS_ERROR, "exists"), codes.AlreadyExists},
+		{"Conflict", service.Errorf(service.CONFLICT_ERROR, "in progress"), codes.Aborted},
+		{"Failed Precondition", service.Errorf(service.FAILED_PRECONDITION_ERROR, "illegal"), codes.FailedPrecondition},
+		{"Out Of Stock", service.Errorf(service.OUT_OF_STOCK_ERROR, "out of stock"), codes.FailedPrecondition},
+		{"Not Implemented", service.Errorf(service.NOT_IMPLEMENTED_ERROR, "disabled"), codes.Unimplemented},
+		{"Internal", service.Errorf(service.INTERNAL_ERROR, "internal"), codes.Internal},
+		{"Non-application Error", errors.New("boom"), codes.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(handlers.GRPCErrorStatusCode(tt.err)); got != tt.want {
+				t.Errorf("GRPCErrorStatusCode() code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package handlers_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	idempotency_memory "github.com/Mik3y-F/order-management-system/pkg/idempotency/memory"
+	idempotency_mock "github.com/Mik3y-F/order-management-system/pkg/idempotency/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// newIdempotentTestGRPCServer returns a test server that records idempotency
+// keys in memory and counts the orders it creates.
+func newIdempotentTestGRPCServer(t *testing.T) (*TestGRPCServer, *int) {
+	s := NewTestGRPCServer(t)
+
+	s.IdempotencyStore = *idempotency_mock.NewStore(idempotency_memory.NewStore())
+	s.ProductRepository.GetProductFunc = mockGetProductFunc
+
+	created := 0
+	s.OrderRepository.CreateOrderFunc = func(ctx context.Context, o *repository.Order) (*repository.Order, error) {
+		created++
+
+		order, err := mockCreateOrderFunc(ctx, o)
+		if err == nil {
+			order.Id = strconv.Itoa(created)
+		}
+		return order, err
+	}
+
+	return s, &created
+}
+
+func newIdempotentCreateOrderRequest(key string) *pb.CreateOrderRequest {
+	return &pb.CreateOrderRequest{
+		CustomerId:     "1",
+		OrderItems:     []*pb.OrderItem{{ProductId: "1", Quantity: 1}},
+		IdempotencyKey: key,
+	}
+}
+
+func TestGRPCServer_CreateOrder_Idempotent(t *testing.T) {
+	s, created := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	first, err := s.CreateOrder(ctx, newIdempotentCreateOrderRequest("key-1"))
+	if err != nil {
+		t.Fatalf("GRPCServer.CreateOrder() error = %v", err)
+	}
+
+	retry, err := s.CreateOrder(ctx, newIdempotentCreateOrderRequest("key-1"))
+	if err != nil {
+		t.Fatalf("GRPCServer.CreateOrder() retry error = %v", err)
+	}
+	if !proto.Equal(retry, first) {
+		t.Errorf("GRPCServer.CreateOrder() retry = %v, want %v", retry, first)
+	}
+
+	other, err := s.CreateOrder(ctx, newIdempotentCreateOrderRequest("key-2"))
+	if err != nil {
+		t.Fatalf("GRPCServer.CreateOrder() error = %v", err)
+	}
+	if proto.Equal(other, first) {
+		t.Errorf("GRPCServer.CreateOrder() with another key = %v, want a new order", other)
+	}
+
+	if *created != 2 {
+		t.Errorf("GRPCServer.CreateOrder() created %d orders, want 2", *created)
+	}
+}
+
+func TestGRPCServer_CreateOrder_NoIdempotencyKey(t *testing.T) {
+	s, created := newIdempotentTestGRPCServer(t)
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.CreateOrder(context.Background(), newIdempotentCreateOrderRequest("")); err != nil {
+			t.Fatalf("GRPCServer.CreateOrder() error = %v", err)
+		}
+	}
+
+	if *created != 2 {
+		t.Errorf("GRPCServer.CreateOrder() created %d orders, want 2", *created)
+	}
+}
+
+func TestGRPCServer_CreateOrder_IdempotencyKeyReused(t *testing.T) {
+	s, created := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	if _, err := s.CreateOrder(ctx, newIdempotentCreateOrderRequest("key-1")); err != nil {
+		t.Fatalf("GRPCServer.CreateOrder() error = %v", err)
+	}
+
+	in := newIdempotentCreateOrderRequest("key-1")
+	in.OrderItems[0].Quantity = 2
+
+	_, err := s.CreateOrder(ctx, in)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GRPCServer.CreateOrder() with a different request error = %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	_, err = s.CreateOrder(ctx, newIdempotentCreateOrderRequest(strings.Repeat("k", 256)))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GRPCServer.CreateOrder() with a long key error = %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	if *created != 1 {
+		t.Errorf("GRPCServer.CreateOrder() created %d orders, want 1", *created)
+	}
+}
+
+func TestGRPCServer_CreateOrder_IdempotencyKeyInProgress(t *testing.T) {
+	s, _ := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	// The retry arrives while the first request is creating the order.
+	var retryErr error
+	s.OrderRepository.CreateOrderFunc = func(ctx context.Context, o *repository.Order) (*repository.Order, error) {
+		_, retryErr = s.CreateOrder(ctx, newIdempotentCreateOrderRequest("key-1"))
+		return mockCreateOrderFunc(ctx, o)
+	}
+
+	if _, err := s.CreateOrder(ctx, newIdempotentCreateOrderRequest("key-1")); err != nil {
+		t.Fatalf("GRPCServer.CreateOrder() error = %v", err)
+	}
+	if status.Code(retryErr) != codes.Aborted {
+		t.Errorf("GRPCServer.CreateOrder() retry in progress error = %v, want code %v", retryErr, codes.Aborted)
+	}
+}
+
+func TestGRPCServer_CreateOrder_IdempotencyKeyReleased(t *testing.T) {
+	s, created := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	// A failed request does not hold on to its key.
+	in := newIdempotentCreateOrderRequest("key-1")
+	in.OrderItems[0].ProductId = ERROR_PRODUCT_TRIGGER
+
+	if _, err := s.CreateOrder(ctx, in); err == nil {
+		t.Fatalf("GRPCServer.CreateOrder() error = nil, want an error")
+	}
+	if _, err := s.CreateOrder(ctx, in); status.Code(err) != codes.Internal {
+		t.Errorf("GRPCServer.CreateOrder() retry error = %v, want code %v", err, codes.Internal)
+	}
+
+	// Nor is the corrected request held to the failed one.
+	in.OrderItems[0].ProductId = "1"
+	if _, err := s.CreateOrder(ctx, in); err != nil {
+		t.Errorf("GRPCServer.CreateOrder() with a corrected request error = %v", err)
+	}
+
+	if *created != 1 {
+		t.Errorf("GRPCServer.CreateOrder() created %d orders, want 1", *created)
+	}
+}
+
+// checkoutService counts the checkouts it processes.
+type checkoutService struct {
+	checkouts int
+}
+
+func (s *checkoutService) ProcessCheckout(ctx context.Context, orderId string) (*service.Order, error) {
+	s.checkouts++
+
+	return &service.Order{Id: orderId, CustomerId: "1", OrderStatus: pkg.OrderStatusPending}, nil
+}
+
+func TestGRPCServer_ProcessCheckout_Idempotent(t *testing.T) {
+	s, _ := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	checkout := &checkoutService{}
+	s.GRPCServer.CheckoutService = checkout
+
+	in := &pb.ProcessCheckoutRequest{OrderId: "1", IdempotencyKey: "key-1"}
+
+	first, err := s.ProcessCheckout(ctx, in)
+	if err != nil {
+		t.Fatalf("GRPCServer.ProcessCheckout() error = %v", err)
+	}
+
+	retry, err := s.ProcessCheckout(ctx, in)
+	if err != nil {
+		t.Fatalf("GRPCServer.ProcessCheckout() retry error = %v", err)
+	}
+	if !proto.Equal(retry, first) {
+		t.Errorf("GRPCServer.ProcessCheckout() retry = %v, want %v", retry, first)
+	}
+
+	// Keys are scoped to the method they are used with.
+	if _, err := s.CreateOrder(ctx, newIdempotentCreateOrderRequest("key-1")); err != nil {
+		t.Errorf("GRPCServer.CreateOrder() with the key of a checkout error = %v", err)
+	}
+
+	if checkout.checkouts != 1 {
+		t.Errorf("GRPCServer.ProcessCheckout() processed %d checkouts, want 1", checkout.checkouts)
+	}
+}
@@ -3,14 +3,27 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func (s *GRPCServer) CreateOrder(ctx context.Context, in *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
 
+	out, err := idempotent(ctx, s, "CreateOrder", in, s.createOrder)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to create order: %w", err))
+	}
+
+	return out, nil
+}
+
+func (s *GRPCServer) createOrder(ctx context.Context, in *pb.CreateOrderRequest) (*pb.CreateOrderResponse, error) {
+
 	var items []*repository.OrderItem
 	for _, item := range in.GetOrderItems() {
 		items = append(items, &repository.OrderItem{
@@ -19,12 +32,16 @@ func (s *GRPCServer) CreateOrder(ctx context.Context, in *pb.CreateOrderRequest)
 		})
 	}
 
+	if err := repository.PriceOrderItems(ctx, s.ProductRepository, s.TaxRates, items); err != nil {
+		return nil, err
+	}
+
 	p, err := s.OrderRepository.CreateOrder(ctx, &repository.Order{
 		CustomerId: in.GetCustomerId(),
 		Items:      items,
 	})
 	if err != nil {
-		return nil, Error(fmt.Errorf("failed to create order: %w", err))
+		return nil, err
 	}
 
 	return &pb.CreateOrderResponse{
@@ -39,56 +56,123 @@ func (s *GRPCServer) GetOrder(ctx context.Context, in *pb.GetOrderRequest) (*pb.
 		return nil, Error(fmt.Errorf("failed to get order: %w", err))
 	}
 
-	var orderItems []*pb.OrderItem
-	for _, item := range order.Items {
-		orderItems = append(orderItems, &pb.OrderItem{
-			ProductId: item.ProductId,
-			Quantity:  uint32(item.Quantity),
-		})
+	status, err := getGRPCOrderStatus(order.OrderStatus)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to get order: %w", err))
 	}
 
 	return &pb.GetOrderResponse{
 		Id:         order.Id,
 		CustomerId: order.CustomerId,
-		OrderItems: orderItems,
-		Status:     getGRPCOrderStatus(order.OrderStatus),
+		OrderItems: getGRPCOrderItems(order.Items),
+		Status:     status,
+		Version:    uint64(order.Version),
+		Subtotal:   getGRPCMoney(order.Subtotal),
+		Tax:        getGRPCMoney(order.Tax),
+		Discount:   getGRPCMoney(order.Discount),
+		Total:      getGRPCMoney(order.Total),
+
+		PromotionCode: getPromotionCode(order),
 	}, nil
 }
 
 func (s *GRPCServer) ListOrders(ctx context.Context, in *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
 
-	orders, err := s.OrderRepository.ListOrders(ctx)
+	filter := &repository.OrderFilter{
+		CustomerId: in.GetCustomerId(),
+	}
+	if in.Status != nil {
+		status, err := getOrderStatus(in.GetStatus())
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to list orders: %w", err))
+		}
+
+		filter.Status = status
+	}
+	if in.CreatedFrom != nil {
+		filter.CreatedFrom = in.GetCreatedFrom().AsTime()
+	}
+	if in.CreatedUntil != nil {
+		filter.CreatedUntil = in.GetCreatedUntil().AsTime()
+	}
+
+	orders, next, err := s.OrderRepository.ListOrders(ctx, filter, &repository.ListOptions{
+		PageSize:  int(in.GetPageSize()),
+		PageToken: in.GetPageToken(),
+		OrderBy:   in.GetOrderBy(),
+	})
 	if err != nil {
 		return nil, Error(fmt.Errorf("failed to list orders: %w", err))
 	}
 
 	var responseOrders []*pb.Order
 	for _, p := range orders {
-		var orderItems []*pb.OrderItem
-		for _, item := range p.Items {
-			orderItems = append(orderItems, &pb.OrderItem{
-				ProductId: item.ProductId,
-				Quantity:  uint32(item.Quantity),
-			})
+		order, err := getGRPCOrder(p)
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to list orders: %w", err))
 		}
 
-		responseOrders = append(responseOrders, &pb.Order{
-			Id:         p.Id,
-			CustomerId: p.CustomerId,
-			OrderItems: orderItems,
-			Status:     getGRPCOrderStatus(p.OrderStatus),
-		})
+		responseOrders = append(responseOrders, order)
 	}
 
 	return &pb.ListOrdersResponse{
-		Orders: responseOrders,
+		Orders:        responseOrders,
+		NextPageToken: next,
+	}, nil
+}
+
+func (s *GRPCServer) ListOrdersByCustomer(
+	ctx context.Context, in *pb.ListOrdersByCustomerRequest) (*pb.ListOrdersByCustomerResponse, error) {
+
+	orders, next, err := s.OrderRepository.ListOrdersByCustomer(ctx, in.GetCustomerId(), &repository.ListOptions{
+		PageSize:  int(in.GetPageSize()),
+		PageToken: in.GetPageToken(),
+		OrderBy:   in.GetOrderBy(),
+	})
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to list customer orders: %w", err))
+	}
+
+	var responseOrders []*pb.Order
+	for _, p := range orders {
+		order, err := getGRPCOrder(p)
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to list customer orders: %w", err))
+		}
+
+		responseOrders = append(responseOrders, order)
+	}
+
+	return &pb.ListOrdersByCustomerResponse{
+		Orders:        responseOrders,
+		NextPageToken: next,
 	}, nil
 }
 
 func (s *GRPCServer) UpdateOrderStatus(
 	ctx context.Context, in *pb.UpdateOrderStatusRequest) (*pb.UpdateOrderStatusResponse, error) {
 
-	order, err := s.OrderRepository.UpdateOrderStatus(ctx, in.GetId(), getOrderStatus(in.GetStatus()))
+	status, err := getOrderStatus(in.GetStatus())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to update order status: %w", err))
+	}
+
+	update := &repository.OrderStatusUpdate{
+		Status: status,
+		Actor:  in.GetActor(),
+		Reason: in.GetReason(),
+	}
+	if in.Version != nil {
+		version := uint(in.GetVersion())
+		update.Version = &version
+	}
+
+	order, err := s.OrderRepository.UpdateOrderStatus(ctx, in.GetId(), update)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to update order status: %w", err))
+	}
+
+	grpcStatus, err := getGRPCOrderStatus(order.OrderStatus)
 	if err != nil {
 		return nil, Error(fmt.Errorf("failed to update order status: %w", err))
 	}
@@ -96,7 +180,8 @@ func (s *GRPCServer) UpdateOrderStatus(
 	return &pb.UpdateOrderStatusResponse{
 		Id:         order.Id,
 		CustomerId: order.CustomerId,
-		Status:     getGRPCOrderStatus(order.OrderStatus),
+		Status:     grpcStatus,
+		Version:    uint64(order.Version),
 	}, nil
 }
 
@@ -110,13 +195,41 @@ func (s *GRPCServer) DeleteOrder(ctx context.Context, in *pb.DeleteOrderRequest)
 	return &pb.DeleteOrderResponse{}, nil
 }
 
+func (s *GRPCServer) GetOrderHistory(
+	ctx context.Context, in *pb.GetOrderHistoryRequest) (*pb.GetOrderHistoryResponse, error) {
+
+	history, err := s.OrderRepository.GetOrderHistory(ctx, in.GetOrderId())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to get order history: %w", err))
+	}
+
+	changes := make([]*pb.OrderStatusChange, 0, len(history))
+	for _, c := range history {
+		change, err := getGRPCOrderStatusChange(c)
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to get order history: %w", err))
+		}
+
+		changes = append(changes, change)
+	}
+
+	return &pb.GetOrderHistoryResponse{
+		Changes: changes,
+	}, nil
+}
+
 func (s *GRPCServer) CreateOrderItem(
 	ctx context.Context, in *pb.CreateOrderItemRequest) (*pb.CreateOrderItemResponse, error) {
 
-	orderItem, err := s.OrderRepository.CreateOrderItem(ctx, in.GetOrderId(), &repository.OrderItem{
+	item := &repository.OrderItem{
 		ProductId: in.GetProductId(),
 		Quantity:  uint(in.GetQuantity()),
-	})
+	}
+	if err := repository.PriceOrderItems(ctx, s.ProductRepository, s.TaxRates, []*repository.OrderItem{item}); err != nil {
+		return nil, Error(fmt.Errorf("failed to create order item: %w", err))
+	}
+
+	orderItem, err := s.OrderRepository.CreateOrderItem(ctx, in.GetOrderId(), item)
 	if err != nil {
 		return nil, Error(fmt.Errorf("failed to create order item: %w", err))
 	}
@@ -137,6 +250,10 @@ func (s *GRPCServer) GetOrderItem(ctx context.Context, in *pb.GetOrderItemReques
 		Id:        orderItem.Id,
 		ProductId: orderItem.ProductId,
 		Quantity:  uint32(orderItem.Quantity),
+		UnitPrice: getGRPCMoney(orderItem.UnitPrice),
+		LineTotal: getGRPCMoney(orderItem.LineTotal),
+		TaxRate:   getGRPCTaxRate(orderItem.TaxRate),
+		Tax:       getGRPCMoney(orderItem.Tax),
 	}, nil
 }
 
@@ -148,17 +265,8 @@ func (s *GRPCServer) ListOrderItems(
 		return nil, Error(fmt.Errorf("failed to list order items: %w", err))
 	}
 
-	var responseOrderItems []*pb.OrderItem
-	for _, item := range orderItems {
-		responseOrderItems = append(responseOrderItems, &pb.OrderItem{
-			Id:        item.Id,
-			ProductId: item.ProductId,
-			Quantity:  uint32(item.Quantity),
-		})
-	}
-
 	return &pb.ListOrderItemsResponse{
-		OrderItems: responseOrderItems,
+		OrderItems: getGRPCOrderItems(orderItems),
 	}, nil
 }
 
@@ -181,6 +289,10 @@ func (s *GRPCServer) UpdateOrderItem(
 		Id:        orderItem.Id,
 		ProductId: orderItem.ProductId,
 		Quantity:  uint32(orderItem.Quantity),
+		UnitPrice: getGRPCMoney(orderItem.UnitPrice),
+		LineTotal: getGRPCMoney(orderItem.LineTotal),
+		TaxRate:   getGRPCTaxRate(orderItem.TaxRate),
+		Tax:       getGRPCMoney(orderItem.Tax),
 	}, nil
 }
 
@@ -195,32 +307,124 @@ func (s *GRPCServer) DeleteOrderItem(
 	return &pb.DeleteOrderItemResponse{}, nil
 }
 
-func getGRPCOrderStatus(status pkg.OrderStatus) pb.OrderStatus {
-	switch status {
-	case pkg.OrderStatusNew:
-		return pb.OrderStatus_NEW
-	case pkg.OrderStatusPending:
-		return pb.OrderStatus_PENDING
-	case pkg.OrderStatusProcessing:
-		return pb.OrderStatus_PROCESSING
-	case pkg.OrderStatusPaid:
-		return pb.OrderStatus_PAID
-	default:
-		return pb.OrderStatus_UNKNOWN
-	}
-}
-
-func getOrderStatus(status pb.OrderStatus) pkg.OrderStatus {
-	switch status {
-	case pb.OrderStatus_NEW:
-		return pkg.OrderStatusNew
-	case pb.OrderStatus_PENDING:
-		return pkg.OrderStatusPending
-	case pb.OrderStatus_PROCESSING:
-		return pkg.OrderStatusProcessing
-	case pb.OrderStatus_PAID:
-		return pkg.OrderStatusPaid
-	default:
-		return pkg.OrderStatusNew
+func getGRPCOrder(order *repository.Order) (*pb.Order, error) {
+	status, err := getGRPCOrderStatus(order.OrderStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.Order{
+		Id:         order.Id,
+		CustomerId: order.CustomerId,
+		OrderItems: getGRPCOrderItems(order.Items),
+		Status:     status,
+		Version:    uint64(order.Version),
+		Subtotal:   getGRPCMoney(order.Subtotal),
+		Tax:        getGRPCMoney(order.Tax),
+		Discount:   getGRPCMoney(order.Discount),
+		Total:      getGRPCMoney(order.Total),
+
+		PromotionCode: getPromotionCode(order),
+	}, nil
+}
+
+// getPromotionCode returns the code of the promotion applied to the order, or
+// an empty string.
+func getPromotionCode(order *repository.Order) string {
+	if order.Promotion == nil {
+		return ""
+	}
+
+	return order.Promotion.Code
+}
+
+func getGRPCOrderItems(items []*repository.OrderItem) []*pb.OrderItem {
+	var orderItems []*pb.OrderItem
+	for _, item := range items {
+		orderItems = append(orderItems, &pb.OrderItem{
+			Id:        item.Id,
+			ProductId: item.ProductId,
+			Quantity:  uint32(item.Quantity),
+			UnitPrice: getGRPCMoney(item.UnitPrice),
+			LineTotal: getGRPCMoney(item.LineTotal),
+			TaxRate:   getGRPCTaxRate(item.TaxRate),
+			Tax:       getGRPCMoney(item.Tax),
+		})
+	}
+
+	return orderItems
+}
+
+func getGRPCTaxRate(rate repository.TaxRate) *pb.TaxRate {
+	if rate == (repository.TaxRate{}) {
+		return nil
+	}
+
+	return &pb.TaxRate{
+		Category:  rate.Category,
+		Rate:      uint32(rate.Rate),
+		Exempt:    rate.Exempt,
+		Inclusive: rate.Inclusive,
+	}
+}
+
+func getGRPCOrderStatusChange(change *repository.OrderStatusChange) (*pb.OrderStatusChange, error) {
+	fromStatus, err := getGRPCOrderStatus(change.FromStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	toStatus, err := getGRPCOrderStatus(change.ToStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, change.CreatedAt)
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "invalid order status change timestamp: %v", err)
+	}
+
+	return &pb.OrderStatusChange{
+		Id:         change.Id,
+		OrderId:    change.OrderId,
+		FromStatus: fromStatus,
+		ToStatus:   toStatus,
+		Actor:      change.Actor,
+		Reason:     change.Reason,
+		Version:    uint64(change.Version),
+		CreatedAt:  timestamppb.New(createdAt),
+	}, nil
+}
+
+// orderStatuses maps every domain order status to its gRPC counterpart. Both
+// directions are derived from this table so that the mapping round-trips.
+var orderStatuses = map[pkg.OrderStatus]pb.OrderStatus{
+	pkg.OrderStatusNew:        pb.OrderStatus_NEW,
+	pkg.OrderStatusPending:    pb.OrderStatus_PENDING,
+	pkg.OrderStatusProcessing: pb.OrderStatus_PROCESSING,
+	pkg.OrderStatusPaid:       pb.OrderStatus_PAID,
+	pkg.OrderStatusCancelled:  pb.OrderStatus_CANCELLED,
+	pkg.OrderStatusFailed:     pb.OrderStatus_FAILED,
+	pkg.OrderStatusRefunded:   pb.OrderStatus_REFUNDED,
+	pkg.OrderStatusShipped:    pb.OrderStatus_SHIPPED,
+	pkg.OrderStatusFulfilled:  pb.OrderStatus_FULFILLED,
+}
+
+func getGRPCOrderStatus(status pkg.OrderStatus) (pb.OrderStatus, error) {
+	s, ok := orderStatuses[status]
+	if !ok {
+		return pb.OrderStatus_UNKNOWN, service.Errorf(service.INTERNAL_ERROR, "unknown order status %q", status)
 	}
+
+	return s, nil
+}
+
+func getOrderStatus(status pb.OrderStatus) (pkg.OrderStatus, error) {
+	for s, grpcStatus := range orderStatuses {
+		if grpcStatus == status {
+			return s, nil
+		}
+	}
+
+	return "", service.Errorf(service.INVALID_ERROR, "unknown order status %v", status)
 }
@@ -4,11 +4,18 @@ import (
 	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
 	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/internal/tax"
 	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -22,6 +29,10 @@ func mockCreateOrderFunc(ctx context.Context, o *repository.Order) (*repository.
 
 	var items []*repository.OrderItem
 	for _, item := range o.Items {
+		if item.UnitPrice.IsZero() {
+			return nil, service.Errorf(service.INVALID_ERROR, "item of product %q is not priced", item.ProductId)
+		}
+
 		items = append(items, &repository.OrderItem{
 			ProductId: item.ProductId,
 			Quantity:  uint(item.Quantity),
@@ -38,6 +49,7 @@ func TestGRPCServer_CreateOrder(t *testing.T) {
 	s := NewTestGRPCServer(t)
 
 	s.OrderRepository.CreateOrderFunc = mockCreateOrderFunc
+	s.ProductRepository.GetProductFunc = mockGetProductFunc
 
 	type args struct {
 		ctx context.Context
@@ -85,6 +97,23 @@ func TestGRPCServer_CreateOrder(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "Create Order Error - Product",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.CreateOrderRequest{
+					CustomerId: "1",
+					OrderItems: []*pb.OrderItem{
+						{
+							ProductId: ERROR_PRODUCT_TRIGGER,
+							Quantity:  1,
+						},
+					},
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -100,6 +129,37 @@ func TestGRPCServer_CreateOrder(t *testing.T) {
 	}
 }
 
+func TestGRPCServer_CreateOrder_Tax(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	rates, err := tax.ParseTable("default=16,food=0", true)
+	if err != nil {
+		t.Fatalf("ParseTable() error = %v", err)
+	}
+	s.GRPCServer.TaxRates = rates
+
+	var got []*repository.OrderItem
+	s.OrderRepository.CreateOrderFunc = func(ctx context.Context, o *repository.Order) (*repository.Order, error) {
+		got = o.Items
+		return mockCreateOrderFunc(ctx, o)
+	}
+	s.ProductRepository.GetProductFunc = mockGetProductFunc
+
+	_, err = s.CreateOrder(context.Background(), &pb.CreateOrderRequest{
+		CustomerId: "1",
+		OrderItems: []*pb.OrderItem{{ProductId: "1", Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("GRPCServer.CreateOrder() error = %v", err)
+	}
+
+	// The item takes the rate of the category of its product.
+	want := repository.TaxRate{Category: "food", Inclusive: true}
+	if len(got) != 1 || got[0].TaxRate != want {
+		t.Errorf("GRPCServer.CreateOrder() stored items %+v, want one with tax rate %+v", got, want)
+	}
+}
+
 func mockGetOrderFunc(ctx context.Context, id string) (*repository.Order, error) {
 	if id == ERROR_ORDER_TRIGGER {
 		return nil, service.Errorf(service.INVALID_ERROR, "intentional error")
@@ -113,8 +173,16 @@ func mockGetOrderFunc(ctx context.Context, id string) (*repository.Order, error)
 			{
 				ProductId: "1",
 				Quantity:  1,
+				UnitPrice: money.New(10000, "KES"),
+				LineTotal: money.New(10000, "KES"),
+				TaxRate:   repository.TaxRate{Category: "default", Rate: 1600, Inclusive: true},
+				Tax:       money.New(1379, "KES"),
 			},
 		},
+		Subtotal: money.New(10000, "KES"),
+		Tax:      money.New(1379, "KES"),
+		Discount: money.Zero("KES"),
+		Total:    money.New(10000, "KES"),
 	}, nil
 }
 func TestGRPCServer_GetOrder(t *testing.T) {
@@ -148,8 +216,16 @@ func TestGRPCServer_GetOrder(t *testing.T) {
 					{
 						ProductId: "1",
 						Quantity:  1,
+						UnitPrice: &pb.Money{Amount: 10000, Currency: "KES"},
+						LineTotal: &pb.Money{Amount: 10000, Currency: "KES"},
+						TaxRate:   &pb.TaxRate{Category: "default", Rate: 1600, Inclusive: true},
+						Tax:       &pb.Money{Amount: 1379, Currency: "KES"},
 					},
 				},
+				Subtotal: &pb.Money{Amount: 10000, Currency: "KES"},
+				Tax:      &pb.Money{Amount: 1379, Currency: "KES"},
+				Discount: &pb.Money{Currency: "KES"},
+				Total:    &pb.Money{Amount: 10000, Currency: "KES"},
 			},
 		},
 		{
@@ -179,7 +255,21 @@ func TestGRPCServer_GetOrder(t *testing.T) {
 	}
 }
 
-func mockListOrdersFunc(ctx context.Context) ([]*repository.Order, error) {
+func mockListOrdersFunc(
+	ctx context.Context, filter *repository.OrderFilter, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+	if filter.CustomerId == ERROR_ORDER_TRIGGER {
+		return nil, "", service.Errorf(service.INVALID_ERROR, "intentional error")
+	}
+
+	if filter.Status != "" && filter.Status != pkg.OrderStatusNew {
+		return nil, "", nil
+	}
+
+	var next string
+	if opts.PageSize == 1 {
+		next = "next-page"
+	}
+
 	return []*repository.Order{
 		{
 			Id:          "1",
@@ -192,7 +282,7 @@ func mockListOrdersFunc(ctx context.Context) ([]*repository.Order, error) {
 				},
 			},
 		},
-	}, nil
+	}, next, nil
 }
 
 func TestGRPCServer_ListOrders(t *testing.T) {
@@ -233,6 +323,55 @@ func TestGRPCServer_ListOrders(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "List Orders Success - Next Page",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListOrdersRequest{PageSize: 1},
+			},
+			want: &pb.ListOrdersResponse{
+				Orders: []*pb.Order{
+					{
+						Id:         "1",
+						CustomerId: "1",
+						Status:     pb.OrderStatus_NEW,
+						OrderItems: []*pb.OrderItem{
+							{
+								ProductId: "1",
+								Quantity:  1,
+							},
+						},
+					},
+				},
+				NextPageToken: "next-page",
+			},
+		},
+		{
+			name: "List Orders Success - Filtered By Status",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListOrdersRequest{Status: pb.OrderStatus_PAID.Enum()},
+			},
+			want: &pb.ListOrdersResponse{},
+		},
+		{
+			name: "List Orders Error - Unknown Status",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListOrdersRequest{Status: pb.OrderStatus_UNKNOWN.Enum()},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "List Orders Error",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListOrdersRequest{CustomerId: ERROR_ORDER_TRIGGER},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -248,15 +387,124 @@ func TestGRPCServer_ListOrders(t *testing.T) {
 	}
 }
 
-func mockUpdateOrderStatusFunc(ctx context.Context, id string, status pkg.OrderStatus) (*repository.Order, error) {
+func mockListOrdersByCustomerFunc(
+	ctx context.Context, customerId string, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+	if customerId == ERROR_ORDER_TRIGGER {
+		return nil, "", service.Errorf(service.INVALID_ERROR, "intentional error")
+	}
+
+	var next string
+	if opts.PageSize == 1 {
+		next = "next-page"
+	}
+
+	return []*repository.Order{
+		{
+			Id:          "2",
+			CustomerId:  customerId,
+			OrderStatus: pkg.OrderStatusNew,
+			Items: []*repository.OrderItem{
+				{
+					Id:        "1",
+					ProductId: "1",
+					Quantity:  3,
+					UnitPrice: money.New(10000, "KES"),
+					LineTotal: money.New(30000, "KES"),
+				},
+			},
+			Subtotal: money.New(30000, "KES"),
+			Tax:      money.New(4800, "KES"),
+			Discount: money.New(3000, "KES"),
+			Total:    money.New(31800, "KES"),
+		},
+	}, next, nil
+}
+
+func TestGRPCServer_ListOrdersByCustomer(t *testing.T) {
+
+	s := NewTestGRPCServer(t)
+
+	s.OrderRepository.ListOrdersByCustomerFunc = mockListOrdersByCustomerFunc
+
+	type args struct {
+		ctx context.Context
+		in  *pb.ListOrdersByCustomerRequest
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *pb.ListOrdersByCustomerResponse
+		wantErr bool
+	}{
+		{
+			name: "List Orders By Customer Success",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListOrdersByCustomerRequest{CustomerId: "1", PageSize: 1},
+			},
+			want: &pb.ListOrdersByCustomerResponse{
+				Orders: []*pb.Order{
+					{
+						Id:         "2",
+						CustomerId: "1",
+						Status:     pb.OrderStatus_NEW,
+						OrderItems: []*pb.OrderItem{
+							{
+								Id:        "1",
+								ProductId: "1",
+								Quantity:  3,
+								UnitPrice: &pb.Money{Amount: 10000, Currency: "KES"},
+								LineTotal: &pb.Money{Amount: 30000, Currency: "KES"},
+							},
+						},
+						Subtotal: &pb.Money{Amount: 30000, Currency: "KES"},
+						Tax:      &pb.Money{Amount: 4800, Currency: "KES"},
+						Discount: &pb.Money{Amount: 3000, Currency: "KES"},
+						Total:    &pb.Money{Amount: 31800, Currency: "KES"},
+					},
+				},
+				NextPageToken: "next-page",
+			},
+		},
+		{
+			name: "List Orders By Customer Error",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListOrdersByCustomerRequest{CustomerId: ERROR_ORDER_TRIGGER},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ListOrdersByCustomer(tt.args.ctx, tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.ListOrdersByCustomer() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("GRPCServer.ListOrdersByCustomer() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mockUpdateOrderStatusFunc(
+	ctx context.Context, id string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
 	if id == ERROR_ORDER_TRIGGER {
 		return nil, service.Errorf(service.INVALID_ERROR, "intentional error")
 	}
 
+	if update.Version != nil && *update.Version != 1 {
+		return nil, service.Errorf(service.FAILED_PRECONDITION_ERROR, "order has been modified")
+	}
+
 	return &repository.Order{
 		Id:          "1",
 		CustomerId:  "1",
-		OrderStatus: status,
+		OrderStatus: update.Status,
+		Version:     2,
 		Items: []*repository.OrderItem{
 			{
 				ProductId: "1",
@@ -294,7 +542,50 @@ func TestGRPCServer_UpdateOrderStatus(t *testing.T) {
 				Id:         "1",
 				CustomerId: "1",
 				Status:     pb.OrderStatus_NEW,
+				Version:    2,
+			},
+		},
+		{
+			name: "Update Order Status Success - Cancelled At Version",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.UpdateOrderStatusRequest{
+					Id:      "1",
+					Status:  pb.OrderStatus_CANCELLED,
+					Version: proto.Uint64(1),
+				},
+			},
+			want: &pb.UpdateOrderStatusResponse{
+				Id:         "1",
+				CustomerId: "1",
+				Status:     pb.OrderStatus_CANCELLED,
+				Version:    2,
+			},
+		},
+		{
+			name: "Update Order Status Error - Stale Version",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.UpdateOrderStatusRequest{
+					Id:      "1",
+					Status:  pb.OrderStatus_CANCELLED,
+					Version: proto.Uint64(3),
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "Update Order Status Error - Unknown Status",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.UpdateOrderStatusRequest{
+					Id:     "1",
+					Status: pb.OrderStatus_UNKNOWN,
+				},
 			},
+			want:    nil,
+			wantErr: true,
 		},
 		{
 			name: "Update Order Status Error",
@@ -324,6 +615,53 @@ func TestGRPCServer_UpdateOrderStatus(t *testing.T) {
 	}
 }
 
+func TestGRPCServer_OrderStatusRoundTrip(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.OrderRepository.UpdateOrderStatusFunc = mockUpdateOrderStatusFunc
+
+	for name, value := range pb.OrderStatus_value {
+		want := pb.OrderStatus(value)
+		t.Run(name, func(t *testing.T) {
+			got, err := s.UpdateOrderStatus(context.Background(), &pb.UpdateOrderStatusRequest{
+				Id:     "1",
+				Status: want,
+			})
+
+			if want == pb.OrderStatus_UNKNOWN {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Errorf("GRPCServer.UpdateOrderStatus() code = %v, want %v", status.Code(err), codes.InvalidArgument)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GRPCServer.UpdateOrderStatus() error = %v", err)
+			}
+			if got.GetStatus() != want {
+				t.Errorf("GRPCServer.UpdateOrderStatus() status = %v, want %v", got.GetStatus(), want)
+			}
+		})
+	}
+
+	for _, orderStatus := range append(pkg.OrderStatuses(), pkg.OrderStatus("unknown")) {
+		orderStatus := orderStatus
+		t.Run(string(orderStatus), func(t *testing.T) {
+			s.OrderRepository.GetOrderFunc = func(ctx context.Context, id string) (*repository.Order, error) {
+				return &repository.Order{Id: id, OrderStatus: orderStatus}, nil
+			}
+
+			_, err := s.GetOrder(context.Background(), &pb.GetOrderRequest{Id: "1"})
+			if orderStatus.IsValid() && err != nil {
+				t.Errorf("GRPCServer.GetOrder() error = %v", err)
+			}
+			if !orderStatus.IsValid() && status.Code(err) != codes.Internal {
+				t.Errorf("GRPCServer.GetOrder() code = %v, want %v", status.Code(err), codes.Internal)
+			}
+		})
+	}
+}
+
 func mockDeleteOrderFunc(ctx context.Context, id string) error {
 	if id == ERROR_ORDER_TRIGGER {
 		return service.Errorf(service.INVALID_ERROR, "intentional error")
@@ -385,11 +723,100 @@ func TestGRPCServer_DeleteOrder(t *testing.T) {
 	}
 }
 
+func mockGetOrderHistoryFunc(ctx context.Context, orderId string) ([]*repository.OrderStatusChange, error) {
+	if orderId == ERROR_ORDER_TRIGGER {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "intentional error")
+	}
+
+	return []*repository.OrderStatusChange{
+		{
+			Id:         "1",
+			OrderId:    orderId,
+			FromStatus: pkg.OrderStatusNew,
+			ToStatus:   pkg.OrderStatusProcessing,
+			Actor:      "checkout",
+			Reason:     "checkout started",
+			Version:    2,
+			CreatedAt:  "2023-10-01T10:00:00Z",
+		},
+	}, nil
+}
+
+func TestGRPCServer_GetOrderHistory(t *testing.T) {
+
+	s := NewTestGRPCServer(t)
+
+	s.OrderRepository.GetOrderHistoryFunc = mockGetOrderHistoryFunc
+
+	type args struct {
+		ctx context.Context
+		in  *pb.GetOrderHistoryRequest
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *pb.GetOrderHistoryResponse
+		wantErr bool
+	}{
+		{
+			name: "Get Order History Success",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.GetOrderHistoryRequest{
+					OrderId: "1",
+				},
+			},
+			want: &pb.GetOrderHistoryResponse{
+				Changes: []*pb.OrderStatusChange{
+					{
+						Id:         "1",
+						OrderId:    "1",
+						FromStatus: pb.OrderStatus_NEW,
+						ToStatus:   pb.OrderStatus_PROCESSING,
+						Actor:      "checkout",
+						Reason:     "checkout started",
+						Version:    2,
+						CreatedAt:  timestamppb.New(time.Date(2023, 10, 1, 10, 0, 0, 0, time.UTC)),
+					},
+				},
+			},
+		},
+		{
+			name: "Get Order History Error",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.GetOrderHistoryRequest{
+					OrderId: ERROR_ORDER_TRIGGER,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := s.GetOrderHistory(tt.args.ctx, tt.args.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.GetOrderHistory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("GRPCServer.GetOrderHistory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func mockCreateOrderItemFunc(ctx context.Context, orderId string, item *repository.OrderItem) (*repository.OrderItem, error) {
 	if orderId == ERROR_ORDER_TRIGGER {
 		return nil, service.Errorf(service.INVALID_ERROR, "intentional error")
 	}
 
+	if item.UnitPrice.IsZero() {
+		return nil, service.Errorf(service.INVALID_ERROR, "item of product %q is not priced", item.ProductId)
+	}
+
 	return &repository.OrderItem{
 		Id:        "1",
 		ProductId: item.ProductId,
@@ -402,6 +829,7 @@ func TestGRPCServer_CreateOrderItem(t *testing.T) {
 	s := NewTestGRPCServer(t)
 
 	s.OrderRepository.CreateOrderItemFunc = mockCreateOrderItemFunc
+	s.ProductRepository.GetProductFunc = mockGetProductFunc
 
 	type args struct {
 		ctx context.Context
@@ -441,6 +869,19 @@ func TestGRPCServer_CreateOrderItem(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "Create Order Item Error - Product",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.CreateOrderItemRequest{
+					OrderId:   "1",
+					ProductId: ERROR_PRODUCT_TRIGGER,
+					Quantity:  1,
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
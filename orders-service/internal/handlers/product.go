@@ -8,6 +8,7 @@ import (
 	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
 	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 )
 
 func (s *GRPCServer) CreateProduct(ctx context.Context, in *pb.CreateProductRequest) (*pb.CreateProductResponse, error) {
@@ -17,7 +18,9 @@ func (s *GRPCServer) CreateProduct(ctx context.Context, in *pb.CreateProductRequ
 	p, err := s.ProductRepository.CreateProduct(ctx, &repository.Product{
 		Name:        in.GetName(),
 		Description: in.GetDescription(),
-		Price:       uint(in.GetPrice()),
+		Price:       getMoney(in.GetPrice()),
+		Category:    in.GetCategory(),
+		Stock:       getStock(in.Stock),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
@@ -41,7 +44,10 @@ func (s *GRPCServer) GetProduct(ctx context.Context, in *pb.GetProductRequest) (
 		Id:          p.Id,
 		Name:        p.Name,
 		Description: p.Description,
-		Price:       uint32(p.Price),
+		Price:       getGRPCMoney(p.Price),
+		Category:    p.Category,
+		Stock:       getGRPCStock(p.Stock),
+		Reserved:    uint32(p.Reserved),
 	}, nil
 }
 
@@ -49,9 +55,20 @@ func (s *GRPCServer) ListProducts(ctx context.Context, in *pb.ListProductsReques
 
 	log.Printf("Received: %v", in)
 
-	products, err := s.ProductRepository.ListProducts(ctx)
+	filter := &repository.ProductFilter{
+		NamePrefix: in.GetNamePrefix(),
+		Currency:   in.GetCurrency(),
+		MinPrice:   in.MinPrice,
+		MaxPrice:   in.MaxPrice,
+	}
+
+	products, next, err := s.ProductRepository.ListProducts(ctx, filter, &repository.ListOptions{
+		PageSize:  int(in.GetPageSize()),
+		PageToken: in.GetPageToken(),
+		OrderBy:   in.GetOrderBy(),
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list products: %w", err)
+		return nil, Error(fmt.Errorf("failed to list products: %w", err))
 	}
 
 	var responseProducts []*pb.Product
@@ -60,12 +77,16 @@ func (s *GRPCServer) ListProducts(ctx context.Context, in *pb.ListProductsReques
 			Id:          p.Id,
 			Name:        p.Name,
 			Description: p.Description,
-			Price:       uint32(p.Price),
+			Price:       getGRPCMoney(p.Price),
+			Category:    p.Category,
+			Stock:       getGRPCStock(p.Stock),
+			Reserved:    uint32(p.Reserved),
 		})
 	}
 
 	return &pb.ListProductsResponse{
-		Products: responseProducts,
+		Products:      responseProducts,
+		NextPageToken: next,
 	}, nil
 }
 
@@ -73,11 +94,20 @@ func (s *GRPCServer) UpdateProduct(ctx context.Context, in *pb.UpdateProductRequ
 
 	log.Printf("Received: %v", in)
 
-	p, err := s.ProductRepository.UpdateProduct(ctx, in.GetId(), &repository.ProductUpdate{
+	update := &repository.ProductUpdate{
 		Name:        pkg.StringPtr(in.GetUpdate().GetName()),
 		Description: pkg.StringPtr(in.GetUpdate().GetDescription()),
-		Price:       pkg.UintPtr(uint(in.GetUpdate().GetPrice())),
-	})
+	}
+	if in.GetUpdate().GetPrice() != nil {
+		price := getMoney(in.GetUpdate().GetPrice())
+		update.Price = &price
+	}
+	if in.GetUpdate().Category != nil {
+		update.Category = pkg.StringPtr(in.GetUpdate().GetCategory())
+	}
+	update.Stock = getStock(in.GetUpdate().Stock)
+
+	p, err := s.ProductRepository.UpdateProduct(ctx, in.GetId(), update)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
@@ -86,7 +116,10 @@ func (s *GRPCServer) UpdateProduct(ctx context.Context, in *pb.UpdateProductRequ
 		Id:          p.Id,
 		Name:        p.Name,
 		Description: p.Description,
-		Price:       uint32(p.Price),
+		Price:       getGRPCMoney(p.Price),
+		Category:    p.Category,
+		Stock:       getGRPCStock(p.Stock),
+		Reserved:    uint32(p.Reserved),
 	}, nil
 }
 
@@ -101,3 +134,37 @@ func (s *GRPCServer) DeleteProduct(ctx context.Context, in *pb.DeleteProductRequ
 
 	return &pb.DeleteProductResponse{}, nil
 }
+
+// getGRPCMoney converts an amount of a response. The zero Money is left unset.
+func getGRPCMoney(m money.Money) *pb.Money {
+	if m == (money.Money{}) {
+		return nil
+	}
+
+	return &pb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+// getMoney converts an amount of a request. A missing amount is no money.
+func getMoney(m *pb.Money) money.Money {
+	return money.New(m.GetAmount(), m.GetCurrency())
+}
+
+// getGRPCStock converts the stock of a response. The stock of products whose
+// stock is not tracked is left unset.
+func getGRPCStock(stock *uint) *uint32 {
+	if stock == nil {
+		return nil
+	}
+
+	v := uint32(*stock)
+	return &v
+}
+
+// getStock converts the stock of a request. A missing stock is nil.
+func getStock(stock *uint32) *uint {
+	if stock == nil {
+		return nil
+	}
+
+	return pkg.UintPtr(uint(*stock))
+}
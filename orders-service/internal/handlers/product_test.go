@@ -8,6 +8,8 @@ import (
 
 	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -22,7 +24,7 @@ func mockCreateProductFunc(ctx context.Context, p *repository.Product) (*reposit
 		Id:          "1",
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100,
+		Price:       money.New(10000, "KES"),
 	}, nil
 }
 
@@ -43,7 +45,7 @@ func TestGRPCServer_CreateProduct(t *testing.T) {
 			request: &pb.CreateProductRequest{
 				Name:        "Test Product",
 				Description: "Test Description",
-				Price:       100,
+				Price:       &pb.Money{Amount: 10000, Currency: "KES"},
 			},
 			want: &pb.CreateProductResponse{
 				Id: "1",
@@ -55,7 +57,7 @@ func TestGRPCServer_CreateProduct(t *testing.T) {
 			request: &pb.CreateProductRequest{
 				Name:        "Error Product",
 				Description: "Test Description",
-				Price:       100,
+				Price:       &pb.Money{Amount: 10000, Currency: "KES"},
 			},
 			wantErr: true,
 		},
@@ -84,7 +86,8 @@ func mockGetProductFunc(ctx context.Context, id string) (*repository.Product, er
 		Id:          "1",
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100,
+		Price:       money.New(10000, "KES"),
+		Category:    "food",
 	}, nil
 }
 
@@ -116,7 +119,8 @@ func TestGRPCServer_GetProduct(t *testing.T) {
 				Id:          "1",
 				Name:        "Test Product",
 				Description: "Test Description",
-				Price:       100,
+				Price:       &pb.Money{Amount: 10000, Currency: "KES"},
+				Category:    "food",
 			},
 			wantErr: false,
 		},
@@ -145,15 +149,29 @@ func TestGRPCServer_GetProduct(t *testing.T) {
 	}
 }
 
-func mockListProductsFunc(ctx context.Context) ([]*repository.Product, error) {
+func mockListProductsFunc(
+	ctx context.Context, filter *repository.ProductFilter, opts *repository.ListOptions) ([]*repository.Product, string, error) {
+	if opts.OrderBy == "description" {
+		return nil, "", errors.New("intentional error")
+	}
+
+	if filter.MinPrice != nil && *filter.MinPrice > 10000 {
+		return nil, "", nil
+	}
+
+	var next string
+	if opts.PageSize == 1 {
+		next = "next-page"
+	}
+
 	return []*repository.Product{
 		{
 			Id:          "1",
 			Name:        "Test Product",
 			Description: "Test Description",
-			Price:       100,
+			Price:       money.New(10000, "KES"),
 		},
-	}, nil
+	}, next, nil
 }
 
 func TestGRPCServer_ListProducts(t *testing.T) {
@@ -184,11 +202,46 @@ func TestGRPCServer_ListProducts(t *testing.T) {
 						Id:          "1",
 						Name:        "Test Product",
 						Description: "Test Description",
-						Price:       100,
+						Price:       &pb.Money{Amount: 10000, Currency: "KES"},
 					},
 				},
 			},
 		},
+		{
+			name: "List Products Success - Next Page",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListProductsRequest{PageSize: 1},
+			},
+			want: &pb.ListProductsResponse{
+				Products: []*pb.Product{
+					{
+						Id:          "1",
+						Name:        "Test Product",
+						Description: "Test Description",
+						Price:       &pb.Money{Amount: 10000, Currency: "KES"},
+					},
+				},
+				NextPageToken: "next-page",
+			},
+		},
+		{
+			name: "List Products Success - Filtered By Price",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListProductsRequest{MinPrice: proto.Int64(20000)},
+			},
+			want: &pb.ListProductsResponse{},
+		},
+		{
+			name: "List Products Error",
+			args: args{
+				ctx: context.Background(),
+				in:  &pb.ListProductsRequest{OrderBy: "description"},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -208,12 +261,23 @@ func mockUpdateProductFunc(ctx context.Context, id string, update *repository.Pr
 	if id == ERROR_PRODUCT_TRIGGER {
 		return nil, errors.New("intentional error")
 	}
-	return &repository.Product{
+	product := &repository.Product{
 		Id:          "1",
 		Name:        "Test Product",
 		Description: "Test Description",
-		Price:       100,
-	}, nil
+		Price:       money.New(10000, "KES"),
+		Reserved:    1,
+	}
+	if update.Price != nil {
+		product.Price = *update.Price
+	}
+	if update.Category != nil {
+		product.Category = *update.Category
+	}
+	if update.Stock != nil {
+		product.Stock = update.Stock
+	}
+	return product, nil
 }
 
 func TestGRPCServer_UpdateProduct(t *testing.T) {
@@ -240,7 +304,50 @@ func TestGRPCServer_UpdateProduct(t *testing.T) {
 					Update: &pb.ProductUpdate{
 						Name:        "Updated Test Product",
 						Description: "Updated Test Description",
-						Price:       200,
+						Price:       &pb.Money{Amount: 20000, Currency: "KES"},
+					},
+				},
+			},
+			want: &pb.UpdateProductResponse{
+				Id:          "1",
+				Name:        "Test Product",
+				Description: "Test Description",
+				Price:       &pb.Money{Amount: 20000, Currency: "KES"},
+				Reserved:    1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Update Product Success - Stock",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.UpdateProductRequest{
+					Id: "1",
+					Update: &pb.ProductUpdate{
+						Name:  "Updated Test Product",
+						Stock: proto.Uint32(7),
+					},
+				},
+			},
+			want: &pb.UpdateProductResponse{
+				Id:          "1",
+				Name:        "Test Product",
+				Description: "Test Description",
+				Price:       &pb.Money{Amount: 10000, Currency: "KES"},
+				Stock:       proto.Uint32(7),
+				Reserved:    1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Update Product Success - Category",
+			args: args{
+				ctx: context.Background(),
+				in: &pb.UpdateProductRequest{
+					Id: "1",
+					Update: &pb.ProductUpdate{
+						Name:     "Updated Test Product",
+						Category: proto.String("medical"),
 					},
 				},
 			},
@@ -248,7 +355,9 @@ func TestGRPCServer_UpdateProduct(t *testing.T) {
 				Id:          "1",
 				Name:        "Test Product",
 				Description: "Test Description",
-				Price:       100,
+				Price:       &pb.Money{Amount: 10000, Currency: "KES"},
+				Category:    "medical",
+				Reserved:    1,
 			},
 			wantErr: false,
 		},
@@ -261,7 +370,7 @@ func TestGRPCServer_UpdateProduct(t *testing.T) {
 					Update: &pb.ProductUpdate{
 						Name:        "Updated Test Product",
 						Description: "Updated Test Description",
-						Price:       200,
+						Price:       &pb.Money{Amount: 20000, Currency: "KES"},
 					},
 				},
 			},
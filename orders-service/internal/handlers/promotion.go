@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *GRPCServer) CreatePromotion(
+	ctx context.Context, in *pb.CreatePromotionRequest) (*pb.CreatePromotionResponse, error) {
+
+	log.Printf("Received: %v", in.GetCode())
+
+	rule, err := getPromotionRule(in.GetRule())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to create promotion: %w", err))
+	}
+
+	p, err := s.PromotionRepository.CreatePromotion(ctx, &repository.Promotion{
+		Code:             in.GetCode(),
+		Description:      in.GetDescription(),
+		Rule:             rule,
+		StartsAt:         getTimestamp(in.GetStartsAt()),
+		EndsAt:           getTimestamp(in.GetEndsAt()),
+		UsageLimit:       uint(in.GetUsageLimit()),
+		PerCustomerLimit: uint(in.GetPerCustomerLimit()),
+	})
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to create promotion: %w", err))
+	}
+
+	return &pb.CreatePromotionResponse{
+		Id: p.Id,
+	}, nil
+}
+
+func (s *GRPCServer) GetPromotion(
+	ctx context.Context, in *pb.GetPromotionRequest) (*pb.GetPromotionResponse, error) {
+
+	log.Printf("Received: %v", in.GetId())
+
+	p, err := s.PromotionRepository.GetPromotion(ctx, in.GetId())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to get promotion: %w", err))
+	}
+
+	promotion, err := getGRPCPromotion(p)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to get promotion: %w", err))
+	}
+
+	return &pb.GetPromotionResponse{
+		Promotion: promotion,
+	}, nil
+}
+
+func (s *GRPCServer) ListPromotions(
+	ctx context.Context, in *pb.ListPromotionsRequest) (*pb.ListPromotionsResponse, error) {
+
+	log.Printf("Received: %v", in)
+
+	promotions, next, err := s.PromotionRepository.ListPromotions(ctx, &repository.ListOptions{
+		PageSize:  int(in.GetPageSize()),
+		PageToken: in.GetPageToken(),
+		OrderBy:   in.GetOrderBy(),
+	})
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to list promotions: %w", err))
+	}
+
+	var responsePromotions []*pb.Promotion
+	for _, p := range promotions {
+		promotion, err := getGRPCPromotion(p)
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to list promotions: %w", err))
+		}
+
+		responsePromotions = append(responsePromotions, promotion)
+	}
+
+	return &pb.ListPromotionsResponse{
+		Promotions:    responsePromotions,
+		NextPageToken: next,
+	}, nil
+}
+
+func (s *GRPCServer) UpdatePromotion(
+	ctx context.Context, in *pb.UpdatePromotionRequest) (*pb.UpdatePromotionResponse, error) {
+
+	log.Printf("Received: %v", in)
+
+	update := &repository.PromotionUpdate{
+		Description: in.GetUpdate().Description,
+	}
+
+	if v := in.GetUpdate().GetStartsAt(); v != nil {
+		update.StartsAt = pkg.StringPtr(getTimestamp(v))
+	}
+
+	if v := in.GetUpdate().GetEndsAt(); v != nil {
+		update.EndsAt = pkg.StringPtr(getTimestamp(v))
+	}
+
+	if v := in.GetUpdate().UsageLimit; v != nil {
+		update.UsageLimit = pkg.UintPtr(uint(*v))
+	}
+
+	if v := in.GetUpdate().PerCustomerLimit; v != nil {
+		update.PerCustomerLimit = pkg.UintPtr(uint(*v))
+	}
+
+	p, err := s.PromotionRepository.UpdatePromotion(ctx, in.GetId(), update)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to update promotion: %w", err))
+	}
+
+	promotion, err := getGRPCPromotion(p)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to update promotion: %w", err))
+	}
+
+	return &pb.UpdatePromotionResponse{
+		Promotion: promotion,
+	}, nil
+}
+
+func (s *GRPCServer) DeletePromotion(
+	ctx context.Context, in *pb.DeletePromotionRequest) (*pb.DeletePromotionResponse, error) {
+
+	log.Printf("Received: %v", in.GetId())
+
+	err := s.PromotionRepository.DeletePromotion(ctx, in.GetId())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to delete promotion: %w", err))
+	}
+
+	return &pb.DeletePromotionResponse{}, nil
+}
+
+func (s *GRPCServer) ApplyPromotion(
+	ctx context.Context, in *pb.ApplyPromotionRequest) (*pb.ApplyPromotionResponse, error) {
+
+	o, err := s.PromotionService.ApplyPromotion(ctx, in.GetOrderId(), in.GetCode())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to apply promotion: %w", err))
+	}
+
+	status, err := getGRPCOrderStatus(o.OrderStatus)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to apply promotion: %w", err))
+	}
+
+	return &pb.ApplyPromotionResponse{
+		OrderId:       o.Id,
+		CustomerId:    o.CustomerId,
+		Status:        status,
+		PromotionCode: o.PromotionCode,
+		Subtotal:      getGRPCMoney(o.Subtotal),
+		Tax:           getGRPCMoney(o.Tax),
+		Discount:      getGRPCMoney(o.Discount),
+		Total:         getGRPCMoney(o.Total),
+	}, nil
+}
+
+func getGRPCPromotion(p *repository.Promotion) (*pb.Promotion, error) {
+	promotionType, err := getGRPCPromotionType(p.Rule.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamps := make([]*timestamppb.Timestamp, 4)
+	for i, ts := range []string{p.StartsAt, p.EndsAt, p.CreatedAt, p.UpdatedAt} {
+		if timestamps[i], err = getGRPCTimestamp(ts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &pb.Promotion{
+		Id:          p.Id,
+		Code:        p.Code,
+		Description: p.Description,
+		Rule: &pb.PromotionRule{
+			Type:        promotionType,
+			Percentage:  uint32(p.Rule.Percentage),
+			AmountOff:   getGRPCMoney(p.Rule.AmountOff),
+			ProductId:   p.Rule.ProductId,
+			BuyQuantity: uint32(p.Rule.BuyQuantity),
+			GetQuantity: uint32(p.Rule.GetQuantity),
+		},
+		StartsAt:         timestamps[0],
+		EndsAt:           timestamps[1],
+		UsageLimit:       uint32(p.UsageLimit),
+		PerCustomerLimit: uint32(p.PerCustomerLimit),
+		Redemptions:      uint32(p.Redemptions),
+		CreatedAt:        timestamps[2],
+		UpdatedAt:        timestamps[3],
+	}, nil
+}
+
+func getPromotionRule(rule *pb.PromotionRule) (repository.PromotionRule, error) {
+	promotionType, err := getPromotionType(rule.GetType())
+	if err != nil {
+		return repository.PromotionRule{}, err
+	}
+
+	return repository.PromotionRule{
+		Type:        promotionType,
+		Percentage:  uint(rule.GetPercentage()),
+		AmountOff:   getMoney(rule.GetAmountOff()),
+		ProductId:   rule.GetProductId(),
+		BuyQuantity: uint(rule.GetBuyQuantity()),
+		GetQuantity: uint(rule.GetGetQuantity()),
+	}, nil
+}
+
+// getGRPCTimestamp converts an RFC3339 timestamp of a response. An empty
+// timestamp is left unset.
+func getGRPCTimestamp(ts string) (*timestamppb.Timestamp, error) {
+	if ts == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "invalid timestamp %q: %v", ts, err)
+	}
+
+	return timestamppb.New(t), nil
+}
+
+// getTimestamp converts a timestamp of a request. A missing timestamp is empty.
+func getTimestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+
+	return ts.AsTime().Format(time.RFC3339)
+}
+
+// promotionTypes maps every promotion type to its gRPC counterpart. Both
+// directions are derived from this table so that the mapping round-trips.
+var promotionTypes = map[repository.PromotionType]pb.PromotionType{
+	repository.PromotionTypePercentage:  pb.PromotionType_PERCENTAGE,
+	repository.PromotionTypeFixedAmount: pb.PromotionType_FIXED_AMOUNT,
+	repository.PromotionTypeBuyXGetY:    pb.PromotionType_BUY_X_GET_Y,
+	repository.PromotionTypeFreeItem:    pb.PromotionType_FREE_ITEM,
+}
+
+func getGRPCPromotionType(t repository.PromotionType) (pb.PromotionType, error) {
+	grpcType, ok := promotionTypes[t]
+	if !ok {
+		return pb.PromotionType_PROMOTION_TYPE_UNSPECIFIED,
+			service.Errorf(service.INTERNAL_ERROR, "unknown promotion type %q", t)
+	}
+
+	return grpcType, nil
+}
+
+func getPromotionType(t pb.PromotionType) (repository.PromotionType, error) {
+	for promotionType, grpcType := range promotionTypes {
+		if grpcType == t {
+			return promotionType, nil
+		}
+	}
+
+	return "", service.Errorf(service.INVALID_ERROR, "unknown promotion type %v", t)
+}
@@ -0,0 +1,409 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/promotions"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	ERROR_PROMOTION_TRIGGER = "Error Promotion"
+)
+
+func newTestPromotion() *repository.Promotion {
+	return &repository.Promotion{
+		Id:          "1",
+		Code:        "SAVE10",
+		Description: "10% off",
+		Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10},
+		EndsAt:      "2124-01-01T00:00:00Z",
+		UsageLimit:  100,
+		Redemptions: 3,
+		CreatedAt:   "2024-01-01T00:00:00Z",
+		UpdatedAt:   "2024-01-02T00:00:00Z",
+	}
+}
+
+func newTestGRPCPromotion() *pb.Promotion {
+	return &pb.Promotion{
+		Id:          "1",
+		Code:        "SAVE10",
+		Description: "10% off",
+		Rule:        &pb.PromotionRule{Type: pb.PromotionType_PERCENTAGE, Percentage: 10},
+		EndsAt:      timestamppb.New(time.Date(2124, 1, 1, 0, 0, 0, 0, time.UTC)),
+		UsageLimit:  100,
+		Redemptions: 3,
+		CreatedAt:   timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:   timestamppb.New(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+	}
+}
+
+func mockCreatePromotionFunc(ctx context.Context, p *repository.Promotion) (*repository.Promotion, error) {
+	if p.Code == ERROR_PROMOTION_TRIGGER {
+		return nil, errors.New("intentional error")
+	}
+
+	want := &repository.Promotion{
+		Code:       "BOGOF",
+		Rule:       repository.PromotionRule{Type: repository.PromotionTypeBuyXGetY, ProductId: "1", BuyQuantity: 1, GetQuantity: 1},
+		StartsAt:   "2024-01-01T00:00:00Z",
+		UsageLimit: 10,
+	}
+	if !reflect.DeepEqual(p, want) {
+		return nil, errors.New("unexpected promotion")
+	}
+
+	return &repository.Promotion{Id: "1"}, nil
+}
+
+func TestGRPCServer_CreatePromotion(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.PromotionRepository.CreatePromotionFunc = mockCreatePromotionFunc
+
+	rule := &pb.PromotionRule{Type: pb.PromotionType_BUY_X_GET_Y, ProductId: "1", BuyQuantity: 1, GetQuantity: 1}
+
+	tests := []struct {
+		name     string
+		request  *pb.CreatePromotionRequest
+		want     *pb.CreatePromotionResponse
+		wantCode codes.Code
+	}{
+		{
+			name: "Create Promotion Success",
+			request: &pb.CreatePromotionRequest{
+				Code:       "BOGOF",
+				Rule:       rule,
+				StartsAt:   timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+				UsageLimit: 10,
+			},
+			want: &pb.CreatePromotionResponse{
+				Id: "1",
+			},
+		},
+		{
+			name: "Create Promotion Failed - Unspecified Type",
+			request: &pb.CreatePromotionRequest{
+				Code: "BOGOF",
+				Rule: &pb.PromotionRule{Percentage: 10},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "Create Promotion Error",
+			request: &pb.CreatePromotionRequest{
+				Code: ERROR_PROMOTION_TRIGGER,
+				Rule: rule,
+			},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.CreatePromotion(context.Background(), tt.request)
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Errorf("GRPCServer.CreatePromotion() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GRPCServer.CreatePromotion() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.CreatePromotion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mockGetPromotionFunc(ctx context.Context, id string) (*repository.Promotion, error) {
+	if id == ERROR_PROMOTION_TRIGGER {
+		return nil, errors.New("intentional error")
+	}
+
+	return newTestPromotion(), nil
+}
+
+func TestGRPCServer_GetPromotion(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.PromotionRepository.GetPromotionFunc = mockGetPromotionFunc
+
+	tests := []struct {
+		name    string
+		request *pb.GetPromotionRequest
+		want    *pb.GetPromotionResponse
+		wantErr bool
+	}{
+		{
+			name:    "Get Promotion Success",
+			request: &pb.GetPromotionRequest{Id: "1"},
+			want:    &pb.GetPromotionResponse{Promotion: newTestGRPCPromotion()},
+		},
+		{
+			name:    "Get Promotion Error",
+			request: &pb.GetPromotionRequest{Id: ERROR_PROMOTION_TRIGGER},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.GetPromotion(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.GetPromotion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.GetPromotion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mockListPromotionsFunc(ctx context.Context, opts *repository.ListOptions) ([]*repository.Promotion, string, error) {
+	if opts.OrderBy == "description" {
+		return nil, "", errors.New("intentional error")
+	}
+
+	var next string
+	if opts.PageSize == 1 {
+		next = "next-page"
+	}
+
+	return []*repository.Promotion{newTestPromotion()}, next, nil
+}
+
+func TestGRPCServer_ListPromotions(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.PromotionRepository.ListPromotionsFunc = mockListPromotionsFunc
+
+	tests := []struct {
+		name    string
+		request *pb.ListPromotionsRequest
+		want    *pb.ListPromotionsResponse
+		wantErr bool
+	}{
+		{
+			name:    "List Promotions Success",
+			request: &pb.ListPromotionsRequest{},
+			want:    &pb.ListPromotionsResponse{Promotions: []*pb.Promotion{newTestGRPCPromotion()}},
+		},
+		{
+			name:    "List Promotions Success - Next Page",
+			request: &pb.ListPromotionsRequest{PageSize: 1},
+			want: &pb.ListPromotionsResponse{
+				Promotions:    []*pb.Promotion{newTestGRPCPromotion()},
+				NextPageToken: "next-page",
+			},
+		},
+		{
+			name:    "List Promotions Error",
+			request: &pb.ListPromotionsRequest{OrderBy: "description"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.ListPromotions(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.ListPromotions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.ListPromotions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mockUpdatePromotionFunc(
+	ctx context.Context, id string, update *repository.PromotionUpdate) (*repository.Promotion, error) {
+
+	if id == ERROR_PROMOTION_TRIGGER {
+		return nil, errors.New("intentional error")
+	}
+
+	// Only the fields set in the request are updated.
+	if update.Description != nil || update.StartsAt != nil || update.PerCustomerLimit != nil {
+		return nil, errors.New("unexpected update")
+	}
+
+	p := newTestPromotion()
+	p.EndsAt = *update.EndsAt
+	p.UsageLimit = *update.UsageLimit
+
+	return p, nil
+}
+
+func TestGRPCServer_UpdatePromotion(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.PromotionRepository.UpdatePromotionFunc = mockUpdatePromotionFunc
+
+	usageLimit := uint32(200)
+	endsAt := time.Date(2125, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	want := newTestGRPCPromotion()
+	want.EndsAt = timestamppb.New(endsAt)
+	want.UsageLimit = 200
+
+	tests := []struct {
+		name    string
+		request *pb.UpdatePromotionRequest
+		want    *pb.UpdatePromotionResponse
+		wantErr bool
+	}{
+		{
+			name: "Update Promotion Success",
+			request: &pb.UpdatePromotionRequest{
+				Id: "1",
+				Update: &pb.PromotionUpdate{
+					EndsAt:     timestamppb.New(endsAt),
+					UsageLimit: &usageLimit,
+				},
+			},
+			want: &pb.UpdatePromotionResponse{Promotion: want},
+		},
+		{
+			name: "Update Promotion Error",
+			request: &pb.UpdatePromotionRequest{
+				Id:     ERROR_PROMOTION_TRIGGER,
+				Update: &pb.PromotionUpdate{UsageLimit: &usageLimit},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.UpdatePromotion(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.UpdatePromotion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.UpdatePromotion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mockDeletePromotionFunc(ctx context.Context, id string) error {
+	if id == ERROR_PROMOTION_TRIGGER {
+		return errors.New("intentional error")
+	}
+
+	return nil
+}
+
+func TestGRPCServer_DeletePromotion(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.PromotionRepository.DeletePromotionFunc = mockDeletePromotionFunc
+
+	tests := []struct {
+		name    string
+		request *pb.DeletePromotionRequest
+		wantErr bool
+	}{
+		{
+			name:    "Delete Promotion Success",
+			request: &pb.DeletePromotionRequest{Id: "1"},
+		},
+		{
+			name:    "Delete Promotion Error",
+			request: &pb.DeletePromotionRequest{Id: ERROR_PROMOTION_TRIGGER},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.GRPCServer.DeletePromotion(context.Background(), tt.request); (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.DeletePromotion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGRPCServer_ApplyPromotion(t *testing.T) {
+	ctx := context.Background()
+	s := NewTestGRPCServer(t)
+
+	promotionRepository := memory.NewPromotionRepository()
+	orderRepository := memory.NewOrderRepository()
+	s.GRPCServer.PromotionService = promotions.NewPromotionService(promotionRepository, orderRepository)
+
+	_, err := promotionRepository.CreatePromotion(ctx, newTestPromotion())
+	if err != nil {
+		t.Fatalf("failed to create promotion: %v", err)
+	}
+
+	order, err := orderRepository.CreateOrder(ctx, &repository.Order{
+		CustomerId: "1",
+		Items:      []*repository.OrderItem{{ProductId: "1", Quantity: 2, UnitPrice: money.New(500, "KES")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		request  *pb.ApplyPromotionRequest
+		want     *pb.ApplyPromotionResponse
+		wantCode codes.Code
+	}{
+		{
+			name:    "Apply Promotion Success",
+			request: &pb.ApplyPromotionRequest{OrderId: order.Id, Code: "SAVE10"},
+			want: &pb.ApplyPromotionResponse{
+				OrderId:       order.Id,
+				CustomerId:    "1",
+				Status:        pb.OrderStatus_NEW,
+				PromotionCode: "SAVE10",
+				Subtotal:      &pb.Money{Amount: 1000, Currency: "KES"},
+				Tax:           &pb.Money{Currency: "KES"},
+				Discount:      &pb.Money{Amount: 100, Currency: "KES"},
+				Total:         &pb.Money{Amount: 900, Currency: "KES"},
+			},
+		},
+		{
+			name:     "Apply Promotion Failed - Unknown Code",
+			request:  &pb.ApplyPromotionRequest{OrderId: order.Id, Code: "UNKNOWN"},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "Apply Promotion Failed - Without Code",
+			request:  &pb.ApplyPromotionRequest{OrderId: order.Id},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.ApplyPromotion(ctx, tt.request)
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Errorf("GRPCServer.ApplyPromotion() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GRPCServer.ApplyPromotion() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.ApplyPromotion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
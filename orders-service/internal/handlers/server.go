@@ -6,10 +6,13 @@ import (
 	"log"
 	"net"
 	"sync"
+	"time"
 
 	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
 	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/internal/watch"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
 	"google.golang.org/grpc"
 )
 
@@ -21,11 +24,28 @@ type GRPCServer struct {
 	mu         sync.Mutex // synchronizes access to the grpcServer
 
 	// Internal services &  repositories
-	CheckoutService service.CheckoutService
+	CheckoutService  service.CheckoutService
+	PromotionService service.PromotionService
+	WebhookService   service.WebhookService
 
-	ProductRepository  repository.ProductRepository
-	CustomerRepository repository.CustomerRepository
-	OrderRepository    repository.OrderRepository
+	ProductRepository   repository.ProductRepository
+	CustomerRepository  repository.CustomerRepository
+	OrderRepository     repository.OrderRepository
+	PromotionRepository repository.PromotionRepository
+	WebhookRepository   repository.WebhookRepository
+
+	// TaxRates decides the tax rate of order items as they are added.
+	TaxRates repository.TaxRates
+
+	// IdempotencyStore records the requests that carry an idempotency key
+	// for IdempotencyTTL, or idempotency.DefaultTTL if it is zero. Keys are
+	// ignored without it.
+	IdempotencyStore idempotency.Store
+	IdempotencyTTL   time.Duration
+
+	// Watch delivers the order events that WatchOrder and WatchCustomerOrders
+	// stream. They fail with UNIMPLEMENTED without it.
+	Watch *watch.Broker
 }
 
 // NewGRPCServer creates a new instance of GRPCServer.
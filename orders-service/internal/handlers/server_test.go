@@ -10,6 +10,7 @@ import (
 	"github.com/Mik3y-F/order-management-system/orders/internal/handlers"
 	grpc_handlers "github.com/Mik3y-F/order-management-system/orders/internal/handlers"
 	"github.com/Mik3y-F/order-management-system/orders/internal/mock"
+	idempotency_mock "github.com/Mik3y-F/order-management-system/pkg/idempotency/mock"
 )
 
 const (
@@ -23,6 +24,11 @@ type TestGRPCServer struct {
 	ProductRepository  mock.ProductRepository
 	CustomerRepository mock.CustomerRepository
 	OrderRepository    mock.OrderRepository
+
+	PromotionRepository mock.PromotionRepository
+	WebhookRepository   mock.WebhookRepository
+
+	IdempotencyStore idempotency_mock.Store
 }
 
 func NewTestGRPCServer(tb testing.TB) *TestGRPCServer {
@@ -36,6 +42,9 @@ func NewTestGRPCServer(tb testing.TB) *TestGRPCServer {
 	s.GRPCServer.ProductRepository = &s.ProductRepository
 	s.GRPCServer.CustomerRepository = &s.CustomerRepository
 	s.GRPCServer.OrderRepository = &s.OrderRepository
+	s.GRPCServer.PromotionRepository = &s.PromotionRepository
+	s.GRPCServer.WebhookRepository = &s.WebhookRepository
+	s.GRPCServer.IdempotencyStore = &s.IdempotencyStore
 
 	return s
 }
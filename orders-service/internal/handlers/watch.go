@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/internal/watch"
+)
+
+func (s *GRPCServer) WatchOrder(in *pb.WatchOrderRequest, stream pb.Orders_WatchOrderServer) error {
+	log.Printf("Received: %v", in)
+
+	ctx := stream.Context()
+
+	if in.GetOrderId() == "" {
+		return Error(service.Errorf(service.INVALID_ERROR, "order id is required"))
+	}
+
+	subscription, err := s.subscribe(watch.Filter{OrderId: in.GetOrderId()})
+	if err != nil {
+		return Error(fmt.Errorf("failed to watch order: %w", err))
+	}
+	defer subscription.Close()
+
+	// The order is read after subscribing, so that every later change is
+	// received from the subscription.
+	order, err := s.OrderRepository.GetOrder(ctx, in.GetOrderId())
+	if err != nil {
+		return Error(fmt.Errorf("failed to watch order: %w", err))
+	}
+
+	if in.FromVersion != nil {
+		changes, err := s.missedChanges(ctx, order, func(c *repository.OrderStatusChange) bool {
+			return uint64(c.Version) > in.GetFromVersion()
+		})
+		if err != nil {
+			return Error(fmt.Errorf("failed to watch order: %w", err))
+		}
+
+		for _, change := range changes {
+			if err := stream.Send(&pb.WatchOrderResponse{Change: change}); err != nil {
+				return err
+			}
+		}
+	}
+
+	grpcOrder, err := getGRPCOrder(order)
+	if err != nil {
+		return Error(fmt.Errorf("failed to watch order: %w", err))
+	}
+
+	if err := stream.Send(&pb.WatchOrderResponse{Order: grpcOrder}); err != nil {
+		return err
+	}
+
+	version := order.Version
+	for !order.OrderStatus.IsFinal() {
+		var event *repository.OrderEvent
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-subscription.Events():
+			if !ok {
+				return Error(watchEndedError(subscription))
+			}
+			event = e
+		}
+
+		if event.Change == nil || event.Order.Version <= version {
+			continue
+		}
+
+		order, version = event.Order, event.Order.Version
+
+		update, err := getGRPCOrderUpdate(event)
+		if err != nil {
+			return Error(fmt.Errorf("failed to watch order: %w", err))
+		}
+
+		if err := stream.Send(&pb.WatchOrderResponse{Order: update.order, Change: update.change}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *GRPCServer) WatchCustomerOrders(
+	in *pb.WatchCustomerOrdersRequest, stream pb.Orders_WatchCustomerOrdersServer) error {
+
+	log.Printf("Received: %v", in)
+
+	ctx := stream.Context()
+
+	if in.GetCustomerId() == "" {
+		return Error(service.Errorf(service.INVALID_ERROR, "customer id is required"))
+	}
+
+	subscription, err := s.subscribe(watch.Filter{CustomerId: in.GetCustomerId()})
+	if err != nil {
+		return Error(fmt.Errorf("failed to watch customer orders: %w", err))
+	}
+	defer subscription.Close()
+
+	// versions holds the version of every order sent so far, to drop the
+	// events of changes that were already replayed.
+	versions := make(map[string]uint)
+
+	if in.UpdatedSince != nil {
+		since := in.GetUpdatedSince().AsTime()
+
+		orders, err := s.customerOrdersUpdatedSince(ctx, in.GetCustomerId(), since)
+		if err != nil {
+			return Error(fmt.Errorf("failed to watch customer orders: %w", err))
+		}
+
+		for _, order := range orders {
+			changes, err := s.missedChanges(ctx, order, func(c *repository.OrderStatusChange) bool {
+				return !parseTime(c.CreatedAt).Before(since)
+			})
+			if err != nil {
+				return Error(fmt.Errorf("failed to watch customer orders: %w", err))
+			}
+
+			for _, change := range changes {
+				if err := stream.Send(&pb.WatchCustomerOrdersResponse{Change: change}); err != nil {
+					return err
+				}
+			}
+
+			grpcOrder, err := getGRPCOrder(order)
+			if err != nil {
+				return Error(fmt.Errorf("failed to watch customer orders: %w", err))
+			}
+
+			if err := stream.Send(&pb.WatchCustomerOrdersResponse{Order: grpcOrder}); err != nil {
+				return err
+			}
+
+			versions[order.Id] = order.Version
+		}
+	}
+
+	for {
+		var event *repository.OrderEvent
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-subscription.Events():
+			if !ok {
+				return Error(watchEndedError(subscription))
+			}
+			event = e
+		}
+
+		if version, ok := versions[event.Order.Id]; ok && event.Order.Version <= version {
+			continue
+		}
+
+		versions[event.Order.Id] = event.Order.Version
+
+		update, err := getGRPCOrderUpdate(event)
+		if err != nil {
+			return Error(fmt.Errorf("failed to watch customer orders: %w", err))
+		}
+
+		err = stream.Send(&pb.WatchCustomerOrdersResponse{Order: update.order, Change: update.change})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *GRPCServer) subscribe(filter watch.Filter) (*watch.Subscription, error) {
+	if s.Watch == nil {
+		return nil, service.Errorf(service.NOT_IMPLEMENTED_ERROR, "watching orders is not enabled")
+	}
+
+	return s.Watch.Subscribe(filter), nil
+}
+
+// missedChanges returns the changes in the history of the order up to its
+// current version that pass the filter, oldest first.
+func (s *GRPCServer) missedChanges(ctx context.Context, order *repository.Order,
+	filter func(c *repository.OrderStatusChange) bool) ([]*pb.OrderStatusChange, error) {
+
+	history, err := s.OrderRepository.GetOrderHistory(ctx, order.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*pb.OrderStatusChange
+	for _, c := range history {
+		if c.Version > order.Version || !filter(c) {
+			continue
+		}
+
+		change, err := getGRPCOrderStatusChange(c)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// customerOrdersUpdatedSince returns the orders of the customer last updated
+// at or after since, least recently updated first.
+func (s *GRPCServer) customerOrdersUpdatedSince(
+	ctx context.Context, customerId string, since time.Time) ([]*repository.Order, error) {
+
+	var orders []*repository.Order
+	opts := &repository.ListOptions{PageSize: repository.MaxPageSize, OrderBy: "updated_at desc"}
+	for {
+		page, next, err := s.OrderRepository.ListOrders(ctx, &repository.OrderFilter{CustomerId: customerId}, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, order := range page {
+			if parseTime(order.UpdatedAt).Before(since) {
+				next = ""
+				break
+			}
+
+			orders = append(orders, order)
+		}
+
+		if next == "" {
+			break
+		}
+
+		opts.PageToken = next
+	}
+
+	for i, j := 0, len(orders)-1; i < j; i, j = i+1, j-1 {
+		orders[i], orders[j] = orders[j], orders[i]
+	}
+
+	return orders, nil
+}
+
+type grpcOrderUpdate struct {
+	order  *pb.Order
+	change *pb.OrderStatusChange
+}
+
+func getGRPCOrderUpdate(event *repository.OrderEvent) (*grpcOrderUpdate, error) {
+	order, err := getGRPCOrder(event.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &grpcOrderUpdate{order: order}
+	if event.Change != nil {
+		if update.change, err = getGRPCOrderStatusChange(event.Change); err != nil {
+			return nil, err
+		}
+	}
+
+	return update, nil
+}
+
+// watchEndedError tells a watcher why its subscription ended.
+func watchEndedError(subscription *watch.Subscription) error {
+	if err := subscription.Err(); err != nil {
+		return service.Errorf(service.CONFLICT_ERROR, "%v, resume from the last version seen", err)
+	}
+
+	return service.Errorf(service.INTERNAL_ERROR, "watch ended")
+}
+
+// parseTime returns the zero time for timestamps that do not parse, which
+// sorts them before any other.
+func parseTime(ts string) time.Time {
+	t, _ := time.Parse(time.RFC3339, ts)
+	return t
+}
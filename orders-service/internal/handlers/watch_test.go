@@ -0,0 +1,235 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/watch"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchStream collects the messages sent on a server stream.
+type watchStream[T any] struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan T
+}
+
+func newWatchStream[T any](ctx context.Context) *watchStream[T] {
+	return &watchStream[T]{ctx: ctx, sent: make(chan T, 16)}
+}
+
+func (s *watchStream[T]) Context() context.Context { return s.ctx }
+
+func (s *watchStream[T]) Send(m T) error {
+	s.sent <- m
+	return nil
+}
+
+func (s *watchStream[T]) receive(t *testing.T) T {
+	t.Helper()
+
+	select {
+	case m := <-s.sent:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no message received")
+		panic("unreachable")
+	}
+}
+
+// setupWatch returns a server that watches the orders of a memory repository,
+// and a function that publishes the events recorded in its outbox.
+func setupWatch(t *testing.T) (*TestGRPCServer, *memory.OrderRepository, func()) {
+	t.Helper()
+
+	s := NewTestGRPCServer(t)
+
+	orders := memory.NewOrderRepository()
+	s.GRPCServer.OrderRepository = orders
+	s.GRPCServer.Watch = watch.NewBroker()
+
+	publisher := outbox.NewInProcessPublisher()
+	publisher.Subscribe(s.GRPCServer.Watch.HandleEvent)
+	relay := outbox.NewRelay(orders.Outbox(), publisher)
+
+	return s, orders, func() {
+		if _, err := relay.Publish(context.Background()); err != nil {
+			t.Fatalf("failed to publish events: %v", err)
+		}
+	}
+}
+
+func createWatchedOrder(t *testing.T, orders repository.OrderRepository) *repository.Order {
+	t.Helper()
+
+	order, err := orders.CreateOrder(context.Background(), &repository.Order{
+		CustomerId: "1",
+		Items:      []*repository.OrderItem{{ProductId: "1", Quantity: 1, UnitPrice: money.New(100, "KES")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	return order
+}
+
+func updateWatchedOrder(t *testing.T, orders repository.OrderRepository, id string, to pkg.OrderStatus) {
+	t.Helper()
+
+	_, err := orders.UpdateOrderStatus(context.Background(), id, &repository.OrderStatusUpdate{Status: to})
+	if err != nil {
+		t.Fatalf("failed to update order status: %v", err)
+	}
+}
+
+func TestGRPCServer_WatchOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, orders, publish := setupWatch(t)
+
+	order := createWatchedOrder(t, orders)
+	updateWatchedOrder(t, orders, order.Id, pkg.OrderStatusProcessing)
+
+	stream := newWatchStream[*pb.WatchOrderResponse](ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.GRPCServer.WatchOrder(&pb.WatchOrderRequest{
+			OrderId:     order.Id,
+			FromVersion: proto.Uint64(1),
+		}, stream)
+	}()
+
+	// The change missed since version 1 is replayed before the current order.
+	if m := stream.receive(t); m.Order != nil || m.GetChange().GetVersion() != 2 ||
+		m.GetChange().GetToStatus() != pb.OrderStatus_PROCESSING {
+		t.Errorf("WatchOrder() sent %v, want the change to processing", m)
+	}
+
+	if m := stream.receive(t); m.Change != nil || m.GetOrder().GetVersion() != 2 {
+		t.Errorf("WatchOrder() sent %v, want the order at version 2", m)
+	}
+
+	// Events of changes that were already sent are dropped.
+	updateWatchedOrder(t, orders, order.Id, pkg.OrderStatusPending)
+	publish()
+
+	m := stream.receive(t)
+	if m.GetOrder().GetStatus() != pb.OrderStatus_PENDING || m.GetChange().GetVersion() != 3 {
+		t.Errorf("WatchOrder() sent %v, want the change to pending", m)
+	}
+
+	// The stream ends once the order reaches a final status.
+	updateWatchedOrder(t, orders, order.Id, pkg.OrderStatusCancelled)
+	publish()
+
+	if m := stream.receive(t); m.GetChange().GetToStatus() != pb.OrderStatus_CANCELLED {
+		t.Errorf("WatchOrder() sent %v, want the change to cancelled", m)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WatchOrder() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("WatchOrder() did not end")
+	}
+}
+
+func TestGRPCServer_WatchOrder_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	s, _, _ := setupWatch(t)
+
+	err := s.GRPCServer.WatchOrder(&pb.WatchOrderRequest{}, newWatchStream[*pb.WatchOrderResponse](ctx))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("WatchOrder() without order id error = %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	err = s.GRPCServer.WatchOrder(&pb.WatchOrderRequest{OrderId: "non-existent-id"},
+		newWatchStream[*pb.WatchOrderResponse](ctx))
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("WatchOrder() of a missing order error = %v, want code %v", err, codes.NotFound)
+	}
+
+	s.GRPCServer.Watch = nil
+	err = s.GRPCServer.WatchOrder(&pb.WatchOrderRequest{OrderId: "1"}, newWatchStream[*pb.WatchOrderResponse](ctx))
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("WatchOrder() without a broker error = %v, want code %v", err, codes.Unimplemented)
+	}
+}
+
+func TestGRPCServer_WatchCustomerOrders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s, orders, publish := setupWatch(t)
+
+	since := time.Now().Add(-time.Minute)
+
+	first := createWatchedOrder(t, orders)
+	updateWatchedOrder(t, orders, first.Id, pkg.OrderStatusProcessing)
+
+	stream := newWatchStream[*pb.WatchCustomerOrdersResponse](ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.GRPCServer.WatchCustomerOrders(&pb.WatchCustomerOrdersRequest{
+			CustomerId:   "1",
+			UpdatedSince: timestamppb.New(since),
+		}, stream)
+	}()
+
+	if m := stream.receive(t); m.GetChange().GetOrderId() != first.Id || m.GetChange().GetVersion() != 2 {
+		t.Errorf("WatchCustomerOrders() sent %v, want the change of the first order", m)
+	}
+
+	if m := stream.receive(t); m.GetOrder().GetId() != first.Id || m.GetOrder().GetVersion() != 2 {
+		t.Errorf("WatchCustomerOrders() sent %v, want the first order at version 2", m)
+	}
+
+	// New orders are sent, replayed changes are not sent again.
+	second := createWatchedOrder(t, orders)
+	publish()
+
+	if m := stream.receive(t); m.GetOrder().GetId() != second.Id || m.Change != nil {
+		t.Errorf("WatchCustomerOrders() sent %v, want the second order", m)
+	}
+
+	updateWatchedOrder(t, orders, first.Id, pkg.OrderStatusCancelled)
+	publish()
+
+	if m := stream.receive(t); m.GetOrder().GetId() != first.Id ||
+		m.GetChange().GetToStatus() != pb.OrderStatus_CANCELLED {
+		t.Errorf("WatchCustomerOrders() sent %v, want the change of the first order to cancelled", m)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("WatchCustomerOrders() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("WatchCustomerOrders() did not end")
+	}
+
+	select {
+	case m := <-stream.sent:
+		t.Errorf("WatchCustomerOrders() sent unexpected %v", m)
+	default:
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *GRPCServer) CreateWebhookSubscription(
+	ctx context.Context, in *pb.CreateWebhookSubscriptionRequest) (*pb.CreateWebhookSubscriptionResponse, error) {
+
+	log.Printf("Received: %v", in.GetUrl())
+
+	subscription, err := s.WebhookRepository.CreateWebhookSubscription(ctx, &repository.WebhookSubscription{
+		Url:        in.GetUrl(),
+		Secret:     in.GetSecret(),
+		EventTypes: in.GetEventTypes(),
+	})
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to create webhook subscription: %w", err))
+	}
+
+	return &pb.CreateWebhookSubscriptionResponse{
+		Id: subscription.Id,
+	}, nil
+}
+
+func (s *GRPCServer) GetWebhookSubscription(
+	ctx context.Context, in *pb.GetWebhookSubscriptionRequest) (*pb.GetWebhookSubscriptionResponse, error) {
+
+	log.Printf("Received: %v", in.GetId())
+
+	subscription, err := s.WebhookRepository.GetWebhookSubscription(ctx, in.GetId())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to get webhook subscription: %w", err))
+	}
+
+	grpcSubscription, err := getGRPCWebhookSubscription(subscription)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to get webhook subscription: %w", err))
+	}
+
+	return &pb.GetWebhookSubscriptionResponse{
+		Subscription: grpcSubscription,
+	}, nil
+}
+
+func (s *GRPCServer) ListWebhookSubscriptions(
+	ctx context.Context, in *pb.ListWebhookSubscriptionsRequest) (*pb.ListWebhookSubscriptionsResponse, error) {
+
+	subscriptions, err := s.WebhookRepository.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to list webhook subscriptions: %w", err))
+	}
+
+	var responseSubscriptions []*pb.WebhookSubscription
+	for _, subscription := range subscriptions {
+		grpcSubscription, err := getGRPCWebhookSubscription(subscription)
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to list webhook subscriptions: %w", err))
+		}
+
+		responseSubscriptions = append(responseSubscriptions, grpcSubscription)
+	}
+
+	return &pb.ListWebhookSubscriptionsResponse{
+		Subscriptions: responseSubscriptions,
+	}, nil
+}
+
+func (s *GRPCServer) DeleteWebhookSubscription(
+	ctx context.Context, in *pb.DeleteWebhookSubscriptionRequest) (*pb.DeleteWebhookSubscriptionResponse, error) {
+
+	log.Printf("Received: %v", in.GetId())
+
+	err := s.WebhookRepository.DeleteWebhookSubscription(ctx, in.GetId())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to delete webhook subscription: %w", err))
+	}
+
+	return &pb.DeleteWebhookSubscriptionResponse{}, nil
+}
+
+func (s *GRPCServer) ListWebhookDeliveries(
+	ctx context.Context, in *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+
+	log.Printf("Received: %v", in)
+
+	filter := &repository.WebhookDeliveryFilter{
+		SubscriptionId: in.GetSubscriptionId(),
+	}
+
+	if in.GetStatus() != pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_UNSPECIFIED {
+		status, err := getWebhookDeliveryStatus(in.GetStatus())
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to list webhook deliveries: %w", err))
+		}
+
+		filter.Status = status
+	}
+
+	deliveries, next, err := s.WebhookRepository.ListWebhookDeliveries(ctx, filter, &repository.ListOptions{
+		PageSize:  int(in.GetPageSize()),
+		PageToken: in.GetPageToken(),
+		OrderBy:   in.GetOrderBy(),
+	})
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to list webhook deliveries: %w", err))
+	}
+
+	var responseDeliveries []*pb.WebhookDelivery
+	for _, d := range deliveries {
+		delivery, err := getGRPCWebhookDelivery(d)
+		if err != nil {
+			return nil, Error(fmt.Errorf("failed to list webhook deliveries: %w", err))
+		}
+
+		responseDeliveries = append(responseDeliveries, delivery)
+	}
+
+	return &pb.ListWebhookDeliveriesResponse{
+		Deliveries:    responseDeliveries,
+		NextPageToken: next,
+	}, nil
+}
+
+func (s *GRPCServer) ReplayWebhookDelivery(
+	ctx context.Context, in *pb.ReplayWebhookDeliveryRequest) (*pb.ReplayWebhookDeliveryResponse, error) {
+
+	log.Printf("Received: %v", in.GetId())
+
+	if err := s.WebhookService.ReplayWebhookDelivery(ctx, in.GetId()); err != nil {
+		return nil, Error(fmt.Errorf("failed to replay webhook delivery: %w", err))
+	}
+
+	d, err := s.WebhookRepository.GetWebhookDelivery(ctx, in.GetId())
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to replay webhook delivery: %w", err))
+	}
+
+	delivery, err := getGRPCWebhookDelivery(d)
+	if err != nil {
+		return nil, Error(fmt.Errorf("failed to replay webhook delivery: %w", err))
+	}
+
+	return &pb.ReplayWebhookDeliveryResponse{
+		Delivery: delivery,
+	}, nil
+}
+
+// getGRPCWebhookSubscription leaves out the secret, which is never returned.
+func getGRPCWebhookSubscription(s *repository.WebhookSubscription) (*pb.WebhookSubscription, error) {
+	createdAt, err := getGRPCTimestamp(s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	updatedAt, err := getGRPCTimestamp(s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.WebhookSubscription{
+		Id:         s.Id,
+		Url:        s.Url,
+		EventTypes: s.EventTypes,
+		CreatedAt:  createdAt,
+		UpdatedAt:  updatedAt,
+	}, nil
+}
+
+func getGRPCWebhookDelivery(d *repository.WebhookDelivery) (*pb.WebhookDelivery, error) {
+	status, err := getGRPCWebhookDeliveryStatus(d.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamps := make([]*timestamppb.Timestamp, 3)
+	for i, ts := range []string{d.NextAttemptAt, d.CreatedAt, d.UpdatedAt} {
+		if timestamps[i], err = getGRPCTimestamp(ts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &pb.WebhookDelivery{
+		Id:               d.Id,
+		SubscriptionId:   d.SubscriptionId,
+		EventId:          d.EventId,
+		EventType:        d.EventType,
+		Status:           status,
+		Attempts:         uint32(d.Attempts),
+		LastResponseCode: int32(d.LastResponseCode),
+		LastError:        d.LastError,
+		NextAttemptAt:    timestamps[0],
+		CreatedAt:        timestamps[1],
+		UpdatedAt:        timestamps[2],
+	}, nil
+}
+
+// webhookDeliveryStatuses maps every delivery status to its gRPC counterpart.
+var webhookDeliveryStatuses = map[repository.WebhookDeliveryStatus]pb.WebhookDeliveryStatus{
+	repository.WebhookDeliveryStatusPending:   pb.WebhookDeliveryStatus_DELIVERY_PENDING,
+	repository.WebhookDeliveryStatusSucceeded: pb.WebhookDeliveryStatus_DELIVERY_SUCCEEDED,
+	repository.WebhookDeliveryStatusFailed:    pb.WebhookDeliveryStatus_DELIVERY_FAILED,
+}
+
+func getGRPCWebhookDeliveryStatus(status repository.WebhookDeliveryStatus) (pb.WebhookDeliveryStatus, error) {
+	grpcStatus, ok := webhookDeliveryStatuses[status]
+	if !ok {
+		return pb.WebhookDeliveryStatus_WEBHOOK_DELIVERY_STATUS_UNSPECIFIED,
+			service.Errorf(service.INTERNAL_ERROR, "unknown webhook delivery status %q", status)
+	}
+
+	return grpcStatus, nil
+}
+
+func getWebhookDeliveryStatus(status pb.WebhookDeliveryStatus) (repository.WebhookDeliveryStatus, error) {
+	for s, grpcStatus := range webhookDeliveryStatuses {
+		if grpcStatus == status {
+			return s, nil
+		}
+	}
+
+	return "", service.Errorf(service.INVALID_ERROR, "unknown webhook delivery status %v", status)
+}
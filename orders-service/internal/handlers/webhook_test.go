@@ -0,0 +1,381 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/Mik3y-F/order-management-system/orders/api/generated"
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/webhooks"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	ERROR_WEBHOOK_TRIGGER = "Error Webhook"
+)
+
+func newTestWebhookSubscription() *repository.WebhookSubscription {
+	return &repository.WebhookSubscription{
+		Id:         "1",
+		Url:        "https://erp.example.com/webhooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{"OrderCreated", "OrderPaid"},
+		CreatedAt:  "2024-01-01T00:00:00Z",
+		UpdatedAt:  "2024-01-02T00:00:00Z",
+	}
+}
+
+func newTestGRPCWebhookSubscription() *pb.WebhookSubscription {
+	return &pb.WebhookSubscription{
+		Id:         "1",
+		Url:        "https://erp.example.com/webhooks",
+		EventTypes: []string{"OrderCreated", "OrderPaid"},
+		CreatedAt:  timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:  timestamppb.New(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+	}
+}
+
+func newTestWebhookDelivery() *repository.WebhookDelivery {
+	return &repository.WebhookDelivery{
+		Id:               "1",
+		SubscriptionId:   "1",
+		EventId:          "event-1",
+		EventType:        "OrderPaid",
+		Payload:          []byte(`{}`),
+		Status:           repository.WebhookDeliveryStatusFailed,
+		Attempts:         8,
+		LastResponseCode: 503,
+		LastError:        "unexpected response 503 Service Unavailable",
+		NextAttemptAt:    "2024-01-01T00:00:00Z",
+		CreatedAt:        "2024-01-01T00:00:00Z",
+		UpdatedAt:        "2024-01-02T00:00:00Z",
+	}
+}
+
+func newTestGRPCWebhookDelivery() *pb.WebhookDelivery {
+	return &pb.WebhookDelivery{
+		Id:               "1",
+		SubscriptionId:   "1",
+		EventId:          "event-1",
+		EventType:        "OrderPaid",
+		Status:           pb.WebhookDeliveryStatus_DELIVERY_FAILED,
+		Attempts:         8,
+		LastResponseCode: 503,
+		LastError:        "unexpected response 503 Service Unavailable",
+		NextAttemptAt:    timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		CreatedAt:        timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:        timestamppb.New(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+	}
+}
+
+func mockCreateWebhookSubscriptionFunc(
+	ctx context.Context, s *repository.WebhookSubscription) (*repository.WebhookSubscription, error) {
+
+	if s.Url == ERROR_WEBHOOK_TRIGGER {
+		return nil, errors.New("intentional error")
+	}
+
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &repository.WebhookSubscription{Id: "1"}, nil
+}
+
+func TestGRPCServer_CreateWebhookSubscription(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.WebhookRepository.CreateWebhookSubscriptionFunc = mockCreateWebhookSubscriptionFunc
+
+	tests := []struct {
+		name     string
+		request  *pb.CreateWebhookSubscriptionRequest
+		want     *pb.CreateWebhookSubscriptionResponse
+		wantCode codes.Code
+	}{
+		{
+			name: "Create Webhook Subscription Success",
+			request: &pb.CreateWebhookSubscriptionRequest{
+				Url:        "https://erp.example.com/webhooks",
+				Secret:     "0123456789abcdef",
+				EventTypes: []string{"OrderPaid"},
+			},
+			want: &pb.CreateWebhookSubscriptionResponse{Id: "1"},
+		},
+		{
+			name: "Create Webhook Subscription Failed - Short Secret",
+			request: &pb.CreateWebhookSubscriptionRequest{
+				Url:        "https://erp.example.com/webhooks",
+				Secret:     "secret",
+				EventTypes: []string{"OrderPaid"},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Create Webhook Subscription Error",
+			request:  &pb.CreateWebhookSubscriptionRequest{Url: ERROR_WEBHOOK_TRIGGER},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.CreateWebhookSubscription(context.Background(), tt.request)
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Errorf("GRPCServer.CreateWebhookSubscription() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GRPCServer.CreateWebhookSubscription() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.CreateWebhookSubscription() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mockGetWebhookSubscriptionFunc(ctx context.Context, id string) (*repository.WebhookSubscription, error) {
+	if id == ERROR_WEBHOOK_TRIGGER {
+		return nil, errors.New("intentional error")
+	}
+
+	return newTestWebhookSubscription(), nil
+}
+
+func TestGRPCServer_GetWebhookSubscription(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.WebhookRepository.GetWebhookSubscriptionFunc = mockGetWebhookSubscriptionFunc
+
+	tests := []struct {
+		name    string
+		request *pb.GetWebhookSubscriptionRequest
+		want    *pb.GetWebhookSubscriptionResponse
+		wantErr bool
+	}{
+		{
+			name:    "Get Webhook Subscription Success",
+			request: &pb.GetWebhookSubscriptionRequest{Id: "1"},
+			want:    &pb.GetWebhookSubscriptionResponse{Subscription: newTestGRPCWebhookSubscription()},
+		},
+		{
+			name:    "Get Webhook Subscription Error",
+			request: &pb.GetWebhookSubscriptionRequest{Id: ERROR_WEBHOOK_TRIGGER},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.GetWebhookSubscription(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.GetWebhookSubscription() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.GetWebhookSubscription() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCServer_ListWebhookSubscriptions(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.WebhookRepository.ListWebhookSubscriptionsFunc = func(
+		ctx context.Context) ([]*repository.WebhookSubscription, error) {
+
+		return []*repository.WebhookSubscription{newTestWebhookSubscription()}, nil
+	}
+
+	got, err := s.GRPCServer.ListWebhookSubscriptions(context.Background(), &pb.ListWebhookSubscriptionsRequest{})
+	if err != nil {
+		t.Fatalf("GRPCServer.ListWebhookSubscriptions() error = %v", err)
+	}
+
+	want := &pb.ListWebhookSubscriptionsResponse{
+		Subscriptions: []*pb.WebhookSubscription{newTestGRPCWebhookSubscription()},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GRPCServer.ListWebhookSubscriptions() = %v, want %v", got, want)
+	}
+}
+
+func TestGRPCServer_DeleteWebhookSubscription(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.WebhookRepository.DeleteWebhookSubscriptionFunc = func(ctx context.Context, id string) error {
+		if id == ERROR_WEBHOOK_TRIGGER {
+			return errors.New("intentional error")
+		}
+
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		request *pb.DeleteWebhookSubscriptionRequest
+		wantErr bool
+	}{
+		{
+			name:    "Delete Webhook Subscription Success",
+			request: &pb.DeleteWebhookSubscriptionRequest{Id: "1"},
+		},
+		{
+			name:    "Delete Webhook Subscription Error",
+			request: &pb.DeleteWebhookSubscriptionRequest{Id: ERROR_WEBHOOK_TRIGGER},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.GRPCServer.DeleteWebhookSubscription(context.Background(), tt.request)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GRPCServer.DeleteWebhookSubscription() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mockListWebhookDeliveriesFunc(ctx context.Context, filter *repository.WebhookDeliveryFilter,
+	opts *repository.ListOptions) ([]*repository.WebhookDelivery, string, error) {
+
+	if filter.SubscriptionId == ERROR_WEBHOOK_TRIGGER {
+		return nil, "", errors.New("intentional error")
+	}
+
+	if filter.Status != repository.WebhookDeliveryStatusFailed {
+		return nil, "", nil
+	}
+
+	var next string
+	if opts.PageSize == 1 {
+		next = "next-page"
+	}
+
+	return []*repository.WebhookDelivery{newTestWebhookDelivery()}, next, nil
+}
+
+func TestGRPCServer_ListWebhookDeliveries(t *testing.T) {
+	s := NewTestGRPCServer(t)
+
+	s.WebhookRepository.ListWebhookDeliveriesFunc = mockListWebhookDeliveriesFunc
+
+	tests := []struct {
+		name     string
+		request  *pb.ListWebhookDeliveriesRequest
+		want     *pb.ListWebhookDeliveriesResponse
+		wantCode codes.Code
+	}{
+		{
+			name: "List Failed Webhook Deliveries Success",
+			request: &pb.ListWebhookDeliveriesRequest{
+				SubscriptionId: "1",
+				Status:         pb.WebhookDeliveryStatus_DELIVERY_FAILED,
+				PageSize:       1,
+			},
+			want: &pb.ListWebhookDeliveriesResponse{
+				Deliveries:    []*pb.WebhookDelivery{newTestGRPCWebhookDelivery()},
+				NextPageToken: "next-page",
+			},
+		},
+		{
+			name:     "List Webhook Deliveries Failed - Unknown Status",
+			request:  &pb.ListWebhookDeliveriesRequest{Status: pb.WebhookDeliveryStatus(42)},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "List Webhook Deliveries Error",
+			request:  &pb.ListWebhookDeliveriesRequest{SubscriptionId: ERROR_WEBHOOK_TRIGGER},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.ListWebhookDeliveries(context.Background(), tt.request)
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Errorf("GRPCServer.ListWebhookDeliveries() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GRPCServer.ListWebhookDeliveries() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GRPCServer.ListWebhookDeliveries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCServer_ReplayWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+	s := NewTestGRPCServer(t)
+
+	webhookRepository := memory.NewWebhookRepository()
+	s.GRPCServer.WebhookRepository = webhookRepository
+	s.GRPCServer.WebhookService = webhooks.NewWebhookService(webhookRepository)
+
+	failed := newTestWebhookDelivery()
+	failed, err := webhookRepository.CreateWebhookDelivery(ctx, failed)
+	if err != nil {
+		t.Fatalf("failed to create delivery: %v", err)
+	}
+
+	pending := newTestWebhookDelivery()
+	pending.EventId = "event-2"
+	pending.Status = repository.WebhookDeliveryStatusPending
+	pending, err = webhookRepository.CreateWebhookDelivery(ctx, pending)
+	if err != nil {
+		t.Fatalf("failed to create delivery: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		request  *pb.ReplayWebhookDeliveryRequest
+		wantCode codes.Code
+	}{
+		{
+			name:    "Replay Webhook Delivery Success",
+			request: &pb.ReplayWebhookDeliveryRequest{Id: failed.Id},
+		},
+		{
+			name:     "Replay Webhook Delivery Failed - Pending Delivery",
+			request:  &pb.ReplayWebhookDeliveryRequest{Id: pending.Id},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name:     "Replay Webhook Delivery Failed - Unknown Delivery",
+			request:  &pb.ReplayWebhookDeliveryRequest{Id: "non-existent-id"},
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GRPCServer.ReplayWebhookDelivery(ctx, tt.request)
+			if tt.wantCode != codes.OK {
+				if status.Code(err) != tt.wantCode {
+					t.Errorf("GRPCServer.ReplayWebhookDelivery() error = %v, want code %v", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GRPCServer.ReplayWebhookDelivery() error = %v", err)
+			}
+
+			d := got.GetDelivery()
+			if d.GetStatus() != pb.WebhookDeliveryStatus_DELIVERY_PENDING || d.GetAttempts() != 0 ||
+				d.GetLastResponseCode() != 503 {
+				t.Errorf("GRPCServer.ReplayWebhookDelivery() = %v, want a pending delivery with no attempts", d)
+			}
+		})
+	}
+}
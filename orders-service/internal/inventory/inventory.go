@@ -0,0 +1,185 @@
+// Package inventory keeps the stock of products in step with the orders that
+// hold it.
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+var _ repository.OrderRepository = (*OrderRepository)(nil)
+
+// OrderRepository wraps an order repository and adjusts product stock as
+// orders change status. Stock is reserved when an order is checked out,
+// committed when it is paid and released when it fails or is cancelled. The
+// order records the reservation, so that exactly the units it reserved are
+// committed or released.
+type OrderRepository struct {
+	repository.OrderRepository
+
+	products repository.ProductRepository
+}
+
+func NewOrderRepository(orders repository.OrderRepository, products repository.ProductRepository) *OrderRepository {
+	return &OrderRepository{
+		OrderRepository: orders,
+		products:        products,
+	}
+}
+
+func (r *OrderRepository) CheckPreconditions() {
+	if r.OrderRepository == nil {
+		panic("no order repository provided")
+	}
+
+	if r.products == nil {
+		panic("no product repository provided")
+	}
+}
+
+// holdsStock reports whether an order in status s has its items reserved.
+func holdsStock(s pkg.OrderStatus) bool {
+	return s == pkg.OrderStatusProcessing || s == pkg.OrderStatusPending
+}
+
+// maxStatusUpdateAttempts bounds how often UpdateOrderStatus reads the order
+// again after losing a race with another change of it.
+const maxStatusUpdateAttempts = 5
+
+// UpdateOrderStatus reserves the order's items before it moves to a status
+// that holds stock, and commits or releases them once it leaves one. It fails
+// with OUT_OF_STOCK_ERROR if a product does not have enough units.
+//
+// Updates without a version that lose a race with another change of the order
+// are made again for the order as it is now.
+func (r *OrderRepository) UpdateOrderStatus(
+	ctx context.Context, id string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
+
+	r.CheckPreconditions()
+
+	order, err := r.OrderRepository.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		updated, err := r.updateOrderStatus(ctx, order, update)
+		if err == nil || update.Version != nil || service.ErrorCode(err) != service.FAILED_PRECONDITION_ERROR ||
+			attempt == maxStatusUpdateAttempts {
+			return updated, err
+		}
+
+		// Orders that did not change since they were read cannot make the
+		// update, e.g. because the transition is not allowed.
+		current, getErr := r.OrderRepository.GetOrder(ctx, id)
+		if getErr != nil || current.Version == order.Version {
+			return nil, err
+		}
+
+		order = current
+	}
+}
+
+// updateOrderStatus adjusts the stock for the order as it was read, and
+// updates its status if it has not changed since.
+func (r *OrderRepository) updateOrderStatus(
+	ctx context.Context, order *repository.Order, update *repository.OrderStatusUpdate) (*repository.Order, error) {
+
+	// The stock is adjusted for the status that was read, so the update must
+	// fail if the order changed since.
+	pinned := *update
+	if pinned.Version == nil {
+		pinned.Version = &order.Version
+	}
+
+	held, holds := holdsStock(order.OrderStatus), holdsStock(update.Status)
+
+	if !held && holds && order.OrderStatus.CanTransitionTo(update.Status) {
+		// Concurrent updates of the same version share the reservation, which
+		// is stored with the status by the one that wins.
+		if pinned.StockReservation == "" {
+			pinned.StockReservation = fmt.Sprintf("%s:%d", order.Id, order.Version)
+		}
+
+		if items := repository.OrderStockItems(order.Items); len(items) > 0 {
+			if err := r.products.ReserveStock(ctx, pinned.StockReservation, items); err != nil {
+				return nil, err
+			}
+		}
+
+		updated, err := r.OrderRepository.UpdateOrderStatus(ctx, order.Id, &pinned)
+		if err != nil {
+			r.releaseUnlessHeld(ctx, order.Id, pinned.StockReservation)
+			return nil, err
+		}
+
+		return updated, nil
+	}
+
+	updated, err := r.OrderRepository.UpdateOrderStatus(ctx, order.Id, &pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	if held && !holds {
+		if updated.OrderStatus == pkg.OrderStatusPaid {
+			r.adjustStock(ctx, order.Id, "commit", r.products.CommitStock, updated.StockReservation)
+		} else {
+			r.adjustStock(ctx, order.Id, "release", r.products.ReleaseStock, updated.StockReservation)
+		}
+	}
+
+	return updated, nil
+}
+
+// releaseUnlessHeld releases a reservation made for a status update that
+// failed, unless the order holds it because a concurrent update of the same
+// version stored it.
+func (r *OrderRepository) releaseUnlessHeld(ctx context.Context, orderId string, reservation string) {
+	current, err := r.OrderRepository.GetOrder(ctx, orderId)
+	if err == nil && holdsStock(current.OrderStatus) && current.StockReservation == reservation {
+		return
+	}
+
+	r.adjustStock(ctx, orderId, "release", r.products.ReleaseStock, reservation)
+}
+
+// DeleteOrder releases the stock held by the order once it is deleted.
+func (r *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	order, err := r.OrderRepository.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := r.OrderRepository.DeleteOrder(ctx, id); err != nil {
+		return err
+	}
+
+	if holdsStock(order.OrderStatus) {
+		r.adjustStock(ctx, order.Id, "release", r.products.ReleaseStock, order.StockReservation)
+	}
+
+	return nil
+}
+
+// adjustStock commits or releases the stock reservation of an order after its
+// status change was stored or failed. Returning an error would misreport the
+// stored status, so failures are logged for the stock to be corrected by hand.
+func (r *OrderRepository) adjustStock(ctx context.Context, orderId string, action string,
+	adjust func(context.Context, string) error, reservation string) {
+
+	if reservation == "" {
+		return
+	}
+
+	if err := adjust(ctx, reservation); err != nil {
+		log.Printf("[inventory] failed to %s stock reservation %s of order %s: %v", action, reservation, orderId, err)
+	}
+}
@@ -0,0 +1,356 @@
+package inventory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/inventory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+// setup returns an inventory backed by memory repositories, with one order for
+// 2 units of a product that has 5 in stock.
+func setup(t *testing.T) (*inventory.OrderRepository, repository.ProductRepository, *repository.Order) {
+	t.Helper()
+
+	return setupWith(t, memory.NewOrderRepository())
+}
+
+// setupWith is setup with the order repository wrapped by the inventory.
+func setupWith(
+	t *testing.T, stored repository.OrderRepository) (*inventory.OrderRepository, repository.ProductRepository, *repository.Order) {
+
+	t.Helper()
+
+	ctx := context.Background()
+	products := memory.NewProductRepository()
+	orders := inventory.NewOrderRepository(stored, products)
+
+	product, err := products.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: money.New(100, "KES"), Stock: pkg.UintPtr(5)})
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	order, err := orders.CreateOrder(ctx, &repository.Order{
+		CustomerId: "customer-1",
+		Items:      []*repository.OrderItem{{ProductId: product.Id, Quantity: 2}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	return orders, products, order
+}
+
+func wantStock(t *testing.T, products repository.ProductRepository, id string, stock, reserved uint) {
+	t.Helper()
+
+	p, err := products.GetProduct(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get product: %v", err)
+	}
+
+	if p.Stock == nil {
+		t.Fatalf("stock is untracked, want %d", stock)
+	}
+	if *p.Stock != stock || p.Reserved != reserved {
+		t.Errorf("stock = %d, reserved = %d, want %d and %d", *p.Stock, p.Reserved, stock, reserved)
+	}
+}
+
+func TestOrderRepository_UpdateOrderStatus(t *testing.T) {
+	type step struct {
+		status   pkg.OrderStatus
+		stock    uint
+		reserved uint
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "Paid",
+			steps: []step{
+				{pkg.OrderStatusProcessing, 3, 2},
+				{pkg.OrderStatusPending, 3, 2},
+				{pkg.OrderStatusPaid, 3, 0},
+				{pkg.OrderStatusShipped, 3, 0},
+			},
+		},
+		{
+			name: "Failed And Checked Out Again",
+			steps: []step{
+				{pkg.OrderStatusProcessing, 3, 2},
+				{pkg.OrderStatusFailed, 5, 0},
+				{pkg.OrderStatusProcessing, 3, 2},
+				{pkg.OrderStatusPaid, 3, 0},
+			},
+		},
+		{
+			name: "Cancelled During Payment",
+			steps: []step{
+				{pkg.OrderStatusProcessing, 3, 2},
+				{pkg.OrderStatusPending, 3, 2},
+				{pkg.OrderStatusCancelled, 5, 0},
+			},
+		},
+		{
+			name: "Cancelled Before Checkout",
+			steps: []step{
+				{pkg.OrderStatusCancelled, 5, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, products, order := setup(t)
+
+			for _, step := range tt.steps {
+				got, err := orders.UpdateOrderStatus(context.Background(), order.Id, &repository.OrderStatusUpdate{
+					Status: step.status,
+				})
+				if err != nil {
+					t.Fatalf("UpdateOrderStatus(%s) error = %v", step.status, err)
+				}
+				if got.OrderStatus != step.status {
+					t.Errorf("UpdateOrderStatus(%s) status = %s", step.status, got.OrderStatus)
+				}
+
+				wantStock(t, products, order.Items[0].ProductId, step.stock, step.reserved)
+			}
+		})
+	}
+}
+
+func TestOrderRepository_UpdateOrderStatus_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		stock    uint
+		status   pkg.OrderStatus
+		wantCode string
+	}{
+		{"Out Of Stock", 1, pkg.OrderStatusProcessing, service.OUT_OF_STOCK_ERROR},
+		{"Illegal Transition", 5, pkg.OrderStatusPaid, service.FAILED_PRECONDITION_ERROR},
+		{"Unknown Status", 5, "unknown", service.INVALID_ERROR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			orders, products, order := setup(t)
+
+			productId := order.Items[0].ProductId
+			_, err := products.UpdateProduct(ctx, productId, &repository.ProductUpdate{Stock: pkg.UintPtr(tt.stock)})
+			if err != nil {
+				t.Fatalf("failed to update product: %v", err)
+			}
+
+			_, err = orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: tt.status})
+			if code := service.ErrorCode(err); code != tt.wantCode {
+				t.Errorf("UpdateOrderStatus() code = %q, want %q (err = %v)", code, tt.wantCode, err)
+			}
+
+			got, err := orders.GetOrder(ctx, order.Id)
+			if err != nil {
+				t.Fatalf("failed to get order: %v", err)
+			}
+			if got.OrderStatus != pkg.OrderStatusNew {
+				t.Errorf("order status = %s, want %s", got.OrderStatus, pkg.OrderStatusNew)
+			}
+
+			wantStock(t, products, productId, tt.stock, 0)
+		})
+	}
+}
+
+func TestOrderRepository_StaleVersion(t *testing.T) {
+	ctx := context.Background()
+	orders, products, order := setup(t)
+
+	stale := order.Version + 1
+	_, err := orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{
+		Status:  pkg.OrderStatusProcessing,
+		Version: &stale,
+	})
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("UpdateOrderStatus() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+
+	// The reservation made before the update is given back.
+	wantStock(t, products, order.Items[0].ProductId, 5, 0)
+}
+
+// racingOrderRepository runs race just before the first status update reaches
+// the repository, like a concurrent change of the order would.
+type racingOrderRepository struct {
+	repository.OrderRepository
+
+	race func(ctx context.Context) error
+}
+
+func (r *racingOrderRepository) UpdateOrderStatus(
+	ctx context.Context, id string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
+
+	if race := r.race; race != nil {
+		r.race = nil
+		if err := race(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.OrderRepository.UpdateOrderStatus(ctx, id, update)
+}
+
+func TestOrderRepository_ConcurrentUpdate(t *testing.T) {
+	ctx := context.Background()
+	stored := &racingOrderRepository{OrderRepository: memory.NewOrderRepository()}
+	orders, products, order := setupWith(t, stored)
+
+	_, err := orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+
+	// The order moves to pending while it is being paid; the payment is still
+	// recorded, and commits the stock reserved at checkout.
+	stored.race = func(ctx context.Context) error {
+		_, err := stored.OrderRepository.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{
+			Status: pkg.OrderStatusPending,
+		})
+		return err
+	}
+
+	got, err := orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusPaid})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+	if got.OrderStatus != pkg.OrderStatusPaid {
+		t.Errorf("UpdateOrderStatus() status = %s, want %s", got.OrderStatus, pkg.OrderStatusPaid)
+	}
+
+	wantStock(t, products, order.Items[0].ProductId, 3, 0)
+
+	// Updates of a given version still fail once the order changed.
+	version := got.Version
+	stored.race = func(ctx context.Context) error {
+		_, err := stored.OrderRepository.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{
+			Status: pkg.OrderStatusShipped,
+		})
+		return err
+	}
+
+	_, err = orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{
+		Status:  pkg.OrderStatusRefunded,
+		Version: &version,
+	})
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("UpdateOrderStatus() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+}
+
+func TestOrderRepository_ItemsLockedDuringPayment(t *testing.T) {
+	ctx := context.Background()
+	orders, _, order := setup(t)
+
+	_, err := orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+
+	item := order.Items[0]
+
+	_, err = orders.CreateOrderItem(ctx, order.Id, &repository.OrderItem{ProductId: item.ProductId, Quantity: 1})
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("CreateOrderItem() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+
+	_, err = orders.UpdateOrderItem(ctx, order.Id, item.Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(1)})
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("UpdateOrderItem() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+
+	err = orders.DeleteOrderItem(ctx, order.Id, item.Id)
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("DeleteOrderItem() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+
+	_, err = orders.ApplyOrderPromotion(ctx, order.Id, &repository.AppliedPromotion{
+		PromotionId: "promotion-1",
+		Code:        "TEN",
+		Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10},
+	}, "")
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("ApplyOrderPromotion() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+}
+
+func TestOrderRepository_DeleteOrder(t *testing.T) {
+	ctx := context.Background()
+	orders, products, order := setup(t)
+
+	_, err := orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+
+	if err := orders.DeleteOrder(ctx, order.Id); err != nil {
+		t.Fatalf("DeleteOrder() error = %v", err)
+	}
+
+	wantStock(t, products, order.Items[0].ProductId, 5, 0)
+}
+
+func TestOrderRepository_StockSetDuringPayment(t *testing.T) {
+	ctx := context.Background()
+	orders, products, _ := setup(t)
+
+	product, err := products.CreateProduct(ctx, &repository.Product{Name: "Untracked", Price: money.New(100, "KES")})
+	if err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	newOrder := func() *repository.Order {
+		order, err := orders.CreateOrder(ctx, &repository.Order{
+			CustomerId: "customer-1",
+			Items:      []*repository.OrderItem{{ProductId: product.Id, Quantity: 2}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create order: %v", err)
+		}
+
+		_, err = orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+		if err != nil {
+			t.Fatalf("UpdateOrderStatus() error = %v", err)
+		}
+
+		return order
+	}
+
+	// The first order is checked out before the stock is tracked, and holds
+	// none of it; the second one reserves its units.
+	untracked := newOrder()
+
+	_, err = products.UpdateProduct(ctx, product.Id, &repository.ProductUpdate{Stock: pkg.UintPtr(5)})
+	if err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+
+	tracked := newOrder()
+	wantStock(t, products, product.Id, 3, 2)
+
+	// Paying for the first order leaves the units of the second one reserved.
+	_, err = orders.UpdateOrderStatus(ctx, untracked.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusPaid})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+	wantStock(t, products, product.Id, 3, 2)
+
+	_, err = orders.UpdateOrderStatus(ctx, tracked.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusFailed})
+	if err != nil {
+		t.Fatalf("UpdateOrderStatus() error = %v", err)
+	}
+	wantStock(t, products, product.Id, 5, 0)
+}
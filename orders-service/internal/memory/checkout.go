@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+var _ repository.CheckoutSagaRepository = (*CheckoutSagaRepository)(nil)
+
+type CheckoutSagaRepository struct {
+	mu    sync.RWMutex
+	ids   []string // insertion order, used for listing
+	sagas map[string]repository.CheckoutSaga
+}
+
+func NewCheckoutSagaRepository() *CheckoutSagaRepository {
+	return &CheckoutSagaRepository{
+		sagas: make(map[string]repository.CheckoutSaga),
+	}
+}
+
+func (r *CheckoutSagaRepository) CreateCheckoutSaga(
+	ctx context.Context, saga *repository.CheckoutSaga) (*repository.CheckoutSaga, error) {
+
+	currentTime := now()
+
+	saga.CreatedAt = currentTime
+	saga.UpdatedAt = currentTime
+	saga.Step = repository.CheckoutStepReserve
+	saga.Status = repository.CheckoutSagaStatusRunning
+	saga.Error = ""
+	saga.Version = 1
+
+	if err := saga.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saga.Id = newID()
+
+	r.sagas[saga.Id] = *saga
+	r.ids = append(r.ids, saga.Id)
+
+	return saga, nil
+}
+
+func (r *CheckoutSagaRepository) GetCheckoutSaga(ctx context.Context, id string) (*repository.CheckoutSaga, error) {
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	saga, ok := r.sagas[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "checkout saga not found")
+	}
+
+	return &saga, nil
+}
+
+func (r *CheckoutSagaRepository) UpdateCheckoutSaga(
+	ctx context.Context, id string, update *repository.CheckoutSagaUpdate) (*repository.CheckoutSaga, error) {
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saga, ok := r.sagas[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "checkout saga not found")
+	}
+
+	if err := saga.ApplyUpdate(update); err != nil {
+		return nil, err
+	}
+
+	saga.UpdatedAt = now()
+	r.sagas[id] = saga
+
+	return &saga, nil
+}
+
+func (r *CheckoutSagaRepository) ListUnfinishedCheckoutSagas(ctx context.Context) ([]*repository.CheckoutSaga, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sagas []*repository.CheckoutSaga
+	for _, id := range r.ids {
+		saga := r.sagas[id]
+		if !saga.Status.Finished() {
+			sagas = append(sagas, &saga)
+		}
+	}
+
+	return sagas, nil
+}
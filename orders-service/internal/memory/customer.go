@@ -0,0 +1,148 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+var _ repository.CustomerRepository = (*CustomerRepository)(nil)
+
+type CustomerRepository struct {
+	mu        sync.RWMutex
+	ids       []string // insertion order, used for listing
+	customers map[string]repository.Customer
+}
+
+func NewCustomerRepository() *CustomerRepository {
+	return &CustomerRepository{
+		customers: make(map[string]repository.Customer),
+	}
+}
+
+func (r *CustomerRepository) CreateCustomer(ctx context.Context, customer *repository.Customer) (*repository.Customer, error) {
+	// Set CreatedAt and UpdatedAt to the current time
+	currentTime := now()
+
+	customer.CreatedAt = currentTime
+	customer.UpdatedAt = currentTime
+
+	err := customer.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid customer provided: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	customer.Id = newID()
+
+	r.customers[customer.Id] = *customer
+	r.ids = append(r.ids, customer.Id)
+
+	return customer, nil
+}
+
+func (r *CustomerRepository) GetCustomer(ctx context.Context, id string) (*repository.Customer, error) {
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	customer, ok := r.customers[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "customer not found")
+	}
+
+	return &customer, nil
+}
+
+func (r *CustomerRepository) ListCustomers(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Customer, string, error) {
+
+	page, err := repository.ParsePage(opts, repository.CustomerSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	customers := make([]*repository.Customer, 0, len(r.ids))
+	for _, id := range r.ids {
+		customer := r.customers[id]
+		customers = append(customers, &customer)
+	}
+
+	customers, next := paginate(customers, page,
+		func(c *repository.Customer) string { return c.SortValue(page.Ordering.Field) },
+		func(c *repository.Customer) string { return c.Id },
+	)
+
+	return customers, next, nil
+}
+
+func (r *CustomerRepository) UpdateCustomer(
+	ctx context.Context, id string, update *repository.CustomerUpdate) (*repository.Customer, error) {
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	customer, ok := r.customers[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "customer not found")
+	}
+
+	if c := update.FirstName; c != nil {
+		customer.FirstName = *c
+	}
+
+	if c := update.LastName; c != nil {
+		customer.LastName = *c
+	}
+
+	if c := update.Email; c != nil {
+		customer.Email = *c
+	}
+
+	if c := update.Phone; c != nil {
+		customer.Phone = *c
+	}
+
+	err := customer.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid customer details provided: %v", err)
+	}
+
+	customer.UpdatedAt = now()
+
+	r.customers[id] = customer
+
+	return &customer, nil
+}
+
+func (r *CustomerRepository) DeleteCustomer(ctx context.Context, id string) error {
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.customers[id]; !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "customer not found")
+	}
+
+	delete(r.customers, id)
+	r.ids = removeID(r.ids, id)
+
+	return nil
+}
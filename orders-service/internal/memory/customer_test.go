@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestCustomerRepository(t *testing.T) {
+	repositorytest.TestCustomerRepository(t, func(t *testing.T) repository.CustomerRepository {
+		return memory.NewCustomerRepository()
+	})
+}
@@ -0,0 +1,71 @@
+// Package memory provides thread-safe, in-memory implementations of the
+// repository interfaces. They are intended for local runs and tests where a
+// Firestore project or emulator is not available; all data is lost when the
+// process exits.
+package memory
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+)
+
+// newID returns a random 20 character identifier, matching the length of the
+// auto-generated Firestore document IDs.
+func newID() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to generate id: " + err.Error())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// now returns the current time formatted the same way as the other backends.
+func now() string {
+	return time.Now().Format(time.RFC3339)
+}
+
+// removeID returns ids without the first occurrence of id.
+func removeID(ids []string, id string) []string {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+
+	return ids
+}
+
+// paginate sorts the records in the order requested by the page and returns
+// the records of the page together with the token of the next page.
+func paginate[T any](records []T, page *repository.Page, sortValue func(T) string, id func(T) string) ([]T, string) {
+	sort.Slice(records, func(i, j int) bool {
+		vi, vj := sortValue(records[i]), sortValue(records[j])
+		if vi == vj {
+			vi, vj = id(records[i]), id(records[j])
+		}
+
+		if page.Ordering.Desc {
+			return vi > vj
+		}
+
+		return vi < vj
+	})
+
+	result := make([]T, 0, page.Size+1)
+	for _, record := range records {
+		if len(result) > page.Size {
+			break
+		}
+
+		if page.After(sortValue(record), id(record)) {
+			result = append(result, record)
+		}
+	}
+
+	return repository.TrimPage(page, result, sortValue, id)
+}
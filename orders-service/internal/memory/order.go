@@ -0,0 +1,496 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	orderPkg "github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+var _ repository.OrderRepository = (*OrderRepository)(nil)
+
+type OrderRepository struct {
+	mu     sync.RWMutex
+	ids    []string // insertion order, used for listing
+	orders map[string]*orderRecord
+
+	// outbox records the events of order creations and status changes.
+	outbox *OutboxRepository
+}
+
+// orderRecord holds an order together with its items, keyed in insertion order.
+type orderRecord struct {
+	order   repository.Order
+	itemIds []string
+	items   map[string]repository.OrderItem
+	history []repository.OrderStatusChange
+}
+
+func NewOrderRepository() *OrderRepository {
+	return &OrderRepository{
+		orders: make(map[string]*orderRecord),
+		outbox: NewOutboxRepository(),
+	}
+}
+
+// Outbox returns the outbox that the repository records its events in.
+func (r *OrderRepository) Outbox() *OutboxRepository {
+	return r.outbox
+}
+
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *repository.Order) (*repository.Order, error) {
+	// Set CreatedAt and UpdatedAt to the current time
+	currentTime := now()
+
+	order.CreatedAt = currentTime
+	order.UpdatedAt = currentTime
+
+	order.OrderStatus = orderPkg.OrderStatusNew
+	order.Version = 1
+
+	err := order.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid order details provided: %v", err)
+	}
+
+	if err := order.UpdateTotals(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	order.Id = newID()
+
+	record := &orderRecord{
+		order: *order,
+		items: make(map[string]repository.OrderItem),
+	}
+	record.order.Items = nil
+	if order.Promotion != nil {
+		promotion := *order.Promotion
+		record.order.Promotion = &promotion
+	}
+
+	for _, item := range order.Items {
+		item.Id = newID()
+		item.CreatedAt = currentTime
+		item.UpdatedAt = currentTime
+
+		record.items[item.Id] = *item
+		record.itemIds = append(record.itemIds, item.Id)
+	}
+
+	event, err := repository.NewOrderCreatedEvent(order)
+	if err != nil {
+		return nil, err
+	}
+
+	r.orders[order.Id] = record
+	r.ids = append(r.ids, order.Id)
+	r.outbox.add(event)
+
+	return order, nil
+}
+
+func (r *OrderRepository) GetOrder(ctx context.Context, id string) (*repository.Order, error) {
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.orders[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	return record.toOrder(), nil
+}
+
+func (r *OrderRepository) ListOrders(
+	ctx context.Context, filter *repository.OrderFilter, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+
+	if filter == nil {
+		filter = &repository.OrderFilter{}
+	} else if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	page, err := repository.ParsePage(opts, repository.OrderSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := make([]*repository.Order, 0)
+	for _, id := range r.ids {
+		record := r.orders[id]
+		if filter.Match(&record.order) {
+			orders = append(orders, record.toOrder())
+		}
+	}
+
+	orders, next := paginate(orders, page,
+		func(o *repository.Order) string { return o.SortValue(page.Ordering.Field) },
+		func(o *repository.Order) string { return o.Id },
+	)
+
+	return orders, next, nil
+}
+
+func (r *OrderRepository) ListOrdersByCustomer(
+	ctx context.Context, customerId string, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+
+	filter, opts, err := repository.CustomerOrders(customerId, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return r.ListOrders(ctx, filter, opts)
+}
+
+func (r *OrderRepository) UpdateOrderStatus(
+	ctx context.Context, orderId string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	// Apply the update to a copy, so that a rejected update leaves no trace.
+	order := record.order
+	change, err := order.ApplyStatusUpdate(update)
+	if err != nil {
+		return nil, err
+	}
+
+	order.UpdatedAt = now()
+
+	change.Id = newID()
+	change.CreatedAt = order.UpdatedAt
+
+	updated := *record
+	updated.order = order
+
+	event, err := repository.NewOrderStatusEvent(updated.toOrder(), change)
+	if err != nil {
+		return nil, err
+	}
+
+	record.order = order
+	record.history = append(record.history, *change)
+	r.outbox.add(event)
+
+	return record.toOrder(), nil
+}
+
+func (r *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[id]; !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	delete(r.orders, id)
+	r.ids = removeID(r.ids, id)
+
+	return nil
+}
+
+func (r *OrderRepository) GetOrderHistory(
+	ctx context.Context, orderId string) ([]*repository.OrderStatusChange, error) {
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	history := make([]*repository.OrderStatusChange, 0, len(record.history))
+	for _, change := range record.history {
+		change := change
+		history = append(history, &change)
+	}
+
+	return history, nil
+}
+
+func (r *OrderRepository) ApplyOrderPromotion(
+	ctx context.Context, orderId string, promotion *repository.AppliedPromotion, replaces string) (*repository.Order, error) {
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	if err := record.order.CheckReplacedPromotion(replaces); err != nil {
+		return nil, err
+	}
+
+	// Update the totals of a copy, so that a rejected promotion leaves no trace.
+	order := record.toOrder()
+	if err := order.PrepareChange("promotion"); err != nil {
+		return nil, err
+	}
+
+	applied := *promotion
+	order.Promotion = &applied
+
+	if err := order.UpdateTotals(); err != nil {
+		return nil, err
+	}
+
+	// The discount changes the taxes of the items too.
+	for _, item := range order.Items {
+		record.items[item.Id] = *item
+	}
+
+	order.UpdatedAt = now()
+	order.Items = nil
+	record.order = *order
+
+	return record.toOrder(), nil
+}
+
+func (r *OrderRepository) CreateOrderItem(
+	ctx context.Context, orderId string, orderItem *repository.OrderItem) (*repository.OrderItem, error) {
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	// Set CreatedAt and UpdatedAt to the current time
+	currentTime := now()
+
+	orderItem.CreatedAt = currentTime
+	orderItem.UpdatedAt = currentTime
+
+	err := orderItem.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid order item details provided: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	orderItem.Id = newID()
+
+	if err := record.updateItems(append(record.toOrderItems(), orderItem)); err != nil {
+		return nil, err
+	}
+
+	*orderItem = record.items[orderItem.Id]
+
+	return orderItem, nil
+}
+
+func (r *OrderRepository) GetOrderItem(
+	ctx context.Context, orderId string, orderItemId string) (*repository.OrderItem, error) {
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	} else if orderItemId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order item id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+	}
+
+	orderItem, ok := record.items[orderItemId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+	}
+
+	return &orderItem, nil
+}
+
+func (r *OrderRepository) ListOrderItems(ctx context.Context, orderId string) ([]*repository.OrderItem, error) {
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	return record.toOrderItems(), nil
+}
+
+func (r *OrderRepository) UpdateOrderItem(
+	ctx context.Context, orderId string, orderItemId string, update *repository.OrderItemUpdate) (*repository.OrderItem, error) {
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	} else if orderItemId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order item id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+	}
+
+	orderItem, ok := record.items[orderItemId]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+	}
+
+	if v := update.Quantity; v != nil {
+		orderItem.Quantity = *v
+	}
+
+	err := orderItem.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid order item details provided: %v", err)
+	}
+
+	// Set UpdatedAt to the current time
+	orderItem.UpdatedAt = now()
+
+	items := record.toOrderItems()
+	for i, item := range items {
+		if item.Id == orderItemId {
+			items[i] = &orderItem
+		}
+	}
+
+	if err := record.updateItems(items); err != nil {
+		return nil, err
+	}
+
+	orderItem = record.items[orderItemId]
+
+	return &orderItem, nil
+}
+
+func (r *OrderRepository) DeleteOrderItem(ctx context.Context, orderId string, orderItemId string) error {
+	if orderId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order id is required")
+	} else if orderItemId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order item id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.orders[orderId]
+	if !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+	}
+
+	if _, ok := record.items[orderItemId]; !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+	}
+
+	items := make([]*repository.OrderItem, 0, len(record.itemIds))
+	for _, item := range record.toOrderItems() {
+		if item.Id != orderItemId {
+			items = append(items, item)
+		}
+	}
+
+	return record.updateItems(items)
+}
+
+// toOrder returns a copy of the stored order with its items attached, so that
+// callers can never mutate repository state through the returned value.
+func (o *orderRecord) toOrder() *repository.Order {
+	order := o.order
+	order.Items = o.toOrderItems()
+
+	if order.Promotion != nil {
+		promotion := *order.Promotion
+		order.Promotion = &promotion
+	}
+
+	return &order
+}
+
+// updateItems replaces the items of the order and recomputes its totals. If
+// the items cannot change or the totals cannot be computed nothing is changed.
+func (o *orderRecord) updateItems(items []*repository.OrderItem) error {
+	order := o.order
+	if err := order.PrepareChange("items"); err != nil {
+		return err
+	}
+
+	order.Items = items
+
+	if err := order.UpdateTotals(); err != nil {
+		return err
+	}
+
+	o.itemIds = make([]string, 0, len(items))
+	o.items = make(map[string]repository.OrderItem, len(items))
+	for _, item := range items {
+		o.itemIds = append(o.itemIds, item.Id)
+		o.items[item.Id] = *item
+	}
+
+	order.Items = nil
+	o.order = order
+
+	return nil
+}
+
+func (o *orderRecord) toOrderItems() []*repository.OrderItem {
+	orderItems := make([]*repository.OrderItem, 0, len(o.itemIds))
+	for _, id := range o.itemIds {
+		item := o.items[id]
+		orderItems = append(orderItems, &item)
+	}
+
+	return orderItems
+}
@@ -0,0 +1,87 @@
+package memory_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestOrderRepository(t *testing.T) {
+	repositorytest.TestOrderRepository(t, func(t *testing.T) repository.OrderRepository {
+		return memory.NewOrderRepository()
+	})
+}
+
+func newTestOrder() *repository.Order {
+	return &repository.Order{
+		CustomerId: "customer-1",
+		Items: []*repository.OrderItem{
+			{ProductId: "product-1", Quantity: 1},
+			{ProductId: "product-2", Quantity: 2},
+		},
+	}
+}
+
+func TestOrderRepository_ReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	orderRepository := memory.NewOrderRepository()
+
+	o, err := orderRepository.CreateOrder(ctx, newTestOrder())
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	// Mutating returned values must not leak into the repository.
+	o.CustomerId = "someone-else"
+	o.Items[0].Quantity = 100
+
+	stored, err := orderRepository.GetOrder(ctx, o.Id)
+	if err != nil {
+		t.Fatalf("OrderRepository.GetOrder() error = %v", err)
+	}
+	if stored.CustomerId != "customer-1" || stored.Items[0].Quantity != 1 {
+		t.Errorf("OrderRepository.GetOrder() = %+v, stored order was mutated", stored)
+	}
+}
+
+func TestOrderRepository_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	orderRepository := memory.NewOrderRepository()
+
+	o, err := orderRepository.CreateOrder(ctx, newTestOrder())
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := orderRepository.CreateOrderItem(ctx, o.Id, &repository.OrderItem{
+				ProductId: "product-3",
+				Quantity:  1,
+			}); err != nil {
+				t.Errorf("failed to create order item: %v", err)
+			}
+
+			if _, _, err := orderRepository.ListOrders(ctx, nil, nil); err != nil {
+				t.Errorf("failed to list orders: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := orderRepository.ListOrderItems(ctx, o.Id)
+	if err != nil {
+		t.Fatalf("OrderRepository.ListOrderItems() error = %v", err)
+	}
+	if len(items) != 52 {
+		t.Errorf("OrderRepository.ListOrderItems() returned %d items, want 52", len(items))
+	}
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+var _ repository.OutboxRepository = (*OutboxRepository)(nil)
+
+// OutboxRepository holds the events recorded by the repository it belongs
+// to, see OrderRepository.Outbox.
+type OutboxRepository struct {
+	mu     sync.Mutex
+	events []outbox.Event
+}
+
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{}
+}
+
+// add records the event. Callers hold the lock of the record the event is
+// about, so that events are added in the order of the changes.
+func (r *OutboxRepository) add(event *outbox.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.Id = newID()
+	event.CreatedAt = now()
+
+	r.events = append(r.events, *event)
+}
+
+func (r *OutboxRepository) ListOutboxEvents(ctx context.Context, limit int) ([]*outbox.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if limit > len(r.events) {
+		limit = len(r.events)
+	}
+
+	events := make([]*outbox.Event, 0, limit)
+	for _, event := range r.events[:limit] {
+		event := event
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	deleted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := r.events[:0]
+	for _, event := range r.events {
+		if !deleted[event.Id] {
+			events = append(events, event)
+		}
+	}
+	r.events = events
+
+	return nil
+}
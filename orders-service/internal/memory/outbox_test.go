@@ -0,0 +1,16 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.OrderRepository, repository.OutboxRepository) {
+		orders := memory.NewOrderRepository()
+		return orders, orders.Outbox()
+	})
+}
@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+var _ repository.ProductRepository = (*ProductRepository)(nil)
+
+type ProductRepository struct {
+	mu           sync.RWMutex
+	ids          []string // insertion order, used for listing
+	products     map[string]repository.Product
+	reservations map[string][]repository.StockItem
+}
+
+func NewProductRepository() *ProductRepository {
+	return &ProductRepository{
+		products:     make(map[string]repository.Product),
+		reservations: make(map[string][]repository.StockItem),
+	}
+}
+
+func (r *ProductRepository) CreateProduct(ctx context.Context, product *repository.Product) (*repository.Product, error) {
+	// Set CreatedAt and UpdatedAt to the current time
+	currentTime := now()
+
+	product.CreatedAt = currentTime
+	product.UpdatedAt = currentTime
+
+	err := product.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid product provided: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product.Id = newID()
+	product.Reserved = 0
+
+	r.products[product.Id] = *product
+	r.ids = append(r.ids, product.Id)
+
+	return product, nil
+}
+
+func (r *ProductRepository) GetProduct(ctx context.Context, id string) (*repository.Product, error) {
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "product not found")
+	}
+
+	return &product, nil
+}
+
+func (r *ProductRepository) ListProducts(
+	ctx context.Context, filter *repository.ProductFilter, opts *repository.ListOptions) ([]*repository.Product, string, error) {
+
+	if filter == nil {
+		filter = &repository.ProductFilter{}
+	} else if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	page, err := repository.ParsePage(opts, repository.ProductSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	products := make([]*repository.Product, 0)
+	for _, id := range r.ids {
+		product := r.products[id]
+		if filter.Match(&product) {
+			products = append(products, &product)
+		}
+	}
+
+	products, next := paginate(products, page,
+		func(p *repository.Product) string { return p.SortValue(page.Ordering.Field) },
+		func(p *repository.Product) string { return p.Id },
+	)
+
+	return products, next, nil
+}
+
+func (r *ProductRepository) UpdateProduct(
+	ctx context.Context, id string, update *repository.ProductUpdate) (*repository.Product, error) {
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	product, ok := r.products[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "product not found")
+	}
+
+	if p := update.Name; p != nil {
+		product.Name = *p
+	}
+
+	if p := update.Description; p != nil {
+		product.Description = *p
+	}
+
+	if p := update.Price; p != nil {
+		product.Price = *p
+	}
+
+	if p := update.Category; p != nil {
+		product.Category = *p
+	}
+
+	if p := update.Stock; p != nil {
+		product.Stock = pkg.UintPtr(*p)
+	}
+
+	err := product.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid product details provided: %v", err)
+	}
+
+	product.UpdatedAt = now()
+
+	r.products[id] = product
+
+	return &product, nil
+}
+
+func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.products[id]; !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "product not found")
+	}
+
+	delete(r.products, id)
+	r.ids = removeID(r.ids, id)
+
+	return nil
+}
+
+func (r *ProductRepository) ReserveStock(ctx context.Context, reservationId string, items []repository.StockItem) error {
+	if reservationId == "" {
+		return service.Errorf(service.INVALID_ERROR, "reservation id is required")
+	}
+
+	items, err := repository.MergeStockItems(items)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	replaced := r.reservations[reservationId]
+	products := r.copyProducts(repository.StockProductIds(replaced, items))
+
+	reserved, err := repository.ReplaceStockReservation(products, replaced, items)
+	if err != nil {
+		return err
+	}
+
+	r.storeProducts(products)
+	r.reservations[reservationId] = reserved
+
+	return nil
+}
+
+func (r *ProductRepository) ReleaseStock(ctx context.Context, reservationId string) error {
+	return r.settleStock(reservationId, (*repository.Product).Release)
+}
+
+func (r *ProductRepository) CommitStock(ctx context.Context, reservationId string) error {
+	return r.settleStock(reservationId, (*repository.Product).Commit)
+}
+
+// settleStock applies adjust to copies of the products of the reservation and
+// only stores them, and forgets the reservation, once every adjustment
+// succeeded.
+func (r *ProductRepository) settleStock(reservationId string, adjust repository.StockAdjustment) error {
+	if reservationId == "" {
+		return service.Errorf(service.INVALID_ERROR, "reservation id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items := r.reservations[reservationId]
+	products := r.copyProducts(repository.StockProductIds(items))
+
+	if err := repository.SettleStockReservation(products, items, adjust); err != nil {
+		return err
+	}
+
+	r.storeProducts(products)
+	delete(r.reservations, reservationId)
+
+	return nil
+}
+
+// copyProducts returns copies of the products with the given ids that exist,
+// for stock adjustments to change before they are stored. The caller must
+// hold the lock.
+func (r *ProductRepository) copyProducts(ids []string) map[string]*repository.Product {
+	products := make(map[string]*repository.Product, len(ids))
+	for _, id := range ids {
+		if product, ok := r.products[id]; ok {
+			products[id] = &product
+		}
+	}
+
+	return products
+}
+
+// storeProducts stores adjusted copies of products. The caller must hold the
+// lock.
+func (r *ProductRepository) storeProducts(products map[string]*repository.Product) {
+	currentTime := now()
+
+	for _, product := range products {
+		product.UpdatedAt = currentTime
+		r.products[product.Id] = *product
+	}
+}
@@ -0,0 +1,58 @@
+package memory_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func TestProductRepository(t *testing.T) {
+	repositorytest.TestProductRepository(t, func(t *testing.T) repository.ProductRepository {
+		return memory.NewProductRepository()
+	})
+}
+
+func TestProductRepository_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	productRepository := memory.NewProductRepository()
+
+	const n = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p, err := productRepository.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: money.New(100, "KES")})
+			if err != nil {
+				t.Errorf("failed to create product: %v", err)
+				return
+			}
+
+			if _, err := productRepository.UpdateProduct(ctx, p.Id, &repository.ProductUpdate{
+				Price: &money.Money{Amount: 200, Currency: "KES"},
+			}); err != nil {
+				t.Errorf("failed to update product: %v", err)
+			}
+
+			if _, _, err := productRepository.ListProducts(ctx, nil, nil); err != nil {
+				t.Errorf("failed to list products: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	products, _, err := productRepository.ListProducts(ctx, nil, nil)
+	if err != nil {
+		t.Fatalf("ProductRepository.ListProducts() error = %v", err)
+	}
+	if len(products) != n {
+		t.Errorf("ProductRepository.ListProducts() returned %d products, want %d", len(products), n)
+	}
+}
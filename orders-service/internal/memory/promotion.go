@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+var _ repository.PromotionRepository = (*PromotionRepository)(nil)
+
+type PromotionRepository struct {
+	mu         sync.RWMutex
+	ids        []string // insertion order, used for listing
+	promotions map[string]repository.Promotion
+
+	// redemptions holds the redemptions of each promotion, keyed by order id.
+	redemptions map[string]map[string]repository.PromotionRedemption
+}
+
+func NewPromotionRepository() *PromotionRepository {
+	return &PromotionRepository{
+		promotions:  make(map[string]repository.Promotion),
+		redemptions: make(map[string]map[string]repository.PromotionRedemption),
+	}
+}
+
+func (r *PromotionRepository) CreatePromotion(
+	ctx context.Context, promotion *repository.Promotion) (*repository.Promotion, error) {
+
+	// Set CreatedAt and UpdatedAt to the current time
+	currentTime := now()
+
+	promotion.CreatedAt = currentTime
+	promotion.UpdatedAt = currentTime
+	promotion.Redemptions = 0
+
+	err := promotion.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid promotion provided: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.findCode(promotion.Code); ok {
+		return nil, service.Errorf(service.ALREADY_EXISTS_ERROR, "promotion already exists")
+	}
+
+	promotion.Id = newID()
+
+	r.promotions[promotion.Id] = *promotion
+	r.ids = append(r.ids, promotion.Id)
+
+	return promotion, nil
+}
+
+func (r *PromotionRepository) GetPromotion(ctx context.Context, id string) (*repository.Promotion, error) {
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	promotion, ok := r.promotions[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	}
+
+	return &promotion, nil
+}
+
+func (r *PromotionRepository) GetPromotionByCode(ctx context.Context, code string) (*repository.Promotion, error) {
+	if code == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "code is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	promotion, ok := r.findCode(code)
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	}
+
+	return &promotion, nil
+}
+
+func (r *PromotionRepository) ListPromotions(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Promotion, string, error) {
+
+	page, err := repository.ParsePage(opts, repository.PromotionSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	promotions := make([]*repository.Promotion, 0, len(r.ids))
+	for _, id := range r.ids {
+		promotion := r.promotions[id]
+		promotions = append(promotions, &promotion)
+	}
+
+	promotions, next := paginate(promotions, page,
+		func(p *repository.Promotion) string { return p.SortValue(page.Ordering.Field) },
+		func(p *repository.Promotion) string { return p.Id },
+	)
+
+	return promotions, next, nil
+}
+
+func (r *PromotionRepository) UpdatePromotion(
+	ctx context.Context, id string, update *repository.PromotionUpdate) (*repository.Promotion, error) {
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	promotion, ok := r.promotions[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	}
+
+	if v := update.Description; v != nil {
+		promotion.Description = *v
+	}
+
+	if v := update.StartsAt; v != nil {
+		promotion.StartsAt = *v
+	}
+
+	if v := update.EndsAt; v != nil {
+		promotion.EndsAt = *v
+	}
+
+	if v := update.UsageLimit; v != nil {
+		promotion.UsageLimit = *v
+	}
+
+	if v := update.PerCustomerLimit; v != nil {
+		promotion.PerCustomerLimit = *v
+	}
+
+	err := promotion.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid promotion details provided: %v", err)
+	}
+
+	promotion.UpdatedAt = now()
+
+	r.promotions[id] = promotion
+
+	return &promotion, nil
+}
+
+func (r *PromotionRepository) DeletePromotion(ctx context.Context, id string) error {
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.promotions[id]; !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	}
+
+	delete(r.promotions, id)
+	delete(r.redemptions, id)
+	r.ids = removeID(r.ids, id)
+
+	return nil
+}
+
+func (r *PromotionRepository) RedeemPromotion(ctx context.Context, redemption *repository.PromotionRedemption) error {
+	if err := redemption.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	promotion, ok := r.promotions[redemption.PromotionId]
+	if !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	}
+
+	redemptions := r.redemptions[promotion.Id]
+	if _, ok := redemptions[redemption.OrderId]; ok {
+		return nil
+	}
+
+	var customerRedemptions uint
+	for _, other := range redemptions {
+		if other.CustomerId == redemption.CustomerId {
+			customerRedemptions++
+		}
+	}
+
+	if err := promotion.CheckRedemption(customerRedemptions); err != nil {
+		return err
+	}
+
+	if redemptions == nil {
+		redemptions = make(map[string]repository.PromotionRedemption)
+		r.redemptions[promotion.Id] = redemptions
+	}
+
+	redemption.CreatedAt = now()
+	redemptions[redemption.OrderId] = *redemption
+
+	promotion.Redemptions++
+	r.promotions[promotion.Id] = promotion
+
+	return nil
+}
+
+func (r *PromotionRepository) ReleasePromotion(ctx context.Context, promotionId string, orderId string) error {
+	if promotionId == "" {
+		return service.Errorf(service.INVALID_ERROR, "promotion id is required")
+	} else if orderId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.redemptions[promotionId][orderId]; !ok {
+		return nil
+	}
+
+	delete(r.redemptions[promotionId], orderId)
+
+	promotion := r.promotions[promotionId]
+	promotion.Redemptions--
+	r.promotions[promotionId] = promotion
+
+	return nil
+}
+
+// findCode returns the promotion with the code. The caller must hold the lock.
+func (r *PromotionRepository) findCode(code string) (repository.Promotion, bool) {
+	for _, promotion := range r.promotions {
+		if promotion.Code == code {
+			return promotion, true
+		}
+	}
+
+	return repository.Promotion{}, false
+}
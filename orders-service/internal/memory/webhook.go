@@ -0,0 +1,272 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+var _ repository.WebhookRepository = (*WebhookRepository)(nil)
+
+type WebhookRepository struct {
+	mu              sync.RWMutex
+	subscriptionIds []string // insertion order, used for listing
+	subscriptions   map[string]repository.WebhookSubscription
+	deliveries      map[string]repository.WebhookDelivery
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		subscriptions: make(map[string]repository.WebhookSubscription),
+		deliveries:    make(map[string]repository.WebhookDelivery),
+	}
+}
+
+func (r *WebhookRepository) CreateWebhookSubscription(
+	ctx context.Context, subscription *repository.WebhookSubscription) (*repository.WebhookSubscription, error) {
+
+	currentTime := now()
+
+	subscription.CreatedAt = currentTime
+	subscription.UpdatedAt = currentTime
+
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscription.Id = newID()
+
+	r.subscriptions[subscription.Id] = copySubscription(subscription)
+	r.subscriptionIds = append(r.subscriptionIds, subscription.Id)
+
+	return subscription, nil
+}
+
+func (r *WebhookRepository) GetWebhookSubscription(
+	ctx context.Context, id string) (*repository.WebhookSubscription, error) {
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "webhook subscription not found")
+	}
+
+	subscription = copySubscription(&subscription)
+
+	return &subscription, nil
+}
+
+func (r *WebhookRepository) ListWebhookSubscriptions(ctx context.Context) ([]*repository.WebhookSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscriptions := make([]*repository.WebhookSubscription, 0, len(r.subscriptionIds))
+	for _, id := range r.subscriptionIds {
+		subscription := r.subscriptions[id]
+		c := copySubscription(&subscription)
+		subscriptions = append(subscriptions, &c)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subscriptions[id]; !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "webhook subscription not found")
+	}
+
+	delete(r.subscriptions, id)
+	r.subscriptionIds = removeID(r.subscriptionIds, id)
+
+	return nil
+}
+
+func (r *WebhookRepository) CreateWebhookDelivery(
+	ctx context.Context, delivery *repository.WebhookDelivery) (*repository.WebhookDelivery, error) {
+
+	currentTime := now()
+
+	delivery.CreatedAt = currentTime
+	delivery.UpdatedAt = currentTime
+
+	if err := delivery.Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.deliveries {
+		if d.SubscriptionId == delivery.SubscriptionId && d.EventId == delivery.EventId {
+			return nil, service.Errorf(service.ALREADY_EXISTS_ERROR, "webhook delivery already exists")
+		}
+	}
+
+	delivery.Id = newID()
+
+	r.deliveries[delivery.Id] = copyDelivery(delivery)
+
+	return delivery, nil
+}
+
+func (r *WebhookRepository) GetWebhookDelivery(ctx context.Context, id string) (*repository.WebhookDelivery, error) {
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "webhook delivery not found")
+	}
+
+	delivery = copyDelivery(&delivery)
+
+	return &delivery, nil
+}
+
+func (r *WebhookRepository) ListWebhookDeliveries(ctx context.Context, filter *repository.WebhookDeliveryFilter,
+	opts *repository.ListOptions) ([]*repository.WebhookDelivery, string, error) {
+
+	if filter == nil {
+		filter = &repository.WebhookDeliveryFilter{}
+	} else if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	page, err := repository.ParsePage(opts, repository.WebhookDeliverySortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := make([]*repository.WebhookDelivery, 0)
+	for _, delivery := range r.deliveries {
+		if filter.Match(&delivery) {
+			c := copyDelivery(&delivery)
+			deliveries = append(deliveries, &c)
+		}
+	}
+
+	deliveries, next := paginate(deliveries, page,
+		func(d *repository.WebhookDelivery) string { return d.SortValue(page.Ordering.Field) },
+		func(d *repository.WebhookDelivery) string { return d.Id },
+	)
+
+	return deliveries, next, nil
+}
+
+func (r *WebhookRepository) ListDueWebhookDeliveries(
+	ctx context.Context, at time.Time, limit int) ([]*repository.WebhookDelivery, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type dueDelivery struct {
+		delivery      *repository.WebhookDelivery
+		nextAttemptAt time.Time
+	}
+
+	due := make([]dueDelivery, 0)
+	for _, delivery := range r.deliveries {
+		if delivery.Status != repository.WebhookDeliveryStatusPending {
+			continue
+		}
+
+		nextAttemptAt, err := time.Parse(time.RFC3339, delivery.NextAttemptAt)
+		if err != nil || nextAttemptAt.After(at) {
+			continue
+		}
+
+		c := copyDelivery(&delivery)
+		due = append(due, dueDelivery{&c, nextAttemptAt})
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].nextAttemptAt.Equal(due[j].nextAttemptAt) {
+			return due[i].delivery.Id < due[j].delivery.Id
+		}
+
+		return due[i].nextAttemptAt.Before(due[j].nextAttemptAt)
+	})
+
+	deliveries := make([]*repository.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		deliveries = append(deliveries, d.delivery)
+	}
+
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) UpdateWebhookDelivery(ctx context.Context, id string,
+	update *repository.WebhookDeliveryUpdate) (*repository.WebhookDelivery, error) {
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "webhook delivery not found")
+	}
+
+	if err := delivery.ApplyUpdate(update); err != nil {
+		return nil, err
+	}
+
+	delivery.UpdatedAt = now()
+	r.deliveries[id] = delivery
+
+	delivery = copyDelivery(&delivery)
+
+	return &delivery, nil
+}
+
+// copySubscription copies the subscription so that callers cannot change the
+// stored event types.
+func copySubscription(s *repository.WebhookSubscription) repository.WebhookSubscription {
+	c := *s
+	c.EventTypes = append([]string(nil), s.EventTypes...)
+
+	return c
+}
+
+// copyDelivery copies the delivery so that callers cannot change the stored
+// payload.
+func copyDelivery(d *repository.WebhookDelivery) repository.WebhookDelivery {
+	c := *d
+	c.Payload = append([]byte(nil), d.Payload...)
+
+	return c
+}
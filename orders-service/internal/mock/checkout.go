@@ -0,0 +1,45 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+)
+
+var _ repository.CheckoutSagaRepository = (*CheckoutSagaRepository)(nil)
+
+type CheckoutSagaRepository struct {
+	CreateCheckoutSagaFunc func(ctx context.Context, saga *repository.CheckoutSaga) (*repository.CheckoutSaga, error)
+	GetCheckoutSagaFunc    func(ctx context.Context, id string) (*repository.CheckoutSaga, error)
+	UpdateCheckoutSagaFunc func(
+		ctx context.Context, id string, update *repository.CheckoutSagaUpdate) (*repository.CheckoutSaga, error)
+	ListUnfinishedCheckoutSagasFunc func(ctx context.Context) ([]*repository.CheckoutSaga, error)
+}
+
+// NewCheckoutSagaRepository returns a mock that delegates every call to r.
+func NewCheckoutSagaRepository(r repository.CheckoutSagaRepository) *CheckoutSagaRepository {
+	return &CheckoutSagaRepository{
+		CreateCheckoutSagaFunc:          r.CreateCheckoutSaga,
+		GetCheckoutSagaFunc:             r.GetCheckoutSaga,
+		UpdateCheckoutSagaFunc:          r.UpdateCheckoutSaga,
+		ListUnfinishedCheckoutSagasFunc: r.ListUnfinishedCheckoutSagas,
+	}
+}
+
+func (m *CheckoutSagaRepository) CreateCheckoutSaga(
+	ctx context.Context, saga *repository.CheckoutSaga) (*repository.CheckoutSaga, error) {
+	return m.CreateCheckoutSagaFunc(ctx, saga)
+}
+
+func (m *CheckoutSagaRepository) GetCheckoutSaga(ctx context.Context, id string) (*repository.CheckoutSaga, error) {
+	return m.GetCheckoutSagaFunc(ctx, id)
+}
+
+func (m *CheckoutSagaRepository) UpdateCheckoutSaga(
+	ctx context.Context, id string, update *repository.CheckoutSagaUpdate) (*repository.CheckoutSaga, error) {
+	return m.UpdateCheckoutSagaFunc(ctx, id, update)
+}
+
+func (m *CheckoutSagaRepository) ListUnfinishedCheckoutSagas(ctx context.Context) ([]*repository.CheckoutSaga, error) {
+	return m.ListUnfinishedCheckoutSagasFunc(ctx)
+}
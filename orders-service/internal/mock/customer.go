@@ -11,11 +11,22 @@ var _ repository.CustomerRepository = (*CustomerRepository)(nil)
 type CustomerRepository struct {
 	CreateCustomerFunc func(ctx context.Context, p *repository.Customer) (*repository.Customer, error)
 	GetCustomerFunc    func(ctx context.Context, id string) (*repository.Customer, error)
-	ListCustomersFunc  func(ctx context.Context) ([]*repository.Customer, error)
+	ListCustomersFunc  func(ctx context.Context, opts *repository.ListOptions) ([]*repository.Customer, string, error)
 	UpdateCustomerFunc func(ctx context.Context, id string, update *repository.CustomerUpdate) (*repository.Customer, error)
 	DeleteCustomerFunc func(ctx context.Context, id string) error
 }
 
+// NewCustomerRepository returns a mock that delegates every call to r.
+func NewCustomerRepository(r repository.CustomerRepository) *CustomerRepository {
+	return &CustomerRepository{
+		CreateCustomerFunc: r.CreateCustomer,
+		GetCustomerFunc:    r.GetCustomer,
+		ListCustomersFunc:  r.ListCustomers,
+		UpdateCustomerFunc: r.UpdateCustomer,
+		DeleteCustomerFunc: r.DeleteCustomer,
+	}
+}
+
 func (m *CustomerRepository) CreateCustomer(ctx context.Context, p *repository.Customer) (*repository.Customer, error) {
 	return m.CreateCustomerFunc(ctx, p)
 }
@@ -24,8 +35,9 @@ func (m *CustomerRepository) GetCustomer(ctx context.Context, id string) (*repos
 	return m.GetCustomerFunc(ctx, id)
 }
 
-func (m *CustomerRepository) ListCustomers(ctx context.Context) ([]*repository.Customer, error) {
-	return m.ListCustomersFunc(ctx)
+func (m *CustomerRepository) ListCustomers(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Customer, string, error) {
+	return m.ListCustomersFunc(ctx, opts)
 }
 
 func (m *CustomerRepository) UpdateCustomer(ctx context.Context, id string, update *repository.CustomerUpdate,
@@ -0,0 +1,55 @@
+package mock_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/mock"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+// The delegating mocks must be indistinguishable from the repository they wrap.
+
+func TestProductRepository(t *testing.T) {
+	repositorytest.TestProductRepository(t, func(t *testing.T) repository.ProductRepository {
+		return mock.NewProductRepository(memory.NewProductRepository())
+	})
+}
+
+func TestCustomerRepository(t *testing.T) {
+	repositorytest.TestCustomerRepository(t, func(t *testing.T) repository.CustomerRepository {
+		return mock.NewCustomerRepository(memory.NewCustomerRepository())
+	})
+}
+
+func TestOrderRepository(t *testing.T) {
+	repositorytest.TestOrderRepository(t, func(t *testing.T) repository.OrderRepository {
+		return mock.NewOrderRepository(memory.NewOrderRepository())
+	})
+}
+
+func TestPromotionRepository(t *testing.T) {
+	repositorytest.TestPromotionRepository(t, func(t *testing.T) repository.PromotionRepository {
+		return mock.NewPromotionRepository(memory.NewPromotionRepository())
+	})
+}
+
+func TestCheckoutSagaRepository(t *testing.T) {
+	repositorytest.TestCheckoutSagaRepository(t, func(t *testing.T) repository.CheckoutSagaRepository {
+		return mock.NewCheckoutSagaRepository(memory.NewCheckoutSagaRepository())
+	})
+}
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.OrderRepository, repository.OutboxRepository) {
+		orders := memory.NewOrderRepository()
+		return mock.NewOrderRepository(orders), mock.NewOutboxRepository(orders.Outbox())
+	})
+}
+
+func TestWebhookRepository(t *testing.T) {
+	repositorytest.TestWebhookRepository(t, func(t *testing.T) repository.WebhookRepository {
+		return mock.NewWebhookRepository(memory.NewWebhookRepository())
+	})
+}
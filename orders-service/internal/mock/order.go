@@ -4,17 +4,22 @@ import (
 	"context"
 
 	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
-	"github.com/Mik3y-F/order-management-system/orders/pkg"
 )
 
 var _ repository.OrderRepository = (*OrderRepository)(nil)
 
 type OrderRepository struct {
-	CreateOrderFunc       func(ctx context.Context, order *repository.Order) (*repository.Order, error)
-	GetOrderFunc          func(ctx context.Context, id string) (*repository.Order, error)
-	ListOrdersFunc        func(ctx context.Context) ([]*repository.Order, error)
-	UpdateOrderStatusFunc func(ctx context.Context, id string, status pkg.OrderStatus) (*repository.Order, error)
-	DeleteOrderFunc       func(ctx context.Context, id string) error
+	CreateOrderFunc func(ctx context.Context, order *repository.Order) (*repository.Order, error)
+	GetOrderFunc    func(ctx context.Context, id string) (*repository.Order, error)
+	ListOrdersFunc  func(
+		ctx context.Context, filter *repository.OrderFilter, opts *repository.ListOptions) ([]*repository.Order, string, error)
+	ListOrdersByCustomerFunc func(
+		ctx context.Context, customerId string, opts *repository.ListOptions) ([]*repository.Order, string, error)
+	UpdateOrderStatusFunc   func(ctx context.Context, id string, update *repository.OrderStatusUpdate) (*repository.Order, error)
+	DeleteOrderFunc         func(ctx context.Context, id string) error
+	GetOrderHistoryFunc     func(ctx context.Context, orderId string) ([]*repository.OrderStatusChange, error)
+	ApplyOrderPromotionFunc func(
+		ctx context.Context, orderId string, promotion *repository.AppliedPromotion, replaces string) (*repository.Order, error)
 
 	CreateOrderItemFunc func(ctx context.Context, orderId string, item *repository.OrderItem) (*repository.OrderItem, error)
 	GetOrderItemFunc    func(ctx context.Context, orderId, itemId string) (*repository.OrderItem, error)
@@ -24,6 +29,26 @@ type OrderRepository struct {
 	DeleteOrderItemFunc func(ctx context.Context, orderId string, itemId string) error
 }
 
+// NewOrderRepository returns a mock that delegates every call to r.
+func NewOrderRepository(r repository.OrderRepository) *OrderRepository {
+	return &OrderRepository{
+		CreateOrderFunc:          r.CreateOrder,
+		GetOrderFunc:             r.GetOrder,
+		ListOrdersFunc:           r.ListOrders,
+		ListOrdersByCustomerFunc: r.ListOrdersByCustomer,
+		UpdateOrderStatusFunc:    r.UpdateOrderStatus,
+		DeleteOrderFunc:          r.DeleteOrder,
+		GetOrderHistoryFunc:      r.GetOrderHistory,
+		ApplyOrderPromotionFunc:  r.ApplyOrderPromotion,
+
+		CreateOrderItemFunc: r.CreateOrderItem,
+		GetOrderItemFunc:    r.GetOrderItem,
+		ListOrderItemsFunc:  r.ListOrderItems,
+		UpdateOrderItemFunc: r.UpdateOrderItem,
+		DeleteOrderItemFunc: r.DeleteOrderItem,
+	}
+}
+
 func (m *OrderRepository) CreateOrder(ctx context.Context, order *repository.Order) (*repository.Order, error) {
 	return m.CreateOrderFunc(ctx, order)
 }
@@ -32,19 +57,35 @@ func (m *OrderRepository) GetOrder(ctx context.Context, id string) (*repository.
 	return m.GetOrderFunc(ctx, id)
 }
 
-func (m *OrderRepository) ListOrders(ctx context.Context) ([]*repository.Order, error) {
-	return m.ListOrdersFunc(ctx)
+func (m *OrderRepository) ListOrders(
+	ctx context.Context, filter *repository.OrderFilter, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+	return m.ListOrdersFunc(ctx, filter, opts)
+}
+
+func (m *OrderRepository) ListOrdersByCustomer(
+	ctx context.Context, customerId string, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+	return m.ListOrdersByCustomerFunc(ctx, customerId, opts)
 }
 
 func (m *OrderRepository) UpdateOrderStatus(
-	ctx context.Context, id string, status pkg.OrderStatus) (*repository.Order, error) {
-	return m.UpdateOrderStatusFunc(ctx, id, status)
+	ctx context.Context, id string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
+	return m.UpdateOrderStatusFunc(ctx, id, update)
 }
 
 func (m *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
 	return m.DeleteOrderFunc(ctx, id)
 }
 
+func (m *OrderRepository) GetOrderHistory(
+	ctx context.Context, orderId string) ([]*repository.OrderStatusChange, error) {
+	return m.GetOrderHistoryFunc(ctx, orderId)
+}
+
+func (m *OrderRepository) ApplyOrderPromotion(
+	ctx context.Context, orderId string, promotion *repository.AppliedPromotion, replaces string) (*repository.Order, error) {
+	return m.ApplyOrderPromotionFunc(ctx, orderId, promotion, replaces)
+}
+
 func (m *OrderRepository) CreateOrderItem(
 	ctx context.Context, orderId string, item *repository.OrderItem) (*repository.OrderItem, error) {
 	return m.CreateOrderItemFunc(ctx, orderId, item)
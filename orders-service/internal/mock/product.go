@@ -11,9 +11,30 @@ var _ repository.ProductRepository = (*ProductRepository)(nil)
 type ProductRepository struct {
 	CreateProductFunc func(ctx context.Context, p *repository.Product) (*repository.Product, error)
 	GetProductFunc    func(ctx context.Context, id string) (*repository.Product, error)
-	ListProductsFunc  func(ctx context.Context) ([]*repository.Product, error)
+	ListProductsFunc  func(
+		ctx context.Context, filter *repository.ProductFilter, opts *repository.ListOptions) ([]*repository.Product, string, error)
 	UpdateProductFunc func(ctx context.Context, id string, update *repository.ProductUpdate) (*repository.Product, error)
 	DeleteProductFunc func(ctx context.Context, id string) error
+
+	ReserveStockFunc func(ctx context.Context, reservationId string, items []repository.StockItem) error
+	ReleaseStockFunc func(ctx context.Context, reservationId string) error
+	CommitStockFunc  func(ctx context.Context, reservationId string) error
+}
+
+// NewProductRepository returns a mock that delegates every call to r. Tests
+// can then replace individual functions and keep working storage for the rest.
+func NewProductRepository(r repository.ProductRepository) *ProductRepository {
+	return &ProductRepository{
+		CreateProductFunc: r.CreateProduct,
+		GetProductFunc:    r.GetProduct,
+		ListProductsFunc:  r.ListProducts,
+		UpdateProductFunc: r.UpdateProduct,
+		DeleteProductFunc: r.DeleteProduct,
+
+		ReserveStockFunc: r.ReserveStock,
+		ReleaseStockFunc: r.ReleaseStock,
+		CommitStockFunc:  r.CommitStock,
+	}
 }
 
 func (m *ProductRepository) CreateProduct(ctx context.Context, p *repository.Product) (*repository.Product, error) {
@@ -24,8 +45,9 @@ func (m *ProductRepository) GetProduct(ctx context.Context, id string) (*reposit
 	return m.GetProductFunc(ctx, id)
 }
 
-func (m *ProductRepository) ListProducts(ctx context.Context) ([]*repository.Product, error) {
-	return m.ListProductsFunc(ctx)
+func (m *ProductRepository) ListProducts(
+	ctx context.Context, filter *repository.ProductFilter, opts *repository.ListOptions) ([]*repository.Product, string, error) {
+	return m.ListProductsFunc(ctx, filter, opts)
 }
 
 func (m *ProductRepository) UpdateProduct(ctx context.Context, id string, update *repository.ProductUpdate,
@@ -36,3 +58,15 @@ func (m *ProductRepository) UpdateProduct(ctx context.Context, id string, update
 func (m *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
 	return m.DeleteProductFunc(ctx, id)
 }
+
+func (m *ProductRepository) ReserveStock(ctx context.Context, reservationId string, items []repository.StockItem) error {
+	return m.ReserveStockFunc(ctx, reservationId, items)
+}
+
+func (m *ProductRepository) ReleaseStock(ctx context.Context, reservationId string) error {
+	return m.ReleaseStockFunc(ctx, reservationId)
+}
+
+func (m *ProductRepository) CommitStock(ctx context.Context, reservationId string) error {
+	return m.CommitStockFunc(ctx, reservationId)
+}
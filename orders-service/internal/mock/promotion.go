@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+)
+
+var _ repository.PromotionRepository = (*PromotionRepository)(nil)
+
+type PromotionRepository struct {
+	CreatePromotionFunc    func(ctx context.Context, p *repository.Promotion) (*repository.Promotion, error)
+	GetPromotionFunc       func(ctx context.Context, id string) (*repository.Promotion, error)
+	GetPromotionByCodeFunc func(ctx context.Context, code string) (*repository.Promotion, error)
+	ListPromotionsFunc     func(ctx context.Context, opts *repository.ListOptions) ([]*repository.Promotion, string, error)
+	UpdatePromotionFunc    func(
+		ctx context.Context, id string, update *repository.PromotionUpdate) (*repository.Promotion, error)
+	DeletePromotionFunc  func(ctx context.Context, id string) error
+	RedeemPromotionFunc  func(ctx context.Context, redemption *repository.PromotionRedemption) error
+	ReleasePromotionFunc func(ctx context.Context, promotionId string, orderId string) error
+}
+
+// NewPromotionRepository returns a mock that delegates every call to r.
+func NewPromotionRepository(r repository.PromotionRepository) *PromotionRepository {
+	return &PromotionRepository{
+		CreatePromotionFunc:    r.CreatePromotion,
+		GetPromotionFunc:       r.GetPromotion,
+		GetPromotionByCodeFunc: r.GetPromotionByCode,
+		ListPromotionsFunc:     r.ListPromotions,
+		UpdatePromotionFunc:    r.UpdatePromotion,
+		DeletePromotionFunc:    r.DeletePromotion,
+		RedeemPromotionFunc:    r.RedeemPromotion,
+		ReleasePromotionFunc:   r.ReleasePromotion,
+	}
+}
+
+func (m *PromotionRepository) CreatePromotion(
+	ctx context.Context, p *repository.Promotion) (*repository.Promotion, error) {
+	return m.CreatePromotionFunc(ctx, p)
+}
+
+func (m *PromotionRepository) GetPromotion(ctx context.Context, id string) (*repository.Promotion, error) {
+	return m.GetPromotionFunc(ctx, id)
+}
+
+func (m *PromotionRepository) GetPromotionByCode(ctx context.Context, code string) (*repository.Promotion, error) {
+	return m.GetPromotionByCodeFunc(ctx, code)
+}
+
+func (m *PromotionRepository) ListPromotions(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Promotion, string, error) {
+	return m.ListPromotionsFunc(ctx, opts)
+}
+
+func (m *PromotionRepository) UpdatePromotion(
+	ctx context.Context, id string, update *repository.PromotionUpdate) (*repository.Promotion, error) {
+	return m.UpdatePromotionFunc(ctx, id, update)
+}
+
+func (m *PromotionRepository) DeletePromotion(ctx context.Context, id string) error {
+	return m.DeletePromotionFunc(ctx, id)
+}
+
+func (m *PromotionRepository) RedeemPromotion(ctx context.Context, redemption *repository.PromotionRedemption) error {
+	return m.RedeemPromotionFunc(ctx, redemption)
+}
+
+func (m *PromotionRepository) ReleasePromotion(ctx context.Context, promotionId string, orderId string) error {
+	return m.ReleasePromotionFunc(ctx, promotionId, orderId)
+}
@@ -0,0 +1,84 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+)
+
+var _ repository.WebhookRepository = (*WebhookRepository)(nil)
+
+type WebhookRepository struct {
+	CreateWebhookSubscriptionFunc func(
+		ctx context.Context, s *repository.WebhookSubscription) (*repository.WebhookSubscription, error)
+	GetWebhookSubscriptionFunc    func(ctx context.Context, id string) (*repository.WebhookSubscription, error)
+	ListWebhookSubscriptionsFunc  func(ctx context.Context) ([]*repository.WebhookSubscription, error)
+	DeleteWebhookSubscriptionFunc func(ctx context.Context, id string) error
+	CreateWebhookDeliveryFunc     func(
+		ctx context.Context, d *repository.WebhookDelivery) (*repository.WebhookDelivery, error)
+	GetWebhookDeliveryFunc    func(ctx context.Context, id string) (*repository.WebhookDelivery, error)
+	ListWebhookDeliveriesFunc func(ctx context.Context, filter *repository.WebhookDeliveryFilter,
+		opts *repository.ListOptions) ([]*repository.WebhookDelivery, string, error)
+	ListDueWebhookDeliveriesFunc func(
+		ctx context.Context, at time.Time, limit int) ([]*repository.WebhookDelivery, error)
+	UpdateWebhookDeliveryFunc func(ctx context.Context, id string,
+		update *repository.WebhookDeliveryUpdate) (*repository.WebhookDelivery, error)
+}
+
+// NewWebhookRepository returns a mock that delegates every call to r.
+func NewWebhookRepository(r repository.WebhookRepository) *WebhookRepository {
+	return &WebhookRepository{
+		CreateWebhookSubscriptionFunc: r.CreateWebhookSubscription,
+		GetWebhookSubscriptionFunc:    r.GetWebhookSubscription,
+		ListWebhookSubscriptionsFunc:  r.ListWebhookSubscriptions,
+		DeleteWebhookSubscriptionFunc: r.DeleteWebhookSubscription,
+		CreateWebhookDeliveryFunc:     r.CreateWebhookDelivery,
+		GetWebhookDeliveryFunc:        r.GetWebhookDelivery,
+		ListWebhookDeliveriesFunc:     r.ListWebhookDeliveries,
+		ListDueWebhookDeliveriesFunc:  r.ListDueWebhookDeliveries,
+		UpdateWebhookDeliveryFunc:     r.UpdateWebhookDelivery,
+	}
+}
+
+func (m *WebhookRepository) CreateWebhookSubscription(
+	ctx context.Context, s *repository.WebhookSubscription) (*repository.WebhookSubscription, error) {
+	return m.CreateWebhookSubscriptionFunc(ctx, s)
+}
+
+func (m *WebhookRepository) GetWebhookSubscription(
+	ctx context.Context, id string) (*repository.WebhookSubscription, error) {
+	return m.GetWebhookSubscriptionFunc(ctx, id)
+}
+
+func (m *WebhookRepository) ListWebhookSubscriptions(ctx context.Context) ([]*repository.WebhookSubscription, error) {
+	return m.ListWebhookSubscriptionsFunc(ctx)
+}
+
+func (m *WebhookRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	return m.DeleteWebhookSubscriptionFunc(ctx, id)
+}
+
+func (m *WebhookRepository) CreateWebhookDelivery(
+	ctx context.Context, d *repository.WebhookDelivery) (*repository.WebhookDelivery, error) {
+	return m.CreateWebhookDeliveryFunc(ctx, d)
+}
+
+func (m *WebhookRepository) GetWebhookDelivery(ctx context.Context, id string) (*repository.WebhookDelivery, error) {
+	return m.GetWebhookDeliveryFunc(ctx, id)
+}
+
+func (m *WebhookRepository) ListWebhookDeliveries(ctx context.Context, filter *repository.WebhookDeliveryFilter,
+	opts *repository.ListOptions) ([]*repository.WebhookDelivery, string, error) {
+	return m.ListWebhookDeliveriesFunc(ctx, filter, opts)
+}
+
+func (m *WebhookRepository) ListDueWebhookDeliveries(
+	ctx context.Context, at time.Time, limit int) ([]*repository.WebhookDelivery, error) {
+	return m.ListDueWebhookDeliveriesFunc(ctx, at, limit)
+}
+
+func (m *WebhookRepository) UpdateWebhookDelivery(ctx context.Context, id string,
+	update *repository.WebhookDeliveryUpdate) (*repository.WebhookDelivery, error) {
+	return m.UpdateWebhookDeliveryFunc(ctx, id, update)
+}
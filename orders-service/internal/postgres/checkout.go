@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+var _ repository.CheckoutSagaRepository = (*CheckoutSagaRepository)(nil)
+
+const checkoutSagaColumns = `id, order_id, step, status, error, version, created_at, updated_at`
+
+type CheckoutSagaRepository struct {
+	db *DB
+}
+
+func NewCheckoutSagaRepository(db *DB) *CheckoutSagaRepository {
+	return &CheckoutSagaRepository{
+		db: db,
+	}
+}
+
+func (r *CheckoutSagaRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *CheckoutSagaRepository) CreateCheckoutSaga(
+	ctx context.Context, saga *repository.CheckoutSaga) (*repository.CheckoutSaga, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := now()
+
+	saga.CreatedAt = formatTime(currentTime)
+	saga.UpdatedAt = formatTime(currentTime)
+	saga.Step = repository.CheckoutStepReserve
+	saga.Status = repository.CheckoutSagaStatusRunning
+	saga.Error = ""
+	saga.Version = 1
+
+	if err := saga.Validate(); err != nil {
+		return nil, err
+	}
+
+	id := newID()
+
+	_, err := r.db.db.ExecContext(ctx, `
+		INSERT INTO checkout_sagas (`+checkoutSagaColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		id, saga.OrderId, string(saga.Step), string(saga.Status), saga.Error, int64(saga.Version),
+		currentTime, currentTime,
+	)
+	if err != nil {
+		return nil, dbError(err, "checkout saga", "create")
+	}
+
+	saga.Id = id
+
+	return saga, nil
+}
+
+func (r *CheckoutSagaRepository) GetCheckoutSaga(ctx context.Context, id string) (*repository.CheckoutSaga, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	return getCheckoutSaga(ctx, r.db.db, id, false)
+}
+
+func (r *CheckoutSagaRepository) UpdateCheckoutSaga(
+	ctx context.Context, id string, update *repository.CheckoutSagaUpdate) (*repository.CheckoutSaga, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	var saga *repository.CheckoutSaga
+	err := r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		if saga, err = getCheckoutSaga(ctx, tx, id, true); err != nil {
+			return err
+		}
+
+		if err := saga.ApplyUpdate(update); err != nil {
+			return err
+		}
+
+		timeNow := now()
+		saga.UpdatedAt = formatTime(timeNow)
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE checkout_sagas SET step = $2, status = $3, error = $4, version = $5, updated_at = $6
+			WHERE id = $1`,
+			id, string(saga.Step), string(saga.Status), saga.Error, int64(saga.Version), timeNow,
+		)
+		return dbError(err, "checkout saga", "update")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saga, nil
+}
+
+func (r *CheckoutSagaRepository) ListUnfinishedCheckoutSagas(ctx context.Context) ([]*repository.CheckoutSaga, error) {
+	r.CheckPreconditions()
+
+	rows, err := r.db.db.QueryContext(ctx, `
+		SELECT `+checkoutSagaColumns+` FROM checkout_sagas
+		WHERE status IN ('running', 'compensating')
+		ORDER BY created_at, id`)
+	if err != nil {
+		return nil, dbError(err, "checkout sagas", "list")
+	}
+	defer rows.Close()
+
+	var sagas []*repository.CheckoutSaga
+	for rows.Next() {
+		saga, err := scanCheckoutSaga(rows)
+		if err != nil {
+			return nil, dbError(err, "checkout sagas", "list")
+		}
+
+		sagas = append(sagas, saga)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "checkout sagas", "list")
+	}
+
+	return sagas, nil
+}
+
+func getCheckoutSaga(ctx context.Context, q queryer, id string, forUpdate bool) (*repository.CheckoutSaga, error) {
+	query := `SELECT ` + checkoutSagaColumns + ` FROM checkout_sagas WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	saga, err := scanCheckoutSaga(q.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, dbError(err, "checkout saga", "get")
+	}
+
+	return saga, nil
+}
+
+func scanCheckoutSaga(s scanner) (*repository.CheckoutSaga, error) {
+	var (
+		saga                 repository.CheckoutSaga
+		step, status         string
+		version              int64
+		createdAt, updatedAt time.Time
+	)
+
+	if err := s.Scan(
+		&saga.Id, &saga.OrderId, &step, &status, &saga.Error, &version, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	saga.Step = repository.CheckoutStep(step)
+	saga.Status = repository.CheckoutSagaStatus(status)
+	saga.Version = uint(version)
+	saga.CreatedAt = formatTime(createdAt)
+	saga.UpdatedAt = formatTime(updatedAt)
+
+	return &saga, nil
+}
@@ -0,0 +1,15 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestCheckoutSagaRepository(t *testing.T) {
+	repositorytest.TestCheckoutSagaRepository(t, func(t *testing.T) repository.CheckoutSagaRepository {
+		return postgres.NewCheckoutSagaRepository(MustOpenDB(t))
+	})
+}
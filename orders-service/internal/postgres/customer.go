@@ -0,0 +1,226 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+var _ repository.CustomerRepository = (*CustomerRepository)(nil)
+
+const customerColumns = `id, first_name, last_name, email, phone, created_at, updated_at`
+
+type CustomerRepository struct {
+	db *DB
+}
+
+func NewCustomerRepository(db *DB) *CustomerRepository {
+	return &CustomerRepository{
+		db: db,
+	}
+}
+
+func (r *CustomerRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *CustomerRepository) CreateCustomer(ctx context.Context, customer *repository.Customer) (*repository.Customer, error) {
+	r.CheckPreconditions()
+
+	currentTime := now()
+	customer.CreatedAt = formatTime(currentTime)
+	customer.UpdatedAt = formatTime(currentTime)
+
+	err := customer.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid customer provided: %v", err)
+	}
+
+	id := newID()
+
+	_, err = r.db.db.ExecContext(ctx, `
+		INSERT INTO customers (id, first_name, last_name, email, phone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		id, customer.FirstName, customer.LastName, customer.Email, customer.Phone, currentTime, currentTime,
+	)
+	if err != nil {
+		return nil, dbError(err, "customer", "create")
+	}
+
+	customer.Id = id
+
+	return customer, nil
+}
+
+func (r *CustomerRepository) GetCustomer(ctx context.Context, id string) (*repository.Customer, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	return getCustomer(ctx, r.db.db, id, false)
+}
+
+// customerCursors parse the page token values of the customer sort fields.
+var customerCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"last_name":  textCursor,
+}
+
+func (r *CustomerRepository) ListCustomers(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Customer, string, error) {
+
+	r.CheckPreconditions()
+
+	page, err := repository.ParsePage(opts, repository.CustomerSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var q listQuery
+	query, err := q.page(customerColumns, "customers", page, customerCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.db.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, "", dbError(err, "customers", "list")
+	}
+	defer rows.Close()
+
+	customers := make([]*repository.Customer, 0)
+	for rows.Next() {
+		customer, err := scanCustomer(rows)
+		if err != nil {
+			return nil, "", dbError(err, "customer", "unmarshall")
+		}
+
+		customers = append(customers, customer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", dbError(err, "customers", "iterate")
+	}
+
+	customers, next := repository.TrimPage(page, customers,
+		func(c *repository.Customer) string { return c.SortValue(page.Ordering.Field) },
+		func(c *repository.Customer) string { return c.Id },
+	)
+
+	return customers, next, nil
+}
+
+func (r *CustomerRepository) UpdateCustomer(
+	ctx context.Context, id string, update *repository.CustomerUpdate) (*repository.Customer, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	var customer *repository.Customer
+	err := r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		if customer, err = getCustomer(ctx, tx, id, true); err != nil {
+			return err
+		}
+
+		if c := update.FirstName; c != nil {
+			customer.FirstName = *c
+		}
+
+		if c := update.LastName; c != nil {
+			customer.LastName = *c
+		}
+
+		if c := update.Email; c != nil {
+			customer.Email = *c
+		}
+
+		if c := update.Phone; c != nil {
+			customer.Phone = *c
+		}
+
+		timeNow := now()
+		customer.UpdatedAt = formatTime(timeNow)
+
+		err = customer.Validate()
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid customer details provided: %v", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE customers SET first_name = $2, last_name = $3, email = $4, phone = $5, updated_at = $6
+			WHERE id = $1`,
+			id, customer.FirstName, customer.LastName, customer.Email, customer.Phone, timeNow,
+		)
+		return dbError(err, "customer", "update")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return customer, nil
+}
+
+func (r *CustomerRepository) DeleteCustomer(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	result, err := r.db.db.ExecContext(ctx, `DELETE FROM customers WHERE id = $1`, id)
+	if err != nil {
+		return dbError(err, "customer", "delete")
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return dbError(err, "customer", "delete")
+	} else if n == 0 {
+		return service.Errorf(service.NOT_FOUND_ERROR, "customer not found")
+	}
+
+	return nil
+}
+
+// getCustomer fetches a customer by id, optionally locking the row for the
+// rest of the surrounding transaction.
+func getCustomer(ctx context.Context, q queryer, id string, forUpdate bool) (*repository.Customer, error) {
+	query := `SELECT ` + customerColumns + ` FROM customers WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	customer, err := scanCustomer(q.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, dbError(err, "customer", "get")
+	}
+
+	return customer, nil
+}
+
+func scanCustomer(s scanner) (*repository.Customer, error) {
+	var (
+		customer             repository.Customer
+		createdAt, updatedAt time.Time
+	)
+
+	if err := s.Scan(
+		&customer.Id, &customer.FirstName, &customer.LastName, &customer.Email, &customer.Phone,
+		&createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	customer.CreatedAt = formatTime(createdAt)
+	customer.UpdatedAt = formatTime(updatedAt)
+
+	return &customer, nil
+}
@@ -0,0 +1,15 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestCustomerRepository(t *testing.T) {
+	repositorytest.TestCustomerRepository(t, func(t *testing.T) repository.CustomerRepository {
+		return postgres.NewCustomerRepository(MustOpenDB(t))
+	})
+}
@@ -0,0 +1,11 @@
+package postgres
+
+import (
+	idempotency_postgres "github.com/Mik3y-F/order-management-system/pkg/idempotency/postgres"
+)
+
+// NewIdempotencyStore returns a store of idempotency keys in the
+// idempotency_keys table.
+func NewIdempotencyStore(db *DB) *idempotency_postgres.Store {
+	return idempotency_postgres.NewStore(db.db, "idempotency_keys")
+}
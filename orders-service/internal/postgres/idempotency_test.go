@@ -0,0 +1,15 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/idempotencytest"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	idempotencytest.TestStore(t, func(t *testing.T) idempotency.Store {
+		return postgres.NewIdempotencyStore(MustOpenDB(t))
+	})
+}
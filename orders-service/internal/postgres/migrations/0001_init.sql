@@ -0,0 +1,43 @@
+CREATE TABLE products (
+	seq         BIGINT GENERATED ALWAYS AS IDENTITY,
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL CHECK (name <> ''),
+	description TEXT NOT NULL DEFAULT '',
+	price       BIGINT NOT NULL CHECK (price >= 0),
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE customers (
+	seq        BIGINT GENERATED ALWAYS AS IDENTITY,
+	id         TEXT PRIMARY KEY,
+	first_name TEXT NOT NULL CHECK (first_name <> ''),
+	last_name  TEXT NOT NULL CHECK (last_name <> ''),
+	email      TEXT NOT NULL CHECK (email <> ''),
+	phone      TEXT NOT NULL CHECK (phone <> ''),
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE orders (
+	seq          BIGINT GENERATED ALWAYS AS IDENTITY,
+	id           TEXT PRIMARY KEY,
+	customer_id  TEXT NOT NULL CHECK (customer_id <> ''),
+	order_status TEXT NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL,
+	updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX orders_customer_id_idx ON orders (customer_id);
+
+CREATE TABLE order_items (
+	seq        BIGINT GENERATED ALWAYS AS IDENTITY,
+	id         TEXT PRIMARY KEY,
+	order_id   TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+	product_id TEXT NOT NULL CHECK (product_id <> ''),
+	quantity   BIGINT NOT NULL CHECK (quantity > 0),
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX order_items_order_id_idx ON order_items (order_id, seq);
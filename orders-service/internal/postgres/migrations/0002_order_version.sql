@@ -0,0 +1,3 @@
+-- Orders carry a version that is bumped on every status change, so clients
+-- can detect concurrent updates.
+ALTER TABLE orders ADD COLUMN version BIGINT NOT NULL DEFAULT 1 CHECK (version > 0);
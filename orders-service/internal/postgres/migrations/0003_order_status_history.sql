@@ -0,0 +1,12 @@
+-- Every status change of an order is recorded as an immutable history entry.
+CREATE TABLE order_status_history (
+	id          TEXT PRIMARY KEY,
+	order_id    TEXT NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+	from_status TEXT NOT NULL,
+	to_status   TEXT NOT NULL,
+	actor       TEXT NOT NULL DEFAULT '',
+	reason      TEXT NOT NULL DEFAULT '',
+	version     BIGINT NOT NULL CHECK (version > 0),
+	created_at  TIMESTAMPTZ NOT NULL,
+	UNIQUE (order_id, version)
+);
@@ -0,0 +1,11 @@
+-- Indexes for the keyset pagination of the List methods, which order by one of
+-- these columns and then by id.
+CREATE INDEX products_created_at_idx ON products (created_at, id);
+CREATE INDEX products_name_idx ON products (name, id);
+CREATE INDEX products_price_idx ON products (price, id);
+
+CREATE INDEX customers_created_at_idx ON customers (created_at, id);
+CREATE INDEX customers_last_name_idx ON customers (last_name, id);
+
+CREATE INDEX orders_created_at_idx ON orders (created_at, id);
+CREATE INDEX orders_updated_at_idx ON orders (updated_at, id);
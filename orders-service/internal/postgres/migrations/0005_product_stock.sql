@@ -0,0 +1,6 @@
+-- Products track the units that can be sold and the units reserved by orders
+-- that are being paid for. Existing products have no stock level (NULL): their
+-- stock is not tracked, so they can still be ordered, until one is set.
+ALTER TABLE products
+	ADD COLUMN stock BIGINT CHECK (stock >= 0),
+	ADD COLUMN reserved BIGINT NOT NULL DEFAULT 0 CHECK (reserved >= 0);
@@ -0,0 +1,21 @@
+-- Order items keep the price of their product at the time they were added and
+-- orders keep their totals, so that later price changes do not change what the
+-- customer is charged. Existing items are priced at the current price of their
+-- product.
+ALTER TABLE order_items
+	ADD COLUMN unit_price BIGINT NOT NULL DEFAULT 0 CHECK (unit_price >= 0),
+	ADD COLUMN line_total BIGINT NOT NULL DEFAULT 0 CHECK (line_total >= 0);
+
+ALTER TABLE orders
+	ADD COLUMN subtotal BIGINT NOT NULL DEFAULT 0 CHECK (subtotal >= 0),
+	ADD COLUMN tax BIGINT NOT NULL DEFAULT 0 CHECK (tax >= 0),
+	ADD COLUMN discount BIGINT NOT NULL DEFAULT 0 CHECK (discount >= 0),
+	ADD COLUMN total BIGINT NOT NULL DEFAULT 0 CHECK (total >= 0);
+
+UPDATE order_items SET unit_price = products.price, line_total = products.price * order_items.quantity
+FROM products
+WHERE products.id = order_items.product_id;
+
+UPDATE orders SET subtotal = totals.subtotal, total = totals.subtotal
+FROM (SELECT order_id, SUM(line_total) AS subtotal FROM order_items GROUP BY order_id) AS totals
+WHERE totals.order_id = orders.id;
@@ -0,0 +1,15 @@
+-- Amounts are stored in the minor units of their currency, e.g. cents, next to
+-- the ISO 4217 code of the currency. All existing amounts were whole Kenyan
+-- shillings. Amounts of zero have no currency, like the zero money.Money.
+ALTER TABLE products ADD COLUMN currency TEXT NOT NULL DEFAULT 'KES';
+ALTER TABLE products ALTER COLUMN currency DROP DEFAULT;
+UPDATE products SET price = price * 100;
+
+ALTER TABLE order_items ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+UPDATE order_items SET currency = 'KES', unit_price = unit_price * 100, line_total = line_total * 100
+WHERE unit_price > 0;
+
+ALTER TABLE orders ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+UPDATE orders SET currency = 'KES', subtotal = subtotal * 100, tax = tax * 100, discount = discount * 100,
+	total = total * 100
+WHERE subtotal > 0 OR tax > 0 OR discount > 0;
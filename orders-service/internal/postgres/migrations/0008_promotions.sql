@@ -0,0 +1,40 @@
+-- Promotions discount the orders they are applied to. Their rule columns are
+-- only set for the promotion types that use them. Each order an active
+-- promotion is applied to is recorded in promotion_redemptions, which is used
+-- to enforce the usage limits.
+CREATE TABLE promotions (
+	seq                BIGINT GENERATED ALWAYS AS IDENTITY,
+	id                 TEXT PRIMARY KEY,
+	code               TEXT NOT NULL UNIQUE CHECK (code <> ''),
+	description        TEXT NOT NULL DEFAULT '',
+	type               TEXT NOT NULL,
+	percentage         BIGINT NOT NULL DEFAULT 0 CHECK (percentage BETWEEN 0 AND 100),
+	currency           TEXT NOT NULL DEFAULT '',
+	amount_off         BIGINT NOT NULL DEFAULT 0 CHECK (amount_off >= 0),
+	product_id         TEXT NOT NULL DEFAULT '',
+	buy_quantity       BIGINT NOT NULL DEFAULT 0 CHECK (buy_quantity >= 0),
+	get_quantity       BIGINT NOT NULL DEFAULT 0 CHECK (get_quantity >= 0),
+	starts_at          TIMESTAMPTZ,
+	ends_at            TIMESTAMPTZ,
+	usage_limit        BIGINT NOT NULL DEFAULT 0 CHECK (usage_limit >= 0),
+	per_customer_limit BIGINT NOT NULL DEFAULT 0 CHECK (per_customer_limit >= 0),
+	redemptions        BIGINT NOT NULL DEFAULT 0 CHECK (redemptions >= 0),
+	created_at         TIMESTAMPTZ NOT NULL,
+	updated_at         TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX promotions_created_at_idx ON promotions (created_at, id);
+
+CREATE TABLE promotion_redemptions (
+	promotion_id TEXT NOT NULL REFERENCES promotions (id) ON DELETE CASCADE,
+	order_id     TEXT NOT NULL,
+	customer_id  TEXT NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (promotion_id, order_id)
+);
+
+CREATE INDEX promotion_redemptions_customer_id_idx ON promotion_redemptions (promotion_id, customer_id);
+
+-- Orders keep a copy of the promotion applied to them as JSON, see
+-- repository.AppliedPromotion.
+ALTER TABLE orders ADD COLUMN promotion JSONB;
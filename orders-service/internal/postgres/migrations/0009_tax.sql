@@ -0,0 +1,11 @@
+-- Products are taxed at the rate of their category. Order items keep the rate
+-- they were added at, in basis points, and the tax on their line total.
+-- Existing items were not taxed.
+ALTER TABLE products ADD COLUMN category TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE order_items
+	ADD COLUMN tax_category TEXT NOT NULL DEFAULT '',
+	ADD COLUMN tax_rate BIGINT NOT NULL DEFAULT 0 CHECK (tax_rate BETWEEN 0 AND 10000),
+	ADD COLUMN tax_exempt BOOLEAN NOT NULL DEFAULT FALSE,
+	ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE,
+	ADD COLUMN tax BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,16 @@
+-- Every checkout runs as a saga whose progress is stored here, so that
+-- checkouts interrupted by a restart are resumed or compensated, see
+-- repository.CheckoutSaga.
+CREATE TABLE checkout_sagas (
+	id         TEXT PRIMARY KEY,
+	order_id   TEXT NOT NULL CHECK (order_id <> ''),
+	step       TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	error      TEXT NOT NULL DEFAULT '',
+	version    BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX checkout_sagas_unfinished_idx ON checkout_sagas (created_at, id)
+	WHERE status IN ('running', 'compensating');
@@ -0,0 +1,14 @@
+-- Events are recorded in the same transaction as the changes that cause them,
+-- and removed once the outbox relay has published them. seq keeps them in the
+-- order they were recorded.
+CREATE TABLE outbox_events (
+	seq            BIGINT GENERATED ALWAYS AS IDENTITY,
+	id             TEXT PRIMARY KEY,
+	type           TEXT NOT NULL,
+	aggregate_type TEXT NOT NULL,
+	aggregate_id   TEXT NOT NULL,
+	payload        JSONB NOT NULL,
+	created_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX outbox_events_seq_idx ON outbox_events (seq);
@@ -0,0 +1,34 @@
+-- Webhook subscriptions have the order events of event_types, a JSON array,
+-- posted to their url. Each event is delivered to a subscription at most once;
+-- a delivery records its attempts and stays in the log after its subscription
+-- is deleted. Failed deliveries are dead letters, kept until they are
+-- replayed.
+CREATE TABLE webhook_subscriptions (
+	seq         BIGINT GENERATED ALWAYS AS IDENTITY,
+	id          TEXT PRIMARY KEY,
+	url         TEXT NOT NULL CHECK (url <> ''),
+	secret      TEXT NOT NULL CHECK (secret <> ''),
+	event_types JSONB NOT NULL,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+	id                 TEXT PRIMARY KEY,
+	subscription_id    TEXT NOT NULL,
+	event_id           TEXT NOT NULL,
+	event_type         TEXT NOT NULL,
+	payload            BYTEA NOT NULL,
+	status             TEXT NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')),
+	attempts           BIGINT NOT NULL DEFAULT 0 CHECK (attempts >= 0),
+	last_response_code BIGINT NOT NULL DEFAULT 0,
+	last_error         TEXT NOT NULL DEFAULT '',
+	next_attempt_at    TIMESTAMPTZ NOT NULL,
+	created_at         TIMESTAMPTZ NOT NULL,
+	updated_at         TIMESTAMPTZ NOT NULL,
+	UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_created_at_idx ON webhook_deliveries (created_at, id);
+CREATE INDEX webhook_deliveries_updated_at_idx ON webhook_deliveries (updated_at, id);
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,25 @@
+-- Stock is reserved under an id, and each reservation records the units it
+-- took from the products whose stock is tracked, so that exactly those are
+-- committed or released, see repository.ProductRepository.ReserveStock.
+-- Orders record the reservation that holds their items.
+CREATE TABLE stock_reservations (
+	id         TEXT NOT NULL CHECK (id <> ''),
+	product_id TEXT NOT NULL,
+	quantity   BIGINT NOT NULL CHECK (quantity > 0),
+	PRIMARY KEY (id, product_id)
+);
+
+ALTER TABLE orders ADD COLUMN stock_reservation TEXT NOT NULL DEFAULT '';
+
+-- Orders that hold stock reserved their items before reservations were
+-- recorded. They get a reservation under their own id with the units of the
+-- products whose stock is tracked now.
+UPDATE orders SET stock_reservation = id WHERE order_status IN ('processing', 'pending');
+
+INSERT INTO stock_reservations (id, product_id, quantity)
+SELECT o.id, i.product_id, SUM(i.quantity)
+FROM orders o
+JOIN order_items i ON i.order_id = o.id
+JOIN products p ON p.id = i.product_id
+WHERE o.order_status IN ('processing', 'pending') AND p.stock IS NOT NULL
+GROUP BY o.id, i.product_id;
@@ -0,0 +1,11 @@
+-- Orders stop counting towards the limits of their promotion once they fail,
+-- are cancelled or are deleted. Redemptions of such orders made before are
+-- removed, and the redemption counts of the promotions follow.
+DELETE FROM promotion_redemptions r
+WHERE NOT EXISTS (
+	SELECT 1 FROM orders o
+	WHERE o.id = r.order_id AND o.order_status NOT IN ('failed', 'cancelled')
+);
+
+UPDATE promotions p
+SET redemptions = (SELECT COUNT(*) FROM promotion_redemptions r WHERE r.promotion_id = p.id);
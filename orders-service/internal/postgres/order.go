@@ -0,0 +1,863 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	orderPkg "github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+var _ repository.OrderRepository = (*OrderRepository)(nil)
+
+const (
+	orderColumns = `id, customer_id, order_status, version, currency, subtotal, tax, discount, total, ` +
+		`promotion, stock_reservation, created_at, updated_at`
+
+	orderItemColumns = `id, order_id, product_id, quantity, currency, unit_price, line_total, ` +
+		`tax_category, tax_rate, tax_exempt, tax_inclusive, tax, created_at, updated_at`
+
+	orderStatusChangeColumns = `id, order_id, from_status, to_status, actor, reason, version, created_at`
+)
+
+type OrderRepository struct {
+	db *DB
+}
+
+func NewOrderRepository(db *DB) *OrderRepository {
+	return &OrderRepository{
+		db: db,
+	}
+}
+
+func (r *OrderRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+// CreateOrder stores the order and all of its items in a single transaction.
+func (r *OrderRepository) CreateOrder(ctx context.Context, order *repository.Order) (*repository.Order, error) {
+	r.CheckPreconditions()
+
+	// Set CreatedAt and UpdatedAt to the current time
+	currentTime := now()
+
+	order.CreatedAt = formatTime(currentTime)
+	order.UpdatedAt = formatTime(currentTime)
+
+	order.OrderStatus = orderPkg.OrderStatusNew
+	order.Version = 1
+
+	err := order.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid order details provided: %v", err)
+	}
+
+	if err := order.UpdateTotals(); err != nil {
+		return nil, err
+	}
+
+	promotion, err := marshallPromotion(order.Promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	id := newID()
+
+	err = r.db.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO orders (`+orderColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			id, order.CustomerId, string(order.OrderStatus), int64(order.Version), order.Total.Currency,
+			order.Subtotal.Amount, order.Tax.Amount, order.Discount.Amount, order.Total.Amount, promotion,
+			order.StockReservation, currentTime, currentTime,
+		)
+		if err != nil {
+			return dbError(err, "order", "create")
+		}
+
+		for _, item := range order.Items {
+			if err := insertOrderItem(ctx, tx, id, item, currentTime); err != nil {
+				return err
+			}
+		}
+
+		created := *order
+		created.Id = id
+
+		event, err := repository.NewOrderCreatedEvent(&created)
+		if err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, event, currentTime)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order.Id = id
+
+	return order, nil
+}
+
+func (r *OrderRepository) GetOrder(ctx context.Context, id string) (*repository.Order, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	return getOrder(ctx, r.db.db, id, false)
+}
+
+// orderCursors parse the page token values of the order sort fields.
+var orderCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"updated_at": timeCursor,
+}
+
+// ListOrders fetches a page of orders, then the items of all of them with a
+// single query.
+func (r *OrderRepository) ListOrders(
+	ctx context.Context, filter *repository.OrderFilter, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+
+	r.CheckPreconditions()
+
+	page, err := repository.ParsePage(opts, repository.OrderSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var q listQuery
+	if filter != nil {
+		if err := filter.Validate(); err != nil {
+			return nil, "", err
+		}
+
+		if filter.CustomerId != "" {
+			q.where(`customer_id = ` + q.arg(filter.CustomerId))
+		}
+		if filter.Status != "" {
+			q.where(`order_status = ` + q.arg(string(filter.Status)))
+		}
+		if !filter.CreatedFrom.IsZero() {
+			q.where(`created_at >= ` + q.arg(filter.CreatedFrom))
+		}
+		if !filter.CreatedUntil.IsZero() {
+			q.where(`created_at < ` + q.arg(filter.CreatedUntil))
+		}
+	}
+
+	query, err := q.page(orderColumns, "orders", page, orderCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.db.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, "", dbError(err, "orders", "list")
+	}
+	defer rows.Close()
+
+	orders := make([]*repository.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, "", dbError(err, "order", "unmarshall")
+		}
+
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", dbError(err, "orders", "iterate")
+	}
+
+	orders, next := repository.TrimPage(page, orders,
+		func(o *repository.Order) string { return o.SortValue(page.Ordering.Field) },
+		func(o *repository.Order) string { return o.Id },
+	)
+
+	if err := attachOrderItems(ctx, r.db.db, orders); err != nil {
+		return nil, "", err
+	}
+
+	return orders, next, nil
+}
+
+func (r *OrderRepository) ListOrdersByCustomer(
+	ctx context.Context, customerId string, opts *repository.ListOptions) ([]*repository.Order, string, error) {
+
+	filter, opts, err := repository.CustomerOrders(customerId, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return r.ListOrders(ctx, filter, opts)
+}
+
+func (r *OrderRepository) UpdateOrderStatus(
+	ctx context.Context, orderId string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	var order *repository.Order
+	err := r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		// The row stays locked until the transaction ends, so the transition
+		// is checked against the status that is actually overwritten.
+		if order, err = getOrder(ctx, tx, orderId, true); err != nil {
+			return err
+		}
+
+		change, err := order.ApplyStatusUpdate(update)
+		if err != nil {
+			return err
+		}
+
+		timeNow := now()
+		order.UpdatedAt = formatTime(timeNow)
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE orders SET order_status = $2, version = $3, stock_reservation = $4, updated_at = $5 WHERE id = $1`,
+			orderId, string(order.OrderStatus), int64(order.Version), order.StockReservation, timeNow,
+		)
+		if err != nil {
+			return dbError(err, "order status", "update")
+		}
+
+		change.Id = newID()
+		change.CreatedAt = order.UpdatedAt
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO order_status_history (`+orderStatusChangeColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			change.Id, orderId, string(change.FromStatus), string(change.ToStatus),
+			change.Actor, change.Reason, int64(change.Version), timeNow,
+		)
+		if err != nil {
+			return dbError(err, "order status history", "insert")
+		}
+
+		event, err := repository.NewOrderStatusEvent(order, change)
+		if err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, event, timeNow)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (r *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	// Order items are removed through the ON DELETE CASCADE constraint.
+	result, err := r.db.db.ExecContext(ctx, `DELETE FROM orders WHERE id = $1`, id)
+	if err != nil {
+		return dbError(err, "order", "delete")
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return dbError(err, "order", "delete")
+	} else if n == 0 {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	return nil
+}
+
+func (r *OrderRepository) GetOrderHistory(
+	ctx context.Context, orderId string) ([]*repository.OrderStatusChange, error) {
+
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	if err := orderExists(ctx, r.db.db, orderId); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.db.QueryContext(ctx, `
+		SELECT `+orderStatusChangeColumns+` FROM order_status_history
+		WHERE order_id = $1 ORDER BY version`, orderId)
+	if err != nil {
+		return nil, dbError(err, "order status history", "list")
+	}
+	defer rows.Close()
+
+	history := make([]*repository.OrderStatusChange, 0)
+	for rows.Next() {
+		change, err := scanOrderStatusChange(rows)
+		if err != nil {
+			return nil, dbError(err, "order status change", "unmarshall")
+		}
+
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "order status history", "iterate")
+	}
+
+	return history, nil
+}
+
+func (r *OrderRepository) ApplyOrderPromotion(
+	ctx context.Context, orderId string, promotion *repository.AppliedPromotion, replaces string) (*repository.Order, error) {
+
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+
+	stored, err := marshallPromotion(promotion)
+	if err != nil {
+		return nil, err
+	}
+
+	var order *repository.Order
+	err = r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		if order, err = getOrder(ctx, tx, orderId, true); err != nil {
+			return err
+		}
+
+		if err := order.CheckReplacedPromotion(replaces); err != nil {
+			return err
+		}
+
+		if err := order.PrepareChange("promotion"); err != nil {
+			return err
+		}
+
+		order.Promotion = promotion
+		if err := order.UpdateTotals(); err != nil {
+			return err
+		}
+
+		timeNow := now()
+		order.UpdatedAt = formatTime(timeNow)
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE orders SET promotion = $2, currency = $3, subtotal = $4, tax = $5, discount = $6, total = $7,
+				version = $8, updated_at = $9
+			WHERE id = $1`,
+			orderId, stored, order.Total.Currency, order.Subtotal.Amount, order.Tax.Amount, order.Discount.Amount,
+			order.Total.Amount, int64(order.Version), timeNow,
+		)
+		if err != nil {
+			return dbError(err, "order promotion", "update")
+		}
+
+		return storeOrderItemTaxes(ctx, tx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (r *OrderRepository) CreateOrderItem(
+	ctx context.Context, orderId string, orderItem *repository.OrderItem) (*repository.OrderItem, error) {
+
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	err := r.db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockOrderForChange(ctx, tx, orderId, "items"); err != nil {
+			return err
+		}
+
+		if err := orderItem.UpdateLineTotal(); err != nil {
+			return err
+		}
+
+		if err := insertOrderItem(ctx, tx, orderId, orderItem, now()); err != nil {
+			return err
+		}
+
+		order, err := storeOrderTotals(ctx, tx, orderId)
+		if err != nil {
+			return err
+		}
+
+		setOrderItemTax(order, orderItem)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orderItem, nil
+}
+
+func (r *OrderRepository) GetOrderItem(
+	ctx context.Context, orderId string, orderItemId string) (*repository.OrderItem, error) {
+
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	} else if orderItemId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order item id is required")
+	}
+
+	return getOrderItem(ctx, r.db.db, orderId, orderItemId, false)
+}
+
+func (r *OrderRepository) ListOrderItems(ctx context.Context, orderId string) ([]*repository.OrderItem, error) {
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	order, err := getOrder(ctx, r.db.db, orderId, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return order.Items, nil
+}
+
+func (r *OrderRepository) UpdateOrderItem(
+	ctx context.Context, orderId string, orderItemId string, update *repository.OrderItemUpdate) (*repository.OrderItem, error) {
+
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	} else if orderItemId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order item id is required")
+	}
+
+	var orderItem *repository.OrderItem
+	err := r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		if err := lockOrderForChange(ctx, tx, orderId, "items"); service.ErrorCode(err) == service.NOT_FOUND_ERROR {
+			return service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+		} else if err != nil {
+			return err
+		}
+
+		if orderItem, err = getOrderItem(ctx, tx, orderId, orderItemId, true); err != nil {
+			return err
+		}
+
+		if v := update.Quantity; v != nil {
+			orderItem.Quantity = *v
+		}
+
+		err = orderItem.Validate()
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid order item details provided: %v", err)
+		}
+
+		if err := orderItem.UpdateLineTotal(); err != nil {
+			return err
+		}
+
+		// Set UpdatedAt to the current time
+		timeNow := now()
+		orderItem.UpdatedAt = formatTime(timeNow)
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE order_items SET quantity = $3, line_total = $4, tax = $5, updated_at = $6
+			WHERE order_id = $1 AND id = $2`,
+			orderId, orderItemId, int64(orderItem.Quantity), orderItem.LineTotal.Amount, orderItem.Tax.Amount, timeNow,
+		)
+		if err != nil {
+			return dbError(err, "order item", "update")
+		}
+
+		order, err := storeOrderTotals(ctx, tx, orderId)
+		if err != nil {
+			return err
+		}
+
+		setOrderItemTax(order, orderItem)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return orderItem, nil
+}
+
+func (r *OrderRepository) DeleteOrderItem(ctx context.Context, orderId string, orderItemId string) error {
+	r.CheckPreconditions()
+
+	if orderId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order id is required")
+	} else if orderItemId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order item id is required")
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		if err := lockOrderForChange(ctx, tx, orderId, "items"); service.ErrorCode(err) == service.NOT_FOUND_ERROR {
+			return service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+		} else if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM order_items WHERE order_id = $1 AND id = $2`, orderId, orderItemId)
+		if err != nil {
+			return dbError(err, "order item", "delete")
+		}
+
+		if n, err := result.RowsAffected(); err != nil {
+			return dbError(err, "order item", "delete")
+		} else if n == 0 {
+			return service.Errorf(service.NOT_FOUND_ERROR, "order item not found")
+		}
+
+		_, err = storeOrderTotals(ctx, tx, orderId)
+		return err
+	})
+}
+
+// getOrder fetches an order together with its items, optionally locking the
+// order row for the rest of the surrounding transaction.
+func getOrder(ctx context.Context, q queryer, id string, forUpdate bool) (*repository.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	order, err := scanOrder(q.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, dbError(err, "order", "get")
+	}
+
+	if err := attachOrderItems(ctx, q, []*repository.Order{order}); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// orderExists returns a NOT_FOUND error if there is no order with the id.
+func orderExists(ctx context.Context, q queryer, id string) error {
+	var exists bool
+
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return dbError(err, "order", "get")
+	} else if !exists {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	}
+
+	return nil
+}
+
+// lockOrderForChange locks the order row for the rest of the surrounding
+// transaction and bumps its version, see Order.PrepareChange. Item changes
+// take the lock first, so that they update the order totals one at a time.
+func lockOrderForChange(ctx context.Context, tx *sql.Tx, id string, what string) error {
+	var (
+		order   = repository.Order{Id: id}
+		version int64
+	)
+
+	err := tx.QueryRowContext(ctx, `SELECT order_status, version FROM orders WHERE id = $1 FOR UPDATE`, id).
+		Scan(&order.OrderStatus, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	} else if err != nil {
+		return dbError(err, "order", "lock")
+	}
+
+	order.Version = uint(version)
+	if err := order.PrepareChange(what); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE orders SET version = $2 WHERE id = $1`, id, int64(order.Version))
+	return dbError(err, "order version", "update")
+}
+
+// storeOrderTotals recomputes the totals and item taxes of a locked order
+// from its stored items, and returns the updated order. The surrounding
+// transaction must be rolled back if it fails, e.g. because an item was added
+// in another currency.
+func storeOrderTotals(ctx context.Context, tx *sql.Tx, id string) (*repository.Order, error) {
+	order, err := getOrder(ctx, tx, id, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := order.UpdateTotals(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE orders SET currency = $2, subtotal = $3, tax = $4, discount = $5, total = $6 WHERE id = $1`,
+		id, order.Total.Currency, order.Subtotal.Amount, order.Tax.Amount, order.Discount.Amount, order.Total.Amount,
+	)
+	if err != nil {
+		return nil, dbError(err, "order totals", "update")
+	}
+
+	if err := storeOrderItemTaxes(ctx, tx, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// storeOrderItemTaxes stores the taxes of the items of an order, which change
+// with the discount of the order, with a single query.
+func storeOrderItemTaxes(ctx context.Context, tx *sql.Tx, order *repository.Order) error {
+	ids := make([]string, len(order.Items))
+	taxes := make([]int64, len(order.Items))
+	for i, item := range order.Items {
+		ids[i], taxes[i] = item.Id, item.Tax.Amount
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE order_items SET tax = taxes.tax
+		FROM unnest($2::text[], $3::bigint[]) AS taxes (id, tax)
+		WHERE order_items.order_id = $1 AND order_items.id = taxes.id AND order_items.tax <> taxes.tax`,
+		order.Id, ids, taxes,
+	)
+	return dbError(err, "order item taxes", "update")
+}
+
+// setOrderItemTax sets the tax of the item to the one it has in the order
+// after the discount of the order is spread over its items.
+func setOrderItemTax(order *repository.Order, orderItem *repository.OrderItem) {
+	for _, item := range order.Items {
+		if item.Id == orderItem.Id {
+			orderItem.Tax = item.Tax
+			return
+		}
+	}
+}
+
+// attachOrderItems loads the items of all given orders with a single query.
+func attachOrderItems(ctx context.Context, q queryer, orders []*repository.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(orders))
+	byId := make(map[string]*repository.Order, len(orders))
+	for i, order := range orders {
+		order.Items = make([]*repository.OrderItem, 0)
+
+		ids[i] = order.Id
+		byId[order.Id] = order
+	}
+
+	rows, err := q.QueryContext(ctx,
+		`SELECT `+orderItemColumns+` FROM order_items WHERE order_id = ANY($1) ORDER BY seq`, ids)
+	if err != nil {
+		return dbError(err, "order items", "list")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderId string
+
+		orderItem, err := scanOrderItem(rows, &orderId)
+		if err != nil {
+			return dbError(err, "order item", "unmarshall")
+		}
+
+		order := byId[orderId]
+		order.Items = append(order.Items, orderItem)
+	}
+	if err := rows.Err(); err != nil {
+		return dbError(err, "order items", "iterate")
+	}
+
+	return nil
+}
+
+// insertOrderItem validates & stores a single order item, assigning its id and
+// timestamps. The line total and tax of the item must already be set, see
+// OrderItem.UpdateLineTotal and Order.UpdateTotals.
+func insertOrderItem(
+	ctx context.Context, q queryer, orderId string, orderItem *repository.OrderItem, currentTime time.Time) error {
+
+	// Set CreatedAt and UpdatedAt to the current time
+	orderItem.CreatedAt = formatTime(currentTime)
+	orderItem.UpdatedAt = formatTime(currentTime)
+
+	err := orderItem.Validate()
+	if err != nil {
+		return service.Errorf(service.INVALID_ERROR, "invalid order item details provided: %v", err)
+	}
+
+	id := newID()
+
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO order_items (`+orderItemColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`,
+		id, orderId, orderItem.ProductId, int64(orderItem.Quantity), orderItem.UnitPrice.Currency,
+		orderItem.UnitPrice.Amount, orderItem.LineTotal.Amount, orderItem.TaxRate.Category,
+		int64(orderItem.TaxRate.Rate), orderItem.TaxRate.Exempt, orderItem.TaxRate.Inclusive, orderItem.Tax.Amount,
+		currentTime, currentTime,
+	)
+	if err := dbError(err, "order item", "create"); service.ErrorCode(err) == service.NOT_FOUND_ERROR {
+		return service.Errorf(service.NOT_FOUND_ERROR, "order not found")
+	} else if err != nil {
+		return err
+	}
+
+	orderItem.Id = id
+
+	return nil
+}
+
+// getOrderItem fetches an order item by order & item id, optionally locking the
+// row for the rest of the surrounding transaction.
+func getOrderItem(
+	ctx context.Context, q queryer, orderId string, orderItemId string, forUpdate bool) (*repository.OrderItem, error) {
+
+	query := `SELECT ` + orderItemColumns + ` FROM order_items WHERE order_id = $1 AND id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	var id string
+	orderItem, err := scanOrderItem(q.QueryRowContext(ctx, query, orderId, orderItemId), &id)
+	if err != nil {
+		return nil, dbError(err, "order item", "get")
+	}
+
+	return orderItem, nil
+}
+
+// marshallPromotion converts the promotion of an order to the value of its
+// JSON column, or NULL if no promotion is applied.
+func marshallPromotion(promotion *repository.AppliedPromotion) ([]byte, error) {
+	if promotion == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(promotion)
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to marshall order promotion: %v", err)
+	}
+
+	return b, nil
+}
+
+func scanOrder(s scanner) (*repository.Order, error) {
+	var (
+		order                          repository.Order
+		status                         string
+		version                        int64
+		currency                       string
+		subtotal, tax, discount, total int64
+		promotion                      []byte
+		createdAt, updatedAt           time.Time
+	)
+
+	if err := s.Scan(
+		&order.Id, &order.CustomerId, &status, &version, &currency, &subtotal, &tax, &discount, &total,
+		&promotion, &order.StockReservation, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if promotion != nil {
+		order.Promotion = &repository.AppliedPromotion{}
+		if err := json.Unmarshal(promotion, order.Promotion); err != nil {
+			return nil, err
+		}
+	}
+
+	order.OrderStatus = orderPkg.OrderStatus(status)
+	order.Version = uint(version)
+	order.Subtotal = money.New(subtotal, currency)
+	order.Tax = money.New(tax, currency)
+	order.Discount = money.New(discount, currency)
+	order.Total = money.New(total, currency)
+	order.CreatedAt = formatTime(createdAt)
+	order.UpdatedAt = formatTime(updatedAt)
+
+	return &order, nil
+}
+
+// scanOrderItem scans an order item row, storing the parent order id in orderId.
+func scanOrderItem(s scanner, orderId *string) (*repository.OrderItem, error) {
+	var (
+		orderItem            repository.OrderItem
+		quantity             int64
+		currency             string
+		unitPrice, lineTotal int64
+		taxRate, tax         int64
+		createdAt, updatedAt time.Time
+	)
+
+	if err := s.Scan(
+		&orderItem.Id, orderId, &orderItem.ProductId, &quantity, &currency, &unitPrice, &lineTotal,
+		&orderItem.TaxRate.Category, &taxRate, &orderItem.TaxRate.Exempt, &orderItem.TaxRate.Inclusive, &tax,
+		&createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	orderItem.Quantity = uint(quantity)
+	orderItem.UnitPrice = money.New(unitPrice, currency)
+	orderItem.LineTotal = money.New(lineTotal, currency)
+	orderItem.TaxRate.Rate = uint(taxRate)
+	orderItem.Tax = money.New(tax, currency)
+	orderItem.CreatedAt = formatTime(createdAt)
+	orderItem.UpdatedAt = formatTime(updatedAt)
+
+	return &orderItem, nil
+}
+
+func scanOrderStatusChange(s scanner) (*repository.OrderStatusChange, error) {
+	var (
+		change               repository.OrderStatusChange
+		fromStatus, toStatus string
+		version              int64
+		createdAt            time.Time
+	)
+
+	if err := s.Scan(
+		&change.Id, &change.OrderId, &fromStatus, &toStatus, &change.Actor, &change.Reason, &version, &createdAt,
+	); err != nil {
+		return nil, err
+	}
+
+	change.FromStatus = orderPkg.OrderStatus(fromStatus)
+	change.ToStatus = orderPkg.OrderStatus(toStatus)
+	change.Version = uint(version)
+	change.CreatedAt = formatTime(createdAt)
+
+	return &change, nil
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+var _ repository.OutboxRepository = (*OutboxRepository)(nil)
+
+const outboxEventColumns = `id, type, aggregate_type, aggregate_id, payload, created_at`
+
+type OutboxRepository struct {
+	db *DB
+}
+
+func NewOutboxRepository(db *DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+func (r *OutboxRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *OutboxRepository) ListOutboxEvents(ctx context.Context, limit int) ([]*outbox.Event, error) {
+	r.CheckPreconditions()
+
+	rows, err := r.db.db.QueryContext(ctx,
+		`SELECT `+outboxEventColumns+` FROM outbox_events ORDER BY seq LIMIT $1`, limit)
+	if err != nil {
+		return nil, dbError(err, "outbox events", "list")
+	}
+	defer rows.Close()
+
+	events := make([]*outbox.Event, 0)
+	for rows.Next() {
+		var (
+			event     outbox.Event
+			createdAt time.Time
+		)
+
+		if err := rows.Scan(&event.Id, &event.Type, &event.AggregateType, &event.AggregateId,
+			&event.Payload, &createdAt); err != nil {
+			return nil, dbError(err, "outbox events", "list")
+		}
+
+		event.CreatedAt = formatTime(createdAt)
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "outbox events", "list")
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	r.CheckPreconditions()
+
+	_, err := r.db.db.ExecContext(ctx, `DELETE FROM outbox_events WHERE id = ANY($1)`, ids)
+	return dbError(err, "outbox events", "delete")
+}
+
+// insertOutboxEvent records the event in the transaction of the change that
+// caused it.
+func insertOutboxEvent(ctx context.Context, q queryer, event *outbox.Event, t time.Time) error {
+	event.Id = newID()
+	event.CreatedAt = formatTime(t)
+
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO outbox_events (`+outboxEventColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		event.Id, event.Type, event.AggregateType, event.AggregateId, []byte(event.Payload), t,
+	)
+	return dbError(err, "outbox event", "create")
+}
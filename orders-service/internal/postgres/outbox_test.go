@@ -0,0 +1,16 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.OrderRepository, repository.OutboxRepository) {
+		db := MustOpenDB(t)
+		return postgres.NewOrderRepository(db), postgres.NewOutboxRepository(db)
+	})
+}
@@ -0,0 +1,447 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+var _ repository.ProductRepository = (*ProductRepository)(nil)
+
+const productColumns = `id, name, description, price, currency, category, stock, reserved, created_at, updated_at`
+
+type ProductRepository struct {
+	db *DB
+}
+
+func NewProductRepository(db *DB) *ProductRepository {
+	return &ProductRepository{
+		db: db,
+	}
+}
+
+func (r *ProductRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *ProductRepository) CreateProduct(ctx context.Context, product *repository.Product) (*repository.Product, error) {
+	r.CheckPreconditions()
+
+	// Set CreatedAt and UpdatedAt to the current time
+	currentTime := now()
+
+	product.CreatedAt = formatTime(currentTime)
+	product.UpdatedAt = formatTime(currentTime)
+
+	err := product.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid product provided: %v", err)
+	}
+
+	id := newID()
+	product.Reserved = 0
+
+	_, err = r.db.db.ExecContext(ctx, `
+		INSERT INTO products (id, name, description, price, currency, category, stock, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		id, product.Name, product.Description, product.Price.Amount, product.Price.Currency, product.Category,
+		nullStock(product.Stock), currentTime, currentTime,
+	)
+	if err != nil {
+		return nil, dbError(err, "product", "create")
+	}
+
+	product.Id = id
+
+	return product, nil
+}
+
+func (r *ProductRepository) GetProduct(ctx context.Context, id string) (*repository.Product, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	return getProduct(ctx, r.db.db, id, false)
+}
+
+// productCursors parse the page token values of the product sort fields.
+var productCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"name":       textCursor,
+	"price":      uintCursor,
+}
+
+func (r *ProductRepository) ListProducts(
+	ctx context.Context, filter *repository.ProductFilter, opts *repository.ListOptions) ([]*repository.Product, string, error) {
+
+	r.CheckPreconditions()
+
+	page, err := repository.ParsePage(opts, repository.ProductSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var q listQuery
+	if filter != nil {
+		if err := filter.Validate(); err != nil {
+			return nil, "", err
+		}
+
+		if filter.NamePrefix != "" {
+			q.where(`starts_with(name, ` + q.arg(filter.NamePrefix) + `)`)
+		}
+		if filter.Currency != "" {
+			q.where(`currency = ` + q.arg(filter.Currency))
+		}
+		if filter.MinPrice != nil {
+			q.where(`price >= ` + q.arg(*filter.MinPrice))
+		}
+		if filter.MaxPrice != nil {
+			q.where(`price <= ` + q.arg(*filter.MaxPrice))
+		}
+	}
+
+	query, err := q.page(productColumns, "products", page, productCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.db.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, "", dbError(err, "products", "list")
+	}
+	defer rows.Close()
+
+	products := make([]*repository.Product, 0)
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, "", dbError(err, "product", "unmarshall")
+		}
+
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", dbError(err, "products", "iterate")
+	}
+
+	products, next := repository.TrimPage(page, products,
+		func(p *repository.Product) string { return p.SortValue(page.Ordering.Field) },
+		func(p *repository.Product) string { return p.Id },
+	)
+
+	return products, next, nil
+}
+
+func (r *ProductRepository) UpdateProduct(ctx context.Context, id string, update *repository.ProductUpdate,
+) (*repository.Product, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	var product *repository.Product
+	err := r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		if product, err = getProduct(ctx, tx, id, true); err != nil {
+			return err
+		}
+
+		if p := update.Name; p != nil {
+			product.Name = *p
+		}
+
+		if p := update.Description; p != nil {
+			product.Description = *p
+		}
+
+		if p := update.Price; p != nil {
+			product.Price = *p
+		}
+
+		if p := update.Category; p != nil {
+			product.Category = *p
+		}
+
+		if p := update.Stock; p != nil {
+			product.Stock = pkg.UintPtr(*p)
+		}
+
+		err = product.Validate()
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid product details provided: %v", err)
+		}
+
+		timeNow := now()
+		product.UpdatedAt = formatTime(timeNow)
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE products SET name = $2, description = $3, price = $4, currency = $5, category = $6, stock = $7,
+				updated_at = $8
+			WHERE id = $1`,
+			id, product.Name, product.Description, product.Price.Amount, product.Price.Currency, product.Category,
+			nullStock(product.Stock), timeNow,
+		)
+		return dbError(err, "product", "update")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+func (r *ProductRepository) DeleteProduct(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	result, err := r.db.db.ExecContext(ctx, `DELETE FROM products WHERE id = $1`, id)
+	if err != nil {
+		return dbError(err, "product", "delete")
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return dbError(err, "product", "delete")
+	} else if n == 0 {
+		return service.Errorf(service.NOT_FOUND_ERROR, "product not found")
+	}
+
+	return nil
+}
+
+func (r *ProductRepository) ReserveStock(ctx context.Context, reservationId string, items []repository.StockItem) error {
+	r.CheckPreconditions()
+
+	if reservationId == "" {
+		return service.Errorf(service.INVALID_ERROR, "reservation id is required")
+	}
+
+	items, err := repository.MergeStockItems(items)
+	if err != nil {
+		return err
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		replaced, err := lockStockReservation(ctx, tx, reservationId)
+		if err != nil {
+			return err
+		}
+
+		products, err := lockProducts(ctx, tx, repository.StockProductIds(replaced, items))
+		if err != nil {
+			return err
+		}
+
+		reserved, err := repository.ReplaceStockReservation(products, replaced, items)
+		if err != nil {
+			return err
+		}
+
+		if err := storeProductStock(ctx, tx, products); err != nil {
+			return err
+		}
+
+		if err := deleteStockReservation(ctx, tx, reservationId); err != nil {
+			return err
+		}
+
+		for _, item := range reserved {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO stock_reservations (id, product_id, quantity) VALUES ($1, $2, $3)`,
+				reservationId, item.ProductId, int64(item.Quantity),
+			)
+			if err != nil {
+				return dbError(err, "stock reservation", "insert")
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *ProductRepository) ReleaseStock(ctx context.Context, reservationId string) error {
+	return r.settleStock(ctx, reservationId, (*repository.Product).Release)
+}
+
+func (r *ProductRepository) CommitStock(ctx context.Context, reservationId string) error {
+	return r.settleStock(ctx, reservationId, (*repository.Product).Commit)
+}
+
+// settleStock applies adjust to the products of the reservation and deletes
+// it in one transaction.
+func (r *ProductRepository) settleStock(
+	ctx context.Context, reservationId string, adjust repository.StockAdjustment) error {
+
+	r.CheckPreconditions()
+
+	if reservationId == "" {
+		return service.Errorf(service.INVALID_ERROR, "reservation id is required")
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		items, err := lockStockReservation(ctx, tx, reservationId)
+		if err != nil {
+			return err
+		}
+
+		products, err := lockProducts(ctx, tx, repository.StockProductIds(items))
+		if err != nil {
+			return err
+		}
+
+		if err := repository.SettleStockReservation(products, items, adjust); err != nil {
+			return err
+		}
+
+		if err := storeProductStock(ctx, tx, products); err != nil {
+			return err
+		}
+
+		return deleteStockReservation(ctx, tx, reservationId)
+	})
+}
+
+// lockStockReservation returns the items of a reservation, or none if it does
+// not exist, and keeps other transactions from changing it until the
+// surrounding one ends. The lock is taken on the id rather than on rows, as
+// reservations that do not exist yet have none.
+func lockStockReservation(ctx context.Context, tx *sql.Tx, id string) ([]repository.StockItem, error) {
+	_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "stock_reservations/"+id)
+	if err != nil {
+		return nil, dbError(err, "stock reservation", "lock")
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT product_id, quantity FROM stock_reservations WHERE id = $1 ORDER BY product_id`, id)
+	if err != nil {
+		return nil, dbError(err, "stock reservation", "get")
+	}
+	defer rows.Close()
+
+	var items []repository.StockItem
+	for rows.Next() {
+		var (
+			item     repository.StockItem
+			quantity int64
+		)
+
+		if err := rows.Scan(&item.ProductId, &quantity); err != nil {
+			return nil, dbError(err, "stock reservation", "scan")
+		}
+
+		item.Quantity = uint(quantity)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "stock reservation", "iterate")
+	}
+
+	return items, nil
+}
+
+func deleteStockReservation(ctx context.Context, tx *sql.Tx, id string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM stock_reservations WHERE id = $1`, id)
+	return dbError(err, "stock reservation", "delete")
+}
+
+// lockProducts locks the products with the given ids that exist, in the
+// order of the ids, so that concurrent adjustments cannot deadlock.
+func lockProducts(ctx context.Context, tx *sql.Tx, ids []string) (map[string]*repository.Product, error) {
+	products := make(map[string]*repository.Product, len(ids))
+	for _, id := range ids {
+		product, err := getProduct(ctx, tx, id, true)
+		if service.ErrorCode(err) == service.NOT_FOUND_ERROR {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		products[id] = product
+	}
+
+	return products, nil
+}
+
+// storeProductStock stores the stock of products after an adjustment.
+func storeProductStock(ctx context.Context, tx *sql.Tx, products map[string]*repository.Product) error {
+	timeNow := now()
+
+	for _, product := range products {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE products SET stock = $2, reserved = $3, updated_at = $4
+			WHERE id = $1`,
+			product.Id, nullStock(product.Stock), int64(product.Reserved), timeNow,
+		)
+		if err != nil {
+			return dbError(err, "product", "update")
+		}
+	}
+
+	return nil
+}
+
+// getProduct fetches a product by id, optionally locking the row for the rest
+// of the surrounding transaction.
+func getProduct(ctx context.Context, q queryer, id string, forUpdate bool) (*repository.Product, error) {
+	query := `SELECT ` + productColumns + ` FROM products WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	product, err := scanProduct(q.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, dbError(err, "product", "get")
+	}
+
+	return product, nil
+}
+
+// nullStock converts the stock of a product to a column value, NULL for
+// products whose stock is not tracked.
+func nullStock(stock *uint) sql.NullInt64 {
+	if stock == nil {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(*stock), Valid: true}
+}
+
+func scanProduct(s scanner) (*repository.Product, error) {
+	var (
+		product              repository.Product
+		price                int64
+		stock                sql.NullInt64
+		reserved             int64
+		currency             string
+		createdAt, updatedAt time.Time
+	)
+
+	if err := s.Scan(
+		&product.Id, &product.Name, &product.Description, &price, &currency, &product.Category, &stock, &reserved,
+		&createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	product.Price = money.New(price, currency)
+	if stock.Valid {
+		product.Stock = pkg.UintPtr(uint(stock.Int64))
+	}
+	product.Reserved = uint(reserved)
+	product.CreatedAt = formatTime(createdAt)
+	product.UpdatedAt = formatTime(updatedAt)
+
+	return &product, nil
+}
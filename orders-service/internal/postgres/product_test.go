@@ -0,0 +1,25 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestProductRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("ProductRepository.CheckPreconditions() did not panic for nil DB")
+		}
+	}()
+
+	postgres.NewProductRepository(nil).CheckPreconditions()
+}
+
+func TestProductRepository(t *testing.T) {
+	repositorytest.TestProductRepository(t, func(t *testing.T) repository.ProductRepository {
+		return postgres.NewProductRepository(MustOpenDB(t))
+	})
+}
@@ -0,0 +1,378 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+var _ repository.PromotionRepository = (*PromotionRepository)(nil)
+
+const promotionColumns = `id, code, description, type, percentage, currency, amount_off, product_id, ` +
+	`buy_quantity, get_quantity, starts_at, ends_at, usage_limit, per_customer_limit, redemptions, ` +
+	`created_at, updated_at`
+
+type PromotionRepository struct {
+	db *DB
+}
+
+func NewPromotionRepository(db *DB) *PromotionRepository {
+	return &PromotionRepository{
+		db: db,
+	}
+}
+
+func (r *PromotionRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *PromotionRepository) CreatePromotion(
+	ctx context.Context, promotion *repository.Promotion) (*repository.Promotion, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := now()
+	promotion.CreatedAt = formatTime(currentTime)
+	promotion.UpdatedAt = formatTime(currentTime)
+	promotion.Redemptions = 0
+
+	err := promotion.Validate()
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid promotion provided: %v", err)
+	}
+
+	startsAt, endsAt := setPromotionWindow(promotion)
+	rule := promotion.Rule
+	id := newID()
+
+	_, err = r.db.db.ExecContext(ctx, `
+		INSERT INTO promotions (`+promotionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`,
+		id, promotion.Code, promotion.Description, string(rule.Type), int64(rule.Percentage),
+		rule.AmountOff.Currency, rule.AmountOff.Amount, rule.ProductId, int64(rule.BuyQuantity),
+		int64(rule.GetQuantity), startsAt, endsAt, int64(promotion.UsageLimit), int64(promotion.PerCustomerLimit),
+		int64(promotion.Redemptions), currentTime, currentTime,
+	)
+	if err != nil {
+		return nil, dbError(err, "promotion", "create")
+	}
+
+	promotion.Id = id
+
+	return promotion, nil
+}
+
+func (r *PromotionRepository) GetPromotion(ctx context.Context, id string) (*repository.Promotion, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	return getPromotion(ctx, r.db.db, "id", id, false)
+}
+
+func (r *PromotionRepository) GetPromotionByCode(ctx context.Context, code string) (*repository.Promotion, error) {
+	r.CheckPreconditions()
+
+	if code == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "code is required")
+	}
+
+	return getPromotion(ctx, r.db.db, "code", code, false)
+}
+
+// promotionCursors parse the page token values of the promotion sort fields.
+var promotionCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"code":       textCursor,
+}
+
+func (r *PromotionRepository) ListPromotions(
+	ctx context.Context, opts *repository.ListOptions) ([]*repository.Promotion, string, error) {
+
+	r.CheckPreconditions()
+
+	page, err := repository.ParsePage(opts, repository.PromotionSortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var q listQuery
+	query, err := q.page(promotionColumns, "promotions", page, promotionCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := r.db.db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		return nil, "", dbError(err, "promotions", "list")
+	}
+	defer rows.Close()
+
+	promotions := make([]*repository.Promotion, 0)
+	for rows.Next() {
+		promotion, err := scanPromotion(rows)
+		if err != nil {
+			return nil, "", dbError(err, "promotion", "unmarshall")
+		}
+
+		promotions = append(promotions, promotion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", dbError(err, "promotions", "iterate")
+	}
+
+	promotions, next := repository.TrimPage(page, promotions,
+		func(p *repository.Promotion) string { return p.SortValue(page.Ordering.Field) },
+		func(p *repository.Promotion) string { return p.Id },
+	)
+
+	return promotions, next, nil
+}
+
+func (r *PromotionRepository) UpdatePromotion(
+	ctx context.Context, id string, update *repository.PromotionUpdate) (*repository.Promotion, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	var promotion *repository.Promotion
+	err := r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		if promotion, err = getPromotion(ctx, tx, "id", id, true); err != nil {
+			return err
+		}
+
+		if v := update.Description; v != nil {
+			promotion.Description = *v
+		}
+
+		if v := update.StartsAt; v != nil {
+			promotion.StartsAt = *v
+		}
+
+		if v := update.EndsAt; v != nil {
+			promotion.EndsAt = *v
+		}
+
+		if v := update.UsageLimit; v != nil {
+			promotion.UsageLimit = *v
+		}
+
+		if v := update.PerCustomerLimit; v != nil {
+			promotion.PerCustomerLimit = *v
+		}
+
+		timeNow := now()
+		promotion.UpdatedAt = formatTime(timeNow)
+
+		err = promotion.Validate()
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid promotion details provided: %v", err)
+		}
+
+		startsAt, endsAt := setPromotionWindow(promotion)
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE promotions SET description = $2, starts_at = $3, ends_at = $4, usage_limit = $5,
+				per_customer_limit = $6, updated_at = $7
+			WHERE id = $1`,
+			id, promotion.Description, startsAt, endsAt, int64(promotion.UsageLimit),
+			int64(promotion.PerCustomerLimit), timeNow,
+		)
+		return dbError(err, "promotion", "update")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func (r *PromotionRepository) DeletePromotion(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	// Redemptions are removed through the ON DELETE CASCADE constraint.
+	result, err := r.db.db.ExecContext(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		return dbError(err, "promotion", "delete")
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return dbError(err, "promotion", "delete")
+	} else if n == 0 {
+		return service.Errorf(service.NOT_FOUND_ERROR, "promotion not found")
+	}
+
+	return nil
+}
+
+// RedeemPromotion locks the promotion row, so that concurrent redemptions are
+// counted one at a time against its limits.
+func (r *PromotionRepository) RedeemPromotion(ctx context.Context, redemption *repository.PromotionRedemption) error {
+	r.CheckPreconditions()
+
+	if err := redemption.Validate(); err != nil {
+		return err
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		promotion, err := getPromotion(ctx, tx, "id", redemption.PromotionId, true)
+		if err != nil {
+			return err
+		}
+
+		var redeemed, customerRedemptions int64
+		err = tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FILTER (WHERE order_id = $2), COUNT(*) FILTER (WHERE customer_id = $3)
+			FROM promotion_redemptions WHERE promotion_id = $1`,
+			redemption.PromotionId, redemption.OrderId, redemption.CustomerId,
+		).Scan(&redeemed, &customerRedemptions)
+		if err != nil {
+			return dbError(err, "promotion redemptions", "count")
+		} else if redeemed > 0 {
+			return nil
+		}
+
+		if err := promotion.CheckRedemption(uint(customerRedemptions)); err != nil {
+			return err
+		}
+
+		timeNow := now()
+		redemption.CreatedAt = formatTime(timeNow)
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO promotion_redemptions (promotion_id, order_id, customer_id, created_at)
+			VALUES ($1, $2, $3, $4)`,
+			redemption.PromotionId, redemption.OrderId, redemption.CustomerId, timeNow,
+		)
+		if err != nil {
+			return dbError(err, "promotion redemption", "create")
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE promotions SET redemptions = redemptions + 1 WHERE id = $1`, redemption.PromotionId)
+		return dbError(err, "promotion redemptions", "update")
+	})
+}
+
+func (r *PromotionRepository) ReleasePromotion(ctx context.Context, promotionId string, orderId string) error {
+	r.CheckPreconditions()
+
+	if promotionId == "" {
+		return service.Errorf(service.INVALID_ERROR, "promotion id is required")
+	} else if orderId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM promotion_redemptions WHERE promotion_id = $1 AND order_id = $2`, promotionId, orderId)
+		if err != nil {
+			return dbError(err, "promotion redemption", "delete")
+		}
+
+		if n, err := result.RowsAffected(); err != nil {
+			return dbError(err, "promotion redemption", "delete")
+		} else if n == 0 {
+			return nil
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE promotions SET redemptions = redemptions - 1 WHERE id = $1`, promotionId)
+		return dbError(err, "promotion redemptions", "update")
+	})
+}
+
+// getPromotion fetches a promotion by id or code, optionally locking the row
+// for the rest of the surrounding transaction.
+func getPromotion(
+	ctx context.Context, q queryer, column string, value string, forUpdate bool) (*repository.Promotion, error) {
+
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE ` + column + ` = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	promotion, err := scanPromotion(q.QueryRowContext(ctx, query, value))
+	if err != nil {
+		return nil, dbError(err, "promotion", "get")
+	}
+
+	return promotion, nil
+}
+
+// setPromotionWindow returns the validated starts_at & ends_at of the
+// promotion as column values, and formats them like the stored timestamps.
+func setPromotionWindow(promotion *repository.Promotion) (startsAt sql.NullTime, endsAt sql.NullTime) {
+	startsAt, endsAt = nullTime(promotion.StartsAt), nullTime(promotion.EndsAt)
+	promotion.StartsAt, promotion.EndsAt = formatNullTime(startsAt), formatNullTime(endsAt)
+
+	return startsAt, endsAt
+}
+
+// nullTime converts an optional RFC3339 timestamp to a nullable column value.
+func nullTime(s string) sql.NullTime {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+
+	return formatTime(t.Time)
+}
+
+func scanPromotion(s scanner) (*repository.Promotion, error) {
+	var (
+		promotion                                 repository.Promotion
+		promotionType, currency                   string
+		percentage, amountOff                     int64
+		buyQuantity, getQuantity                  int64
+		usageLimit, perCustomerLimit, redemptions int64
+		startsAt, endsAt                          sql.NullTime
+		createdAt, updatedAt                      time.Time
+	)
+
+	if err := s.Scan(
+		&promotion.Id, &promotion.Code, &promotion.Description, &promotionType, &percentage, &currency, &amountOff,
+		&promotion.Rule.ProductId, &buyQuantity, &getQuantity, &startsAt, &endsAt, &usageLimit, &perCustomerLimit,
+		&redemptions, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	promotion.Rule.Type = repository.PromotionType(promotionType)
+	promotion.Rule.Percentage = uint(percentage)
+	promotion.Rule.AmountOff = money.New(amountOff, currency)
+	promotion.Rule.BuyQuantity = uint(buyQuantity)
+	promotion.Rule.GetQuantity = uint(getQuantity)
+	promotion.StartsAt = formatNullTime(startsAt)
+	promotion.EndsAt = formatNullTime(endsAt)
+	promotion.UsageLimit = uint(usageLimit)
+	promotion.PerCustomerLimit = uint(perCustomerLimit)
+	promotion.Redemptions = uint(redemptions)
+	promotion.CreatedAt = formatTime(createdAt)
+	promotion.UpdatedAt = formatTime(updatedAt)
+
+	return &promotion, nil
+}
@@ -0,0 +1,15 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository/repositorytest"
+)
+
+func TestPromotionRepository(t *testing.T) {
+	repositorytest.TestPromotionRepository(t, func(t *testing.T) repository.PromotionRepository {
+		return postgres.NewPromotionRepository(MustOpenDB(t))
+	})
+}
@@ -0,0 +1,375 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+var _ repository.WebhookRepository = (*WebhookRepository)(nil)
+
+const (
+	webhookSubscriptionColumns = `id, url, secret, event_types, created_at, updated_at`
+
+	webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, ` +
+		`last_response_code, last_error, next_attempt_at, created_at, updated_at`
+)
+
+type WebhookRepository struct {
+	db *DB
+}
+
+func NewWebhookRepository(db *DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
+
+func (r *WebhookRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *WebhookRepository) CreateWebhookSubscription(
+	ctx context.Context, subscription *repository.WebhookSubscription) (*repository.WebhookSubscription, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := now()
+
+	subscription.CreatedAt = formatTime(currentTime)
+	subscription.UpdatedAt = formatTime(currentTime)
+
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+
+	eventTypes, err := json.Marshal(subscription.EventTypes)
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to marshall event types: %v", err)
+	}
+
+	id := newID()
+
+	_, err = r.db.db.ExecContext(ctx, `
+		INSERT INTO webhook_subscriptions (`+webhookSubscriptionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		id, subscription.Url, subscription.Secret, eventTypes, currentTime, currentTime,
+	)
+	if err != nil {
+		return nil, dbError(err, "webhook subscription", "create")
+	}
+
+	subscription.Id = id
+
+	return subscription, nil
+}
+
+func (r *WebhookRepository) GetWebhookSubscription(
+	ctx context.Context, id string) (*repository.WebhookSubscription, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	subscription, err := scanWebhookSubscription(r.db.db.QueryRowContext(ctx,
+		`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id))
+	if err != nil {
+		return nil, dbError(err, "webhook subscription", "get")
+	}
+
+	return subscription, nil
+}
+
+func (r *WebhookRepository) ListWebhookSubscriptions(ctx context.Context) ([]*repository.WebhookSubscription, error) {
+	r.CheckPreconditions()
+
+	rows, err := r.db.db.QueryContext(ctx,
+		`SELECT `+webhookSubscriptionColumns+` FROM webhook_subscriptions ORDER BY seq`)
+	if err != nil {
+		return nil, dbError(err, "webhook subscriptions", "list")
+	}
+	defer rows.Close()
+
+	subscriptions := make([]*repository.WebhookSubscription, 0)
+	for rows.Next() {
+		subscription, err := scanWebhookSubscription(rows)
+		if err != nil {
+			return nil, dbError(err, "webhook subscriptions", "list")
+		}
+
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "webhook subscriptions", "list")
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	result, err := r.db.db.ExecContext(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return dbError(err, "webhook subscription", "delete")
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return dbError(err, "webhook subscription", "delete")
+	} else if n == 0 {
+		return service.Errorf(service.NOT_FOUND_ERROR, "webhook subscription not found")
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) CreateWebhookDelivery(
+	ctx context.Context, delivery *repository.WebhookDelivery) (*repository.WebhookDelivery, error) {
+
+	r.CheckPreconditions()
+
+	currentTime := now()
+
+	delivery.CreatedAt = formatTime(currentTime)
+	delivery.UpdatedAt = formatTime(currentTime)
+
+	if err := delivery.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Validated above.
+	nextAttemptAt, _ := time.Parse(time.RFC3339, delivery.NextAttemptAt)
+
+	id := newID()
+
+	_, err := r.db.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		id, delivery.SubscriptionId, delivery.EventId, delivery.EventType, delivery.Payload, string(delivery.Status),
+		int64(delivery.Attempts), int64(delivery.LastResponseCode), delivery.LastError, nextAttemptAt,
+		currentTime, currentTime,
+	)
+	if err != nil {
+		return nil, dbError(err, "webhook delivery", "create")
+	}
+
+	delivery.Id = id
+
+	return delivery, nil
+}
+
+func (r *WebhookRepository) GetWebhookDelivery(ctx context.Context, id string) (*repository.WebhookDelivery, error) {
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	return getWebhookDelivery(ctx, r.db.db, id, false)
+}
+
+// webhookDeliveryCursors parse the page token values of the delivery sort
+// fields.
+var webhookDeliveryCursors = map[string]func(string) (any, error){
+	"created_at": timeCursor,
+	"updated_at": timeCursor,
+}
+
+func (r *WebhookRepository) ListWebhookDeliveries(ctx context.Context, filter *repository.WebhookDeliveryFilter,
+	opts *repository.ListOptions) ([]*repository.WebhookDelivery, string, error) {
+
+	r.CheckPreconditions()
+
+	page, err := repository.ParsePage(opts, repository.WebhookDeliverySortFields...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var q listQuery
+	if filter != nil {
+		if err := filter.Validate(); err != nil {
+			return nil, "", err
+		}
+
+		if filter.SubscriptionId != "" {
+			q.where(`subscription_id = ` + q.arg(filter.SubscriptionId))
+		}
+		if filter.Status != "" {
+			q.where(`status = ` + q.arg(string(filter.Status)))
+		}
+	}
+
+	query, err := q.page(webhookDeliveryColumns, "webhook_deliveries", page,
+		webhookDeliveryCursors[page.Ordering.Field])
+	if err != nil {
+		return nil, "", err
+	}
+
+	deliveries, err := r.queryWebhookDeliveries(ctx, query, q.args...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	deliveries, next := repository.TrimPage(page, deliveries,
+		func(d *repository.WebhookDelivery) string { return d.SortValue(page.Ordering.Field) },
+		func(d *repository.WebhookDelivery) string { return d.Id },
+	)
+
+	return deliveries, next, nil
+}
+
+func (r *WebhookRepository) ListDueWebhookDeliveries(
+	ctx context.Context, at time.Time, limit int) ([]*repository.WebhookDelivery, error) {
+
+	r.CheckPreconditions()
+
+	return r.queryWebhookDeliveries(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at, id LIMIT $2`, at, limit)
+}
+
+func (r *WebhookRepository) UpdateWebhookDelivery(ctx context.Context, id string,
+	update *repository.WebhookDeliveryUpdate) (*repository.WebhookDelivery, error) {
+
+	r.CheckPreconditions()
+
+	if id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "id is required")
+	}
+
+	var delivery *repository.WebhookDelivery
+	err := r.db.withTx(ctx, func(tx *sql.Tx) (err error) {
+		if delivery, err = getWebhookDelivery(ctx, tx, id, true); err != nil {
+			return err
+		}
+
+		if err := delivery.ApplyUpdate(update); err != nil {
+			return err
+		}
+
+		// Validated by ApplyUpdate.
+		nextAttemptAt, _ := time.Parse(time.RFC3339, delivery.NextAttemptAt)
+
+		timeNow := now()
+		delivery.UpdatedAt = formatTime(timeNow)
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = $3, last_response_code = $4, last_error = $5, next_attempt_at = $6,
+				updated_at = $7
+			WHERE id = $1`,
+			id, string(delivery.Status), int64(delivery.Attempts), int64(delivery.LastResponseCode),
+			delivery.LastError, nextAttemptAt, timeNow,
+		)
+		return dbError(err, "webhook delivery", "update")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (r *WebhookRepository) queryWebhookDeliveries(
+	ctx context.Context, query string, args ...any) ([]*repository.WebhookDelivery, error) {
+
+	rows, err := r.db.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, dbError(err, "webhook deliveries", "list")
+	}
+	defer rows.Close()
+
+	deliveries := make([]*repository.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, dbError(err, "webhook deliveries", "list")
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "webhook deliveries", "list")
+	}
+
+	return deliveries, nil
+}
+
+func getWebhookDelivery(
+	ctx context.Context, q queryer, id string, forUpdate bool) (*repository.WebhookDelivery, error) {
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+
+	delivery, err := scanWebhookDelivery(q.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, dbError(err, "webhook delivery", "get")
+	}
+
+	return delivery, nil
+}
+
+func scanWebhookSubscription(s scanner) (*repository.WebhookSubscription, error) {
+	var (
+		subscription         repository.WebhookSubscription
+		eventTypes           []byte
+		createdAt, updatedAt time.Time
+	)
+
+	if err := s.Scan(
+		&subscription.Id, &subscription.Url, &subscription.Secret, &eventTypes, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(eventTypes, &subscription.EventTypes); err != nil {
+		return nil, err
+	}
+
+	subscription.CreatedAt = formatTime(createdAt)
+	subscription.UpdatedAt = formatTime(updatedAt)
+
+	return &subscription, nil
+}
+
+func scanWebhookDelivery(s scanner) (*repository.WebhookDelivery, error) {
+	var (
+		delivery                            repository.WebhookDelivery
+		status                              string
+		attempts, lastResponseCode          int64
+		nextAttemptAt, createdAt, updatedAt time.Time
+	)
+
+	if err := s.Scan(
+		&delivery.Id, &delivery.SubscriptionId, &delivery.EventId, &delivery.EventType, &delivery.Payload, &status,
+		&attempts, &lastResponseCode, &delivery.LastError, &nextAttemptAt, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	delivery.Status = repository.WebhookDeliveryStatus(status)
+	delivery.Attempts = uint(attempts)
+	delivery.LastResponseCode = int(lastResponseCode)
+	delivery.NextAttemptAt = formatTime(nextAttemptAt)
+	delivery.CreatedAt = formatTime(createdAt)
+	delivery.UpdatedAt = formatTime(updatedAt)
+
+	return &delivery, nil
+}
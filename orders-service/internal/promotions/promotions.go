@@ -0,0 +1,126 @@
+// Package promotions applies promotion codes to orders.
+package promotions
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+var _ service.PromotionService = (*PromotionService)(nil)
+
+type PromotionService struct {
+	promotionRepository repository.PromotionRepository
+	orderRepository     repository.OrderRepository
+}
+
+func NewPromotionService(
+	promotionRepository repository.PromotionRepository,
+	orderRepository repository.OrderRepository) *PromotionService {
+
+	return &PromotionService{
+		promotionRepository: promotionRepository,
+		orderRepository:     orderRepository,
+	}
+}
+
+func (s *PromotionService) CheckPreconditions() {
+	if s.promotionRepository == nil {
+		panic("promotionRepository is required")
+	}
+
+	if s.orderRepository == nil {
+		panic("orderRepository is required")
+	}
+}
+
+// ApplyPromotion redeems the promotion for the order and stores a copy of its
+// rule with the order, whose totals then include the discount. The promotion
+// replaced, if any, is released so that it no longer counts towards its
+// limits. It fails with FAILED_PRECONDITION_ERROR if the promotion is not
+// active, has reached its limits, or the order can no longer be checked out,
+// and with CONFLICT_ERROR if a concurrent call changed the promotion of the
+// order first; the redemption is released then too.
+func (s *PromotionService) ApplyPromotion(ctx context.Context, orderId string, code string) (*service.Order, error) {
+	s.CheckPreconditions()
+
+	if orderId == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "order id is required")
+	} else if code == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "code is required")
+	}
+
+	promotion, err := s.promotionRepository.GetPromotionByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := promotion.CheckActive(time.Now()); err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepository.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !order.OrderStatus.CanTransitionTo(pkg.OrderStatusProcessing) {
+		return nil, service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"promotions cannot be applied to a %s order", order.OrderStatus)
+	}
+
+	previous := order.Promotion
+	if previous != nil && previous.PromotionId == promotion.Id {
+		return order.ServiceOrder(), nil
+	}
+
+	err = s.promotionRepository.RedeemPromotion(ctx, &repository.PromotionRedemption{
+		PromotionId: promotion.Id,
+		OrderId:     order.Id,
+		CustomerId:  order.CustomerId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var replaces string
+	if previous != nil {
+		replaces = previous.PromotionId
+	}
+
+	order, err = s.orderRepository.ApplyOrderPromotion(ctx, orderId, &repository.AppliedPromotion{
+		PromotionId: promotion.Id,
+		Code:        promotion.Code,
+		Rule:        promotion.Rule,
+	}, replaces)
+	if err != nil {
+		s.releaseUnapplied(ctx, promotion.Id, orderId)
+		return nil, err
+	}
+
+	if previous != nil {
+		if err := s.promotionRepository.ReleasePromotion(ctx, previous.PromotionId, orderId); err != nil {
+			log.Printf("[promotions] failed to release promotion %s of order %s: %v", previous.PromotionId, orderId, err)
+		}
+	}
+
+	return order.ServiceOrder(), nil
+}
+
+// releaseUnapplied releases the redemption of a promotion that could not be
+// applied to the order, unless a concurrent call applied the same promotion,
+// whose redemption it shares.
+func (s *PromotionService) releaseUnapplied(ctx context.Context, promotionId string, orderId string) {
+	order, err := s.orderRepository.GetOrder(ctx, orderId)
+	if err == nil && order.Promotion != nil && order.Promotion.PromotionId == promotionId {
+		return
+	}
+
+	if err := s.promotionRepository.ReleasePromotion(ctx, promotionId, orderId); err != nil {
+		log.Printf("[promotions] failed to release promotion %s of order %s: %v", promotionId, orderId, err)
+	}
+}
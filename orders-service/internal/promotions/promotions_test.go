@@ -0,0 +1,264 @@
+package promotions_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/mock"
+	"github.com/Mik3y-F/order-management-system/orders/internal/promotions"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+// setup returns a promotion service backed by memory repositories, with one
+// order of 2 units at 100 KES each.
+func setup(t *testing.T) (*promotions.PromotionService, repository.PromotionRepository, repository.OrderRepository, *repository.Order) {
+	t.Helper()
+
+	promotionRepository := memory.NewPromotionRepository()
+	orderRepository := memory.NewOrderRepository()
+
+	order, err := orderRepository.CreateOrder(context.Background(), &repository.Order{
+		CustomerId: "customer-1",
+		Items:      []*repository.OrderItem{{ProductId: "product-1", Quantity: 2, UnitPrice: money.New(10000, "KES")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	return promotions.NewPromotionService(promotionRepository, orderRepository), promotionRepository, orderRepository, order
+}
+
+func createPromotion(t *testing.T, r repository.PromotionRepository, promotion *repository.Promotion) *repository.Promotion {
+	t.Helper()
+
+	if promotion.Rule.Type == "" {
+		promotion.Rule = repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10}
+	}
+
+	p, err := r.CreatePromotion(context.Background(), promotion)
+	if err != nil {
+		t.Fatalf("failed to create promotion: %v", err)
+	}
+
+	return p
+}
+
+func wantRedemptions(t *testing.T, r repository.PromotionRepository, id string, want uint) {
+	t.Helper()
+
+	p, err := r.GetPromotion(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get promotion: %v", err)
+	}
+
+	if p.Redemptions != want {
+		t.Errorf("promotion %s redemptions = %d, want %d", p.Code, p.Redemptions, want)
+	}
+}
+
+func TestPromotionService_ApplyPromotion(t *testing.T) {
+	ctx := context.Background()
+	s, promotionRepository, _, order := setup(t)
+
+	ten := createPromotion(t, promotionRepository, &repository.Promotion{Code: "TEN"})
+	fifty := createPromotion(t, promotionRepository, &repository.Promotion{
+		Code: "FIFTY",
+		Rule: repository.PromotionRule{Type: repository.PromotionTypeFixedAmount, AmountOff: money.New(5000, "KES")},
+	})
+
+	got, err := s.ApplyPromotion(ctx, order.Id, "TEN")
+	if err != nil {
+		t.Fatalf("ApplyPromotion() error = %v", err)
+	}
+	if got.PromotionCode != "TEN" || got.Discount != money.New(2000, "KES") || got.Total != money.New(18000, "KES") {
+		t.Errorf("ApplyPromotion() = %+v, want TEN with a discount of 20 and a total of 180", got)
+	}
+	wantRedemptions(t, promotionRepository, ten.Id, 1)
+
+	// Applying the same promotion again is not counted twice.
+	if _, err := s.ApplyPromotion(ctx, order.Id, "TEN"); err != nil {
+		t.Fatalf("ApplyPromotion() again error = %v", err)
+	}
+	wantRedemptions(t, promotionRepository, ten.Id, 1)
+
+	// Another promotion replaces it and releases its redemption.
+	got, err = s.ApplyPromotion(ctx, order.Id, "FIFTY")
+	if err != nil {
+		t.Fatalf("ApplyPromotion() error = %v", err)
+	}
+	if got.PromotionCode != "FIFTY" || got.Total != money.New(15000, "KES") {
+		t.Errorf("ApplyPromotion() = %+v, want FIFTY with a total of 150", got)
+	}
+	wantRedemptions(t, promotionRepository, ten.Id, 0)
+	wantRedemptions(t, promotionRepository, fifty.Id, 1)
+}
+
+func TestPromotionService_ApplyPromotion_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion *repository.Promotion
+		code      string
+		status    pkg.OrderStatus
+		wantCode  string
+	}{
+		{
+			name:     "Without Code",
+			code:     "",
+			wantCode: service.INVALID_ERROR,
+		},
+		{
+			name:     "Unknown Code",
+			code:     "UNKNOWN",
+			wantCode: service.NOT_FOUND_ERROR,
+		},
+		{
+			name:      "Not Started",
+			promotion: &repository.Promotion{Code: "SOON", StartsAt: "2124-01-01T00:00:00Z"},
+			code:      "SOON",
+			wantCode:  service.FAILED_PRECONDITION_ERROR,
+		},
+		{
+			name:      "Ended",
+			promotion: &repository.Promotion{Code: "OVER", EndsAt: "2024-01-01T00:00:00Z"},
+			code:      "OVER",
+			wantCode:  service.FAILED_PRECONDITION_ERROR,
+		},
+		{
+			name:      "Checked Out",
+			promotion: &repository.Promotion{Code: "TEN"},
+			code:      "TEN",
+			status:    pkg.OrderStatusProcessing,
+			wantCode:  service.FAILED_PRECONDITION_ERROR,
+		},
+		{
+			name: "Other Currency",
+			promotion: &repository.Promotion{
+				Code: "DOLLARS",
+				Rule: repository.PromotionRule{Type: repository.PromotionTypeFixedAmount, AmountOff: money.New(50, "USD")},
+			},
+			code:     "DOLLARS",
+			wantCode: service.INVALID_ERROR,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, promotionRepository, orderRepository, order := setup(t)
+
+			var promotion *repository.Promotion
+			if tt.promotion != nil {
+				promotion = createPromotion(t, promotionRepository, tt.promotion)
+			}
+
+			if tt.status != "" {
+				_, err := orderRepository.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: tt.status})
+				if err != nil {
+					t.Fatalf("failed to update order status: %v", err)
+				}
+			}
+
+			_, err := s.ApplyPromotion(ctx, order.Id, tt.code)
+			if code := service.ErrorCode(err); code != tt.wantCode {
+				t.Errorf("ApplyPromotion() code = %q, want %q (err = %v)", code, tt.wantCode, err)
+			}
+
+			got, err := orderRepository.GetOrder(ctx, order.Id)
+			if err != nil {
+				t.Fatalf("failed to get order: %v", err)
+			}
+			if got.Promotion != nil || got.Total != order.Total {
+				t.Errorf("order promotion = %+v, total = %v, want none and %v", got.Promotion, got.Total, order.Total)
+			}
+
+			// A promotion that could not be applied is not counted.
+			if promotion != nil {
+				wantRedemptions(t, promotionRepository, promotion.Id, 0)
+			}
+		})
+	}
+}
+
+func TestPromotionService_ApplyPromotion_UsedUp(t *testing.T) {
+	ctx := context.Background()
+	s, promotionRepository, orderRepository, order := setup(t)
+
+	createPromotion(t, promotionRepository, &repository.Promotion{Code: "ONCE", UsageLimit: 1})
+
+	if _, err := s.ApplyPromotion(ctx, order.Id, "ONCE"); err != nil {
+		t.Fatalf("ApplyPromotion() error = %v", err)
+	}
+
+	other, err := orderRepository.CreateOrder(ctx, &repository.Order{
+		CustomerId: "customer-2",
+		Items:      []*repository.OrderItem{{ProductId: "product-1", Quantity: 1, UnitPrice: money.New(10000, "KES")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+
+	_, err = s.ApplyPromotion(ctx, other.Id, "ONCE")
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("ApplyPromotion() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+}
+
+func TestPromotionService_ApplyPromotion_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	_, promotionRepository, orderRepository, order := setup(t)
+
+	ten := createPromotion(t, promotionRepository, &repository.Promotion{Code: "TEN"})
+	fifty := createPromotion(t, promotionRepository, &repository.Promotion{
+		Code: "FIFTY",
+		Rule: repository.PromotionRule{Type: repository.PromotionTypeFixedAmount, AmountOff: money.New(5000, "KES")},
+	})
+
+	orders := mock.NewOrderRepository(orderRepository)
+	s := promotions.NewPromotionService(promotionRepository, orders)
+
+	// race applies code with another call just before the next promotion is
+	// stored.
+	apply := orders.ApplyOrderPromotionFunc
+	race := func(code string) {
+		orders.ApplyOrderPromotionFunc = func(
+			ctx context.Context, orderId string, promotion *repository.AppliedPromotion, replaces string) (*repository.Order, error) {
+
+			orders.ApplyOrderPromotionFunc = apply
+			if _, err := s.ApplyPromotion(ctx, orderId, code); err != nil {
+				t.Fatalf("ApplyPromotion(%s) error = %v", code, err)
+			}
+
+			return apply(ctx, orderId, promotion, replaces)
+		}
+	}
+
+	// The call that loses gives its redemption back.
+	race("FIFTY")
+	_, err := s.ApplyPromotion(ctx, order.Id, "TEN")
+	if code := service.ErrorCode(err); code != service.CONFLICT_ERROR {
+		t.Errorf("ApplyPromotion() code = %q, want %q", code, service.CONFLICT_ERROR)
+	}
+	wantRedemptions(t, promotionRepository, ten.Id, 0)
+	wantRedemptions(t, promotionRepository, fifty.Id, 1)
+
+	// Unless the other call applied the same promotion, whose redemption is
+	// shared.
+	race("TEN")
+	_, err = s.ApplyPromotion(ctx, order.Id, "TEN")
+	if code := service.ErrorCode(err); code != service.CONFLICT_ERROR {
+		t.Errorf("ApplyPromotion() code = %q, want %q", code, service.CONFLICT_ERROR)
+	}
+	wantRedemptions(t, promotionRepository, ten.Id, 1)
+	wantRedemptions(t, promotionRepository, fifty.Id, 0)
+
+	got, err := orderRepository.GetOrder(ctx, order.Id)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	if got.Promotion == nil || got.Promotion.Code != "TEN" {
+		t.Errorf("order promotion = %+v, want TEN", got.Promotion)
+	}
+}
@@ -0,0 +1,138 @@
+package promotions
+
+import (
+	"context"
+	"log"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+var _ repository.OrderRepository = (*OrderRepository)(nil)
+
+// OrderRepository wraps an order repository and keeps the redemptions of
+// promotions in step with the orders they are applied to. An order stops
+// counting towards the limits of its promotion once it fails, is cancelled or
+// is deleted, and counts again when a failed order is checked out again.
+type OrderRepository struct {
+	repository.OrderRepository
+
+	promotions repository.PromotionRepository
+}
+
+func NewOrderRepository(orders repository.OrderRepository, promotions repository.PromotionRepository) *OrderRepository {
+	return &OrderRepository{
+		OrderRepository: orders,
+		promotions:      promotions,
+	}
+}
+
+func (r *OrderRepository) CheckPreconditions() {
+	if r.OrderRepository == nil {
+		panic("no order repository provided")
+	}
+
+	if r.promotions == nil {
+		panic("no promotion repository provided")
+	}
+}
+
+// redeemed reports whether an order in status s counts towards the limits of
+// its promotion.
+func redeemed(s pkg.OrderStatus) bool {
+	return s != pkg.OrderStatusFailed && s != pkg.OrderStatusCancelled
+}
+
+// UpdateOrderStatus redeems the promotion of an order before it is checked
+// out, and releases it once the order fails or is cancelled. Redeeming it
+// again for an order that holds its redemption does nothing; an order that
+// failed is checked against the limits again, and fails to be checked out with
+// FAILED_PRECONDITION_ERROR if the promotion reached them meanwhile.
+func (r *OrderRepository) UpdateOrderStatus(
+	ctx context.Context, id string, update *repository.OrderStatusUpdate) (*repository.Order, error) {
+
+	r.CheckPreconditions()
+
+	var promotionId string
+	if update.Status == pkg.OrderStatusProcessing {
+		order, err := r.OrderRepository.GetOrder(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if order.Promotion != nil && order.OrderStatus.CanTransitionTo(update.Status) {
+			promotionId = order.Promotion.PromotionId
+
+			err := r.promotions.RedeemPromotion(ctx, &repository.PromotionRedemption{
+				PromotionId: promotionId,
+				OrderId:     order.Id,
+				CustomerId:  order.CustomerId,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	updated, err := r.OrderRepository.UpdateOrderStatus(ctx, id, update)
+	if err != nil {
+		if promotionId != "" {
+			r.releaseUnlessRedeemed(ctx, id, promotionId)
+		}
+		return nil, err
+	}
+
+	// The promotion may have been replaced after the order was read, in
+	// which case the one redeemed above is not the order's to hold.
+	if promotionId != "" && (updated.Promotion == nil || updated.Promotion.PromotionId != promotionId) {
+		r.release(ctx, id, promotionId)
+	}
+
+	if !redeemed(updated.OrderStatus) && updated.Promotion != nil {
+		r.release(ctx, id, updated.Promotion.PromotionId)
+	}
+
+	return updated, nil
+}
+
+// releaseUnlessRedeemed releases a redemption made for a status update that
+// failed, unless the order counts towards the promotion anyway, e.g. because
+// it was never released or a concurrent update checked it out.
+func (r *OrderRepository) releaseUnlessRedeemed(ctx context.Context, orderId string, promotionId string) {
+	current, err := r.OrderRepository.GetOrder(ctx, orderId)
+	if err == nil && redeemed(current.OrderStatus) &&
+		current.Promotion != nil && current.Promotion.PromotionId == promotionId {
+		return
+	}
+
+	r.release(ctx, orderId, promotionId)
+}
+
+// DeleteOrder releases the promotion of the order once it is deleted.
+func (r *OrderRepository) DeleteOrder(ctx context.Context, id string) error {
+	r.CheckPreconditions()
+
+	order, err := r.OrderRepository.GetOrder(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := r.OrderRepository.DeleteOrder(ctx, id); err != nil {
+		return err
+	}
+
+	if order.Promotion != nil {
+		r.release(ctx, id, order.Promotion.PromotionId)
+	}
+
+	return nil
+}
+
+// release releases the redemption of a promotion by an order after its change
+// was stored. Returning an error would misreport the stored change, so
+// failures are logged for the redemption to be removed by hand.
+func (r *OrderRepository) release(ctx context.Context, orderId string, promotionId string) {
+	if err := r.promotions.ReleasePromotion(ctx, promotionId, orderId); err != nil {
+		log.Printf("[promotions] failed to release promotion %s of order %s: %v", promotionId, orderId, err)
+	}
+}
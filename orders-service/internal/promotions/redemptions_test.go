@@ -0,0 +1,122 @@
+package promotions_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/promotions"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func updateStatus(t *testing.T, r repository.OrderRepository, id string, status pkg.OrderStatus) {
+	t.Helper()
+
+	if _, err := r.UpdateOrderStatus(context.Background(), id, &repository.OrderStatusUpdate{Status: status}); err != nil {
+		t.Fatalf("UpdateOrderStatus(%s) error = %v", status, err)
+	}
+}
+
+func TestOrderRepository_UpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []pkg.OrderStatus
+		want     uint
+	}{
+		{
+			name:     "Paid",
+			statuses: []pkg.OrderStatus{pkg.OrderStatusProcessing, pkg.OrderStatusPending, pkg.OrderStatusPaid},
+			want:     1,
+		},
+		{
+			name:     "Failed",
+			statuses: []pkg.OrderStatus{pkg.OrderStatusProcessing, pkg.OrderStatusFailed},
+			want:     0,
+		},
+		{
+			name:     "Failed And Checked Out Again",
+			statuses: []pkg.OrderStatus{pkg.OrderStatusProcessing, pkg.OrderStatusFailed, pkg.OrderStatusProcessing},
+			want:     1,
+		},
+		{
+			name:     "Cancelled",
+			statuses: []pkg.OrderStatus{pkg.OrderStatusCancelled},
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, promotionRepository, orderRepository, order := setup(t)
+			orders := promotions.NewOrderRepository(orderRepository, promotionRepository)
+
+			ten := createPromotion(t, promotionRepository, &repository.Promotion{Code: "TEN"})
+			if _, err := s.ApplyPromotion(context.Background(), order.Id, "TEN"); err != nil {
+				t.Fatalf("ApplyPromotion() error = %v", err)
+			}
+
+			for _, status := range tt.statuses {
+				updateStatus(t, orders, order.Id, status)
+			}
+
+			wantRedemptions(t, promotionRepository, ten.Id, tt.want)
+		})
+	}
+}
+
+func TestOrderRepository_UpdateOrderStatus_UsedUp(t *testing.T) {
+	ctx := context.Background()
+	s, promotionRepository, orderRepository, order := setup(t)
+	orders := promotions.NewOrderRepository(orderRepository, promotionRepository)
+
+	once := createPromotion(t, promotionRepository, &repository.Promotion{Code: "ONCE", UsageLimit: 1})
+	if _, err := s.ApplyPromotion(ctx, order.Id, "ONCE"); err != nil {
+		t.Fatalf("ApplyPromotion() error = %v", err)
+	}
+
+	updateStatus(t, orders, order.Id, pkg.OrderStatusProcessing)
+	updateStatus(t, orders, order.Id, pkg.OrderStatusFailed)
+
+	// Another order takes the redemption the failed order gave back.
+	other, err := orderRepository.CreateOrder(ctx, &repository.Order{
+		CustomerId: "customer-2",
+		Items:      []*repository.OrderItem{{ProductId: "product-1", Quantity: 1, UnitPrice: money.New(10000, "KES")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	if _, err := s.ApplyPromotion(ctx, other.Id, "ONCE"); err != nil {
+		t.Fatalf("ApplyPromotion() error = %v", err)
+	}
+
+	_, err = orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("UpdateOrderStatus() code = %q, want %q", code, service.FAILED_PRECONDITION_ERROR)
+	}
+
+	got, err := orderRepository.GetOrder(ctx, order.Id)
+	if err != nil {
+		t.Fatalf("failed to get order: %v", err)
+	}
+	if got.OrderStatus != pkg.OrderStatusFailed {
+		t.Errorf("order status = %s, want %s", got.OrderStatus, pkg.OrderStatusFailed)
+	}
+	wantRedemptions(t, promotionRepository, once.Id, 1)
+}
+
+func TestOrderRepository_DeleteOrder(t *testing.T) {
+	ctx := context.Background()
+	s, promotionRepository, orderRepository, order := setup(t)
+	orders := promotions.NewOrderRepository(orderRepository, promotionRepository)
+
+	ten := createPromotion(t, promotionRepository, &repository.Promotion{Code: "TEN"})
+	if _, err := s.ApplyPromotion(ctx, order.Id, "TEN"); err != nil {
+		t.Fatalf("ApplyPromotion() error = %v", err)
+	}
+
+	if err := orders.DeleteOrder(ctx, order.Id); err != nil {
+		t.Fatalf("DeleteOrder() error = %v", err)
+	}
+	wantRedemptions(t, promotionRepository, ten.Id, 0)
+}
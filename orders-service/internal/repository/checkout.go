@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+// CheckoutStep is a step of a checkout saga. The steps run in the order they
+// are listed in; if one fails, the steps before it are compensated.
+type CheckoutStep string
+
+const (
+	// CheckoutStepReserve moves the order to processing, which reserves its
+	// items under a reservation named after the saga, so that running it
+	// again replaces the reservation rather than adding to it. It is
+	// compensated by failing the order, which releases them.
+	CheckoutStepReserve CheckoutStep = "reserve"
+
+	// CheckoutStepCharge requests the payment from the customer.
+	CheckoutStepCharge CheckoutStep = "charge"
+
+	// CheckoutStepConfirm checks that the payments service has taken over the
+	// order. Once the payment is requested the checkout is not compensated.
+	CheckoutStepConfirm CheckoutStep = "confirm"
+)
+
+// CheckoutSteps lists the steps of a checkout saga in the order they run.
+var CheckoutSteps = []CheckoutStep{CheckoutStepReserve, CheckoutStepCharge, CheckoutStepConfirm}
+
+// IsValid reports whether s is a known checkout step.
+func (s CheckoutStep) IsValid() bool {
+	for _, known := range CheckoutSteps {
+		if s == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+type CheckoutSagaStatus string
+
+const (
+	// CheckoutSagaStatusRunning sagas are running their Step.
+	CheckoutSagaStatusRunning CheckoutSagaStatus = "running"
+
+	// CheckoutSagaStatusCompleted sagas ran all of their steps.
+	CheckoutSagaStatusCompleted CheckoutSagaStatus = "completed"
+
+	// CheckoutSagaStatusCompensating sagas failed at their Step and are
+	// undoing the steps before it.
+	CheckoutSagaStatusCompensating CheckoutSagaStatus = "compensating"
+
+	// CheckoutSagaStatusCompensated sagas failed and were undone.
+	CheckoutSagaStatusCompensated CheckoutSagaStatus = "compensated"
+)
+
+// IsValid reports whether s is a known checkout saga status.
+func (s CheckoutSagaStatus) IsValid() bool {
+	switch s {
+	case CheckoutSagaStatusRunning, CheckoutSagaStatusCompleted,
+		CheckoutSagaStatusCompensating, CheckoutSagaStatusCompensated:
+		return true
+	}
+
+	return false
+}
+
+// Finished reports whether a saga in status s has nothing left to do.
+func (s CheckoutSagaStatus) Finished() bool {
+	return s == CheckoutSagaStatusCompleted || s == CheckoutSagaStatusCompensated
+}
+
+// CheckoutSaga records the progress of the checkout of an order, so that a
+// checkout interrupted by a restart of the service can be resumed or
+// compensated.
+type CheckoutSaga struct {
+	Id      string `json:"id"`
+	OrderId string `json:"order_id"`
+
+	// Step is the step being run or compensated, or the last step that ran
+	// once the saga is finished.
+	Step   CheckoutStep       `json:"step"`
+	Status CheckoutSagaStatus `json:"status"`
+
+	// Error is why the saga is compensated.
+	Error string `json:"error"`
+
+	// Version is bumped by every update of the saga.
+	Version   uint   `json:"version"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func (s *CheckoutSaga) Validate() error {
+	if s.OrderId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	if !s.Step.IsValid() {
+		return service.Errorf(service.INVALID_ERROR, "unknown checkout step %q", s.Step)
+	}
+
+	if !s.Status.IsValid() {
+		return service.Errorf(service.INVALID_ERROR, "unknown checkout saga status %q", s.Status)
+	}
+
+	return nil
+}
+
+// CheckoutSagaUpdate moves a saga to its next step or status.
+type CheckoutSagaUpdate struct {
+	Step   CheckoutStep       `json:"step"`
+	Status CheckoutSagaStatus `json:"status"`
+	Error  string             `json:"error"`
+
+	// Version must match the version of the stored saga, so that a saga is
+	// only advanced by one runner at a time.
+	Version uint `json:"version"`
+}
+
+// ApplyUpdate applies the update to the saga and bumps its version.
+// Repositories call it while holding the stored saga, so that the check and
+// the write happen atomically. Finished sagas cannot be updated.
+func (s *CheckoutSaga) ApplyUpdate(update *CheckoutSagaUpdate) error {
+	if update.Version != s.Version {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"checkout saga has been modified: version is %d, not %d", s.Version, update.Version)
+	}
+
+	if s.Status.Finished() {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR, "checkout saga is already %s", s.Status)
+	}
+
+	updated := *s
+	updated.Step = update.Step
+	updated.Status = update.Status
+	updated.Error = update.Error
+
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+
+	*s = updated
+	s.Version++
+
+	return nil
+}
+
+type CheckoutSagaRepository interface {
+	// CreateCheckoutSaga starts a saga at its first step.
+	CreateCheckoutSaga(ctx context.Context, saga *CheckoutSaga) (*CheckoutSaga, error)
+	GetCheckoutSaga(ctx context.Context, id string) (*CheckoutSaga, error)
+	UpdateCheckoutSaga(ctx context.Context, id string, update *CheckoutSagaUpdate) (*CheckoutSaga, error)
+
+	// ListUnfinishedCheckoutSagas returns the running and compensating sagas,
+	// oldest first.
+	ListUnfinishedCheckoutSagas(ctx context.Context) ([]*CheckoutSaga, error)
+}
@@ -43,10 +43,27 @@ type CustomerUpdate struct {
 	Email     *string
 }
 
+// CustomerSortFields are the fields ListCustomers can order by.
+var CustomerSortFields = []string{"created_at", "last_name"}
+
+// SortValue returns the value of a field in CustomerSortFields in a form that
+// sorts like the field itself.
+func (c *Customer) SortValue(field string) string {
+	switch field {
+	case "last_name":
+		return c.LastName
+	default:
+		return sortableTime(c.CreatedAt)
+	}
+}
+
 type CustomerRepository interface {
 	CreateCustomer(ctx context.Context, customer *Customer) (*Customer, error)
 	GetCustomer(ctx context.Context, id string) (*Customer, error)
-	ListCustomers(ctx context.Context) ([]*Customer, error)
+
+	// ListCustomers returns a page of customers together with the token of the
+	// next page, or an empty token if this is the last page.
+	ListCustomers(ctx context.Context, opts *ListOptions) ([]*Customer, string, error)
 	UpdateCustomer(ctx context.Context, id string, update *CustomerUpdate) (*Customer, error)
 	DeleteCustomer(ctx context.Context, id string) error
 }
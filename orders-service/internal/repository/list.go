@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// ListOptions controls the pagination and ordering of the List methods. The
+// zero value requests the first DefaultPageSize records, oldest first.
+type ListOptions struct {
+	// PageSize is the maximum number of records to return. Values above
+	// MaxPageSize are lowered to it.
+	PageSize int
+
+	// PageToken is the token returned with the previous page, or empty for the
+	// first page. It is only valid with the same OrderBy.
+	PageToken string
+
+	// OrderBy is a field name, optionally followed by " desc", e.g.
+	// "price desc". The fields that can be ordered by depend on the method.
+	OrderBy string
+}
+
+// withDefaultOrder returns opts, or a copy of it that orders by orderBy if opts
+// does not specify an ordering.
+func (o *ListOptions) withDefaultOrder(orderBy string) *ListOptions {
+	if o != nil && o.OrderBy != "" {
+		return o
+	}
+
+	opts := ListOptions{OrderBy: orderBy}
+	if o != nil {
+		opts.PageSize = o.PageSize
+		opts.PageToken = o.PageToken
+	}
+
+	return &opts
+}
+
+// Ordering is a field to order records by and its direction. Records with the
+// same value are ordered by id.
+type Ordering struct {
+	Field string
+	Desc  bool
+}
+
+func (o Ordering) String() string {
+	if o.Desc {
+		return o.Field + " desc"
+	}
+
+	return o.Field
+}
+
+// Cursor is the position of the last record of the previous page: its value
+// for the ordering field, as returned by SortValue, and its id.
+type Cursor struct {
+	Value string `json:"v"`
+	Id    string `json:"i"`
+}
+
+// Page is a validated ListOptions.
+type Page struct {
+	Ordering Ordering
+	Size     int
+
+	// Cursor is nil for the first page.
+	Cursor *Cursor
+}
+
+// pageToken is the content of a page token. The ordering is included so that a
+// token cannot be used with a different order_by.
+type pageToken struct {
+	Ordering string `json:"o"`
+	Cursor
+}
+
+// ParsePage validates opts against the fields that can be ordered by. The first
+// field is the default ordering.
+func ParsePage(opts *ListOptions, fields ...string) (*Page, error) {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+
+	page := &Page{
+		Ordering: Ordering{Field: fields[0]},
+		Size:     opts.PageSize,
+	}
+
+	if page.Size < 0 {
+		return nil, service.Errorf(service.INVALID_ERROR, "page_size must not be negative")
+	} else if page.Size == 0 {
+		page.Size = DefaultPageSize
+	} else if page.Size > MaxPageSize {
+		page.Size = MaxPageSize
+	}
+
+	if opts.OrderBy != "" {
+		ordering, err := parseOrdering(opts.OrderBy, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Ordering = ordering
+	}
+
+	if opts.PageToken != "" {
+		cursor, err := parsePageToken(opts.PageToken, page.Ordering)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Cursor = cursor
+	}
+
+	return page, nil
+}
+
+func parseOrdering(orderBy string, fields []string) (Ordering, error) {
+	parts := strings.Fields(orderBy)
+
+	var ordering Ordering
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 2 && strings.EqualFold(parts[1], "asc"):
+	case len(parts) == 2 && strings.EqualFold(parts[1], "desc"):
+		ordering.Desc = true
+	default:
+		return Ordering{}, service.Errorf(service.INVALID_ERROR, "invalid order_by %q", orderBy)
+	}
+
+	for _, field := range fields {
+		if parts[0] == field {
+			ordering.Field = field
+			return ordering, nil
+		}
+	}
+
+	return Ordering{}, service.Errorf(service.INVALID_ERROR,
+		"cannot order by %q, expected one of %s", parts[0], strings.Join(fields, ", "))
+}
+
+func parsePageToken(token string, ordering Ordering) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid page_token")
+	}
+
+	var t pageToken
+	if err := json.Unmarshal(b, &t); err != nil || t.Id == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid page_token")
+	}
+
+	if t.Ordering != ordering.String() {
+		return nil, service.Errorf(service.INVALID_ERROR, "page_token was issued for a different order_by")
+	}
+
+	return &t.Cursor, nil
+}
+
+// NextPageToken returns the token of the page that starts after the record
+// with the given sort value and id.
+func (p *Page) NextPageToken(value, id string) string {
+	b, err := json.Marshal(pageToken{
+		Ordering: p.Ordering.String(),
+		Cursor:   Cursor{Value: value, Id: id},
+	})
+	if err != nil {
+		panic("failed to encode page token: " + err.Error())
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// After reports whether a record with the given sort value and id comes after
+// the cursor in the page's ordering.
+func (p *Page) After(value, id string) bool {
+	if p.Cursor == nil {
+		return true
+	}
+
+	c := strings.Compare(value, p.Cursor.Value)
+	if c == 0 {
+		c = strings.Compare(id, p.Cursor.Id)
+	}
+
+	if p.Ordering.Desc {
+		return c < 0
+	}
+
+	return c > 0
+}
+
+// TrimPage cuts records, which were fetched with a limit of one more than the
+// page size, down to the page size. It returns the token of the next page, or
+// an empty token if there are no more records.
+func TrimPage[T any](page *Page, records []T, sortValue func(T) string, id func(T) string) ([]T, string) {
+	if len(records) <= page.Size {
+		return records, ""
+	}
+
+	records = records[:page.Size]
+	last := records[len(records)-1]
+
+	return records, page.NextPageToken(sortValue(last), id(last))
+}
+
+// sortableUint formats n so that the results sort in numeric order.
+func sortableUint(n uint) string {
+	return fmt.Sprintf("%020d", n)
+}
+
+// sortableTime converts an RFC3339 timestamp to UTC, so that the results sort
+// in chronological order. Malformed timestamps are returned unchanged.
+func sortableTime(s string) string {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return s
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
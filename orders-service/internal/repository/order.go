@@ -2,15 +2,31 @@ package repository
 
 import (
 	"context"
+	"math/big"
+	"sort"
+	"time"
 
 	"github.com/Mik3y-F/order-management-system/orders/internal/service"
 	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 )
 
 type OrderItem struct {
 	Id        string `json:"id"`
 	ProductId string `json:"product_id"`
 	Quantity  uint   `json:"quantity"`
+
+	// UnitPrice is the price of the product when the item was added, see
+	// PriceOrderItems. LineTotal is UnitPrice times Quantity.
+	UnitPrice money.Money `json:"unit_price"`
+	LineTotal money.Money `json:"line_total"`
+
+	// TaxRate is the tax rate of the product's category when the item was
+	// added, and Tax the tax at that rate on LineTotal less the item's share
+	// of the order discount, see Order.UpdateTotals.
+	TaxRate TaxRate     `json:"tax_rate"`
+	Tax     money.Money `json:"tax"`
+
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -24,9 +40,34 @@ func (o *OrderItem) Validate() error {
 		return service.Errorf(service.INVALID_ERROR, "quantity is required")
 	}
 
+	return o.TaxRate.Validate()
+}
+
+// UpdateLineTotal sets the line total and the tax of the item from its unit
+// price, before any order discount; UpdateTotals reduces the tax by the
+// discount of the order. It fails with INVALID_ERROR if the line total does
+// not fit in an amount.
+func (o *OrderItem) UpdateLineTotal() error {
+	lineTotal, err := o.lineTotal()
+	if err != nil {
+		return err
+	}
+
+	o.LineTotal = lineTotal
+	o.Tax = o.TaxRate.Tax(lineTotal)
+
 	return nil
 }
 
+func (o *OrderItem) lineTotal() (money.Money, error) {
+	lineTotal, err := o.UnitPrice.Mul(o.Quantity)
+	if err != nil {
+		return money.Money{}, service.Errorf(service.INVALID_ERROR, "invalid line total of product %q: %v", o.ProductId, err)
+	}
+
+	return lineTotal, nil
+}
+
 type OrderItemUpdate struct {
 	Quantity *uint `json:"quantity"`
 }
@@ -36,8 +77,30 @@ type Order struct {
 	CustomerId  string          `json:"customer_id"`
 	Items       []*OrderItem    `json:"items"`
 	OrderStatus pkg.OrderStatus `json:"order_status"`
-	CreatedAt   string          `json:"created_at"`
-	UpdatedAt   string          `json:"updated_at"`
+	Version     uint            `json:"version"`
+
+	// Subtotal is the sum of the line totals of the items and Tax the sum of
+	// their taxes. Total is what the customer is charged: Subtotal plus the
+	// tax of items with tax-exclusive prices, minus Discount. The discount is
+	// spread over the items it applies to, and each item is taxed on what is
+	// left of its line total. Repositories keep them up to date with
+	// UpdateTotals. All amounts of an order are in the same currency.
+	Subtotal money.Money `json:"subtotal"`
+	Tax      money.Money `json:"tax"`
+	Discount money.Money `json:"discount"`
+	Total    money.Money `json:"total"`
+
+	// Promotion is the promotion applied to the order, if any. Discount is
+	// computed from its rule whenever the totals are updated.
+	Promotion *AppliedPromotion `json:"promotion"`
+
+	// StockReservation is the id of the stock reservation made for the items
+	// when the order was last checked out. It holds them while the order is
+	// processing or pending, see ProductRepository.ReserveStock.
+	StockReservation string `json:"stock_reservation"`
+
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
 }
 
 func (o *Order) Validate() error {
@@ -59,15 +122,449 @@ func (o *Order) Validate() error {
 	return nil
 }
 
+// AppliedPromotion is the copy of a promotion kept by the order it is applied
+// to, so that later changes to the promotion do not change the order.
+type AppliedPromotion struct {
+	PromotionId string        `json:"promotion_id"`
+	Code        string        `json:"code"`
+	Rule        PromotionRule `json:"rule"`
+}
+
+func (p *AppliedPromotion) Validate() error {
+	if p == nil {
+		return service.Errorf(service.INVALID_ERROR, "promotion is required")
+	}
+
+	if p.PromotionId == "" {
+		return service.Errorf(service.INVALID_ERROR, "promotion id is required")
+	}
+
+	return p.Rule.Validate()
+}
+
+// UpdateTotals recomputes the line totals and taxes of the items and the
+// totals of the order from the item prices, tax rates and the applied
+// promotion. Repositories call it whenever the items or the promotion change.
+// It fails with INVALID_ERROR if the items are priced in different currencies,
+// the promotion discounts another currency or the totals do not fit in an
+// amount, and leaves the order unchanged then.
+func (o *Order) UpdateTotals() error {
+	lineTotals := make([]money.Money, len(o.Items))
+	for i, item := range o.Items {
+		lineTotal, err := item.lineTotal()
+		if err != nil {
+			return err
+		}
+
+		lineTotals[i] = lineTotal
+	}
+
+	subtotal, err := money.Sum(lineTotals...)
+	if err != nil {
+		return service.Errorf(service.INVALID_ERROR, "invalid order subtotal: %v", err)
+	}
+
+	discount := o.Discount
+	discounted := func(*OrderItem) bool { return true }
+	if o.Promotion != nil {
+		discount, err = o.Promotion.Rule.Discount(o.Items, subtotal)
+		if err != nil {
+			return service.Errorf(service.INVALID_ERROR, "invalid order discount: %v", err)
+		}
+
+		discounted = o.Promotion.Rule.AppliesTo
+	}
+
+	// Each item is taxed on its line total less its share of the discount, so
+	// that the tax is that of what the customer pays.
+	shares := discountShares(o.Items, lineTotals, discounted, discount)
+
+	taxes := make([]money.Money, len(o.Items))
+	var exclusiveTaxes []money.Money
+	for i, item := range o.Items {
+		taxes[i] = item.TaxRate.Tax(money.New(lineTotals[i].Amount-shares[i], lineTotals[i].Currency))
+		if !item.TaxRate.Inclusive {
+			exclusiveTaxes = append(exclusiveTaxes, taxes[i])
+		}
+	}
+
+	tax, err := money.Sum(taxes...)
+	if err != nil {
+		return service.Errorf(service.INVALID_ERROR, "invalid order tax: %v", err)
+	}
+
+	exclusiveTax, err := money.Sum(exclusiveTaxes...)
+	if err != nil {
+		return service.Errorf(service.INVALID_ERROR, "invalid order tax: %v", err)
+	}
+
+	total, err := subtotal.Add(exclusiveTax)
+	if err == nil {
+		total, err = total.Sub(discount)
+	}
+	if err != nil {
+		return service.Errorf(service.INVALID_ERROR, "invalid order total: %v", err)
+	}
+
+	if total.IsNegative() {
+		total = money.Zero(total.Currency)
+	}
+
+	for i, item := range o.Items {
+		item.LineTotal, item.Tax = lineTotals[i], taxes[i]
+	}
+	o.Subtotal, o.Tax, o.Discount, o.Total = subtotal, tax, discount, total
+
+	// Give the amounts that are not set yet the currency of the order, so
+	// that repositories can store a single currency for all of them.
+	if o.Tax == (money.Money{}) {
+		o.Tax = money.Zero(total.Currency)
+	}
+	if o.Discount == (money.Money{}) {
+		o.Discount = money.Zero(total.Currency)
+	}
+
+	return nil
+}
+
+// discountShares spreads the discount over the line totals of the discounted
+// items in proportion to them, and returns the share of each item in minor
+// units. Shares are rounded down, and the units left over go to the items
+// with the largest remainders, so that the shares add up to the discount and
+// no share exceeds its line total.
+func discountShares(
+	items []*OrderItem, lineTotals []money.Money, discounted func(*OrderItem) bool, discount money.Money) []int64 {
+
+	shares := make([]int64, len(items))
+
+	var base int64
+	for i, item := range items {
+		if discounted(item) {
+			base += lineTotals[i].Amount
+		}
+	}
+
+	amount := min(discount.Amount, base)
+	if amount <= 0 {
+		return shares
+	}
+
+	type remainder struct {
+		i int
+		r *big.Int
+	}
+
+	// The products of amounts may not fit in an int64.
+	left := amount
+	remainders := make([]remainder, 0, len(items))
+	for i, item := range items {
+		if !discounted(item) {
+			continue
+		}
+
+		share := new(big.Int).Mul(big.NewInt(amount), big.NewInt(lineTotals[i].Amount))
+		share, r := share.QuoRem(share, big.NewInt(base), new(big.Int))
+
+		shares[i] = share.Int64()
+		left -= shares[i]
+		remainders = append(remainders, remainder{i: i, r: r})
+	}
+
+	sort.SliceStable(remainders, func(a, b int) bool { return remainders[a].r.Cmp(remainders[b].r) > 0 })
+	for k := 0; left > 0; k++ {
+		shares[remainders[k].i]++
+		left--
+	}
+
+	return shares
+}
+
+// PriceOrderItems sets the unit price of each item to the current price of its
+// product, and its tax rate to the current rate of the product's category. It
+// is called when items are added to an order, so that later price and rate
+// changes do not change what the customer is charged. Items are not taxed if
+// rates is nil.
+func PriceOrderItems(ctx context.Context, products ProductRepository, rates TaxRates, items []*OrderItem) error {
+	priced := make(map[string]*Product)
+
+	for _, item := range items {
+		product, ok := priced[item.ProductId]
+		if !ok {
+			if item.ProductId == "" {
+				return service.Errorf(service.INVALID_ERROR, "product_id is required")
+			}
+
+			var err error
+			product, err = products.GetProduct(ctx, item.ProductId)
+			if service.ErrorCode(err) == service.NOT_FOUND_ERROR {
+				return service.Errorf(service.NOT_FOUND_ERROR, "product %q not found", item.ProductId)
+			} else if err != nil {
+				return service.Errorf(service.INTERNAL_ERROR, "failed to get product: %v", err)
+			}
+
+			priced[item.ProductId] = product
+		}
+
+		item.UnitPrice = product.Price
+		item.TaxRate = TaxRate{Category: product.Category}
+		if rates != nil {
+			item.TaxRate = rates.TaxRate(product.Category)
+		}
+	}
+
+	return nil
+}
+
+// OrderStatusUpdate moves an order to a new status.
+type OrderStatusUpdate struct {
+	Status pkg.OrderStatus `json:"status"`
+
+	// Version, when set, must match the version of the stored order. Callers
+	// that read an order before deciding on its next status set it so that
+	// the update fails if someone else changed the order in the meantime.
+	Version *uint `json:"version"`
+
+	// Actor and Reason are recorded in the order's status history.
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
+
+	// StockReservation, when set, replaces the stock reservation of the order
+	// in the same write as its status.
+	StockReservation string `json:"stock_reservation"`
+}
+
+// OrderStatusChange is an entry in the status history of an order. Entries
+// are written together with the status update they record and are never
+// changed afterwards.
+type OrderStatusChange struct {
+	Id         string          `json:"id"`
+	OrderId    string          `json:"order_id"`
+	FromStatus pkg.OrderStatus `json:"from_status"`
+	ToStatus   pkg.OrderStatus `json:"to_status"`
+	Actor      string          `json:"actor"`
+	Reason     string          `json:"reason"`
+	Version    uint            `json:"version"` // order version after the change
+	CreatedAt  string          `json:"created_at"`
+}
+
+// ApplyStatusUpdate moves the order to the requested status and bumps its
+// version. Repositories call it while holding the stored order, so that the
+// check and the write happen atomically. The returned change is to be stored
+// in the same write; the repository sets its id and timestamp.
+func (o *Order) ApplyStatusUpdate(update *OrderStatusUpdate) (*OrderStatusChange, error) {
+	if !update.Status.IsValid() {
+		return nil, service.Errorf(service.INVALID_ERROR, "unknown order status %q", update.Status)
+	}
+
+	if update.Version != nil && *update.Version != o.Version {
+		return nil, service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"order has been modified: version is %d, not %d", o.Version, *update.Version)
+	}
+
+	if !o.OrderStatus.CanTransitionTo(update.Status) {
+		return nil, service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"order cannot move from %s to %s", o.OrderStatus, update.Status)
+	}
+
+	change := &OrderStatusChange{
+		OrderId:    o.Id,
+		FromStatus: o.OrderStatus,
+		ToStatus:   update.Status,
+		Actor:      update.Actor,
+		Reason:     update.Reason,
+		Version:    o.Version + 1,
+	}
+
+	o.OrderStatus = update.Status
+	o.Version++
+
+	if update.StockReservation != "" {
+		o.StockReservation = update.StockReservation
+	}
+
+	return change, nil
+}
+
+// PrepareChange fails with FAILED_PRECONDITION_ERROR unless the items or
+// promotion of the order, as named by what, can change, which they only can
+// while the order is new or failed. Otherwise it bumps the version of the
+// order, so that a status update pinned to the version that was read, e.g. by
+// a checkout that reserved the old items, fails. Repositories call it while
+// holding the stored order and store the version with the change.
+func (o *Order) PrepareChange(what string) error {
+	if o.OrderStatus != pkg.OrderStatusNew && o.OrderStatus != pkg.OrderStatusFailed {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"%s of a %s order cannot be changed", what, o.OrderStatus)
+	}
+
+	o.Version++
+
+	return nil
+}
+
+// CheckReplacedPromotion fails with CONFLICT_ERROR unless the promotion with
+// id replaces, or none if it is empty, is applied to the order. Repositories
+// call it while holding the stored order, before applying another promotion.
+func (o *Order) CheckReplacedPromotion(replaces string) error {
+	var applied string
+	if o.Promotion != nil {
+		applied = o.Promotion.PromotionId
+	}
+
+	if applied != replaces {
+		return service.Errorf(service.CONFLICT_ERROR, "the promotion of the order has changed")
+	}
+
+	return nil
+}
+
+// ServiceOrder converts the order to the order the services return.
+func (o *Order) ServiceOrder() *service.Order {
+	items := make([]*service.OrderItem, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = &service.OrderItem{
+			Id:           item.Id,
+			ProductId:    item.ProductId,
+			Quantity:     item.Quantity,
+			UnitPrice:    item.UnitPrice,
+			LineTotal:    item.LineTotal,
+			TaxCategory:  item.TaxRate.Category,
+			TaxRate:      item.TaxRate.Rate,
+			TaxExempt:    item.TaxRate.Exempt,
+			TaxInclusive: item.TaxRate.Inclusive,
+			Tax:          item.Tax,
+			CreatedAt:    item.CreatedAt,
+			UpdatedAt:    item.UpdatedAt,
+		}
+	}
+
+	var promotionCode string
+	if o.Promotion != nil {
+		promotionCode = o.Promotion.Code
+	}
+
+	return &service.Order{
+		Id:          o.Id,
+		CustomerId:  o.CustomerId,
+		Items:       items,
+		OrderStatus: o.OrderStatus,
+		Subtotal:    o.Subtotal,
+		Tax:         o.Tax,
+		Discount:    o.Discount,
+		Total:       o.Total,
+		CreatedAt:   o.CreatedAt,
+		UpdatedAt:   o.UpdatedAt,
+
+		PromotionCode: promotionCode,
+	}
+}
+
+// OrderSortFields are the fields ListOrders can order by.
+var OrderSortFields = []string{"created_at", "updated_at"}
+
+// SortValue returns the value of a field in OrderSortFields in a form that
+// sorts like the field itself.
+func (o *Order) SortValue(field string) string {
+	switch field {
+	case "updated_at":
+		return sortableTime(o.UpdatedAt)
+	default:
+		return sortableTime(o.CreatedAt)
+	}
+}
+
+// OrderFilter restricts ListOrders to the matching orders. Unset fields match
+// every order.
+type OrderFilter struct {
+	CustomerId string
+	Status     pkg.OrderStatus
+
+	// CreatedFrom is inclusive, CreatedUntil is exclusive.
+	CreatedFrom  time.Time
+	CreatedUntil time.Time
+}
+
+func (f *OrderFilter) Validate() error {
+	if f.Status != "" && !f.Status.IsValid() {
+		return service.Errorf(service.INVALID_ERROR, "unknown order status %q", f.Status)
+	}
+
+	if !f.CreatedFrom.IsZero() && !f.CreatedUntil.IsZero() && !f.CreatedFrom.Before(f.CreatedUntil) {
+		return service.Errorf(service.INVALID_ERROR, "created_from must be before created_until")
+	}
+
+	return nil
+}
+
+// Match reports whether the order passes the filter.
+func (f *OrderFilter) Match(o *Order) bool {
+	if f.CustomerId != "" && o.CustomerId != f.CustomerId {
+		return false
+	}
+
+	if f.Status != "" && o.OrderStatus != f.Status {
+		return false
+	}
+
+	if f.CreatedFrom.IsZero() && f.CreatedUntil.IsZero() {
+		return true
+	}
+
+	createdAt, err := time.Parse(time.RFC3339, o.CreatedAt)
+	if err != nil {
+		return false
+	}
+
+	if !f.CreatedFrom.IsZero() && createdAt.Before(f.CreatedFrom) {
+		return false
+	}
+
+	if !f.CreatedUntil.IsZero() && !createdAt.Before(f.CreatedUntil) {
+		return false
+	}
+
+	return true
+}
+
+// CustomerOrders returns the ListOrders arguments that implement
+// ListOrdersByCustomer.
+func CustomerOrders(customerId string, opts *ListOptions) (*OrderFilter, *ListOptions, error) {
+	if customerId == "" {
+		return nil, nil, service.Errorf(service.INVALID_ERROR, "customer id is required")
+	}
+
+	return &OrderFilter{CustomerId: customerId}, opts.withDefaultOrder("created_at desc"), nil
+}
+
 type OrderRepository interface {
 
 	// Order CRUD
 	CreateOrder(ctx context.Context, order *Order) (*Order, error)
 	GetOrder(ctx context.Context, id string) (*Order, error)
-	ListOrders(ctx context.Context) ([]*Order, error)
-	UpdateOrderStatus(ctx context.Context, orderId string, status pkg.OrderStatus) (*Order, error)
+
+	// ListOrders returns a page of the orders that match the filter, together
+	// with the token of the next page, or an empty token if this is the last
+	// page. A nil filter matches every order.
+	ListOrders(ctx context.Context, filter *OrderFilter, opts *ListOptions) ([]*Order, string, error)
+
+	// ListOrdersByCustomer returns a page of the customer's orders, newest
+	// first unless opts orders them otherwise.
+	ListOrdersByCustomer(ctx context.Context, customerId string, opts *ListOptions) ([]*Order, string, error)
+
+	UpdateOrderStatus(ctx context.Context, orderId string, update *OrderStatusUpdate) (*Order, error)
 	DeleteOrder(ctx context.Context, id string) error
 
+	// GetOrderHistory returns the status changes of the order, oldest first.
+	GetOrderHistory(ctx context.Context, orderId string) ([]*OrderStatusChange, error)
+
+	// ApplyOrderPromotion applies the promotion to the order in place of the
+	// promotion with id replaces, or of none if it is empty, and updates its
+	// totals. It fails with CONFLICT_ERROR if the order has another promotion,
+	// e.g. because a concurrent call applied one first. The caller is
+	// responsible for redeeming the promotion.
+	ApplyOrderPromotion(
+		ctx context.Context, orderId string, promotion *AppliedPromotion, replaces string) (*Order, error)
+
 	// OrderItem CRUD
 	CreateOrderItem(ctx context.Context, orderId string, orderItem *OrderItem) (*OrderItem, error)
 	GetOrderItem(ctx context.Context, orderId string, orderItemId string) (*OrderItem, error)
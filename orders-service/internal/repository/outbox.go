@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+// OrderAggregate is the aggregate type of order events.
+const OrderAggregate = "order"
+
+// OrderEvent is the payload of the events recorded about an order.
+type OrderEvent struct {
+	Order *Order `json:"order"`
+
+	// Change is set for the events of status changes.
+	Change *OrderStatusChange `json:"change,omitempty"`
+}
+
+// NewOrderCreatedEvent returns the event recorded when the order is created.
+func NewOrderCreatedEvent(order *Order) (*outbox.Event, error) {
+	return newOrderEvent(pkg.EventOrderCreated, &OrderEvent{Order: order})
+}
+
+// NewOrderStatusEvent returns the event recorded when the order changes
+// status, e.g. OrderPaid.
+func NewOrderStatusEvent(order *Order, change *OrderStatusChange) (*outbox.Event, error) {
+	return newOrderEvent(change.ToStatus.EventType(), &OrderEvent{Order: order, Change: change})
+}
+
+func newOrderEvent(eventType string, payload *OrderEvent) (*outbox.Event, error) {
+	event, err := outbox.NewEvent(eventType, OrderAggregate, payload.Order.Id, payload)
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "%v", err)
+	}
+
+	return event, nil
+}
+
+var _ outbox.Store = OutboxRepository(nil)
+
+// OutboxRepository reads the events that the other repositories record in the
+// same write as the changes that cause them. It is drained by an outbox.Relay.
+type OutboxRepository interface {
+	// ListOutboxEvents returns up to limit events, oldest first.
+	ListOutboxEvents(ctx context.Context, limit int) ([]*outbox.Event, error)
+
+	// DeleteOutboxEvents removes published events. Unknown ids are ignored.
+	DeleteOutboxEvents(ctx context.Context, ids []string) error
+}
@@ -2,31 +2,309 @@ package repository
 
 import (
 	"context"
+	"sort"
+	"strings"
 
 	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 )
 
 type Product struct {
 	Id          string
 	Name        string
 	Description string
-	Price       uint
-	CreatedAt   string
-	UpdatedAt   string
+	Price       money.Money
+
+	// Category decides the tax rate of the product, see TaxRates. Products
+	// without a category are taxed at the default rate.
+	Category string
+
+	// Stock is the number of units that can still be sold. Reserved is the
+	// number of units held by orders that are being paid for; they leave the
+	// inventory when the order is paid and return to Stock otherwise.
+	//
+	// Stock is nil for products whose stock is not tracked, such as products
+	// created before stock was: they can always be ordered and reserve
+	// nothing until their stock is set.
+	Stock    *uint
+	Reserved uint
+
+	CreatedAt string
+	UpdatedAt string
 }
 
+// ProductUpdate changes a product. Setting Stock starts tracking the stock of
+// untracked products; orders placed before then hold none of it. Reserved is
+// only changed through the stock methods of ProductRepository.
 type ProductUpdate struct {
 	Name        *string
 	Description *string
-	Price       *uint
+	Price       *money.Money
+	Category    *string
+	Stock       *uint
+}
+
+// StockItem is a quantity of a product to reserve, release or commit.
+type StockItem struct {
+	ProductId string
+	Quantity  uint
+}
+
+// OrderStockItems returns the stock held by the items of an order.
+func OrderStockItems(items []*OrderItem) []StockItem {
+	stock := make([]StockItem, 0, len(items))
+	for _, item := range items {
+		stock = append(stock, StockItem{ProductId: item.ProductId, Quantity: item.Quantity})
+	}
+
+	return stock
+}
+
+// MergeStockItems validates items and returns them with one entry per product,
+// sorted by product id. Repositories adjust products in this order so that
+// concurrent adjustments lock them in the same order.
+func MergeStockItems(items []StockItem) ([]StockItem, error) {
+	if len(items) == 0 {
+		return nil, service.Errorf(service.INVALID_ERROR, "stock items are required")
+	}
+
+	quantities := make(map[string]uint)
+	for _, item := range items {
+		if item.ProductId == "" {
+			return nil, service.Errorf(service.INVALID_ERROR, "product_id is required")
+		}
+
+		if item.Quantity == 0 {
+			return nil, service.Errorf(service.INVALID_ERROR, "quantity is required")
+		}
+
+		quantities[item.ProductId] += item.Quantity
+	}
+
+	merged := make([]StockItem, 0, len(quantities))
+	for id, quantity := range quantities {
+		merged = append(merged, StockItem{ProductId: id, Quantity: quantity})
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].ProductId < merged[j].ProductId })
+
+	return merged, nil
+}
+
+// Reserve moves quantity units from the product's stock to its reserved stock.
+// Repositories call it while holding the stored product. Untracked products
+// reserve nothing.
+func (p *Product) Reserve(quantity uint) error {
+	if p.Stock == nil {
+		return nil
+	}
+
+	if *p.Stock < quantity {
+		return service.Errorf(service.OUT_OF_STOCK_ERROR,
+			"product %q is out of stock: %d requested, %d available", p.Id, quantity, *p.Stock)
+	}
+
+	// The stock is replaced rather than changed in place, as copies of the
+	// product may share it.
+	p.Stock = pkg.UintPtr(*p.Stock - quantity)
+	p.Reserved += quantity
+
+	return nil
+}
+
+// Release returns quantity reserved units to the product's stock. Untracked
+// products have nothing to release.
+func (p *Product) Release(quantity uint) error {
+	if p.Stock == nil {
+		return nil
+	}
+
+	if p.Reserved < quantity {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"product %q has %d reserved units, cannot release %d", p.Id, p.Reserved, quantity)
+	}
+
+	p.Reserved -= quantity
+	p.Stock = pkg.UintPtr(*p.Stock + quantity)
+
+	return nil
+}
+
+// Commit removes quantity reserved units from the inventory once they are sold.
+// Untracked products have nothing to commit.
+func (p *Product) Commit(quantity uint) error {
+	if p.Stock == nil {
+		return nil
+	}
+
+	if p.Reserved < quantity {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"product %q has %d reserved units, cannot commit %d", p.Id, p.Reserved, quantity)
+	}
+
+	p.Reserved -= quantity
+
+	return nil
+}
+
+// StockAdjustment is one of Product.Reserve, Product.Release and
+// Product.Commit.
+type StockAdjustment func(p *Product, quantity uint) error
+
+// StockProductIds returns the ids of the products of the items, sorted and
+// without duplicates. Repositories adjust products in this order so that
+// concurrent adjustments lock them in the same order.
+func StockProductIds(items ...[]StockItem) []string {
+	seen := make(map[string]bool)
+
+	var ids []string
+	for _, list := range items {
+		for _, item := range list {
+			if !seen[item.ProductId] {
+				seen[item.ProductId] = true
+				ids = append(ids, item.ProductId)
+			}
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// ReplaceStockReservation returns the units of the replaced reservation to
+// the stock of products and reserves the items, which must be merged, in its
+// place. It returns what the new reservation holds: the items of products
+// whose stock is tracked. products holds the stored products by id and is
+// changed in place; products of the replaced reservation that were deleted
+// since have nothing to return.
+func ReplaceStockReservation(products map[string]*Product, replaced, items []StockItem) ([]StockItem, error) {
+	if err := SettleStockReservation(products, replaced, (*Product).Release); err != nil {
+		return nil, err
+	}
+
+	reserved := make([]StockItem, 0, len(items))
+	for _, item := range items {
+		product, ok := products[item.ProductId]
+		if !ok {
+			return nil, service.Errorf(service.NOT_FOUND_ERROR, "product %q not found", item.ProductId)
+		}
+
+		if err := product.Reserve(item.Quantity); err != nil {
+			return nil, err
+		}
+
+		if product.Stock != nil {
+			reserved = append(reserved, item)
+		}
+	}
+
+	return reserved, nil
+}
+
+// SettleStockReservation releases or commits the items of a reservation with
+// adjust. products holds the stored products by id and is changed in place;
+// products that were deleted since the reservation have nothing to settle.
+func SettleStockReservation(products map[string]*Product, items []StockItem, adjust StockAdjustment) error {
+	for _, item := range items {
+		product, ok := products[item.ProductId]
+		if !ok {
+			continue
+		}
+
+		if err := adjust(product, item.Quantity); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProductSortFields are the fields ListProducts can order by.
+var ProductSortFields = []string{"created_at", "name", "price"}
+
+// SortValue returns the value of a field in ProductSortFields in a form that
+// sorts like the field itself.
+func (p *Product) SortValue(field string) string {
+	switch field {
+	case "name":
+		return p.Name
+	case "price":
+		// Validate keeps prices from being negative.
+		return sortableUint(uint(p.Price.Amount))
+	default:
+		return sortableTime(p.CreatedAt)
+	}
+}
+
+// ProductFilter restricts ListProducts to the matching products. Unset fields
+// match every product. MinPrice and MaxPrice are in minor units and are
+// usually combined with Currency.
+type ProductFilter struct {
+	NamePrefix string
+	Currency   string
+	MinPrice   *int64
+	MaxPrice   *int64
+}
+
+func (f *ProductFilter) Validate() error {
+	if f.Currency != "" && !money.IsSupported(f.Currency) {
+		return service.Errorf(service.INVALID_ERROR, "currency %q is not supported", f.Currency)
+	}
+
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return service.Errorf(service.INVALID_ERROR, "min_price must not be greater than max_price")
+	}
+
+	return nil
+}
+
+// Match reports whether the product passes the filter.
+func (f *ProductFilter) Match(p *Product) bool {
+	if !strings.HasPrefix(p.Name, f.NamePrefix) {
+		return false
+	}
+
+	if f.Currency != "" && p.Price.Currency != f.Currency {
+		return false
+	}
+
+	if f.MinPrice != nil && p.Price.Amount < *f.MinPrice {
+		return false
+	}
+
+	if f.MaxPrice != nil && p.Price.Amount > *f.MaxPrice {
+		return false
+	}
+
+	return true
 }
 
 type ProductRepository interface {
 	CreateProduct(ctx context.Context, product *Product) (*Product, error)
 	GetProduct(ctx context.Context, id string) (*Product, error)
-	ListProducts(ctx context.Context) ([]*Product, error)
+
+	// ListProducts returns a page of the products that match the filter,
+	// together with the token of the next page, or an empty token if this is
+	// the last page. A nil filter matches every product.
+	ListProducts(ctx context.Context, filter *ProductFilter, opts *ListOptions) ([]*Product, string, error)
 	UpdateProduct(ctx context.Context, id string, update *ProductUpdate) (*Product, error)
 	DeleteProduct(ctx context.Context, id string) error
+
+	// ReserveStock takes the items out of stock for the reservation with the
+	// given id and records what it took, which leaves out products whose
+	// stock is not tracked. Either every product is reserved or, if one of
+	// them is missing or short of units, none is. Reserving under the id of
+	// an existing reservation replaces it, so that a reservation whose outcome
+	// was lost can be made again without holding the units twice.
+	ReserveStock(ctx context.Context, reservationId string, items []StockItem) error
+
+	// ReleaseStock and CommitStock return the units of the reservation to
+	// stock or remove them from the inventory, and delete the reservation.
+	// Unknown reservations hold nothing, so both are safe to repeat.
+	ReleaseStock(ctx context.Context, reservationId string) error
+	CommitStock(ctx context.Context, reservationId string) error
 }
 
 func (p *Product) Validate() error {
@@ -34,5 +312,13 @@ func (p *Product) Validate() error {
 		return service.Errorf(service.INVALID_ERROR, "name is required")
 	}
 
+	if !money.IsSupported(p.Price.Currency) {
+		return service.Errorf(service.INVALID_ERROR, "price currency %q is not supported", p.Price.Currency)
+	}
+
+	if p.Price.IsNegative() {
+		return service.Errorf(service.INVALID_ERROR, "price must not be negative")
+	}
+
 	return nil
 }
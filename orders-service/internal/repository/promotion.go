@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+type PromotionType string
+
+const (
+	// PromotionTypePercentage takes a percentage off the order subtotal.
+	PromotionTypePercentage PromotionType = "percentage"
+
+	// PromotionTypeFixedAmount takes a fixed amount off the order subtotal.
+	PromotionTypeFixedAmount PromotionType = "fixed_amount"
+
+	// PromotionTypeBuyXGetY makes GetQuantity units of a product free for
+	// every BuyQuantity units of it that are paid for.
+	PromotionTypeBuyXGetY PromotionType = "buy_x_get_y"
+
+	// PromotionTypeFreeItem makes GetQuantity units of a product free.
+	PromotionTypeFreeItem PromotionType = "free_item"
+)
+
+// PromotionTypes lists every known promotion type.
+var PromotionTypes = []PromotionType{
+	PromotionTypePercentage, PromotionTypeFixedAmount, PromotionTypeBuyXGetY, PromotionTypeFreeItem,
+}
+
+// IsValid reports whether t is a known promotion type.
+func (t PromotionType) IsValid() bool {
+	for _, known := range PromotionTypes {
+		if t == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PromotionRule is how a promotion discounts an order. Only the fields used
+// by its type are set.
+type PromotionRule struct {
+	Type PromotionType `json:"type"`
+
+	// Percentage is the share of the subtotal taken off, from 1 to 100.
+	Percentage uint `json:"percentage,omitempty"`
+
+	// AmountOff is taken off the subtotal of orders in the same currency.
+	AmountOff money.Money `json:"amount_off"`
+
+	// ProductId is the product that buy X get Y and free item promotions make
+	// units of free. The free units must be in the order to be discounted.
+	ProductId   string `json:"product_id,omitempty"`
+	BuyQuantity uint   `json:"buy_quantity,omitempty"`
+	GetQuantity uint   `json:"get_quantity,omitempty"`
+}
+
+func (r *PromotionRule) Validate() error {
+	switch r.Type {
+	case PromotionTypePercentage:
+		if r.Percentage == 0 || r.Percentage > 100 {
+			return service.Errorf(service.INVALID_ERROR, "percentage must be between 1 and 100")
+		}
+
+	case PromotionTypeFixedAmount:
+		if !money.IsSupported(r.AmountOff.Currency) {
+			return service.Errorf(service.INVALID_ERROR, "amount off currency %q is not supported", r.AmountOff.Currency)
+		}
+
+		if r.AmountOff.Amount <= 0 {
+			return service.Errorf(service.INVALID_ERROR, "amount off must be positive")
+		}
+
+	case PromotionTypeBuyXGetY, PromotionTypeFreeItem:
+		if r.ProductId == "" {
+			return service.Errorf(service.INVALID_ERROR, "product_id is required")
+		}
+
+		if r.Type == PromotionTypeBuyXGetY && r.BuyQuantity == 0 {
+			return service.Errorf(service.INVALID_ERROR, "buy quantity is required")
+		}
+
+		if r.GetQuantity == 0 {
+			return service.Errorf(service.INVALID_ERROR, "get quantity is required")
+		}
+
+	default:
+		return service.Errorf(service.INVALID_ERROR, "unknown promotion type %q", r.Type)
+	}
+
+	return nil
+}
+
+// Discount returns the discount the rule gives on an order with the given
+// items and subtotal. The discount never exceeds the subtotal.
+func (r *PromotionRule) Discount(items []*OrderItem, subtotal money.Money) (money.Money, error) {
+	var (
+		discount money.Money
+		err      error
+	)
+
+	switch r.Type {
+	case PromotionTypePercentage:
+		// Split the amount so that multiplying it cannot overflow; the
+		// discount is rounded down to the unit the currency is charged in,
+		// e.g. whole shillings, so that it leaves a total that can be paid.
+		percentage := int64(r.Percentage)
+		unit := money.ChargeableUnit(subtotal.Currency)
+		base := 100 * unit
+		discount = money.New(
+			(subtotal.Amount/base*percentage+subtotal.Amount%base*percentage/base)*unit, subtotal.Currency)
+
+	case PromotionTypeFixedAmount:
+		if subtotal.IsZero() {
+			return money.Zero(subtotal.Currency), nil
+		}
+
+		discount = r.AmountOff
+		if subtotal.Currency != discount.Currency {
+			return money.Money{}, money.ErrCurrencyMismatch
+		}
+
+	case PromotionTypeBuyXGetY, PromotionTypeFreeItem:
+		var quantity uint
+		for _, item := range items {
+			if item.ProductId == r.ProductId {
+				quantity += item.Quantity
+			}
+		}
+
+		free := r.GetQuantity
+		if r.Type == PromotionTypeBuyXGetY {
+			free = quantity / (r.BuyQuantity + r.GetQuantity) * r.GetQuantity
+		}
+
+		discount, err = freeUnitsPrice(items, r.ProductId, free)
+		if err != nil {
+			return money.Money{}, err
+		}
+
+	default:
+		return money.Money{}, service.Errorf(service.INVALID_ERROR, "unknown promotion type %q", r.Type)
+	}
+
+	if discount.IsZero() {
+		return money.Zero(subtotal.Currency), nil
+	} else if discount.Amount > subtotal.Amount {
+		discount.Amount = subtotal.Amount
+	}
+
+	return discount, nil
+}
+
+// AppliesTo reports whether the rule discounts the item: every item for
+// percentage and fixed amount promotions, and the items of the free product
+// otherwise.
+func (r *PromotionRule) AppliesTo(item *OrderItem) bool {
+	switch r.Type {
+	case PromotionTypeBuyXGetY, PromotionTypeFreeItem:
+		return item.ProductId == r.ProductId
+	default:
+		return true
+	}
+}
+
+// freeUnitsPrice returns the price of up to free units of the product, taken
+// from the items in order.
+func freeUnitsPrice(items []*OrderItem, productId string, free uint) (money.Money, error) {
+	var price money.Money
+
+	for _, item := range items {
+		if free == 0 {
+			break
+		}
+
+		if item.ProductId != productId {
+			continue
+		}
+
+		units := min(item.Quantity, free)
+		free -= units
+
+		unitsPrice, err := item.UnitPrice.Mul(units)
+		if err == nil {
+			price, err = price.Add(unitsPrice)
+		}
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	return price, nil
+}
+
+type Promotion struct {
+	Id string `json:"id"`
+
+	// Code is what customers enter to apply the promotion. Codes are unique.
+	Code        string        `json:"code"`
+	Description string        `json:"description"`
+	Rule        PromotionRule `json:"rule"`
+
+	// StartsAt and EndsAt are RFC3339 timestamps that limit when the promotion
+	// can be applied. Either may be empty for an open-ended promotion.
+	StartsAt string `json:"starts_at"`
+	EndsAt   string `json:"ends_at"`
+
+	// UsageLimit caps the number of orders the promotion can be applied to,
+	// PerCustomerLimit the number of orders of a single customer. Zero means
+	// no limit. Redemptions is the number of orders it is applied to.
+	UsageLimit       uint `json:"usage_limit"`
+	PerCustomerLimit uint `json:"per_customer_limit"`
+	Redemptions      uint `json:"redemptions"`
+
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func (p *Promotion) Validate() error {
+	if p.Code == "" {
+		return service.Errorf(service.INVALID_ERROR, "code is required")
+	}
+
+	if err := p.Rule.Validate(); err != nil {
+		return err
+	}
+
+	startsAt, err := parseOptionalTime("starts_at", p.StartsAt)
+	if err != nil {
+		return err
+	}
+
+	endsAt, err := parseOptionalTime("ends_at", p.EndsAt)
+	if err != nil {
+		return err
+	}
+
+	if !startsAt.IsZero() && !endsAt.IsZero() && !startsAt.Before(endsAt) {
+		return service.Errorf(service.INVALID_ERROR, "starts_at must be before ends_at")
+	}
+
+	return nil
+}
+
+// CheckActive fails with FAILED_PRECONDITION_ERROR unless the promotion can be
+// applied at the given time.
+func (p *Promotion) CheckActive(at time.Time) error {
+	if startsAt, err := parseOptionalTime("starts_at", p.StartsAt); err != nil {
+		return err
+	} else if !startsAt.IsZero() && at.Before(startsAt) {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR, "promotion %q has not started", p.Code)
+	}
+
+	if endsAt, err := parseOptionalTime("ends_at", p.EndsAt); err != nil {
+		return err
+	} else if !endsAt.IsZero() && !at.Before(endsAt) {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR, "promotion %q has ended", p.Code)
+	}
+
+	return nil
+}
+
+func parseOptionalTime(field string, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, service.Errorf(service.INVALID_ERROR, "%s must be an RFC3339 timestamp", field)
+	}
+
+	return t, nil
+}
+
+// PromotionUpdate changes the terms of a promotion. Its code and rule cannot
+// change, as orders keep a copy of them.
+type PromotionUpdate struct {
+	Description      *string
+	StartsAt         *string
+	EndsAt           *string
+	UsageLimit       *uint
+	PerCustomerLimit *uint
+}
+
+// PromotionSortFields are the fields ListPromotions can order by.
+var PromotionSortFields = []string{"created_at", "code"}
+
+// SortValue returns the value of a field in PromotionSortFields in a form that
+// sorts like the field itself.
+func (p *Promotion) SortValue(field string) string {
+	switch field {
+	case "code":
+		return p.Code
+	default:
+		return sortableTime(p.CreatedAt)
+	}
+}
+
+// PromotionRedemption records that a promotion is applied to an order.
+type PromotionRedemption struct {
+	PromotionId string `json:"promotion_id"`
+	OrderId     string `json:"order_id"`
+	CustomerId  string `json:"customer_id"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func (r *PromotionRedemption) Validate() error {
+	if r.PromotionId == "" {
+		return service.Errorf(service.INVALID_ERROR, "promotion id is required")
+	}
+
+	if r.OrderId == "" {
+		return service.Errorf(service.INVALID_ERROR, "order id is required")
+	}
+
+	if r.CustomerId == "" {
+		return service.Errorf(service.INVALID_ERROR, "customer id is required")
+	}
+
+	return nil
+}
+
+// CheckRedemption fails with FAILED_PRECONDITION_ERROR if the promotion cannot
+// be redeemed once more by a customer who has redeemed it customerRedemptions
+// times already. Repositories call it while holding the stored promotion.
+func (p *Promotion) CheckRedemption(customerRedemptions uint) error {
+	if p.UsageLimit != 0 && p.Redemptions >= p.UsageLimit {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR, "promotion %q has been used up", p.Code)
+	}
+
+	if p.PerCustomerLimit != 0 && customerRedemptions >= p.PerCustomerLimit {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"promotion %q has been used the maximum number of times by the customer", p.Code)
+	}
+
+	return nil
+}
+
+type PromotionRepository interface {
+	CreatePromotion(ctx context.Context, promotion *Promotion) (*Promotion, error)
+	GetPromotion(ctx context.Context, id string) (*Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*Promotion, error)
+
+	// ListPromotions returns a page of promotions together with the token of
+	// the next page, or an empty token if this is the last page.
+	ListPromotions(ctx context.Context, opts *ListOptions) ([]*Promotion, string, error)
+	UpdatePromotion(ctx context.Context, id string, update *PromotionUpdate) (*Promotion, error)
+	DeletePromotion(ctx context.Context, id string) error
+
+	// RedeemPromotion records that the promotion is applied to an order. It
+	// fails with FAILED_PRECONDITION_ERROR if the promotion has reached its
+	// usage limit or the customer's cap. Redeeming a promotion again for the
+	// same order does nothing.
+	RedeemPromotion(ctx context.Context, redemption *PromotionRedemption) error
+
+	// ReleasePromotion removes the redemption of the promotion by the order,
+	// e.g. when another promotion replaces it or the order fails. Releasing a
+	// promotion the order has not redeemed does nothing.
+	ReleasePromotion(ctx context.Context, promotionId string, orderId string) error
+}
@@ -0,0 +1,202 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+)
+
+// TestCheckoutSagaRepository runs the checkout saga repository conformance
+// suite against repositories returned by newRepository.
+func TestCheckoutSagaRepository(t *testing.T, newRepository func(t *testing.T) repository.CheckoutSagaRepository) {
+	t.Run("CreateCheckoutSaga", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		// The saga always starts at the first step, whatever it is given.
+		start := time.Now()
+		saga, err := r.CreateCheckoutSaga(ctx, &repository.CheckoutSaga{
+			OrderId: uniqueName("order"),
+			Step:    repository.CheckoutStepConfirm,
+			Status:  repository.CheckoutSagaStatusCompleted,
+			Error:   "error",
+		})
+		wantCode(t, "CreateCheckoutSaga()", err, "")
+
+		if saga.Id == "" {
+			t.Errorf("CreateCheckoutSaga() did not set an id")
+		}
+		if saga.Step != repository.CheckoutStepReserve || saga.Status != repository.CheckoutSagaStatusRunning ||
+			saga.Error != "" || saga.Version != 1 {
+			t.Errorf("CreateCheckoutSaga() = %+v, want a running saga at the reserve step", saga)
+		}
+		wantTimestamp(t, "CreatedAt", saga.CreatedAt, start)
+		wantTimestamp(t, "UpdatedAt", saga.UpdatedAt, start)
+
+		got, err := r.GetCheckoutSaga(ctx, saga.Id)
+		wantCode(t, "GetCheckoutSaga()", err, "")
+		if *got != *saga {
+			t.Errorf("GetCheckoutSaga() = %+v, want %+v", got, saga)
+		}
+	})
+
+	t.Run("CreateCheckoutSaga_Invalid", func(t *testing.T) {
+		_, err := newRepository(t).CreateCheckoutSaga(context.Background(), &repository.CheckoutSaga{})
+		wantCode(t, "CreateCheckoutSaga() without order id", err, service.INVALID_ERROR)
+	})
+
+	t.Run("GetCheckoutSaga_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetCheckoutSaga(context.Background(), "")
+		wantCode(t, "GetCheckoutSaga() without id", err, service.INVALID_ERROR)
+
+		_, err = r.GetCheckoutSaga(context.Background(), "non-existent-id")
+		wantCode(t, "GetCheckoutSaga()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("UpdateCheckoutSaga", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		saga, err := r.CreateCheckoutSaga(ctx, &repository.CheckoutSaga{OrderId: uniqueName("order")})
+		wantCode(t, "CreateCheckoutSaga()", err, "")
+
+		updated, err := r.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+			Step:    repository.CheckoutStepCharge,
+			Status:  repository.CheckoutSagaStatusCompensating,
+			Error:   "payment failed",
+			Version: saga.Version,
+		})
+		wantCode(t, "UpdateCheckoutSaga()", err, "")
+
+		if updated.Step != repository.CheckoutStepCharge || updated.Status != repository.CheckoutSagaStatusCompensating ||
+			updated.Error != "payment failed" || updated.Version != saga.Version+1 {
+			t.Errorf("UpdateCheckoutSaga() = %+v, want a compensating saga at the charge step", updated)
+		}
+		if updated.OrderId != saga.OrderId || updated.CreatedAt != saga.CreatedAt {
+			t.Errorf("UpdateCheckoutSaga() = %+v, want the order and creation time of %+v", updated, saga)
+		}
+
+		got, err := r.GetCheckoutSaga(ctx, saga.Id)
+		wantCode(t, "GetCheckoutSaga()", err, "")
+		if *got != *updated {
+			t.Errorf("GetCheckoutSaga() = %+v, want %+v", got, updated)
+		}
+
+		// The update is pinned to the version it was read at.
+		_, err = r.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+			Step:    repository.CheckoutStepCharge,
+			Status:  repository.CheckoutSagaStatusCompensated,
+			Version: saga.Version,
+		})
+		wantCode(t, "UpdateCheckoutSaga() of an old version", err, service.FAILED_PRECONDITION_ERROR)
+
+		finished, err := r.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+			Step:    repository.CheckoutStepCharge,
+			Status:  repository.CheckoutSagaStatusCompensated,
+			Error:   updated.Error,
+			Version: updated.Version,
+		})
+		wantCode(t, "UpdateCheckoutSaga()", err, "")
+
+		// Finished sagas are never changed again.
+		_, err = r.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+			Step:    repository.CheckoutStepReserve,
+			Status:  repository.CheckoutSagaStatusRunning,
+			Version: finished.Version,
+		})
+		wantCode(t, "UpdateCheckoutSaga() of a finished saga", err, service.FAILED_PRECONDITION_ERROR)
+	})
+
+	t.Run("UpdateCheckoutSaga_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		saga, err := r.CreateCheckoutSaga(ctx, &repository.CheckoutSaga{OrderId: uniqueName("order")})
+		wantCode(t, "CreateCheckoutSaga()", err, "")
+
+		_, err = r.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+			Step:    "refund",
+			Status:  repository.CheckoutSagaStatusRunning,
+			Version: saga.Version,
+		})
+		wantCode(t, "UpdateCheckoutSaga() with an unknown step", err, service.INVALID_ERROR)
+
+		_, err = r.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+			Step:    repository.CheckoutStepCharge,
+			Status:  "paused",
+			Version: saga.Version,
+		})
+		wantCode(t, "UpdateCheckoutSaga() with an unknown status", err, service.INVALID_ERROR)
+
+		_, err = r.UpdateCheckoutSaga(ctx, "non-existent-id", &repository.CheckoutSagaUpdate{
+			Step:    repository.CheckoutStepCharge,
+			Status:  repository.CheckoutSagaStatusRunning,
+			Version: 1,
+		})
+		wantCode(t, "UpdateCheckoutSaga() of a missing saga", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("ListUnfinishedCheckoutSagas", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		// Sagas are ordered by creation time, which has second precision.
+		statuses := []repository.CheckoutSagaStatus{
+			repository.CheckoutSagaStatusRunning,
+			repository.CheckoutSagaStatusCompleted,
+			repository.CheckoutSagaStatusCompensating,
+			repository.CheckoutSagaStatusCompensated,
+		}
+		ids := make(map[string]repository.CheckoutSagaStatus)
+		var want []string
+		for i, status := range statuses {
+			if i > 0 {
+				time.Sleep(time.Second)
+			}
+
+			saga, err := r.CreateCheckoutSaga(ctx, &repository.CheckoutSaga{OrderId: uniqueName("order")})
+			wantCode(t, "CreateCheckoutSaga()", err, "")
+
+			if status != repository.CheckoutSagaStatusRunning {
+				_, err = r.UpdateCheckoutSaga(ctx, saga.Id, &repository.CheckoutSagaUpdate{
+					Step:    repository.CheckoutStepCharge,
+					Status:  status,
+					Version: saga.Version,
+				})
+				wantCode(t, "UpdateCheckoutSaga()", err, "")
+			}
+
+			ids[saga.Id] = status
+			if !status.Finished() {
+				want = append(want, saga.Id)
+			}
+		}
+
+		sagas, err := r.ListUnfinishedCheckoutSagas(ctx)
+		wantCode(t, "ListUnfinishedCheckoutSagas()", err, "")
+
+		// Other tests may share the database, so only the sagas created here
+		// are checked.
+		var got []string
+		for _, saga := range sagas {
+			if _, ok := ids[saga.Id]; ok {
+				got = append(got, saga.Id)
+			}
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("ListUnfinishedCheckoutSagas() returned %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("ListUnfinishedCheckoutSagas() returned %v, want %v", got, want)
+				break
+			}
+		}
+	})
+}
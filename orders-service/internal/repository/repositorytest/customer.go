@@ -0,0 +1,217 @@
+package repositorytest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+func newTestCustomer() *repository.Customer {
+	return &repository.Customer{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john.doe@example.com",
+		Phone:     "+254700000000",
+	}
+}
+
+// TestCustomerRepository runs the customer repository conformance suite
+// against repositories returned by newRepository.
+func TestCustomerRepository(t *testing.T, newRepository func(t *testing.T) repository.CustomerRepository) {
+	t.Run("CreateCustomer", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		c, err := r.CreateCustomer(ctx, newTestCustomer())
+		wantCode(t, "CreateCustomer()", err, "")
+
+		if c.Id == "" {
+			t.Errorf("CreateCustomer() id is empty")
+		}
+		wantTimestamp(t, "CreatedAt", c.CreatedAt, start)
+		if c.UpdatedAt != c.CreatedAt {
+			t.Errorf("CreateCustomer() UpdatedAt = %q, want %q", c.UpdatedAt, c.CreatedAt)
+		}
+
+		got, err := r.GetCustomer(ctx, c.Id)
+		wantCode(t, "GetCustomer()", err, "")
+		if !reflect.DeepEqual(got, c) {
+			t.Errorf("GetCustomer() = %+v, want %+v", got, c)
+		}
+	})
+
+	t.Run("CreateCustomer_Invalid", func(t *testing.T) {
+		for _, field := range []string{"FirstName", "LastName", "Email", "Phone"} {
+			c := newTestCustomer()
+			reflect.ValueOf(c).Elem().FieldByName(field).SetString("")
+
+			_, err := newRepository(t).CreateCustomer(context.Background(), c)
+			wantCode(t, "CreateCustomer() without "+field, err, service.INVALID_ERROR)
+		}
+	})
+
+	t.Run("GetCustomer_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetCustomer(context.Background(), "does-not-exist")
+		wantCode(t, "GetCustomer()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetCustomer(context.Background(), "")
+		wantCode(t, "GetCustomer()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListCustomers", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		c1, err := r.CreateCustomer(ctx, newTestCustomer())
+		wantCode(t, "CreateCustomer()", err, "")
+		c2, err := r.CreateCustomer(ctx, newTestCustomer())
+		wantCode(t, "CreateCustomer()", err, "")
+
+		customers := listAll(t, "ListCustomers()", func(pageToken string) ([]*repository.Customer, string, error) {
+			return r.ListCustomers(ctx, &repository.ListOptions{PageToken: pageToken})
+		})
+
+		found := make(map[string]*repository.Customer)
+		for _, c := range customers {
+			found[c.Id] = c
+		}
+		for _, want := range []*repository.Customer{c1, c2} {
+			if got := found[want.Id]; !reflect.DeepEqual(got, want) {
+				t.Errorf("ListCustomers() contains %+v, want %+v", got, want)
+			}
+		}
+	})
+
+	t.Run("ListCustomers_Pages", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		lastName := uniqueName("Doe")
+		want := make(map[string]bool)
+		for _, suffix := range []string{"b", "a", "c"} {
+			customer := newTestCustomer()
+			customer.LastName = lastName + suffix
+
+			c, err := r.CreateCustomer(ctx, customer)
+			wantCode(t, "CreateCustomer()", err, "")
+			want[c.Id] = true
+		}
+
+		for _, orderBy := range []string{"last_name", "last_name desc", "created_at", "created_at desc"} {
+			customers := listAll(t, "ListCustomers()", func(pageToken string) ([]*repository.Customer, string, error) {
+				return r.ListCustomers(ctx, &repository.ListOptions{PageSize: 2, PageToken: pageToken, OrderBy: orderBy})
+			})
+
+			field, desc, _ := strings.Cut(orderBy, " ")
+			seen := make(map[string]bool)
+			for i, c := range customers {
+				if seen[c.Id] {
+					t.Errorf("ListCustomers() ordered by %q returned customer %q twice", orderBy, c.Id)
+				}
+				seen[c.Id] = true
+
+				if i == 0 {
+					continue
+				}
+
+				prev, cur := customers[i-1].SortValue(field), c.SortValue(field)
+				if (desc == "" && prev > cur) || (desc != "" && prev < cur) {
+					t.Errorf("ListCustomers() ordered by %q returned %q before %q", orderBy, prev, cur)
+				}
+			}
+			for id := range want {
+				if !seen[id] {
+					t.Errorf("ListCustomers() ordered by %q does not contain customer %q", orderBy, id)
+				}
+			}
+		}
+
+		_, _, err := r.ListCustomers(ctx, &repository.ListOptions{OrderBy: "email"})
+		wantCode(t, "ListCustomers()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("UpdateCustomer", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		c, err := r.CreateCustomer(ctx, newTestCustomer())
+		wantCode(t, "CreateCustomer()", err, "")
+
+		start := time.Now()
+		got, err := r.UpdateCustomer(ctx, c.Id, &repository.CustomerUpdate{
+			FirstName: pkg.StringPtr("Jane"),
+			Email:     pkg.StringPtr("jane.doe@example.com"),
+		})
+		wantCode(t, "UpdateCustomer()", err, "")
+
+		want := &repository.Customer{
+			Id:        c.Id,
+			FirstName: "Jane",
+			LastName:  c.LastName,
+			Email:     "jane.doe@example.com",
+			Phone:     c.Phone,
+			CreatedAt: c.CreatedAt,
+			UpdatedAt: got.UpdatedAt,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("UpdateCustomer() = %+v, want %+v", got, want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		stored, err := r.GetCustomer(ctx, c.Id)
+		wantCode(t, "GetCustomer()", err, "")
+		if !reflect.DeepEqual(stored, got) {
+			t.Errorf("GetCustomer() = %+v, want %+v", stored, got)
+		}
+	})
+
+	t.Run("UpdateCustomer_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		c, err := r.CreateCustomer(ctx, newTestCustomer())
+		wantCode(t, "CreateCustomer()", err, "")
+
+		_, err = r.UpdateCustomer(ctx, c.Id, &repository.CustomerUpdate{Phone: pkg.StringPtr("")})
+		wantCode(t, "UpdateCustomer()", err, service.INVALID_ERROR)
+
+		// The rejected update must not have been stored.
+		stored, err := r.GetCustomer(ctx, c.Id)
+		wantCode(t, "GetCustomer()", err, "")
+		if !reflect.DeepEqual(stored, c) {
+			t.Errorf("GetCustomer() = %+v, want %+v", stored, c)
+		}
+	})
+
+	t.Run("UpdateCustomer_NotFound", func(t *testing.T) {
+		_, err := newRepository(t).UpdateCustomer(context.Background(), "does-not-exist", &repository.CustomerUpdate{
+			FirstName: pkg.StringPtr("Jane"),
+		})
+		wantCode(t, "UpdateCustomer()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("DeleteCustomer", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		c, err := r.CreateCustomer(ctx, newTestCustomer())
+		wantCode(t, "CreateCustomer()", err, "")
+
+		wantCode(t, "DeleteCustomer()", r.DeleteCustomer(ctx, c.Id), "")
+
+		_, err = r.GetCustomer(ctx, c.Id)
+		wantCode(t, "GetCustomer()", err, service.NOT_FOUND_ERROR)
+
+		wantCode(t, "DeleteCustomer()", r.DeleteCustomer(ctx, c.Id), service.NOT_FOUND_ERROR)
+		wantCode(t, "DeleteCustomer()", r.DeleteCustomer(ctx, ""), service.INVALID_ERROR)
+	})
+}
@@ -0,0 +1,1218 @@
+package repositorytest
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func newTestOrder() *repository.Order {
+	return &repository.Order{
+		CustomerId: "customer-1",
+		Items: []*repository.OrderItem{
+			{ProductId: "product-1", Quantity: 1, UnitPrice: kes(100)},
+			{ProductId: "product-2", Quantity: 2, UnitPrice: kes(250)},
+		},
+	}
+}
+
+// wantOrder compares orders field by field. Items are matched by id because
+// not every backend guarantees their order.
+func wantOrder(t *testing.T, op string, got, want *repository.Order) {
+	t.Helper()
+
+	g, w := *got, *want
+	g.Items, w.Items = nil, nil
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("%s = %+v, want %+v", op, g, w)
+	}
+
+	wantOrderItems(t, op, got.Items, want.Items)
+}
+
+// wantTotals checks the stored totals of an order.
+func wantTotals(t *testing.T, op string, r repository.OrderRepository, id string, subtotal, total money.Money) {
+	t.Helper()
+
+	o, err := r.GetOrder(context.Background(), id)
+	wantCode(t, op, err, "")
+
+	if o.Subtotal != subtotal || o.Total != total {
+		t.Errorf("%s subtotal = %v, total = %v, want %v and %v", op, o.Subtotal, o.Total, subtotal, total)
+	}
+}
+
+func wantOrderItems(t *testing.T, op string, got, want []*repository.OrderItem) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s returned %d items, want %d", op, len(got), len(want))
+		return
+	}
+
+	found := make(map[string]*repository.OrderItem)
+	for _, item := range got {
+		found[item.Id] = item
+	}
+	for _, w := range want {
+		if g := found[w.Id]; !reflect.DeepEqual(g, w) {
+			t.Errorf("%s item = %+v, want %+v", op, g, w)
+		}
+	}
+}
+
+// TestOrderRepository runs the order repository conformance suite against
+// repositories returned by newRepository.
+func TestOrderRepository(t *testing.T, newRepository func(t *testing.T) repository.OrderRepository) {
+	t.Run("CreateOrder", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		if o.Id == "" {
+			t.Errorf("CreateOrder() id is empty")
+		}
+		if o.OrderStatus != pkg.OrderStatusNew {
+			t.Errorf("CreateOrder() status = %q, want %q", o.OrderStatus, pkg.OrderStatusNew)
+		}
+		wantTimestamp(t, "CreatedAt", o.CreatedAt, start)
+		if o.UpdatedAt != o.CreatedAt {
+			t.Errorf("CreateOrder() UpdatedAt = %q, want %q", o.UpdatedAt, o.CreatedAt)
+		}
+
+		for _, item := range o.Items {
+			if item.Id == "" {
+				t.Errorf("CreateOrder() item id is empty")
+			}
+			wantTimestamp(t, "item CreatedAt", item.CreatedAt, start)
+			if want, _ := item.UnitPrice.Mul(item.Quantity); item.LineTotal != want {
+				t.Errorf("CreateOrder() item LineTotal = %v, want %v", item.LineTotal, want)
+			}
+		}
+		if o.Subtotal != kes(600) || o.Total != kes(600) {
+			t.Errorf("CreateOrder() subtotal = %v, total = %v, want %v", o.Subtotal, o.Total, kes(600))
+		}
+
+		got, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		wantOrder(t, "GetOrder()", got, o)
+
+		items, err := r.ListOrderItems(ctx, o.Id)
+		wantCode(t, "ListOrderItems()", err, "")
+		wantOrderItems(t, "ListOrderItems()", items, o.Items)
+	})
+
+	t.Run("CreateOrder_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o := newTestOrder()
+		o.CustomerId = ""
+		_, err := r.CreateOrder(ctx, o)
+		wantCode(t, "CreateOrder() without customer", err, service.INVALID_ERROR)
+
+		o = newTestOrder()
+		o.Items = nil
+		_, err = r.CreateOrder(ctx, o)
+		wantCode(t, "CreateOrder() without items", err, service.INVALID_ERROR)
+
+		o = newTestOrder()
+		o.Items[1].Quantity = 0
+		_, err = r.CreateOrder(ctx, o)
+		wantCode(t, "CreateOrder() with an invalid item", err, service.INVALID_ERROR)
+
+		o = newTestOrder()
+		o.Items[1].UnitPrice = money.New(250, "USD")
+		_, err = r.CreateOrder(ctx, o)
+		wantCode(t, "CreateOrder() with items in two currencies", err, service.INVALID_ERROR)
+
+		o = newTestOrder()
+		o.Items[1].UnitPrice = kes(math.MaxInt64)
+		_, err = r.CreateOrder(ctx, o)
+		wantCode(t, "CreateOrder() with an overflowing total", err, service.INVALID_ERROR)
+	})
+
+	t.Run("CreateOrder_Tax", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		vat := repository.TaxRate{Category: "default", Rate: 1600}
+		vatInclusive := repository.TaxRate{Category: "default", Rate: 1600, Inclusive: true}
+
+		o, err := r.CreateOrder(ctx, &repository.Order{
+			CustomerId: "customer-1",
+			Items: []*repository.OrderItem{
+				{ProductId: "product-1", Quantity: 1, UnitPrice: kes(11600), TaxRate: vatInclusive},
+				{ProductId: "product-2", Quantity: 2, UnitPrice: kes(5000), TaxRate: vat},
+				{ProductId: "product-3", Quantity: 1, UnitPrice: kes(3000), TaxRate: repository.TaxRate{
+					Category: "medical", Exempt: true,
+				}},
+				{ProductId: "product-4", Quantity: 1, UnitPrice: kes(2000), TaxRate: repository.TaxRate{
+					Category: "food",
+				}},
+			},
+		})
+		wantCode(t, "CreateOrder()", err, "")
+
+		for i, want := range []money.Money{kes(1600), kes(1600), kes(0), kes(0)} {
+			if o.Items[i].Tax != want {
+				t.Errorf("CreateOrder() item %d Tax = %v, want %v", i, o.Items[i].Tax, want)
+			}
+		}
+
+		// Only the exclusive tax is added to the total.
+		if o.Subtotal != kes(26600) || o.Tax != kes(3200) || o.Total != kes(28200) {
+			t.Errorf("CreateOrder() subtotal = %v, tax = %v, total = %v, want %v, %v and %v",
+				o.Subtotal, o.Tax, o.Total, kes(26600), kes(3200), kes(28200))
+		}
+
+		got, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		wantOrder(t, "GetOrder()", got, o)
+
+		// Changing the quantity recomputes the tax of the line at the rate it
+		// was added with.
+		item, err := r.UpdateOrderItem(ctx, o.Id, o.Items[1].Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(3)})
+		wantCode(t, "UpdateOrderItem()", err, "")
+		if item.Tax != kes(2400) || item.TaxRate != vat {
+			t.Errorf("UpdateOrderItem() tax = %v at %+v, want %v at %+v", item.Tax, item.TaxRate, kes(2400), vat)
+		}
+
+		got, err = r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		if got.Subtotal != kes(31600) || got.Tax != kes(4000) || got.Total != kes(34000) {
+			t.Errorf("GetOrder() subtotal = %v, tax = %v, total = %v, want %v, %v and %v",
+				got.Subtotal, got.Tax, got.Total, kes(31600), kes(4000), kes(34000))
+		}
+	})
+
+	t.Run("CreateOrder_InvalidTax", func(t *testing.T) {
+		r := newRepository(t)
+
+		for name, rate := range map[string]repository.TaxRate{
+			"above 100%":  {Rate: repository.MaxTaxRate + 1},
+			"exempt rate": {Rate: 1600, Exempt: true},
+		} {
+			o := newTestOrder()
+			o.Items[0].TaxRate = rate
+			_, err := r.CreateOrder(context.Background(), o)
+			wantCode(t, "CreateOrder() with a tax rate "+name, err, service.INVALID_ERROR)
+		}
+	})
+
+	t.Run("GetOrder_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetOrder(context.Background(), "does-not-exist")
+		wantCode(t, "GetOrder()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetOrder(context.Background(), "")
+		wantCode(t, "GetOrder()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListOrders", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o1, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+		o2, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		orders := listAll(t, "ListOrders()", func(pageToken string) ([]*repository.Order, string, error) {
+			return r.ListOrders(ctx, nil, &repository.ListOptions{PageToken: pageToken})
+		})
+
+		found := make(map[string]*repository.Order)
+		for _, o := range orders {
+			found[o.Id] = o
+		}
+		for _, want := range []*repository.Order{o1, o2} {
+			got, ok := found[want.Id]
+			if !ok {
+				t.Errorf("ListOrders() does not contain order %q", want.Id)
+				continue
+			}
+			wantOrder(t, "ListOrders()", got, want)
+		}
+	})
+
+	t.Run("ListOrders_Filter", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		customerId := uniqueName("customer")
+		var orders []*repository.Order
+		for i := 0; i < 3; i++ {
+			order := newTestOrder()
+			order.CustomerId = customerId
+
+			o, err := r.CreateOrder(ctx, order)
+			wantCode(t, "CreateOrder()", err, "")
+			orders = append(orders, o)
+		}
+
+		_, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		processing, err := r.UpdateOrderStatus(ctx, orders[1].Id, &repository.OrderStatusUpdate{
+			Status: pkg.OrderStatusProcessing,
+		})
+		wantCode(t, "UpdateOrderStatus()", err, "")
+		orders[1] = processing
+
+		hour := time.Hour
+		tests := []struct {
+			name   string
+			filter *repository.OrderFilter
+			want   []*repository.Order
+		}{
+			{"customer", &repository.OrderFilter{CustomerId: customerId}, orders},
+			{"status", &repository.OrderFilter{
+				CustomerId: customerId, Status: pkg.OrderStatusProcessing,
+			}, orders[1:2]},
+			{"created before now", &repository.OrderFilter{
+				CustomerId: customerId, CreatedUntil: time.Now().Add(hour),
+			}, orders},
+			{"created after now", &repository.OrderFilter{
+				CustomerId: customerId, CreatedFrom: time.Now().Add(hour),
+			}, nil},
+			{"created in the last hour", &repository.OrderFilter{
+				CustomerId: customerId, CreatedFrom: time.Now().Add(-hour), CreatedUntil: time.Now().Add(hour),
+			}, orders},
+		}
+		for _, tt := range tests {
+			got := listAll(t, "ListOrders()", func(pageToken string) ([]*repository.Order, string, error) {
+				return r.ListOrders(ctx, tt.filter, &repository.ListOptions{PageSize: 2, PageToken: pageToken})
+			})
+
+			if len(got) != len(tt.want) {
+				t.Errorf("ListOrders() with %s returned %d orders, want %d", tt.name, len(got), len(tt.want))
+				continue
+			}
+
+			found := make(map[string]*repository.Order)
+			for _, o := range got {
+				found[o.Id] = o
+			}
+			for _, want := range tt.want {
+				got, ok := found[want.Id]
+				if !ok {
+					t.Errorf("ListOrders() with %s does not contain order %q", tt.name, want.Id)
+					continue
+				}
+				wantOrder(t, "ListOrders() with "+tt.name, got, want)
+			}
+		}
+	})
+
+	t.Run("ListOrders_Pages", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		customerId := uniqueName("customer")
+		for i := 0; i < 5; i++ {
+			order := newTestOrder()
+			order.CustomerId = customerId
+
+			_, err := r.CreateOrder(ctx, order)
+			wantCode(t, "CreateOrder()", err, "")
+		}
+
+		filter := &repository.OrderFilter{CustomerId: customerId}
+		for _, orderBy := range []string{"created_at", "created_at desc", "updated_at desc"} {
+			pages := 0
+			orders := listAll(t, "ListOrders()", func(pageToken string) ([]*repository.Order, string, error) {
+				pages++
+				return r.ListOrders(ctx, filter, &repository.ListOptions{PageSize: 2, PageToken: pageToken, OrderBy: orderBy})
+			})
+
+			if pages != 3 {
+				t.Errorf("ListOrders() ordered by %q returned %d pages, want 3", orderBy, pages)
+			}
+
+			field, desc, _ := strings.Cut(orderBy, " ")
+			seen := make(map[string]bool)
+			for i, o := range orders {
+				if seen[o.Id] {
+					t.Errorf("ListOrders() ordered by %q returned order %q twice", orderBy, o.Id)
+				}
+				seen[o.Id] = true
+
+				if i == 0 {
+					continue
+				}
+
+				prev, cur := orders[i-1].SortValue(field), o.SortValue(field)
+				if (desc == "" && prev > cur) || (desc != "" && prev < cur) {
+					t.Errorf("ListOrders() ordered by %q returned %q before %q", orderBy, prev, cur)
+				}
+			}
+			if len(seen) != 5 {
+				t.Errorf("ListOrders() ordered by %q returned %d orders, want 5", orderBy, len(seen))
+			}
+		}
+	})
+
+	t.Run("ListOrders_Invalid", func(t *testing.T) {
+		r := newRepository(t)
+
+		now := time.Now()
+		tests := []struct {
+			name   string
+			filter *repository.OrderFilter
+			opts   *repository.ListOptions
+		}{
+			{"unknown status", &repository.OrderFilter{Status: "unknown"}, nil},
+			{"empty created range", &repository.OrderFilter{CreatedFrom: now, CreatedUntil: now}, nil},
+			{"unknown order by field", nil, &repository.ListOptions{OrderBy: "customer_id"}},
+			{"malformed page token", nil, &repository.ListOptions{PageToken: "not a token"}},
+		}
+		for _, tt := range tests {
+			_, _, err := r.ListOrders(context.Background(), tt.filter, tt.opts)
+			wantCode(t, "ListOrders() with "+tt.name, err, service.INVALID_ERROR)
+		}
+	})
+
+	t.Run("ListOrdersByCustomer", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		customerId := uniqueName("customer")
+		for i := 0; i < 3; i++ {
+			order := newTestOrder()
+			order.CustomerId = customerId
+
+			_, err := r.CreateOrder(ctx, order)
+			wantCode(t, "CreateOrder()", err, "")
+		}
+
+		_, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		orders := listAll(t, "ListOrdersByCustomer()", func(pageToken string) ([]*repository.Order, string, error) {
+			return r.ListOrdersByCustomer(ctx, customerId, &repository.ListOptions{PageSize: 2, PageToken: pageToken})
+		})
+
+		if len(orders) != 3 {
+			t.Fatalf("ListOrdersByCustomer() returned %d orders, want 3", len(orders))
+		}
+		for i, o := range orders {
+			if o.CustomerId != customerId {
+				t.Errorf("ListOrdersByCustomer() returned order %q of customer %q", o.Id, o.CustomerId)
+			}
+			if want := len(newTestOrder().Items); len(o.Items) != want {
+				t.Errorf("ListOrdersByCustomer() returned order %q with %d items, want %d", o.Id, len(o.Items), want)
+			}
+
+			if i == 0 {
+				continue
+			}
+
+			prev, cur := orders[i-1].SortValue("created_at"), o.SortValue("created_at")
+			if prev < cur {
+				t.Errorf("ListOrdersByCustomer() returned %q before %q, want newest first", prev, cur)
+			}
+		}
+	})
+
+	t.Run("ListOrdersByCustomer_Invalid", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, _, err := r.ListOrdersByCustomer(context.Background(), "", nil)
+		wantCode(t, "ListOrdersByCustomer() without a customer", err, service.INVALID_ERROR)
+
+		_, _, err = r.ListOrdersByCustomer(context.Background(), "customer", &repository.ListOptions{OrderBy: "status"})
+		wantCode(t, "ListOrdersByCustomer() with an unknown order by field", err, service.INVALID_ERROR)
+	})
+
+	t.Run("UpdateOrderStatus", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		if o.Version != 1 {
+			t.Errorf("CreateOrder() version = %d, want 1", o.Version)
+		}
+
+		start := time.Now()
+		got, err := r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{
+			Status: pkg.OrderStatusProcessing, StockReservation: "reservation-1",
+		})
+		wantCode(t, "UpdateOrderStatus()", err, "")
+
+		want := *o
+		want.OrderStatus = pkg.OrderStatusProcessing
+		want.Version = 2
+		want.StockReservation = "reservation-1"
+		want.UpdatedAt = got.UpdatedAt
+		wantOrder(t, "UpdateOrderStatus()", got, &want)
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		stored, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		wantOrder(t, "GetOrder()", stored, got)
+
+		// A matching version is accepted.
+		version := got.Version
+		got, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{
+			Status:  pkg.OrderStatusPending,
+			Version: &version,
+		})
+		wantCode(t, "UpdateOrderStatus()", err, "")
+		if got.OrderStatus != pkg.OrderStatusPending || got.Version != 3 {
+			t.Errorf("UpdateOrderStatus() = %s at version %d, want %s at version 3",
+				got.OrderStatus, got.Version, pkg.OrderStatusPending)
+		}
+
+		// Updates without a reservation keep the one of the order.
+		if got.StockReservation != "reservation-1" {
+			t.Errorf("UpdateOrderStatus() stock reservation = %q, want %q", got.StockReservation, "reservation-1")
+		}
+	})
+
+	t.Run("UpdateOrderStatus_IllegalTransition", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusPaid})
+		wantCode(t, "UpdateOrderStatus() new to paid", err, service.FAILED_PRECONDITION_ERROR)
+
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusNew})
+		wantCode(t, "UpdateOrderStatus() new to new", err, service.FAILED_PRECONDITION_ERROR)
+
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{Status: "unknown"})
+		wantCode(t, "UpdateOrderStatus() to an unknown status", err, service.INVALID_ERROR)
+
+		// Rejected updates must not have been stored.
+		stored, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		wantOrder(t, "GetOrder()", stored, o)
+	})
+
+	t.Run("UpdateOrderStatus_StaleVersion", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+		wantCode(t, "UpdateOrderStatus()", err, "")
+
+		// The caller read the order before it was checked out.
+		stale := o.Version
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{
+			Status:  pkg.OrderStatusCancelled,
+			Version: &stale,
+		})
+		wantCode(t, "UpdateOrderStatus()", err, service.FAILED_PRECONDITION_ERROR)
+
+		stored, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		if stored.OrderStatus != pkg.OrderStatusProcessing {
+			t.Errorf("GetOrder() status = %s, want %s", stored.OrderStatus, pkg.OrderStatusProcessing)
+		}
+	})
+
+	t.Run("UpdateOrderStatus_Concurrent", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		// Only one checkout can move the order out of new.
+		const n = 10
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			go func() {
+				_, err := r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+				errs <- err
+			}()
+		}
+
+		succeeded := 0
+		for i := 0; i < n; i++ {
+			err := <-errs
+			switch service.ErrorCode(err) {
+			case "":
+				succeeded++
+			case service.FAILED_PRECONDITION_ERROR:
+			default:
+				t.Errorf("UpdateOrderStatus() error = %v", err)
+			}
+		}
+		if succeeded != 1 {
+			t.Errorf("UpdateOrderStatus() succeeded %d times, want once", succeeded)
+		}
+
+		stored, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		if stored.Version != 2 {
+			t.Errorf("GetOrder() version = %d, want 2", stored.Version)
+		}
+	})
+
+	t.Run("UpdateOrderStatus_NotFound", func(t *testing.T) {
+		_, err := newRepository(t).UpdateOrderStatus(context.Background(), "does-not-exist", &repository.OrderStatusUpdate{
+			Status: pkg.OrderStatusProcessing,
+		})
+		wantCode(t, "UpdateOrderStatus()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("GetOrderHistory", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		history, err := r.GetOrderHistory(ctx, o.Id)
+		wantCode(t, "GetOrderHistory()", err, "")
+		if len(history) != 0 {
+			t.Errorf("GetOrderHistory() returned %d changes for a new order, want 0", len(history))
+		}
+
+		start := time.Now()
+		updates := []*repository.OrderStatusUpdate{
+			{Status: pkg.OrderStatusProcessing, Actor: "checkout", Reason: "checkout started"},
+			{Status: pkg.OrderStatusPending, Actor: "payments", Reason: "stk push sent"},
+			{Status: pkg.OrderStatusPaid, Actor: "payments", Reason: "The service request is processed successfully."},
+		}
+		for _, update := range updates {
+			_, err := r.UpdateOrderStatus(ctx, o.Id, update)
+			wantCode(t, "UpdateOrderStatus()", err, "")
+		}
+
+		// Rejected updates are not recorded.
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusNew})
+		wantCode(t, "UpdateOrderStatus()", err, service.FAILED_PRECONDITION_ERROR)
+
+		history, err = r.GetOrderHistory(ctx, o.Id)
+		wantCode(t, "GetOrderHistory()", err, "")
+		if len(history) != len(updates) {
+			t.Fatalf("GetOrderHistory() returned %d changes, want %d", len(history), len(updates))
+		}
+
+		from := pkg.OrderStatusNew
+		for i, got := range history {
+			if got.Id == "" {
+				t.Errorf("GetOrderHistory()[%d] has no id", i)
+			}
+			wantTimestamp(t, "CreatedAt", got.CreatedAt, start)
+
+			want := repository.OrderStatusChange{
+				Id:         got.Id,
+				OrderId:    o.Id,
+				FromStatus: from,
+				ToStatus:   updates[i].Status,
+				Actor:      updates[i].Actor,
+				Reason:     updates[i].Reason,
+				Version:    uint(i + 2),
+				CreatedAt:  got.CreatedAt,
+			}
+			if !reflect.DeepEqual(*got, want) {
+				t.Errorf("GetOrderHistory()[%d] = %+v, want %+v", i, *got, want)
+			}
+
+			from = updates[i].Status
+		}
+	})
+
+	t.Run("GetOrderHistory_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetOrderHistory(context.Background(), "does-not-exist")
+		wantCode(t, "GetOrderHistory()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetOrderHistory(context.Background(), "")
+		wantCode(t, "GetOrderHistory()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("DeleteOrder", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		wantCode(t, "DeleteOrder()", r.DeleteOrder(ctx, o.Id), "")
+
+		_, err = r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, service.NOT_FOUND_ERROR)
+
+		// Items go with their order.
+		_, err = r.ListOrderItems(ctx, o.Id)
+		wantCode(t, "ListOrderItems()", err, service.NOT_FOUND_ERROR)
+		_, err = r.GetOrderItem(ctx, o.Id, o.Items[0].Id)
+		wantCode(t, "GetOrderItem()", err, service.NOT_FOUND_ERROR)
+
+		wantCode(t, "DeleteOrder()", r.DeleteOrder(ctx, o.Id), service.NOT_FOUND_ERROR)
+		wantCode(t, "DeleteOrder()", r.DeleteOrder(ctx, ""), service.INVALID_ERROR)
+	})
+
+	t.Run("CreateOrderItem", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		start := time.Now()
+		item, err := r.CreateOrderItem(ctx, o.Id, &repository.OrderItem{ProductId: "product-3", Quantity: 3, UnitPrice: kes(50)})
+		wantCode(t, "CreateOrderItem()", err, "")
+		if item.LineTotal != kes(150) {
+			t.Errorf("CreateOrderItem() LineTotal = %v, want %v", item.LineTotal, kes(150))
+		}
+
+		if item.Id == "" {
+			t.Errorf("CreateOrderItem() id is empty")
+		}
+		wantTimestamp(t, "CreatedAt", item.CreatedAt, start)
+
+		got, err := r.GetOrderItem(ctx, o.Id, item.Id)
+		wantCode(t, "GetOrderItem()", err, "")
+		if !reflect.DeepEqual(got, item) {
+			t.Errorf("GetOrderItem() = %+v, want %+v", got, item)
+		}
+
+		items, err := r.ListOrderItems(ctx, o.Id)
+		wantCode(t, "ListOrderItems()", err, "")
+		wantOrderItems(t, "ListOrderItems()", items, append(o.Items, item))
+
+		stored, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		wantOrderItems(t, "GetOrder()", stored.Items, append(o.Items, item))
+		wantTotals(t, "GetOrder()", r, o.Id, kes(750), kes(750))
+	})
+
+	t.Run("CreateOrderItem_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		_, err = r.CreateOrderItem(ctx, o.Id, &repository.OrderItem{Quantity: 1})
+		wantCode(t, "CreateOrderItem() without product", err, service.INVALID_ERROR)
+
+		_, err = r.CreateOrderItem(ctx, o.Id, &repository.OrderItem{ProductId: "product-3"})
+		wantCode(t, "CreateOrderItem() without quantity", err, service.INVALID_ERROR)
+
+		_, err = r.CreateOrderItem(ctx, o.Id, &repository.OrderItem{
+			ProductId: "product-3",
+			Quantity:  1,
+			UnitPrice: money.New(50, "USD"),
+		})
+		wantCode(t, "CreateOrderItem() in another currency", err, service.INVALID_ERROR)
+
+		// The rejected items must not have been stored.
+		items, err := r.ListOrderItems(ctx, o.Id)
+		wantCode(t, "ListOrderItems()", err, "")
+		wantOrderItems(t, "ListOrderItems()", items, o.Items)
+		wantTotals(t, "GetOrder()", r, o.Id, kes(600), kes(600))
+	})
+
+	t.Run("CreateOrderItem_OrderNotFound", func(t *testing.T) {
+		_, err := newRepository(t).CreateOrderItem(context.Background(), "does-not-exist", &repository.OrderItem{
+			ProductId: "product-1",
+			Quantity:  1,
+		})
+		wantCode(t, "CreateOrderItem()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("GetOrderItem_NotFound", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o1, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+		o2, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		_, err = r.GetOrderItem(ctx, o1.Id, "does-not-exist")
+		wantCode(t, "GetOrderItem()", err, service.NOT_FOUND_ERROR)
+
+		// Items are only reachable through the order they belong to.
+		_, err = r.GetOrderItem(ctx, o2.Id, o1.Items[0].Id)
+		wantCode(t, "GetOrderItem() of another order", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetOrderItem(ctx, "", o1.Items[0].Id)
+		wantCode(t, "GetOrderItem()", err, service.INVALID_ERROR)
+		_, err = r.GetOrderItem(ctx, o1.Id, "")
+		wantCode(t, "GetOrderItem()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListOrderItems_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.ListOrderItems(context.Background(), "does-not-exist")
+		wantCode(t, "ListOrderItems()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.ListOrderItems(context.Background(), "")
+		wantCode(t, "ListOrderItems()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("UpdateOrderItem", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		start := time.Now()
+		item := o.Items[0]
+		got, err := r.UpdateOrderItem(ctx, o.Id, item.Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(5)})
+		wantCode(t, "UpdateOrderItem()", err, "")
+
+		want := *item
+		want.Quantity = 5
+		want.LineTotal = kes(500)
+		want.UpdatedAt = got.UpdatedAt
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("UpdateOrderItem() = %+v, want %+v", got, &want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		stored, err := r.GetOrderItem(ctx, o.Id, item.Id)
+		wantCode(t, "GetOrderItem()", err, "")
+		if !reflect.DeepEqual(stored, got) {
+			t.Errorf("GetOrderItem() = %+v, want %+v", stored, got)
+		}
+		wantTotals(t, "GetOrder()", r, o.Id, kes(1000), kes(1000))
+	})
+
+	t.Run("UpdateOrderItem_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		item := o.Items[0]
+		_, err = r.UpdateOrderItem(ctx, o.Id, item.Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(0)})
+		wantCode(t, "UpdateOrderItem()", err, service.INVALID_ERROR)
+
+		_, err = r.UpdateOrderItem(ctx, o.Id, item.Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(math.MaxInt64)})
+		wantCode(t, "UpdateOrderItem() with an overflowing line total", err, service.INVALID_ERROR)
+
+		// The rejected update must not have been stored.
+		stored, err := r.GetOrderItem(ctx, o.Id, item.Id)
+		wantCode(t, "GetOrderItem()", err, "")
+		if !reflect.DeepEqual(stored, item) {
+			t.Errorf("GetOrderItem() = %+v, want %+v", stored, item)
+		}
+	})
+
+	t.Run("UpdateOrderItem_NotFound", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		update := &repository.OrderItemUpdate{Quantity: pkg.UintPtr(5)}
+
+		_, err = r.UpdateOrderItem(ctx, o.Id, "does-not-exist", update)
+		wantCode(t, "UpdateOrderItem()", err, service.NOT_FOUND_ERROR)
+		_, err = r.UpdateOrderItem(ctx, "does-not-exist", o.Items[0].Id, update)
+		wantCode(t, "UpdateOrderItem()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("DeleteOrderItem", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		item := o.Items[0]
+		wantCode(t, "DeleteOrderItem()", r.DeleteOrderItem(ctx, o.Id, item.Id), "")
+
+		_, err = r.GetOrderItem(ctx, o.Id, item.Id)
+		wantCode(t, "GetOrderItem()", err, service.NOT_FOUND_ERROR)
+
+		items, err := r.ListOrderItems(ctx, o.Id)
+		wantCode(t, "ListOrderItems()", err, "")
+		wantOrderItems(t, "ListOrderItems()", items, o.Items[1:])
+		wantTotals(t, "GetOrder()", r, o.Id, kes(500), kes(500))
+
+		wantCode(t, "DeleteOrderItem()", r.DeleteOrderItem(ctx, o.Id, item.Id), service.NOT_FOUND_ERROR)
+		wantCode(t, "DeleteOrderItem()", r.DeleteOrderItem(ctx, o.Id, ""), service.INVALID_ERROR)
+	})
+
+	t.Run("ApplyOrderPromotion", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			rule     repository.PromotionRule
+			discount money.Money
+		}{
+			{
+				name:     "Percentage",
+				rule:     repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 50},
+				discount: kes(300),
+			},
+			{
+				// Discounts are rounded down to whole shillings, which is
+				// what M-Pesa can charge.
+				name:     "Percentage Rounded Down",
+				rule:     repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 25},
+				discount: kes(100),
+			},
+			{
+				name:     "Fixed Amount",
+				rule:     repository.PromotionRule{Type: repository.PromotionTypeFixedAmount, AmountOff: kes(150)},
+				discount: kes(150),
+			},
+			{
+				name:     "Fixed Amount Above Subtotal",
+				rule:     repository.PromotionRule{Type: repository.PromotionTypeFixedAmount, AmountOff: kes(1000)},
+				discount: kes(600),
+			},
+			{
+				name: "Buy X Get Y",
+				rule: repository.PromotionRule{
+					Type: repository.PromotionTypeBuyXGetY, ProductId: "product-2", BuyQuantity: 1, GetQuantity: 1,
+				},
+				discount: kes(250),
+			},
+			{
+				name: "Free Item",
+				rule: repository.PromotionRule{
+					Type: repository.PromotionTypeFreeItem, ProductId: "product-1", GetQuantity: 1,
+				},
+				discount: kes(100),
+			},
+			{
+				name: "Free Item Not In Order",
+				rule: repository.PromotionRule{
+					Type: repository.PromotionTypeFreeItem, ProductId: "product-3", GetQuantity: 1,
+				},
+				discount: kes(0),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				r := newRepository(t)
+
+				o, err := r.CreateOrder(ctx, newTestOrder())
+				wantCode(t, "CreateOrder()", err, "")
+
+				promotion := &repository.AppliedPromotion{PromotionId: "promotion-1", Code: "SAVE", Rule: tt.rule}
+
+				start := time.Now()
+				got, err := r.ApplyOrderPromotion(ctx, o.Id, promotion, "")
+				wantCode(t, "ApplyOrderPromotion()", err, "")
+
+				total, err := kes(600).Sub(tt.discount)
+				if err != nil {
+					t.Fatalf("failed to compute total: %v", err)
+				}
+
+				want := *o
+				want.Version = o.Version + 1
+				want.Promotion = promotion
+				want.Discount = tt.discount
+				want.Total = total
+				want.UpdatedAt = got.UpdatedAt
+				wantOrder(t, "ApplyOrderPromotion()", got, &want)
+				wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+				stored, err := r.GetOrder(ctx, o.Id)
+				wantCode(t, "GetOrder()", err, "")
+				wantOrder(t, "GetOrder()", stored, got)
+			})
+		}
+	})
+
+	t.Run("ApplyOrderPromotion_ItemsChange", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		_, err = r.ApplyOrderPromotion(ctx, o.Id, &repository.AppliedPromotion{
+			PromotionId: "promotion-1",
+			Code:        "BOGOF",
+			Rule: repository.PromotionRule{
+				Type: repository.PromotionTypeBuyXGetY, ProductId: "product-2", BuyQuantity: 1, GetQuantity: 1,
+			},
+		}, "")
+		wantCode(t, "ApplyOrderPromotion()", err, "")
+
+		// The discount follows the items the promotion applies to.
+		_, err = r.UpdateOrderItem(ctx, o.Id, o.Items[1].Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(4)})
+		wantCode(t, "UpdateOrderItem()", err, "")
+		wantTotals(t, "GetOrder()", r, o.Id, kes(1100), kes(600))
+
+		wantCode(t, "DeleteOrderItem()", r.DeleteOrderItem(ctx, o.Id, o.Items[1].Id), "")
+		wantTotals(t, "GetOrder()", r, o.Id, kes(100), kes(100))
+	})
+
+	t.Run("ChangeOrder_Checkout", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		// Changes bump the version, so that a checkout that read the order
+		// before them cannot start.
+		item, err := r.CreateOrderItem(ctx, o.Id, &repository.OrderItem{
+			ProductId: "product-1", Quantity: 1, UnitPrice: kes(100),
+		})
+		wantCode(t, "CreateOrderItem()", err, "")
+
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{
+			Status: pkg.OrderStatusProcessing, Version: &o.Version,
+		})
+		wantCode(t, "UpdateOrderStatus()", err, service.FAILED_PRECONDITION_ERROR)
+
+		// Orders that are checked out keep their items and promotion.
+		_, err = r.UpdateOrderStatus(ctx, o.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusProcessing})
+		wantCode(t, "UpdateOrderStatus()", err, "")
+
+		_, err = r.CreateOrderItem(ctx, o.Id, &repository.OrderItem{
+			ProductId: "product-1", Quantity: 1, UnitPrice: kes(100),
+		})
+		wantCode(t, "CreateOrderItem()", err, service.FAILED_PRECONDITION_ERROR)
+
+		_, err = r.UpdateOrderItem(ctx, o.Id, item.Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(2)})
+		wantCode(t, "UpdateOrderItem()", err, service.FAILED_PRECONDITION_ERROR)
+
+		wantCode(t, "DeleteOrderItem()", r.DeleteOrderItem(ctx, o.Id, item.Id), service.FAILED_PRECONDITION_ERROR)
+
+		_, err = r.ApplyOrderPromotion(ctx, o.Id, &repository.AppliedPromotion{
+			PromotionId: "promotion-1",
+			Code:        "SAVE",
+			Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10},
+		}, "")
+		wantCode(t, "ApplyOrderPromotion()", err, service.FAILED_PRECONDITION_ERROR)
+
+		wantTotals(t, "GetOrder()", r, o.Id, kes(700), kes(700))
+	})
+
+	t.Run("ApplyOrderPromotion_Tax", func(t *testing.T) {
+		vat := repository.TaxRate{Category: "default", Rate: 1600}
+		vatInclusive := repository.TaxRate{Category: "default", Rate: 1600, Inclusive: true}
+
+		// Each line is taxed on what is left of it after its share of the
+		// discount, so a free order carries no tax.
+		tests := []struct {
+			name  string
+			rule  repository.PromotionRule
+			taxes []money.Money
+			total money.Money
+		}{
+			{
+				name:  "Percentage",
+				rule:  repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 50},
+				taxes: []money.Money{kes(800), kes(800)},
+				total: kes(11600),
+			},
+			{
+				name:  "Full Percentage",
+				rule:  repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 100},
+				taxes: []money.Money{kes(0), kes(0)},
+				total: kes(0),
+			},
+			{
+				name: "Free Item",
+				rule: repository.PromotionRule{
+					Type: repository.PromotionTypeFreeItem, ProductId: "product-2", GetQuantity: 1,
+				},
+				taxes: []money.Money{kes(1600), kes(800)},
+				total: kes(17400),
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				r := newRepository(t)
+
+				o, err := r.CreateOrder(ctx, &repository.Order{
+					CustomerId: "customer-1",
+					Items: []*repository.OrderItem{
+						{ProductId: "product-1", Quantity: 1, UnitPrice: kes(11600), TaxRate: vatInclusive},
+						{ProductId: "product-2", Quantity: 2, UnitPrice: kes(5000), TaxRate: vat},
+					},
+				})
+				wantCode(t, "CreateOrder()", err, "")
+
+				got, err := r.ApplyOrderPromotion(ctx, o.Id, &repository.AppliedPromotion{
+					PromotionId: "promotion-1", Code: "SAVE", Rule: tt.rule,
+				}, "")
+				wantCode(t, "ApplyOrderPromotion()", err, "")
+
+				tax, err := money.Sum(tt.taxes...)
+				if err != nil {
+					t.Fatalf("failed to compute tax: %v", err)
+				}
+
+				for i, want := range tt.taxes {
+					if got.Items[i].Tax != want {
+						t.Errorf("ApplyOrderPromotion() item %d Tax = %v, want %v", i, got.Items[i].Tax, want)
+					}
+				}
+
+				if got.Tax != tax || got.Total != tt.total {
+					t.Errorf("ApplyOrderPromotion() tax = %v, total = %v, want %v and %v",
+						got.Tax, got.Total, tax, tt.total)
+				}
+
+				stored, err := r.GetOrder(ctx, o.Id)
+				wantCode(t, "GetOrder()", err, "")
+				wantOrder(t, "GetOrder()", stored, got)
+			})
+		}
+
+		// Item changes spread the discount again.
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, &repository.Order{
+			CustomerId: "customer-1",
+			Items: []*repository.OrderItem{
+				{ProductId: "product-1", Quantity: 1, UnitPrice: kes(11600), TaxRate: vatInclusive},
+			},
+			Promotion: &repository.AppliedPromotion{
+				PromotionId: "promotion-1",
+				Code:        "FREE",
+				Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 100},
+			},
+		})
+		wantCode(t, "CreateOrder()", err, "")
+		if o.Items[0].Tax != kes(0) || o.Tax != kes(0) {
+			t.Errorf("CreateOrder() item tax = %v, tax = %v, want %v", o.Items[0].Tax, o.Tax, kes(0))
+		}
+
+		item, err := r.CreateOrderItem(ctx, o.Id, &repository.OrderItem{
+			ProductId: "product-2", Quantity: 2, UnitPrice: kes(5000), TaxRate: vat,
+		})
+		wantCode(t, "CreateOrderItem()", err, "")
+		if item.Tax != kes(0) {
+			t.Errorf("CreateOrderItem() tax = %v, want %v", item.Tax, kes(0))
+		}
+
+		item, err = r.UpdateOrderItem(ctx, o.Id, item.Id, &repository.OrderItemUpdate{Quantity: pkg.UintPtr(3)})
+		wantCode(t, "UpdateOrderItem()", err, "")
+		if item.Tax != kes(0) {
+			t.Errorf("UpdateOrderItem() tax = %v, want %v", item.Tax, kes(0))
+		}
+
+		got, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		for i, item := range got.Items {
+			if item.Tax != kes(0) {
+				t.Errorf("GetOrder() item %d Tax = %v, want %v", i, item.Tax, kes(0))
+			}
+		}
+		if got.Subtotal != kes(26600) || got.Tax != kes(0) || got.Total != kes(0) {
+			t.Errorf("GetOrder() subtotal = %v, tax = %v, total = %v, want %v, %v and %v",
+				got.Subtotal, got.Tax, got.Total, kes(26600), kes(0), kes(0))
+		}
+	})
+
+	t.Run("ApplyOrderPromotion_Replaced", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		ten := &repository.AppliedPromotion{
+			PromotionId: "promotion-1",
+			Code:        "TEN",
+			Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10},
+		}
+		fifty := &repository.AppliedPromotion{
+			PromotionId: "promotion-2",
+			Code:        "FIFTY",
+			Rule:        repository.PromotionRule{Type: repository.PromotionTypeFixedAmount, AmountOff: kes(50)},
+		}
+
+		_, err = r.ApplyOrderPromotion(ctx, o.Id, ten, "promotion-2")
+		wantCode(t, "ApplyOrderPromotion() replacing a promotion that is not applied", err, service.CONFLICT_ERROR)
+
+		applied, err := r.ApplyOrderPromotion(ctx, o.Id, ten, "")
+		wantCode(t, "ApplyOrderPromotion()", err, "")
+
+		// A promotion applied concurrently is not replaced unknowingly.
+		_, err = r.ApplyOrderPromotion(ctx, o.Id, fifty, "")
+		wantCode(t, "ApplyOrderPromotion() replacing no promotion", err, service.CONFLICT_ERROR)
+
+		got, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		wantOrder(t, "GetOrder()", got, applied)
+
+		got, err = r.ApplyOrderPromotion(ctx, o.Id, fifty, "promotion-1")
+		wantCode(t, "ApplyOrderPromotion()", err, "")
+		if got.Promotion == nil || got.Promotion.Code != "FIFTY" || got.Discount != kes(50) {
+			t.Errorf("ApplyOrderPromotion() promotion = %+v, discount = %v, want FIFTY and %v",
+				got.Promotion, got.Discount, kes(50))
+		}
+	})
+
+	t.Run("ApplyOrderPromotion_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		o, err := r.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		_, err = r.ApplyOrderPromotion(ctx, o.Id, &repository.AppliedPromotion{
+			PromotionId: "promotion-1",
+			Code:        "DOLLARS",
+			Rule:        repository.PromotionRule{Type: repository.PromotionTypeFixedAmount, AmountOff: money.New(100, "USD")},
+		}, "")
+		wantCode(t, "ApplyOrderPromotion() in another currency", err, service.INVALID_ERROR)
+
+		_, err = r.ApplyOrderPromotion(ctx, o.Id, &repository.AppliedPromotion{
+			PromotionId: "promotion-1",
+			Code:        "NOTHING",
+			Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage},
+		}, "")
+		wantCode(t, "ApplyOrderPromotion() with an invalid rule", err, service.INVALID_ERROR)
+
+		_, err = r.ApplyOrderPromotion(ctx, o.Id, nil, "")
+		wantCode(t, "ApplyOrderPromotion() without a promotion", err, service.INVALID_ERROR)
+
+		// The rejected promotions must not have been stored.
+		stored, err := r.GetOrder(ctx, o.Id)
+		wantCode(t, "GetOrder()", err, "")
+		wantOrder(t, "GetOrder()", stored, o)
+	})
+
+	t.Run("ApplyOrderPromotion_NotFound", func(t *testing.T) {
+		promotion := &repository.AppliedPromotion{
+			PromotionId: "promotion-1",
+			Code:        "TEN",
+			Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10},
+		}
+
+		_, err := newRepository(t).ApplyOrderPromotion(context.Background(), "does-not-exist", promotion, "")
+		wantCode(t, "ApplyOrderPromotion()", err, service.NOT_FOUND_ERROR)
+
+		_, err = newRepository(t).ApplyOrderPromotion(context.Background(), "", promotion, "")
+		wantCode(t, "ApplyOrderPromotion()", err, service.INVALID_ERROR)
+	})
+}
@@ -0,0 +1,146 @@
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+// drainOutbox deletes every event in the outbox. The other suites leave
+// events behind, and the outbox is listed in order, so the events of a test
+// could otherwise be beyond the limit of a listing.
+func drainOutbox(t *testing.T, r repository.OutboxRepository) {
+	t.Helper()
+
+	for {
+		events, err := r.ListOutboxEvents(context.Background(), 100)
+		wantCode(t, "ListOutboxEvents()", err, "")
+		if len(events) == 0 {
+			return
+		}
+
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.Id
+		}
+
+		err = r.DeleteOutboxEvents(context.Background(), ids)
+		wantCode(t, "DeleteOutboxEvents()", err, "")
+	}
+}
+
+// orderEvents lists the events of the order in the outbox.
+func orderEvents(t *testing.T, r repository.OutboxRepository, orderId string) []*outbox.Event {
+	t.Helper()
+
+	events, err := r.ListOutboxEvents(context.Background(), 100)
+	wantCode(t, "ListOutboxEvents()", err, "")
+
+	var found []*outbox.Event
+	for _, event := range events {
+		if event.AggregateId == orderId {
+			found = append(found, event)
+		}
+	}
+
+	return found
+}
+
+// TestOutboxRepository runs the outbox repository conformance suite against
+// the repositories returned by newRepositories. The order repository must
+// record its events in the outbox repository.
+func TestOutboxRepository(t *testing.T,
+	newRepositories func(t *testing.T) (repository.OrderRepository, repository.OutboxRepository)) {
+
+	t.Run("OrderEvents", func(t *testing.T) {
+		ctx := context.Background()
+		orders, r := newRepositories(t)
+		drainOutbox(t, r)
+
+		start := time.Now()
+		order, err := orders.CreateOrder(ctx, newTestOrder())
+		wantCode(t, "CreateOrder()", err, "")
+
+		_, err = orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{
+			Status: pkg.OrderStatusProcessing,
+			Actor:  "checkout",
+			Reason: "checkout started",
+		})
+		wantCode(t, "UpdateOrderStatus()", err, "")
+
+		// A rejected change records no event.
+		_, err = orders.UpdateOrderStatus(ctx, order.Id, &repository.OrderStatusUpdate{Status: pkg.OrderStatusNew})
+		if err == nil {
+			t.Fatalf("UpdateOrderStatus() of an invalid transition error = nil, want an error")
+		}
+
+		events := orderEvents(t, r, order.Id)
+		if len(events) != 2 {
+			t.Fatalf("ListOutboxEvents() returned %d events of the order, want 2", len(events))
+		}
+
+		wantTypes := []string{pkg.EventOrderCreated, "OrderProcessing"}
+		wantStatuses := []pkg.OrderStatus{pkg.OrderStatusNew, pkg.OrderStatusProcessing}
+		for i, event := range events {
+			if event.Id == "" || event.Type != wantTypes[i] || event.AggregateType != repository.OrderAggregate {
+				t.Errorf("ListOutboxEvents()[%d] = %+v, want a %s event of the order", i, event, wantTypes[i])
+			}
+			wantTimestamp(t, "CreatedAt", event.CreatedAt, start)
+
+			var payload repository.OrderEvent
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				t.Fatalf("failed to unmarshal %s payload: %v", event.Type, err)
+			}
+
+			if payload.Order == nil || payload.Order.Id != order.Id || payload.Order.OrderStatus != wantStatuses[i] ||
+				len(payload.Order.Items) != len(order.Items) {
+				t.Errorf("%s payload order = %+v, want the order in status %s", event.Type, payload.Order, wantStatuses[i])
+			}
+
+			if (payload.Change != nil) != (i == 1) {
+				t.Errorf("%s payload change = %+v", event.Type, payload.Change)
+			} else if payload.Change != nil && (payload.Change.Id == "" || payload.Change.ToStatus != pkg.OrderStatusProcessing ||
+				payload.Change.Reason != "checkout started") {
+				t.Errorf("%s payload change = %+v, want the change to processing", event.Type, payload.Change)
+			}
+		}
+
+		if events[0].Id == events[1].Id {
+			t.Errorf("ListOutboxEvents() returned events with the same id %q", events[0].Id)
+		}
+
+		err = r.DeleteOutboxEvents(ctx, []string{events[0].Id, events[1].Id, "non-existent-id"})
+		wantCode(t, "DeleteOutboxEvents()", err, "")
+
+		if events := orderEvents(t, r, order.Id); len(events) != 0 {
+			t.Errorf("ListOutboxEvents() after deleting returned %d events of the order, want none", len(events))
+		}
+	})
+
+	t.Run("ListOutboxEvents_Limit", func(t *testing.T) {
+		ctx := context.Background()
+		orders, r := newRepositories(t)
+		drainOutbox(t, r)
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			order, err := orders.CreateOrder(ctx, newTestOrder())
+			wantCode(t, "CreateOrder()", err, "")
+
+			ids = append(ids, order.Id)
+		}
+
+		events, err := r.ListOutboxEvents(ctx, 2)
+		wantCode(t, "ListOutboxEvents()", err, "")
+
+		// Events are listed in the order they were recorded.
+		if len(events) != 2 || events[0].AggregateId != ids[0] || events[1].AggregateId != ids[1] {
+			t.Errorf("ListOutboxEvents() = %d events, want the events of the first 2 orders", len(events))
+		}
+	})
+}
@@ -0,0 +1,574 @@
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+// TestProductRepository runs the product repository conformance suite against
+// repositories returned by newRepository.
+func TestProductRepository(t *testing.T, newRepository func(t *testing.T) repository.ProductRepository) {
+	t.Run("CreateProduct", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		p, err := r.CreateProduct(ctx, &repository.Product{
+			Name:        "Test Product",
+			Description: "Test Description",
+			Price:       kes(100),
+			Category:    "food",
+			Stock:       pkg.UintPtr(5),
+			Reserved:    3,
+		})
+		wantCode(t, "CreateProduct()", err, "")
+
+		if p.Id == "" {
+			t.Errorf("CreateProduct() id is empty")
+		}
+		if p.Stock == nil || *p.Stock != 5 || p.Reserved != 0 {
+			t.Errorf("CreateProduct() stock = %s, reserved = %d, want 5 and 0", formatStock(p.Stock), p.Reserved)
+		}
+		wantTimestamp(t, "CreatedAt", p.CreatedAt, start)
+		if p.UpdatedAt != p.CreatedAt {
+			t.Errorf("CreateProduct() UpdatedAt = %q, want %q", p.UpdatedAt, p.CreatedAt)
+		}
+
+		got, err := r.GetProduct(ctx, p.Id)
+		wantCode(t, "GetProduct()", err, "")
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("GetProduct() = %+v, want %+v", got, p)
+		}
+	})
+
+	t.Run("CreateProduct_Invalid", func(t *testing.T) {
+		r := newRepository(t)
+
+		for _, p := range []*repository.Product{
+			{Price: kes(100)},
+			{Name: "No Currency", Price: money.Money{Amount: 100}},
+			{Name: "Unsupported Currency", Price: money.New(100, "XXX")},
+			{Name: "Negative Price", Price: kes(-100)},
+		} {
+			_, err := r.CreateProduct(context.Background(), p)
+			wantCode(t, "CreateProduct("+p.Name+")", err, service.INVALID_ERROR)
+		}
+	})
+
+	t.Run("GetProduct_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetProduct(context.Background(), "does-not-exist")
+		wantCode(t, "GetProduct()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetProduct(context.Background(), "")
+		wantCode(t, "GetProduct()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListProducts", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p1, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 1", Price: kes(100)})
+		wantCode(t, "CreateProduct()", err, "")
+		p2, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 2", Price: kes(200)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		products := listAll(t, "ListProducts()", func(pageToken string) ([]*repository.Product, string, error) {
+			return r.ListProducts(ctx, nil, &repository.ListOptions{PageToken: pageToken})
+		})
+
+		found := make(map[string]*repository.Product)
+		for _, p := range products {
+			found[p.Id] = p
+		}
+		for _, want := range []*repository.Product{p1, p2} {
+			if got := found[want.Id]; !reflect.DeepEqual(got, want) {
+				t.Errorf("ListProducts() contains %+v, want %+v", got, want)
+			}
+		}
+	})
+
+	t.Run("ListProducts_Pages", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		prefix := uniqueName("Paged")
+		for i, name := range []string{"c", "a", "e", "b", "d"} {
+			_, err := r.CreateProduct(ctx, &repository.Product{Name: prefix + " " + name, Price: kes(int64(100 * (i + 1)))})
+			wantCode(t, "CreateProduct()", err, "")
+		}
+
+		filter := &repository.ProductFilter{NamePrefix: prefix}
+
+		tests := []struct {
+			orderBy string
+			want    []string
+		}{
+			{"name", []string{"a", "b", "c", "d", "e"}},
+			{"name desc", []string{"e", "d", "c", "b", "a"}},
+			{"price", []string{"c", "a", "e", "b", "d"}},
+			{"price desc", []string{"d", "b", "e", "a", "c"}},
+		}
+		for _, tt := range tests {
+			pages := 0
+			products := listAll(t, "ListProducts()", func(pageToken string) ([]*repository.Product, string, error) {
+				pages++
+				return r.ListProducts(ctx, filter, &repository.ListOptions{
+					PageSize:  2,
+					PageToken: pageToken,
+					OrderBy:   tt.orderBy,
+				})
+			})
+
+			var got []string
+			for _, p := range products {
+				got = append(got, strings.TrimPrefix(p.Name, prefix+" "))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListProducts() ordered by %q = %v, want %v", tt.orderBy, got, tt.want)
+			}
+			if pages != 3 {
+				t.Errorf("ListProducts() ordered by %q returned %d pages, want 3", tt.orderBy, pages)
+			}
+		}
+	})
+
+	t.Run("ListProducts_Filter", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		prefix := uniqueName("Filtered")
+		for _, price := range []money.Money{kes(100), kes(200), kes(300), money.New(250, "USD")} {
+			_, err := r.CreateProduct(ctx, &repository.Product{Name: fmt.Sprintf("%s %v", prefix, price), Price: price})
+			wantCode(t, "CreateProduct()", err, "")
+		}
+
+		tests := []struct {
+			name   string
+			filter *repository.ProductFilter
+			want   []money.Money
+		}{
+			{"prefix", &repository.ProductFilter{NamePrefix: prefix},
+				[]money.Money{kes(100), kes(200), money.New(250, "USD"), kes(300)}},
+			{"currency", &repository.ProductFilter{NamePrefix: prefix, Currency: "KES"},
+				[]money.Money{kes(100), kes(200), kes(300)}},
+			{"min price", &repository.ProductFilter{NamePrefix: prefix, Currency: "KES", MinPrice: pkg.Int64Ptr(200)},
+				[]money.Money{kes(200), kes(300)}},
+			{"max price", &repository.ProductFilter{NamePrefix: prefix, Currency: "KES", MaxPrice: pkg.Int64Ptr(200)},
+				[]money.Money{kes(100), kes(200)}},
+			{"price range", &repository.ProductFilter{
+				NamePrefix: prefix, Currency: "KES", MinPrice: pkg.Int64Ptr(150), MaxPrice: pkg.Int64Ptr(250),
+			}, []money.Money{kes(200)}},
+			{"no match", &repository.ProductFilter{NamePrefix: prefix + "0"}, nil},
+		}
+		for _, tt := range tests {
+			products, next, err := r.ListProducts(ctx, tt.filter, &repository.ListOptions{OrderBy: "price"})
+			wantCode(t, "ListProducts()", err, "")
+			if next != "" {
+				t.Errorf("ListProducts() with %s returned a next page", tt.name)
+			}
+
+			var got []money.Money
+			for _, p := range products {
+				got = append(got, p.Price)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListProducts() with %s = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("ListProducts_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		prefix := uniqueName("Invalid")
+		for i := 0; i < 2; i++ {
+			_, err := r.CreateProduct(ctx, &repository.Product{Name: prefix, Price: kes(100)})
+			wantCode(t, "CreateProduct()", err, "")
+		}
+
+		filter := &repository.ProductFilter{NamePrefix: prefix}
+		_, next, err := r.ListProducts(ctx, filter, &repository.ListOptions{PageSize: 1, OrderBy: "name"})
+		wantCode(t, "ListProducts()", err, "")
+
+		tests := []struct {
+			name   string
+			filter *repository.ProductFilter
+			opts   *repository.ListOptions
+		}{
+			{"negative page size", nil, &repository.ListOptions{PageSize: -1}},
+			{"unknown order by field", nil, &repository.ListOptions{OrderBy: "description"}},
+			{"unknown order by direction", nil, &repository.ListOptions{OrderBy: "name up"}},
+			{"malformed page token", nil, &repository.ListOptions{PageToken: "not a token"}},
+			{"page token of another order by", nil, &repository.ListOptions{PageToken: next, OrderBy: "price"}},
+			{"min price above max price", &repository.ProductFilter{
+				MinPrice: pkg.Int64Ptr(200), MaxPrice: pkg.Int64Ptr(100),
+			}, nil},
+			{"unsupported currency", &repository.ProductFilter{Currency: "XXX"}, nil},
+		}
+		for _, tt := range tests {
+			_, _, err := r.ListProducts(ctx, tt.filter, tt.opts)
+			wantCode(t, "ListProducts() with "+tt.name, err, service.INVALID_ERROR)
+		}
+	})
+
+	t.Run("UpdateProduct", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreateProduct(ctx, &repository.Product{
+			Name:        "Test Product",
+			Description: "Test Description",
+			Price:       kes(100),
+		})
+		wantCode(t, "CreateProduct()", err, "")
+
+		start := time.Now()
+		got, err := r.UpdateProduct(ctx, p.Id, &repository.ProductUpdate{
+			Name:     pkg.StringPtr("Updated Product"),
+			Price:    &money.Money{Amount: 200, Currency: "USD"},
+			Category: pkg.StringPtr("medical"),
+			Stock:    pkg.UintPtr(7),
+		})
+		wantCode(t, "UpdateProduct()", err, "")
+
+		want := &repository.Product{
+			Id:          p.Id,
+			Name:        "Updated Product",
+			Description: "Test Description",
+			Price:       money.New(200, "USD"),
+			Category:    "medical",
+			Stock:       pkg.UintPtr(7),
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   got.UpdatedAt,
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("UpdateProduct() = %+v, want %+v", got, want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		stored, err := r.GetProduct(ctx, p.Id)
+		wantCode(t, "GetProduct()", err, "")
+		if !reflect.DeepEqual(stored, got) {
+			t.Errorf("GetProduct() = %+v, want %+v", stored, got)
+		}
+	})
+
+	t.Run("UpdateProduct_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: kes(100)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		_, err = r.UpdateProduct(ctx, p.Id, &repository.ProductUpdate{Name: pkg.StringPtr("")})
+		wantCode(t, "UpdateProduct()", err, service.INVALID_ERROR)
+
+		// The rejected update must not have been stored.
+		stored, err := r.GetProduct(ctx, p.Id)
+		wantCode(t, "GetProduct()", err, "")
+		if !reflect.DeepEqual(stored, p) {
+			t.Errorf("GetProduct() = %+v, want %+v", stored, p)
+		}
+	})
+
+	t.Run("UpdateProduct_NotFound", func(t *testing.T) {
+		_, err := newRepository(t).UpdateProduct(context.Background(), "does-not-exist", &repository.ProductUpdate{
+			Name: pkg.StringPtr("Updated Product"),
+		})
+		wantCode(t, "UpdateProduct()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("DeleteProduct", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: kes(100)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		wantCode(t, "DeleteProduct()", r.DeleteProduct(ctx, p.Id), "")
+
+		_, err = r.GetProduct(ctx, p.Id)
+		wantCode(t, "GetProduct()", err, service.NOT_FOUND_ERROR)
+
+		wantCode(t, "DeleteProduct()", r.DeleteProduct(ctx, p.Id), service.NOT_FOUND_ERROR)
+		wantCode(t, "DeleteProduct()", r.DeleteProduct(ctx, ""), service.INVALID_ERROR)
+	})
+
+	t.Run("ReserveStock", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p1, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 1", Price: kes(100), Stock: pkg.UintPtr(5)})
+		wantCode(t, "CreateProduct()", err, "")
+		p2, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 2", Price: kes(200), Stock: pkg.UintPtr(2)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		// Items of the same product are reserved together.
+		err = r.ReserveStock(ctx, "reservation-1", []repository.StockItem{
+			{ProductId: p1.Id, Quantity: 2},
+			{ProductId: p2.Id, Quantity: 2},
+			{ProductId: p1.Id, Quantity: 1},
+		})
+		wantCode(t, "ReserveStock()", err, "")
+		wantStock(t, "ReserveStock()", r, p1.Id, 2, 3)
+		wantStock(t, "ReserveStock()", r, p2.Id, 0, 2)
+
+		err = r.ReserveStock(ctx, "reservation-2", []repository.StockItem{{ProductId: p1.Id, Quantity: 1}})
+		wantCode(t, "ReserveStock()", err, "")
+		wantStock(t, "ReserveStock()", r, p1.Id, 1, 4)
+
+		wantCode(t, "ReleaseStock()", r.ReleaseStock(ctx, "reservation-2"), "")
+		wantStock(t, "ReleaseStock()", r, p1.Id, 2, 3)
+
+		wantCode(t, "CommitStock()", r.CommitStock(ctx, "reservation-1"), "")
+		wantStock(t, "CommitStock()", r, p1.Id, 2, 0)
+		wantStock(t, "CommitStock()", r, p2.Id, 0, 0)
+
+		// Settled reservations are gone, so settling them again does nothing.
+		wantCode(t, "ReleaseStock()", r.ReleaseStock(ctx, "reservation-1"), "")
+		wantCode(t, "CommitStock()", r.CommitStock(ctx, "reservation-2"), "")
+		wantStock(t, "ReleaseStock()", r, p1.Id, 2, 0)
+	})
+
+	t.Run("ReserveStock_Again", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p1, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 1", Price: kes(100), Stock: pkg.UintPtr(5)})
+		wantCode(t, "CreateProduct()", err, "")
+		p2, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 2", Price: kes(200), Stock: pkg.UintPtr(2)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		// Reserving again under the same id holds the units once.
+		items := []repository.StockItem{{ProductId: p1.Id, Quantity: 2}}
+		wantCode(t, "ReserveStock()", r.ReserveStock(ctx, "reservation-1", items), "")
+		wantCode(t, "ReserveStock()", r.ReserveStock(ctx, "reservation-1", items), "")
+		wantStock(t, "ReserveStock()", r, p1.Id, 3, 2)
+
+		// Other items replace the ones reserved before.
+		err = r.ReserveStock(ctx, "reservation-1", []repository.StockItem{
+			{ProductId: p1.Id, Quantity: 1}, {ProductId: p2.Id, Quantity: 1},
+		})
+		wantCode(t, "ReserveStock()", err, "")
+		wantStock(t, "ReserveStock()", r, p1.Id, 4, 1)
+		wantStock(t, "ReserveStock()", r, p2.Id, 1, 1)
+
+		// A replacement that cannot be reserved leaves the reservation as it was.
+		err = r.ReserveStock(ctx, "reservation-1", []repository.StockItem{{ProductId: p2.Id, Quantity: 3}})
+		wantCode(t, "ReserveStock()", err, service.OUT_OF_STOCK_ERROR)
+		wantStock(t, "ReserveStock()", r, p1.Id, 4, 1)
+		wantStock(t, "ReserveStock()", r, p2.Id, 1, 1)
+
+		wantCode(t, "ReleaseStock()", r.ReleaseStock(ctx, "reservation-1"), "")
+		wantStock(t, "ReleaseStock()", r, p1.Id, 5, 0)
+		wantStock(t, "ReleaseStock()", r, p2.Id, 2, 0)
+	})
+
+	t.Run("ReserveStock_OutOfStock", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p1, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 1", Price: kes(100), Stock: pkg.UintPtr(5)})
+		wantCode(t, "CreateProduct()", err, "")
+		p2, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 2", Price: kes(200), Stock: pkg.UintPtr(1)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		err = r.ReserveStock(ctx, "reservation-1", []repository.StockItem{
+			{ProductId: p1.Id, Quantity: 1},
+			{ProductId: p2.Id, Quantity: 2},
+		})
+		wantCode(t, "ReserveStock()", err, service.OUT_OF_STOCK_ERROR)
+
+		// Nothing is reserved unless everything is.
+		wantStock(t, "ReserveStock()", r, p1.Id, 5, 0)
+		wantStock(t, "ReserveStock()", r, p2.Id, 1, 0)
+
+		wantCode(t, "ReleaseStock()", r.ReleaseStock(ctx, "reservation-1"), "")
+		wantStock(t, "ReleaseStock()", r, p1.Id, 5, 0)
+	})
+
+	t.Run("ReserveStock_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: kes(100), Stock: pkg.UintPtr(5)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		tests := []struct {
+			name        string
+			reservation string
+			items       []repository.StockItem
+			code        string
+		}{
+			{"no reservation id", "", []repository.StockItem{{ProductId: p.Id, Quantity: 1}}, service.INVALID_ERROR},
+			{"no items", "reservation-1", nil, service.INVALID_ERROR},
+			{"no product id", "reservation-1", []repository.StockItem{{Quantity: 1}}, service.INVALID_ERROR},
+			{"no quantity", "reservation-1", []repository.StockItem{{ProductId: p.Id}}, service.INVALID_ERROR},
+			{"missing product", "reservation-1", []repository.StockItem{
+				{ProductId: p.Id, Quantity: 1}, {ProductId: "does-not-exist", Quantity: 1},
+			}, service.NOT_FOUND_ERROR},
+		}
+		for _, tt := range tests {
+			err := r.ReserveStock(ctx, tt.reservation, tt.items)
+			wantCode(t, "ReserveStock() with "+tt.name, err, tt.code)
+		}
+		wantStock(t, "ReserveStock()", r, p.Id, 5, 0)
+
+		wantCode(t, "ReleaseStock()", r.ReleaseStock(ctx, ""), service.INVALID_ERROR)
+		wantCode(t, "CommitStock()", r.CommitStock(ctx, ""), service.INVALID_ERROR)
+	})
+
+	t.Run("ReserveStock_Untracked", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: kes(100)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		// Products without a stock level can always be ordered, and hold no
+		// reserved units.
+		items := []repository.StockItem{{ProductId: p.Id, Quantity: 10}}
+		wantCode(t, "ReserveStock()", r.ReserveStock(ctx, "reservation-1", items), "")
+
+		got, err := r.GetProduct(ctx, p.Id)
+		wantCode(t, "GetProduct()", err, "")
+		if got.Stock != nil || got.Reserved != 0 {
+			t.Errorf("ReserveStock() left stock = %s, reserved = %d, want untracked and 0",
+				formatStock(got.Stock), got.Reserved)
+		}
+
+		// Setting the stock starts tracking it.
+		_, err = r.UpdateProduct(ctx, p.Id, &repository.ProductUpdate{Stock: pkg.UintPtr(3)})
+		wantCode(t, "UpdateProduct()", err, "")
+
+		wantCode(t, "ReserveStock()", r.ReserveStock(ctx, "reservation-2", items), service.OUT_OF_STOCK_ERROR)
+		err = r.ReserveStock(ctx, "reservation-2", []repository.StockItem{{ProductId: p.Id, Quantity: 2}})
+		wantCode(t, "ReserveStock()", err, "")
+		wantStock(t, "ReserveStock()", r, p.Id, 1, 2)
+
+		// Reservations made before then hold none of it.
+		wantCode(t, "CommitStock()", r.CommitStock(ctx, "reservation-1"), "")
+		wantStock(t, "CommitStock()", r, p.Id, 1, 2)
+
+		wantCode(t, "ReleaseStock()", r.ReleaseStock(ctx, "reservation-2"), "")
+		wantStock(t, "ReleaseStock()", r, p.Id, 3, 0)
+	})
+
+	t.Run("ReserveStock_DeletedProduct", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p1, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 1", Price: kes(100), Stock: pkg.UintPtr(5)})
+		wantCode(t, "CreateProduct()", err, "")
+		p2, err := r.CreateProduct(ctx, &repository.Product{Name: "Product 2", Price: kes(200), Stock: pkg.UintPtr(2)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		err = r.ReserveStock(ctx, "reservation-1", []repository.StockItem{
+			{ProductId: p1.Id, Quantity: 1}, {ProductId: p2.Id, Quantity: 1},
+		})
+		wantCode(t, "ReserveStock()", err, "")
+
+		// Deleted products have nothing to release.
+		wantCode(t, "DeleteProduct()", r.DeleteProduct(ctx, p2.Id), "")
+		wantCode(t, "ReleaseStock()", r.ReleaseStock(ctx, "reservation-1"), "")
+		wantStock(t, "ReleaseStock()", r, p1.Id, 5, 0)
+	})
+
+	t.Run("ReserveStock_Concurrent", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: kes(100), Stock: pkg.UintPtr(5)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		const attempts = 10
+
+		var wg sync.WaitGroup
+		codes := make([]string, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := r.ReserveStock(ctx, fmt.Sprintf("reservation-%d", i),
+					[]repository.StockItem{{ProductId: p.Id, Quantity: 1}})
+				codes[i] = service.ErrorCode(err)
+			}(i)
+		}
+		wg.Wait()
+
+		// Backends with optimistic transactions may give up on contended
+		// reservations, but none may sell more than the stock.
+		reserved := 0
+		for _, code := range codes {
+			switch code {
+			case "":
+				reserved++
+			case service.OUT_OF_STOCK_ERROR, service.INTERNAL_ERROR:
+			default:
+				t.Errorf("ReserveStock() code = %q, want success or out of stock", code)
+			}
+		}
+		if reserved > 5 {
+			t.Errorf("ReserveStock() reserved %d units of a product with 5", reserved)
+		}
+		wantStock(t, "ReserveStock()", r, p.Id, uint(5-reserved), uint(reserved))
+	})
+
+	t.Run("ReserveStock_ConcurrentSameId", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreateProduct(ctx, &repository.Product{Name: "Test Product", Price: kes(100), Stock: pkg.UintPtr(5)})
+		wantCode(t, "CreateProduct()", err, "")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := r.ReserveStock(ctx, "reservation-1", []repository.StockItem{{ProductId: p.Id, Quantity: 2}})
+				if code := service.ErrorCode(err); code != "" && code != service.INTERNAL_ERROR {
+					t.Errorf("ReserveStock() code = %q, want success", code)
+				}
+			}()
+		}
+		wg.Wait()
+
+		// Retries of a reservation hold its units once, however they interleave.
+		wantStock(t, "ReserveStock()", r, p.Id, 3, 2)
+	})
+}
+
+// wantStock fails the test if the stock of a stored product differs from want.
+func wantStock(t *testing.T, op string, r repository.ProductRepository, id string, stock, reserved uint) {
+	t.Helper()
+
+	p, err := r.GetProduct(context.Background(), id)
+	wantCode(t, "GetProduct()", err, "")
+
+	if p.Stock == nil || *p.Stock != stock || p.Reserved != reserved {
+		t.Errorf("%s left stock = %s, reserved = %d, want %d and %d", op, formatStock(p.Stock), p.Reserved, stock, reserved)
+	}
+}
+
+// formatStock formats the stock of a product, which is nil when untracked.
+func formatStock(stock *uint) string {
+	if stock == nil {
+		return "untracked"
+	}
+
+	return fmt.Sprint(*stock)
+}
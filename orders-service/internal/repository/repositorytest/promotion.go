@@ -0,0 +1,341 @@
+package repositorytest
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+// newTestPromotion returns a promotion with a code that is unique to the test
+// run, as codes are unique across the repository.
+func newTestPromotion() *repository.Promotion {
+	return &repository.Promotion{
+		Code:        uniqueName("SAVE10"),
+		Description: "10% off",
+		Rule:        repository.PromotionRule{Type: repository.PromotionTypePercentage, Percentage: 10},
+		StartsAt:    "2024-01-01T00:00:00Z",
+		EndsAt:      "2124-01-01T00:00:00Z",
+	}
+}
+
+// TestPromotionRepository runs the promotion repository conformance suite
+// against repositories returned by newRepository.
+func TestPromotionRepository(t *testing.T, newRepository func(t *testing.T) repository.PromotionRepository) {
+	t.Run("CreatePromotion", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		p, err := r.CreatePromotion(ctx, newTestPromotion())
+		wantCode(t, "CreatePromotion()", err, "")
+
+		if p.Id == "" {
+			t.Errorf("CreatePromotion() id is empty")
+		}
+		wantTimestamp(t, "CreatedAt", p.CreatedAt, start)
+		if p.UpdatedAt != p.CreatedAt {
+			t.Errorf("CreatePromotion() UpdatedAt = %q, want %q", p.UpdatedAt, p.CreatedAt)
+		}
+
+		got, err := r.GetPromotion(ctx, p.Id)
+		wantCode(t, "GetPromotion()", err, "")
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("GetPromotion() = %+v, want %+v", got, p)
+		}
+
+		got, err = r.GetPromotionByCode(ctx, p.Code)
+		wantCode(t, "GetPromotionByCode()", err, "")
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("GetPromotionByCode() = %+v, want %+v", got, p)
+		}
+	})
+
+	t.Run("CreatePromotion_Rules", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		for _, rule := range []repository.PromotionRule{
+			{Type: repository.PromotionTypeFixedAmount, AmountOff: kes(500)},
+			{Type: repository.PromotionTypeBuyXGetY, ProductId: "product-1", BuyQuantity: 2, GetQuantity: 1},
+			{Type: repository.PromotionTypeFreeItem, ProductId: "product-1", GetQuantity: 1},
+		} {
+			promotion := newTestPromotion()
+			promotion.Rule = rule
+			promotion.StartsAt, promotion.EndsAt = "", ""
+
+			p, err := r.CreatePromotion(ctx, promotion)
+			wantCode(t, "CreatePromotion() with a "+string(rule.Type)+" rule", err, "")
+
+			got, err := r.GetPromotion(ctx, p.Id)
+			wantCode(t, "GetPromotion()", err, "")
+			if !reflect.DeepEqual(got, p) {
+				t.Errorf("GetPromotion() = %+v, want %+v", got, p)
+			}
+		}
+	})
+
+	t.Run("CreatePromotion_Invalid", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(p *repository.Promotion)
+		}{
+			{"Without Code", func(p *repository.Promotion) { p.Code = "" }},
+			{"Unknown Type", func(p *repository.Promotion) { p.Rule.Type = "unknown" }},
+			{"Percentage Above 100", func(p *repository.Promotion) { p.Rule.Percentage = 101 }},
+			{"Fixed Amount Without Amount", func(p *repository.Promotion) {
+				p.Rule = repository.PromotionRule{Type: repository.PromotionTypeFixedAmount}
+			}},
+			{"Buy X Get Y Without Buy Quantity", func(p *repository.Promotion) {
+				p.Rule = repository.PromotionRule{
+					Type: repository.PromotionTypeBuyXGetY, ProductId: "product-1", GetQuantity: 1,
+				}
+			}},
+			{"Free Item Without Product", func(p *repository.Promotion) {
+				p.Rule = repository.PromotionRule{Type: repository.PromotionTypeFreeItem, GetQuantity: 1}
+			}},
+			{"Malformed Start", func(p *repository.Promotion) { p.StartsAt = "yesterday" }},
+			{"Ends Before Start", func(p *repository.Promotion) { p.StartsAt, p.EndsAt = p.EndsAt, p.StartsAt }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				p := newTestPromotion()
+				tt.modify(p)
+
+				_, err := newRepository(t).CreatePromotion(context.Background(), p)
+				wantCode(t, "CreatePromotion()", err, service.INVALID_ERROR)
+			})
+		}
+	})
+
+	t.Run("CreatePromotion_DuplicateCode", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreatePromotion(ctx, newTestPromotion())
+		wantCode(t, "CreatePromotion()", err, "")
+
+		duplicate := newTestPromotion()
+		duplicate.Code = p.Code
+
+		_, err = r.CreatePromotion(ctx, duplicate)
+		wantCode(t, "CreatePromotion()", err, service.ALREADY_EXISTS_ERROR)
+	})
+
+	t.Run("GetPromotion_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetPromotion(context.Background(), "does-not-exist")
+		wantCode(t, "GetPromotion()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetPromotion(context.Background(), "")
+		wantCode(t, "GetPromotion()", err, service.INVALID_ERROR)
+
+		_, err = r.GetPromotionByCode(context.Background(), uniqueName("MISSING"))
+		wantCode(t, "GetPromotionByCode()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetPromotionByCode(context.Background(), "")
+		wantCode(t, "GetPromotionByCode()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListPromotions_Pages", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		code := uniqueName("CODE")
+		want := make(map[string]bool)
+		for _, suffix := range []string{"b", "a", "c"} {
+			promotion := newTestPromotion()
+			promotion.Code = code + suffix
+
+			p, err := r.CreatePromotion(ctx, promotion)
+			wantCode(t, "CreatePromotion()", err, "")
+			want[p.Id] = true
+		}
+
+		for _, orderBy := range []string{"code", "code desc", "created_at", "created_at desc"} {
+			promotions := listAll(t, "ListPromotions()", func(pageToken string) ([]*repository.Promotion, string, error) {
+				return r.ListPromotions(ctx, &repository.ListOptions{PageSize: 2, PageToken: pageToken, OrderBy: orderBy})
+			})
+
+			field, desc, _ := strings.Cut(orderBy, " ")
+			seen := make(map[string]bool)
+			for i, p := range promotions {
+				if seen[p.Id] {
+					t.Errorf("ListPromotions() ordered by %q returned promotion %q twice", orderBy, p.Id)
+				}
+				seen[p.Id] = true
+
+				if i == 0 {
+					continue
+				}
+
+				prev, cur := promotions[i-1].SortValue(field), p.SortValue(field)
+				if (desc == "" && prev > cur) || (desc != "" && prev < cur) {
+					t.Errorf("ListPromotions() ordered by %q returned %q before %q", orderBy, prev, cur)
+				}
+			}
+			for id := range want {
+				if !seen[id] {
+					t.Errorf("ListPromotions() ordered by %q does not contain promotion %q", orderBy, id)
+				}
+			}
+		}
+
+		_, _, err := r.ListPromotions(ctx, &repository.ListOptions{OrderBy: "description"})
+		wantCode(t, "ListPromotions()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("UpdatePromotion", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreatePromotion(ctx, newTestPromotion())
+		wantCode(t, "CreatePromotion()", err, "")
+
+		start := time.Now()
+		got, err := r.UpdatePromotion(ctx, p.Id, &repository.PromotionUpdate{
+			Description:      pkg.StringPtr("10% off, twice per customer"),
+			EndsAt:           pkg.StringPtr(""),
+			UsageLimit:       pkg.UintPtr(100),
+			PerCustomerLimit: pkg.UintPtr(2),
+		})
+		wantCode(t, "UpdatePromotion()", err, "")
+
+		want := *p
+		want.Description = "10% off, twice per customer"
+		want.EndsAt = ""
+		want.UsageLimit = 100
+		want.PerCustomerLimit = 2
+		want.UpdatedAt = got.UpdatedAt
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("UpdatePromotion() = %+v, want %+v", got, &want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		stored, err := r.GetPromotion(ctx, p.Id)
+		wantCode(t, "GetPromotion()", err, "")
+		if !reflect.DeepEqual(stored, got) {
+			t.Errorf("GetPromotion() = %+v, want %+v", stored, got)
+		}
+	})
+
+	t.Run("UpdatePromotion_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreatePromotion(ctx, newTestPromotion())
+		wantCode(t, "CreatePromotion()", err, "")
+
+		_, err = r.UpdatePromotion(ctx, p.Id, &repository.PromotionUpdate{EndsAt: pkg.StringPtr(p.StartsAt)})
+		wantCode(t, "UpdatePromotion()", err, service.INVALID_ERROR)
+
+		// The rejected update must not have been stored.
+		stored, err := r.GetPromotion(ctx, p.Id)
+		wantCode(t, "GetPromotion()", err, "")
+		if !reflect.DeepEqual(stored, p) {
+			t.Errorf("GetPromotion() = %+v, want %+v", stored, p)
+		}
+	})
+
+	t.Run("UpdatePromotion_NotFound", func(t *testing.T) {
+		_, err := newRepository(t).UpdatePromotion(context.Background(), "does-not-exist", &repository.PromotionUpdate{
+			Description: pkg.StringPtr("Gone"),
+		})
+		wantCode(t, "UpdatePromotion()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("DeletePromotion", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p, err := r.CreatePromotion(ctx, newTestPromotion())
+		wantCode(t, "CreatePromotion()", err, "")
+
+		wantCode(t, "DeletePromotion()", r.DeletePromotion(ctx, p.Id), "")
+
+		_, err = r.GetPromotion(ctx, p.Id)
+		wantCode(t, "GetPromotion()", err, service.NOT_FOUND_ERROR)
+
+		wantCode(t, "DeletePromotion()", r.DeletePromotion(ctx, p.Id), service.NOT_FOUND_ERROR)
+		wantCode(t, "DeletePromotion()", r.DeletePromotion(ctx, ""), service.INVALID_ERROR)
+	})
+
+	t.Run("RedeemPromotion", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		promotion := newTestPromotion()
+		promotion.UsageLimit = 3
+		promotion.PerCustomerLimit = 2
+
+		p, err := r.CreatePromotion(ctx, promotion)
+		wantCode(t, "CreatePromotion()", err, "")
+
+		redeem := func(orderId, customerId string) error {
+			return r.RedeemPromotion(ctx, &repository.PromotionRedemption{
+				PromotionId: p.Id,
+				OrderId:     orderId,
+				CustomerId:  customerId,
+			})
+		}
+
+		wantRedemptions := func(want uint) {
+			t.Helper()
+
+			got, err := r.GetPromotion(ctx, p.Id)
+			wantCode(t, "GetPromotion()", err, "")
+			if got.Redemptions != want {
+				t.Errorf("GetPromotion() redemptions = %d, want %d", got.Redemptions, want)
+			}
+		}
+
+		wantCode(t, "RedeemPromotion()", redeem("order-1", "customer-1"), "")
+		wantCode(t, "RedeemPromotion()", redeem("order-2", "customer-1"), "")
+
+		// Redeeming again for the same order is not counted twice.
+		wantCode(t, "RedeemPromotion() again", redeem("order-2", "customer-1"), "")
+		wantRedemptions(2)
+
+		wantCode(t, "RedeemPromotion() over the customer cap", redeem("order-3", "customer-1"),
+			service.FAILED_PRECONDITION_ERROR)
+
+		wantCode(t, "RedeemPromotion()", redeem("order-4", "customer-2"), "")
+		wantCode(t, "RedeemPromotion() over the usage limit", redeem("order-5", "customer-3"),
+			service.FAILED_PRECONDITION_ERROR)
+		wantRedemptions(3)
+
+		// Releasing a redemption makes room for another.
+		wantCode(t, "ReleasePromotion()", r.ReleasePromotion(ctx, p.Id, "order-1"), "")
+		wantRedemptions(2)
+
+		wantCode(t, "ReleasePromotion() again", r.ReleasePromotion(ctx, p.Id, "order-1"), "")
+		wantRedemptions(2)
+
+		wantCode(t, "RedeemPromotion()", redeem("order-3", "customer-1"), "")
+		wantRedemptions(3)
+	})
+
+	t.Run("RedeemPromotion_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		err := r.RedeemPromotion(ctx, &repository.PromotionRedemption{
+			PromotionId: "does-not-exist",
+			OrderId:     "order-1",
+			CustomerId:  "customer-1",
+		})
+		wantCode(t, "RedeemPromotion()", err, service.NOT_FOUND_ERROR)
+
+		err = r.RedeemPromotion(ctx, &repository.PromotionRedemption{PromotionId: "does-not-exist", OrderId: "order-1"})
+		wantCode(t, "RedeemPromotion() without a customer", err, service.INVALID_ERROR)
+
+		wantCode(t, "ReleasePromotion()", r.ReleasePromotion(ctx, "does-not-exist", "order-1"), "")
+		wantCode(t, "ReleasePromotion()", r.ReleasePromotion(ctx, "", "order-1"), service.INVALID_ERROR)
+	})
+}
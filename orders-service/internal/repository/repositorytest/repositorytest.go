@@ -0,0 +1,77 @@
+// Package repositorytest provides a conformance suite for the repository
+// interfaces. Every storage backend runs the suite from its own tests so that
+// they can be swapped for one another without changing behaviour.
+//
+// The suites only assume that the records they create themselves exist, so
+// they can run against shared databases such as the Firestore emulator.
+package repositorytest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+// kes returns amount cents of Kenyan shillings, the currency the suites use.
+func kes(amount int64) money.Money {
+	return money.New(amount, "KES")
+}
+
+// wantCode fails the test unless err carries the given application error code.
+// An empty code asserts that err is nil.
+func wantCode(t *testing.T, op string, err error, code string) {
+	t.Helper()
+
+	if got := service.ErrorCode(err); got != code {
+		t.Fatalf("%s error = %v, want code %q", op, err, code)
+	}
+}
+
+// wantTimestamp fails the test unless ts is an RFC3339 timestamp no earlier
+// than since and not in the future.
+func wantTimestamp(t *testing.T, field string, ts string, since time.Time) {
+	t.Helper()
+
+	got, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		t.Fatalf("%s = %q, want an RFC3339 timestamp: %v", field, ts, err)
+	}
+
+	// Timestamps are stored with second precision.
+	since = since.Truncate(time.Second)
+	if got.Before(since) || got.After(time.Now().Add(time.Second)) {
+		t.Errorf("%s = %v, want between %v and now", field, got, since)
+	}
+}
+
+// uniqueName returns prefix followed by a suffix that is unique to the test
+// run, so that records can be told apart in a shared database.
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s %d", prefix, time.Now().UnixNano())
+}
+
+// listAll calls list with the page token of the previous call until it returns
+// the last page, and returns the records of all pages.
+func listAll[T any](t *testing.T, op string, list func(pageToken string) ([]T, string, error)) []T {
+	t.Helper()
+
+	var records []T
+	for pageToken, pages := "", 0; ; pages++ {
+		if pages > 1000 {
+			t.Fatalf("%s did not return the last page", op)
+		}
+
+		page, next, err := list(pageToken)
+		wantCode(t, op, err, "")
+
+		records = append(records, page...)
+		if next == "" {
+			return records
+		}
+
+		pageToken = next
+	}
+}
@@ -0,0 +1,299 @@
+package repositorytest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+func newTestWebhookSubscription() *repository.WebhookSubscription {
+	return &repository.WebhookSubscription{
+		Url:        "https://erp.example.com/webhooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{pkg.EventOrderCreated, pkg.OrderStatusPaid.EventType()},
+	}
+}
+
+// newTestWebhookDelivery returns a pending delivery to a subscription that is
+// unique to the test run, so that listings by subscription only return the
+// deliveries of the test.
+func newTestWebhookDelivery(subscriptionId string, nextAttemptAt time.Time) *repository.WebhookDelivery {
+	return &repository.WebhookDelivery{
+		SubscriptionId: subscriptionId,
+		EventId:        uniqueName("event"),
+		EventType:      pkg.EventOrderCreated,
+		Payload:        []byte(`{"id":"event"}`),
+		Status:         repository.WebhookDeliveryStatusPending,
+		NextAttemptAt:  nextAttemptAt.Truncate(time.Second).Format(time.RFC3339),
+	}
+}
+
+// TestWebhookRepository runs the webhook repository conformance suite against
+// repositories returned by newRepository.
+func TestWebhookRepository(t *testing.T, newRepository func(t *testing.T) repository.WebhookRepository) {
+	t.Run("WebhookSubscriptions", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		s, err := r.CreateWebhookSubscription(ctx, newTestWebhookSubscription())
+		wantCode(t, "CreateWebhookSubscription()", err, "")
+
+		if s.Id == "" {
+			t.Errorf("CreateWebhookSubscription() id is empty")
+		}
+		wantTimestamp(t, "CreatedAt", s.CreatedAt, start)
+
+		got, err := r.GetWebhookSubscription(ctx, s.Id)
+		wantCode(t, "GetWebhookSubscription()", err, "")
+		if !reflect.DeepEqual(got, s) {
+			t.Errorf("GetWebhookSubscription() = %+v, want %+v", got, s)
+		}
+
+		subscriptions, err := r.ListWebhookSubscriptions(ctx)
+		wantCode(t, "ListWebhookSubscriptions()", err, "")
+
+		found := false
+		for _, subscription := range subscriptions {
+			if subscription.Id == s.Id {
+				found = reflect.DeepEqual(subscription, s)
+			}
+		}
+		if !found {
+			t.Errorf("ListWebhookSubscriptions() did not return %+v", s)
+		}
+
+		err = r.DeleteWebhookSubscription(ctx, s.Id)
+		wantCode(t, "DeleteWebhookSubscription()", err, "")
+
+		_, err = r.GetWebhookSubscription(ctx, s.Id)
+		wantCode(t, "GetWebhookSubscription() after delete", err, service.NOT_FOUND_ERROR)
+
+		err = r.DeleteWebhookSubscription(ctx, s.Id)
+		wantCode(t, "DeleteWebhookSubscription() after delete", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("CreateWebhookSubscription_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		for name, modify := range map[string]func(s *repository.WebhookSubscription){
+			"relative url":       func(s *repository.WebhookSubscription) { s.Url = "/webhooks" },
+			"ftp url":            func(s *repository.WebhookSubscription) { s.Url = "ftp://erp.example.com" },
+			"short secret":       func(s *repository.WebhookSubscription) { s.Secret = "secret" },
+			"no event types":     func(s *repository.WebhookSubscription) { s.EventTypes = nil },
+			"unknown event type": func(s *repository.WebhookSubscription) { s.EventTypes = []string{"OrderNew"} },
+		} {
+			s := newTestWebhookSubscription()
+			modify(s)
+
+			_, err := r.CreateWebhookSubscription(ctx, s)
+			wantCode(t, "CreateWebhookSubscription() with "+name, err, service.INVALID_ERROR)
+		}
+	})
+
+	t.Run("GetWebhookSubscription_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetWebhookSubscription(context.Background(), "")
+		wantCode(t, "GetWebhookSubscription() without id", err, service.INVALID_ERROR)
+
+		_, err = r.GetWebhookSubscription(context.Background(), "non-existent-id")
+		wantCode(t, "GetWebhookSubscription()", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("CreateWebhookDelivery", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		d, err := r.CreateWebhookDelivery(ctx, newTestWebhookDelivery(uniqueName("subscription"), start))
+		wantCode(t, "CreateWebhookDelivery()", err, "")
+
+		if d.Id == "" {
+			t.Errorf("CreateWebhookDelivery() id is empty")
+		}
+		wantTimestamp(t, "CreatedAt", d.CreatedAt, start)
+
+		got, err := r.GetWebhookDelivery(ctx, d.Id)
+		wantCode(t, "GetWebhookDelivery()", err, "")
+		if !reflect.DeepEqual(got, d) {
+			t.Errorf("GetWebhookDelivery() = %+v, want %+v", got, d)
+		}
+
+		// An event is delivered to a subscription once.
+		duplicate := newTestWebhookDelivery(d.SubscriptionId, start)
+		duplicate.EventId = d.EventId
+		_, err = r.CreateWebhookDelivery(ctx, duplicate)
+		wantCode(t, "CreateWebhookDelivery() of a delivered event", err, service.ALREADY_EXISTS_ERROR)
+
+		// The same event can be delivered to another subscription.
+		other := newTestWebhookDelivery(uniqueName("subscription"), start)
+		other.EventId = d.EventId
+		_, err = r.CreateWebhookDelivery(ctx, other)
+		wantCode(t, "CreateWebhookDelivery() to another subscription", err, "")
+	})
+
+	t.Run("CreateWebhookDelivery_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		d := newTestWebhookDelivery("", time.Now())
+		_, err := r.CreateWebhookDelivery(ctx, d)
+		wantCode(t, "CreateWebhookDelivery() without subscription id", err, service.INVALID_ERROR)
+
+		d = newTestWebhookDelivery(uniqueName("subscription"), time.Now())
+		d.Status = "lost"
+		_, err = r.CreateWebhookDelivery(ctx, d)
+		wantCode(t, "CreateWebhookDelivery() with an unknown status", err, service.INVALID_ERROR)
+	})
+
+	t.Run("UpdateWebhookDelivery", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		d, err := r.CreateWebhookDelivery(ctx, newTestWebhookDelivery(uniqueName("subscription"), time.Now()))
+		wantCode(t, "CreateWebhookDelivery()", err, "")
+
+		update := &repository.WebhookDeliveryUpdate{
+			Status:           repository.WebhookDeliveryStatusFailed,
+			Attempts:         3,
+			LastResponseCode: 503,
+			LastError:        "503 Service Unavailable",
+			NextAttemptAt:    d.NextAttemptAt,
+		}
+
+		updated, err := r.UpdateWebhookDelivery(ctx, d.Id, update)
+		wantCode(t, "UpdateWebhookDelivery()", err, "")
+
+		if updated.Status != update.Status || updated.Attempts != 3 || updated.LastResponseCode != 503 ||
+			updated.LastError != update.LastError {
+			t.Errorf("UpdateWebhookDelivery() = %+v, want a failed delivery after 3 attempts", updated)
+		}
+		if updated.EventId != d.EventId || !reflect.DeepEqual(updated.Payload, d.Payload) {
+			t.Errorf("UpdateWebhookDelivery() = %+v, want the event of %+v", updated, d)
+		}
+
+		got, err := r.GetWebhookDelivery(ctx, d.Id)
+		wantCode(t, "GetWebhookDelivery()", err, "")
+		if !reflect.DeepEqual(got, updated) {
+			t.Errorf("GetWebhookDelivery() = %+v, want %+v", got, updated)
+		}
+
+		update.Status = "lost"
+		_, err = r.UpdateWebhookDelivery(ctx, d.Id, update)
+		wantCode(t, "UpdateWebhookDelivery() with an unknown status", err, service.INVALID_ERROR)
+
+		_, err = r.UpdateWebhookDelivery(ctx, "non-existent-id", &repository.WebhookDeliveryUpdate{
+			Status:        repository.WebhookDeliveryStatusPending,
+			NextAttemptAt: d.NextAttemptAt,
+		})
+		wantCode(t, "UpdateWebhookDelivery() of a missing delivery", err, service.NOT_FOUND_ERROR)
+	})
+
+	t.Run("ListWebhookDeliveries", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		subscriptionId := uniqueName("subscription")
+		want := make(map[string]repository.WebhookDeliveryStatus)
+		for _, status := range []repository.WebhookDeliveryStatus{
+			repository.WebhookDeliveryStatusPending,
+			repository.WebhookDeliveryStatusFailed,
+			repository.WebhookDeliveryStatusFailed,
+		} {
+			d, err := r.CreateWebhookDelivery(ctx, newTestWebhookDelivery(subscriptionId, time.Now()))
+			wantCode(t, "CreateWebhookDelivery()", err, "")
+
+			if status != d.Status {
+				_, err = r.UpdateWebhookDelivery(ctx, d.Id, &repository.WebhookDeliveryUpdate{
+					Status: status, NextAttemptAt: d.NextAttemptAt,
+				})
+				wantCode(t, "UpdateWebhookDelivery()", err, "")
+			}
+
+			want[d.Id] = status
+		}
+
+		for _, status := range []repository.WebhookDeliveryStatus{"", repository.WebhookDeliveryStatusFailed} {
+			filter := &repository.WebhookDeliveryFilter{SubscriptionId: subscriptionId, Status: status}
+			deliveries := listAll(t, "ListWebhookDeliveries()",
+				func(pageToken string) ([]*repository.WebhookDelivery, string, error) {
+					return r.ListWebhookDeliveries(ctx, filter, &repository.ListOptions{PageSize: 1, PageToken: pageToken})
+				})
+
+			wantCount := 0
+			for _, s := range want {
+				if status == "" || s == status {
+					wantCount++
+				}
+			}
+
+			if len(deliveries) != wantCount {
+				t.Errorf("ListWebhookDeliveries() with status %q returned %d deliveries, want %d",
+					status, len(deliveries), wantCount)
+			}
+			for _, d := range deliveries {
+				if s, ok := want[d.Id]; !ok || (status != "" && s != status) {
+					t.Errorf("ListWebhookDeliveries() with status %q returned %+v", status, d)
+				}
+			}
+		}
+
+		_, _, err := r.ListWebhookDeliveries(ctx, &repository.WebhookDeliveryFilter{Status: "lost"}, nil)
+		wantCode(t, "ListWebhookDeliveries() with an unknown status", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListDueWebhookDeliveries", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		now := time.Now()
+		subscriptionId := uniqueName("subscription")
+
+		var ids []string
+		for _, nextAttemptAt := range []time.Time{now.Add(-time.Minute), now.Add(-time.Hour), now.Add(time.Hour)} {
+			d, err := r.CreateWebhookDelivery(ctx, newTestWebhookDelivery(subscriptionId, nextAttemptAt))
+			wantCode(t, "CreateWebhookDelivery()", err, "")
+
+			ids = append(ids, d.Id)
+		}
+
+		// Deliveries that are not pending are never due.
+		d, err := r.CreateWebhookDelivery(ctx, newTestWebhookDelivery(subscriptionId, now.Add(-time.Hour)))
+		wantCode(t, "CreateWebhookDelivery()", err, "")
+
+		_, err = r.UpdateWebhookDelivery(ctx, d.Id, &repository.WebhookDeliveryUpdate{
+			Status: repository.WebhookDeliveryStatusFailed, NextAttemptAt: d.NextAttemptAt,
+		})
+		wantCode(t, "UpdateWebhookDelivery()", err, "")
+
+		deliveries, err := r.ListDueWebhookDeliveries(ctx, now, 1000)
+		wantCode(t, "ListDueWebhookDeliveries()", err, "")
+
+		// Other tests may share the database, so only the deliveries created
+		// here are checked.
+		var got []string
+		for _, d := range deliveries {
+			if d.SubscriptionId == subscriptionId {
+				got = append(got, d.Id)
+			}
+		}
+
+		want := []string{ids[1], ids[0]}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ListDueWebhookDeliveries() returned %v, want %v", got, want)
+		}
+
+		deliveries, err = r.ListDueWebhookDeliveries(ctx, now, 0)
+		wantCode(t, "ListDueWebhookDeliveries() with limit 0", err, "")
+		if len(deliveries) != 0 {
+			t.Errorf("ListDueWebhookDeliveries() with limit 0 returned %d deliveries, want none", len(deliveries))
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+// MaxTaxRate is the highest tax rate, in basis points.
+const MaxTaxRate = 10000
+
+// TaxRate is how an order item is taxed. Items keep the rate of their product
+// category at the time they were added, see PriceOrderItems.
+type TaxRate struct {
+	// Category is the product category the rate applies to.
+	Category string `json:"category"`
+
+	// Rate is in basis points, e.g. 1600 for 16%. Zero-rated items are
+	// taxable at a rate of 0, while Exempt items are not taxable at all.
+	Rate   uint `json:"rate"`
+	Exempt bool `json:"exempt"`
+
+	// Inclusive is set if the unit price includes the tax, as is usual for
+	// consumer prices. Otherwise the tax is added to the price.
+	Inclusive bool `json:"inclusive"`
+}
+
+func (r *TaxRate) Validate() error {
+	if r.Rate > MaxTaxRate {
+		return service.Errorf(service.INVALID_ERROR, "tax rate must be at most %d basis points", MaxTaxRate)
+	}
+
+	if r.Exempt && r.Rate != 0 {
+		return service.Errorf(service.INVALID_ERROR, "exempt items cannot have a tax rate")
+	}
+
+	return nil
+}
+
+// Tax returns the tax on an amount at the rate, rounded half up. For an
+// inclusive rate the tax is the part of the amount that is tax, rounded to the
+// minor unit. Exclusive tax is added to what the customer pays, so it is
+// rounded to the unit the currency is charged in, e.g. whole shillings.
+func (r *TaxRate) Tax(amount money.Money) money.Money {
+	if r.Exempt || r.Rate == 0 {
+		return money.Zero(amount.Currency)
+	}
+
+	// Divide before multiplying so that large amounts cannot overflow.
+	rate := int64(r.Rate)
+	unit := int64(1)
+	base := int64(MaxTaxRate)
+	if r.Inclusive {
+		base += rate
+	} else {
+		unit = money.ChargeableUnit(amount.Currency)
+		base *= unit
+	}
+
+	q, rem := amount.Amount/base, amount.Amount%base
+
+	return money.New((q*rate+(rem*rate+base/2)/base)*unit, amount.Currency)
+}
+
+// TaxRates looks up the tax rate of a product category.
+type TaxRates interface {
+	TaxRate(category string) TaxRate
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+// MinWebhookSecretLength is the shortest secret webhook deliveries can be
+// signed with.
+const MinWebhookSecretLength = 16
+
+// WebhookEventTypes lists the order events that webhooks can subscribe to.
+// Orders never return to the new status, so there is no OrderNew event.
+var WebhookEventTypes = func() []string {
+	types := []string{pkg.EventOrderCreated}
+	for _, status := range pkg.OrderStatuses() {
+		if status != pkg.OrderStatusNew {
+			types = append(types, status.EventType())
+		}
+	}
+
+	return types
+}()
+
+// IsWebhookEventType reports whether webhooks can subscribe to events of the
+// type.
+func IsWebhookEventType(eventType string) bool {
+	for _, known := range WebhookEventTypes {
+		if eventType == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookSubscription has the events of EventTypes posted to Url, signed with
+// Secret.
+type WebhookSubscription struct {
+	Id         string
+	Url        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  string
+	UpdatedAt  string
+}
+
+func (s *WebhookSubscription) Validate() error {
+	u, err := url.Parse(s.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return service.Errorf(service.INVALID_ERROR, "url must be an absolute http or https URL")
+	}
+
+	if len(s.Secret) < MinWebhookSecretLength {
+		return service.Errorf(service.INVALID_ERROR, "secret must be at least %d bytes", MinWebhookSecretLength)
+	}
+
+	if len(s.EventTypes) == 0 {
+		return service.Errorf(service.INVALID_ERROR, "at least one event type is required")
+	}
+
+	for _, eventType := range s.EventTypes {
+		if !IsWebhookEventType(eventType) {
+			return service.Errorf(service.INVALID_ERROR, "unknown event type %q", eventType)
+		}
+	}
+
+	return nil
+}
+
+// Subscribes reports whether the subscription receives events of the type.
+func (s *WebhookSubscription) Subscribes(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryStatusPending deliveries are attempted at their
+	// NextAttemptAt.
+	WebhookDeliveryStatusPending WebhookDeliveryStatus = "pending"
+
+	// WebhookDeliveryStatusSucceeded deliveries were accepted by the
+	// subscriber.
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+
+	// WebhookDeliveryStatusFailed deliveries ran out of attempts. They are
+	// kept as dead letters until they are replayed.
+	WebhookDeliveryStatusFailed WebhookDeliveryStatus = "failed"
+)
+
+// IsValid reports whether s is a known delivery status.
+func (s WebhookDeliveryStatus) IsValid() bool {
+	switch s {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusSucceeded, WebhookDeliveryStatusFailed:
+		return true
+	}
+
+	return false
+}
+
+// WebhookDelivery is an event to post to a subscription, and the log of the
+// attempts to post it. There is at most one delivery of an event to a
+// subscription.
+type WebhookDelivery struct {
+	Id             string
+	SubscriptionId string
+	EventId        string
+	EventType      string
+
+	// Payload is the body posted to the subscription.
+	Payload []byte
+
+	Status WebhookDeliveryStatus
+
+	// Attempts counts the attempts so far. The last one ended with
+	// LastResponseCode, which is 0 if no response was received, and
+	// LastError.
+	Attempts         uint
+	LastResponseCode int
+	LastError        string
+
+	// NextAttemptAt is the RFC3339 timestamp of the next attempt of a pending
+	// delivery.
+	NextAttemptAt string
+
+	CreatedAt string
+	UpdatedAt string
+}
+
+func (d *WebhookDelivery) Validate() error {
+	if d.SubscriptionId == "" {
+		return service.Errorf(service.INVALID_ERROR, "subscription id is required")
+	}
+
+	if d.EventId == "" {
+		return service.Errorf(service.INVALID_ERROR, "event id is required")
+	}
+
+	if d.EventType == "" {
+		return service.Errorf(service.INVALID_ERROR, "event type is required")
+	}
+
+	if !d.Status.IsValid() {
+		return service.Errorf(service.INVALID_ERROR, "unknown delivery status %q", d.Status)
+	}
+
+	if _, err := time.Parse(time.RFC3339, d.NextAttemptAt); err != nil {
+		return service.Errorf(service.INVALID_ERROR, "next_attempt_at must be an RFC3339 timestamp")
+	}
+
+	return nil
+}
+
+// WebhookDeliveryUpdate records an attempt of a delivery, or resets it.
+type WebhookDeliveryUpdate struct {
+	Status           WebhookDeliveryStatus
+	Attempts         uint
+	LastResponseCode int
+	LastError        string
+	NextAttemptAt    string
+}
+
+// ApplyUpdate changes the delivery as update requests.
+func (d *WebhookDelivery) ApplyUpdate(update *WebhookDeliveryUpdate) error {
+	d.Status = update.Status
+	d.Attempts = update.Attempts
+	d.LastResponseCode = update.LastResponseCode
+	d.LastError = update.LastError
+	d.NextAttemptAt = update.NextAttemptAt
+
+	return d.Validate()
+}
+
+// WebhookDeliverySortFields are the fields ListWebhookDeliveries can order by.
+var WebhookDeliverySortFields = []string{"created_at", "updated_at"}
+
+// SortValue returns the value of a field in WebhookDeliverySortFields in a
+// form that sorts like the field itself.
+func (d *WebhookDelivery) SortValue(field string) string {
+	switch field {
+	case "updated_at":
+		return sortableTime(d.UpdatedAt)
+	default:
+		return sortableTime(d.CreatedAt)
+	}
+}
+
+// WebhookDeliveryFilter restricts ListWebhookDeliveries to the matching
+// deliveries. Unset fields match every delivery.
+type WebhookDeliveryFilter struct {
+	SubscriptionId string
+	Status         WebhookDeliveryStatus
+}
+
+func (f *WebhookDeliveryFilter) Validate() error {
+	if f.Status != "" && !f.Status.IsValid() {
+		return service.Errorf(service.INVALID_ERROR, "unknown delivery status %q", f.Status)
+	}
+
+	return nil
+}
+
+// Match reports whether the delivery passes the filter.
+func (f *WebhookDeliveryFilter) Match(d *WebhookDelivery) bool {
+	if f.SubscriptionId != "" && d.SubscriptionId != f.SubscriptionId {
+		return false
+	}
+
+	if f.Status != "" && d.Status != f.Status {
+		return false
+	}
+
+	return true
+}
+
+type WebhookRepository interface {
+	CreateWebhookSubscription(ctx context.Context, subscription *WebhookSubscription) (*WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id string) (*WebhookSubscription, error)
+
+	// ListWebhookSubscriptions returns every subscription, oldest first.
+	ListWebhookSubscriptions(ctx context.Context) ([]*WebhookSubscription, error)
+
+	// DeleteWebhookSubscription removes the subscription. Its deliveries are
+	// kept, but no longer attempted.
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+
+	// CreateWebhookDelivery fails with ALREADY_EXISTS_ERROR if the event is
+	// already delivered to the subscription.
+	CreateWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) (*WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+
+	// ListWebhookDeliveries returns a page of the deliveries that match the
+	// filter, together with the token of the next page, or an empty token if
+	// this is the last page. A nil filter matches every delivery.
+	ListWebhookDeliveries(
+		ctx context.Context, filter *WebhookDeliveryFilter, opts *ListOptions) ([]*WebhookDelivery, string, error)
+
+	// ListDueWebhookDeliveries returns up to limit pending deliveries whose
+	// next attempt is due at the given time, the longest due first.
+	ListDueWebhookDeliveries(ctx context.Context, at time.Time, limit int) ([]*WebhookDelivery, error)
+
+	UpdateWebhookDelivery(
+		ctx context.Context, id string, update *WebhookDeliveryUpdate) (*WebhookDelivery, error)
+}
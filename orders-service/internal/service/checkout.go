@@ -4,12 +4,24 @@ import (
 	"context"
 
 	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 )
 
 type OrderItem struct {
-	Id        string `json:"id"`
-	ProductId string `json:"product_id"`
-	Quantity  uint   `json:"quantity"`
+	Id        string      `json:"id"`
+	ProductId string      `json:"product_id"`
+	Quantity  uint        `json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"`
+	LineTotal money.Money `json:"line_total"`
+
+	// Tax is the tax on LineTotal at TaxRate basis points, see
+	// repository.TaxRate.
+	TaxCategory  string      `json:"tax_category"`
+	TaxRate      uint        `json:"tax_rate"`
+	TaxExempt    bool        `json:"tax_exempt"`
+	TaxInclusive bool        `json:"tax_inclusive"`
+	Tax          money.Money `json:"tax"`
+
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -19,10 +31,31 @@ type Order struct {
 	CustomerId  string          `json:"customer_id"`
 	Items       []*OrderItem    `json:"items"`
 	OrderStatus pkg.OrderStatus `json:"order_status"`
-	CreatedAt   string          `json:"created_at"`
-	UpdatedAt   string          `json:"updated_at"`
+
+	// Total is the amount charged for the order, net of the discount of the
+	// promotion with PromotionCode, if any.
+	Subtotal      money.Money `json:"subtotal"`
+	Tax           money.Money `json:"tax"`
+	Discount      money.Money `json:"discount"`
+	Total         money.Money `json:"total"`
+	PromotionCode string      `json:"promotion_code"`
+
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
 }
 
 type CheckoutService interface {
 	ProcessCheckout(ctx context.Context, orderID string) (*Order, error)
 }
+
+type PromotionService interface {
+	// ApplyPromotion applies the promotion with the code to an order that has
+	// not been checked out yet, replacing any promotion applied before.
+	ApplyPromotion(ctx context.Context, orderId string, code string) (*Order, error)
+}
+
+type WebhookService interface {
+	// ReplayWebhookDelivery schedules a dead-lettered delivery to be attempted
+	// again right away, with a fresh set of attempts.
+	ReplayWebhookDelivery(ctx context.Context, id string) error
+}
@@ -7,11 +7,14 @@ import (
 
 // Application error codes.
 const (
-	ALREADY_EXISTS_ERROR  = "already_exists"
-	INTERNAL_ERROR        = "internal"
-	INVALID_ERROR         = "invalid"
-	NOT_FOUND_ERROR       = "not_found"
-	NOT_IMPLEMENTED_ERROR = "not_implemented"
+	ALREADY_EXISTS_ERROR      = "already_exists"
+	CONFLICT_ERROR            = "conflict"
+	FAILED_PRECONDITION_ERROR = "failed_precondition"
+	INTERNAL_ERROR            = "internal"
+	INVALID_ERROR             = "invalid"
+	NOT_FOUND_ERROR           = "not_found"
+	NOT_IMPLEMENTED_ERROR     = "not_implemented"
+	OUT_OF_STOCK_ERROR        = "out_of_stock"
 )
 
 // Error represents an application-specific error. Application errors can be
@@ -0,0 +1,108 @@
+// Package tax configures the tax rates of product categories.
+package tax
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+)
+
+const (
+	// DefaultCategory names the rate of products whose category has no rate
+	// of its own, including products without a category.
+	DefaultCategory = "default"
+
+	// Exempt is the rate of categories that are not taxable.
+	Exempt = "exempt"
+
+	// DefaultRates is the standard rate of Kenyan VAT.
+	DefaultRates = DefaultCategory + "=16"
+)
+
+var _ repository.TaxRates = (*Table)(nil)
+
+// Table holds the tax rates of product categories.
+type Table struct {
+	inclusive  bool
+	rates      map[string]repository.TaxRate
+	defaultTax repository.TaxRate
+}
+
+// ParseTable parses rates of the form "default=16,food=0,medical=exempt",
+// where each rate is a percentage with up to two decimals or "exempt". The
+// default rate is 0 unless given. If inclusive is set, prices include the tax.
+func ParseTable(rates string, inclusive bool) (*Table, error) {
+	t := &Table{
+		inclusive: inclusive,
+		rates:     make(map[string]repository.TaxRate),
+	}
+
+	for _, entry := range strings.Split(rates, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		category, value, ok := strings.Cut(entry, "=")
+		category, value = strings.TrimSpace(category), strings.TrimSpace(value)
+		if !ok || category == "" {
+			return nil, fmt.Errorf("invalid tax rate %q, want category=rate", entry)
+		}
+
+		if _, ok := t.rates[category]; ok {
+			return nil, fmt.Errorf("duplicate tax rate for category %q", category)
+		}
+
+		rate, err := parseRate(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tax rate of category %q: %w", category, err)
+		}
+
+		rate.Category = category
+		rate.Inclusive = inclusive
+		t.rates[category] = rate
+	}
+
+	t.defaultTax = t.rates[DefaultCategory]
+	t.defaultTax.Inclusive = inclusive
+
+	return t, nil
+}
+
+// parseRate parses "exempt" or a percentage into a rate in basis points.
+func parseRate(s string) (repository.TaxRate, error) {
+	if s == Exempt {
+		return repository.TaxRate{Exempt: true}, nil
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if len(fraction) > 2 {
+		return repository.TaxRate{}, fmt.Errorf("%q has more than two decimals", s)
+	}
+
+	basisPoints, err := strconv.ParseUint(whole+fraction+strings.Repeat("0", 2-len(fraction)), 10, 32)
+	if err != nil || whole == "" {
+		return repository.TaxRate{}, fmt.Errorf("%q is not a percentage", s)
+	}
+
+	rate := repository.TaxRate{Rate: uint(basisPoints)}
+	if err := rate.Validate(); err != nil {
+		return repository.TaxRate{}, err
+	}
+
+	return rate, nil
+}
+
+// TaxRate returns the rate of the category, or the default rate if the
+// category has none.
+func (t *Table) TaxRate(category string) repository.TaxRate {
+	rate, ok := t.rates[category]
+	if !ok {
+		rate = t.defaultTax
+		rate.Category = category
+	}
+
+	return rate
+}
@@ -0,0 +1,95 @@
+package tax_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/tax"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func TestParseTable(t *testing.T) {
+	table, err := tax.ParseTable("default=16, food=0, medical=exempt, fuel=8.5", true)
+	if err != nil {
+		t.Fatalf("ParseTable() error = %v", err)
+	}
+
+	tests := []struct {
+		category string
+		want     repository.TaxRate
+	}{
+		{"", repository.TaxRate{Rate: 1600, Inclusive: true}},
+		{"toys", repository.TaxRate{Category: "toys", Rate: 1600, Inclusive: true}},
+		{"food", repository.TaxRate{Category: "food", Rate: 0, Inclusive: true}},
+		{"medical", repository.TaxRate{Category: "medical", Exempt: true, Inclusive: true}},
+		{"fuel", repository.TaxRate{Category: "fuel", Rate: 850, Inclusive: true}},
+	}
+	for _, tt := range tests {
+		if got := table.TaxRate(tt.category); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TaxRate(%q) = %+v, want %+v", tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestParseTable_NoDefault(t *testing.T) {
+	table, err := tax.ParseTable("", false)
+	if err != nil {
+		t.Fatalf("ParseTable() error = %v", err)
+	}
+
+	want := repository.TaxRate{Category: "toys"}
+	if got := table.TaxRate("toys"); !reflect.DeepEqual(got, want) {
+		t.Errorf("TaxRate() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseTable_Invalid(t *testing.T) {
+	for _, rates := range []string{
+		"16",
+		"=16",
+		"default=",
+		"default=sixteen",
+		"default=-1",
+		"default=16.125",
+		"default=101",
+		"default=16,default=8",
+	} {
+		if _, err := tax.ParseTable(rates, true); err == nil {
+			t.Errorf("ParseTable(%q) error = nil, want an error", rates)
+		}
+	}
+}
+
+func TestTaxRate_Tax(t *testing.T) {
+	tests := []struct {
+		name   string
+		rate   repository.TaxRate
+		amount money.Money
+		want   int64
+	}{
+		{"Exclusive", repository.TaxRate{Rate: 1600}, money.New(1000, "USD"), 160},
+		{"Exclusive Rounded Down", repository.TaxRate{Rate: 1600}, money.New(1003, "USD"), 160},
+		{"Exclusive Rounded Up", repository.TaxRate{Rate: 1600}, money.New(1004, "USD"), 161},
+		{"Exclusive Rounded Half Up", repository.TaxRate{Rate: 1000}, money.New(5, "USD"), 1},
+		// KES is charged in whole shillings, so the tax added on top is too.
+		{"Exclusive Shillings", repository.TaxRate{Rate: 1600}, money.New(100000, "KES"), 16000},
+		{"Exclusive Shillings Rounded Down", repository.TaxRate{Rate: 1600}, money.New(102500, "KES"), 16400},
+		{"Exclusive Shillings Rounded Up", repository.TaxRate{Rate: 1600}, money.New(103000, "KES"), 16500},
+		{"Exclusive Shillings Rounded Half Up", repository.TaxRate{Rate: 1000}, money.New(500, "KES"), 100},
+		{"Inclusive", repository.TaxRate{Rate: 1600, Inclusive: true}, money.New(1160, "KES"), 160},
+		{"Inclusive Rounded", repository.TaxRate{Rate: 1600, Inclusive: true}, money.New(1000, "KES"), 138},
+		{"Zero Rated", repository.TaxRate{Rate: 0}, money.New(1000, "KES"), 0},
+		{"Exempt", repository.TaxRate{Exempt: true}, money.New(1000, "KES"), 0},
+		{"Large Amount", repository.TaxRate{Rate: 1600}, money.New(1<<60, "USD"), 184467440737095516},
+		{"Large Amount Shillings", repository.TaxRate{Rate: 1600}, money.New(1<<60, "KES"), 184467440737095500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.rate.Tax(tt.amount)
+			if want := money.New(tt.want, tt.amount.Currency); got != want {
+				t.Errorf("Tax(%v) = %v, want %v", tt.amount, got, want)
+			}
+		})
+	}
+}
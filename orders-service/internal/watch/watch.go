@@ -0,0 +1,156 @@
+// Package watch fans the order events published from the outbox out to the
+// clients watching the orders, e.g. the WatchOrder stream of a storefront
+// waiting for a payment to land.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+// DefaultBufferSize is how many events a subscription holds for a watcher
+// that has not caught up yet.
+const DefaultBufferSize = 64
+
+// ErrOverflow ends a subscription whose watcher fell more than the buffer size
+// behind. Watchers resume from the last version they have seen.
+var ErrOverflow = errors.New("watcher fell behind")
+
+// Filter selects the orders a subscription receives events of. Unset fields
+// match every order.
+type Filter struct {
+	OrderId    string
+	CustomerId string
+}
+
+func (f Filter) match(order *repository.Order) bool {
+	if f.OrderId != "" && order.Id != f.OrderId {
+		return false
+	}
+
+	if f.CustomerId != "" && order.CustomerId != f.CustomerId {
+		return false
+	}
+
+	return true
+}
+
+// Broker delivers order events to its subscriptions. Events are only seen by
+// the subscriptions of the process whose outbox relay published them.
+type Broker struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription]struct{}
+
+	// BufferSize defaults to DefaultBufferSize.
+	BufferSize int
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscriptions: make(map[*Subscription]struct{}),
+		BufferSize:    DefaultBufferSize,
+	}
+}
+
+// Subscribe returns a subscription to the events of the orders that match the
+// filter, published from now on. The caller must close it.
+func (b *Broker) Subscribe(filter Filter) *Subscription {
+	size := b.BufferSize
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+
+	s := &Subscription{
+		broker: b,
+		filter: filter,
+		events: make(chan *repository.OrderEvent, size),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscriptions[s] = struct{}{}
+
+	return s
+}
+
+// HandleEvent delivers an order event to the matching subscriptions. It is an
+// outbox.Handler. It never blocks: subscriptions that are full are ended with
+// ErrOverflow.
+func (b *Broker) HandleEvent(ctx context.Context, event *outbox.Event) error {
+	if event.AggregateType != repository.OrderAggregate {
+		return nil
+	}
+
+	var payload repository.OrderEvent
+	if err := json.Unmarshal(event.Payload, &payload); err != nil || payload.Order == nil {
+		// Publishing the event again would not help.
+		log.Printf("[watch] dropping event %s with invalid payload: %v", event.Id, err)
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscriptions {
+		if !s.filter.match(payload.Order) {
+			continue
+		}
+
+		select {
+		case s.events <- &payload:
+		default:
+			s.end(ErrOverflow)
+		}
+	}
+
+	return nil
+}
+
+// Subscription receives the events of the orders that match its filter.
+type Subscription struct {
+	broker *Broker
+	filter Filter
+	events chan *repository.OrderEvent
+	err    error
+}
+
+// Events returns the channel of events. It is closed when the subscription
+// ends, after which Err tells why.
+func (s *Subscription) Events() <-chan *repository.OrderEvent {
+	return s.events
+}
+
+// Err returns ErrOverflow if the subscription was ended because it was full,
+// and nil otherwise.
+func (s *Subscription) Err() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	return s.err
+}
+
+// Close ends the subscription. It may be called more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.end(nil)
+}
+
+// end removes the subscription from the broker, whose lock must be held.
+func (s *Subscription) end(err error) {
+	if _, ok := s.broker.subscriptions[s]; !ok {
+		return
+	}
+
+	delete(s.broker.subscriptions, s)
+	s.err = err
+	close(s.events)
+}
@@ -0,0 +1,110 @@
+package watch_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/watch"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+func newTestEvent(t *testing.T, orderId string, customerId string, version uint) *outbox.Event {
+	t.Helper()
+
+	order := &repository.Order{Id: orderId, CustomerId: customerId, OrderStatus: pkg.OrderStatusPaid, Version: version}
+	event, err := repository.NewOrderStatusEvent(order, &repository.OrderStatusChange{
+		OrderId:    orderId,
+		FromStatus: pkg.OrderStatusPending,
+		ToStatus:   pkg.OrderStatusPaid,
+		Version:    version,
+	})
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+
+	return event
+}
+
+func TestBroker_HandleEvent(t *testing.T) {
+	ctx := context.Background()
+	b := watch.NewBroker()
+
+	order := b.Subscribe(watch.Filter{OrderId: "order-1"})
+	defer order.Close()
+
+	customer := b.Subscribe(watch.Filter{CustomerId: "customer-1"})
+	defer customer.Close()
+
+	for _, event := range []*outbox.Event{
+		newTestEvent(t, "order-1", "customer-1", 1),
+		newTestEvent(t, "order-2", "customer-1", 1),
+		newTestEvent(t, "order-3", "customer-2", 1),
+		{Id: "payment", Type: "PaymentCreated", AggregateType: "payment", Payload: []byte(`{}`)},
+	} {
+		if err := b.HandleEvent(ctx, event); err != nil {
+			t.Fatalf("HandleEvent() error = %v", err)
+		}
+	}
+
+	if got := len(order.Events()); got != 1 {
+		t.Errorf("order subscription received %d events, want 1", got)
+	}
+
+	if got := len(customer.Events()); got != 2 {
+		t.Errorf("customer subscription received %d events, want 2", got)
+	}
+
+	e := <-order.Events()
+	if e.Order.Id != "order-1" || e.Change == nil || e.Change.ToStatus != pkg.OrderStatusPaid || e.Change.Version != 1 {
+		t.Errorf("order subscription received %+v, want the change of order-1 to paid", e)
+	}
+}
+
+func TestBroker_HandleEvent_Overflow(t *testing.T) {
+	ctx := context.Background()
+	b := watch.NewBroker()
+	b.BufferSize = 1
+
+	s := b.Subscribe(watch.Filter{OrderId: "order-1"})
+	defer s.Close()
+
+	for version := uint(1); version <= 2; version++ {
+		if err := b.HandleEvent(ctx, newTestEvent(t, "order-1", "customer-1", version)); err != nil {
+			t.Fatalf("HandleEvent() error = %v", err)
+		}
+	}
+
+	if e, ok := <-s.Events(); !ok || e.Change.Version != 1 {
+		t.Errorf("subscription received %+v, want version 1", e)
+	}
+
+	if _, ok := <-s.Events(); ok {
+		t.Errorf("subscription was not ended")
+	}
+
+	if err := s.Err(); err != watch.ErrOverflow {
+		t.Errorf("Err() = %v, want %v", err, watch.ErrOverflow)
+	}
+}
+
+func TestSubscription_Close(t *testing.T) {
+	b := watch.NewBroker()
+
+	s := b.Subscribe(watch.Filter{})
+	s.Close()
+	s.Close()
+
+	if err := b.HandleEvent(context.Background(), newTestEvent(t, "order-1", "customer-1", 1)); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	if _, ok := <-s.Events(); ok {
+		t.Errorf("closed subscription received an event")
+	}
+
+	if err := s.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,349 @@
+// Package webhooks posts order events to the systems of merchants that
+// subscribe to them, such as their ERP.
+//
+// Every event is posted as the JSON encoded outbox.Event, with these headers:
+//
+//	X-Webhook-Id:        the id of the event, for receivers to drop duplicates
+//	X-Webhook-Event:     the event type, e.g. OrderPaid
+//	X-Webhook-Timestamp: the Unix time of the attempt
+//	X-Webhook-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// The signature is keyed with the secret of the subscription. A delivery
+// succeeds on a 2xx response, and is otherwise retried with exponential
+// backoff until it runs out of attempts and is dead-lettered.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+const (
+	IdHeader        = "X-Webhook-Id"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a delivery is attempted before it
+	// is dead-lettered.
+	DefaultMaxAttempts = 8
+
+	// DefaultInitialBackoff is the wait after the first failed attempt. It
+	// doubles after every further failure, up to DefaultMaxBackoff.
+	DefaultInitialBackoff = 30 * time.Second
+	DefaultMaxBackoff     = time.Hour
+
+	// DefaultInterval is how often a WebhookService looks for due
+	// deliveries.
+	DefaultInterval = 5 * time.Second
+
+	// DefaultBatchSize is how many due deliveries a WebhookService reads at
+	// once.
+	DefaultBatchSize = 100
+
+	// DefaultTimeout bounds every attempt.
+	DefaultTimeout = 10 * time.Second
+)
+
+var _ service.WebhookService = (*WebhookService)(nil)
+
+// WebhookService records a delivery of every order event to every webhook
+// subscription of its type, and attempts the deliveries as they fall due.
+type WebhookService struct {
+	webhookRepository repository.WebhookRepository
+
+	// Client defaults to an http.Client with DefaultTimeout.
+	Client *http.Client
+
+	MaxAttempts    uint
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Interval       time.Duration
+	BatchSize      int
+}
+
+func NewWebhookService(webhookRepository repository.WebhookRepository) *WebhookService {
+	return &WebhookService{
+		webhookRepository: webhookRepository,
+		Client:            &http.Client{Timeout: DefaultTimeout},
+		MaxAttempts:       DefaultMaxAttempts,
+		InitialBackoff:    DefaultInitialBackoff,
+		MaxBackoff:        DefaultMaxBackoff,
+		Interval:          DefaultInterval,
+		BatchSize:         DefaultBatchSize,
+	}
+}
+
+func (s *WebhookService) CheckPreconditions() {
+	if s.webhookRepository == nil {
+		panic("webhookRepository is required")
+	}
+}
+
+// HandleEvent records a delivery of an order event to every subscription of
+// its type. It is an outbox.Handler; events that are published again are
+// not delivered again.
+func (s *WebhookService) HandleEvent(ctx context.Context, event *outbox.Event) error {
+	s.CheckPreconditions()
+
+	if event.AggregateType != repository.OrderAggregate {
+		return nil
+	}
+
+	subscriptions, err := s.webhookRepository.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(event.Type) {
+			continue
+		}
+
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return service.Errorf(service.INTERNAL_ERROR, "failed to marshal event %s: %v", event.Id, err)
+			}
+		}
+
+		_, err := s.webhookRepository.CreateWebhookDelivery(ctx, &repository.WebhookDelivery{
+			SubscriptionId: subscription.Id,
+			EventId:        event.Id,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         repository.WebhookDeliveryStatusPending,
+			NextAttemptAt:  time.Now().Format(time.RFC3339),
+		})
+		if err != nil && service.ErrorCode(err) != service.ALREADY_EXISTS_ERROR {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run attempts due deliveries until ctx is done. Failures are logged and
+// retried after the interval.
+func (s *WebhookService) Run(ctx context.Context) error {
+	s.CheckPreconditions()
+
+	ticker := time.NewTicker(s.interval())
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[webhooks] failed to deliver webhooks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue attempts every delivery that is due, and returns how many it
+// attempted. A failed attempt is not an error; it is recorded with the
+// delivery.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	s.CheckPreconditions()
+
+	attempted := 0
+	for {
+		deliveries, err := s.webhookRepository.ListDueWebhookDeliveries(ctx, time.Now(), s.batchSize())
+		if err != nil {
+			return attempted, err
+		}
+
+		for _, delivery := range deliveries {
+			if err := s.deliver(ctx, delivery); err != nil {
+				return attempted, err
+			}
+
+			attempted++
+		}
+
+		if len(deliveries) < s.batchSize() {
+			return attempted, nil
+		}
+	}
+}
+
+// deliver attempts the delivery and records the outcome.
+func (s *WebhookService) deliver(ctx context.Context, delivery *repository.WebhookDelivery) error {
+	update := &repository.WebhookDeliveryUpdate{
+		Attempts:      delivery.Attempts + 1,
+		NextAttemptAt: delivery.NextAttemptAt,
+	}
+
+	subscription, err := s.webhookRepository.GetWebhookSubscription(ctx, delivery.SubscriptionId)
+	switch {
+	case service.ErrorCode(err) == service.NOT_FOUND_ERROR:
+		update.Status = repository.WebhookDeliveryStatusFailed
+		update.LastError = "webhook subscription was deleted"
+	case err != nil:
+		return err
+	default:
+		update.LastResponseCode, err = s.post(ctx, subscription, delivery)
+		if err == nil {
+			update.Status = repository.WebhookDeliveryStatusSucceeded
+		} else if update.LastError = err.Error(); update.Attempts >= s.maxAttempts() {
+			update.Status = repository.WebhookDeliveryStatusFailed
+		} else {
+			update.Status = repository.WebhookDeliveryStatusPending
+			update.NextAttemptAt = time.Now().Add(s.backoff(update.Attempts)).Format(time.RFC3339)
+		}
+	}
+
+	if update.Status == repository.WebhookDeliveryStatusFailed {
+		log.Printf("[webhooks] delivery %s of event %s failed after %d attempts: %s",
+			delivery.Id, delivery.EventId, update.Attempts, update.LastError)
+	}
+
+	_, err = s.webhookRepository.UpdateWebhookDelivery(ctx, delivery.Id, update)
+	return err
+}
+
+// post sends the delivery to the subscription and returns the response code,
+// or 0 if there was no response. It fails unless the code is 2xx.
+func (s *WebhookService) post(ctx context.Context,
+	subscription *repository.WebhookSubscription, delivery *repository.WebhookDelivery) (int, error) {
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdHeader, delivery.EventId)
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// ReplayWebhookDelivery fails with FAILED_PRECONDITION_ERROR unless the
+// delivery is dead-lettered. The outcome of its last attempt is kept until
+// the next one.
+func (s *WebhookService) ReplayWebhookDelivery(ctx context.Context, id string) error {
+	s.CheckPreconditions()
+
+	delivery, err := s.webhookRepository.GetWebhookDelivery(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if delivery.Status != repository.WebhookDeliveryStatusFailed {
+		return service.Errorf(service.FAILED_PRECONDITION_ERROR,
+			"only failed deliveries can be replayed, delivery is %s", delivery.Status)
+	}
+
+	_, err = s.webhookRepository.UpdateWebhookDelivery(ctx, id, &repository.WebhookDeliveryUpdate{
+		Status:           repository.WebhookDeliveryStatusPending,
+		LastResponseCode: delivery.LastResponseCode,
+		LastError:        delivery.LastError,
+		NextAttemptAt:    time.Now().Format(time.RFC3339),
+	})
+
+	return err
+}
+
+// Sign returns the signature of a delivery posted at the Unix timestamp, as
+// sent in the X-Webhook-Signature header.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (s *WebhookService) backoff(attempts uint) time.Duration {
+	backoff := s.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultInitialBackoff
+	}
+
+	maxBackoff := s.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	for i := uint(1); i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+
+	return backoff
+}
+
+func (s *WebhookService) client() *http.Client {
+	if s.Client == nil {
+		return &http.Client{Timeout: DefaultTimeout}
+	}
+
+	return s.Client
+}
+
+func (s *WebhookService) maxAttempts() uint {
+	if s.MaxAttempts == 0 {
+		return DefaultMaxAttempts
+	}
+
+	return s.MaxAttempts
+}
+
+func (s *WebhookService) interval() time.Duration {
+	if s.Interval <= 0 {
+		return DefaultInterval
+	}
+
+	return s.Interval
+}
+
+func (s *WebhookService) batchSize() int {
+	if s.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+
+	return s.BatchSize
+}
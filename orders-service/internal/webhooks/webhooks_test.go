@@ -0,0 +1,299 @@
+package webhooks_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/orders/internal/memory"
+	"github.com/Mik3y-F/order-management-system/orders/internal/repository"
+	"github.com/Mik3y-F/order-management-system/orders/internal/service"
+	"github.com/Mik3y-F/order-management-system/orders/internal/webhooks"
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+const secret = "0123456789abcdef"
+
+// receiver records the deliveries posted to it and answers with its status
+// code.
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	w.WriteHeader(r.status)
+}
+
+// setup returns a webhook service backed by a memory repository, with a
+// subscription to OrderPaid events that are posted to a receiver answering
+// with status.
+func setup(t *testing.T, status int) (*webhooks.WebhookService, repository.WebhookRepository, *receiver, *repository.WebhookSubscription) {
+	t.Helper()
+
+	rcv := &receiver{status: status}
+	server := httptest.NewServer(rcv)
+	t.Cleanup(server.Close)
+
+	r := memory.NewWebhookRepository()
+
+	subscription, err := r.CreateWebhookSubscription(context.Background(), &repository.WebhookSubscription{
+		Url:        server.URL,
+		Secret:     secret,
+		EventTypes: []string{pkg.OrderStatusPaid.EventType()},
+	})
+	if err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	s := webhooks.NewWebhookService(r)
+	s.Client = server.Client()
+	s.MaxAttempts = 2
+
+	return s, r, rcv, subscription
+}
+
+func newTestEvent(t *testing.T, eventType string) *outbox.Event {
+	t.Helper()
+
+	event, err := outbox.NewEvent(eventType, repository.OrderAggregate, "order-1", map[string]string{"id": "order-1"})
+	if err != nil {
+		t.Fatalf("failed to create event: %v", err)
+	}
+
+	event.Id = "event-" + eventType
+	event.CreatedAt = "2024-01-01T00:00:00Z"
+
+	return event
+}
+
+func listDeliveries(t *testing.T, r repository.WebhookRepository) []*repository.WebhookDelivery {
+	t.Helper()
+
+	deliveries, _, err := r.ListWebhookDeliveries(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+
+	return deliveries
+}
+
+// makeDue moves the next attempt of the delivery to now.
+func makeDue(t *testing.T, r repository.WebhookRepository, d *repository.WebhookDelivery) {
+	t.Helper()
+
+	_, err := r.UpdateWebhookDelivery(context.Background(), d.Id, &repository.WebhookDeliveryUpdate{
+		Status:           d.Status,
+		Attempts:         d.Attempts,
+		LastResponseCode: d.LastResponseCode,
+		LastError:        d.LastError,
+		NextAttemptAt:    time.Now().Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatalf("failed to update delivery: %v", err)
+	}
+}
+
+func TestWebhookService_HandleEvent(t *testing.T) {
+	ctx := context.Background()
+	s, r, _, subscription := setup(t, http.StatusOK)
+
+	paid := newTestEvent(t, pkg.OrderStatusPaid.EventType())
+	for _, event := range []*outbox.Event{
+		paid,
+		paid, // published again
+		newTestEvent(t, pkg.EventOrderCreated),
+		{Id: "payment-event", Type: pkg.OrderStatusPaid.EventType(), AggregateType: "payment"},
+	} {
+		if err := s.HandleEvent(ctx, event); err != nil {
+			t.Fatalf("HandleEvent() error = %v", err)
+		}
+	}
+
+	deliveries := listDeliveries(t, r)
+	if len(deliveries) != 1 {
+		t.Fatalf("HandleEvent() recorded %d deliveries, want 1", len(deliveries))
+	}
+
+	d := deliveries[0]
+	if d.SubscriptionId != subscription.Id || d.EventId != paid.Id || d.EventType != paid.Type ||
+		d.Status != repository.WebhookDeliveryStatusPending {
+		t.Errorf("HandleEvent() recorded %+v, want a pending delivery of %s", d, paid.Id)
+	}
+}
+
+func TestWebhookService_DeliverDue(t *testing.T) {
+	ctx := context.Background()
+	s, r, rcv, _ := setup(t, http.StatusNoContent)
+
+	if err := s.HandleEvent(ctx, newTestEvent(t, pkg.OrderStatusPaid.EventType())); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	n, err := s.DeliverDue(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("DeliverDue() = %d, %v, want 1 delivery", n, err)
+	}
+
+	if len(rcv.requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(rcv.requests))
+	}
+
+	req, body := rcv.requests[0], rcv.bodies[0]
+	if req.Method != http.MethodPost {
+		t.Errorf("request method = %s, want POST", req.Method)
+	}
+	if got := req.Header.Get(webhooks.EventHeader); got != "OrderPaid" {
+		t.Errorf("%s = %q, want OrderPaid", webhooks.EventHeader, got)
+	}
+	if got := req.Header.Get(webhooks.IdHeader); got != "event-OrderPaid" {
+		t.Errorf("%s = %q, want event-OrderPaid", webhooks.IdHeader, got)
+	}
+
+	timestamp := req.Header.Get(webhooks.TimestampHeader)
+	if got, want := req.Header.Get(webhooks.SignatureHeader), webhooks.Sign(secret, timestamp, body); got != want {
+		t.Errorf("%s = %q, want %q", webhooks.SignatureHeader, got, want)
+	}
+
+	d := listDeliveries(t, r)[0]
+	if d.Status != repository.WebhookDeliveryStatusSucceeded || d.Attempts != 1 || d.LastResponseCode != 204 {
+		t.Errorf("delivery = %+v, want succeeded after 1 attempt with 204", d)
+	}
+
+	// Deliveries are attempted once they are due.
+	if n, err := s.DeliverDue(ctx); err != nil || n != 0 {
+		t.Errorf("DeliverDue() = %d, %v, want no deliveries", n, err)
+	}
+}
+
+func TestWebhookService_DeliverDue_Retries(t *testing.T) {
+	ctx := context.Background()
+	s, r, rcv, _ := setup(t, http.StatusServiceUnavailable)
+
+	if err := s.HandleEvent(ctx, newTestEvent(t, pkg.OrderStatusPaid.EventType())); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	start := time.Now()
+	if _, err := s.DeliverDue(ctx); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	d := listDeliveries(t, r)[0]
+	if d.Status != repository.WebhookDeliveryStatusPending || d.Attempts != 1 || d.LastResponseCode != 503 {
+		t.Fatalf("delivery = %+v, want pending after 1 attempt with 503", d)
+	}
+
+	nextAttemptAt, _ := time.Parse(time.RFC3339, d.NextAttemptAt)
+	if want := start.Add(webhooks.DefaultInitialBackoff).Truncate(time.Second); nextAttemptAt.Before(want) {
+		t.Errorf("delivery NextAttemptAt = %s, want at least %s", d.NextAttemptAt, want)
+	}
+
+	// The last attempt dead-letters the delivery.
+	makeDue(t, r, d)
+	if _, err := s.DeliverDue(ctx); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	d = listDeliveries(t, r)[0]
+	if d.Status != repository.WebhookDeliveryStatusFailed || d.Attempts != 2 || d.LastError == "" {
+		t.Errorf("delivery = %+v, want failed after 2 attempts", d)
+	}
+
+	if len(rcv.requests) != 2 {
+		t.Errorf("receiver got %d requests, want 2", len(rcv.requests))
+	}
+}
+
+func TestWebhookService_DeliverDue_DeletedSubscription(t *testing.T) {
+	ctx := context.Background()
+	s, r, rcv, subscription := setup(t, http.StatusOK)
+
+	if err := s.HandleEvent(ctx, newTestEvent(t, pkg.OrderStatusPaid.EventType())); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	if err := r.DeleteWebhookSubscription(ctx, subscription.Id); err != nil {
+		t.Fatalf("failed to delete subscription: %v", err)
+	}
+
+	if _, err := s.DeliverDue(ctx); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	if d := listDeliveries(t, r)[0]; d.Status != repository.WebhookDeliveryStatusFailed {
+		t.Errorf("delivery = %+v, want failed", d)
+	}
+
+	if len(rcv.requests) != 0 {
+		t.Errorf("receiver got %d requests, want none", len(rcv.requests))
+	}
+}
+
+func TestWebhookService_ReplayWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+	s, r, rcv, _ := setup(t, http.StatusInternalServerError)
+	s.MaxAttempts = 1
+
+	if err := s.HandleEvent(ctx, newTestEvent(t, pkg.OrderStatusPaid.EventType())); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	d := listDeliveries(t, r)[0]
+
+	err := s.ReplayWebhookDelivery(ctx, d.Id)
+	if code := service.ErrorCode(err); code != service.FAILED_PRECONDITION_ERROR {
+		t.Errorf("ReplayWebhookDelivery() of a pending delivery error = %v, want %s", err, service.FAILED_PRECONDITION_ERROR)
+	}
+
+	if _, err := s.DeliverDue(ctx); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	if err := s.ReplayWebhookDelivery(ctx, d.Id); err != nil {
+		t.Fatalf("ReplayWebhookDelivery() error = %v", err)
+	}
+
+	d = listDeliveries(t, r)[0]
+	if d.Status != repository.WebhookDeliveryStatusPending || d.Attempts != 0 || d.LastResponseCode != 500 {
+		t.Errorf("replayed delivery = %+v, want pending with the outcome of its last attempt", d)
+	}
+
+	rcv.status = http.StatusOK
+	if _, err := s.DeliverDue(ctx); err != nil {
+		t.Fatalf("DeliverDue() error = %v", err)
+	}
+
+	if d := listDeliveries(t, r)[0]; d.Status != repository.WebhookDeliveryStatusSucceeded {
+		t.Errorf("delivery = %+v, want succeeded", d)
+	}
+
+	err = s.ReplayWebhookDelivery(ctx, "non-existent-id")
+	if code := service.ErrorCode(err); code != service.NOT_FOUND_ERROR {
+		t.Errorf("ReplayWebhookDelivery() of a missing delivery error = %v, want %s", err, service.NOT_FOUND_ERROR)
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac 0123456789abcdef
+	want := "sha256=e4f8e2ecae2295b2ddb2f0b5584c8275e226c0ebe9b3b819e70156bb67122e3e"
+
+	if got := webhooks.Sign(secret, "1700000000", []byte("{}")); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
@@ -10,7 +10,14 @@ type HealthCheckResponse = pb.HealthCheckResponse
 type UpdateOrderStatusRequest = pb.UpdateOrderStatusRequest
 type UpdateOrderStatusResponse = pb.UpdateOrderStatusResponse
 
+type OrderStatus = pb.OrderStatus
+
+var OrderStatusNew = pb.OrderStatus_NEW
+var OrderStatusProcessing = pb.OrderStatus_PROCESSING
 var OrderStatusPaid = pb.OrderStatus_PAID
 var OrderStatusCancelled = pb.OrderStatus_CANCELLED
 var OrderStatusFailed = pb.OrderStatus_FAILED
 var OrderStatusPending = pb.OrderStatus_PENDING
+var OrderStatusRefunded = pb.OrderStatus_REFUNDED
+var OrderStatusShipped = pb.OrderStatus_SHIPPED
+var OrderStatusFulfilled = pb.OrderStatus_FULFILLED
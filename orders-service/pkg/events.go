@@ -0,0 +1,15 @@
+package pkg
+
+import "strings"
+
+// Types of the events the orders service publishes about orders, see
+// repository.OrderEvent for their payload.
+const (
+	EventOrderCreated = "OrderCreated"
+)
+
+// EventType returns the type of the event published when an order moves to
+// status s, e.g. OrderPaid for paid orders.
+func (s OrderStatus) EventType() string {
+	return "Order" + strings.ToUpper(string(s[:1])) + string(s[1:])
+}
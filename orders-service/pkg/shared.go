@@ -1,5 +1,7 @@
 package pkg
 
+import "sort"
+
 type OrderStatus string
 
 const (
@@ -7,4 +9,62 @@ const (
 	OrderStatusPending    OrderStatus = "pending"
 	OrderStatusProcessing OrderStatus = "processing"
 	OrderStatusPaid       OrderStatus = "paid"
+	OrderStatusCancelled  OrderStatus = "cancelled"
+	OrderStatusFailed     OrderStatus = "failed"
+	OrderStatusRefunded   OrderStatus = "refunded"
+	OrderStatusShipped    OrderStatus = "shipped"
+	OrderStatusFulfilled  OrderStatus = "fulfilled"
 )
+
+// orderStatusTransitions lists the statuses an order may move to from each
+// status. An order is checked out (processing), waits for the customer to
+// confirm the payment (pending) and is then either paid or failed. Failed
+// orders can be checked out again. Paid orders are shipped and fulfilled, and
+// may be refunded at any point of the way. Cancelled and refunded orders are
+// final.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew:        {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusPending, OrderStatusPaid, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusPending:    {OrderStatusPaid, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusFailed:     {OrderStatusProcessing, OrderStatusCancelled},
+	OrderStatusPaid:       {OrderStatusShipped, OrderStatusRefunded},
+	OrderStatusShipped:    {OrderStatusFulfilled, OrderStatusRefunded},
+	OrderStatusFulfilled:  {OrderStatusRefunded},
+	OrderStatusCancelled:  {},
+	OrderStatusRefunded:   {},
+}
+
+// OrderStatuses returns every known order status, sorted by name.
+func OrderStatuses() []OrderStatus {
+	statuses := make([]OrderStatus, 0, len(orderStatusTransitions))
+	for s := range orderStatusTransitions {
+		statuses = append(statuses, s)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+
+	return statuses
+}
+
+// IsValid reports whether s is a known order status.
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether an order in status s may move to next.
+// Moving to the current status is not a transition and is never allowed.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsFinal reports whether an order in status s can no longer change status.
+func (s OrderStatus) IsFinal() bool {
+	return len(orderStatusTransitions[s]) == 0
+}
@@ -0,0 +1,93 @@
+package pkg_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/orders/pkg"
+)
+
+func TestOrderStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from pkg.OrderStatus
+		to   pkg.OrderStatus
+		want bool
+	}{
+		{pkg.OrderStatusNew, pkg.OrderStatusProcessing, true},
+		{pkg.OrderStatusNew, pkg.OrderStatusCancelled, true},
+		{pkg.OrderStatusNew, pkg.OrderStatusPaid, false},
+		{pkg.OrderStatusNew, pkg.OrderStatusNew, false},
+		{pkg.OrderStatusProcessing, pkg.OrderStatusPending, true},
+		{pkg.OrderStatusProcessing, pkg.OrderStatusNew, false},
+		{pkg.OrderStatusPending, pkg.OrderStatusPaid, true},
+		{pkg.OrderStatusPending, pkg.OrderStatusFailed, true},
+		{pkg.OrderStatusPending, pkg.OrderStatusProcessing, false},
+		{pkg.OrderStatusFailed, pkg.OrderStatusProcessing, true},
+		{pkg.OrderStatusPaid, pkg.OrderStatusNew, false},
+		{pkg.OrderStatusPaid, pkg.OrderStatusCancelled, false},
+		{pkg.OrderStatusPaid, pkg.OrderStatusShipped, true},
+		{pkg.OrderStatusPaid, pkg.OrderStatusRefunded, true},
+		{pkg.OrderStatusShipped, pkg.OrderStatusFulfilled, true},
+		{pkg.OrderStatusShipped, pkg.OrderStatusPaid, false},
+		{pkg.OrderStatusShipped, pkg.OrderStatusRefunded, true},
+		{pkg.OrderStatusFulfilled, pkg.OrderStatusRefunded, true},
+		{pkg.OrderStatusRefunded, pkg.OrderStatusPaid, false},
+		{pkg.OrderStatusCancelled, pkg.OrderStatusProcessing, false},
+		{pkg.OrderStatusCancelled, pkg.OrderStatusRefunded, false},
+		{pkg.OrderStatus("unknown"), pkg.OrderStatusNew, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("OrderStatus.CanTransitionTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderStatus_IsValid(t *testing.T) {
+	if !pkg.OrderStatusCancelled.IsValid() {
+		t.Errorf("OrderStatus.IsValid() = false for %q", pkg.OrderStatusCancelled)
+	}
+
+	if pkg.OrderStatus("unknown").IsValid() {
+		t.Errorf("OrderStatus.IsValid() = true for an unknown status")
+	}
+}
+
+func TestOrderStatus_IsFinal(t *testing.T) {
+	for status, want := range map[pkg.OrderStatus]bool{
+		pkg.OrderStatusPending:   false,
+		pkg.OrderStatusFulfilled: false,
+		pkg.OrderStatusCancelled: true,
+		pkg.OrderStatusRefunded:  true,
+	} {
+		if got := status.IsFinal(); got != want {
+			t.Errorf("OrderStatus.IsFinal() = %v for %q, want %v", got, status, want)
+		}
+	}
+}
+
+func TestOrderStatuses(t *testing.T) {
+	statuses := pkg.OrderStatuses()
+	if len(statuses) != 9 {
+		t.Errorf("OrderStatuses() returned %d statuses, want 9", len(statuses))
+	}
+
+	for _, s := range statuses {
+		if !s.IsValid() {
+			t.Errorf("OrderStatuses() returned invalid status %q", s)
+		}
+	}
+}
+
+func TestOrderStatus_EventType(t *testing.T) {
+	for status, want := range map[pkg.OrderStatus]string{
+		pkg.OrderStatusPaid:      "OrderPaid",
+		pkg.OrderStatusFailed:    "OrderFailed",
+		pkg.OrderStatusCancelled: "OrderCancelled",
+	} {
+		if got := status.EventType(); got != want {
+			t.Errorf("OrderStatus.EventType() = %q, want %q", got, want)
+		}
+	}
+}
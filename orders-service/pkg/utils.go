@@ -13,3 +13,5 @@ func MustGetEnv(key string) string {
 func StringPtr(s string) *string { return &s }
 
 func UintPtr(i uint) *uint { return &i }
+
+func Int64Ptr(i int64) *int64 { return &i }
@@ -0,0 +1,70 @@
+// Command fakedaraja serves a fake of the Daraja (M-Pesa) API, to run the
+// payments service locally without network access or M-Pesa credentials.
+// Point the payments service at it with MPESA_BASE_URL.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/mpesa/mpesatest"
+)
+
+const (
+	FAKE_DARAJA_ADDR = "FAKE_DARAJA_ADDR"
+
+	// FAKE_DARAJA_RESULT is the result of every STK push: success, cancelled,
+	// insufficient_funds, timeout or lost.
+	FAKE_DARAJA_RESULT = "FAKE_DARAJA_RESULT"
+
+	// FAKE_DARAJA_CALLBACK_DELAY is how long the fake customer takes to
+	// respond to an STK push, as a Go duration, e.g. "2s".
+	FAKE_DARAJA_CALLBACK_DELAY = "FAKE_DARAJA_CALLBACK_DELAY"
+
+	// MPESA_PASSKEY, if set, must match the passwords of STK pushes, as for
+	// the payments service.
+	MPESA_PASSKEY = "MPESA_PASSKEY" // #nosec G101 - This is an env variable name
+
+	DEFAULT_FAKE_DARAJA_ADDR   = ":8081"
+	DEFAULT_FAKE_DARAJA_RESULT = "success"
+)
+
+func main() {
+
+	addr := os.Getenv(FAKE_DARAJA_ADDR)
+	if addr == "" {
+		addr = DEFAULT_FAKE_DARAJA_ADDR
+	}
+
+	resultName := os.Getenv(FAKE_DARAJA_RESULT)
+	if resultName == "" {
+		resultName = DEFAULT_FAKE_DARAJA_RESULT
+	}
+
+	result, err := mpesatest.ParseResult(resultName)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", FAKE_DARAJA_RESULT, err)
+	}
+
+	server := mpesatest.NewServer()
+	server.DefaultResult = result
+	server.Passkey = os.Getenv(MPESA_PASSKEY)
+
+	if v := os.Getenv(FAKE_DARAJA_CALLBACK_DELAY); v != "" {
+		server.CallbackDelay, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid %s %q: %v", FAKE_DARAJA_CALLBACK_DELAY, v, err)
+		}
+	}
+
+	log.Printf("fake Daraja API listening on %s, STK pushes result in %q", addr, resultName)
+
+	s := &http.Server{
+		Addr:              addr,
+		Handler:           server,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	log.Fatal(s.ListenAndServe())
+}
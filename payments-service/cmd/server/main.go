@@ -4,24 +4,86 @@ import (
 	"context"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	orders "github.com/Mik3y-F/order-management-system/orders/pkg/client"
 	db "github.com/Mik3y-F/order-management-system/payments/internal/firebase"
 	ecom_grpc "github.com/Mik3y-F/order-management-system/payments/internal/handlers/grpc"
+	ecom_http "github.com/Mik3y-F/order-management-system/payments/internal/handlers/http"
+	"github.com/Mik3y-F/order-management-system/payments/internal/memory"
 	"github.com/Mik3y-F/order-management-system/payments/internal/mpesa"
+	"github.com/Mik3y-F/order-management-system/payments/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	idempotency_memory "github.com/Mik3y-F/order-management-system/pkg/idempotency/memory"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
 )
 
 const (
-	BIND_ADDRESS = "BIND_ADDRESS"
-	PORT         = "PORT"
+	BIND_ADDRESS    = "BIND_ADDRESS"
+	PORT            = "PORT"
+	STORAGE_BACKEND = "STORAGE_BACKEND"
 
-	DEFAULT_BIND_ADDRESS = "localhost"
-	DEFAULT_PORT         = "50051"
+	// IDEMPOTENCY_TTL is how long responses to requests with an idempotency
+	// key are replayed, as a Go duration, e.g. "24h".
+	IDEMPOTENCY_TTL = "IDEMPOTENCY_TTL"
+
+	// EVENTS_FILE is the file that payment events are appended to as JSON
+	// lines. Without it, events are only logged.
+	EVENTS_FILE = "EVENTS_FILE"
+
+	// HTTP_ADDR is the bind address of the HTTP server that receives M-Pesa
+	// callbacks. With HTTP_DOMAIN set, it serves TLS for that domain on :443
+	// with a Let's Encrypt certificate instead.
+	HTTP_ADDR   = "HTTP_ADDR"
+	HTTP_DOMAIN = "HTTP_DOMAIN"
+
+	// CALLBACK_URL is the public URL of the M-Pesa callback route, for servers
+	// behind a proxy. It defaults to the callback URL of the HTTP server.
+	CALLBACK_URL = "CALLBACK_URL"
+
+	// REFUND_CALLBACK_URL is the public URL of the M-Pesa reversal result
+	// route. It defaults to the refund callback URL of the HTTP server.
+	REFUND_CALLBACK_URL = "REFUND_CALLBACK_URL"
+
+	// CALLBACK_ALLOWED_IPS is a comma separated list of the IP addresses and
+	// CIDR ranges that M-Pesa callbacks are accepted from. Without it,
+	// callbacks are accepted from any address.
+	CALLBACK_ALLOWED_IPS = "CALLBACK_ALLOWED_IPS"
+
+	// RECONCILE_AFTER is how long a payment waits for its M-Pesa callback
+	// before its status is queried from M-Pesa, as a Go duration, e.g. "5m".
+	RECONCILE_AFTER = "RECONCILE_AFTER"
+
+	DEFAULT_BIND_ADDRESS    = "localhost"
+	DEFAULT_HTTP_ADDR       = ":8080"
+	DEFAULT_PORT            = "50051"
+	DEFAULT_STORAGE_BACKEND = STORAGE_BACKEND_FIRESTORE
+	DEFAULT_IDEMPOTENCY_TTL = idempotency.DefaultTTL
+	DEFAULT_RECONCILE_AFTER = mpesa.DefaultReconcileAfter
+
+	// Supported values for STORAGE_BACKEND.
+	STORAGE_BACKEND_FIRESTORE = "firestore"
+	STORAGE_BACKEND_MEMORY    = "memory"
+	STORAGE_BACKEND_POSTGRES  = "postgres"
 )
 
+// repositories groups the storage implementations used by the server.
+type repositories struct {
+	payments    repository.PaymentsRepository
+	refunds     repository.RefundsRepository
+	idempotency idempotency.Store
+	outbox      repository.OutboxRepository
+}
+
 func main() {
 
-	ctx := context.Background()
+	// The servers shut down gracefully on SIGINT or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	log.Printf("Starting server")
 
@@ -35,6 +97,34 @@ func main() {
 		port = DEFAULT_PORT
 	}
 
+	httpAddr := os.Getenv(HTTP_ADDR)
+	if httpAddr == "" {
+		httpAddr = DEFAULT_HTTP_ADDR
+	}
+
+	storageBackend := os.Getenv(STORAGE_BACKEND)
+	if storageBackend == "" {
+		storageBackend = DEFAULT_STORAGE_BACKEND
+	}
+
+	idempotencyTTL := DEFAULT_IDEMPOTENCY_TTL
+	if v := os.Getenv(IDEMPOTENCY_TTL); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid %s: %q", IDEMPOTENCY_TTL, v)
+		}
+		idempotencyTTL = d
+	}
+
+	reconcileAfter := DEFAULT_RECONCILE_AFTER
+	if v := os.Getenv(RECONCILE_AFTER); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid %s: %q", RECONCILE_AFTER, v)
+		}
+		reconcileAfter = d
+	}
+
 	s := ecom_grpc.NewGRPCServer()
 
 	mpesaService := mpesa.NewMpesaService()
@@ -46,23 +136,140 @@ func main() {
 	}
 	orderClient := orders.NewGrpcOrderClient(conn)
 
-	// Setup firebase client and firestore service
-	firebase := db.NewFirebaseService()
-	firestoreClient, err := firebase.GetApp().Firestore(ctx)
-	if err != nil {
-		log.Fatalf("failed to create firestore client: %v", err)
-	}
-	defer firestoreClient.Close()
+	repos, closeRepos := newRepositories(ctx, storageBackend)
+	defer closeRepos()
 
-	firestoreService := db.NewFirestoreService(firestoreClient)
-	paymentRepository := db.NewPaymentsRepository(firestoreService)
+	paymentService := mpesa.NewPaymentsService(mpesaService, orderClient, repos.payments, repos.refunds)
 
-	paymentService := mpesa.NewPaymentsService(mpesaService, orderClient, paymentRepository)
+	publisher, closePublisher := newPublisher(os.Getenv(EVENTS_FILE))
+	defer closePublisher()
+
+	// Events recorded before the last shutdown are published along with new
+	// ones.
+	go outbox.NewRelay(repos.outbox, publisher).Run(ctx)
+
+	// Payments whose callback was lost are settled from their status in
+	// M-Pesa.
+	reconciler := mpesa.NewReconciler(paymentService)
+	reconciler.After = reconcileAfter
+	go reconciler.Run(ctx)
 
 	// Register internal services
 	s.PaymentsService = paymentService
+	s.PaymentsRepository = repos.payments
+	s.RefundsRepository = repos.refunds
+	s.IdempotencyStore = repos.idempotency
+	s.IdempotencyTTL = idempotencyTTL
+
+	httpServer := ecom_http.NewHTTPServer()
+	httpServer.Addr = httpAddr
+	httpServer.Domain = os.Getenv(HTTP_DOMAIN)
+	httpServer.PaymentsService = paymentService
+
+	httpServer.CallbackAllowlist, err = ecom_http.ParseAllowlist(os.Getenv(CALLBACK_ALLOWED_IPS))
+	if err != nil {
+		log.Fatalf("invalid %s: %v", CALLBACK_ALLOWED_IPS, err)
+	}
+
+	if err := httpServer.Open(); err != nil {
+		log.Fatalf("failed to open http server: %v", err)
+	}
+	defer httpServer.Close()
+
+	log.Printf("Serving M-Pesa callbacks on %s", httpServer.CallbackURL())
+
+	s.CallbackURL = os.Getenv(CALLBACK_URL)
+	if s.CallbackURL == "" {
+		s.CallbackURL = httpServer.CallbackURL()
+	}
+
+	s.RefundCallbackURL = os.Getenv(REFUND_CALLBACK_URL)
+	if s.RefundCallbackURL == "" {
+		s.RefundCallbackURL = httpServer.RefundCallbackURL()
+	}
+
+	go func() {
+		<-ctx.Done()
+		log.Printf("Shutting down server")
+		s.Stop()
+	}()
 
 	if err := s.Run(ctx, bindAddress, port); err != nil {
 		log.Fatalf("failed to serve: %v", err)
 	}
 }
+
+// newRepositories sets up the repositories for the given storage backend. The
+// returned function releases any resources held by the backend.
+func newRepositories(ctx context.Context, backend string) (*repositories, func()) {
+	log.Printf("Using %s storage backend", backend)
+
+	switch backend {
+	case STORAGE_BACKEND_FIRESTORE:
+		// Setup firebase client and firestore service
+		firebase := db.NewFirebaseService()
+		firestoreClient, err := firebase.GetApp().Firestore(ctx)
+		if err != nil {
+			log.Fatalf("failed to create firestore client: %v", err)
+		}
+
+		firestoreService := db.NewFirestoreService(firestoreClient)
+
+		return &repositories{
+			payments:    db.NewPaymentsRepository(firestoreService),
+			refunds:     db.NewRefundsRepository(firestoreService),
+			idempotency: db.NewIdempotencyStore(firestoreService),
+			outbox:      db.NewOutboxRepository(firestoreService),
+		}, func() { firestoreClient.Close() }
+
+	case STORAGE_BACKEND_MEMORY:
+		payments := memory.NewPaymentsRepository()
+
+		return &repositories{
+			payments:    payments,
+			refunds:     memory.NewRefundsRepository(payments.Outbox()),
+			idempotency: idempotency_memory.NewStore(),
+			outbox:      payments.Outbox(),
+		}, func() {}
+
+	case STORAGE_BACKEND_POSTGRES:
+		postgresDB := postgres.NewDB(pkg.MustGetEnv(postgres.POSTGRES_DSN))
+		if err := postgresDB.Open(); err != nil {
+			log.Fatalf("failed to open postgres database: %v", err)
+		}
+
+		return &repositories{
+			payments:    postgres.NewPaymentsRepository(postgresDB),
+			refunds:     postgres.NewRefundsRepository(postgresDB),
+			idempotency: postgres.NewIdempotencyStore(postgresDB),
+			outbox:      postgres.NewOutboxRepository(postgresDB),
+		}, func() { postgresDB.Close() }
+
+	default:
+		log.Fatalf("unsupported storage backend %q", backend)
+		return nil, nil
+	}
+}
+
+// newPublisher returns the publisher of payment events: a file if path is set,
+// and the log otherwise. The returned function closes the publisher.
+func newPublisher(path string) (outbox.Publisher, func()) {
+	if path != "" {
+		log.Printf("Publishing events to %s", path)
+
+		publisher, err := outbox.NewFilePublisher(path)
+		if err != nil {
+			log.Fatalf("failed to create events publisher: %v", err)
+		}
+
+		return publisher, func() { publisher.Close() }
+	}
+
+	publisher := outbox.NewInProcessPublisher()
+	publisher.Subscribe(func(ctx context.Context, event *outbox.Event) error {
+		log.Printf("[events] %s %s %s", event.Type, event.AggregateType, event.AggregateId)
+		return nil
+	})
+
+	return publisher, func() {}
+}
@@ -0,0 +1,16 @@
+package firebase
+
+import (
+	idempotency_firebase "github.com/Mik3y-F/order-management-system/pkg/idempotency/firebase"
+)
+
+// NewIdempotencyStore returns a store of idempotency keys in the
+// idempotencyKeys collection. Set a TTL policy on its expiresAt field to
+// delete expired keys.
+func NewIdempotencyStore(db *FirestoreService) *idempotency_firebase.Store {
+	s := idempotency_firebase.NewStore(db.client, "idempotencyKeys")
+	s.CreatedAtField = "createdAt"
+	s.ExpiresAtField = "expiresAt"
+
+	return s
+}
@@ -0,0 +1,24 @@
+package firebase_test
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/payments/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/idempotencytest"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	idempotencytest.TestStore(t, func(t *testing.T) idempotency.Store {
+		ctx := context.Background()
+
+		firestoreClient, err := db.NewFirebaseService().GetApp().Firestore(ctx)
+		if err != nil {
+			t.Fatalf("failed to create firestore client: %v", err)
+		}
+		t.Cleanup(func() { firestoreClient.Close() })
+
+		return db.NewIdempotencyStore(db.NewFirestoreService(firestoreClient))
+	})
+}
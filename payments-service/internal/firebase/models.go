@@ -1,13 +1,56 @@
 package firebase
 
+import "time"
+
 type PaymentModel struct {
-	ID          string `firestore:"id"`
-	Amount      uint   `firestore:"amount"`
-	Status      string `firestore:"status"`
-	OrderID     string `firestore:"orderId"`
-	Phone       string `firestore:"phone"`
-	Reference   string `firestore:"reference"`
-	Description string `firestore:"description"`
-	CreatedAt   string `firestore:"createdAt"`
-	UpdatedAt   string `firestore:"updatedAt"`
+	// Amount is in the minor units of Currency. Payments stored before the
+	// currency was recorded have no currency and an amount in whole shillings.
+	Amount            int64  `firestore:"amount"`
+	Currency          string `firestore:"currency"`
+	MerchantRequestID string `firestore:"merchantRequestId"`
+	CheckoutRequestID string `firestore:"checkoutRequestId"`
+	Status            string `firestore:"status"`
+	OrderID           string `firestore:"orderId"`
+	Phone             string `firestore:"phone"`
+	Reference         string `firestore:"reference"`
+	Description       string `firestore:"description"`
+	CallbackToken     string `firestore:"callbackToken"`
+	CreatedAt         string `firestore:"createdAt"`
+	UpdatedAt         string `firestore:"updatedAt"`
+
+	ResultCode         int    `firestore:"resultCode"`
+	ResultDesc         string `firestore:"resultDesc"`
+	MpesaReceiptNumber string `firestore:"mpesaReceiptNumber"`
+	PaidAmount         int64  `firestore:"paidAmount"`
+	PaidCurrency       string `firestore:"paidCurrency"`
+	PayerPhone         string `firestore:"payerPhone"`
+	TransactionTime    string `firestore:"transactionTime"`
+}
+
+type RefundModel struct {
+	PaymentID                string `firestore:"paymentId"`
+	OrderID                  string `firestore:"orderId"`
+	Amount                   int64  `firestore:"amount"`
+	Currency                 string `firestore:"currency"`
+	Status                   string `firestore:"status"`
+	Reason                   string `firestore:"reason"`
+	ResultCode               string `firestore:"resultCode"`
+	ResultDesc               string `firestore:"resultDesc"`
+	TransactionID            string `firestore:"transactionId"`
+	ConversationID           string `firestore:"conversationId"`
+	OriginatorConversationID string `firestore:"originatorConversationId"`
+	CallbackToken            string `firestore:"callbackToken"`
+	CreatedAt                string `firestore:"createdAt"`
+	UpdatedAt                string `firestore:"updatedAt"`
+}
+
+// OutboxEventModel is stored in the outboxEvents collection. CreatedAt is a
+// timestamp, whose sub-second precision keeps events in the order they were
+// recorded.
+type OutboxEventModel struct {
+	Type          string    `firestore:"type"`
+	AggregateType string    `firestore:"aggregateType"`
+	AggregateId   string    `firestore:"aggregateId"`
+	Payload       string    `firestore:"payload"`
+	CreatedAt     time.Time `firestore:"createdAt"`
 }
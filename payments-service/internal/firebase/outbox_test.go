@@ -0,0 +1,35 @@
+package firebase_test
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/payments/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+)
+
+func TestOutboxRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("OutboxRepository.CheckPreconditions() did not panic for nil DB")
+		}
+	}()
+
+	db.NewOutboxRepository(nil).CheckPreconditions()
+}
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.PaymentsRepository, repository.OutboxRepository) {
+		ctx := context.Background()
+
+		firestoreClient, err := db.NewFirebaseService().GetApp().Firestore(ctx)
+		if err != nil {
+			t.Fatalf("failed to create firestore client: %v", err)
+		}
+		t.Cleanup(func() { firestoreClient.Close() })
+
+		firestoreService := db.NewFirestoreService(firestoreClient)
+		return db.NewPaymentsRepository(firestoreService), db.NewOutboxRepository(firestoreService)
+	})
+}
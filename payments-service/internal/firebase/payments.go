@@ -2,11 +2,16 @@ package firebase
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
 	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ repository.PaymentsRepository = (*PaymentsRepository)(nil)
@@ -46,9 +51,25 @@ func (r *PaymentsRepository) CreatePayment(ctx context.Context, payment *reposit
 	}
 	paymentModel := r.marshallPayment(payment)
 
-	docRef, _, err := r.paymentsCollection().Add(ctx, paymentModel)
+	docRef := r.paymentsCollection().NewDoc()
+
+	err = r.runTransaction(ctx, "create payment", func(tx *firestore.Transaction) error {
+		if err := tx.Create(docRef, paymentModel); err != nil {
+			return err
+		}
+
+		created := *payment
+		created.Id = docRef.ID
+
+		event, err := repository.NewPaymentCreatedEvent(&created)
+		if err != nil {
+			return err
+		}
+
+		return createOutboxEvent(tx, r.db, event)
+	})
 	if err != nil {
-		return "", service.Errorf(service.INTERNAL_ERROR, "failed to create payment: %v", err)
+		return "", err
 	}
 
 	payment.Id = docRef.ID
@@ -71,7 +92,9 @@ func (r *PaymentsRepository) getPaymentByID(ctx context.Context, paymentID strin
 
 	docRef := r.paymentsCollection().Doc(paymentID)
 	doc, err := docRef.Get(ctx)
-	if err != nil {
+	if status.Code(err) == codes.NotFound {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	} else if err != nil {
 		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get payment: %v", err)
 	}
 
@@ -82,32 +105,137 @@ func (r *PaymentsRepository) getPaymentByID(ctx context.Context, paymentID strin
 	}
 
 	payment := r.unmarshallPayment(&paymentModel)
+	payment.Id = doc.Ref.ID
 
 	return payment, nil
 }
 
 func (r *PaymentsRepository) UpdatePaymentStatus(
-	ctx context.Context, paymentID string, status repository.PaymentStatus) error {
+	ctx context.Context, paymentID string, paymentStatus repository.PaymentStatus) error {
+	r.CheckPreconditions()
+
+	return r.updatePayment(ctx, paymentID, "update payment status", func(payment *repository.Payment) error {
+		payment.Status = paymentStatus
+		return nil
+	})
+}
+
+func (r *PaymentsRepository) SetPaymentRequestIDs(
+	ctx context.Context, paymentID, merchantRequestID, checkoutRequestID string) error {
 	r.CheckPreconditions()
 
 	if paymentID == "" {
 		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
 	}
 
-	payment, err := r.getPaymentByID(ctx, paymentID)
-	if err != nil {
-		return err
+	_, err := r.paymentsCollection().Doc(paymentID).Update(ctx, []firestore.Update{
+		{Path: "merchantRequestId", Value: merchantRequestID},
+		{Path: "checkoutRequestId", Value: checkoutRequestID},
+		{Path: "updatedAt", Value: time.Now().Format(time.RFC3339)},
+	})
+	if status.Code(err) == codes.NotFound {
+		return service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to set payment request ids: %v", err)
 	}
 
-	payment.Status = status
-	payment.UpdatedAt = time.Now().Format(time.RFC3339)
+	return nil
+}
 
-	opaymentModel := r.marshallPayment(payment)
-	_, err = r.paymentsCollection().Doc(paymentID).Set(ctx, opaymentModel)
-	if err != nil {
-		return service.Errorf(service.INTERNAL_ERROR, "failed to update order status: %v", err)
+func (r *PaymentsRepository) SettlePayment(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+	r.CheckPreconditions()
+
+	return r.updatePayment(ctx, paymentID, "settle payment", func(payment *repository.Payment) error {
+		if payment.Status != repository.PaymentStatusPending {
+			return service.Errorf(service.CONFLICT_ERROR, "payment %s is already %s", paymentID, payment.Status)
+		}
+
+		payment.Settle(result)
+		return nil
+	})
+}
+
+func (r *PaymentsRepository) RecordPaymentReceipt(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
 	}
-	return nil
+
+	docRef := r.paymentsCollection().Doc(paymentID)
+
+	// The status of the payment does not change, so no event is recorded.
+	return r.runTransaction(ctx, "record payment receipt", func(tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+		} else if err != nil {
+			return err
+		}
+
+		var paymentModel PaymentModel
+		if err := doc.DataTo(&paymentModel); err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to decode payment: %v", err)
+		}
+
+		payment := r.unmarshallPayment(&paymentModel)
+		if payment.Status != repository.PaymentStatusPaid || payment.MpesaReceiptNumber != "" {
+			return service.Errorf(service.CONFLICT_ERROR,
+				"payment %s is %s with receipt %q, only paid payments without one can record it",
+				paymentID, payment.Status, payment.MpesaReceiptNumber)
+		}
+
+		payment.RecordReceipt(result)
+		payment.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		return tx.Set(docRef, r.marshallPayment(payment))
+	})
+}
+
+// updatePayment applies update to the payment in a transaction, and records
+// the status event of the updated payment.
+func (r *PaymentsRepository) updatePayment(
+	ctx context.Context, paymentID string, action string, update func(payment *repository.Payment) error) error {
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	docRef := r.paymentsCollection().Doc(paymentID)
+
+	return r.runTransaction(ctx, action, func(tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+		} else if err != nil {
+			return err
+		}
+
+		var paymentModel PaymentModel
+		if err := doc.DataTo(&paymentModel); err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to decode payment: %v", err)
+		}
+
+		payment := r.unmarshallPayment(&paymentModel)
+		payment.Id = paymentID
+		if err := update(payment); err != nil {
+			return err
+		}
+		payment.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		if err := tx.Set(docRef, r.marshallPayment(payment)); err != nil {
+			return err
+		}
+
+		event, err := repository.NewPaymentStatusEvent(payment)
+		if err != nil {
+			return err
+		}
+
+		return createOutboxEvent(tx, r.db, event)
+	})
 }
 
 func (r *PaymentsRepository) GetPaymentByMerchantRequestID(
@@ -118,7 +246,25 @@ func (r *PaymentsRepository) GetPaymentByMerchantRequestID(
 		return nil, service.Errorf(service.INVALID_ERROR, "invalid merchant request ID provided")
 	}
 
-	query := r.paymentsCollection().Where("reference", "==", merchantRequestID).Limit(1)
+	return r.getPaymentWhere(ctx, "merchantRequestId", merchantRequestID)
+}
+
+func (r *PaymentsRepository) GetPaymentByReceiptNumber(
+	ctx context.Context, receiptNumber string) (*repository.Payment, error) {
+	r.CheckPreconditions()
+
+	if receiptNumber == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid receipt number provided")
+	}
+
+	return r.getPaymentWhere(ctx, "mpesaReceiptNumber", receiptNumber)
+}
+
+// getPaymentWhere returns the payment whose field has the value.
+func (r *PaymentsRepository) getPaymentWhere(
+	ctx context.Context, field string, value string) (*repository.Payment, error) {
+
+	query := r.paymentsCollection().Where(field, "==", value).Limit(1)
 	docs, err := query.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get payment: %v", err)
@@ -135,33 +281,124 @@ func (r *PaymentsRepository) GetPaymentByMerchantRequestID(
 	}
 
 	payment := r.unmarshallPayment(&paymentModel)
+	payment.Id = docs[0].Ref.ID
 
 	return payment, nil
 }
 
+func (r *PaymentsRepository) ListPendingPayments(
+	ctx context.Context, createdBefore time.Time, limit int) ([]*repository.Payment, error) {
+	r.CheckPreconditions()
+
+	iter := r.paymentsCollection().
+		Where("status", "==", string(repository.PaymentStatusPending)).
+		Where("createdAt", "<", createdBefore.Format(time.RFC3339)).
+		OrderBy("createdAt", firestore.Asc).
+		Documents(ctx)
+	defer iter.Stop()
+
+	// Payments stored before checkout request ids were recorded are skipped
+	// here, as Firestore cannot filter on a second field with a range.
+	var payments []*repository.Payment
+	for len(payments) < limit {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to list pending payments: %v", err)
+		}
+
+		var paymentModel PaymentModel
+		if err := doc.DataTo(&paymentModel); err != nil {
+			return nil, service.Errorf(service.INTERNAL_ERROR, "failed to decode payment: %v", err)
+		}
+
+		if paymentModel.CheckoutRequestID == "" {
+			continue
+		}
+
+		payment := r.unmarshallPayment(&paymentModel)
+		payment.Id = doc.Ref.ID
+
+		payments = append(payments, payment)
+	}
+
+	return payments, nil
+}
+
+// runTransaction runs fn in a transaction. Application errors returned by fn
+// are passed through, other failures are reported as failing to do action.
+func (r *PaymentsRepository) runTransaction(
+	ctx context.Context, action string, fn func(tx *firestore.Transaction) error) error {
+
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(tx)
+	})
+
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return err
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to %s: %v", action, err)
+	}
+
+	return nil
+}
+
 func (r *PaymentsRepository) marshallPayment(payment *repository.Payment) *PaymentModel {
 	return &PaymentModel{
-		Amount:      payment.Amount,
-		Status:      string(payment.Status),
-		OrderID:     payment.OrderID,
-		Phone:       payment.Phone,
-		Reference:   payment.Reference,
-		Description: payment.Description,
-		CreatedAt:   payment.CreatedAt,
-		UpdatedAt:   payment.UpdatedAt,
+		Amount:            payment.Amount.Amount,
+		Currency:          payment.Amount.Currency,
+		MerchantRequestID: payment.MerchantRequestID,
+		CheckoutRequestID: payment.CheckoutRequestID,
+		Status:            string(payment.Status),
+		OrderID:           payment.OrderID,
+		Phone:             payment.Phone,
+		Reference:         payment.Reference,
+		Description:       payment.Description,
+		CallbackToken:     payment.CallbackToken,
+		CreatedAt:         payment.CreatedAt,
+		UpdatedAt:         payment.UpdatedAt,
+
+		ResultCode:         payment.ResultCode,
+		ResultDesc:         payment.ResultDesc,
+		MpesaReceiptNumber: payment.MpesaReceiptNumber,
+		PaidAmount:         payment.PaidAmount.Amount,
+		PaidCurrency:       payment.PaidAmount.Currency,
+		PayerPhone:         payment.PayerPhone,
+		TransactionTime:    payment.TransactionTime,
 	}
 }
 
 func (r *PaymentsRepository) unmarshallPayment(paymentModel *PaymentModel) *repository.Payment {
 	return &repository.Payment{
-		Id:          paymentModel.ID,
-		Amount:      paymentModel.Amount,
-		Status:      repository.PaymentStatus(paymentModel.Status),
-		OrderID:     paymentModel.OrderID,
-		Phone:       paymentModel.Phone,
-		Reference:   paymentModel.Reference,
-		Description: paymentModel.Description,
-		CreatedAt:   paymentModel.CreatedAt,
-		UpdatedAt:   paymentModel.UpdatedAt,
+		Amount:            unmarshallAmount(paymentModel),
+		MerchantRequestID: paymentModel.MerchantRequestID,
+		CheckoutRequestID: paymentModel.CheckoutRequestID,
+		Status:            repository.PaymentStatus(paymentModel.Status),
+		OrderID:           paymentModel.OrderID,
+		Phone:             paymentModel.Phone,
+		Reference:         paymentModel.Reference,
+		Description:       paymentModel.Description,
+		CallbackToken:     paymentModel.CallbackToken,
+		CreatedAt:         paymentModel.CreatedAt,
+		UpdatedAt:         paymentModel.UpdatedAt,
+
+		ResultCode:         paymentModel.ResultCode,
+		ResultDesc:         paymentModel.ResultDesc,
+		MpesaReceiptNumber: paymentModel.MpesaReceiptNumber,
+		PaidAmount:         money.New(paymentModel.PaidAmount, paymentModel.PaidCurrency),
+		PayerPhone:         paymentModel.PayerPhone,
+		TransactionTime:    paymentModel.TransactionTime,
 	}
 }
+
+// unmarshallAmount returns the amount of a payment, converting the whole
+// shillings of payments stored without a currency.
+func unmarshallAmount(paymentModel *PaymentModel) money.Money {
+	if paymentModel.Currency == "" {
+		return money.New(paymentModel.Amount*100, "KES")
+	}
+
+	return money.New(paymentModel.Amount, paymentModel.Currency)
+}
@@ -0,0 +1,34 @@
+package firebase_test
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/payments/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+)
+
+func TestPaymentsRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("PaymentsRepository.CheckPreconditions() did not panic for nil DB")
+		}
+	}()
+
+	db.NewPaymentsRepository(nil).CheckPreconditions()
+}
+
+func TestPaymentsRepository(t *testing.T) {
+	repositorytest.TestPaymentsRepository(t, func(t *testing.T) repository.PaymentsRepository {
+		ctx := context.Background()
+
+		firestoreClient, err := db.NewFirebaseService().GetApp().Firestore(ctx)
+		if err != nil {
+			t.Fatalf("failed to create firestore client: %v", err)
+		}
+		t.Cleanup(func() { firestoreClient.Close() })
+
+		return db.NewPaymentsRepository(db.NewFirestoreService(firestoreClient))
+	})
+}
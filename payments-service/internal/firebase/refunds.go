@@ -0,0 +1,253 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ repository.RefundsRepository = (*RefundsRepository)(nil)
+
+type RefundsRepository struct {
+	db *FirestoreService
+}
+
+func NewRefundsRepository(db *FirestoreService) *RefundsRepository {
+	return &RefundsRepository{
+		db: db,
+	}
+}
+
+func (r *RefundsRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *RefundsRepository) refundsCollection() *firestore.CollectionRef {
+	r.CheckPreconditions()
+
+	return r.db.client.Collection("refunds")
+}
+
+func (r *RefundsRepository) CreateRefund(
+	ctx context.Context, refund *repository.Refund, limit money.Money) (string, error) {
+	r.CheckPreconditions()
+
+	currentTime := time.Now()
+	refund.CreatedAt = currentTime.Format(time.RFC3339)
+	refund.UpdatedAt = currentTime.Format(time.RFC3339)
+
+	err := refund.Validate()
+	if err != nil {
+		return "", service.Errorf(service.INVALID_ERROR, "invalid refund details provided: %v", err)
+	}
+
+	docRef := r.refundsCollection().NewDoc()
+
+	// The refunds of the payment are read in the transaction, so that it is
+	// retried if another refund of the payment is created first.
+	query := r.refundsCollection().Where("paymentId", "==", refund.PaymentID)
+
+	err = r.runTransaction(ctx, "create refund", func(tx *firestore.Transaction) error {
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+
+		refunds := make([]*repository.Refund, 0, len(docs))
+		for _, doc := range docs {
+			other, err := unmarshallRefundDoc(doc)
+			if err != nil {
+				return err
+			}
+
+			refunds = append(refunds, other)
+		}
+
+		if err := repository.CheckRefundLimit(refund, refunds, limit); err != nil {
+			return err
+		}
+
+		if err := tx.Create(docRef, marshallRefund(refund)); err != nil {
+			return err
+		}
+
+		created := *refund
+		created.Id = docRef.ID
+
+		event, err := repository.NewRefundCreatedEvent(&created)
+		if err != nil {
+			return err
+		}
+
+		return createOutboxEvent(tx, r.db, event)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	refund.Id = docRef.ID
+
+	return refund.Id, nil
+}
+
+func (r *RefundsRepository) GetRefundByID(ctx context.Context, refundID string) (*repository.Refund, error) {
+	r.CheckPreconditions()
+
+	if refundID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid refund ID provided")
+	}
+
+	doc, err := r.refundsCollection().Doc(refundID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "refund not found")
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get refund: %v", err)
+	}
+
+	return unmarshallRefundDoc(doc)
+}
+
+func (r *RefundsRepository) ListRefundsByPayment(
+	ctx context.Context, paymentID string) ([]*repository.Refund, error) {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	// Payments have few refunds, so they are sorted here rather than with a
+	// composite index.
+	docs, err := r.refundsCollection().Where("paymentId", "==", paymentID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to list refunds: %v", err)
+	}
+
+	refunds := make([]*repository.Refund, 0, len(docs))
+	for _, doc := range docs {
+		refund, err := unmarshallRefundDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		refunds = append(refunds, refund)
+	}
+
+	sort.SliceStable(refunds, func(i, j int) bool { return refunds[i].CreatedAt < refunds[j].CreatedAt })
+
+	return refunds, nil
+}
+
+func (r *RefundsRepository) SettleRefund(
+	ctx context.Context, refundID string, result *repository.RefundResult) error {
+	r.CheckPreconditions()
+
+	if refundID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid refund ID provided")
+	}
+
+	docRef := r.refundsCollection().Doc(refundID)
+
+	return r.runTransaction(ctx, "settle refund", func(tx *firestore.Transaction) error {
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return service.Errorf(service.NOT_FOUND_ERROR, "refund not found")
+		} else if err != nil {
+			return err
+		}
+
+		refund, err := unmarshallRefundDoc(doc)
+		if err != nil {
+			return err
+		}
+
+		if refund.Status != repository.RefundStatusPending {
+			return service.Errorf(service.CONFLICT_ERROR, "refund %s is already %s", refundID, refund.Status)
+		}
+
+		refund.Settle(result)
+		refund.UpdatedAt = time.Now().Format(time.RFC3339)
+
+		if err := tx.Set(docRef, marshallRefund(refund)); err != nil {
+			return err
+		}
+
+		event, err := repository.NewRefundStatusEvent(refund)
+		if err != nil {
+			return err
+		}
+
+		return createOutboxEvent(tx, r.db, event)
+	})
+}
+
+// runTransaction runs fn in a transaction. Application errors returned by fn
+// are passed through, other failures are reported as failing to do action.
+func (r *RefundsRepository) runTransaction(
+	ctx context.Context, action string, fn func(tx *firestore.Transaction) error) error {
+
+	err := r.db.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return fn(tx)
+	})
+
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) {
+		return err
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to %s: %v", action, err)
+	}
+
+	return nil
+}
+
+func marshallRefund(refund *repository.Refund) *RefundModel {
+	return &RefundModel{
+		PaymentID:                refund.PaymentID,
+		OrderID:                  refund.OrderID,
+		Amount:                   refund.Amount.Amount,
+		Currency:                 refund.Amount.Currency,
+		Status:                   string(refund.Status),
+		Reason:                   refund.Reason,
+		ResultCode:               refund.ResultCode,
+		ResultDesc:               refund.ResultDesc,
+		TransactionID:            refund.TransactionID,
+		ConversationID:           refund.ConversationID,
+		OriginatorConversationID: refund.OriginatorConversationID,
+		CallbackToken:            refund.CallbackToken,
+		CreatedAt:                refund.CreatedAt,
+		UpdatedAt:                refund.UpdatedAt,
+	}
+}
+
+func unmarshallRefundDoc(doc *firestore.DocumentSnapshot) (*repository.Refund, error) {
+	var refundModel RefundModel
+	if err := doc.DataTo(&refundModel); err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to decode refund: %v", err)
+	}
+
+	return &repository.Refund{
+		Id:                       doc.Ref.ID,
+		PaymentID:                refundModel.PaymentID,
+		OrderID:                  refundModel.OrderID,
+		Amount:                   money.New(refundModel.Amount, refundModel.Currency),
+		Status:                   repository.RefundStatus(refundModel.Status),
+		Reason:                   refundModel.Reason,
+		ResultCode:               refundModel.ResultCode,
+		ResultDesc:               refundModel.ResultDesc,
+		TransactionID:            refundModel.TransactionID,
+		ConversationID:           refundModel.ConversationID,
+		OriginatorConversationID: refundModel.OriginatorConversationID,
+		CallbackToken:            refundModel.CallbackToken,
+		CreatedAt:                refundModel.CreatedAt,
+		UpdatedAt:                refundModel.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,34 @@
+package firebase_test
+
+import (
+	"context"
+	"testing"
+
+	db "github.com/Mik3y-F/order-management-system/payments/internal/firebase"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+)
+
+func TestRefundsRepository_CheckPreconditions(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("RefundsRepository.CheckPreconditions() did not panic for nil DB")
+		}
+	}()
+
+	db.NewRefundsRepository(nil).CheckPreconditions()
+}
+
+func TestRefundsRepository(t *testing.T) {
+	repositorytest.TestRefundsRepository(t, func(t *testing.T) repository.RefundsRepository {
+		ctx := context.Background()
+
+		firestoreClient, err := db.NewFirebaseService().GetApp().Firestore(ctx)
+		if err != nil {
+			t.Fatalf("failed to create firestore client: %v", err)
+		}
+		t.Cleanup(func() { firestoreClient.Close() })
+
+		return db.NewRefundsRepository(db.NewFirestoreService(firestoreClient))
+	})
+}
@@ -33,6 +33,8 @@ func GRPCErrorStatusCode(err error) error {
 		return status.Error(codes.NotFound, service.ErrorMessage(err))
 	case service.ALREADY_EXISTS_ERROR:
 		return status.Error(codes.AlreadyExists, service.ErrorMessage(err))
+	case service.CONFLICT_ERROR:
+		return status.Error(codes.Aborted, service.ErrorMessage(err))
 	case service.INTERNAL_ERROR:
 		return status.Error(codes.Internal, service.ErrorMessage(err))
 	default:
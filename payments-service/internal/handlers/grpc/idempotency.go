@@ -0,0 +1,28 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	"google.golang.org/protobuf/proto"
+)
+
+// idempotent calls handle once per idempotency key of method and replays the
+// response to retries of the request, see idempotency.Handle.
+func idempotent[Req idempotency.Request, Resp proto.Message](
+	ctx context.Context, s *GRPCServer, method string, in Req,
+	handle func(context.Context, Req) (Resp, error)) (Resp, error) {
+
+	out, err := idempotency.Handle(ctx, idempotency.Handler{Store: s.IdempotencyStore, TTL: s.IdempotencyTTL},
+		method, in, handle)
+
+	// The codes of idempotency errors are application error codes.
+	var idempotencyErr *idempotency.Error
+	if errors.As(err, &idempotencyErr) {
+		return out, service.Errorf(idempotencyErr.Code, "%s", idempotencyErr.Message)
+	}
+
+	return out, err
+}
@@ -0,0 +1,101 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/Mik3y-F/order-management-system/payments/api/generated"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	idempotency_memory "github.com/Mik3y-F/order-management-system/pkg/idempotency/memory"
+	idempotency_mock "github.com/Mik3y-F/order-management-system/pkg/idempotency/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// newIdempotentTestGRPCServer returns a test server that records idempotency
+// keys in memory and counts the payments it requests.
+func newIdempotentTestGRPCServer(t *testing.T) (*TestGRPCServer, *int) {
+	s := NewTestGRPCServer(t)
+
+	s.IdempotencyStore = *idempotency_mock.NewStore(idempotency_memory.NewStore())
+
+	requested := 0
+	s.PaymentsService.ProcessPaymentFunc = func(ctx context.Context, p *service.Payment) (*service.PaymentResponse, error) {
+		requested++
+		return mockProcessPaymentFunc(ctx, p)
+	}
+
+	return s, &requested
+}
+
+func newIdempotentPaymentRequest(key string, reference string) *pb.MpesaPaymentRequest {
+	return &pb.MpesaPaymentRequest{
+		Amount:         &pb.Money{Amount: 10000, Currency: "KES"},
+		PhoneNumber:    254700000000,
+		Reference:      reference,
+		Description:    "description",
+		IdempotencyKey: key,
+	}
+}
+
+func TestGRPCServer_ProcessPayment_Idempotent(t *testing.T) {
+	s, requested := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	first, err := s.ProcessPayment(ctx, newIdempotentPaymentRequest("key-1", "reference"))
+	if err != nil {
+		t.Fatalf("GRPCServer.ProcessPayment() error = %v", err)
+	}
+
+	retry, err := s.ProcessPayment(ctx, newIdempotentPaymentRequest("key-1", "reference"))
+	if err != nil {
+		t.Fatalf("GRPCServer.ProcessPayment() retry error = %v", err)
+	}
+	if !proto.Equal(retry, first) {
+		t.Errorf("GRPCServer.ProcessPayment() retry = %v, want %v", retry, first)
+	}
+
+	if _, err := s.ProcessPayment(ctx, newIdempotentPaymentRequest("key-2", "reference")); err != nil {
+		t.Fatalf("GRPCServer.ProcessPayment() error = %v", err)
+	}
+
+	if *requested != 2 {
+		t.Errorf("GRPCServer.ProcessPayment() requested %d payments, want 2", *requested)
+	}
+}
+
+func TestGRPCServer_ProcessPayment_KeyReused(t *testing.T) {
+	s, requested := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	if _, err := s.ProcessPayment(ctx, newIdempotentPaymentRequest("key-1", "reference")); err != nil {
+		t.Fatalf("GRPCServer.ProcessPayment() error = %v", err)
+	}
+
+	_, err := s.ProcessPayment(ctx, newIdempotentPaymentRequest("key-1", "other reference"))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GRPCServer.ProcessPayment() error = %v, want code %v", err, codes.InvalidArgument)
+	}
+
+	if *requested != 1 {
+		t.Errorf("GRPCServer.ProcessPayment() requested %d payments, want 1", *requested)
+	}
+}
+
+func TestGRPCServer_ProcessPayment_KeyReleased(t *testing.T) {
+	s, requested := newIdempotentTestGRPCServer(t)
+	ctx := context.Background()
+
+	// Failed requests do not keep their key, so they can be retried.
+	for i := 0; i < 2; i++ {
+		_, err := s.ProcessPayment(ctx, newIdempotentPaymentRequest("key-1", ERROR_PAYMENT_TRIGGER))
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("GRPCServer.ProcessPayment() error = %v, want code %v", err, codes.InvalidArgument)
+		}
+	}
+
+	if *requested != 2 {
+		t.Errorf("GRPCServer.ProcessPayment() requested %d payments, want 2", *requested)
+	}
+}
@@ -4,22 +4,37 @@ import (
 	"context"
 
 	pb "github.com/Mik3y-F/order-management-system/payments/api/generated"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
 	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 )
 
 func (s *GRPCServer) ProcessPayment(ctx context.Context, in *pb.MpesaPaymentRequest) (*pb.MpesaPaymentResponse, error) {
 
-	callBackURL := "https://order-management-system.herokuapp.com/payments/callback"
+	out, err := idempotent(ctx, s, "ProcessMpesaPayment", in, s.processPayment)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return out, nil
+}
+
+func (s *GRPCServer) processPayment(ctx context.Context, in *pb.MpesaPaymentRequest) (*pb.MpesaPaymentResponse, error) {
+
+	if s.CallbackURL == "" {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "payment callback url is not configured")
+	}
 
 	p, err := s.PaymentsService.ProcessPayment(ctx, &service.Payment{
-		Amount:      uint(in.GetAmount()),
+		OrderId:     in.GetOrderId(),
+		Amount:      money.New(in.GetAmount().GetAmount(), in.GetAmount().GetCurrency()),
 		PhoneNumber: uint(in.GetPhoneNumber()),
-		CallbackURL: callBackURL,
+		CallbackURL: s.CallbackURL,
 		Reference:   in.GetReference(),
 		Description: in.GetDescription(),
 	})
 	if err != nil {
-		return nil, Error(err)
+		return nil, err
 	}
 
 	return &pb.MpesaPaymentResponse{
@@ -29,3 +44,173 @@ func (s *GRPCServer) ProcessPayment(ctx context.Context, in *pb.MpesaPaymentRequ
 		MerchantRequestId: p.MerchantRequestID,
 	}, nil
 }
+
+func (s *GRPCServer) GetPayment(ctx context.Context, in *pb.GetPaymentRequest) (*pb.GetPaymentResponse, error) {
+
+	payment, err := s.getPayment(ctx, in)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	refunds, err := s.listRefunds(ctx, payment)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return &pb.GetPaymentResponse{
+		Payment: marshalPayment(payment),
+		Refunds: refunds,
+	}, nil
+}
+
+func (s *GRPCServer) getPayment(ctx context.Context, in *pb.GetPaymentRequest) (*repository.Payment, error) {
+
+	if s.PaymentsRepository == nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "payments repository is not configured")
+	}
+
+	switch {
+	case in.GetId() != "" && in.GetMpesaReceiptNumber() != "":
+		return nil, service.Errorf(service.INVALID_ERROR, "payment id and receipt number are mutually exclusive")
+	case in.GetId() != "":
+		return s.PaymentsRepository.GetPaymentByID(ctx, in.GetId())
+	case in.GetMpesaReceiptNumber() != "":
+		return s.PaymentsRepository.GetPaymentByReceiptNumber(ctx, in.GetMpesaReceiptNumber())
+	default:
+		return nil, service.Errorf(service.INVALID_ERROR, "payment id or receipt number is required")
+	}
+}
+
+func (s *GRPCServer) listRefunds(ctx context.Context, payment *repository.Payment) ([]*pb.Refund, error) {
+
+	if s.RefundsRepository == nil {
+		return nil, nil
+	}
+
+	refunds, err := s.RefundsRepository.ListRefundsByPayment(ctx, payment.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*pb.Refund, 0, len(refunds))
+	for _, refund := range refunds {
+		out = append(out, marshalRefund(refund))
+	}
+
+	return out, nil
+}
+
+func (s *GRPCServer) RefundPayment(ctx context.Context, in *pb.RefundPaymentRequest) (*pb.RefundPaymentResponse, error) {
+
+	out, err := idempotent(ctx, s, "RefundPayment", in, s.refundPayment)
+	if err != nil {
+		return nil, Error(err)
+	}
+
+	return out, nil
+}
+
+func (s *GRPCServer) refundPayment(ctx context.Context, in *pb.RefundPaymentRequest) (*pb.RefundPaymentResponse, error) {
+
+	if s.RefundCallbackURL == "" {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "refund callback url is not configured")
+	}
+
+	if in.GetPaymentId() == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "payment id is required")
+	}
+
+	r, err := s.PaymentsService.RefundPayment(ctx, &service.Refund{
+		PaymentId: in.GetPaymentId(),
+		Amount:    money.New(in.GetAmount().GetAmount(), in.GetAmount().GetCurrency()),
+		Reason:    in.GetReason(),
+		ResultURL: s.RefundCallbackURL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.RefundPaymentResponse{
+		RefundId:                 r.RefundId,
+		Amount:                   marshalMoney(r.Amount),
+		ConversationId:           r.ConversationID,
+		OriginatorConversationId: r.OriginatorConversationID,
+		ResponseDescription:      r.ResponseDescription,
+	}, nil
+}
+
+func marshalPayment(payment *repository.Payment) *pb.Payment {
+	p := &pb.Payment{
+		Id:                 payment.Id,
+		OrderId:            payment.OrderID,
+		Amount:             marshalMoney(payment.Amount),
+		Status:             marshalPaymentStatus(payment.Status),
+		PhoneNumber:        payment.Phone,
+		Reference:          payment.Reference,
+		Description:        payment.Description,
+		MerchantRequestId:  payment.MerchantRequestID,
+		CheckoutRequestId:  payment.CheckoutRequestID,
+		CreatedAt:          payment.CreatedAt,
+		UpdatedAt:          payment.UpdatedAt,
+		ResultCode:         int32(payment.ResultCode),
+		ResultDesc:         payment.ResultDesc,
+		MpesaReceiptNumber: payment.MpesaReceiptNumber,
+		PayerPhoneNumber:   payment.PayerPhone,
+		TransactionTime:    payment.TransactionTime,
+	}
+
+	// Payments settled without a callback have no paid amount.
+	if payment.PaidAmount.Currency != "" {
+		p.PaidAmount = marshalMoney(payment.PaidAmount)
+	}
+
+	return p
+}
+
+func marshalMoney(m money.Money) *pb.Money {
+	return &pb.Money{Amount: m.Amount, Currency: m.Currency}
+}
+
+func marshalPaymentStatus(status repository.PaymentStatus) pb.PaymentStatus {
+	switch status {
+	case repository.PaymentStatusPending:
+		return pb.PaymentStatus_PAYMENT_STATUS_PENDING
+	case repository.PaymentStatusPaid:
+		return pb.PaymentStatus_PAYMENT_STATUS_PAID
+	case repository.PaymentStatusFailed:
+		return pb.PaymentStatus_PAYMENT_STATUS_FAILED
+	default:
+		return pb.PaymentStatus_PAYMENT_STATUS_UNSPECIFIED
+	}
+}
+
+func marshalRefund(refund *repository.Refund) *pb.Refund {
+	return &pb.Refund{
+		Id:                       refund.Id,
+		PaymentId:                refund.PaymentID,
+		OrderId:                  refund.OrderID,
+		Amount:                   marshalMoney(refund.Amount),
+		Status:                   marshalRefundStatus(refund.Status),
+		Reason:                   refund.Reason,
+		CreatedAt:                refund.CreatedAt,
+		UpdatedAt:                refund.UpdatedAt,
+		ResultCode:               refund.ResultCode,
+		ResultDesc:               refund.ResultDesc,
+		TransactionId:            refund.TransactionID,
+		ConversationId:           refund.ConversationID,
+		OriginatorConversationId: refund.OriginatorConversationID,
+	}
+}
+
+func marshalRefundStatus(status repository.RefundStatus) pb.RefundStatus {
+	switch status {
+	case repository.RefundStatusPending:
+		return pb.RefundStatus_REFUND_STATUS_PENDING
+	case repository.RefundStatusCompleted:
+		return pb.RefundStatus_REFUND_STATUS_COMPLETED
+	case repository.RefundStatusFailed:
+		return pb.RefundStatus_REFUND_STATUS_FAILED
+	default:
+		return pb.RefundStatus_REFUND_STATUS_UNSPECIFIED
+	}
+}
@@ -6,7 +6,12 @@ import (
 	"testing"
 
 	pb "github.com/Mik3y-F/order-management-system/payments/api/generated"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
 	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -19,6 +24,10 @@ func mockProcessPaymentFunc(ctx context.Context, p *service.Payment) (*service.P
 		return nil, service.Errorf(service.INVALID_ERROR, "invalid request: %s", p.Reference)
 	}
 
+	if p.CallbackURL != TEST_CALLBACK_URL {
+		return nil, service.Errorf(service.INVALID_ERROR, "unexpected callback url: %s", p.CallbackURL)
+	}
+
 	return &service.PaymentResponse{
 		CheckoutRequestID: "checkoutRequestID",
 		CustomerMessage:   "customerMessage",
@@ -48,7 +57,7 @@ func TestGRPCServer_ProcessPayment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				in: &pb.MpesaPaymentRequest{
-					Amount:      100,
+					Amount:      &pb.Money{Amount: 10000, Currency: "KES"},
 					PhoneNumber: 254700000000,
 					Reference:   "reference",
 					Description: "description",
@@ -67,7 +76,7 @@ func TestGRPCServer_ProcessPayment(t *testing.T) {
 			args: args{
 				ctx: context.Background(),
 				in: &pb.MpesaPaymentRequest{
-					Amount:      100,
+					Amount:      &pb.Money{Amount: 10000, Currency: "KES"},
 					PhoneNumber: 254700000000,
 					Reference:   ERROR_PAYMENT_TRIGGER,
 					Description: "description",
@@ -90,3 +99,150 @@ func TestGRPCServer_ProcessPayment(t *testing.T) {
 		})
 	}
 }
+
+func TestGRPCServer_ProcessPayment_NoCallbackURL(t *testing.T) {
+
+	s := NewTestGRPCServer(t)
+
+	s.PaymentsService.ProcessPaymentFunc = mockProcessPaymentFunc
+	s.GRPCServer.CallbackURL = ""
+
+	_, err := s.ProcessPayment(context.Background(), &pb.MpesaPaymentRequest{
+		Amount:      &pb.Money{Amount: 10000, Currency: "KES"},
+		PhoneNumber: 254700000000,
+		Reference:   "reference",
+	})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("GRPCServer.ProcessPayment() error = %v, want code %v", err, codes.Internal)
+	}
+}
+
+func TestGRPCServer_GetPayment(t *testing.T) {
+
+	s := NewTestGRPCServer(t)
+
+	payment := &repository.Payment{
+		Id:                 "payment-1",
+		OrderID:            "order-1",
+		Amount:             money.New(10000, "KES"),
+		Status:             repository.PaymentStatusPaid,
+		Phone:              "254700000000",
+		MerchantRequestID:  "29115-34620561-1",
+		CheckoutRequestID:  "ws_CO_191220191020363925",
+		CreatedAt:          "2019-12-19T10:20:36Z",
+		UpdatedAt:          "2019-12-19T10:21:20Z",
+		ResultDesc:         "The service request is processed successfully.",
+		MpesaReceiptNumber: "NLJ7RT61SV",
+		PaidAmount:         money.New(10000, "KES"),
+		PayerPhone:         "254700000000",
+		TransactionTime:    "2019-12-19T07:21:15Z",
+		CallbackToken:      "callback-token",
+	}
+
+	s.PaymentsRepository.GetPaymentByIDFunc = func(ctx context.Context, id string) (*repository.Payment, error) {
+		if id != payment.Id {
+			return nil, service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+		}
+		return payment, nil
+	}
+	s.PaymentsRepository.GetPaymentByReceiptNumberFunc = func(
+		ctx context.Context, receiptNumber string) (*repository.Payment, error) {
+		if receiptNumber != payment.MpesaReceiptNumber {
+			return nil, service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+		}
+		return payment, nil
+	}
+	s.RefundsRepository.ListRefundsByPaymentFunc = func(
+		ctx context.Context, paymentID string) ([]*repository.Refund, error) {
+		return []*repository.Refund{{
+			Id:            "refund-1",
+			PaymentID:     paymentID,
+			OrderID:       "order-1",
+			Amount:        money.New(4000, "KES"),
+			Status:        repository.RefundStatusCompleted,
+			ResultCode:    "0",
+			TransactionID: "NLJ41HAY6Q",
+			CallbackToken: "refund-token",
+		}}, nil
+	}
+
+	want := &pb.Payment{
+		Id:                 "payment-1",
+		OrderId:            "order-1",
+		Amount:             &pb.Money{Amount: 10000, Currency: "KES"},
+		Status:             pb.PaymentStatus_PAYMENT_STATUS_PAID,
+		PhoneNumber:        "254700000000",
+		MerchantRequestId:  "29115-34620561-1",
+		CheckoutRequestId:  "ws_CO_191220191020363925",
+		CreatedAt:          "2019-12-19T10:20:36Z",
+		UpdatedAt:          "2019-12-19T10:21:20Z",
+		ResultDesc:         "The service request is processed successfully.",
+		MpesaReceiptNumber: "NLJ7RT61SV",
+		PaidAmount:         &pb.Money{Amount: 10000, Currency: "KES"},
+		PayerPhoneNumber:   "254700000000",
+		TransactionTime:    "2019-12-19T07:21:15Z",
+	}
+
+	wantRefunds := []*pb.Refund{{
+		Id:            "refund-1",
+		PaymentId:     "payment-1",
+		OrderId:       "order-1",
+		Amount:        &pb.Money{Amount: 4000, Currency: "KES"},
+		Status:        pb.RefundStatus_REFUND_STATUS_COMPLETED,
+		ResultCode:    "0",
+		TransactionId: "NLJ41HAY6Q",
+	}}
+
+	tests := []struct {
+		name     string
+		in       *pb.GetPaymentRequest
+		want     *pb.Payment
+		wantCode codes.Code
+	}{
+		{
+			name: "By ID",
+			in:   &pb.GetPaymentRequest{Id: "payment-1"},
+			want: want,
+		},
+		{
+			name: "By Receipt Number",
+			in:   &pb.GetPaymentRequest{MpesaReceiptNumber: "NLJ7RT61SV"},
+			want: want,
+		},
+		{
+			name:     "Not Found",
+			in:       &pb.GetPaymentRequest{Id: "payment-2"},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "No Lookup",
+			in:       &pb.GetPaymentRequest{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Both Lookups",
+			in:       &pb.GetPaymentRequest{Id: "payment-1", MpesaReceiptNumber: "NLJ7RT61SV"},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := s.GetPayment(context.Background(), tt.in)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("GRPCServer.GetPayment() error = %v, want code %v", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			if !proto.Equal(got.GetPayment(), tt.want) {
+				t.Errorf("GRPCServer.GetPayment() = %v, want %v", got.GetPayment(), tt.want)
+			}
+
+			if len(got.GetRefunds()) != 1 || !proto.Equal(got.GetRefunds()[0], wantRefunds[0]) {
+				t.Errorf("GRPCServer.GetPayment() refunds = %v, want %v", got.GetRefunds(), wantRefunds)
+			}
+		})
+	}
+}
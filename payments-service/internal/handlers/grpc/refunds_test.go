@@ -0,0 +1,136 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/Mik3y-F/order-management-system/payments/api/generated"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	idempotency_memory "github.com/Mik3y-F/order-management-system/pkg/idempotency/memory"
+	idempotency_mock "github.com/Mik3y-F/order-management-system/pkg/idempotency/mock"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func mockRefundPaymentFunc(ctx context.Context, r *service.Refund) (*service.RefundResponse, error) {
+
+	if r.PaymentId != "payment-1" {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	}
+
+	if r.ResultURL != TEST_REFUND_CALLBACK_URL {
+		return nil, service.Errorf(service.INVALID_ERROR, "unexpected result url: %s", r.ResultURL)
+	}
+
+	amount := r.Amount
+	if amount.IsZero() {
+		amount = money.New(10000, "KES")
+	}
+
+	return &service.RefundResponse{
+		RefundId:                 "refund-1",
+		Amount:                   amount,
+		ConversationID:           "AG_20191219_00004e48cf7e3533f581",
+		OriginatorConversationID: "10571-7910404-1",
+		ResponseDescription:      "Accept the service request successfully.",
+	}, nil
+}
+
+func TestGRPCServer_RefundPayment(t *testing.T) {
+
+	s := NewTestGRPCServer(t)
+	s.PaymentsService.RefundPaymentFunc = mockRefundPaymentFunc
+
+	tests := []struct {
+		name       string
+		in         *pb.RefundPaymentRequest
+		wantAmount *pb.Money
+		wantCode   codes.Code
+	}{
+		{
+			name:       "Full Refund",
+			in:         &pb.RefundPaymentRequest{PaymentId: "payment-1", Reason: "Out of stock"},
+			wantAmount: &pb.Money{Amount: 10000, Currency: "KES"},
+		},
+		{
+			name: "Partial Refund",
+			in: &pb.RefundPaymentRequest{
+				PaymentId: "payment-1",
+				Amount:    &pb.Money{Amount: 4000, Currency: "KES"},
+			},
+			wantAmount: &pb.Money{Amount: 4000, Currency: "KES"},
+		},
+		{
+			name:     "No Payment ID",
+			in:       &pb.RefundPaymentRequest{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "Unknown Payment",
+			in:       &pb.RefundPaymentRequest{PaymentId: "payment-2"},
+			wantCode: codes.NotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := s.RefundPayment(context.Background(), tt.in)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("GRPCServer.RefundPayment() error = %v, want code %v", err, tt.wantCode)
+			}
+			if err != nil {
+				return
+			}
+
+			if got.GetRefundId() != "refund-1" || !proto.Equal(got.GetAmount(), tt.wantAmount) {
+				t.Errorf("GRPCServer.RefundPayment() = %v, want refund-1 of %v", got, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestGRPCServer_RefundPayment_NoCallbackURL(t *testing.T) {
+
+	s := NewTestGRPCServer(t)
+
+	s.PaymentsService.RefundPaymentFunc = mockRefundPaymentFunc
+	s.GRPCServer.RefundCallbackURL = ""
+
+	_, err := s.RefundPayment(context.Background(), &pb.RefundPaymentRequest{PaymentId: "payment-1"})
+	if status.Code(err) != codes.Internal {
+		t.Errorf("GRPCServer.RefundPayment() error = %v, want code %v", err, codes.Internal)
+	}
+}
+
+func TestGRPCServer_RefundPayment_Idempotent(t *testing.T) {
+
+	s := NewTestGRPCServer(t)
+	s.IdempotencyStore = *idempotency_mock.NewStore(idempotency_memory.NewStore())
+
+	requested := 0
+	s.PaymentsService.RefundPaymentFunc = func(ctx context.Context, r *service.Refund) (*service.RefundResponse, error) {
+		requested++
+		return mockRefundPaymentFunc(ctx, r)
+	}
+
+	in := &pb.RefundPaymentRequest{PaymentId: "payment-1", IdempotencyKey: "key-1"}
+
+	first, err := s.RefundPayment(context.Background(), in)
+	if err != nil {
+		t.Fatalf("GRPCServer.RefundPayment() error = %v", err)
+	}
+
+	retry, err := s.RefundPayment(context.Background(), in)
+	if err != nil {
+		t.Fatalf("GRPCServer.RefundPayment() retry error = %v", err)
+	}
+	if !proto.Equal(retry, first) {
+		t.Errorf("GRPCServer.RefundPayment() retry = %v, want %v", retry, first)
+	}
+
+	if requested != 1 {
+		t.Errorf("GRPCServer.RefundPayment() requested %d refunds, want 1", requested)
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"log"
 	"net"
 	"sync"
+	"time"
 
 	pb "github.com/Mik3y-F/order-management-system/payments/api/generated"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
 	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
 
 	"google.golang.org/grpc"
 )
@@ -21,6 +24,28 @@ type GRPCServer struct {
 
 	// Internal servicesx
 	PaymentsService service.PaymentsService
+
+	// PaymentsRepository serves GetPayment. Payments cannot be looked up
+	// without it.
+	PaymentsRepository repository.PaymentsRepository
+
+	// RefundsRepository adds the refunds of payments to GetPayment. They are
+	// left out without it.
+	RefundsRepository repository.RefundsRepository
+
+	// CallbackURL is where M-Pesa sends the results of payment requests.
+	// Payments cannot be processed without it.
+	CallbackURL string
+
+	// RefundCallbackURL is where M-Pesa sends the results of reversals.
+	// Payments cannot be refunded without it.
+	RefundCallbackURL string
+
+	// IdempotencyStore records the requests that carry an idempotency key for
+	// IdempotencyTTL, or idempotency.DefaultTTL if it is zero. Keys are
+	// ignored without it.
+	IdempotencyStore idempotency.Store
+	IdempotencyTTL   time.Duration
 }
 
 // NewGRPCServer creates a new instance of GRPCServer.
@@ -9,17 +9,23 @@ import (
 
 	ecom_grpc "github.com/Mik3y-F/order-management-system/payments/internal/handlers/grpc"
 	"github.com/Mik3y-F/order-management-system/payments/internal/mock"
+	idempotency_mock "github.com/Mik3y-F/order-management-system/pkg/idempotency/mock"
 )
 
 const (
-	INVALID_PORT = "70000"
+	INVALID_PORT             = "70000"
+	TEST_CALLBACK_URL        = "https://payments.example.com/callback"
+	TEST_REFUND_CALLBACK_URL = "https://payments.example.com/refunds"
 )
 
 type TestGRPCServer struct {
 	*ecom_grpc.GRPCServer
 
 	// Add mock services here
-	PaymentsService mock.PaymentsService
+	PaymentsService    mock.PaymentsService
+	PaymentsRepository mock.PaymentsRepository
+	RefundsRepository  mock.RefundsRepository
+	IdempotencyStore   idempotency_mock.Store
 }
 
 func NewTestGRPCServer(tb testing.TB) *TestGRPCServer {
@@ -29,6 +35,11 @@ func NewTestGRPCServer(tb testing.TB) *TestGRPCServer {
 
 	// Set mock services here
 	s.GRPCServer.PaymentsService = &s.PaymentsService
+	s.GRPCServer.PaymentsRepository = &s.PaymentsRepository
+	s.GRPCServer.RefundsRepository = &s.RefundsRepository
+	s.GRPCServer.IdempotencyStore = &s.IdempotencyStore
+	s.GRPCServer.CallbackURL = TEST_CALLBACK_URL
+	s.GRPCServer.RefundCallbackURL = TEST_REFUND_CALLBACK_URL
 
 	return s
 }
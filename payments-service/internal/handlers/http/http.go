@@ -32,6 +32,10 @@ type HTTPServer struct {
 	Addr   string
 	Domain string
 
+	// CallbackAllowlist restricts the addresses that M-Pesa callbacks are
+	// accepted from. Callbacks from any address are accepted if it is empty.
+	CallbackAllowlist []*net.IPNet
+
 	// Services
 	PaymentsService service.PaymentsService
 }
@@ -48,9 +52,12 @@ func NewHTTPServer() *HTTPServer {
 		},
 	}
 
+	s.server.Handler = s.router
+
 	s.router.Use(middleware.Logger)
 
 	s.registerCallbackRoutes(s.router)
+	s.registerRefundRoutes(s.router)
 
 	return s
 }
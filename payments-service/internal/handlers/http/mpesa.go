@@ -1,27 +1,154 @@
 package http
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
 	"github.com/go-chi/chi/v5"
 	"github.com/jwambugu/mpesa-golang-sdk"
 )
 
+// CallbackPath is the path M-Pesa POSTs the results of STK push requests to,
+// followed by the callback token of the payment.
+const CallbackPath = "/callback"
+
+// callbackResponse acknowledges a callback to M-Pesa.
+type callbackResponse struct {
+	ResultCode int    `json:"ResultCode"`
+	ResultDesc string `json:"ResultDesc"`
+}
+
 func (s *HTTPServer) registerCallbackRoutes(r *chi.Mux) {
-	r.Get("/callback", s.handleMpesaCallback)
+	r.Post(CallbackPath+"/{token}", s.handleMpesaCallback)
+}
+
+// CallbackURL returns the base URL of the M-Pesa callbacks of the running
+// server.
+func (s *HTTPServer) CallbackURL() string {
+	return s.URL() + CallbackPath
+}
+
+// ParseAllowlist parses a comma separated list of IP addresses and CIDR
+// ranges, e.g. "196.201.214.200,196.201.213.0/24".
+func ParseAllowlist(s string) ([]*net.IPNet, error) {
+	var allowlist []*net.IPNet
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", entry)
+			}
+
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+
+			allowlist = append(allowlist, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q", entry)
+		}
+
+		allowlist = append(allowlist, ipNet)
+	}
+
+	return allowlist, nil
+}
+
+// allowed reports whether callbacks are accepted from the remote address of
+// the request.
+func (s *HTTPServer) allowed(r *http.Request) bool {
+	if len(s.CallbackAllowlist) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range s.CallbackAllowlist {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (s *HTTPServer) handleMpesaCallback(w http.ResponseWriter, r *http.Request) {
+	if !s.allowed(r) {
+		log.Printf("rejected mpesa callback from %s", r.RemoteAddr)
+		writeCallbackResponse(w, http.StatusForbidden, "Rejected")
+		return
+	}
+
 	callback, err := mpesa.UnmarshalSTKPushCallback(r)
 	if err != nil {
-		log.Fatalln(err)
+		log.Printf("invalid mpesa callback: %v", err)
+		writeCallbackResponse(w, http.StatusBadRequest, "Rejected")
+		return
 	}
 
-	err = s.PaymentsService.HandleMpesaCallback(r.Context(), &callback.Body.STKCallback)
+	// Callbacks carry the phone number of the payer, so only their ids and
+	// results are logged, once they are verified.
+	stkCallback := &callback.Body.STKCallback
+	err = s.PaymentsService.HandleMpesaCallback(r.Context(), chi.URLParam(r, "token"), stkCallback)
 	if err != nil {
-		log.Fatalln(err)
+		log.Printf("failed to handle mpesa callback %s: %v", stkCallback.CheckoutRequestID, err)
+		writeCallbackResponse(w, errorStatusCode(err), "Rejected")
+		return
 	}
 
-	log.Printf("%+v", callback)
+	log.Printf("handled mpesa callback %s with result %d", stkCallback.CheckoutRequestID, stkCallback.ResultCode)
+
+	writeCallbackResponse(w, http.StatusOK, "Accepted")
+}
+
+// errorStatusCode returns the HTTP status code of an application error.
+func errorStatusCode(err error) int {
+	switch service.ErrorCode(err) {
+	case service.INVALID_ERROR:
+		return http.StatusBadRequest
+	case service.AUTHENTICATION_ERROR:
+		return http.StatusForbidden
+	case service.NOT_FOUND_ERROR:
+		return http.StatusNotFound
+	case service.CONFLICT_ERROR:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeCallbackResponse(w http.ResponseWriter, status int, desc string) {
+	resultCode := 0
+	if status != http.StatusOK {
+		resultCode = 1
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(callbackResponse{ResultCode: resultCode, ResultDesc: desc}); err != nil {
+		log.Printf("failed to write mpesa callback response: %v", err)
+	}
 }
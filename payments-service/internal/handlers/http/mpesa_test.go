@@ -0,0 +1,237 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	ecom_http "github.com/Mik3y-F/order-management-system/payments/internal/handlers/http"
+	"github.com/Mik3y-F/order-management-system/payments/internal/mock"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+)
+
+const testCallbackToken = "callback-token"
+
+const testCallback = `{
+	"Body": {
+		"stkCallback": {
+			"MerchantRequestID": "29115-34620561-1",
+			"CheckoutRequestID": "ws_CO_191220191020363925",
+			"ResultCode": 0,
+			"ResultDesc": "The service request is processed successfully."
+		}
+	}
+}`
+
+// openTestHTTPServer opens a server on a random port that is closed with the
+// test.
+func openTestHTTPServer(t *testing.T, payments *mock.PaymentsService) *ecom_http.HTTPServer {
+	t.Helper()
+
+	s := ecom_http.NewHTTPServer()
+	s.Addr = "localhost:0"
+	s.PaymentsService = payments
+
+	if err := s.Open(); err != nil {
+		t.Fatalf("failed to open server: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestHTTPServer_MpesaCallback(t *testing.T) {
+
+	var handled *service.PaymentCallback
+	payments := &mock.PaymentsService{
+		HandleMpesaCallbackFunc: func(ctx context.Context, token string, p *service.PaymentCallback) error {
+			if token != testCallbackToken {
+				return service.Errorf(service.AUTHENTICATION_ERROR, "invalid callback token")
+			}
+
+			if p.MerchantRequestID == "failing" {
+				return service.Errorf(service.INTERNAL_ERROR, "failed to update order")
+			}
+
+			handled = p
+			return nil
+		},
+	}
+
+	s := openTestHTTPServer(t, payments)
+
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		body           string
+		wantStatus     int
+		wantResultCode int
+	}{
+		{
+			name:       "Callback Accepted",
+			method:     http.MethodPost,
+			token:      testCallbackToken,
+			body:       testCallback,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid Token",
+			method:         http.MethodPost,
+			token:          "guessed-token",
+			body:           testCallback,
+			wantStatus:     http.StatusForbidden,
+			wantResultCode: 1,
+		},
+		{
+			name:           "Invalid Callback",
+			method:         http.MethodPost,
+			token:          testCallbackToken,
+			body:           "not json",
+			wantStatus:     http.StatusBadRequest,
+			wantResultCode: 1,
+		},
+		{
+			name:           "Callback Handling Failed",
+			method:         http.MethodPost,
+			token:          testCallbackToken,
+			body:           strings.Replace(testCallback, "29115-34620561-1", "failing", 1),
+			wantStatus:     http.StatusInternalServerError,
+			wantResultCode: 1,
+		},
+		{
+			name:       "Callback Method Not Allowed",
+			method:     http.MethodGet,
+			token:      testCallbackToken,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			req, err := http.NewRequest(tt.method, s.CallbackURL()+"/"+tt.token, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("failed to send callback: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("callback status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if tt.method != http.MethodPost {
+				return
+			}
+
+			var ack struct{ ResultCode int }
+			if err := json.NewDecoder(resp.Body).Decode(&ack); err != nil {
+				t.Fatalf("failed to decode callback response: %v", err)
+			}
+
+			if ack.ResultCode != tt.wantResultCode {
+				t.Errorf("callback ResultCode = %d, want %d", ack.ResultCode, tt.wantResultCode)
+			}
+		})
+	}
+
+	if handled == nil || handled.CheckoutRequestID != "ws_CO_191220191020363925" {
+		t.Errorf("HandleMpesaCallback() got %+v, want the accepted callback", handled)
+	}
+}
+
+func TestHTTPServer_MpesaCallback_Allowlist(t *testing.T) {
+
+	payments := &mock.PaymentsService{
+		HandleMpesaCallbackFunc: func(ctx context.Context, token string, p *service.PaymentCallback) error {
+			return nil
+		},
+	}
+
+	tests := []struct {
+		name       string
+		allowlist  string
+		wantStatus int
+	}{
+		{
+			name:       "Allowed Address",
+			allowlist:  "196.201.214.200, 127.0.0.1",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Allowed Range",
+			allowlist:  "127.0.0.0/8",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Address Not Allowed",
+			allowlist:  "196.201.214.0/24",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s := openTestHTTPServer(t, payments)
+
+			allowlist, err := ecom_http.ParseAllowlist(tt.allowlist)
+			if err != nil {
+				t.Fatalf("ParseAllowlist() error = %v", err)
+			}
+			s.CallbackAllowlist = allowlist
+
+			resp, err := http.Post(s.CallbackURL()+"/"+testCallbackToken, "application/json",
+				strings.NewReader(testCallback))
+			if err != nil {
+				t.Fatalf("failed to send callback: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("callback status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestParseAllowlist(t *testing.T) {
+
+	allowlist, err := ecom_http.ParseAllowlist("196.201.214.200,196.201.213.0/24,2001:db8::1")
+	if err != nil {
+		t.Fatalf("ParseAllowlist() error = %v", err)
+	}
+
+	contains := func(ip string) bool {
+		for _, ipNet := range allowlist {
+			if ipNet.Contains(net.ParseIP(ip)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for ip, want := range map[string]bool{
+		"196.201.214.200": true,
+		"196.201.214.201": false,
+		"196.201.213.17":  true,
+		"2001:db8::1":     true,
+		"2001:db8::2":     false,
+	} {
+		if got := contains(ip); got != want {
+			t.Errorf("allowlist contains %s = %v, want %v", ip, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"not-an-ip", "10.0.0.0/33"} {
+		if _, err := ecom_http.ParseAllowlist(invalid); err == nil {
+			t.Errorf("ParseAllowlist(%q) error = nil, want an error", invalid)
+		}
+	}
+}
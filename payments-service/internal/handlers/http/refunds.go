@@ -0,0 +1,76 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// RefundCallbackPath is the path M-Pesa POSTs the results of reversals to,
+// followed by the refund id, its callback token and "result" or "timeout".
+const RefundCallbackPath = "/refunds"
+
+// reversalResult is the body of the result of a reversal.
+type reversalResult struct {
+	Result *service.ReversalResult `json:"Result"`
+}
+
+func (s *HTTPServer) registerRefundRoutes(r *chi.Mux) {
+	r.Post(RefundCallbackPath+"/{id}/{token}/result", s.handleReversalResult)
+	r.Post(RefundCallbackPath+"/{id}/{token}/timeout", s.handleReversalTimeout)
+}
+
+// RefundCallbackURL returns the base URL of the M-Pesa reversal results of
+// the running server.
+func (s *HTTPServer) RefundCallbackURL() string {
+	return s.URL() + RefundCallbackPath
+}
+
+func (s *HTTPServer) handleReversalResult(w http.ResponseWriter, r *http.Request) {
+	if !s.allowed(r) {
+		log.Printf("rejected mpesa reversal result from %s", r.RemoteAddr)
+		writeCallbackResponse(w, http.StatusForbidden, "Rejected")
+		return
+	}
+
+	var body reversalResult
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Result == nil {
+		log.Printf("invalid mpesa reversal result: %v", err)
+		writeCallbackResponse(w, http.StatusBadRequest, "Rejected")
+		return
+	}
+
+	// Only the ids and results of verified results are logged, like those of
+	// payment callbacks.
+	refundID := chi.URLParam(r, "id")
+	err := s.PaymentsService.HandleReversalResult(r.Context(), refundID, chi.URLParam(r, "token"), body.Result)
+	if err != nil {
+		log.Printf("failed to handle mpesa reversal result of refund %s: %v", refundID, err)
+		writeCallbackResponse(w, errorStatusCode(err), "Rejected")
+		return
+	}
+	log.Printf("handled mpesa reversal result of refund %s with result %v", refundID, body.Result.ResultCode)
+
+	writeCallbackResponse(w, http.StatusOK, "Accepted")
+}
+
+func (s *HTTPServer) handleReversalTimeout(w http.ResponseWriter, r *http.Request) {
+	if !s.allowed(r) {
+		log.Printf("rejected mpesa reversal timeout from %s", r.RemoteAddr)
+		writeCallbackResponse(w, http.StatusForbidden, "Rejected")
+		return
+	}
+
+	refundID := chi.URLParam(r, "id")
+	err := s.PaymentsService.HandleReversalTimeout(r.Context(), refundID, chi.URLParam(r, "token"))
+	if err != nil {
+		log.Printf("failed to handle mpesa reversal timeout of refund %s: %v", refundID, err)
+		writeCallbackResponse(w, errorStatusCode(err), "Rejected")
+		return
+	}
+
+	writeCallbackResponse(w, http.StatusOK, "Accepted")
+}
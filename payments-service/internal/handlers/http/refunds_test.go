@@ -0,0 +1,165 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	ecom_http "github.com/Mik3y-F/order-management-system/payments/internal/handlers/http"
+	"github.com/Mik3y-F/order-management-system/payments/internal/mock"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+)
+
+const testReversalResult = `{
+	"Result": {
+		"ResultType": 0,
+		"ResultCode": 0,
+		"ResultDesc": "The service request is processed successfully.",
+		"OriginatorConversationID": "20001-1700000000-1",
+		"ConversationID": "AG_20231120_0123456789abcdef0123",
+		"TransactionID": "NLJ41HAY6Q"
+	}
+}`
+
+func TestHTTPServer_ReversalResult(t *testing.T) {
+
+	var handled *service.ReversalResult
+	var timedOut string
+	authenticate := func(refundID, token string) error {
+		if token != testCallbackToken {
+			return service.Errorf(service.AUTHENTICATION_ERROR, "invalid callback token")
+		}
+
+		if refundID == "settled" {
+			return service.Errorf(service.CONFLICT_ERROR, "refund is already completed")
+		}
+
+		return nil
+	}
+
+	payments := &mock.PaymentsService{
+		HandleReversalResultFunc: func(
+			ctx context.Context, refundID, token string, r *service.ReversalResult) error {
+			if err := authenticate(refundID, token); err != nil {
+				return err
+			}
+
+			handled = r
+			return nil
+		},
+		HandleReversalTimeoutFunc: func(ctx context.Context, refundID, token string) error {
+			if err := authenticate(refundID, token); err != nil {
+				return err
+			}
+
+			timedOut = refundID
+			return nil
+		},
+	}
+
+	s := openTestHTTPServer(t, payments)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		wantStatus     int
+		wantResultCode int
+	}{
+		{
+			name:       "Result Accepted",
+			path:       "/refund-1/" + testCallbackToken + "/result",
+			body:       testReversalResult,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Timeout Accepted",
+			path:       "/refund-2/" + testCallbackToken + "/timeout",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid Token",
+			path:           "/refund-1/guessed-token/result",
+			body:           testReversalResult,
+			wantStatus:     http.StatusForbidden,
+			wantResultCode: 1,
+		},
+		{
+			name:           "Invalid Result",
+			path:           "/refund-1/" + testCallbackToken + "/result",
+			body:           "{}",
+			wantStatus:     http.StatusBadRequest,
+			wantResultCode: 1,
+		},
+		{
+			name:           "Already Settled",
+			path:           "/settled/" + testCallbackToken + "/timeout",
+			wantStatus:     http.StatusConflict,
+			wantResultCode: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			resp, err := http.Post(s.RefundCallbackURL()+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("failed to send reversal result: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("reversal result status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			var ack struct{ ResultCode int }
+			if err := json.NewDecoder(resp.Body).Decode(&ack); err != nil {
+				t.Fatalf("failed to decode reversal result response: %v", err)
+			}
+
+			if ack.ResultCode != tt.wantResultCode {
+				t.Errorf("reversal result ResultCode = %d, want %d", ack.ResultCode, tt.wantResultCode)
+			}
+		})
+	}
+
+	if handled == nil || handled.TransactionID != "NLJ41HAY6Q" || fmt.Sprint(handled.ResultCode) != "0" {
+		t.Errorf("HandleReversalResult() got %+v, want the accepted result", handled)
+	}
+
+	if timedOut != "refund-2" {
+		t.Errorf("HandleReversalTimeout() got refund %q, want %q", timedOut, "refund-2")
+	}
+}
+
+func TestHTTPServer_ReversalResult_Allowlist(t *testing.T) {
+
+	payments := &mock.PaymentsService{
+		HandleReversalResultFunc: func(
+			ctx context.Context, refundID, token string, r *service.ReversalResult) error {
+			t.Errorf("HandleReversalResult() called for a rejected address")
+			return nil
+		},
+	}
+
+	s := openTestHTTPServer(t, payments)
+
+	allowlist, err := ecom_http.ParseAllowlist("196.201.214.0/24")
+	if err != nil {
+		t.Fatalf("ParseAllowlist() error = %v", err)
+	}
+	s.CallbackAllowlist = allowlist
+
+	resp, err := http.Post(s.RefundCallbackURL()+"/refund-1/"+testCallbackToken+"/result", "application/json",
+		strings.NewReader(testReversalResult))
+	if err != nil {
+		t.Fatalf("failed to send reversal result: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("reversal result status = %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
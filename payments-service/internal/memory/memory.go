@@ -0,0 +1,27 @@
+// Package memory provides thread-safe, in-memory implementations of the
+// repository interfaces. They are intended for local runs and tests where a
+// Firestore project or emulator is not available; all data is lost when the
+// process exits.
+package memory
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// newID returns a random 20 character identifier, matching the length of the
+// auto-generated Firestore document IDs.
+func newID() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic("failed to generate id: " + err.Error())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// now returns the current time formatted the same way as the other backends.
+func now() string {
+	return time.Now().Format(time.RFC3339)
+}
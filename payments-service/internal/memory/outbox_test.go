@@ -0,0 +1,16 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/memory"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.PaymentsRepository, repository.OutboxRepository) {
+		payments := memory.NewPaymentsRepository()
+		return payments, payments.Outbox()
+	})
+}
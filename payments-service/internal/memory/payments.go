@@ -0,0 +1,263 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+)
+
+var _ repository.PaymentsRepository = (*PaymentsRepository)(nil)
+
+type PaymentsRepository struct {
+	mu       sync.RWMutex
+	payments map[string]repository.Payment
+
+	// outbox records the events of payment creations and status changes.
+	outbox *OutboxRepository
+}
+
+func NewPaymentsRepository() *PaymentsRepository {
+	return &PaymentsRepository{
+		payments: make(map[string]repository.Payment),
+		outbox:   NewOutboxRepository(),
+	}
+}
+
+// Outbox returns the outbox that the repository records its events in.
+func (r *PaymentsRepository) Outbox() *OutboxRepository {
+	return r.outbox
+}
+
+func (r *PaymentsRepository) CreatePayment(ctx context.Context, payment *repository.Payment) (string, error) {
+	currentTime := now()
+	payment.CreatedAt = currentTime
+	payment.UpdatedAt = currentTime
+
+	err := payment.Validate()
+	if err != nil {
+		return "", service.Errorf(service.INVALID_ERROR, "invalid payment details provided: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment.Id = newID()
+
+	event, err := repository.NewPaymentCreatedEvent(payment)
+	if err != nil {
+		return "", err
+	}
+
+	r.payments[payment.Id] = *payment
+	r.outbox.add(event)
+
+	return payment.Id, nil
+}
+
+func (r *PaymentsRepository) GetPaymentByID(ctx context.Context, paymentID string) (*repository.Payment, error) {
+	if paymentID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	}
+
+	return &payment, nil
+}
+
+func (r *PaymentsRepository) GetPaymentByMerchantRequestID(
+	ctx context.Context, merchantRequestID string) (*repository.Payment, error) {
+
+	if merchantRequestID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid merchant request ID provided")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, payment := range r.payments {
+		if payment.MerchantRequestID == merchantRequestID {
+			return &payment, nil
+		}
+	}
+
+	return nil, service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+}
+
+func (r *PaymentsRepository) GetPaymentByReceiptNumber(
+	ctx context.Context, receiptNumber string) (*repository.Payment, error) {
+
+	if receiptNumber == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid receipt number provided")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, payment := range r.payments {
+		if payment.MpesaReceiptNumber == receiptNumber {
+			return &payment, nil
+		}
+	}
+
+	return nil, service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+}
+
+func (r *PaymentsRepository) UpdatePaymentStatus(
+	ctx context.Context, paymentID string, status repository.PaymentStatus) error {
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	}
+
+	payment.Status = status
+	payment.UpdatedAt = now()
+
+	event, err := repository.NewPaymentStatusEvent(&payment)
+	if err != nil {
+		return err
+	}
+
+	r.payments[paymentID] = payment
+	r.outbox.add(event)
+
+	return nil
+}
+
+func (r *PaymentsRepository) SetPaymentRequestIDs(
+	ctx context.Context, paymentID, merchantRequestID, checkoutRequestID string) error {
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	}
+
+	payment.MerchantRequestID = merchantRequestID
+	payment.CheckoutRequestID = checkoutRequestID
+	payment.UpdatedAt = now()
+
+	r.payments[paymentID] = payment
+
+	return nil
+}
+
+func (r *PaymentsRepository) SettlePayment(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	}
+
+	if payment.Status != repository.PaymentStatusPending {
+		return service.Errorf(service.CONFLICT_ERROR, "payment %s is already %s", paymentID, payment.Status)
+	}
+
+	payment.Settle(result)
+	payment.UpdatedAt = now()
+
+	event, err := repository.NewPaymentStatusEvent(&payment)
+	if err != nil {
+		return err
+	}
+
+	r.payments[paymentID] = payment
+	r.outbox.add(event)
+
+	return nil
+}
+
+func (r *PaymentsRepository) RecordPaymentReceipt(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	payment, ok := r.payments[paymentID]
+	if !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "payment not found")
+	}
+
+	if payment.Status != repository.PaymentStatusPaid || payment.MpesaReceiptNumber != "" {
+		return service.Errorf(service.CONFLICT_ERROR,
+			"payment %s is %s with receipt %q, only paid payments without one can record it",
+			paymentID, payment.Status, payment.MpesaReceiptNumber)
+	}
+
+	payment.RecordReceipt(result)
+	payment.UpdatedAt = now()
+
+	r.payments[paymentID] = payment
+
+	return nil
+}
+
+func (r *PaymentsRepository) ListPendingPayments(
+	ctx context.Context, createdBefore time.Time, limit int) ([]*repository.Payment, error) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var payments []*repository.Payment
+	for _, payment := range r.payments {
+		if payment.Status != repository.PaymentStatusPending || payment.CheckoutRequestID == "" {
+			continue
+		}
+
+		createdAt, err := time.Parse(time.RFC3339, payment.CreatedAt)
+		if err != nil || !createdAt.Before(createdBefore) {
+			continue
+		}
+
+		p := payment
+		payments = append(payments, &p)
+	}
+
+	sort.Slice(payments, func(i, j int) bool {
+		if payments[i].CreatedAt != payments[j].CreatedAt {
+			return payments[i].CreatedAt < payments[j].CreatedAt
+		}
+		return payments[i].Id < payments[j].Id
+	})
+
+	if len(payments) > limit {
+		payments = payments[:limit]
+	}
+
+	return payments, nil
+}
@@ -0,0 +1,57 @@
+package memory_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/memory"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func TestPaymentsRepository(t *testing.T) {
+	repositorytest.TestPaymentsRepository(t, func(t *testing.T) repository.PaymentsRepository {
+		return memory.NewPaymentsRepository()
+	})
+}
+
+func TestPaymentsRepository_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	paymentsRepository := memory.NewPaymentsRepository()
+
+	id, err := paymentsRepository.CreatePayment(ctx, &repository.Payment{
+		Amount:            money.New(100, "KES"),
+		MerchantRequestID: "merchant-request-1",
+		Status:            repository.PaymentStatusPending,
+	})
+	if err != nil {
+		t.Fatalf("failed to create payment: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := paymentsRepository.UpdatePaymentStatus(ctx, id, repository.PaymentStatusPaid); err != nil {
+				t.Errorf("PaymentsRepository.UpdatePaymentStatus() error = %v", err)
+			}
+
+			if _, err := paymentsRepository.GetPaymentByMerchantRequestID(ctx, "merchant-request-1"); err != nil {
+				t.Errorf("PaymentsRepository.GetPaymentByMerchantRequestID() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := paymentsRepository.GetPaymentByID(ctx, id)
+	if err != nil {
+		t.Fatalf("PaymentsRepository.GetPaymentByID() error = %v", err)
+	}
+	if got.Status != repository.PaymentStatusPaid {
+		t.Errorf("PaymentsRepository.GetPaymentByID() status = %q, want %q", got.Status, repository.PaymentStatusPaid)
+	}
+}
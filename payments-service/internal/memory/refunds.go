@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+var _ repository.RefundsRepository = (*RefundsRepository)(nil)
+
+type RefundsRepository struct {
+	mu      sync.RWMutex
+	refunds map[string]repository.Refund
+	order   []string // ids in the order the refunds were created
+
+	// outbox records the events of refund creations and status changes.
+	outbox *OutboxRepository
+}
+
+// NewRefundsRepository returns a repository that records its events in
+// outbox, e.g. the Outbox of the payments repository, or in an outbox of its
+// own if it is nil.
+func NewRefundsRepository(outbox *OutboxRepository) *RefundsRepository {
+	if outbox == nil {
+		outbox = NewOutboxRepository()
+	}
+
+	return &RefundsRepository{
+		refunds: make(map[string]repository.Refund),
+		outbox:  outbox,
+	}
+}
+
+// Outbox returns the outbox that the repository records its events in.
+func (r *RefundsRepository) Outbox() *OutboxRepository {
+	return r.outbox
+}
+
+func (r *RefundsRepository) CreateRefund(
+	ctx context.Context, refund *repository.Refund, limit money.Money) (string, error) {
+
+	currentTime := now()
+	refund.CreatedAt = currentTime
+	refund.UpdatedAt = currentTime
+
+	err := refund.Validate()
+	if err != nil {
+		return "", service.Errorf(service.INVALID_ERROR, "invalid refund details provided: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var refunds []*repository.Refund
+	for _, id := range r.order {
+		if other := r.refunds[id]; other.PaymentID == refund.PaymentID {
+			refunds = append(refunds, &other)
+		}
+	}
+
+	if err := repository.CheckRefundLimit(refund, refunds, limit); err != nil {
+		return "", err
+	}
+
+	refund.Id = newID()
+
+	event, err := repository.NewRefundCreatedEvent(refund)
+	if err != nil {
+		return "", err
+	}
+
+	r.refunds[refund.Id] = *refund
+	r.order = append(r.order, refund.Id)
+	r.outbox.add(event)
+
+	return refund.Id, nil
+}
+
+func (r *RefundsRepository) GetRefundByID(ctx context.Context, refundID string) (*repository.Refund, error) {
+	if refundID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid refund ID provided")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	refund, ok := r.refunds[refundID]
+	if !ok {
+		return nil, service.Errorf(service.NOT_FOUND_ERROR, "refund not found")
+	}
+
+	return &refund, nil
+}
+
+func (r *RefundsRepository) ListRefundsByPayment(
+	ctx context.Context, paymentID string) ([]*repository.Refund, error) {
+
+	if paymentID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var refunds []*repository.Refund
+	for _, id := range r.order {
+		refund := r.refunds[id]
+		if refund.PaymentID == paymentID {
+			refunds = append(refunds, &refund)
+		}
+	}
+
+	return refunds, nil
+}
+
+func (r *RefundsRepository) SettleRefund(
+	ctx context.Context, refundID string, result *repository.RefundResult) error {
+
+	if refundID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid refund ID provided")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	refund, ok := r.refunds[refundID]
+	if !ok {
+		return service.Errorf(service.NOT_FOUND_ERROR, "refund not found")
+	}
+
+	if refund.Status != repository.RefundStatusPending {
+		return service.Errorf(service.CONFLICT_ERROR, "refund %s is already %s", refundID, refund.Status)
+	}
+
+	refund.Settle(result)
+	refund.UpdatedAt = now()
+
+	event, err := repository.NewRefundStatusEvent(&refund)
+	if err != nil {
+		return err
+	}
+
+	r.refunds[refundID] = refund
+	r.outbox.add(event)
+
+	return nil
+}
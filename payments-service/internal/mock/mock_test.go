@@ -0,0 +1,31 @@
+package mock_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/memory"
+	"github.com/Mik3y-F/order-management-system/payments/internal/mock"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+)
+
+// The delegating mocks must be indistinguishable from the repository they wrap.
+
+func TestPaymentsRepository(t *testing.T) {
+	repositorytest.TestPaymentsRepository(t, func(t *testing.T) repository.PaymentsRepository {
+		return mock.NewPaymentsRepository(memory.NewPaymentsRepository())
+	})
+}
+
+func TestRefundsRepository(t *testing.T) {
+	repositorytest.TestRefundsRepository(t, func(t *testing.T) repository.RefundsRepository {
+		return mock.NewRefundsRepository(memory.NewRefundsRepository(nil))
+	})
+}
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.PaymentsRepository, repository.OutboxRepository) {
+		payments := memory.NewPaymentsRepository()
+		return mock.NewPaymentsRepository(payments), mock.NewOutboxRepository(payments.Outbox())
+	})
+}
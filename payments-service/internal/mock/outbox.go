@@ -0,0 +1,31 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+var _ repository.OutboxRepository = (*OutboxRepository)(nil)
+
+type OutboxRepository struct {
+	ListOutboxEventsFunc   func(ctx context.Context, limit int) ([]*outbox.Event, error)
+	DeleteOutboxEventsFunc func(ctx context.Context, ids []string) error
+}
+
+// NewOutboxRepository returns a mock that delegates every call to r.
+func NewOutboxRepository(r repository.OutboxRepository) *OutboxRepository {
+	return &OutboxRepository{
+		ListOutboxEventsFunc:   r.ListOutboxEvents,
+		DeleteOutboxEventsFunc: r.DeleteOutboxEvents,
+	}
+}
+
+func (m *OutboxRepository) ListOutboxEvents(ctx context.Context, limit int) ([]*outbox.Event, error) {
+	return m.ListOutboxEventsFunc(ctx, limit)
+}
+
+func (m *OutboxRepository) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	return m.DeleteOutboxEventsFunc(ctx, ids)
+}
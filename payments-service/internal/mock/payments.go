@@ -10,13 +10,31 @@ var _ service.PaymentsService = (*PaymentsService)(nil)
 
 type PaymentsService struct {
 	ProcessPaymentFunc      func(ctx context.Context, p *service.Payment) (*service.PaymentResponse, error)
-	HandleMpesaCallbackFunc func(ctx context.Context, p *service.PaymentCallback) error
+	HandleMpesaCallbackFunc func(ctx context.Context, token string, p *service.PaymentCallback) error
+
+	RefundPaymentFunc         func(ctx context.Context, r *service.Refund) (*service.RefundResponse, error)
+	HandleReversalResultFunc  func(ctx context.Context, refundID, token string, r *service.ReversalResult) error
+	HandleReversalTimeoutFunc func(ctx context.Context, refundID, token string) error
 }
 
 func (m *PaymentsService) ProcessPayment(ctx context.Context, p *service.Payment) (*service.PaymentResponse, error) {
 	return m.ProcessPaymentFunc(ctx, p)
 }
 
-func (m *PaymentsService) HandleMpesaCallback(ctx context.Context, p *service.PaymentCallback) error {
-	return m.HandleMpesaCallbackFunc(ctx, p)
+func (m *PaymentsService) HandleMpesaCallback(
+	ctx context.Context, token string, p *service.PaymentCallback) error {
+	return m.HandleMpesaCallbackFunc(ctx, token, p)
+}
+
+func (m *PaymentsService) RefundPayment(ctx context.Context, r *service.Refund) (*service.RefundResponse, error) {
+	return m.RefundPaymentFunc(ctx, r)
+}
+
+func (m *PaymentsService) HandleReversalResult(
+	ctx context.Context, refundID, token string, r *service.ReversalResult) error {
+	return m.HandleReversalResultFunc(ctx, refundID, token, r)
+}
+
+func (m *PaymentsService) HandleReversalTimeout(ctx context.Context, refundID, token string) error {
+	return m.HandleReversalTimeoutFunc(ctx, refundID, token)
 }
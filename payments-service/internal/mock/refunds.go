@@ -0,0 +1,46 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+var _ repository.RefundsRepository = (*RefundsRepository)(nil)
+
+type RefundsRepository struct {
+	CreateRefundFunc         func(ctx context.Context, refund *repository.Refund, limit money.Money) (string, error)
+	GetRefundByIDFunc        func(ctx context.Context, refundID string) (*repository.Refund, error)
+	ListRefundsByPaymentFunc func(ctx context.Context, paymentID string) ([]*repository.Refund, error)
+	SettleRefundFunc         func(ctx context.Context, refundID string, result *repository.RefundResult) error
+}
+
+// NewRefundsRepository returns a mock that delegates every call to r.
+func NewRefundsRepository(r repository.RefundsRepository) *RefundsRepository {
+	return &RefundsRepository{
+		CreateRefundFunc:         r.CreateRefund,
+		GetRefundByIDFunc:        r.GetRefundByID,
+		ListRefundsByPaymentFunc: r.ListRefundsByPayment,
+		SettleRefundFunc:         r.SettleRefund,
+	}
+}
+
+func (m *RefundsRepository) CreateRefund(
+	ctx context.Context, refund *repository.Refund, limit money.Money) (string, error) {
+	return m.CreateRefundFunc(ctx, refund, limit)
+}
+
+func (m *RefundsRepository) GetRefundByID(ctx context.Context, refundID string) (*repository.Refund, error) {
+	return m.GetRefundByIDFunc(ctx, refundID)
+}
+
+func (m *RefundsRepository) ListRefundsByPayment(
+	ctx context.Context, paymentID string) ([]*repository.Refund, error) {
+	return m.ListRefundsByPaymentFunc(ctx, paymentID)
+}
+
+func (m *RefundsRepository) SettleRefund(
+	ctx context.Context, refundID string, result *repository.RefundResult) error {
+	return m.SettleRefundFunc(ctx, refundID, result)
+}
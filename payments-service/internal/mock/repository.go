@@ -0,0 +1,82 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+)
+
+var _ repository.PaymentsRepository = (*PaymentsRepository)(nil)
+
+type PaymentsRepository struct {
+	CreatePaymentFunc                 func(ctx context.Context, payment *repository.Payment) (string, error)
+	GetPaymentByIDFunc                func(ctx context.Context, paymentID string) (*repository.Payment, error)
+	GetPaymentByMerchantRequestIDFunc func(ctx context.Context, merchantRequestID string) (*repository.Payment, error)
+	GetPaymentByReceiptNumberFunc     func(ctx context.Context, receiptNumber string) (*repository.Payment, error)
+	UpdatePaymentStatusFunc           func(ctx context.Context, paymentID string, status repository.PaymentStatus) error
+	SetPaymentRequestIDsFunc          func(ctx context.Context, paymentID, merchantRequestID, checkoutRequestID string) error
+	SettlePaymentFunc                 func(ctx context.Context, paymentID string, result *repository.PaymentResult) error
+	RecordPaymentReceiptFunc          func(ctx context.Context, paymentID string, result *repository.PaymentResult) error
+	ListPendingPaymentsFunc           func(
+		ctx context.Context, createdBefore time.Time, limit int) ([]*repository.Payment, error)
+}
+
+// NewPaymentsRepository returns a mock that delegates every call to r. Tests
+// can then replace individual functions and keep working storage for the rest.
+func NewPaymentsRepository(r repository.PaymentsRepository) *PaymentsRepository {
+	return &PaymentsRepository{
+		CreatePaymentFunc:                 r.CreatePayment,
+		GetPaymentByIDFunc:                r.GetPaymentByID,
+		GetPaymentByMerchantRequestIDFunc: r.GetPaymentByMerchantRequestID,
+		GetPaymentByReceiptNumberFunc:     r.GetPaymentByReceiptNumber,
+		UpdatePaymentStatusFunc:           r.UpdatePaymentStatus,
+		SetPaymentRequestIDsFunc:          r.SetPaymentRequestIDs,
+		SettlePaymentFunc:                 r.SettlePayment,
+		RecordPaymentReceiptFunc:          r.RecordPaymentReceipt,
+		ListPendingPaymentsFunc:           r.ListPendingPayments,
+	}
+}
+
+func (m *PaymentsRepository) CreatePayment(ctx context.Context, payment *repository.Payment) (string, error) {
+	return m.CreatePaymentFunc(ctx, payment)
+}
+
+func (m *PaymentsRepository) GetPaymentByID(ctx context.Context, paymentID string) (*repository.Payment, error) {
+	return m.GetPaymentByIDFunc(ctx, paymentID)
+}
+
+func (m *PaymentsRepository) GetPaymentByMerchantRequestID(
+	ctx context.Context, merchantRequestID string) (*repository.Payment, error) {
+	return m.GetPaymentByMerchantRequestIDFunc(ctx, merchantRequestID)
+}
+
+func (m *PaymentsRepository) GetPaymentByReceiptNumber(
+	ctx context.Context, receiptNumber string) (*repository.Payment, error) {
+	return m.GetPaymentByReceiptNumberFunc(ctx, receiptNumber)
+}
+
+func (m *PaymentsRepository) UpdatePaymentStatus(
+	ctx context.Context, paymentID string, status repository.PaymentStatus) error {
+	return m.UpdatePaymentStatusFunc(ctx, paymentID, status)
+}
+
+func (m *PaymentsRepository) SetPaymentRequestIDs(
+	ctx context.Context, paymentID, merchantRequestID, checkoutRequestID string) error {
+	return m.SetPaymentRequestIDsFunc(ctx, paymentID, merchantRequestID, checkoutRequestID)
+}
+
+func (m *PaymentsRepository) SettlePayment(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+	return m.SettlePaymentFunc(ctx, paymentID, result)
+}
+
+func (m *PaymentsRepository) RecordPaymentReceipt(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+	return m.RecordPaymentReceiptFunc(ctx, paymentID, result)
+}
+
+func (m *PaymentsRepository) ListPendingPayments(
+	ctx context.Context, createdBefore time.Time, limit int) ([]*repository.Payment, error) {
+	return m.ListPendingPaymentsFunc(ctx, createdBefore, limit)
+}
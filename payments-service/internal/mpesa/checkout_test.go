@@ -0,0 +1,227 @@
+package mpesa_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ecom_http "github.com/Mik3y-F/order-management-system/payments/internal/handlers/http"
+	"github.com/Mik3y-F/order-management-system/payments/internal/memory"
+	ecom_mpesa "github.com/Mik3y-F/order-management-system/payments/internal/mpesa"
+	"github.com/Mik3y-F/order-management-system/payments/internal/mpesa/mpesatest"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"github.com/jwambugu/mpesa-golang-sdk"
+)
+
+// checkout runs payments against a fake Daraja API, which sends its
+// callbacks to the HTTP server of the payments service.
+type checkout struct {
+	daraja     *mpesatest.Server
+	payments   *memory.PaymentsRepository
+	refunds    *memory.RefundsRepository
+	orders     *ordersClient
+	service    *ecom_mpesa.PaymentsService
+	reconciler *ecom_mpesa.Reconciler
+	callbacks  *ecom_http.HTTPServer
+}
+
+func setupCheckout(t *testing.T) *checkout {
+	t.Helper()
+
+	t.Setenv(ecom_mpesa.MPESA_BUSINESS_SHORT_CODE, "174379")
+	t.Setenv(ecom_mpesa.MPESA_PASSKEY, "passkey")
+	t.Setenv(ecom_mpesa.MPESA_INITIATOR_NAME, "testapi")
+	t.Setenv(ecom_mpesa.MPESA_SECURITY_CREDENTIAL, "credential")
+
+	daraja := mpesatest.NewServer()
+	daraja.ConsumerKey = "key"
+	daraja.ConsumerSecret = "secret"
+	daraja.Passkey = "passkey"
+	daraja.ManualCallbacks = true
+
+	server := httptest.NewServer(daraja)
+	t.Cleanup(server.Close)
+
+	m, err := ecom_mpesa.NewMpesa(server.Client(), "key", "secret", mpesa.Sandbox, server.URL)
+	if err != nil {
+		t.Fatalf("NewMpesa() error = %v", err)
+	}
+
+	payments := memory.NewPaymentsRepository()
+	c := &checkout{
+		daraja:   daraja,
+		payments: payments,
+		refunds:  memory.NewRefundsRepository(payments.Outbox()),
+		orders:   &ordersClient{},
+	}
+	c.service = ecom_mpesa.NewPaymentsService(m, c.orders, c.payments, c.refunds)
+	c.reconciler = ecom_mpesa.NewReconciler(c.service)
+
+	c.callbacks = ecom_http.NewHTTPServer()
+	c.callbacks.Addr = "localhost:0"
+	c.callbacks.PaymentsService = c.service
+	if err := c.callbacks.Open(); err != nil {
+		t.Fatalf("failed to open callback server: %v", err)
+	}
+	t.Cleanup(func() { c.callbacks.Close() })
+
+	return c
+}
+
+// pay requests a payment of KES 100 from the phone number.
+func (c *checkout) pay(t *testing.T, phoneNumber uint) *service.PaymentResponse {
+	t.Helper()
+
+	res, err := c.service.ProcessPayment(context.Background(), &service.Payment{
+		OrderId:     "order-1",
+		PhoneNumber: phoneNumber,
+		Amount:      money.New(10000, "KES"),
+		Reference:   "order-1",
+		Description: "Order 1",
+		CallbackURL: c.callbacks.CallbackURL(),
+	})
+	if err != nil {
+		t.Fatalf("ProcessPayment() error = %v", err)
+	}
+
+	return res
+}
+
+// wantPaymentStatus checks the status of the payment, and returns it.
+func (c *checkout) wantPaymentStatus(
+	t *testing.T, res *service.PaymentResponse, want repository.PaymentStatus) *repository.Payment {
+	t.Helper()
+
+	payment, err := c.payments.GetPaymentByMerchantRequestID(context.Background(), res.MerchantRequestID)
+	if err != nil {
+		t.Fatalf("failed to get payment: %v", err)
+	}
+
+	if payment.Status != want {
+		t.Errorf("payment %s has status %q, want %q", res.CheckoutRequestID, payment.Status, want)
+	}
+
+	return payment
+}
+
+func TestCheckout(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     mpesatest.Result
+		wantStatus repository.PaymentStatus
+	}{
+		{
+			name:       "Success",
+			result:     mpesatest.ResultSuccess,
+			wantStatus: repository.PaymentStatusPaid,
+		},
+		{
+			name:       "User Cancelled",
+			result:     mpesatest.ResultCancelled,
+			wantStatus: repository.PaymentStatusFailed,
+		},
+		{
+			name:       "Insufficient Funds",
+			result:     mpesatest.ResultInsufficientFunds,
+			wantStatus: repository.PaymentStatusFailed,
+		},
+		{
+			name:       "Timeout",
+			result:     mpesatest.ResultTimeout,
+			wantStatus: repository.PaymentStatusFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			c := setupCheckout(t)
+			c.daraja.Script(254700000000, tt.result)
+
+			res := c.pay(t, 254700000000)
+			c.wantPaymentStatus(t, res, repository.PaymentStatusPending)
+
+			transaction, ok := c.daraja.Transaction(res.CheckoutRequestID)
+			if !ok || transaction.Request.Amount != 100 || transaction.Request.PhoneNumber != 254700000000 {
+				t.Fatalf("STK push = %+v, want KES 100 from 254700000000", transaction)
+			}
+
+			if err := c.daraja.SendCallbacks(ctx); err != nil {
+				t.Fatalf("SendCallbacks() error = %v", err)
+			}
+
+			payment := c.wantPaymentStatus(t, res, tt.wantStatus)
+			if payment.ResultCode != tt.result.Code || payment.ResultDesc != tt.result.Desc {
+				t.Errorf("payment result = %d: %s, want %d: %s",
+					payment.ResultCode, payment.ResultDesc, tt.result.Code, tt.result.Desc)
+			}
+
+			if tt.wantStatus != repository.PaymentStatusPaid {
+				if payment.MpesaReceiptNumber != "" {
+					t.Errorf("failed payment has receipt number %q", payment.MpesaReceiptNumber)
+				}
+				return
+			}
+
+			transaction, _ = c.daraja.Transaction(res.CheckoutRequestID)
+			if payment.MpesaReceiptNumber == "" || payment.MpesaReceiptNumber != transaction.ReceiptNumber {
+				t.Errorf("payment receipt number = %q, want %q", payment.MpesaReceiptNumber, transaction.ReceiptNumber)
+			}
+
+			if want := money.New(10000, "KES"); payment.PaidAmount != want {
+				t.Errorf("payment paid amount = %v, want %v", payment.PaidAmount, want)
+			}
+
+			if payment.PayerPhone != "254700000000" {
+				t.Errorf("payment payer phone = %q, want %q", payment.PayerPhone, "254700000000")
+			}
+
+			transactionTime, err := time.Parse(time.RFC3339, payment.TransactionTime)
+			if err != nil || time.Since(transactionTime) > time.Minute {
+				t.Errorf("payment transaction time = %q, want the time of the callback", payment.TransactionTime)
+			}
+
+			got, err := c.payments.GetPaymentByReceiptNumber(ctx, payment.MpesaReceiptNumber)
+			if err != nil || got.Id != payment.Id {
+				t.Errorf("GetPaymentByReceiptNumber() = %v, %v, want payment %s", got, err, payment.Id)
+			}
+		})
+	}
+}
+
+func TestCheckout_InvalidRequest(t *testing.T) {
+	c := setupCheckout(t)
+
+	// The fake rejects STK pushes with an invalid password.
+	c.daraja.Passkey = "another-passkey"
+
+	_, err := c.service.ProcessPayment(context.Background(), &service.Payment{
+		OrderId:     "order-1",
+		PhoneNumber: 254700000000,
+		Amount:      money.New(10000, "KES"),
+		CallbackURL: c.callbacks.CallbackURL(),
+	})
+	if err == nil {
+		t.Fatalf("ProcessPayment() error = nil, want an error")
+	}
+
+	// The payment was stored before the push, and fails with it.
+	events, err := c.payments.Outbox().ListOutboxEvents(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("ListOutboxEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("ProcessPayment() recorded %d events, want the creation and failure of the payment", len(events))
+	}
+
+	payment, err := c.payments.GetPaymentByID(context.Background(), events[0].AggregateId)
+	if err != nil {
+		t.Fatalf("GetPaymentByID() error = %v", err)
+	}
+	if payment.Status != repository.PaymentStatusFailed || payment.CheckoutRequestID != "" {
+		t.Errorf("payment of a rejected STK push = %+v, want a failed payment without a checkout request", payment)
+	}
+}
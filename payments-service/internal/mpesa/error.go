@@ -31,8 +31,11 @@ func findCodeInError(err error, code string) (string, bool) {
 
 	quotedPattern := regexp.QuoteMeta(code)
 
-	re := regexp.MustCompile(`(?m)` + quotedPattern + `: (.*)`)
+	re := regexp.MustCompile(`(?m)` + quotedPattern + `: ?(.*)`)
 	match := re.FindStringSubmatch(err.Error())
+	if match == nil {
+		return "", false
+	}
 
-	return match[0], len(match) > 0
+	return match[0], true
 }
@@ -1,7 +1,11 @@
 package mpesa
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 
 	"github.com/Mik3y-F/order-management-system/pkg"
 	"github.com/jwambugu/mpesa-golang-sdk"
@@ -11,10 +15,25 @@ const (
 	MPESA_CONSUMER_KEY    = "MPESA_CONSUMER_KEY"    // #nosec G101 - This is an env variable name
 	MPESA_CONSUMER_SECRET = "MPESA_CONSUMER_SECRET" // #nosec G101 - This is an env variable name
 	ENVIRONMENT           = "ENVIRONMENT"
+
+	// MPESA_BASE_URL replaces the base URL of the Daraja API, e.g. to run
+	// against a local fake of the API.
+	MPESA_BASE_URL = "MPESA_BASE_URL"
+)
+
+// Base URLs of the Daraja API environments.
+const (
+	sandboxBaseURL    = "https://sandbox.safaricom.co.ke"
+	productionBaseURL = "https://api.safaricom.co.ke"
 )
 
 type Mpesa struct {
 	app *mpesa.Mpesa
+
+	// client and baseURL send the requests the SDK does not support, such as
+	// reversals.
+	client  mpesa.HttpClient
+	baseURL string
 }
 
 func NewMpesaService() *Mpesa {
@@ -31,9 +50,58 @@ func NewMpesaService() *Mpesa {
 		mpesaEnv = mpesa.Sandbox
 	}
 
-	mpesaApp := mpesa.NewApp(http.DefaultClient, consumerKey, consumerSecret, mpesaEnv)
+	m, err := NewMpesa(http.DefaultClient, consumerKey, consumerSecret, mpesaEnv, os.Getenv(MPESA_BASE_URL))
+	if err != nil {
+		panic(err)
+	}
 
-	return &Mpesa{
-		app: mpesaApp,
+	return m
+}
+
+// NewMpesa returns a client of the Daraja API of env that sends its requests
+// with client, or http.DefaultClient if it is nil. If baseURL is set, the
+// requests are sent to it instead, e.g. to the URL of an mpesatest.Server.
+func NewMpesa(
+	client *http.Client, consumerKey, consumerSecret string, env mpesa.Environment, baseURL string) (*Mpesa, error) {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var httpClient mpesa.HttpClient = client
+	if baseURL != "" {
+		base, err := url.Parse(baseURL)
+		if err != nil || base.Scheme == "" || base.Host == "" {
+			return nil, fmt.Errorf("invalid M-Pesa base URL %q", baseURL)
+		}
+
+		httpClient = &baseURLClient{base: base, client: client}
+	}
+
+	envBaseURL := sandboxBaseURL
+	if env == mpesa.Production {
+		envBaseURL = productionBaseURL
 	}
+
+	return &Mpesa{
+		app:     mpesa.NewApp(httpClient, consumerKey, consumerSecret, env),
+		client:  httpClient,
+		baseURL: envBaseURL,
+	}, nil
+}
+
+// baseURLClient sends requests to the Daraja API to another base URL.
+type baseURLClient struct {
+	base   *url.URL
+	client *http.Client
+}
+
+func (c *baseURLClient) Do(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = c.base.Scheme
+	req.URL.Host = c.base.Host
+	req.URL.Path = strings.TrimSuffix(c.base.Path, "/") + req.URL.Path
+	req.Host = ""
+
+	return c.client.Do(req)
 }
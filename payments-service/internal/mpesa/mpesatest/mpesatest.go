@@ -0,0 +1,650 @@
+// Package mpesatest provides a fake of the Daraja (M-Pesa) API, to test
+// payments and run the payments service without network access or
+// credentials. It implements OAuth, STK push, the STK push query and
+// reversals, and sends the callbacks of STK pushes and the results of
+// reversals with scripted outcomes.
+//
+// Point an mpesa.Mpesa at a Server with the base URL of an
+// httptest.Server, or run cmd/fakedaraja and set MPESA_BASE_URL.
+package mpesatest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jwambugu/mpesa-golang-sdk"
+)
+
+// Paths of the Daraja API endpoints served by a Server.
+const (
+	AuthPath     = "/oauth/v1/generate"
+	STKPushPath  = "/mpesa/stkpush/v1/processrequest"
+	STKQueryPath = "/mpesa/stkpushquery/v1/query"
+	ReversalPath = "/mpesa/reversal/v1/request"
+)
+
+// Error codes returned by a Server, as by the Daraja API.
+const (
+	ErrorInvalidRequest     = "400.002.02"
+	ErrorInvalidCredentials = "400.008.01"
+	ErrorInvalidAccessToken = "404.001.03"
+	ErrorTransactionPending = "500.001.1001"
+)
+
+// Result codes of failed reversals.
+const (
+	ReversalAlreadyReversed = "R000001"
+	ReversalInvalidOriginal = "R000002"
+)
+
+// DefaultCallbackDelay is the CallbackDelay of new servers.
+const DefaultCallbackDelay = 100 * time.Millisecond
+
+// timeZone is East Africa Time, the time zone of M-Pesa timestamps.
+var timeZone = time.FixedZone("EAT", 3*60*60)
+
+// Result is the outcome of an STK push, sent in its callback and returned by
+// its transaction status query.
+type Result struct {
+	Code int
+	Desc string
+
+	// Lost results are never sent as a callback, as if the callback was lost
+	// on the way. They are only returned by the status query.
+	Lost bool
+}
+
+// The results of the common outcomes of STK pushes.
+var (
+	ResultSuccess           = Result{Code: 0, Desc: "The service request is processed successfully."}
+	ResultCancelled         = Result{Code: 1032, Desc: "Request cancelled by user"}
+	ResultInsufficientFunds = Result{Code: 1, Desc: "The balance is insufficient for the transaction"}
+	ResultTimeout           = Result{Code: 1037, Desc: "DS timeout user cannot be reached"}
+	ResultCallbackLost      = Result{Code: 0, Desc: ResultSuccess.Desc, Lost: true}
+)
+
+// ParseResult returns the result named success, cancelled,
+// insufficient_funds, timeout or lost.
+func ParseResult(name string) (Result, error) {
+	switch name {
+	case "success":
+		return ResultSuccess, nil
+	case "cancelled":
+		return ResultCancelled, nil
+	case "insufficient_funds":
+		return ResultInsufficientFunds, nil
+	case "timeout":
+		return ResultTimeout, nil
+	case "lost":
+		return ResultCallbackLost, nil
+	default:
+		return Result{}, fmt.Errorf("unknown result %q", name)
+	}
+}
+
+// Transaction is an STK push received by a Server.
+type Transaction struct {
+	MerchantRequestID string
+	CheckoutRequestID string
+	Request           mpesa.STKPushRequest
+	Result            Result
+
+	// Completed is set once the customer has responded to the STK push, and
+	// ReceiptNumber if they paid. Reversed is the amount reversed so far.
+	Completed     bool
+	ReceiptNumber string
+	Reversed      uint
+}
+
+// ReversalRequest is the body of a reversal request.
+type ReversalRequest struct {
+	Initiator              string `json:"Initiator"`
+	SecurityCredential     string `json:"SecurityCredential"`
+	CommandID              string `json:"CommandID"`
+	TransactionID          string `json:"TransactionID"`
+	Amount                 uint   `json:"Amount"`
+	ReceiverParty          uint   `json:"ReceiverParty"`
+	RecieverIdentifierType string `json:"RecieverIdentifierType"`
+	ResultURL              string `json:"ResultURL"`
+	QueueTimeOutURL        string `json:"QueueTimeOutURL"`
+	Remarks                string `json:"Remarks"`
+	Occasion               string `json:"Occasion"`
+}
+
+// ReversalResponse acknowledges a reversal request.
+type ReversalResponse struct {
+	OriginatorConversationID string `json:"OriginatorConversationID"`
+	ConversationID           string `json:"ConversationID"`
+	ResponseCode             string `json:"ResponseCode"`
+	ResponseDescription      string `json:"ResponseDescription"`
+}
+
+// ReversalResult is the body of the result of a reversal, POSTed to its
+// ResultURL.
+type ReversalResult struct {
+	Result struct {
+		ResultType int `json:"ResultType"`
+
+		// ResultCode is the number 0 for successful reversals, and a string
+		// such as "R000002" otherwise.
+		ResultCode               interface{} `json:"ResultCode"`
+		ResultDesc               string      `json:"ResultDesc"`
+		OriginatorConversationID string      `json:"OriginatorConversationID"`
+		ConversationID           string      `json:"ConversationID"`
+		TransactionID            string      `json:"TransactionID"`
+	} `json:"Result"`
+}
+
+// Server is a fake of the Daraja API. Its zero value is not usable; create
+// servers with NewServer.
+type Server struct {
+	mux *http.ServeMux
+
+	mu           sync.Mutex
+	tokens       map[string]bool
+	transactions map[string]*Transaction // by CheckoutRequestID
+	scripts      map[uint64][]Result     // by phone number
+	pending      []*callback
+	seq          int
+
+	// ConsumerKey and ConsumerSecret, if set, must authenticate the OAuth
+	// requests. Any credentials are accepted otherwise.
+	ConsumerKey    string
+	ConsumerSecret string
+
+	// Passkey, if set, must match the passwords of STK pushes and queries.
+	Passkey string
+
+	// DefaultResult is the result of the STK pushes without a scripted
+	// result. It defaults to ResultSuccess.
+	DefaultResult Result
+
+	// STKCallbackURL and ReversalResultURL, if set, receive the callbacks
+	// instead of the URLs in the requests.
+	STKCallbackURL    string
+	ReversalResultURL string
+
+	// CallbackDelay is how long the customer takes to respond to an STK push,
+	// and M-Pesa to process a reversal, before the callback is sent. With
+	// ManualCallbacks, callbacks are only sent by SendCallbacks instead.
+	CallbackDelay   time.Duration
+	ManualCallbacks bool
+
+	// Client sends the callbacks.
+	Client *http.Client
+}
+
+// callback is a result waiting to be sent.
+type callback struct {
+	url string
+
+	// complete records the result on the server, and returns the body of the
+	// callback, or nil if it is not sent. It is called with the lock held.
+	complete func() interface{}
+}
+
+func NewServer() *Server {
+	s := &Server{
+		mux:           http.NewServeMux(),
+		tokens:        make(map[string]bool),
+		transactions:  make(map[string]*Transaction),
+		scripts:       make(map[uint64][]Result),
+		DefaultResult: ResultSuccess,
+		CallbackDelay: DefaultCallbackDelay,
+		Client:        &http.Client{Timeout: 10 * time.Second},
+	}
+
+	s.mux.HandleFunc(AuthPath, s.handleAuth)
+	s.mux.HandleFunc(STKPushPath, s.authenticated(s.handleSTKPush))
+	s.mux.HandleFunc(STKQueryPath, s.authenticated(s.handleSTKQuery))
+	s.mux.HandleFunc(ReversalPath, s.authenticated(s.handleReversal))
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Script sets the results of the next STK pushes to a phone number, in order.
+// Later pushes get the DefaultResult.
+func (s *Server) Script(phoneNumber uint64, results ...Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripts[phoneNumber] = append(s.scripts[phoneNumber], results...)
+}
+
+// Transaction returns the STK push with the checkout request id.
+func (s *Server) Transaction(checkoutRequestID string) (Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.transactions[checkoutRequestID]
+	if !ok {
+		return Transaction{}, false
+	}
+
+	return *t, true
+}
+
+// SendCallbacks sends every callback that has not been sent yet, oldest
+// first, and returns the first error. It is meant for ManualCallbacks.
+func (s *Server) SendCallbacks(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	var firstErr error
+	for _, c := range pending {
+		if err := s.send(ctx, c); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// SendLostCallback sends the callback of a completed STK push whose result
+// was Lost, as if it arrived late.
+func (s *Server) SendLostCallback(ctx context.Context, checkoutRequestID string) error {
+	s.mu.Lock()
+	t, ok := s.transactions[checkoutRequestID]
+	if !ok || !t.Completed || !t.Result.Lost {
+		s.mu.Unlock()
+		return fmt.Errorf("no lost callback of STK push %s", checkoutRequestID)
+	}
+
+	url := t.Request.CallBackURL
+	if s.STKCallbackURL != "" {
+		url = s.STKCallbackURL
+	}
+	s.mu.Unlock()
+
+	return s.send(ctx, &callback{
+		url:      url,
+		complete: func() interface{} { return stkCallback(t) },
+	})
+}
+
+// schedule queues a callback, whose lock must be held, to be sent after the
+// delay or by SendCallbacks.
+func (s *Server) schedule(c *callback) {
+	if s.ManualCallbacks {
+		s.pending = append(s.pending, c)
+		return
+	}
+
+	time.AfterFunc(s.CallbackDelay, func() {
+		if err := s.send(context.Background(), c); err != nil {
+			log.Printf("[mpesatest] %v", err)
+		}
+	})
+}
+
+func (s *Server) send(ctx context.Context, c *callback) error {
+	s.mu.Lock()
+	b := c.complete()
+	s.mu.Unlock()
+
+	if b == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode callback: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create callback to %s: %v", c.url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send callback to %s: %v", c.url, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("callback to %s failed with status %s", c.url, res.Status)
+	}
+
+	return nil
+}
+
+func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	key, secret, ok := r.BasicAuth()
+	if !ok || (s.ConsumerKey != "" && (key != s.ConsumerKey || secret != s.ConsumerSecret)) {
+		writeError(w, http.StatusBadRequest, ErrorInvalidCredentials, "Invalid Authentication passed")
+		return
+	}
+
+	token := randomHex(16)
+
+	s.mu.Lock()
+	s.tokens[token] = true
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": token,
+		"expires_in":   "3599",
+	})
+}
+
+// authenticated requires POST requests with an access token issued by the
+// server.
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		s.mu.Lock()
+		ok := s.tokens[token]
+		s.mu.Unlock()
+
+		if !ok {
+			writeError(w, http.StatusUnauthorized, ErrorInvalidAccessToken, "Invalid Access Token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *Server) handleSTKPush(w http.ResponseWriter, r *http.Request) {
+	var req mpesa.STKPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Bad Request - Invalid JSON")
+		return
+	}
+
+	if field := s.invalidSTKPushField(&req); field != "" {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Bad Request - Invalid "+field)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	t := &Transaction{
+		MerchantRequestID: fmt.Sprintf("%d-%d-1", 10000+s.seq, time.Now().Unix()),
+		CheckoutRequestID: fmt.Sprintf("ws_CO_%s%d", time.Now().Format("02012006150405"), s.seq),
+		Request:           req,
+		Result:            s.nextResult(req.PhoneNumber),
+	}
+	s.transactions[t.CheckoutRequestID] = t
+
+	url := req.CallBackURL
+	if s.STKCallbackURL != "" {
+		url = s.STKCallbackURL
+	}
+
+	s.schedule(&callback{
+		url: url,
+		complete: func() interface{} {
+			t.Completed = true
+			if t.Result.Code == 0 {
+				t.ReceiptNumber = randomReceiptNumber()
+			}
+
+			if t.Result.Lost {
+				return nil
+			}
+			return stkCallback(t)
+		},
+	})
+
+	writeJSON(w, http.StatusOK, &mpesa.GeneralRequestResponse{
+		MerchantRequestID:   t.MerchantRequestID,
+		CheckoutRequestID:   t.CheckoutRequestID,
+		ResponseCode:        "0",
+		ResponseDescription: "Success. Request accepted for processing",
+		CustomerMessage:     "Success. Request accepted for processing",
+	})
+}
+
+// invalidSTKPushField returns the name of the first invalid field of an STK
+// push, if any.
+func (s *Server) invalidSTKPushField(req *mpesa.STKPushRequest) string {
+	switch {
+	case req.BusinessShortCode == 0:
+		return "BusinessShortCode"
+	case !s.validPassword(req.BusinessShortCode, req.Password, req.Timestamp):
+		return "Password"
+	case req.TransactionType != "CustomerPayBillOnline" && req.TransactionType != "CustomerBuyGoodsOnline":
+		return "TransactionType"
+	case req.Amount == 0:
+		return "Amount"
+	case req.PhoneNumber == 0:
+		return "PhoneNumber"
+	case req.CallBackURL == "" && s.STKCallbackURL == "":
+		return "CallBackURL"
+	default:
+		return ""
+	}
+}
+
+func (s *Server) validPassword(shortCode uint, password string, timestamp string) bool {
+	if s.Passkey == "" {
+		return true
+	}
+
+	want := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d%s%s", shortCode, s.Passkey, timestamp)))
+	return password == want
+}
+
+// nextResult returns the result of the next STK push to the phone number,
+// whose lock must be held.
+func (s *Server) nextResult(phoneNumber uint64) Result {
+	results := s.scripts[phoneNumber]
+	if len(results) == 0 {
+		return s.DefaultResult
+	}
+
+	s.scripts[phoneNumber] = results[1:]
+
+	return results[0]
+}
+
+// stkCallback returns the callback of a completed transaction.
+func stkCallback(t *Transaction) *mpesa.STKPushCallback {
+	cb := &mpesa.STKPushCallback{}
+	cb.Body.STKCallback = mpesa.STKCallback{
+		MerchantRequestID: t.MerchantRequestID,
+		CheckoutRequestID: t.CheckoutRequestID,
+		ResultCode:        t.Result.Code,
+		ResultDesc:        t.Result.Desc,
+	}
+
+	if t.Result.Code == 0 {
+		cb.Body.STKCallback.CallbackMetadata.Item = []mpesa.STKCallbackItem{
+			{Name: "Amount", Value: t.Request.Amount},
+			{Name: "MpesaReceiptNumber", Value: t.ReceiptNumber},
+			{Name: "TransactionDate", Value: json.Number(time.Now().In(timeZone).Format("20060102150405"))},
+			{Name: "PhoneNumber", Value: t.Request.PhoneNumber},
+		}
+	}
+
+	return cb
+}
+
+func (s *Server) handleSTKQuery(w http.ResponseWriter, r *http.Request) {
+	var req mpesa.STKQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Bad Request - Invalid JSON")
+		return
+	}
+
+	if req.BusinessShortCode == 0 || !s.validPassword(req.BusinessShortCode, req.Password, req.Timestamp) {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Bad Request - Invalid Password")
+		return
+	}
+
+	t, ok := s.Transaction(req.CheckoutRequestID)
+	if !ok {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Bad Request - Invalid CheckoutRequestID")
+		return
+	}
+
+	if !t.Completed {
+		writeError(w, http.StatusInternalServerError, ErrorTransactionPending, "The transaction is being processed")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &mpesa.GeneralRequestResponse{
+		MerchantRequestID:   t.MerchantRequestID,
+		CheckoutRequestID:   t.CheckoutRequestID,
+		ResponseCode:        "0",
+		ResponseDescription: "The service request has been accepted successsfully",
+		ResultCode:          strconv.Itoa(t.Result.Code),
+		ResultDesc:          t.Result.Desc,
+	})
+}
+
+func (s *Server) handleReversal(w http.ResponseWriter, r *http.Request) {
+	var req ReversalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Bad Request - Invalid JSON")
+		return
+	}
+
+	var field string
+	switch {
+	case req.CommandID != "TransactionReversal":
+		field = "CommandID"
+	case req.TransactionID == "":
+		field = "TransactionID"
+	case req.Amount == 0:
+		field = "Amount"
+	case req.ResultURL == "" && s.ReversalResultURL == "":
+		field = "ResultURL"
+	}
+	if field != "" {
+		writeError(w, http.StatusBadRequest, ErrorInvalidRequest, "Bad Request - Invalid "+field)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	res := &ReversalResponse{
+		OriginatorConversationID: fmt.Sprintf("%d-%d-1", 20000+s.seq, time.Now().Unix()),
+		ConversationID:           fmt.Sprintf("AG_%s_%s", time.Now().Format("20060102"), randomHex(10)),
+		ResponseCode:             "0",
+		ResponseDescription:      "Accept the service request successfully.",
+	}
+
+	result := &ReversalResult{}
+	result.Result.OriginatorConversationID = res.OriginatorConversationID
+	result.Result.ConversationID = res.ConversationID
+	result.Result.TransactionID = randomReceiptNumber()
+
+	url := req.ResultURL
+	if s.ReversalResultURL != "" {
+		url = s.ReversalResultURL
+	}
+
+	s.schedule(&callback{
+		url: url,
+		complete: func() interface{} {
+			result.Result.ResultCode, result.Result.ResultDesc = s.reverse(&req)
+			return result
+		},
+	})
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// reverse reverses the amount of the request from the paid transaction with
+// its receipt number, whose lock must be held, and returns the result code
+// and description. Transactions can be reversed in parts, up to their amount.
+func (s *Server) reverse(req *ReversalRequest) (interface{}, string) {
+	for _, t := range s.transactions {
+		if t.ReceiptNumber == "" || t.ReceiptNumber != req.TransactionID {
+			continue
+		}
+
+		if t.Reversed >= t.Request.Amount {
+			return ReversalAlreadyReversed, "The transaction has already been reversed."
+		}
+
+		if req.Amount > t.Request.Amount-t.Reversed {
+			return ReversalInvalidOriginal, "The amount exceeds the amount left on the original transaction."
+		}
+
+		t.Reversed += req.Amount
+
+		return 0, "The service request is processed successfully."
+	}
+
+	return ReversalInvalidOriginal, "The OriginalTransactionID is invalid."
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("[mpesatest] failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]string{
+		"requestId":    randomHex(8),
+		"errorCode":    code,
+		"errorMessage": message,
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// randomReceiptNumber returns a random M-Pesa receipt number, e.g.
+// NLJ7RT61SV.
+func randomReceiptNumber() string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	b := make([]byte, 10)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			panic(err)
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+
+	return string(b)
+}
@@ -0,0 +1,364 @@
+package mpesatest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/mpesa/mpesatest"
+	"github.com/jwambugu/mpesa-golang-sdk"
+)
+
+const (
+	testShortCode = 174379
+	testPasskey   = "passkey"
+)
+
+// hostClient sends the requests of the SDK to a test server.
+type hostClient struct {
+	url *url.URL
+}
+
+func (c *hostClient) Do(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = c.url.Scheme
+	req.URL.Host = c.url.Host
+
+	return http.DefaultClient.Do(req)
+}
+
+// receiver records the callbacks sent to it.
+type receiver struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+func newReceiver(t *testing.T) *receiver {
+	t.Helper()
+
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(req.Body); err != nil {
+			t.Errorf("failed to read callback: %v", err)
+		}
+
+		r.mu.Lock()
+		r.bodies = append(r.bodies, body.Bytes())
+		r.mu.Unlock()
+	}))
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// last decodes the last callback received into v.
+func (r *receiver) last(t *testing.T, v interface{}) {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.bodies) == 0 {
+		t.Fatalf("no callback received")
+	}
+
+	if err := json.Unmarshal(r.bodies[len(r.bodies)-1], v); err != nil {
+		t.Fatalf("failed to decode callback: %v", err)
+	}
+}
+
+func setupServer(t *testing.T) (*mpesatest.Server, *mpesa.Mpesa, *httptest.Server) {
+	t.Helper()
+
+	daraja := mpesatest.NewServer()
+	daraja.ConsumerKey = "key"
+	daraja.ConsumerSecret = "secret"
+	daraja.Passkey = testPasskey
+	daraja.ManualCallbacks = true
+
+	server := httptest.NewServer(daraja)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server url: %v", err)
+	}
+
+	return daraja, mpesa.NewApp(&hostClient{url: u}, "key", "secret", mpesa.Sandbox), server
+}
+
+func newTestSTKPush(phoneNumber uint64, callbackURL string) mpesa.STKPushRequest {
+	return mpesa.STKPushRequest{
+		BusinessShortCode: testShortCode,
+		TransactionType:   "CustomerPayBillOnline",
+		Amount:            100,
+		PartyA:            uint(phoneNumber),
+		PartyB:            testShortCode,
+		PhoneNumber:       phoneNumber,
+		CallBackURL:       callbackURL,
+		AccountReference:  "order-1",
+		TransactionDesc:   "Order 1",
+	}
+}
+
+func TestServer_Auth(t *testing.T) {
+	ctx := context.Background()
+
+	_, _, server := setupServer(t)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse server url: %v", err)
+	}
+
+	app := mpesa.NewApp(&hostClient{url: u}, "key", "wrong-secret", mpesa.Sandbox)
+	if _, err := app.GenerateAccessToken(ctx); err == nil {
+		t.Errorf("GenerateAccessToken() with invalid credentials error = nil, want an error")
+	}
+
+	_, err = app.STKPush(ctx, testPasskey, newTestSTKPush(254700000000, server.URL))
+	if err == nil {
+		t.Errorf("STKPush() without an access token error = nil, want an error")
+	}
+
+	res, err := http.Post(server.URL+mpesatest.STKPushPath, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("failed to send STK push: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("STK push without an access token status = %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestServer_STKPush(t *testing.T) {
+	tests := []struct {
+		name    string
+		passkey string
+		req     func(req *mpesa.STKPushRequest)
+		wantErr string
+	}{
+		{
+			name:    "Valid",
+			passkey: testPasskey,
+			req:     func(req *mpesa.STKPushRequest) {},
+		},
+		{
+			name:    "Invalid Password",
+			passkey: "another-passkey",
+			req:     func(req *mpesa.STKPushRequest) {},
+			wantErr: "Invalid Password",
+		},
+		{
+			name:    "Invalid Transaction Type",
+			passkey: testPasskey,
+			req:     func(req *mpesa.STKPushRequest) { req.TransactionType = "CustomerPay" },
+			wantErr: "Invalid TransactionType",
+		},
+		{
+			name:    "No Amount",
+			passkey: testPasskey,
+			req:     func(req *mpesa.STKPushRequest) { req.Amount = 0 },
+			wantErr: "Invalid Amount",
+		},
+		{
+			name:    "No Callback URL",
+			passkey: testPasskey,
+			req:     func(req *mpesa.STKPushRequest) { req.CallBackURL = "" },
+			wantErr: "Invalid CallBackURL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daraja, app, server := setupServer(t)
+
+			req := newTestSTKPush(254700000000, server.URL)
+			tt.req(&req)
+
+			res, err := app.STKPush(context.Background(), tt.passkey, req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("STKPush() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("STKPush() error = %v", err)
+			}
+
+			transaction, ok := daraja.Transaction(res.CheckoutRequestID)
+			if !ok {
+				t.Fatalf("Transaction(%q) not found", res.CheckoutRequestID)
+			}
+
+			if transaction.MerchantRequestID != res.MerchantRequestID || transaction.Completed {
+				t.Errorf("Transaction() = %+v, want a pending transaction of %q", transaction, res.MerchantRequestID)
+			}
+		})
+	}
+}
+
+func TestServer_STKQuery(t *testing.T) {
+	ctx := context.Background()
+
+	daraja, app, _ := setupServer(t)
+	callbacks := newReceiver(t)
+
+	daraja.Script(254700000000, mpesatest.ResultCancelled)
+
+	res, err := app.STKPush(ctx, testPasskey, newTestSTKPush(254700000000, callbacks.URL))
+	if err != nil {
+		t.Fatalf("STKPush() error = %v", err)
+	}
+
+	query := mpesa.STKQueryRequest{BusinessShortCode: testShortCode, CheckoutRequestID: res.CheckoutRequestID}
+
+	_, err = app.STKQuery(ctx, testPasskey, query)
+	if err == nil || !strings.Contains(err.Error(), mpesatest.ErrorTransactionPending) {
+		t.Errorf("STKQuery() of a pending transaction error = %v, want %q", err, mpesatest.ErrorTransactionPending)
+	}
+
+	if err := daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	var callback mpesa.STKPushCallback
+	callbacks.last(t, &callback)
+
+	if got := callback.Body.STKCallback; got.CheckoutRequestID != res.CheckoutRequestID || got.ResultCode != 1032 {
+		t.Errorf("callback = %+v, want result 1032 of %q", got, res.CheckoutRequestID)
+	}
+
+	status, err := app.STKQuery(ctx, testPasskey, query)
+	if err != nil {
+		t.Fatalf("STKQuery() error = %v", err)
+	}
+
+	if status.ResultCode != "1032" || status.ResultDesc != mpesatest.ResultCancelled.Desc {
+		t.Errorf("STKQuery() = %s: %s, want 1032: %s", status.ResultCode, status.ResultDesc, mpesatest.ResultCancelled.Desc)
+	}
+}
+
+func TestServer_Reversal(t *testing.T) {
+	ctx := context.Background()
+
+	daraja, app, server := setupServer(t)
+	callbacks := newReceiver(t)
+
+	push, err := app.STKPush(ctx, testPasskey, newTestSTKPush(254700000000, callbacks.URL))
+	if err != nil {
+		t.Fatalf("STKPush() error = %v", err)
+	}
+
+	if err := daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	transaction, _ := daraja.Transaction(push.CheckoutRequestID)
+	if transaction.ReceiptNumber == "" {
+		t.Fatalf("Transaction() of a paid STK push has no receipt number")
+	}
+
+	token, err := app.GenerateAccessToken(ctx)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		transactionID  string
+		amount         uint
+		wantResultCode interface{}
+	}{
+		{
+			name:           "Partially Reversed",
+			transactionID:  transaction.ReceiptNumber,
+			amount:         40,
+			wantResultCode: 0.0,
+		},
+		{
+			name:           "More Than Left",
+			transactionID:  transaction.ReceiptNumber,
+			amount:         100,
+			wantResultCode: mpesatest.ReversalInvalidOriginal,
+		},
+		{
+			name:           "Reversed",
+			transactionID:  transaction.ReceiptNumber,
+			amount:         60,
+			wantResultCode: 0.0,
+		},
+		{
+			name:           "Already Reversed",
+			transactionID:  transaction.ReceiptNumber,
+			amount:         1,
+			wantResultCode: mpesatest.ReversalAlreadyReversed,
+		},
+		{
+			name:           "Unknown Transaction",
+			transactionID:  "NLJ7RT61SV",
+			amount:         100,
+			wantResultCode: mpesatest.ReversalInvalidOriginal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(&mpesatest.ReversalRequest{
+				CommandID:     "TransactionReversal",
+				TransactionID: tt.transactionID,
+				Amount:        tt.amount,
+				ReceiverParty: testShortCode,
+				ResultURL:     callbacks.URL,
+			})
+			if err != nil {
+				t.Fatalf("failed to encode reversal: %v", err)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+mpesatest.ReversalPath, bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to create reversal: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("failed to send reversal: %v", err)
+			}
+			defer res.Body.Close()
+
+			var ack mpesatest.ReversalResponse
+			if err := json.NewDecoder(res.Body).Decode(&ack); err != nil {
+				t.Fatalf("failed to decode reversal response: %v", err)
+			}
+
+			if res.StatusCode != http.StatusOK || ack.ResponseCode != "0" {
+				t.Fatalf("reversal response = %d %+v, want it accepted", res.StatusCode, ack)
+			}
+
+			if err := daraja.SendCallbacks(ctx); err != nil {
+				t.Fatalf("SendCallbacks() error = %v", err)
+			}
+
+			var result mpesatest.ReversalResult
+			callbacks.last(t, &result)
+
+			if result.Result.ConversationID != ack.ConversationID || result.Result.ResultCode != tt.wantResultCode {
+				t.Errorf("reversal result = %+v, want result code %v", result.Result, tt.wantResultCode)
+			}
+		})
+	}
+
+	if transaction, _ := daraja.Transaction(push.CheckoutRequestID); transaction.Reversed != 100 {
+		t.Errorf("Transaction() of a reversed payment reversed %d, want 100", transaction.Reversed)
+	}
+}
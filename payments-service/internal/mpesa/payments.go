@@ -2,12 +2,23 @@ package mpesa
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
 	"github.com/Mik3y-F/order-management-system/payments/internal/service"
 	"github.com/Mik3y-F/order-management-system/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 	"github.com/jwambugu/mpesa-golang-sdk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	orders "github.com/Mik3y-F/order-management-system/orders/pkg/client"
 )
@@ -15,20 +26,34 @@ import (
 const (
 	MPESA_BUSINESS_SHORT_CODE = "MPESA_BUSINESS_SHORT_CODE" // #nosec G101 - This is an env variable name
 	MPESA_PASSKEY             = "MPESA_PASSKEY"             // #nosec G101 - This is an env variable name
+
+	// ORDER_STATUS_ACTOR is recorded in the history of the orders whose
+	// status this service changes.
+	ORDER_STATUS_ACTOR = "payments-service"
+
+	// MPESA_CURRENCY is the only currency M-Pesa accepts.
+	MPESA_CURRENCY = "KES"
 )
 
+// mpesaTimeZone is East Africa Time, the time zone of M-Pesa timestamps.
+var mpesaTimeZone = time.FixedZone("EAT", 3*60*60)
+
 var _ service.PaymentsService = (*PaymentsService)(nil)
 
 type PaymentsService struct {
 	mpesa        *Mpesa
 	db           repository.PaymentsRepository
+	refunds      repository.RefundsRepository
 	ordersClient orders.OrdersClient
 }
 
 func NewPaymentsService(
-	mpesa *Mpesa, orderClient orders.OrdersClient, db repository.PaymentsRepository) *PaymentsService {
+	mpesa *Mpesa, orderClient orders.OrdersClient, db repository.PaymentsRepository,
+	refunds repository.RefundsRepository) *PaymentsService {
 	return &PaymentsService{
 		mpesa:        mpesa,
+		db:           db,
+		refunds:      refunds,
 		ordersClient: orderClient,
 	}
 }
@@ -37,52 +62,87 @@ func (s *PaymentsService) CheckPreconditions() {
 	if s.mpesa == nil {
 		panic("no Mpesa service provided")
 	}
+
+	if s.db == nil {
+		panic("no payments repository provided")
+	}
+
+	if s.refunds == nil {
+		panic("no refunds repository provided")
+	}
 }
 
 func (s *PaymentsService) ProcessPayment(ctx context.Context, payment *service.Payment) (*service.PaymentResponse, error) {
 	s.CheckPreconditions()
 
-	// stored in an environemnt variable for now:- assumption is that the system handles orders for a single business
-	businessShortCode, err := pkg.StringToUint(pkg.MustGetEnv(MPESA_BUSINESS_SHORT_CODE))
+	businessShortCode, passKey, err := stkPushCredentials()
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert business short code to uint: %v", err)
+		return nil, err
 	}
 
-	passKey := pkg.MustGetEnv(MPESA_PASSKEY)
+	amount, err := stkPushAmount(payment.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	callbackToken, err := newCallbackToken()
+	if err != nil {
+		return nil, err
+	}
 
 	_, err = s.ordersClient.UpdateOrderStatus(ctx, &orders.UpdateOrderStatusRequest{
-		Id:     "1",
+		Id:     payment.OrderId,
 		Status: orders.OrderStatusPending,
+		Actor:  ORDER_STATUS_ACTOR,
+		Reason: "M-Pesa STK push sent",
 	})
 	if err != nil {
 		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to update order status: %v", err)
 	}
 
+	// The payment is stored before its push is sent, so that a push is never
+	// live without a payment for its callback to settle.
+	paymentID, err := s.db.CreatePayment(ctx, &repository.Payment{
+		OrderID:       payment.OrderId,
+		Amount:        payment.Amount,
+		Phone:         fmt.Sprint(payment.PhoneNumber),
+		Reference:     payment.Reference,
+		Description:   payment.Description,
+		Status:        repository.PaymentStatusPending,
+		CallbackToken: callbackToken,
+	})
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to store payment record: %v", err)
+	}
+
 	stkPushRes, err := s.mpesa.app.STKPush(ctx, passKey, mpesa.STKPushRequest{
 		BusinessShortCode: businessShortCode,
 		TransactionType:   "CustomerBuyGoodsOnline",
-		Amount:            payment.Amount,
+		Amount:            amount,
 		PartyA:            payment.PhoneNumber,
 		PartyB:            businessShortCode,
 		PhoneNumber:       uint64(payment.PhoneNumber),
-		CallBackURL:       payment.CallbackURL,
+		CallBackURL:       strings.TrimSuffix(payment.CallbackURL, "/") + "/" + callbackToken,
 		AccountReference:  payment.Reference,
 		TransactionDesc:   payment.Description,
 	})
 	if err != nil {
+		// The push was never accepted, so no callback will settle the payment.
+		settleErr := s.db.SettlePayment(ctx, paymentID, &repository.PaymentResult{
+			Status:     repository.PaymentStatusFailed,
+			ResultDesc: err.Error(),
+		})
+		if settleErr != nil {
+			return nil, service.Errorf(service.INTERNAL_ERROR,
+				"failed to process payment: %v; and to fail payment %s: %v", err, paymentID, settleErr)
+		}
+
 		return nil, fmt.Errorf("failed to process payment: %v", MpesaErrorToInternalError(err))
 	}
 
-	_, err = s.db.CreatePayment(ctx, &repository.Payment{
-		Amount:            payment.Amount,
-		Phone:             fmt.Sprint(payment.PhoneNumber),
-		Reference:         payment.Reference,
-		Description:       payment.Description,
-		MerchantRequestID: stkPushRes.MerchantRequestID,
-		Status:            repository.PaymentStatusPending,
-	})
+	err = s.db.SetPaymentRequestIDs(ctx, paymentID, stkPushRes.MerchantRequestID, stkPushRes.CheckoutRequestID)
 	if err != nil {
-		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to store payment record: %v", err)
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to store payment request ids: %v", err)
 	}
 
 	return &service.PaymentResponse{
@@ -94,38 +154,232 @@ func (s *PaymentsService) ProcessPayment(ctx context.Context, payment *service.P
 
 }
 
-func (s *PaymentsService) HandleMpesaCallback(ctx context.Context, callback *service.PaymentCallback) error {
+// stkPushCredentials returns the business short code and passkey of STK
+// pushes.
+func stkPushCredentials() (uint, string, error) {
+	// stored in an environemnt variable for now:- assumption is that the system handles orders for a single business
+	businessShortCode, err := pkg.StringToUint(pkg.MustGetEnv(MPESA_BUSINESS_SHORT_CODE))
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to convert business short code to uint: %v", err)
+	}
+
+	return businessShortCode, pkg.MustGetEnv(MPESA_PASSKEY), nil
+}
+
+// stkPushAmount converts an amount to the whole shillings of an STK push.
+func stkPushAmount(amount money.Money) (uint, error) {
+	if amount.Currency != MPESA_CURRENCY {
+		return 0, service.Errorf(service.INVALID_ERROR,
+			"M-Pesa payments must be in %s, not %q", MPESA_CURRENCY, amount.Currency)
+	}
+
+	shillings, err := amount.MajorUnits()
+	if err != nil {
+		return 0, service.Errorf(service.INVALID_ERROR, "invalid M-Pesa amount: %v", err)
+	}
+
+	if shillings <= 0 {
+		return 0, service.Errorf(service.INVALID_ERROR, "M-Pesa amount must be positive, got %s", amount)
+	}
+
+	return uint(shillings), nil
+}
+
+// newCallbackToken returns a random token that authenticates the callback of
+// a payment.
+func newCallbackToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", service.Errorf(service.INTERNAL_ERROR, "failed to generate callback token: %v", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func (s *PaymentsService) HandleMpesaCallback(
+	ctx context.Context, token string, callback *service.PaymentCallback) error {
 	s.CheckPreconditions()
 
 	payment, err := s.db.GetPaymentByMerchantRequestID(ctx, callback.MerchantRequestID)
-	if err != nil {
+	if code := service.ErrorCode(err); code == service.NOT_FOUND_ERROR || code == service.INVALID_ERROR {
+		// Unknown payments are rejected like a wrong token, so that callers
+		// cannot probe for merchant request ids.
+		return service.Errorf(service.AUTHENTICATION_ERROR, "invalid callback token")
+	} else if err != nil {
 		return service.Errorf(service.INTERNAL_ERROR, "failed to get payment: %v", err)
 	}
 
-	if callback.ResultCode != 0 {
-		_, err := s.ordersClient.UpdateOrderStatus(ctx, &orders.UpdateOrderStatusRequest{
-			Id:     payment.OrderID,
-			Status: orders.OrderStatusFailed,
-		})
-		if err != nil {
-			return service.Errorf(service.INTERNAL_ERROR, "failed to update order status(Failed): %v", err)
-		}
+	if err := verifyCallback(payment, token, callback); err != nil {
+		return err
+	}
 
-		err = s.db.UpdatePaymentStatus(ctx, payment.Id, repository.PaymentStatusFailed)
-		if err != nil {
-			return service.Errorf(service.INTERNAL_ERROR, "failed to update payment status(Failed): %v", err)
+	result := callbackResult(callback)
+
+	// The reconciler settles payments without their receipt, which a late
+	// callback of the payment still brings, and refunds need.
+	if payment.Status == repository.PaymentStatusPaid && payment.MpesaReceiptNumber == "" &&
+		result.Status == repository.PaymentStatusPaid {
+		err := s.db.RecordPaymentReceipt(ctx, payment.Id, result)
+		if code := service.ErrorCode(err); code == service.CONFLICT_ERROR {
+			return err
+		} else if err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to record payment receipt: %v", err)
 		}
 
-		return service.Errorf(service.NOT_IMPLEMENTED_ERROR, "invalid request: %s", callback.ResultDesc)
+		return nil
 	}
 
-	_, err = s.ordersClient.UpdateOrderStatus(ctx, &orders.UpdateOrderStatusRequest{
-		Id:     payment.OrderID,
-		Status: orders.OrderStatusPaid,
+	return s.settlePayment(ctx, payment, result)
+}
+
+// paymentResult returns the result of an STK push with the result code and
+// description. A result code of 0 means the payment was made.
+func paymentResult(resultCode int, resultDesc string) *repository.PaymentResult {
+	status := repository.PaymentStatusPaid
+	if resultCode != 0 {
+		status = repository.PaymentStatusFailed
+	}
+
+	return &repository.PaymentResult{
+		Status:     status,
+		ResultCode: resultCode,
+		ResultDesc: resultDesc,
+	}
+}
+
+// callbackResult returns the result reported by a verified callback, with
+// the receipt, amount, payer and time of successful payments.
+func callbackResult(callback *service.PaymentCallback) *repository.PaymentResult {
+	result := paymentResult(callback.ResultCode, callback.ResultDesc)
+	if callback.ResultCode != 0 {
+		return result
+	}
+
+	result.MpesaReceiptNumber = callbackItem(callback, "MpesaReceiptNumber")
+	result.PayerPhone = callbackItem(callback, "PhoneNumber")
+
+	if amount, err := strconv.ParseFloat(callbackItem(callback, "Amount"), 64); err == nil {
+		result.PaidAmount = money.New(int64(math.Round(amount*100)), MPESA_CURRENCY)
+	}
+
+	// M-Pesa reports transaction dates in Kenyan time, e.g. 20191219102115.
+	transactionTime, err := time.ParseInLocation(
+		"20060102150405", callbackItem(callback, "TransactionDate"), mpesaTimeZone)
+	if err == nil {
+		result.TransactionTime = transactionTime.Local().Format(time.RFC3339)
+	}
+
+	return result
+}
+
+// settlePayment records the result of the STK push of a pending payment, from
+// its callback or a transaction status query, on the payment and its order.
+func (s *PaymentsService) settlePayment(
+	ctx context.Context, payment *repository.Payment, result *repository.PaymentResult) error {
+
+	if payment.Status != repository.PaymentStatusPending {
+		return service.Errorf(service.CONFLICT_ERROR, "payment %s is already %s", payment.Id, payment.Status)
+	}
+
+	orderStatus := orders.OrderStatusPaid
+	if result.Status != repository.PaymentStatusPaid {
+		orderStatus = orders.OrderStatusFailed
+	}
+
+	if err := s.moveOrder(ctx, payment.OrderID, orderStatus, result.ResultDesc); err != nil {
+		return err
+	}
+
+	err := s.db.SettlePayment(ctx, payment.Id, result)
+	if code := service.ErrorCode(err); code == service.CONFLICT_ERROR {
+		return err
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to settle payment(%s): %v", result.Status, err)
+	}
+
+	return nil
+}
+
+// moveOrder moves the order to the status. Orders that can no longer move to
+// it, e.g. orders cancelled while their STK push was pending, are left as
+// they are: the payment or refund is recorded regardless, so that the money
+// can still be accounted for and reversed.
+func (s *PaymentsService) moveOrder(
+	ctx context.Context, orderID string, orderStatus orders.OrderStatus, reason string) error {
+
+	_, err := s.ordersClient.UpdateOrderStatus(ctx, &orders.UpdateOrderStatusRequest{
+		Id:     orderID,
+		Status: orderStatus,
+		Actor:  ORDER_STATUS_ACTOR,
+		Reason: reason,
 	})
+	if status.Code(err) == codes.FailedPrecondition {
+		log.Printf("order %s was left as it is instead of moving to %s: %v", orderID, orderStatus, err)
+		return nil
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to update order status(%s): %v", orderStatus, err)
+	}
+
+	return nil
+}
+
+// verifyCallback checks that the callback carries the token of the payment
+// and answers its STK push. The amount and phone number of successful
+// payments must match the payment too.
+func verifyCallback(payment *repository.Payment, token string, callback *service.PaymentCallback) error {
+	// Payments requested before callback tokens were introduced have none and
+	// cannot be confirmed by a callback.
+	if payment.CallbackToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(payment.CallbackToken)) != 1 {
+		return service.Errorf(service.AUTHENTICATION_ERROR, "invalid callback token")
+	}
+
+	if callback.CheckoutRequestID != payment.CheckoutRequestID {
+		return service.Errorf(service.AUTHENTICATION_ERROR,
+			"callback checkout request id %q does not match payment %s", callback.CheckoutRequestID, payment.Id)
+	}
+
+	if callback.ResultCode != 0 {
+		return nil
+	}
+
+	amount, err := stkPushAmount(payment.Amount)
 	if err != nil {
-		return service.Errorf(service.INTERNAL_ERROR, "failed to update order status(Paid): %v", err)
+		return err
+	}
+
+	if got := callbackItem(callback, "Amount"); got != strconv.FormatUint(uint64(amount), 10) {
+		return service.Errorf(service.AUTHENTICATION_ERROR,
+			"callback amount %q does not match payment %s", got, payment.Id)
+	}
+
+	if got := callbackItem(callback, "PhoneNumber"); got != payment.Phone {
+		return service.Errorf(service.AUTHENTICATION_ERROR,
+			"callback phone number %q does not match payment %s", got, payment.Id)
 	}
 
 	return nil
 }
+
+// callbackItem returns the value of the named item of the callback metadata as
+// a string, with numbers in their shortest form, e.g. "1" for an amount of
+// 1.00. Missing items are empty.
+func callbackItem(callback *service.PaymentCallback, name string) string {
+	for _, item := range callback.CallbackMetadata.Item {
+		if item.Name != name {
+			continue
+		}
+
+		switch v := item.Value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			return v
+		case nil:
+			return ""
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+
+	return ""
+}
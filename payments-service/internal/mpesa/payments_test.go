@@ -0,0 +1,272 @@
+package mpesa_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	orderPkg "github.com/Mik3y-F/order-management-system/orders/pkg"
+	orders "github.com/Mik3y-F/order-management-system/orders/pkg/client"
+	"github.com/Mik3y-F/order-management-system/payments/internal/memory"
+	ecom_mpesa "github.com/Mik3y-F/order-management-system/payments/internal/mpesa"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+	"github.com/jwambugu/mpesa-golang-sdk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testCallbackToken = "callback-token"
+
+// ordersClient records the order status updates of the payments service. If
+// statuses is set, it holds the status of each order and updates are checked
+// against the order status transitions, like the orders service does.
+type ordersClient struct {
+	orders.OrdersClient
+
+	mu       sync.Mutex
+	updates  []*orders.UpdateOrderStatusRequest
+	statuses map[string]orderPkg.OrderStatus
+}
+
+func (c *ordersClient) UpdateOrderStatus(
+	ctx context.Context, req *orders.UpdateOrderStatusRequest) (*orders.UpdateOrderStatusResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.statuses != nil {
+		from, next := c.statuses[req.Id], orderPkg.OrderStatus(strings.ToLower(req.Status.String()))
+		if !from.CanTransitionTo(next) {
+			return nil, status.Errorf(codes.FailedPrecondition, "order cannot move from %s to %s", from, next)
+		}
+		c.statuses[req.Id] = next
+	}
+
+	c.updates = append(c.updates, req)
+	return &orders.UpdateOrderStatusResponse{}, nil
+}
+
+// setStatus moves the order to the status, e.g. as a customer cancelling it
+// would, and starts checking updates against the order status transitions.
+func (c *ordersClient) setStatus(orderID string, orderStatus orderPkg.OrderStatus) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.statuses == nil {
+		c.statuses = make(map[string]orderPkg.OrderStatus)
+	}
+	c.statuses[orderID] = orderStatus
+}
+
+// orderStatus returns the status of the order, once set with setStatus.
+func (c *ordersClient) orderStatus(orderID string) orderPkg.OrderStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.statuses[orderID]
+}
+
+func newTestCallback(payment *repository.Payment) *service.PaymentCallback {
+	return &service.PaymentCallback{
+		MerchantRequestID: payment.MerchantRequestID,
+		CheckoutRequestID: payment.CheckoutRequestID,
+		ResultDesc:        "The service request is processed successfully.",
+		CallbackMetadata: mpesa.STKCallbackMetadata{Item: []mpesa.STKCallbackItem{
+			{Name: "Amount", Value: 100.00},
+			{Name: "MpesaReceiptNumber", Value: "NLJ7RT61SV"},
+			{Name: "TransactionDate", Value: 20191219102115.0},
+			{Name: "PhoneNumber", Value: 254700000000.0},
+		}},
+	}
+}
+
+func TestPaymentsService_HandleMpesaCallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		callback   func(c *service.PaymentCallback)
+		wantCode   string
+		wantStatus repository.PaymentStatus
+	}{
+		{
+			name:       "Paid",
+			token:      testCallbackToken,
+			callback:   func(c *service.PaymentCallback) {},
+			wantStatus: repository.PaymentStatusPaid,
+		},
+		{
+			name:  "Failed",
+			token: testCallbackToken,
+			callback: func(c *service.PaymentCallback) {
+				c.ResultCode = 1032
+				c.ResultDesc = "Request cancelled by user"
+				c.CallbackMetadata.Item = nil
+			},
+			wantStatus: repository.PaymentStatusFailed,
+		},
+		{
+			name:       "Wrong Token",
+			token:      "guessed-token",
+			callback:   func(c *service.PaymentCallback) {},
+			wantCode:   service.AUTHENTICATION_ERROR,
+			wantStatus: repository.PaymentStatusPending,
+		},
+		{
+			name:       "Unknown Payment",
+			token:      testCallbackToken,
+			callback:   func(c *service.PaymentCallback) { c.MerchantRequestID = "unknown" },
+			wantCode:   service.AUTHENTICATION_ERROR,
+			wantStatus: repository.PaymentStatusPending,
+		},
+		{
+			name:       "Wrong Checkout Request",
+			token:      testCallbackToken,
+			callback:   func(c *service.PaymentCallback) { c.CheckoutRequestID = "ws_CO_other" },
+			wantCode:   service.AUTHENTICATION_ERROR,
+			wantStatus: repository.PaymentStatusPending,
+		},
+		{
+			name:  "Wrong Amount",
+			token: testCallbackToken,
+			callback: func(c *service.PaymentCallback) {
+				c.CallbackMetadata.Item[0].Value = 1.00
+			},
+			wantCode:   service.AUTHENTICATION_ERROR,
+			wantStatus: repository.PaymentStatusPending,
+		},
+		{
+			name:  "Wrong Phone Number",
+			token: testCallbackToken,
+			callback: func(c *service.PaymentCallback) {
+				c.CallbackMetadata.Item[3].Value = 254711111111.0
+			},
+			wantCode:   service.AUTHENTICATION_ERROR,
+			wantStatus: repository.PaymentStatusPending,
+		},
+		{
+			name:  "Missing Metadata",
+			token: testCallbackToken,
+			callback: func(c *service.PaymentCallback) {
+				c.CallbackMetadata.Item = nil
+			},
+			wantCode:   service.AUTHENTICATION_ERROR,
+			wantStatus: repository.PaymentStatusPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			payments := memory.NewPaymentsRepository()
+			client := &ordersClient{}
+			s := ecom_mpesa.NewPaymentsService(&ecom_mpesa.Mpesa{}, client, payments, memory.NewRefundsRepository(nil))
+
+			payment := &repository.Payment{
+				Amount:            money.New(10000, "KES"),
+				MerchantRequestID: "29115-34620561-1",
+				CheckoutRequestID: "ws_CO_191220191020363925",
+				Status:            repository.PaymentStatusPending,
+				OrderID:           "order-1",
+				Phone:             "254700000000",
+				CallbackToken:     testCallbackToken,
+			}
+			id, err := payments.CreatePayment(ctx, payment)
+			if err != nil {
+				t.Fatalf("failed to create payment: %v", err)
+			}
+
+			callback := newTestCallback(payment)
+			tt.callback(callback)
+
+			err = s.HandleMpesaCallback(ctx, tt.token, callback)
+			if code := service.ErrorCode(err); code != tt.wantCode {
+				t.Fatalf("HandleMpesaCallback() error = %v, want code %q", err, tt.wantCode)
+			}
+
+			got, err := payments.GetPaymentByID(ctx, id)
+			if err != nil {
+				t.Fatalf("failed to get payment: %v", err)
+			}
+
+			if got.Status != tt.wantStatus {
+				t.Errorf("payment status = %q, want %q", got.Status, tt.wantStatus)
+			}
+
+			if tt.wantStatus == repository.PaymentStatusPaid {
+				// 2019-12-19 10:21:15 in Nairobi.
+				want := time.Date(2019, 12, 19, 7, 21, 15, 0, time.UTC)
+				transactionTime, err := time.Parse(time.RFC3339, got.TransactionTime)
+				if err != nil || !transactionTime.Equal(want) {
+					t.Errorf("payment transaction time = %q, want %v", got.TransactionTime, want)
+				}
+
+				if got.MpesaReceiptNumber != "NLJ7RT61SV" {
+					t.Errorf("payment receipt number = %q, want %q", got.MpesaReceiptNumber, "NLJ7RT61SV")
+				}
+			}
+
+			if tt.wantCode != "" && len(client.updates) != 0 {
+				t.Errorf("HandleMpesaCallback() updated the order of a rejected callback: %v", client.updates)
+			}
+		})
+	}
+}
+
+func TestPaymentsService_HandleMpesaCallback_Replayed(t *testing.T) {
+	ctx := context.Background()
+
+	payments := memory.NewPaymentsRepository()
+	client := &ordersClient{}
+	s := ecom_mpesa.NewPaymentsService(&ecom_mpesa.Mpesa{}, client, payments, memory.NewRefundsRepository(nil))
+
+	payment := &repository.Payment{
+		Amount:            money.New(10000, "KES"),
+		MerchantRequestID: "29115-34620561-1",
+		CheckoutRequestID: "ws_CO_191220191020363925",
+		Status:            repository.PaymentStatusPending,
+		Phone:             "254700000000",
+		CallbackToken:     testCallbackToken,
+	}
+	if _, err := payments.CreatePayment(ctx, payment); err != nil {
+		t.Fatalf("failed to create payment: %v", err)
+	}
+
+	if err := s.HandleMpesaCallback(ctx, testCallbackToken, newTestCallback(payment)); err != nil {
+		t.Fatalf("HandleMpesaCallback() error = %v", err)
+	}
+
+	err := s.HandleMpesaCallback(ctx, testCallbackToken, newTestCallback(payment))
+	if code := service.ErrorCode(err); code != service.CONFLICT_ERROR {
+		t.Errorf("HandleMpesaCallback() of a replayed callback error = %v, want code %q", err, service.CONFLICT_ERROR)
+	}
+
+	if len(client.updates) != 1 {
+		t.Errorf("order status updated %d times, want 1", len(client.updates))
+	}
+}
+
+func TestPaymentsService_HandleMpesaCallback_NoToken(t *testing.T) {
+	ctx := context.Background()
+
+	payments := memory.NewPaymentsRepository()
+	s := ecom_mpesa.NewPaymentsService(&ecom_mpesa.Mpesa{}, &ordersClient{}, payments, memory.NewRefundsRepository(nil))
+
+	// Payments requested before callback tokens were introduced have none.
+	payment := &repository.Payment{
+		Amount:            money.New(10000, "KES"),
+		MerchantRequestID: "29115-34620561-1",
+		Status:            repository.PaymentStatusPending,
+		Phone:             "254700000000",
+	}
+	if _, err := payments.CreatePayment(ctx, payment); err != nil {
+		t.Fatalf("failed to create payment: %v", err)
+	}
+
+	err := s.HandleMpesaCallback(ctx, "", newTestCallback(payment))
+	if code := service.ErrorCode(err); code != service.AUTHENTICATION_ERROR {
+		t.Errorf("HandleMpesaCallback() error = %v, want code %q", err, service.AUTHENTICATION_ERROR)
+	}
+}
@@ -0,0 +1,166 @@
+package mpesa
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/jwambugu/mpesa-golang-sdk"
+)
+
+const (
+	// DefaultReconcileAfter is how long a payment waits for its callback
+	// before the Reconciler queries its STK push. STK push prompts expire
+	// after about a minute.
+	DefaultReconcileAfter = 5 * time.Minute
+
+	// DefaultReconcileInterval is how often a Reconciler looks for payments
+	// to reconcile.
+	DefaultReconcileInterval = time.Minute
+
+	// DefaultReconcileBatchSize is how many payments a Reconciler queries
+	// at once.
+	DefaultReconcileBatchSize = 100
+)
+
+// Reconciler settles the payments whose callback never arrived, with the
+// result of their STK push from the Daraja transaction status API.
+type Reconciler struct {
+	payments *PaymentsService
+
+	After     time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewReconciler(payments *PaymentsService) *Reconciler {
+	return &Reconciler{
+		payments:  payments,
+		After:     DefaultReconcileAfter,
+		Interval:  DefaultReconcileInterval,
+		BatchSize: DefaultReconcileBatchSize,
+	}
+}
+
+func (r *Reconciler) CheckPreconditions() {
+	if r.payments == nil {
+		panic("no payments service provided")
+	}
+
+	r.payments.CheckPreconditions()
+}
+
+// Run reconciles payments until ctx is done. Failures are logged and retried
+// after the interval.
+func (r *Reconciler) Run(ctx context.Context) error {
+	r.CheckPreconditions()
+
+	ticker := time.NewTicker(r.interval())
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[reconciler] failed to reconcile payments: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile queries the STK pushes of up to a batch of the payments pending
+// for longer than After, oldest first, and settles those with a result. It
+// returns how many it settled. Payments whose query fails, e.g. because the
+// transaction is still being processed, stay pending until the next run.
+func (r *Reconciler) Reconcile(ctx context.Context) (int, error) {
+	r.CheckPreconditions()
+
+	payments, err := r.payments.db.ListPendingPayments(ctx, time.Now().Add(-r.after()), r.batchSize())
+	if err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for _, payment := range payments {
+		if err := r.reconcile(ctx, payment); err != nil {
+			if ctx.Err() != nil {
+				return settled, ctx.Err()
+			}
+
+			log.Printf("[reconciler] failed to reconcile payment %s: %v", payment.Id, err)
+			continue
+		}
+
+		settled++
+	}
+
+	return settled, nil
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, payment *repository.Payment) error {
+	res, err := r.payments.queryPayment(ctx, payment)
+	if err != nil {
+		return err
+	}
+
+	resultCode, err := strconv.Atoi(res.ResultCode)
+	if err != nil {
+		return fmt.Errorf("invalid result code %q", res.ResultCode)
+	}
+
+	log.Printf("[reconciler] payment %s has result %d: %s", payment.Id, resultCode, res.ResultDesc)
+
+	return r.payments.settlePayment(ctx, payment, paymentResult(resultCode, res.ResultDesc))
+}
+
+func (r *Reconciler) after() time.Duration {
+	if r.After <= 0 {
+		return DefaultReconcileAfter
+	}
+
+	return r.After
+}
+
+func (r *Reconciler) interval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultReconcileInterval
+	}
+
+	return r.Interval
+}
+
+func (r *Reconciler) batchSize() int {
+	if r.BatchSize <= 0 {
+		return DefaultReconcileBatchSize
+	}
+
+	return r.BatchSize
+}
+
+// queryPayment returns the result of the STK push of the payment from the
+// Daraja transaction status API.
+func (s *PaymentsService) queryPayment(
+	ctx context.Context, payment *repository.Payment) (*mpesa.GeneralRequestResponse, error) {
+
+	businessShortCode, passKey, err := stkPushCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.mpesa.app.STKQuery(ctx, passKey, mpesa.STKQueryRequest{
+		BusinessShortCode: businessShortCode,
+		CheckoutRequestID: payment.CheckoutRequestID,
+	})
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to query payment: %v", err)
+	}
+
+	return res, nil
+}
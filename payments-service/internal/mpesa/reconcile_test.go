@@ -0,0 +1,109 @@
+package mpesa_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/mpesa/mpesatest"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func TestReconciler_Reconcile(t *testing.T) {
+	ctx := context.Background()
+
+	c := setupCheckout(t)
+	c.reconciler.After = time.Nanosecond
+
+	cancelled := mpesatest.ResultCancelled
+	cancelled.Lost = true
+
+	c.daraja.Script(254700000001, mpesatest.ResultCallbackLost)
+	c.daraja.Script(254700000002, cancelled)
+
+	lostPaid := c.pay(t, 254700000001)
+	lostCancelled := c.pay(t, 254700000002)
+
+	// The customers respond, but neither callback arrives.
+	if err := c.daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	// The customer has not responded to this one yet.
+	processing := c.pay(t, 254700000003)
+
+	settled, err := c.reconciler.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if settled != 2 {
+		t.Errorf("Reconcile() settled %d payments, want 2", settled)
+	}
+
+	c.wantPaymentStatus(t, lostPaid, repository.PaymentStatusPaid)
+	c.wantPaymentStatus(t, lostCancelled, repository.PaymentStatusFailed)
+	c.wantPaymentStatus(t, processing, repository.PaymentStatusPending)
+
+	// Settled payments are not queried again.
+	settled, err = c.reconciler.Reconcile(ctx)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if settled != 0 {
+		t.Errorf("Reconcile() settled %d payments again, want 0", settled)
+	}
+}
+
+func TestReconciler_LateCallback(t *testing.T) {
+	ctx := context.Background()
+
+	c := setupCheckout(t)
+	c.reconciler.After = time.Nanosecond
+	c.daraja.Script(254700000000, mpesatest.ResultCallbackLost)
+
+	res := c.pay(t, 254700000000)
+	if err := c.daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	if _, err := c.reconciler.Reconcile(ctx); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// The status query reports no receipt, which the reversal of a refund
+	// needs.
+	payment := c.wantPaymentStatus(t, res, repository.PaymentStatusPaid)
+	if payment.MpesaReceiptNumber != "" {
+		t.Fatalf("reconciled payment has receipt number %q", payment.MpesaReceiptNumber)
+	}
+
+	_, err := c.refund(t, payment, money.Money{})
+	if code := service.ErrorCode(err); code != service.CONFLICT_ERROR {
+		t.Errorf("RefundPayment() of a payment without a receipt error = %v, want code %q", err, service.CONFLICT_ERROR)
+	}
+
+	// The callback arrives late, with the receipt.
+	if err := c.daraja.SendLostCallback(ctx, res.CheckoutRequestID); err != nil {
+		t.Fatalf("SendLostCallback() error = %v", err)
+	}
+
+	payment = c.wantPaymentStatus(t, res, repository.PaymentStatusPaid)
+	transaction, _ := c.daraja.Transaction(res.CheckoutRequestID)
+	if payment.MpesaReceiptNumber == "" || payment.MpesaReceiptNumber != transaction.ReceiptNumber {
+		t.Errorf("payment receipt number = %q, want %q", payment.MpesaReceiptNumber, transaction.ReceiptNumber)
+	}
+
+	refund, err := c.refund(t, payment, money.Money{})
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+	c.wantRefundStatus(t, refund, repository.RefundStatusPending)
+
+	// Callbacks of payments that already have their receipt are rejected.
+	err = c.daraja.SendLostCallback(ctx, res.CheckoutRequestID)
+	if err == nil {
+		t.Errorf("SendLostCallback() of a payment with a receipt error = nil, want an error")
+	}
+}
@@ -0,0 +1,327 @@
+package mpesa
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+
+	orders "github.com/Mik3y-F/order-management-system/orders/pkg/client"
+)
+
+const (
+	// MPESA_INITIATOR_NAME and MPESA_SECURITY_CREDENTIAL authenticate the API
+	// operator that initiates reversals. The security credential is the
+	// initiator password encrypted with the M-Pesa public certificate.
+	MPESA_INITIATOR_NAME      = "MPESA_INITIATOR_NAME"      // #nosec G101 - This is an env variable name
+	MPESA_SECURITY_CREDENTIAL = "MPESA_SECURITY_CREDENTIAL" // #nosec G101 - This is an env variable name
+
+	// shortCodeIdentifierType identifies the receiver party of a reversal as
+	// an organisation short code.
+	shortCodeIdentifierType = "11"
+)
+
+// reversalCredentials returns the initiator name and security credential of
+// reversals, and the business short code they are made from.
+func reversalCredentials() (string, string, uint, error) {
+	initiator := os.Getenv(MPESA_INITIATOR_NAME)
+	credential := os.Getenv(MPESA_SECURITY_CREDENTIAL)
+	if initiator == "" || credential == "" {
+		return "", "", 0, service.Errorf(service.INTERNAL_ERROR,
+			"refunds need %s and %s to be set", MPESA_INITIATOR_NAME, MPESA_SECURITY_CREDENTIAL)
+	}
+
+	businessShortCode, err := pkg.StringToUint(pkg.MustGetEnv(MPESA_BUSINESS_SHORT_CODE))
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to convert business short code to uint: %v", err)
+	}
+
+	return initiator, credential, businessShortCode, nil
+}
+
+func (s *PaymentsService) RefundPayment(ctx context.Context, refund *service.Refund) (*service.RefundResponse, error) {
+	s.CheckPreconditions()
+
+	initiator, credential, businessShortCode, err := reversalCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := s.db.GetPaymentByID(ctx, refund.PaymentId)
+	if code := service.ErrorCode(err); code == service.NOT_FOUND_ERROR || code == service.INVALID_ERROR {
+		return nil, err
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get payment: %v", err)
+	}
+
+	if payment.Status != repository.PaymentStatusPaid {
+		return nil, service.Errorf(service.CONFLICT_ERROR,
+			"payment %s is %s, only paid payments can be refunded", payment.Id, payment.Status)
+	}
+
+	// Reversals name the M-Pesa transaction by its receipt, which payments
+	// settled by the reconciler only have once their callback arrives.
+	if payment.MpesaReceiptNumber == "" {
+		return nil, service.Errorf(service.CONFLICT_ERROR,
+			"payment %s has no M-Pesa receipt number yet, so it cannot be refunded", payment.Id)
+	}
+
+	refundable, err := s.refundableAmount(ctx, payment)
+	if err != nil {
+		return nil, err
+	}
+
+	if refundable.IsZero() {
+		return nil, service.Errorf(service.CONFLICT_ERROR, "payment %s is already refunded", payment.Id)
+	}
+
+	amount := refund.Amount
+	if amount.IsZero() {
+		amount = refundable
+	}
+
+	shillings, err := stkPushAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if left, err := refundable.Sub(amount); err != nil || left.IsNegative() {
+		return nil, service.Errorf(service.INVALID_ERROR,
+			"refund of %s exceeds the %s left to refund on payment %s", amount, refundable, payment.Id)
+	}
+
+	callbackToken, err := newCallbackToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &repository.Refund{
+		PaymentID:     payment.Id,
+		OrderID:       payment.OrderID,
+		Amount:        amount,
+		Status:        repository.RefundStatusPending,
+		Reason:        refund.Reason,
+		CallbackToken: callbackToken,
+	}
+	// Refunds requested at the same time are checked against the paid amount
+	// again as they are stored, and the ones that would exceed it conflict.
+	refundID, err := s.refunds.CreateRefund(ctx, record, paidAmount(payment))
+	if code := service.ErrorCode(err); code == service.CONFLICT_ERROR {
+		return nil, err
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to store refund record: %v", err)
+	}
+
+	resultURL := strings.TrimSuffix(refund.ResultURL, "/") + "/" + refundID + "/" + callbackToken
+	reversal, err := s.mpesa.reverse(ctx, reversalRequest{
+		Initiator:              initiator,
+		SecurityCredential:     credential,
+		CommandID:              "TransactionReversal",
+		TransactionID:          payment.MpesaReceiptNumber,
+		Amount:                 shillings,
+		ReceiverParty:          businessShortCode,
+		RecieverIdentifierType: shortCodeIdentifierType,
+		ResultURL:              resultURL + "/result",
+		QueueTimeOutURL:        resultURL + "/timeout",
+		Remarks:                reversalRemarks(refund.Reason),
+		Occasion:               payment.Reference,
+	})
+	if err != nil {
+		// The reversal was never accepted, so no result will settle the refund.
+		settleErr := s.refunds.SettleRefund(ctx, refundID, &repository.RefundResult{
+			Status:     repository.RefundStatusFailed,
+			ResultDesc: err.Error(),
+		})
+		if settleErr != nil {
+			return nil, service.Errorf(service.INTERNAL_ERROR,
+				"failed to request reversal: %v; and to fail refund %s: %v", err, refundID, settleErr)
+		}
+
+		return nil, fmt.Errorf("failed to request reversal: %v", MpesaErrorToInternalError(err))
+	}
+
+	return &service.RefundResponse{
+		RefundId:                 refundID,
+		Amount:                   amount,
+		ConversationID:           reversal.ConversationID,
+		OriginatorConversationID: reversal.OriginatorConversationID,
+		ResponseDescription:      reversal.ResponseDescription,
+	}, nil
+}
+
+// reversalRemarks returns the remarks of a reversal, which M-Pesa requires
+// and limits to 100 characters.
+func reversalRemarks(reason string) string {
+	if reason == "" {
+		return "Refund"
+	}
+
+	if len(reason) > 100 {
+		return reason[:100]
+	}
+
+	return reason
+}
+
+// paidAmount returns the amount M-Pesa reports was paid for the payment, or
+// its requested amount for payments settled before results were stored.
+func paidAmount(payment *repository.Payment) money.Money {
+	if payment.PaidAmount.IsZero() {
+		return payment.Amount
+	}
+
+	return payment.PaidAmount
+}
+
+// refundableAmount returns the amount of the payment that is not refunded or
+// being refunded yet.
+func (s *PaymentsService) refundableAmount(ctx context.Context, payment *repository.Payment) (money.Money, error) {
+	refunds, err := s.refunds.ListRefundsByPayment(ctx, payment.Id)
+	if err != nil {
+		return money.Money{}, service.Errorf(service.INTERNAL_ERROR, "failed to list refunds: %v", err)
+	}
+
+	refundable := paidAmount(payment)
+	for _, refund := range refunds {
+		if refund.Status == repository.RefundStatusFailed {
+			continue
+		}
+
+		if refundable, err = refundable.Sub(refund.Amount); err != nil {
+			return money.Money{}, service.Errorf(service.INTERNAL_ERROR, "failed to add up refunds: %v", err)
+		}
+	}
+
+	if refundable.IsNegative() {
+		return money.Zero(refundable.Currency), nil
+	}
+
+	return refundable, nil
+}
+
+func (s *PaymentsService) HandleReversalResult(
+	ctx context.Context, refundID string, token string, result *service.ReversalResult) error {
+	s.CheckPreconditions()
+
+	refund, err := s.authenticateRefund(ctx, refundID, token)
+	if err != nil {
+		return err
+	}
+
+	settled := &repository.RefundResult{
+		Status:                   repository.RefundStatusFailed,
+		ResultCode:               fmt.Sprint(result.ResultCode),
+		ResultDesc:               result.ResultDesc,
+		TransactionID:            result.TransactionID,
+		ConversationID:           result.ConversationID,
+		OriginatorConversationID: result.OriginatorConversationID,
+	}
+
+	if settled.ResultCode == "0" {
+		settled.Status = repository.RefundStatusCompleted
+	}
+
+	// The refund is recorded before the order is updated: M-Pesa has moved
+	// the money whatever the order service makes of it.
+	if err := s.settleRefund(ctx, refund, settled); err != nil {
+		return err
+	}
+
+	if settled.Status != repository.RefundStatusCompleted {
+		return nil
+	}
+
+	return s.refundOrder(ctx, refund)
+}
+
+func (s *PaymentsService) HandleReversalTimeout(ctx context.Context, refundID string, token string) error {
+	s.CheckPreconditions()
+
+	refund, err := s.authenticateRefund(ctx, refundID, token)
+	if err != nil {
+		return err
+	}
+
+	return s.settleRefund(ctx, refund, &repository.RefundResult{
+		Status:     repository.RefundStatusFailed,
+		ResultDesc: "The reversal timed out in the M-Pesa queue.",
+	})
+}
+
+// authenticateRefund returns the pending refund whose reversal result carries
+// the token.
+func (s *PaymentsService) authenticateRefund(
+	ctx context.Context, refundID string, token string) (*repository.Refund, error) {
+
+	refund, err := s.refunds.GetRefundByID(ctx, refundID)
+	if code := service.ErrorCode(err); code == service.NOT_FOUND_ERROR || code == service.INVALID_ERROR {
+		// Unknown refunds are rejected like a wrong token, so that callers
+		// cannot probe for refund ids.
+		return nil, service.Errorf(service.AUTHENTICATION_ERROR, "invalid callback token")
+	} else if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "failed to get refund: %v", err)
+	}
+
+	if refund.CallbackToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(refund.CallbackToken)) != 1 {
+		return nil, service.Errorf(service.AUTHENTICATION_ERROR, "invalid callback token")
+	}
+
+	if refund.Status != repository.RefundStatusPending {
+		return nil, service.Errorf(service.CONFLICT_ERROR, "refund %s is already %s", refund.Id, refund.Status)
+	}
+
+	return refund, nil
+}
+
+// refundOrder moves the order of the completed refund to refunded once the
+// completed refunds of its payment add up to the paid amount. Orders that are
+// refunded in part keep their status, and so do orders that can no longer be
+// refunded, such as cancelled orders.
+func (s *PaymentsService) refundOrder(ctx context.Context, refund *repository.Refund) error {
+	payment, err := s.db.GetPaymentByID(ctx, refund.PaymentID)
+	if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to get payment: %v", err)
+	}
+
+	refunds, err := s.refunds.ListRefundsByPayment(ctx, payment.Id)
+	if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to list refunds: %v", err)
+	}
+
+	var refunded money.Money
+	for _, r := range refunds {
+		if r.Status != repository.RefundStatusCompleted {
+			continue
+		}
+
+		if refunded, err = refunded.Add(r.Amount); err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to add up refunds: %v", err)
+		}
+	}
+
+	if refunded != paidAmount(payment) {
+		return nil
+	}
+
+	return s.moveOrder(ctx, payment.OrderID, orders.OrderStatusRefunded, "M-Pesa payment reversed")
+}
+
+// settleRefund records the result of the reversal of a pending refund.
+func (s *PaymentsService) settleRefund(
+	ctx context.Context, refund *repository.Refund, result *repository.RefundResult) error {
+
+	err := s.refunds.SettleRefund(ctx, refund.Id, result)
+	if code := service.ErrorCode(err); code == service.CONFLICT_ERROR {
+		return err
+	} else if err != nil {
+		return service.Errorf(service.INTERNAL_ERROR, "failed to settle refund(%s): %v", result.Status, err)
+	}
+
+	return nil
+}
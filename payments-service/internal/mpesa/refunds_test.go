@@ -0,0 +1,240 @@
+package mpesa_test
+
+import (
+	"context"
+	"testing"
+
+	orderPkg "github.com/Mik3y-F/order-management-system/orders/pkg"
+	orders "github.com/Mik3y-F/order-management-system/orders/pkg/client"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+// paid pays KES 100 and returns the paid payment.
+func (c *checkout) paid(t *testing.T) *repository.Payment {
+	t.Helper()
+
+	res := c.pay(t, 254700000000)
+	if err := c.daraja.SendCallbacks(context.Background()); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	return c.wantPaymentStatus(t, res, repository.PaymentStatusPaid)
+}
+
+// refund requests a refund of the amount of the payment, which is all of
+// what is left if the amount is zero.
+func (c *checkout) refund(
+	t *testing.T, payment *repository.Payment, amount money.Money) (*service.RefundResponse, error) {
+	t.Helper()
+
+	return c.service.RefundPayment(context.Background(), &service.Refund{
+		PaymentId: payment.Id,
+		Amount:    amount,
+		Reason:    "Out of stock",
+		ResultURL: c.callbacks.RefundCallbackURL(),
+	})
+}
+
+// wantRefundStatus checks the status of the refund, and returns it.
+func (c *checkout) wantRefundStatus(
+	t *testing.T, res *service.RefundResponse, want repository.RefundStatus) *repository.Refund {
+	t.Helper()
+
+	refund, err := c.refunds.GetRefundByID(context.Background(), res.RefundId)
+	if err != nil {
+		t.Fatalf("failed to get refund: %v", err)
+	}
+
+	if refund.Status != want {
+		t.Errorf("refund %s has status %q, want %q", res.RefundId, refund.Status, want)
+	}
+
+	return refund
+}
+
+// refunded reports whether the order was moved to refunded.
+func (c *checkout) refunded() bool {
+	c.orders.mu.Lock()
+	defer c.orders.mu.Unlock()
+
+	for _, update := range c.orders.updates {
+		if update.Status == orders.OrderStatusRefunded {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestRefunds(t *testing.T) {
+	ctx := context.Background()
+
+	c := setupCheckout(t)
+	payment := c.paid(t)
+
+	partial, err := c.refund(t, payment, money.New(4000, "KES"))
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+	c.wantRefundStatus(t, partial, repository.RefundStatusPending)
+
+	// The pending refund counts towards the amount refunded.
+	_, err = c.refund(t, payment, money.New(7000, "KES"))
+	if code := service.ErrorCode(err); code != service.INVALID_ERROR {
+		t.Errorf("RefundPayment() of more than is left error = %v, want code %q", err, service.INVALID_ERROR)
+	}
+
+	if err := c.daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	refund := c.wantRefundStatus(t, partial, repository.RefundStatusCompleted)
+	if refund.TransactionID == "" || refund.ConversationID != partial.ConversationID {
+		t.Errorf("refund = %+v, want the result of conversation %q", refund, partial.ConversationID)
+	}
+
+	if c.refunded() {
+		t.Errorf("order of a partially refunded payment was moved to refunded")
+	}
+
+	rest, err := c.refund(t, payment, money.Money{})
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+
+	if want := money.New(6000, "KES"); rest.Amount != want {
+		t.Errorf("RefundPayment() of the rest refunded %v, want %v", rest.Amount, want)
+	}
+
+	if err := c.daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	c.wantRefundStatus(t, rest, repository.RefundStatusCompleted)
+
+	if !c.refunded() {
+		t.Errorf("order of a refunded payment was not moved to refunded")
+	}
+
+	transaction, _ := c.daraja.Transaction(payment.CheckoutRequestID)
+	if transaction.Reversed != 100 {
+		t.Errorf("M-Pesa reversed %d of the payment, want 100", transaction.Reversed)
+	}
+
+	_, err = c.refund(t, payment, money.Money{})
+	if code := service.ErrorCode(err); code != service.CONFLICT_ERROR {
+		t.Errorf("RefundPayment() of a refunded payment error = %v, want code %q", err, service.CONFLICT_ERROR)
+	}
+}
+
+func TestRefunds_Timeout(t *testing.T) {
+	ctx := context.Background()
+
+	c := setupCheckout(t)
+	payment := c.paid(t)
+
+	res, err := c.refund(t, payment, money.Money{})
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+	refund := c.wantRefundStatus(t, res, repository.RefundStatusPending)
+
+	err = c.service.HandleReversalTimeout(ctx, refund.Id, "guessed-token")
+	if code := service.ErrorCode(err); code != service.AUTHENTICATION_ERROR {
+		t.Errorf("HandleReversalTimeout() error = %v, want code %q", err, service.AUTHENTICATION_ERROR)
+	}
+
+	if err := c.service.HandleReversalTimeout(ctx, refund.Id, refund.CallbackToken); err != nil {
+		t.Fatalf("HandleReversalTimeout() error = %v", err)
+	}
+	c.wantRefundStatus(t, res, repository.RefundStatusFailed)
+
+	// The result that arrives after the timeout is rejected.
+	if err := c.daraja.SendCallbacks(ctx); err == nil {
+		t.Errorf("SendCallbacks() of the result of a timed out reversal error = nil, want an error")
+	}
+	c.wantRefundStatus(t, res, repository.RefundStatusFailed)
+
+	if c.refunded() {
+		t.Errorf("order of a failed refund was moved to refunded")
+	}
+}
+
+func TestRefunds_NotPaid(t *testing.T) {
+	c := setupCheckout(t)
+
+	res := c.pay(t, 254700000000)
+	payment := c.wantPaymentStatus(t, res, repository.PaymentStatusPending)
+
+	_, err := c.refund(t, payment, money.Money{})
+	if code := service.ErrorCode(err); code != service.CONFLICT_ERROR {
+		t.Errorf("RefundPayment() of a pending payment error = %v, want code %q", err, service.CONFLICT_ERROR)
+	}
+
+	_, err = c.service.RefundPayment(context.Background(), &service.Refund{PaymentId: "unknown"})
+	if code := service.ErrorCode(err); code != service.NOT_FOUND_ERROR {
+		t.Errorf("RefundPayment() of an unknown payment error = %v, want code %q", err, service.NOT_FOUND_ERROR)
+	}
+}
+
+func TestRefunds_CancelledOrder(t *testing.T) {
+	ctx := context.Background()
+
+	c := setupCheckout(t)
+
+	// The customer cancels the order while the STK push is pending, and then
+	// pays anyway.
+	res := c.pay(t, 254700000000)
+	c.orders.setStatus("order-1", orderPkg.OrderStatusCancelled)
+
+	if err := c.daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	payment := c.wantPaymentStatus(t, res, repository.PaymentStatusPaid)
+	if payment.MpesaReceiptNumber == "" {
+		t.Fatalf("payment of a cancelled order has no receipt number")
+	}
+
+	refund, err := c.refund(t, payment, money.Money{})
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+
+	if err := c.daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	c.wantRefundStatus(t, refund, repository.RefundStatusCompleted)
+
+	if got := c.orders.orderStatus("order-1"); got != orderPkg.OrderStatusCancelled {
+		t.Errorf("order status = %q, want %q", got, orderPkg.OrderStatusCancelled)
+	}
+}
+
+func TestRefunds_ShippedOrder(t *testing.T) {
+	ctx := context.Background()
+
+	c := setupCheckout(t)
+	c.orders.setStatus("order-1", orderPkg.OrderStatusProcessing)
+
+	payment := c.paid(t)
+	c.orders.setStatus("order-1", orderPkg.OrderStatusShipped)
+
+	refund, err := c.refund(t, payment, money.Money{})
+	if err != nil {
+		t.Fatalf("RefundPayment() error = %v", err)
+	}
+
+	if err := c.daraja.SendCallbacks(ctx); err != nil {
+		t.Fatalf("SendCallbacks() error = %v", err)
+	}
+
+	c.wantRefundStatus(t, refund, repository.RefundStatusCompleted)
+
+	if got := c.orders.orderStatus("order-1"); got != orderPkg.OrderStatusRefunded {
+		t.Errorf("order status = %q, want %q", got, orderPkg.OrderStatusRefunded)
+	}
+}
@@ -0,0 +1,80 @@
+package mpesa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const reversalPath = "/mpesa/reversal/v1/request"
+
+// reversalRequest is the body of a request to the Reversal API, which the
+// SDK does not support.
+type reversalRequest struct {
+	Initiator              string `json:"Initiator"`
+	SecurityCredential     string `json:"SecurityCredential"`
+	CommandID              string `json:"CommandID"`
+	TransactionID          string `json:"TransactionID"`
+	Amount                 uint   `json:"Amount"`
+	ReceiverParty          uint   `json:"ReceiverParty"`
+	RecieverIdentifierType string `json:"RecieverIdentifierType"`
+	ResultURL              string `json:"ResultURL"`
+	QueueTimeOutURL        string `json:"QueueTimeOutURL"`
+	Remarks                string `json:"Remarks"`
+	Occasion               string `json:"Occasion"`
+}
+
+// reversalResponse acknowledges a reversal request. The result of the
+// reversal is POSTed to its ResultURL later on.
+type reversalResponse struct {
+	OriginatorConversationID string `json:"OriginatorConversationID"`
+	ConversationID           string `json:"ConversationID"`
+	ResponseCode             string `json:"ResponseCode"`
+	ResponseDescription      string `json:"ResponseDescription"`
+
+	// Set instead when the request is rejected.
+	RequestID    string `json:"requestId"`
+	ErrorCode    string `json:"errorCode"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// reverse sends the reversal request to the Daraja API. Errors are in the
+// format of the SDK, so that MpesaErrorToInternalError understands them.
+func (m *Mpesa) reverse(ctx context.Context, req reversalRequest) (*reversalResponse, error) {
+	accessToken, err := m.app.GenerateAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("mpesa: failed to encode reversal request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+reversalPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("mpesa: failed to create reversal request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+
+	httpRes, err := m.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("mpesa: failed to send reversal request: %v", err)
+	}
+	defer httpRes.Body.Close()
+
+	var res reversalResponse
+	if err := json.NewDecoder(httpRes.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("mpesa: failed to decode reversal response: %v", err)
+	}
+
+	if httpRes.StatusCode != http.StatusOK || res.ErrorCode != "" {
+		return nil, fmt.Errorf("mpesa: reversal request ID %v failed with error code %v:%v",
+			res.RequestID, res.ErrorCode, res.ErrorMessage)
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,19 @@
+CREATE TABLE payments (
+	seq                 BIGINT GENERATED ALWAYS AS IDENTITY,
+	id                  TEXT PRIMARY KEY,
+	amount              BIGINT NOT NULL CHECK (amount >= 0),
+	merchant_request_id TEXT NOT NULL DEFAULT '',
+	status              TEXT NOT NULL,
+	order_id            TEXT NOT NULL DEFAULT '',
+	phone               TEXT NOT NULL DEFAULT '',
+	reference           TEXT NOT NULL DEFAULT '',
+	description         TEXT NOT NULL DEFAULT '',
+	created_at          TIMESTAMPTZ NOT NULL,
+	updated_at          TIMESTAMPTZ NOT NULL
+);
+
+-- M-Pesa merchant request ids identify a single STK push.
+CREATE UNIQUE INDEX payments_merchant_request_id_idx ON payments (merchant_request_id)
+	WHERE merchant_request_id <> '';
+
+CREATE INDEX payments_order_id_idx ON payments (order_id);
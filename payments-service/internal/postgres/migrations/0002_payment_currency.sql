@@ -0,0 +1,6 @@
+-- Amounts are stored in the minor units of their currency, e.g. cents, next to
+-- the ISO 4217 code of the currency. All existing amounts were whole Kenyan
+-- shillings.
+ALTER TABLE payments ADD COLUMN currency TEXT NOT NULL DEFAULT 'KES';
+ALTER TABLE payments ALTER COLUMN currency DROP DEFAULT;
+UPDATE payments SET amount = amount * 100;
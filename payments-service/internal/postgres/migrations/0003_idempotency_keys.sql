@@ -0,0 +1,14 @@
+-- Requests with an idempotency key are recorded with their response, which is
+-- replayed to retries of the request until the record expires. The response
+-- is NULL while the first request is in progress.
+CREATE TABLE payment_idempotency_keys (
+	method      TEXT NOT NULL,
+	key         TEXT NOT NULL CHECK (key <> ''),
+	fingerprint TEXT NOT NULL,
+	response    BYTEA,
+	created_at  TIMESTAMPTZ NOT NULL,
+	expires_at  TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (method, key)
+);
+
+CREATE INDEX payment_idempotency_keys_expires_at_idx ON payment_idempotency_keys (expires_at);
@@ -0,0 +1,5 @@
+-- Callbacks are checked against the STK push they answer and the secret token
+-- in their URL. Payments requested before have neither and cannot be
+-- confirmed by a callback.
+ALTER TABLE payments ADD COLUMN checkout_request_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN callback_token TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- The reconciler looks up the payments still pending, oldest first.
+CREATE INDEX payments_pending_idx ON payments (created_at) WHERE status = 'pending';
@@ -0,0 +1,14 @@
+-- The result of the STK push of settled payments, from their M-Pesa callback
+-- or a transaction status query. The receipt, paid amount, payer and
+-- transaction time are only reported by callbacks.
+ALTER TABLE payments ADD COLUMN result_code INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN result_desc TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN mpesa_receipt_number TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN paid_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN paid_currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN payer_phone TEXT NOT NULL DEFAULT '';
+ALTER TABLE payments ADD COLUMN transaction_time TIMESTAMPTZ;
+
+-- M-Pesa receipt numbers identify a single transaction.
+CREATE UNIQUE INDEX payments_mpesa_receipt_number_idx ON payments (mpesa_receipt_number)
+	WHERE mpesa_receipt_number <> '';
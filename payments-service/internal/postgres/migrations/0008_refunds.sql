@@ -0,0 +1,22 @@
+-- Refunds reverse some or all of a paid payment through M-Pesa. They are
+-- pending until M-Pesa reports the result of the reversal.
+CREATE TABLE refunds (
+	seq                        BIGINT GENERATED ALWAYS AS IDENTITY,
+	id                         TEXT PRIMARY KEY,
+	payment_id                 TEXT NOT NULL,
+	order_id                   TEXT NOT NULL DEFAULT '',
+	amount                     BIGINT NOT NULL CHECK (amount > 0),
+	currency                   TEXT NOT NULL,
+	status                     TEXT NOT NULL,
+	reason                     TEXT NOT NULL DEFAULT '',
+	result_code                TEXT NOT NULL DEFAULT '',
+	result_desc                TEXT NOT NULL DEFAULT '',
+	transaction_id             TEXT NOT NULL DEFAULT '',
+	conversation_id            TEXT NOT NULL DEFAULT '',
+	originator_conversation_id TEXT NOT NULL DEFAULT '',
+	callback_token             TEXT NOT NULL DEFAULT '',
+	created_at                 TIMESTAMPTZ NOT NULL,
+	updated_at                 TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX refunds_payment_id_idx ON refunds (payment_id, seq);
@@ -0,0 +1,16 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	repositorytest.TestOutboxRepository(t, func(t *testing.T) (repository.PaymentsRepository, repository.OutboxRepository) {
+		db := MustOpenDB(t)
+		return postgres.NewPaymentsRepository(db), postgres.NewOutboxRepository(db)
+	})
+}
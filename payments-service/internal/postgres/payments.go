@@ -0,0 +1,342 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+var _ repository.PaymentsRepository = (*PaymentsRepository)(nil)
+
+const paymentColumns = `id, amount, currency, merchant_request_id, checkout_request_id, status, order_id, ` +
+	`phone, reference, description, callback_token, created_at, updated_at, result_code, result_desc, ` +
+	`mpesa_receipt_number, paid_amount, paid_currency, payer_phone, transaction_time`
+
+type PaymentsRepository struct {
+	db *DB
+}
+
+func NewPaymentsRepository(db *DB) *PaymentsRepository {
+	return &PaymentsRepository{
+		db: db,
+	}
+}
+
+func (r *PaymentsRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *PaymentsRepository) CreatePayment(ctx context.Context, payment *repository.Payment) (string, error) {
+	r.CheckPreconditions()
+
+	currentTime := now()
+	payment.CreatedAt = formatTime(currentTime)
+	payment.UpdatedAt = formatTime(currentTime)
+
+	err := payment.Validate()
+	if err != nil {
+		return "", service.Errorf(service.INVALID_ERROR, "invalid payment details provided: %v", err)
+	}
+
+	transactionTime, err := parseTransactionTime(payment.TransactionTime)
+	if err != nil {
+		return "", err
+	}
+
+	id := newID()
+
+	err = r.db.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO payments (`+paymentColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`,
+			id, payment.Amount.Amount, payment.Amount.Currency, payment.MerchantRequestID,
+			payment.CheckoutRequestID, string(payment.Status), payment.OrderID,
+			payment.Phone, payment.Reference, payment.Description, payment.CallbackToken, currentTime, currentTime,
+			payment.ResultCode, payment.ResultDesc, payment.MpesaReceiptNumber, payment.PaidAmount.Amount,
+			payment.PaidAmount.Currency, payment.PayerPhone, transactionTime,
+		)
+		if err != nil {
+			return dbError(err, "payment", "create")
+		}
+
+		created := *payment
+		created.Id = id
+
+		event, err := repository.NewPaymentCreatedEvent(&created)
+		if err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, event, currentTime)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payment.Id = id
+
+	return payment.Id, nil
+}
+
+func (r *PaymentsRepository) GetPaymentByID(ctx context.Context, paymentID string) (*repository.Payment, error) {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	payment, err := scanPayment(r.db.db.QueryRowContext(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE id = $1`, paymentID))
+	if err != nil {
+		return nil, dbError(err, "payment", "get")
+	}
+
+	return payment, nil
+}
+
+func (r *PaymentsRepository) GetPaymentByMerchantRequestID(
+	ctx context.Context, merchantRequestID string) (*repository.Payment, error) {
+	r.CheckPreconditions()
+
+	if merchantRequestID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid merchant request ID provided")
+	}
+
+	payment, err := scanPayment(r.db.db.QueryRowContext(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE merchant_request_id = $1`, merchantRequestID))
+	if err != nil {
+		return nil, dbError(err, "payment", "get")
+	}
+
+	return payment, nil
+}
+
+func (r *PaymentsRepository) GetPaymentByReceiptNumber(
+	ctx context.Context, receiptNumber string) (*repository.Payment, error) {
+	r.CheckPreconditions()
+
+	if receiptNumber == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid receipt number provided")
+	}
+
+	payment, err := scanPayment(r.db.db.QueryRowContext(ctx,
+		`SELECT `+paymentColumns+` FROM payments WHERE mpesa_receipt_number = $1`, receiptNumber))
+	if err != nil {
+		return nil, dbError(err, "payment", "get")
+	}
+
+	return payment, nil
+}
+
+func (r *PaymentsRepository) UpdatePaymentStatus(
+	ctx context.Context, paymentID string, status repository.PaymentStatus) error {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		timeNow := now()
+
+		payment, err := scanPayment(tx.QueryRowContext(ctx, `
+			UPDATE payments SET status = $2, updated_at = $3 WHERE id = $1
+			RETURNING `+paymentColumns, paymentID, string(status), timeNow))
+		if err != nil {
+			return dbError(err, "payment", "update")
+		}
+
+		event, err := repository.NewPaymentStatusEvent(payment)
+		if err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, event, timeNow)
+	})
+}
+
+func (r *PaymentsRepository) SetPaymentRequestIDs(
+	ctx context.Context, paymentID, merchantRequestID, checkoutRequestID string) error {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	var id string
+	err := r.db.db.QueryRowContext(ctx, `
+		UPDATE payments SET merchant_request_id = $2, checkout_request_id = $3, updated_at = $4
+		WHERE id = $1
+		RETURNING id`, paymentID, merchantRequestID, checkoutRequestID, now()).Scan(&id)
+
+	return dbError(err, "payment", "update")
+}
+
+func (r *PaymentsRepository) SettlePayment(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	transactionTime, err := parseTransactionTime(result.TransactionTime)
+	if err != nil {
+		return err
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		timeNow := now()
+
+		payment, err := scanPayment(tx.QueryRowContext(ctx,
+			`SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, paymentID))
+		if err != nil {
+			return dbError(err, "payment", "get")
+		}
+
+		if payment.Status != repository.PaymentStatusPending {
+			return service.Errorf(service.CONFLICT_ERROR, "payment %s is already %s", paymentID, payment.Status)
+		}
+
+		payment, err = scanPayment(tx.QueryRowContext(ctx, `
+			UPDATE payments SET status = $2, updated_at = $3, result_code = $4, result_desc = $5,
+				mpesa_receipt_number = $6, paid_amount = $7, paid_currency = $8, payer_phone = $9,
+				transaction_time = $10
+			WHERE id = $1
+			RETURNING `+paymentColumns,
+			paymentID, string(result.Status), timeNow, result.ResultCode, result.ResultDesc,
+			result.MpesaReceiptNumber, result.PaidAmount.Amount, result.PaidAmount.Currency, result.PayerPhone,
+			transactionTime))
+		if err != nil {
+			return dbError(err, "payment", "update")
+		}
+
+		event, err := repository.NewPaymentStatusEvent(payment)
+		if err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, event, timeNow)
+	})
+}
+
+func (r *PaymentsRepository) RecordPaymentReceipt(
+	ctx context.Context, paymentID string, result *repository.PaymentResult) error {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	transactionTime, err := parseTransactionTime(result.TransactionTime)
+	if err != nil {
+		return err
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		payment, err := scanPayment(tx.QueryRowContext(ctx,
+			`SELECT `+paymentColumns+` FROM payments WHERE id = $1 FOR UPDATE`, paymentID))
+		if err != nil {
+			return dbError(err, "payment", "get")
+		}
+
+		if payment.Status != repository.PaymentStatusPaid || payment.MpesaReceiptNumber != "" {
+			return service.Errorf(service.CONFLICT_ERROR,
+				"payment %s is %s with receipt %q, only paid payments without one can record it",
+				paymentID, payment.Status, payment.MpesaReceiptNumber)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE payments SET updated_at = $2, mpesa_receipt_number = $3, paid_amount = $4, paid_currency = $5,
+				payer_phone = $6, transaction_time = $7
+			WHERE id = $1`,
+			paymentID, now(), result.MpesaReceiptNumber, result.PaidAmount.Amount, result.PaidAmount.Currency,
+			result.PayerPhone, transactionTime)
+
+		return dbError(err, "payment", "update")
+	})
+}
+
+func (r *PaymentsRepository) ListPendingPayments(
+	ctx context.Context, createdBefore time.Time, limit int) ([]*repository.Payment, error) {
+	r.CheckPreconditions()
+
+	rows, err := r.db.db.QueryContext(ctx, `
+		SELECT `+paymentColumns+` FROM payments
+		WHERE status = $1 AND checkout_request_id <> '' AND created_at < $2
+		ORDER BY created_at, seq
+		LIMIT $3`, string(repository.PaymentStatusPending), createdBefore, limit)
+	if err != nil {
+		return nil, dbError(err, "payments", "list")
+	}
+	defer rows.Close()
+
+	var payments []*repository.Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, dbError(err, "payments", "list")
+		}
+
+		payments = append(payments, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "payments", "list")
+	}
+
+	return payments, nil
+}
+
+func scanPayment(s scanner) (*repository.Payment, error) {
+	var (
+		payment              repository.Payment
+		amount               int64
+		currency             string
+		status               string
+		createdAt, updatedAt time.Time
+		paidAmount           int64
+		paidCurrency         string
+		transactionTime      sql.NullTime
+	)
+
+	if err := s.Scan(
+		&payment.Id, &amount, &currency, &payment.MerchantRequestID, &payment.CheckoutRequestID, &status,
+		&payment.OrderID, &payment.Phone, &payment.Reference, &payment.Description, &payment.CallbackToken,
+		&createdAt, &updatedAt, &payment.ResultCode, &payment.ResultDesc, &payment.MpesaReceiptNumber,
+		&paidAmount, &paidCurrency, &payment.PayerPhone, &transactionTime,
+	); err != nil {
+		return nil, err
+	}
+
+	payment.Amount = money.New(amount, currency)
+	payment.Status = repository.PaymentStatus(status)
+	payment.CreatedAt = formatTime(createdAt)
+	payment.UpdatedAt = formatTime(updatedAt)
+	payment.PaidAmount = money.New(paidAmount, paidCurrency)
+	if transactionTime.Valid {
+		payment.TransactionTime = formatTime(transactionTime.Time)
+	}
+
+	return &payment, nil
+}
+
+// parseTransactionTime parses the RFC 3339 transaction time of a payment. The
+// transaction time of unsettled payments is empty, and stored as NULL.
+func parseTransactionTime(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, service.Errorf(service.INVALID_ERROR, "invalid transaction time %q", s)
+	}
+
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
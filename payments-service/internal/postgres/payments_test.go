@@ -0,0 +1,38 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func TestPaymentsRepository(t *testing.T) {
+	repositorytest.TestPaymentsRepository(t, func(t *testing.T) repository.PaymentsRepository {
+		return postgres.NewPaymentsRepository(MustOpenDB(t))
+	})
+}
+
+func TestPaymentsRepository_CreatePayment_DuplicateMerchantRequestID(t *testing.T) {
+	ctx := context.Background()
+	paymentsRepository := postgres.NewPaymentsRepository(MustOpenDB(t))
+
+	payment := &repository.Payment{
+		Amount:            money.New(100, "KES"),
+		MerchantRequestID: "merchant-request-1",
+		Status:            repository.PaymentStatusPending,
+	}
+	if _, err := paymentsRepository.CreatePayment(ctx, payment); err != nil {
+		t.Fatalf("PaymentsRepository.CreatePayment() error = %v", err)
+	}
+
+	// Merchant request ids identify a single STK push.
+	duplicate := *payment
+	if _, err := paymentsRepository.CreatePayment(ctx, &duplicate); service.ErrorCode(err) != service.ALREADY_EXISTS_ERROR {
+		t.Errorf("PaymentsRepository.CreatePayment() error = %v, want %q", err, service.ALREADY_EXISTS_ERROR)
+	}
+}
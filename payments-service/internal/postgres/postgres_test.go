@@ -0,0 +1,53 @@
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/postgres"
+)
+
+// POSTGRES_TEST_DSN points the tests at a disposable database. All tables are
+// truncated before each test, so never point it at a database holding real data.
+const POSTGRES_TEST_DSN = "POSTGRES_TEST_DSN"
+
+// MustOpenDB opens & migrates the test database, skipping the test when no
+// database has been configured.
+func MustOpenDB(tb testing.TB) *postgres.DB {
+	tb.Helper()
+
+	dsn := os.Getenv(POSTGRES_TEST_DSN)
+	if dsn == "" {
+		tb.Skipf("%s is not set, skipping postgres tests", POSTGRES_TEST_DSN)
+	}
+
+	db := postgres.NewDB(dsn)
+	if err := db.Open(); err != nil {
+		tb.Fatalf("failed to open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	if err := db.Truncate(context.Background()); err != nil {
+		tb.Fatalf("failed to truncate database: %v", err)
+	}
+
+	return db
+}
+
+func TestDB_Open(t *testing.T) {
+	db := MustOpenDB(t)
+
+	// Migrations are idempotent, so reopening an up-to-date database must work.
+	again := postgres.NewDB(db.DSN)
+	if err := again.Open(); err != nil {
+		t.Fatalf("DB.Open() error = %v", err)
+	}
+	defer again.Close()
+}
+
+func TestDB_Open_MissingDSN(t *testing.T) {
+	if err := postgres.NewDB("").Open(); err == nil {
+		t.Errorf("DB.Open() error = nil, want error for missing dsn")
+	}
+}
@@ -0,0 +1,212 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+var _ repository.RefundsRepository = (*RefundsRepository)(nil)
+
+const refundColumns = `id, payment_id, order_id, amount, currency, status, reason, result_code, result_desc, ` +
+	`transaction_id, conversation_id, originator_conversation_id, callback_token, created_at, updated_at`
+
+type RefundsRepository struct {
+	db *DB
+}
+
+func NewRefundsRepository(db *DB) *RefundsRepository {
+	return &RefundsRepository{
+		db: db,
+	}
+}
+
+func (r *RefundsRepository) CheckPreconditions() {
+	if r.db == nil {
+		panic("no DB service provided")
+	}
+}
+
+func (r *RefundsRepository) CreateRefund(
+	ctx context.Context, refund *repository.Refund, limit money.Money) (string, error) {
+	r.CheckPreconditions()
+
+	currentTime := now()
+	refund.CreatedAt = formatTime(currentTime)
+	refund.UpdatedAt = formatTime(currentTime)
+
+	err := refund.Validate()
+	if err != nil {
+		return "", service.Errorf(service.INVALID_ERROR, "invalid refund details provided: %v", err)
+	}
+
+	id := newID()
+
+	err = r.db.withTx(ctx, func(tx *sql.Tx) error {
+		// Refunds of the same payment are created one at a time, so that each
+		// sees the ones created before it.
+		_, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "refunds/"+refund.PaymentID)
+		if err != nil {
+			return dbError(err, "refund", "create")
+		}
+
+		refunds, err := listRefunds(ctx, tx, refund.PaymentID)
+		if err != nil {
+			return err
+		}
+
+		if err := repository.CheckRefundLimit(refund, refunds, limit); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO refunds (`+refundColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			id, refund.PaymentID, refund.OrderID, refund.Amount.Amount, refund.Amount.Currency,
+			string(refund.Status), refund.Reason, refund.ResultCode, refund.ResultDesc, refund.TransactionID,
+			refund.ConversationID, refund.OriginatorConversationID, refund.CallbackToken, currentTime, currentTime,
+		)
+		if err != nil {
+			return dbError(err, "refund", "create")
+		}
+
+		created := *refund
+		created.Id = id
+
+		event, err := repository.NewRefundCreatedEvent(&created)
+		if err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, event, currentTime)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	refund.Id = id
+
+	return refund.Id, nil
+}
+
+func (r *RefundsRepository) GetRefundByID(ctx context.Context, refundID string) (*repository.Refund, error) {
+	r.CheckPreconditions()
+
+	if refundID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid refund ID provided")
+	}
+
+	refund, err := scanRefund(r.db.db.QueryRowContext(ctx,
+		`SELECT `+refundColumns+` FROM refunds WHERE id = $1`, refundID))
+	if err != nil {
+		return nil, dbError(err, "refund", "get")
+	}
+
+	return refund, nil
+}
+
+func (r *RefundsRepository) ListRefundsByPayment(
+	ctx context.Context, paymentID string) ([]*repository.Refund, error) {
+	r.CheckPreconditions()
+
+	if paymentID == "" {
+		return nil, service.Errorf(service.INVALID_ERROR, "invalid payment ID provided")
+	}
+
+	return listRefunds(ctx, r.db.db, paymentID)
+}
+
+// listRefunds returns the refunds of a payment, oldest first.
+func listRefunds(ctx context.Context, q queryer, paymentID string) ([]*repository.Refund, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT `+refundColumns+` FROM refunds WHERE payment_id = $1 ORDER BY seq`, paymentID)
+	if err != nil {
+		return nil, dbError(err, "refunds", "list")
+	}
+	defer rows.Close()
+
+	var refunds []*repository.Refund
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, dbError(err, "refunds", "list")
+		}
+
+		refunds = append(refunds, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(err, "refunds", "list")
+	}
+
+	return refunds, nil
+}
+
+func (r *RefundsRepository) SettleRefund(
+	ctx context.Context, refundID string, result *repository.RefundResult) error {
+	r.CheckPreconditions()
+
+	if refundID == "" {
+		return service.Errorf(service.INVALID_ERROR, "invalid refund ID provided")
+	}
+
+	return r.db.withTx(ctx, func(tx *sql.Tx) error {
+		timeNow := now()
+
+		refund, err := scanRefund(tx.QueryRowContext(ctx,
+			`SELECT `+refundColumns+` FROM refunds WHERE id = $1 FOR UPDATE`, refundID))
+		if err != nil {
+			return dbError(err, "refund", "get")
+		}
+
+		if refund.Status != repository.RefundStatusPending {
+			return service.Errorf(service.CONFLICT_ERROR, "refund %s is already %s", refundID, refund.Status)
+		}
+
+		refund, err = scanRefund(tx.QueryRowContext(ctx, `
+			UPDATE refunds SET status = $2, updated_at = $3, result_code = $4, result_desc = $5,
+				transaction_id = $6, conversation_id = $7, originator_conversation_id = $8
+			WHERE id = $1
+			RETURNING `+refundColumns,
+			refundID, string(result.Status), timeNow, result.ResultCode, result.ResultDesc,
+			result.TransactionID, result.ConversationID, result.OriginatorConversationID))
+		if err != nil {
+			return dbError(err, "refund", "update")
+		}
+
+		event, err := repository.NewRefundStatusEvent(refund)
+		if err != nil {
+			return err
+		}
+
+		return insertOutboxEvent(ctx, tx, event, timeNow)
+	})
+}
+
+func scanRefund(s scanner) (*repository.Refund, error) {
+	var (
+		refund               repository.Refund
+		amount               int64
+		currency             string
+		status               string
+		createdAt, updatedAt time.Time
+	)
+
+	if err := s.Scan(
+		&refund.Id, &refund.PaymentID, &refund.OrderID, &amount, &currency, &status, &refund.Reason,
+		&refund.ResultCode, &refund.ResultDesc, &refund.TransactionID, &refund.ConversationID,
+		&refund.OriginatorConversationID, &refund.CallbackToken, &createdAt, &updatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	refund.Amount = money.New(amount, currency)
+	refund.Status = repository.RefundStatus(status)
+	refund.CreatedAt = formatTime(createdAt)
+	refund.UpdatedAt = formatTime(updatedAt)
+
+	return &refund, nil
+}
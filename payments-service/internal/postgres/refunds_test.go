@@ -0,0 +1,15 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/postgres"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository/repositorytest"
+)
+
+func TestRefundsRepository(t *testing.T) {
+	repositorytest.TestRefundsRepository(t, func(t *testing.T) repository.RefundsRepository {
+		return postgres.NewRefundsRepository(MustOpenDB(t))
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+const (
+	// PaymentAggregate is the aggregate type of payment events.
+	PaymentAggregate = "payment"
+
+	// EventPaymentCreated is recorded when a payment is requested. Status
+	// changes record the event type of the new status, e.g. PaymentPaid.
+	EventPaymentCreated = "PaymentCreated"
+
+	// RefundAggregate is the aggregate type of refund events.
+	RefundAggregate = "refund"
+
+	// EventRefundCreated is recorded when a refund is requested. Status
+	// changes record the event type of the new status, e.g. RefundCompleted.
+	EventRefundCreated = "RefundCreated"
+)
+
+// PaymentEvent is the payload of the events recorded about a payment.
+type PaymentEvent struct {
+	Payment *Payment `json:"payment"`
+}
+
+// NewPaymentCreatedEvent returns the event recorded when the payment is
+// created.
+func NewPaymentCreatedEvent(payment *Payment) (*outbox.Event, error) {
+	return newPaymentEvent(EventPaymentCreated, payment)
+}
+
+// NewPaymentStatusEvent returns the event recorded when the payment changes
+// status.
+func NewPaymentStatusEvent(payment *Payment) (*outbox.Event, error) {
+	return newPaymentEvent(payment.Status.EventType(), payment)
+}
+
+func newPaymentEvent(eventType string, payment *Payment) (*outbox.Event, error) {
+	event, err := outbox.NewEvent(eventType, PaymentAggregate, payment.Id, &PaymentEvent{Payment: payment})
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "%v", err)
+	}
+
+	return event, nil
+}
+
+// RefundEvent is the payload of the events recorded about a refund.
+type RefundEvent struct {
+	Refund *Refund `json:"refund"`
+}
+
+// NewRefundCreatedEvent returns the event recorded when the refund is created.
+func NewRefundCreatedEvent(refund *Refund) (*outbox.Event, error) {
+	return newRefundEvent(EventRefundCreated, refund)
+}
+
+// NewRefundStatusEvent returns the event recorded when the refund changes
+// status.
+func NewRefundStatusEvent(refund *Refund) (*outbox.Event, error) {
+	return newRefundEvent(refund.Status.EventType(), refund)
+}
+
+func newRefundEvent(eventType string, refund *Refund) (*outbox.Event, error) {
+	event, err := outbox.NewEvent(eventType, RefundAggregate, refund.Id, &RefundEvent{Refund: refund})
+	if err != nil {
+		return nil, service.Errorf(service.INTERNAL_ERROR, "%v", err)
+	}
+
+	return event, nil
+}
+
+var _ outbox.Store = OutboxRepository(nil)
+
+// OutboxRepository reads the events that the other repositories record in the
+// same write as the changes that cause them. It is drained by an outbox.Relay.
+type OutboxRepository interface {
+	// ListOutboxEvents returns up to limit events, oldest first.
+	ListOutboxEvents(ctx context.Context, limit int) ([]*outbox.Event, error)
+
+	// DeleteOutboxEvents removes published events. Unknown ids are ignored.
+	DeleteOutboxEvents(ctx context.Context, ids []string) error
+}
@@ -1,6 +1,13 @@
 package repository
 
-import "context"
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
 
 type PaymentStatus string
 
@@ -10,26 +17,113 @@ const (
 	PaymentStatusFailed  PaymentStatus = "failed"
 )
 
+// EventType returns the type of the event published when a payment moves to
+// status s, e.g. PaymentPaid for paid payments.
+func (s PaymentStatus) EventType() string {
+	name := string(s)
+	if name != "" {
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	return "Payment" + name
+}
+
 type Payment struct {
-	Id                string
-	Amount            uint
-	MerchantRequestID string
-	Status            PaymentStatus
-	OrderID           string
-	Phone             string
-	Reference         string
-	Description       string
-	CreatedAt         string
-	UpdatedAt         string
+	Id                string        `json:"id"`
+	Amount            money.Money   `json:"amount"`
+	MerchantRequestID string        `json:"merchant_request_id"`
+	CheckoutRequestID string        `json:"checkout_request_id"`
+	Status            PaymentStatus `json:"status"`
+	OrderID           string        `json:"order_id"`
+	Phone             string        `json:"phone"`
+	Reference         string        `json:"reference"`
+	Description       string        `json:"description"`
+	CreatedAt         string        `json:"created_at"`
+	UpdatedAt         string        `json:"updated_at"`
+
+	// The result of the STK push, once the payment is settled. Payments
+	// settled by a transaction status query have no receipt, paid amount,
+	// payer or transaction time, as the query does not report them.
+	ResultCode         int         `json:"result_code"`
+	ResultDesc         string      `json:"result_desc"`
+	MpesaReceiptNumber string      `json:"mpesa_receipt_number"`
+	PaidAmount         money.Money `json:"paid_amount"`
+	PayerPhone         string      `json:"payer_phone"`
+	TransactionTime    string      `json:"transaction_time"`
+
+	// CallbackToken is the secret in the callback URL of the payment, which
+	// authenticates its M-Pesa callback. It is left out of payment events.
+	CallbackToken string `json:"-"`
+}
+
+// PaymentResult is the outcome of the STK push of a payment, from its M-Pesa
+// callback or a transaction status query.
+type PaymentResult struct {
+	Status             PaymentStatus
+	ResultCode         int
+	ResultDesc         string
+	MpesaReceiptNumber string
+	PaidAmount         money.Money
+	PayerPhone         string
+
+	// TransactionTime is in RFC 3339 format, like CreatedAt.
+	TransactionTime string
 }
 
 func (p *Payment) Validate() error {
+	if !money.IsSupported(p.Amount.Currency) {
+		return errors.New("amount currency is not supported")
+	}
+
+	if p.Amount.IsNegative() {
+		return errors.New("amount must not be negative")
+	}
+
 	return nil
 }
 
+// Settle records the result on the payment.
+func (p *Payment) Settle(result *PaymentResult) {
+	p.Status = result.Status
+	p.ResultCode = result.ResultCode
+	p.ResultDesc = result.ResultDesc
+	p.RecordReceipt(result)
+}
+
+// RecordReceipt records the receipt, paid amount, payer and transaction time
+// of the result on the payment.
+func (p *Payment) RecordReceipt(result *PaymentResult) {
+	p.MpesaReceiptNumber = result.MpesaReceiptNumber
+	p.PaidAmount = result.PaidAmount
+	p.PayerPhone = result.PayerPhone
+	p.TransactionTime = result.TransactionTime
+}
+
 type PaymentsRepository interface {
 	CreatePayment(ctx context.Context, payment *Payment) (string, error)
 	GetPaymentByID(ctx context.Context, paymentID string) (*Payment, error)
 	GetPaymentByMerchantRequestID(ctx context.Context, merchantRequestID string) (*Payment, error)
+	GetPaymentByReceiptNumber(ctx context.Context, receiptNumber string) (*Payment, error)
 	UpdatePaymentStatus(ctx context.Context, paymentID string, status PaymentStatus) error
+
+	// SetPaymentRequestIDs records the ids that M-Pesa gave the STK push of a
+	// payment. Payments are stored before their push is sent, so that a push
+	// is never live without a payment to settle.
+	SetPaymentRequestIDs(ctx context.Context, paymentID, merchantRequestID, checkoutRequestID string) error
+
+	// SettlePayment records the result of a pending payment and moves it to
+	// the status of the result. Payments that are no longer pending are left
+	// as they are, with a CONFLICT error.
+	SettlePayment(ctx context.Context, paymentID string, result *PaymentResult) error
+
+	// RecordPaymentReceipt records the receipt, paid amount, payer and
+	// transaction time of the result on a paid payment that was settled
+	// without them, e.g. by the reconciler. Other payments are left as they
+	// are, with a CONFLICT error.
+	RecordPaymentReceipt(ctx context.Context, paymentID string, result *PaymentResult) error
+
+	// ListPendingPayments returns up to limit pending payments created before
+	// createdBefore, oldest first. Payments without a CheckoutRequestID are
+	// left out, as their STK push cannot be queried.
+	ListPendingPayments(ctx context.Context, createdBefore time.Time, limit int) ([]*Payment, error)
 }
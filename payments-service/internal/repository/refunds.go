@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusCompleted RefundStatus = "completed"
+	RefundStatusFailed    RefundStatus = "failed"
+)
+
+// EventType returns the type of the event published when a refund moves to
+// status s, e.g. RefundCompleted for completed refunds.
+func (s RefundStatus) EventType() string {
+	name := string(s)
+	if name != "" {
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	return "Refund" + name
+}
+
+// Refund is a reversal of some or all of a paid payment. It is pending until
+// M-Pesa reports the result of the reversal.
+type Refund struct {
+	Id        string       `json:"id"`
+	PaymentID string       `json:"payment_id"`
+	OrderID   string       `json:"order_id"`
+	Amount    money.Money  `json:"amount"`
+	Status    RefundStatus `json:"status"`
+	Reason    string       `json:"reason"`
+	CreatedAt string       `json:"created_at"`
+	UpdatedAt string       `json:"updated_at"`
+
+	// The result of the reversal, once the refund is settled. Result codes
+	// are "0" for completed reversals and e.g. "R000001" otherwise.
+	ResultCode               string `json:"result_code"`
+	ResultDesc               string `json:"result_desc"`
+	TransactionID            string `json:"transaction_id"`
+	ConversationID           string `json:"conversation_id"`
+	OriginatorConversationID string `json:"originator_conversation_id"`
+
+	// CallbackToken is the secret in the result URLs of the reversal, which
+	// authenticates its M-Pesa result. It is left out of refund events.
+	CallbackToken string `json:"-"`
+}
+
+// RefundResult is the outcome of the reversal of a refund.
+type RefundResult struct {
+	Status                   RefundStatus
+	ResultCode               string
+	ResultDesc               string
+	TransactionID            string
+	ConversationID           string
+	OriginatorConversationID string
+}
+
+func (r *Refund) Validate() error {
+	if r.PaymentID == "" {
+		return errors.New("payment id is required")
+	}
+
+	if !money.IsSupported(r.Amount.Currency) {
+		return errors.New("amount currency is not supported")
+	}
+
+	if r.Amount.IsNegative() || r.Amount.IsZero() {
+		return errors.New("amount must be positive")
+	}
+
+	return nil
+}
+
+// Settle records the result on the refund.
+func (r *Refund) Settle(result *RefundResult) {
+	r.Status = result.Status
+	r.ResultCode = result.ResultCode
+	r.ResultDesc = result.ResultDesc
+	r.TransactionID = result.TransactionID
+	r.ConversationID = result.ConversationID
+	r.OriginatorConversationID = result.OriginatorConversationID
+}
+
+// CheckRefundLimit fails with CONFLICT if the refund and the refunds of its
+// payment that have not failed add up to more than limit.
+func CheckRefundLimit(refund *Refund, refunds []*Refund, limit money.Money) error {
+	left := limit
+	for _, other := range refunds {
+		if other.Status == RefundStatusFailed {
+			continue
+		}
+
+		var err error
+		if left, err = left.Sub(other.Amount); err != nil {
+			return service.Errorf(service.INTERNAL_ERROR, "failed to add up refunds: %v", err)
+		}
+	}
+
+	left, err := left.Sub(refund.Amount)
+	if err != nil {
+		return service.Errorf(service.INVALID_ERROR, "invalid refund amount: %v", err)
+	}
+
+	if left.IsNegative() {
+		return service.Errorf(service.CONFLICT_ERROR,
+			"refund of %s exceeds what is left to refund of the %s of payment %s", refund.Amount, limit, refund.PaymentID)
+	}
+
+	return nil
+}
+
+type RefundsRepository interface {
+	// CreateRefund stores a refund unless it and the refunds of its payment
+	// that have not failed add up to more than limit, e.g. the paid amount of
+	// the payment, which fails with CONFLICT. The check and the write are
+	// atomic, so concurrent refunds cannot exceed the limit together.
+	CreateRefund(ctx context.Context, refund *Refund, limit money.Money) (string, error)
+	GetRefundByID(ctx context.Context, refundID string) (*Refund, error)
+
+	// ListRefundsByPayment returns the refunds of a payment, oldest first.
+	ListRefundsByPayment(ctx context.Context, paymentID string) ([]*Refund, error)
+
+	// SettleRefund records the result of a pending refund and moves it to the
+	// status of the result. Refunds that are no longer pending are left as
+	// they are, with a CONFLICT error.
+	SettleRefund(ctx context.Context, refundID string, result *RefundResult) error
+}
@@ -0,0 +1,134 @@
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+// drainOutbox deletes every event in the outbox. The other suites leave
+// events behind, and the outbox is listed in order, so the events of a test
+// could otherwise be beyond the limit of a listing.
+func drainOutbox(t *testing.T, r repository.OutboxRepository) {
+	t.Helper()
+
+	for {
+		events, err := r.ListOutboxEvents(context.Background(), 100)
+		wantCode(t, "ListOutboxEvents()", err, "")
+		if len(events) == 0 {
+			return
+		}
+
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.Id
+		}
+
+		err = r.DeleteOutboxEvents(context.Background(), ids)
+		wantCode(t, "DeleteOutboxEvents()", err, "")
+	}
+}
+
+// paymentEvents lists the events of the payment in the outbox.
+func paymentEvents(t *testing.T, r repository.OutboxRepository, paymentID string) []*outbox.Event {
+	t.Helper()
+
+	events, err := r.ListOutboxEvents(context.Background(), 100)
+	wantCode(t, "ListOutboxEvents()", err, "")
+
+	var found []*outbox.Event
+	for _, event := range events {
+		if event.AggregateId == paymentID {
+			found = append(found, event)
+		}
+	}
+
+	return found
+}
+
+// TestOutboxRepository runs the outbox repository conformance suite against
+// the repositories returned by newRepositories. The payments repository must
+// record its events in the outbox repository.
+func TestOutboxRepository(t *testing.T,
+	newRepositories func(t *testing.T) (repository.PaymentsRepository, repository.OutboxRepository)) {
+
+	t.Run("PaymentEvents", func(t *testing.T) {
+		ctx := context.Background()
+		payments, r := newRepositories(t)
+		drainOutbox(t, r)
+
+		start := time.Now()
+		id, err := payments.CreatePayment(ctx, newTestPayment())
+		wantCode(t, "CreatePayment()", err, "")
+
+		err = payments.UpdatePaymentStatus(ctx, id, repository.PaymentStatusPaid)
+		wantCode(t, "UpdatePaymentStatus()", err, "")
+
+		// A failed update records no event.
+		err = payments.UpdatePaymentStatus(ctx, "does-not-exist", repository.PaymentStatusPaid)
+		wantCode(t, "UpdatePaymentStatus()", err, service.NOT_FOUND_ERROR)
+
+		events := paymentEvents(t, r, id)
+		if len(events) != 2 {
+			t.Fatalf("ListOutboxEvents() returned %d events of the payment, want 2", len(events))
+		}
+
+		wantTypes := []string{repository.EventPaymentCreated, "PaymentPaid"}
+		wantStatuses := []repository.PaymentStatus{repository.PaymentStatusPending, repository.PaymentStatusPaid}
+		for i, event := range events {
+			if event.Id == "" || event.Type != wantTypes[i] || event.AggregateType != repository.PaymentAggregate {
+				t.Errorf("ListOutboxEvents()[%d] = %+v, want a %s event of the payment", i, event, wantTypes[i])
+			}
+			wantTimestamp(t, "CreatedAt", event.CreatedAt, start)
+
+			var payload repository.PaymentEvent
+			if err := json.Unmarshal(event.Payload, &payload); err != nil {
+				t.Fatalf("failed to unmarshal %s payload: %v", event.Type, err)
+			}
+
+			if payload.Payment == nil || payload.Payment.Id != id || payload.Payment.Status != wantStatuses[i] ||
+				payload.Payment.OrderID != "order-1" {
+				t.Errorf("%s payload payment = %+v, want the payment in status %s", event.Type, payload.Payment,
+					wantStatuses[i])
+			}
+		}
+
+		if events[0].Id == events[1].Id {
+			t.Errorf("ListOutboxEvents() returned events with the same id %q", events[0].Id)
+		}
+
+		err = r.DeleteOutboxEvents(ctx, []string{events[0].Id, events[1].Id, "does-not-exist"})
+		wantCode(t, "DeleteOutboxEvents()", err, "")
+
+		if events := paymentEvents(t, r, id); len(events) != 0 {
+			t.Errorf("ListOutboxEvents() after deleting returned %d events of the payment, want none", len(events))
+		}
+	})
+
+	t.Run("ListOutboxEvents_Limit", func(t *testing.T) {
+		ctx := context.Background()
+		payments, r := newRepositories(t)
+		drainOutbox(t, r)
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := payments.CreatePayment(ctx, newTestPayment())
+			wantCode(t, "CreatePayment()", err, "")
+
+			ids = append(ids, id)
+		}
+
+		events, err := r.ListOutboxEvents(ctx, 2)
+		wantCode(t, "ListOutboxEvents()", err, "")
+
+		// Events are listed in the order they were recorded.
+		if len(events) != 2 || events[0].AggregateId != ids[0] || events[1].AggregateId != ids[1] {
+			t.Errorf("ListOutboxEvents() = %d events, want the events of the first 2 payments", len(events))
+		}
+	})
+}
@@ -0,0 +1,375 @@
+package repositorytest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func newTestPayment() *repository.Payment {
+	return &repository.Payment{
+		Amount:            money.New(100, "KES"),
+		MerchantRequestID: uniqueID("merchant-request"),
+		CheckoutRequestID: uniqueID("checkout-request"),
+		Status:            repository.PaymentStatusPending,
+		OrderID:           "order-1",
+		Phone:             "254700000000",
+		Reference:         "reference",
+		Description:       "description",
+		CallbackToken:     uniqueID("callback-token"),
+	}
+}
+
+// TestPaymentsRepository runs the payments repository conformance suite
+// against repositories returned by newRepository.
+func TestPaymentsRepository(t *testing.T, newRepository func(t *testing.T) repository.PaymentsRepository) {
+	t.Run("CreatePayment", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		p := newTestPayment()
+		id, err := r.CreatePayment(ctx, p)
+		wantCode(t, "CreatePayment()", err, "")
+
+		if id == "" {
+			t.Fatalf("CreatePayment() id is empty")
+		}
+		wantTimestamp(t, "CreatedAt", p.CreatedAt, start)
+		if p.UpdatedAt != p.CreatedAt {
+			t.Errorf("CreatePayment() UpdatedAt = %q, want %q", p.UpdatedAt, p.CreatedAt)
+		}
+
+		want := *p
+		want.Id = id
+
+		got, err := r.GetPaymentByID(ctx, id)
+		wantCode(t, "GetPaymentByID()", err, "")
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByID() = %+v, want %+v", got, &want)
+		}
+	})
+
+	t.Run("GetPaymentByID_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetPaymentByID(context.Background(), "does-not-exist")
+		wantCode(t, "GetPaymentByID()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetPaymentByID(context.Background(), "")
+		wantCode(t, "GetPaymentByID()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("GetPaymentByMerchantRequestID", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		_, err := r.CreatePayment(ctx, newTestPayment())
+		wantCode(t, "CreatePayment()", err, "")
+
+		p := newTestPayment()
+		id, err := r.CreatePayment(ctx, p)
+		wantCode(t, "CreatePayment()", err, "")
+
+		want := *p
+		want.Id = id
+
+		got, err := r.GetPaymentByMerchantRequestID(ctx, p.MerchantRequestID)
+		wantCode(t, "GetPaymentByMerchantRequestID()", err, "")
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByMerchantRequestID() = %+v, want %+v", got, &want)
+		}
+	})
+
+	t.Run("GetPaymentByMerchantRequestID_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetPaymentByMerchantRequestID(context.Background(), uniqueID("merchant-request"))
+		wantCode(t, "GetPaymentByMerchantRequestID()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetPaymentByMerchantRequestID(context.Background(), "")
+		wantCode(t, "GetPaymentByMerchantRequestID()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("UpdatePaymentStatus", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p := newTestPayment()
+		id, err := r.CreatePayment(ctx, p)
+		wantCode(t, "CreatePayment()", err, "")
+
+		start := time.Now()
+		err = r.UpdatePaymentStatus(ctx, id, repository.PaymentStatusPaid)
+		wantCode(t, "UpdatePaymentStatus()", err, "")
+
+		got, err := r.GetPaymentByID(ctx, id)
+		wantCode(t, "GetPaymentByID()", err, "")
+
+		want := *p
+		want.Id = id
+		want.Status = repository.PaymentStatusPaid
+		want.UpdatedAt = got.UpdatedAt
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByID() = %+v, want %+v", got, &want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+	})
+
+	t.Run("SetPaymentRequestIDs", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p := newTestPayment()
+		p.MerchantRequestID = ""
+		p.CheckoutRequestID = ""
+		id, err := r.CreatePayment(ctx, p)
+		wantCode(t, "CreatePayment()", err, "")
+
+		merchantRequestID := uniqueID("merchant-request")
+		checkoutRequestID := uniqueID("checkout-request")
+
+		start := time.Now()
+		err = r.SetPaymentRequestIDs(ctx, id, merchantRequestID, checkoutRequestID)
+		wantCode(t, "SetPaymentRequestIDs()", err, "")
+
+		got, err := r.GetPaymentByMerchantRequestID(ctx, merchantRequestID)
+		wantCode(t, "GetPaymentByMerchantRequestID()", err, "")
+
+		want := *p
+		want.Id = id
+		want.MerchantRequestID = merchantRequestID
+		want.CheckoutRequestID = checkoutRequestID
+		want.UpdatedAt = got.UpdatedAt
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByMerchantRequestID() = %+v, want %+v", got, &want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+	})
+
+	t.Run("SetPaymentRequestIDs_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		err := r.SetPaymentRequestIDs(context.Background(), "does-not-exist",
+			uniqueID("merchant-request"), uniqueID("checkout-request"))
+		wantCode(t, "SetPaymentRequestIDs()", err, service.NOT_FOUND_ERROR)
+
+		err = r.SetPaymentRequestIDs(context.Background(), "",
+			uniqueID("merchant-request"), uniqueID("checkout-request"))
+		wantCode(t, "SetPaymentRequestIDs()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("SettlePayment", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p := newTestPayment()
+		id, err := r.CreatePayment(ctx, p)
+		wantCode(t, "CreatePayment()", err, "")
+
+		result := &repository.PaymentResult{
+			Status:             repository.PaymentStatusPaid,
+			ResultDesc:         "The service request is processed successfully.",
+			MpesaReceiptNumber: uniqueID("receipt"),
+			PaidAmount:         money.New(100, "KES"),
+			PayerPhone:         "254700000000",
+			TransactionTime:    time.Now().Add(-time.Minute).Truncate(time.Second).Format(time.RFC3339),
+		}
+
+		start := time.Now()
+		err = r.SettlePayment(ctx, id, result)
+		wantCode(t, "SettlePayment()", err, "")
+
+		got, err := r.GetPaymentByID(ctx, id)
+		wantCode(t, "GetPaymentByID()", err, "")
+
+		want := *p
+		want.Id = id
+		want.Settle(result)
+		want.UpdatedAt = got.UpdatedAt
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByID() = %+v, want %+v", got, &want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		got, err = r.GetPaymentByReceiptNumber(ctx, result.MpesaReceiptNumber)
+		wantCode(t, "GetPaymentByReceiptNumber()", err, "")
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByReceiptNumber() = %+v, want %+v", got, &want)
+		}
+
+		// Settled payments keep their first result.
+		err = r.SettlePayment(ctx, id, &repository.PaymentResult{
+			Status:     repository.PaymentStatusFailed,
+			ResultCode: 1032,
+			ResultDesc: "Request cancelled by user",
+		})
+		wantCode(t, "SettlePayment()", err, service.CONFLICT_ERROR)
+
+		got, err = r.GetPaymentByID(ctx, id)
+		wantCode(t, "GetPaymentByID()", err, "")
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByID() after a conflicting SettlePayment() = %+v, want %+v", got, &want)
+		}
+	})
+
+	t.Run("RecordPaymentReceipt", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		p := newTestPayment()
+		id, err := r.CreatePayment(ctx, p)
+		wantCode(t, "CreatePayment()", err, "")
+
+		receipt := &repository.PaymentResult{
+			Status:             repository.PaymentStatusPaid,
+			MpesaReceiptNumber: uniqueID("receipt"),
+			PaidAmount:         money.New(100, "KES"),
+			PayerPhone:         "254700000000",
+			TransactionTime:    time.Now().Add(-time.Minute).Truncate(time.Second).Format(time.RFC3339),
+		}
+
+		// Pending payments settle with their receipt instead.
+		err = r.RecordPaymentReceipt(ctx, id, receipt)
+		wantCode(t, "RecordPaymentReceipt()", err, service.CONFLICT_ERROR)
+
+		// Payments settled by a status query have no receipt.
+		result := &repository.PaymentResult{
+			Status:     repository.PaymentStatusPaid,
+			ResultDesc: "The service request is processed successfully.",
+		}
+		err = r.SettlePayment(ctx, id, result)
+		wantCode(t, "SettlePayment()", err, "")
+
+		start := time.Now()
+		err = r.RecordPaymentReceipt(ctx, id, receipt)
+		wantCode(t, "RecordPaymentReceipt()", err, "")
+
+		got, err := r.GetPaymentByReceiptNumber(ctx, receipt.MpesaReceiptNumber)
+		wantCode(t, "GetPaymentByReceiptNumber()", err, "")
+
+		want := *p
+		want.Id = id
+		want.Settle(result)
+		want.RecordReceipt(receipt)
+		want.UpdatedAt = got.UpdatedAt
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetPaymentByReceiptNumber() = %+v, want %+v", got, &want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		// Payments keep their first receipt.
+		another := *receipt
+		another.MpesaReceiptNumber = uniqueID("receipt")
+		err = r.RecordPaymentReceipt(ctx, id, &another)
+		wantCode(t, "RecordPaymentReceipt()", err, service.CONFLICT_ERROR)
+
+		err = r.RecordPaymentReceipt(ctx, "does-not-exist", receipt)
+		wantCode(t, "RecordPaymentReceipt()", err, service.NOT_FOUND_ERROR)
+
+		err = r.RecordPaymentReceipt(ctx, "", receipt)
+		wantCode(t, "RecordPaymentReceipt()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("SettlePayment_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		result := &repository.PaymentResult{Status: repository.PaymentStatusFailed, ResultCode: 1032}
+
+		err := r.SettlePayment(context.Background(), "does-not-exist", result)
+		wantCode(t, "SettlePayment()", err, service.NOT_FOUND_ERROR)
+
+		err = r.SettlePayment(context.Background(), "", result)
+		wantCode(t, "SettlePayment()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("GetPaymentByReceiptNumber_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		// Pending payments have no receipt number.
+		_, err := r.CreatePayment(context.Background(), newTestPayment())
+		wantCode(t, "CreatePayment()", err, "")
+
+		_, err = r.GetPaymentByReceiptNumber(context.Background(), uniqueID("receipt"))
+		wantCode(t, "GetPaymentByReceiptNumber()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetPaymentByReceiptNumber(context.Background(), "")
+		wantCode(t, "GetPaymentByReceiptNumber()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListPendingPayments", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		var ids []string
+		for i := 0; i < 3; i++ {
+			id, err := r.CreatePayment(ctx, newTestPayment())
+			wantCode(t, "CreatePayment()", err, "")
+			ids = append(ids, id)
+		}
+
+		err := r.UpdatePaymentStatus(ctx, ids[1], repository.PaymentStatusPaid)
+		wantCode(t, "UpdatePaymentStatus()", err, "")
+
+		unqueryable := newTestPayment()
+		unqueryable.CheckoutRequestID = ""
+		unqueryableID, err := r.CreatePayment(ctx, unqueryable)
+		wantCode(t, "CreatePayment()", err, "")
+
+		// Timestamps are stored with second precision.
+		got, err := r.ListPendingPayments(ctx, time.Now().Add(time.Second), 1000)
+		wantCode(t, "ListPendingPayments()", err, "")
+
+		listed := make(map[string]*repository.Payment)
+		for i, p := range got {
+			listed[p.Id] = p
+
+			if p.Status != repository.PaymentStatusPending {
+				t.Errorf("ListPendingPayments() returned payment %s with status %q", p.Id, p.Status)
+			}
+			if i > 0 && p.CreatedAt < got[i-1].CreatedAt {
+				t.Errorf("ListPendingPayments() is not ordered oldest first")
+			}
+		}
+
+		for _, id := range []string{ids[0], ids[2]} {
+			if listed[id] == nil {
+				t.Errorf("ListPendingPayments() did not return pending payment %s", id)
+			}
+		}
+		for _, id := range []string{ids[1], unqueryableID} {
+			if listed[id] != nil {
+				t.Errorf("ListPendingPayments() returned payment %s", id)
+			}
+		}
+
+		got, err = r.ListPendingPayments(ctx, time.Now().Add(-time.Hour), 1000)
+		wantCode(t, "ListPendingPayments()", err, "")
+		for _, p := range got {
+			if p.Id == ids[0] || p.Id == ids[2] {
+				t.Errorf("ListPendingPayments() returned payment %s created after createdBefore", p.Id)
+			}
+		}
+
+		got, err = r.ListPendingPayments(ctx, time.Now().Add(time.Second), 1)
+		wantCode(t, "ListPendingPayments()", err, "")
+		if len(got) != 1 {
+			t.Errorf("ListPendingPayments() returned %d payments, want 1", len(got))
+		}
+	})
+
+	t.Run("UpdatePaymentStatus_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		err := r.UpdatePaymentStatus(context.Background(), "does-not-exist", repository.PaymentStatusPaid)
+		wantCode(t, "UpdatePaymentStatus()", err, service.NOT_FOUND_ERROR)
+
+		err = r.UpdatePaymentStatus(context.Background(), "", repository.PaymentStatusPaid)
+		wantCode(t, "UpdatePaymentStatus()", err, service.INVALID_ERROR)
+	})
+}
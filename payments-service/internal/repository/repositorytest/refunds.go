@@ -0,0 +1,268 @@
+package repositorytest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/repository"
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+// testRefundLimit is more than the refunds of a test add up to.
+var testRefundLimit = money.New(100000, "KES")
+
+func newTestRefund(paymentID string) *repository.Refund {
+	return &repository.Refund{
+		PaymentID:     paymentID,
+		OrderID:       "order-1",
+		Amount:        money.New(5000, "KES"),
+		Status:        repository.RefundStatusPending,
+		Reason:        "reason",
+		CallbackToken: uniqueID("callback-token"),
+	}
+}
+
+// TestRefundsRepository runs the refunds repository conformance suite
+// against repositories returned by newRepository.
+func TestRefundsRepository(t *testing.T, newRepository func(t *testing.T) repository.RefundsRepository) {
+	t.Run("CreateRefund", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		start := time.Now()
+		refund := newTestRefund(uniqueID("payment"))
+		id, err := r.CreateRefund(ctx, refund, testRefundLimit)
+		wantCode(t, "CreateRefund()", err, "")
+
+		if id == "" {
+			t.Fatalf("CreateRefund() id is empty")
+		}
+		wantTimestamp(t, "CreatedAt", refund.CreatedAt, start)
+		if refund.UpdatedAt != refund.CreatedAt {
+			t.Errorf("CreateRefund() UpdatedAt = %q, want %q", refund.UpdatedAt, refund.CreatedAt)
+		}
+
+		want := *refund
+		want.Id = id
+
+		got, err := r.GetRefundByID(ctx, id)
+		wantCode(t, "GetRefundByID()", err, "")
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetRefundByID() = %+v, want %+v", got, &want)
+		}
+	})
+
+	t.Run("CreateRefund_Invalid", func(t *testing.T) {
+		r := newRepository(t)
+
+		refund := newTestRefund(uniqueID("payment"))
+		refund.Amount = money.Zero("KES")
+		_, err := r.CreateRefund(context.Background(), refund, testRefundLimit)
+		wantCode(t, "CreateRefund()", err, service.INVALID_ERROR)
+
+		_, err = r.CreateRefund(context.Background(), newTestRefund(""), testRefundLimit)
+		wantCode(t, "CreateRefund()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("CreateRefund_Limit", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		paymentID := uniqueID("payment")
+		limit := money.New(12000, "KES")
+
+		failed := newTestRefund(paymentID)
+		id, err := r.CreateRefund(ctx, failed, limit)
+		wantCode(t, "CreateRefund()", err, "")
+
+		err = r.SettleRefund(ctx, id, &repository.RefundResult{Status: repository.RefundStatusFailed})
+		wantCode(t, "SettleRefund()", err, "")
+
+		// Failed refunds do not count towards the limit.
+		for i := 0; i < 2; i++ {
+			_, err = r.CreateRefund(ctx, newTestRefund(paymentID), limit)
+			wantCode(t, "CreateRefund()", err, "")
+		}
+		_, err = r.CreateRefund(ctx, newTestRefund(paymentID), limit)
+		wantCode(t, "CreateRefund()", err, service.CONFLICT_ERROR)
+
+		refund := newTestRefund(paymentID)
+		refund.Amount = money.New(2000, "KES")
+		_, err = r.CreateRefund(ctx, refund, limit)
+		wantCode(t, "CreateRefund()", err, "")
+
+		refunds, err := r.ListRefundsByPayment(ctx, paymentID)
+		wantCode(t, "ListRefundsByPayment()", err, "")
+		if len(refunds) != 4 {
+			t.Errorf("ListRefundsByPayment() returned %d refunds, want 4", len(refunds))
+		}
+	})
+
+	t.Run("CreateRefund_Concurrent", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		// Only two of the refunds fit in the limit, however they interleave.
+		paymentID := uniqueID("payment")
+		limit := money.New(10000, "KES")
+
+		const n = 5
+		errs := make(chan error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				_, err := r.CreateRefund(ctx, newTestRefund(paymentID), limit)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			switch service.ErrorCode(err) {
+			case "":
+				created++
+			case service.CONFLICT_ERROR:
+			default:
+				t.Errorf("CreateRefund() error = %v, want nil or code %q", err, service.CONFLICT_ERROR)
+			}
+		}
+		if created != 2 {
+			t.Errorf("CreateRefund() created %d of %d concurrent refunds, want 2", created, n)
+		}
+
+		refunds, err := r.ListRefundsByPayment(ctx, paymentID)
+		wantCode(t, "ListRefundsByPayment()", err, "")
+		if len(refunds) != 2 {
+			t.Errorf("ListRefundsByPayment() returned %d refunds, want 2", len(refunds))
+		}
+	})
+
+	t.Run("GetRefundByID_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		_, err := r.GetRefundByID(context.Background(), "does-not-exist")
+		wantCode(t, "GetRefundByID()", err, service.NOT_FOUND_ERROR)
+
+		_, err = r.GetRefundByID(context.Background(), "")
+		wantCode(t, "GetRefundByID()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("ListRefundsByPayment", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		paymentID := uniqueID("payment")
+
+		var want []string
+		for i := 0; i < 3; i++ {
+			id, err := r.CreateRefund(ctx, newTestRefund(paymentID), testRefundLimit)
+			wantCode(t, "CreateRefund()", err, "")
+			want = append(want, id)
+		}
+
+		_, err := r.CreateRefund(ctx, newTestRefund(uniqueID("payment")), testRefundLimit)
+		wantCode(t, "CreateRefund()", err, "")
+
+		got, err := r.ListRefundsByPayment(ctx, paymentID)
+		wantCode(t, "ListRefundsByPayment()", err, "")
+
+		listed := make(map[string]bool)
+		for i, refund := range got {
+			listed[refund.Id] = true
+
+			if refund.PaymentID != paymentID {
+				t.Errorf("ListRefundsByPayment() returned refund %s of payment %s", refund.Id, refund.PaymentID)
+			}
+			if i > 0 && refund.CreatedAt < got[i-1].CreatedAt {
+				t.Errorf("ListRefundsByPayment() is not ordered oldest first")
+			}
+		}
+
+		if len(got) != len(want) {
+			t.Errorf("ListRefundsByPayment() returned %d refunds, want %d", len(got), len(want))
+		}
+		for _, id := range want {
+			if !listed[id] {
+				t.Errorf("ListRefundsByPayment() did not return refund %s", id)
+			}
+		}
+
+		got, err = r.ListRefundsByPayment(ctx, uniqueID("payment"))
+		wantCode(t, "ListRefundsByPayment()", err, "")
+		if len(got) != 0 {
+			t.Errorf("ListRefundsByPayment() of a payment without refunds = %v, want none", got)
+		}
+
+		_, err = r.ListRefundsByPayment(ctx, "")
+		wantCode(t, "ListRefundsByPayment()", err, service.INVALID_ERROR)
+	})
+
+	t.Run("SettleRefund", func(t *testing.T) {
+		ctx := context.Background()
+		r := newRepository(t)
+
+		refund := newTestRefund(uniqueID("payment"))
+		id, err := r.CreateRefund(ctx, refund, testRefundLimit)
+		wantCode(t, "CreateRefund()", err, "")
+
+		result := &repository.RefundResult{
+			Status:                   repository.RefundStatusCompleted,
+			ResultCode:               "0",
+			ResultDesc:               "The service request is processed successfully.",
+			TransactionID:            uniqueID("transaction"),
+			ConversationID:           uniqueID("conversation"),
+			OriginatorConversationID: uniqueID("originator-conversation"),
+		}
+
+		start := time.Now()
+		err = r.SettleRefund(ctx, id, result)
+		wantCode(t, "SettleRefund()", err, "")
+
+		got, err := r.GetRefundByID(ctx, id)
+		wantCode(t, "GetRefundByID()", err, "")
+
+		want := *refund
+		want.Id = id
+		want.Settle(result)
+		want.UpdatedAt = got.UpdatedAt
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetRefundByID() = %+v, want %+v", got, &want)
+		}
+		wantTimestamp(t, "UpdatedAt", got.UpdatedAt, start)
+
+		// Settled refunds keep their first result.
+		err = r.SettleRefund(ctx, id, &repository.RefundResult{
+			Status:     repository.RefundStatusFailed,
+			ResultCode: "R000001",
+			ResultDesc: "The transaction has already been reversed.",
+		})
+		wantCode(t, "SettleRefund()", err, service.CONFLICT_ERROR)
+
+		got, err = r.GetRefundByID(ctx, id)
+		wantCode(t, "GetRefundByID()", err, "")
+		if !reflect.DeepEqual(got, &want) {
+			t.Errorf("GetRefundByID() after a conflicting SettleRefund() = %+v, want %+v", got, &want)
+		}
+	})
+
+	t.Run("SettleRefund_NotFound", func(t *testing.T) {
+		r := newRepository(t)
+
+		result := &repository.RefundResult{Status: repository.RefundStatusFailed, ResultCode: "R000002"}
+
+		err := r.SettleRefund(context.Background(), "does-not-exist", result)
+		wantCode(t, "SettleRefund()", err, service.NOT_FOUND_ERROR)
+
+		err = r.SettleRefund(context.Background(), "", result)
+		wantCode(t, "SettleRefund()", err, service.INVALID_ERROR)
+	})
+}
@@ -0,0 +1,54 @@
+// Package repositorytest provides a conformance suite for the repository
+// interfaces. Every storage backend runs the suite from its own tests so that
+// they can be swapped for one another without changing behaviour.
+//
+// The suites only assume that the records they create themselves exist, so
+// they can run against shared databases such as the Firestore emulator.
+package repositorytest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/payments/internal/service"
+)
+
+// wantCode fails the test unless err carries the given application error code.
+// An empty code asserts that err is nil.
+func wantCode(t *testing.T, op string, err error, code string) {
+	t.Helper()
+
+	if got := service.ErrorCode(err); got != code {
+		t.Fatalf("%s error = %v, want code %q", op, err, code)
+	}
+}
+
+// wantTimestamp fails the test unless ts is an RFC3339 timestamp no earlier
+// than since and not in the future.
+func wantTimestamp(t *testing.T, field string, ts string, since time.Time) {
+	t.Helper()
+
+	got, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		t.Fatalf("%s = %q, want an RFC3339 timestamp: %v", field, ts, err)
+	}
+
+	// Timestamps are stored with second precision.
+	since = since.Truncate(time.Second)
+	if got.Before(since) || got.After(time.Now().Add(time.Second)) {
+		t.Errorf("%s = %v, want between %v and now", field, got, since)
+	}
+}
+
+// uniqueID returns a random identifier, so that lookups by external ids do not
+// collide with records left behind by other tests.
+func uniqueID(prefix string) string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return prefix + "-" + hex.EncodeToString(b)
+}
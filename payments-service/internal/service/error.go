@@ -8,6 +8,7 @@ import (
 // Application error codes.
 const (
 	ALREADY_EXISTS_ERROR  = "already_exists"
+	CONFLICT_ERROR        = "conflict"
 	INTERNAL_ERROR        = "internal"
 	INVALID_ERROR         = "invalid"
 	NOT_FOUND_ERROR       = "not_found"
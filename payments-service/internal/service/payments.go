@@ -3,19 +3,20 @@ package service
 import (
 	"context"
 
+	"github.com/Mik3y-F/order-management-system/pkg/money"
 	"github.com/jwambugu/mpesa-golang-sdk"
 )
 
 type Payment struct {
-	Id          string `json:"id"`
-	OrderId     string `json:"orderId"`
-	PhoneNumber uint   `json:"phoneNumber"`
-	Amount      uint   `json:"amount"`
-	Reference   string `json:"reference"`
-	Description string `json:"description"`
-	CallbackURL string `json:"callbackUrl"`
-	CreatedAt   string `json:"createdAt"`
-	UpdatedAt   string `json:"updatedAt"`
+	Id          string      `json:"id"`
+	OrderId     string      `json:"orderId"`
+	PhoneNumber uint        `json:"phoneNumber"`
+	Amount      money.Money `json:"amount"`
+	Reference   string      `json:"reference"`
+	Description string      `json:"description"`
+	CallbackURL string      `json:"callbackUrl"`
+	CreatedAt   string      `json:"createdAt"`
+	UpdatedAt   string      `json:"updatedAt"`
 }
 
 type PaymentResponse struct {
@@ -29,7 +30,57 @@ type PaymentResponse struct {
 // I know I know, this is a bit of a hack but it works for now
 type PaymentCallback = mpesa.STKCallback
 
+// Refund asks for some or all of a paid payment back.
+type Refund struct {
+	PaymentId string `json:"paymentId"`
+	// Amount is refunded, or everything not refunded yet if it is zero.
+	Amount money.Money `json:"amount"`
+	Reason string      `json:"reason"`
+	// ResultURL is where M-Pesa sends the result of the reversal, followed
+	// by path segments with the refund id and a new callback token.
+	ResultURL string `json:"resultUrl"`
+}
+
+type RefundResponse struct {
+	RefundId                 string      `json:"refundId"`
+	Amount                   money.Money `json:"amount"`
+	ConversationID           string      `json:"ConversationID"`
+	OriginatorConversationID string      `json:"OriginatorConversationID"`
+	ResponseDescription      string      `json:"ResponseDescription"`
+}
+
+// ReversalResult is the result of an M-Pesa reversal, POSTed to its result
+// URL.
+type ReversalResult struct {
+	ResultType int `json:"ResultType"`
+	// ResultCode is the number 0 for completed reversals, and a string such
+	// as "R000001" otherwise.
+	ResultCode               interface{} `json:"ResultCode"`
+	ResultDesc               string      `json:"ResultDesc"`
+	OriginatorConversationID string      `json:"OriginatorConversationID"`
+	ConversationID           string      `json:"ConversationID"`
+	TransactionID            string      `json:"TransactionID"`
+}
+
 type PaymentsService interface {
+	// ProcessPayment sends an STK push whose callback is the CallbackURL of
+	// the payment followed by a path segment with a new callback token.
 	ProcessPayment(ctx context.Context, payment *Payment) (*PaymentResponse, error)
-	HandleMpesaCallback(ctx context.Context, callback *PaymentCallback) error
+
+	// HandleMpesaCallback acts on the result of a payment request, once the
+	// callback is authenticated by the token in its URL and matches the
+	// stored payment.
+	HandleMpesaCallback(ctx context.Context, token string, callback *PaymentCallback) error
+
+	// RefundPayment requests the reversal of some or all of a paid payment.
+	// The refund is pending until the result of the reversal is handled.
+	RefundPayment(ctx context.Context, refund *Refund) (*RefundResponse, error)
+
+	// HandleReversalResult settles a refund with the result of its reversal,
+	// once the result is authenticated by the token in its URL.
+	HandleReversalResult(ctx context.Context, refundID string, token string, result *ReversalResult) error
+
+	// HandleReversalTimeout fails a refund whose reversal timed out in the
+	// M-Pesa queue, once authenticated by the token in its URL.
+	HandleReversalTimeout(ctx context.Context, refundID string, token string) error
 }
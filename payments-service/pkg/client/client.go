@@ -14,6 +14,8 @@ var _ PaymentsClient = (*GrpcPaymentsClient)(nil)
 type PaymentsClient interface {
 	HealthCheck(ctx context.Context, req *HealthCheckRequest) (*HealthCheckResponse, error)
 	ProcessMpesaPayment(ctx context.Context, req *ProcessMpesaPaymentRequest) (*ProcessMpesaPaymentResponse, error)
+	GetPayment(ctx context.Context, req *GetPaymentRequest) (*GetPaymentResponse, error)
+	RefundPayment(ctx context.Context, req *RefundPaymentRequest) (*RefundPaymentResponse, error)
 }
 
 type GrpcPaymentsClient struct {
@@ -4,8 +4,29 @@ import (
 	pb "github.com/Mik3y-F/order-management-system/payments/api/generated"
 )
 
+type Money = pb.Money
+type Payment = pb.Payment
+type PaymentStatus = pb.PaymentStatus
+
+var PaymentStatusPending = pb.PaymentStatus_PAYMENT_STATUS_PENDING
+var PaymentStatusPaid = pb.PaymentStatus_PAYMENT_STATUS_PAID
+var PaymentStatusFailed = pb.PaymentStatus_PAYMENT_STATUS_FAILED
+
+type Refund = pb.Refund
+type RefundStatus = pb.RefundStatus
+
+var RefundStatusPending = pb.RefundStatus_REFUND_STATUS_PENDING
+var RefundStatusCompleted = pb.RefundStatus_REFUND_STATUS_COMPLETED
+var RefundStatusFailed = pb.RefundStatus_REFUND_STATUS_FAILED
+
 type HealthCheckRequest = pb.HealthCheckRequest
 type HealthCheckResponse = pb.HealthCheckResponse
 
 type ProcessMpesaPaymentRequest = pb.MpesaPaymentRequest
 type ProcessMpesaPaymentResponse = pb.MpesaPaymentResponse
+
+type GetPaymentRequest = pb.GetPaymentRequest
+type GetPaymentResponse = pb.GetPaymentResponse
+
+type RefundPaymentRequest = pb.RefundPaymentRequest
+type RefundPaymentResponse = pb.RefundPaymentResponse
@@ -5,3 +5,11 @@ import "context"
 func (c *GrpcPaymentsClient) ProcessMpesaPayment(ctx context.Context, req *ProcessMpesaPaymentRequest) (*ProcessMpesaPaymentResponse, error) {
 	return c.client.ProcessMpesaPayment(ctx, req)
 }
+
+func (c *GrpcPaymentsClient) GetPayment(ctx context.Context, req *GetPaymentRequest) (*GetPaymentResponse, error) {
+	return c.client.GetPayment(ctx, req)
+}
+
+func (c *GrpcPaymentsClient) RefundPayment(ctx context.Context, req *RefundPaymentRequest) (*RefundPaymentResponse, error) {
+	return c.client.RefundPayment(ctx, req)
+}
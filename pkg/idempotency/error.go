@@ -0,0 +1,48 @@
+package idempotency
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes. They have the values of the matching application error codes
+// of the services, so that services can return them as their own.
+const (
+	CONFLICT_ERROR  = "conflict"
+	INTERNAL_ERROR  = "internal"
+	INVALID_ERROR   = "invalid"
+	NOT_FOUND_ERROR = "not_found"
+)
+
+// Error is an error of a request or a store, with one of the error codes.
+type Error struct {
+	// Machine-readable error code.
+	Code string
+
+	// Human-readable error message.
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("error: code=%s message=%s", e.Code, e.Message)
+}
+
+// ErrorCode unwraps an Error and returns its code. Other errors always return
+// INTERNAL_ERROR.
+func ErrorCode(err error) string {
+	var e *Error
+	if err == nil {
+		return ""
+	} else if errors.As(err, &e) {
+		return e.Code
+	}
+	return INTERNAL_ERROR
+}
+
+// Errorf returns an Error with a given code and formatted message.
+func Errorf(code string, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
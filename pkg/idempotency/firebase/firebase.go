@@ -0,0 +1,183 @@
+// Package firebase keeps idempotency records in a Firestore collection. Each
+// record is stored with the method and escaped key as its document id. The
+// expiry is a timestamp, so that a Firestore TTL policy on it can delete
+// expired records.
+package firebase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ idempotency.Store = (*Store)(nil)
+
+type Store struct {
+	client     *firestore.Client
+	collection string
+
+	// CreatedAtField and ExpiresAtField name the timestamp fields of the
+	// documents, so that they follow the naming of the other collections of
+	// the service. They default to created_at and expires_at.
+	CreatedAtField string
+	ExpiresAtField string
+}
+
+func NewStore(client *firestore.Client, collection string) *Store {
+	return &Store{
+		client:         client,
+		collection:     collection,
+		CreatedAtField: "created_at",
+		ExpiresAtField: "expires_at",
+	}
+}
+
+func (s *Store) CheckPreconditions() {
+	if s.client == nil {
+		panic("no Firestore client provided")
+	}
+
+	if s.collection == "" {
+		panic("no collection provided")
+	}
+}
+
+func (s *Store) recordDoc(method string, key string) *firestore.DocumentRef {
+	s.CheckPreconditions()
+
+	// Keys are chosen by clients, so they are escaped to be valid document ids.
+	return s.client.Collection(s.collection).Doc(method + ":" + url.PathEscape(key))
+}
+
+// ReserveIdempotencyKey replaces expired records itself, as Firestore TTL
+// policies only delete expired documents eventually.
+func (s *Store) ReserveIdempotencyKey(
+	ctx context.Context, record *idempotency.Record) (*idempotency.Record, error) {
+
+	s.CheckPreconditions()
+
+	timeNow := time.Now()
+	record.CreatedAt = timeNow.Format(time.RFC3339)
+	record.Response = nil
+
+	if err := record.Validate(); err != nil {
+		return nil, err
+	}
+
+	docRef := s.recordDoc(record.Method, record.Key)
+
+	var stored *idempotency.Record
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		stored = nil
+
+		doc, err := tx.Get(docRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return idempotency.Errorf(idempotency.INTERNAL_ERROR, "failed to get idempotency key: %v", err)
+		}
+
+		if err == nil {
+			existing, err := s.unmarshallRecordDoc(doc)
+			if err != nil {
+				return err
+			}
+
+			if !existing.Expired(timeNow) {
+				stored = existing
+				return nil
+			}
+		}
+
+		return tx.Set(docRef, s.marshallRecord(record))
+	})
+
+	var idempotencyErr *idempotency.Error
+	if errors.As(err, &idempotencyErr) {
+		return nil, err
+	} else if err != nil {
+		return nil, idempotency.Errorf(idempotency.INTERNAL_ERROR, "failed to reserve idempotency key: %v", err)
+	}
+
+	return stored, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(
+	ctx context.Context, method string, key string, response []byte, expiresAt string) error {
+
+	s.CheckPreconditions()
+
+	if len(response) == 0 {
+		return idempotency.Errorf(idempotency.INVALID_ERROR, "response is required")
+	}
+
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return idempotency.Errorf(idempotency.INVALID_ERROR, "expires_at must be an RFC3339 timestamp")
+	}
+
+	_, err = s.recordDoc(method, key).Update(ctx, []firestore.Update{
+		{Path: "response", Value: response},
+		{Path: s.ExpiresAtField, Value: t},
+	})
+	if status.Code(err) == codes.NotFound {
+		return idempotency.Errorf(idempotency.NOT_FOUND_ERROR, "idempotency key not found")
+	} else if err != nil {
+		return idempotency.Errorf(idempotency.INTERNAL_ERROR, "failed to update idempotency key: %v", err)
+	}
+
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, method string, key string) error {
+	s.CheckPreconditions()
+
+	if _, err := s.recordDoc(method, key).Delete(ctx); err != nil {
+		return idempotency.Errorf(idempotency.INTERNAL_ERROR, "failed to delete idempotency key: %v", err)
+	}
+
+	return nil
+}
+
+// marshallRecord returns the fields of the document of a validated record.
+func (s *Store) marshallRecord(record *idempotency.Record) map[string]interface{} {
+	expiresAt, _ := time.Parse(time.RFC3339, record.ExpiresAt)
+
+	return map[string]interface{}{
+		"method":         record.Method,
+		"key":            record.Key,
+		"fingerprint":    record.Fingerprint,
+		"response":       record.Response,
+		s.CreatedAtField: record.CreatedAt,
+		s.ExpiresAtField: expiresAt,
+	}
+}
+
+func (s *Store) unmarshallRecordDoc(doc *firestore.DocumentSnapshot) (*idempotency.Record, error) {
+	data := doc.Data()
+
+	method, _ := data["method"].(string)
+	key, _ := data["key"].(string)
+	fingerprint, _ := data["fingerprint"].(string)
+	response, _ := data["response"].([]byte)
+	createdAt, _ := data[s.CreatedAtField].(string)
+
+	expiresAt, ok := data[s.ExpiresAtField].(time.Time)
+	if !ok {
+		return nil, idempotency.Errorf(idempotency.INTERNAL_ERROR,
+			"failed to unmarshall idempotency key: %s is not a timestamp", s.ExpiresAtField)
+	}
+
+	return &idempotency.Record{
+		Method:      method,
+		Key:         key,
+		Fingerprint: fingerprint,
+		Response:    response,
+		CreatedAt:   createdAt,
+		ExpiresAt:   expiresAt.Local().Format(time.RFC3339),
+	}, nil
+}
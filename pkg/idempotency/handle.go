@@ -0,0 +1,169 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// DefaultTTL is how long the response to a request with an idempotency
+	// key is replayed to its retries.
+	DefaultTTL = 24 * time.Hour
+
+	// lease is how long a key is held by a request that has not finished,
+	// e.g. because the server stopped while handling it.
+	lease = 5 * time.Minute
+
+	// keyField is the JSON name of the idempotency key field of requests,
+	// whatever the field is called in the proto file.
+	keyField = "idempotencyKey"
+)
+
+// Request is a request with an optional idempotency key field.
+type Request interface {
+	proto.Message
+	GetIdempotencyKey() string
+}
+
+// Handler records the requests that carry an idempotency key in Store for
+// TTL, or DefaultTTL if it is zero. Requests are handled as they come if
+// Store is nil.
+type Handler struct {
+	Store Store
+	TTL   time.Duration
+}
+
+func (h Handler) ttl() time.Duration {
+	if h.TTL > 0 {
+		return h.TTL
+	}
+
+	return DefaultTTL
+}
+
+// Handle calls handle once per idempotency key of method and replays the
+// response to retries of the request. A key used with a different request is
+// rejected with INVALID_ERROR, and one held by a request that is in progress
+// with CONFLICT_ERROR. Failed requests release their key so that they can be
+// retried; their errors are returned as they are.
+func Handle[Req Request, Resp proto.Message](
+	ctx context.Context, h Handler, method string, in Req,
+	handle func(context.Context, Req) (Resp, error)) (Resp, error) {
+
+	var zero Resp
+
+	key := in.GetIdempotencyKey()
+	if key == "" || h.Store == nil {
+		return handle(ctx, in)
+	}
+
+	fingerprint, err := requestFingerprint(in)
+	if err != nil {
+		return zero, err
+	}
+
+	stored, err := h.Store.ReserveIdempotencyKey(ctx, &Record{
+		Method:      method,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(lease).Format(time.RFC3339),
+	})
+	if err != nil {
+		return zero, err
+	} else if stored != nil {
+		return replayResponse[Resp](stored, fingerprint)
+	}
+
+	out, err := handle(ctx, in)
+
+	// The outcome is recorded even if the caller has gone away, as it is what
+	// its retries must see.
+	ctx = context.WithoutCancel(ctx)
+
+	if err != nil {
+		if releaseErr := h.Store.ReleaseIdempotencyKey(ctx, method, key); releaseErr != nil {
+			log.Printf("[idempotency] failed to release key %q of %s: %v", key, method, releaseErr)
+		}
+
+		return zero, err
+	}
+
+	// The response is wrapped so that it is never empty and is checked to be
+	// of the right type when replayed.
+	response, err := anypb.New(out)
+	if err == nil {
+		var b []byte
+		if b, err = proto.Marshal(response); err == nil {
+			expiresAt := time.Now().Add(h.ttl()).Format(time.RFC3339)
+			err = h.Store.CompleteIdempotencyKey(ctx, method, key, b, expiresAt)
+		}
+	}
+	if err != nil {
+		// The request is done, so its response is returned anyway; retries
+		// are rejected until the lease of the key runs out.
+		log.Printf("[idempotency] failed to complete key %q of %s: %v", key, method, err)
+	}
+
+	return out, nil
+}
+
+// replayResponse returns the stored response to a request with the same
+// idempotency key as the request with the given fingerprint.
+func replayResponse[Resp proto.Message](stored *Record, fingerprint string) (Resp, error) {
+	var zero Resp
+
+	if stored.Fingerprint != fingerprint {
+		return zero, Errorf(INVALID_ERROR, "idempotency key %q was used with a different request", stored.Key)
+	}
+
+	if !stored.Completed() {
+		return zero, Errorf(CONFLICT_ERROR, "a request with idempotency key %q is in progress", stored.Key)
+	}
+
+	var response anypb.Any
+	if err := proto.Unmarshal(stored.Response, &response); err != nil {
+		return zero, Errorf(INTERNAL_ERROR, "failed to unmarshall stored response: %v", err)
+	}
+
+	m, err := response.UnmarshalNew()
+	if err != nil {
+		return zero, Errorf(INTERNAL_ERROR, "failed to unmarshall stored response: %v", err)
+	}
+
+	out, ok := m.(Resp)
+	if !ok {
+		return zero, Errorf(INTERNAL_ERROR, "stored response is a %s", response.GetTypeUrl())
+	}
+
+	return out, nil
+}
+
+// requestFingerprint hashes the request without its idempotency key.
+func requestFingerprint(in Request) (string, error) {
+	if err := ValidateKey(in.GetIdempotencyKey()); err != nil {
+		return "", err
+	}
+
+	m := proto.Clone(in).ProtoReflect()
+
+	field := m.Descriptor().Fields().ByJSONName(keyField)
+	if field == nil {
+		return "", Errorf(INTERNAL_ERROR, "%s has no %s field", m.Descriptor().FullName(), keyField)
+	}
+	m.Clear(field)
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m.Interface())
+	if err != nil {
+		return "", Errorf(INTERNAL_ERROR, "failed to marshall request: %v", err)
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,96 @@
+// Package idempotency lets clients retry requests safely: a request that
+// carries an idempotency key is handled once, and retries of it with the same
+// key get the response of the first one instead of being handled again. The
+// keys and responses are recorded in a Store, see Handle.
+package idempotency
+
+import (
+	"context"
+	"time"
+)
+
+// MaxKeyLength is the longest idempotency key clients can send.
+const MaxKeyLength = 255
+
+// Record remembers a request that carried an idempotency key, so that retries
+// of the request get the response of the first one instead of being executed
+// again.
+type Record struct {
+	// Keys are chosen by clients and scoped to the method they are used with.
+	Method string `json:"method"`
+	Key    string `json:"key"`
+
+	// Fingerprint identifies the request. A key can only be reused with the
+	// request it was first used with.
+	Fingerprint string `json:"fingerprint"`
+
+	// Response is the marshalled response to the request. It is empty while
+	// the first request is in progress.
+	Response []byte `json:"response"`
+
+	// ExpiresAt is the RFC3339 timestamp after which the record is forgotten
+	// and the key can be used again.
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func (r *Record) Validate() error {
+	if r.Method == "" {
+		return Errorf(INVALID_ERROR, "method is required")
+	}
+
+	if err := ValidateKey(r.Key); err != nil {
+		return err
+	}
+
+	if r.Fingerprint == "" {
+		return Errorf(INVALID_ERROR, "fingerprint is required")
+	}
+
+	if _, err := time.Parse(time.RFC3339, r.ExpiresAt); err != nil {
+		return Errorf(INVALID_ERROR, "expires_at must be an RFC3339 timestamp")
+	}
+
+	return nil
+}
+
+// Expired reports whether the record is expired at the given time. Records
+// with an invalid expiry are treated as expired.
+func (r *Record) Expired(at time.Time) bool {
+	expiresAt, err := time.Parse(time.RFC3339, r.ExpiresAt)
+
+	return err != nil || !at.Before(expiresAt)
+}
+
+// Completed reports whether the response to the request is stored.
+func (r *Record) Completed() bool {
+	return len(r.Response) > 0
+}
+
+func ValidateKey(key string) error {
+	if key == "" {
+		return Errorf(INVALID_ERROR, "idempotency key is required")
+	}
+
+	if len(key) > MaxKeyLength {
+		return Errorf(INVALID_ERROR, "idempotency key must be at most %d bytes", MaxKeyLength)
+	}
+
+	return nil
+}
+
+// Store records the idempotency keys of a service.
+type Store interface {
+	// ReserveIdempotencyKey stores the record unless an unexpired record with
+	// the same method and key exists, in which case that record is returned.
+	// It returns nil if the key is reserved for the caller.
+	ReserveIdempotencyKey(ctx context.Context, record *Record) (*Record, error)
+
+	// CompleteIdempotencyKey stores the response to the request that reserved
+	// the key and keeps it until expiresAt.
+	CompleteIdempotencyKey(ctx context.Context, method string, key string, response []byte, expiresAt string) error
+
+	// ReleaseIdempotencyKey forgets the key, so that the request can be
+	// retried. Releasing a key that is not stored is not an error.
+	ReleaseIdempotencyKey(ctx context.Context, method string, key string) error
+}
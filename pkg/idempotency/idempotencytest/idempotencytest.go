@@ -0,0 +1,257 @@
+// Package idempotencytest checks that idempotency stores behave alike.
+package idempotencytest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+)
+
+// newTestRecord returns a record of a key that is unique to the
+// test run, held for a minute.
+func newTestRecord() *idempotency.Record {
+	return &idempotency.Record{
+		Method:      "CreateRecord",
+		Key:         uniqueName("key"),
+		Fingerprint: "fingerprint-1",
+		ExpiresAt:   time.Now().Add(time.Minute).Format(time.RFC3339),
+	}
+}
+
+// TestStore runs the idempotency store conformance suite against stores
+// returned by newStore.
+func TestStore(t *testing.T, newStore func(t *testing.T) idempotency.Store) {
+	t.Run("ReserveIdempotencyKey", func(t *testing.T) {
+		ctx := context.Background()
+		r := newStore(t)
+
+		record := newTestRecord()
+
+		start := time.Now()
+		stored, err := r.ReserveIdempotencyKey(ctx, record)
+		wantCode(t, "ReserveIdempotencyKey()", err, "")
+		if stored != nil {
+			t.Fatalf("ReserveIdempotencyKey() = %+v, want nil for a new key", stored)
+		}
+		wantTimestamp(t, "CreatedAt", record.CreatedAt, start)
+
+		// The key is held by the first request, whatever the retry looks like.
+		retry := newTestRecord()
+		retry.Key = record.Key
+		retry.Fingerprint = "fingerprint-2"
+
+		stored, err = r.ReserveIdempotencyKey(ctx, retry)
+		wantCode(t, "ReserveIdempotencyKey() of a reserved key", err, "")
+		if stored == nil {
+			t.Fatalf("ReserveIdempotencyKey() of a reserved key = nil, want the stored record")
+		}
+		if stored.Method != record.Method || stored.Key != record.Key || stored.Fingerprint != record.Fingerprint ||
+			stored.Completed() || stored.CreatedAt != record.CreatedAt {
+			t.Errorf("ReserveIdempotencyKey() of a reserved key = %+v, want %+v", stored, record)
+		}
+
+		// Keys are scoped to their method.
+		other := newTestRecord()
+		other.Method = "OtherMethod"
+		other.Key = record.Key
+
+		stored, err = r.ReserveIdempotencyKey(ctx, other)
+		wantCode(t, "ReserveIdempotencyKey() of another method", err, "")
+		if stored != nil {
+			t.Errorf("ReserveIdempotencyKey() of another method = %+v, want nil", stored)
+		}
+	})
+
+	t.Run("ReserveIdempotencyKey_Invalid", func(t *testing.T) {
+		r := newStore(t)
+
+		for name, update := range map[string]func(*idempotency.Record){
+			"without method":         func(record *idempotency.Record) { record.Method = "" },
+			"without key":            func(record *idempotency.Record) { record.Key = "" },
+			"with a long key":        func(record *idempotency.Record) { record.Key = strings.Repeat("k", 256) },
+			"without fingerprint":    func(record *idempotency.Record) { record.Fingerprint = "" },
+			"with an invalid expiry": func(record *idempotency.Record) { record.ExpiresAt = "tomorrow" },
+		} {
+			record := newTestRecord()
+			update(record)
+
+			_, err := r.ReserveIdempotencyKey(context.Background(), record)
+			wantCode(t, "ReserveIdempotencyKey() "+name, err, idempotency.INVALID_ERROR)
+		}
+	})
+
+	t.Run("ReserveIdempotencyKey_Expired", func(t *testing.T) {
+		ctx := context.Background()
+		r := newStore(t)
+
+		record := newTestRecord()
+		record.ExpiresAt = time.Now().Add(-time.Minute).Format(time.RFC3339)
+
+		_, err := r.ReserveIdempotencyKey(ctx, record)
+		wantCode(t, "ReserveIdempotencyKey()", err, "")
+
+		// An expired key can be used again, with any request.
+		retry := newTestRecord()
+		retry.Key = record.Key
+		retry.Fingerprint = "fingerprint-2"
+
+		stored, err := r.ReserveIdempotencyKey(ctx, retry)
+		wantCode(t, "ReserveIdempotencyKey() of an expired key", err, "")
+		if stored != nil {
+			t.Errorf("ReserveIdempotencyKey() of an expired key = %+v, want nil", stored)
+		}
+	})
+
+	t.Run("ReserveIdempotencyKey_Concurrent", func(t *testing.T) {
+		ctx := context.Background()
+		r := newStore(t)
+
+		key := uniqueName("key")
+
+		const attempts = 10
+
+		var wg sync.WaitGroup
+		reserved := make([]bool, attempts)
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				record := newTestRecord()
+				record.Key = key
+
+				stored, err := r.ReserveIdempotencyKey(ctx, record)
+				reserved[i] = err == nil && stored == nil
+			}(i)
+		}
+		wg.Wait()
+
+		// Backends with optimistic transactions may give up on contended
+		// reservations, but no two requests may hold the key.
+		n := 0
+		for _, ok := range reserved {
+			if ok {
+				n++
+			}
+		}
+		if n > 1 {
+			t.Errorf("ReserveIdempotencyKey() reserved a key %d times", n)
+		}
+	})
+
+	t.Run("CompleteIdempotencyKey", func(t *testing.T) {
+		ctx := context.Background()
+		r := newStore(t)
+
+		record := newTestRecord()
+		_, err := r.ReserveIdempotencyKey(ctx, record)
+		wantCode(t, "ReserveIdempotencyKey()", err, "")
+
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		err = r.CompleteIdempotencyKey(ctx, record.Method, record.Key, []byte("response"), expiresAt.Format(time.RFC3339))
+		wantCode(t, "CompleteIdempotencyKey()", err, "")
+
+		stored, err := r.ReserveIdempotencyKey(ctx, newTestRecordOf(record))
+		wantCode(t, "ReserveIdempotencyKey()", err, "")
+		if stored == nil || string(stored.Response) != "response" {
+			t.Fatalf("ReserveIdempotencyKey() of a completed key = %+v, want the response", stored)
+		}
+		if got, err := time.Parse(time.RFC3339, stored.ExpiresAt); err != nil || !got.Equal(expiresAt) {
+			t.Errorf("ReserveIdempotencyKey() ExpiresAt = %q, want %v", stored.ExpiresAt, expiresAt)
+		}
+	})
+
+	t.Run("CompleteIdempotencyKey_Invalid", func(t *testing.T) {
+		ctx := context.Background()
+		r := newStore(t)
+
+		record := newTestRecord()
+		_, err := r.ReserveIdempotencyKey(ctx, record)
+		wantCode(t, "ReserveIdempotencyKey()", err, "")
+
+		err = r.CompleteIdempotencyKey(ctx, record.Method, record.Key, nil, record.ExpiresAt)
+		wantCode(t, "CompleteIdempotencyKey() without response", err, idempotency.INVALID_ERROR)
+
+		err = r.CompleteIdempotencyKey(ctx, record.Method, record.Key, []byte("response"), "tomorrow")
+		wantCode(t, "CompleteIdempotencyKey() with an invalid expiry", err, idempotency.INVALID_ERROR)
+
+		stored, err := r.ReserveIdempotencyKey(ctx, newTestRecordOf(record))
+		wantCode(t, "ReserveIdempotencyKey()", err, "")
+		if stored == nil || stored.Completed() {
+			t.Errorf("ReserveIdempotencyKey() = %+v, want the record in progress", stored)
+		}
+	})
+
+	t.Run("CompleteIdempotencyKey_NotFound", func(t *testing.T) {
+		err := newStore(t).CompleteIdempotencyKey(context.Background(), "CreateRecord", uniqueName("key"),
+			[]byte("response"), time.Now().Add(time.Hour).Format(time.RFC3339))
+		wantCode(t, "CompleteIdempotencyKey()", err, idempotency.NOT_FOUND_ERROR)
+	})
+
+	t.Run("ReleaseIdempotencyKey", func(t *testing.T) {
+		ctx := context.Background()
+		r := newStore(t)
+
+		record := newTestRecord()
+		_, err := r.ReserveIdempotencyKey(ctx, record)
+		wantCode(t, "ReserveIdempotencyKey()", err, "")
+
+		err = r.ReleaseIdempotencyKey(ctx, record.Method, record.Key)
+		wantCode(t, "ReleaseIdempotencyKey()", err, "")
+
+		stored, err := r.ReserveIdempotencyKey(ctx, newTestRecordOf(record))
+		wantCode(t, "ReserveIdempotencyKey() of a released key", err, "")
+		if stored != nil {
+			t.Errorf("ReserveIdempotencyKey() of a released key = %+v, want nil", stored)
+		}
+
+		err = r.ReleaseIdempotencyKey(ctx, record.Method, uniqueName("key"))
+		wantCode(t, "ReleaseIdempotencyKey() of an unknown key", err, "")
+	})
+}
+
+// newTestRecordOf returns a retry of the request of record.
+func newTestRecordOf(record *idempotency.Record) *idempotency.Record {
+	retry := newTestRecord()
+	retry.Method = record.Method
+	retry.Key = record.Key
+	retry.Fingerprint = record.Fingerprint
+
+	return retry
+}
+
+func wantCode(t *testing.T, op string, err error, code string) {
+	t.Helper()
+
+	if got := idempotency.ErrorCode(err); got != code {
+		t.Fatalf("%s error = %v, want code %q", op, err, code)
+	}
+}
+
+// wantTimestamp fails the test unless ts is an RFC3339 timestamp no earlier
+// than since and not in the future.
+func wantTimestamp(t *testing.T, field string, ts string, since time.Time) {
+	t.Helper()
+
+	got, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		t.Fatalf("%s = %q, want an RFC3339 timestamp: %v", field, ts, err)
+	}
+
+	// Timestamps are stored with second precision.
+	since = since.Truncate(time.Second)
+	if got.Before(since) || got.After(time.Now().Add(time.Second)) {
+		t.Errorf("%s = %v, want between %v and now", field, got, since)
+	}
+}
+
+// uniqueName returns prefix followed by a suffix that is unique to the test
+// run, so that records can be told apart in a shared database.
+func uniqueName(prefix string) string {
+	return fmt.Sprintf("%s %d", prefix, time.Now().UnixNano())
+}
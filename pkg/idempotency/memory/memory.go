@@ -0,0 +1,95 @@
+// Package memory keeps idempotency records in memory, for development and
+// tests.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+)
+
+var _ idempotency.Store = (*Store)(nil)
+
+type Store struct {
+	mu      sync.Mutex
+	records map[storeKey]idempotency.Record
+}
+
+type storeKey struct {
+	method, key string
+}
+
+func NewStore() *Store {
+	return &Store{
+		records: make(map[storeKey]idempotency.Record),
+	}
+}
+
+func (s *Store) ReserveIdempotencyKey(
+	ctx context.Context, record *idempotency.Record) (*idempotency.Record, error) {
+
+	record.CreatedAt = time.Now().Format(time.RFC3339)
+	record.Response = nil
+
+	if err := record.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Expired records are dropped here, as nothing else would remove them.
+	timeNow := time.Now()
+	for k, stored := range s.records {
+		if stored.Expired(timeNow) {
+			delete(s.records, k)
+		}
+	}
+
+	k := storeKey{record.Method, record.Key}
+	if stored, ok := s.records[k]; ok {
+		return &stored, nil
+	}
+
+	s.records[k] = *record
+
+	return nil, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(
+	ctx context.Context, method string, key string, response []byte, expiresAt string) error {
+
+	if len(response) == 0 {
+		return idempotency.Errorf(idempotency.INVALID_ERROR, "response is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := storeKey{method, key}
+	record, ok := s.records[k]
+	if !ok {
+		return idempotency.Errorf(idempotency.NOT_FOUND_ERROR, "idempotency key not found")
+	}
+
+	record.Response = append([]byte(nil), response...)
+	record.ExpiresAt = expiresAt
+	if err := record.Validate(); err != nil {
+		return err
+	}
+
+	s.records[k] = record
+
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, method string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, storeKey{method, key})
+
+	return nil
+}
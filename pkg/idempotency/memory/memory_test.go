@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/idempotencytest"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/memory"
+)
+
+func TestStore(t *testing.T) {
+	idempotencytest.TestStore(t, func(t *testing.T) idempotency.Store {
+		return memory.NewStore()
+	})
+}
@@ -0,0 +1,42 @@
+// Package mock provides an idempotency store whose methods can be replaced
+// one at a time in tests.
+package mock
+
+import (
+	"context"
+
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+)
+
+var _ idempotency.Store = (*Store)(nil)
+
+type Store struct {
+	ReserveIdempotencyKeyFunc func(
+		ctx context.Context, record *idempotency.Record) (*idempotency.Record, error)
+	CompleteIdempotencyKeyFunc func(
+		ctx context.Context, method string, key string, response []byte, expiresAt string) error
+	ReleaseIdempotencyKeyFunc func(ctx context.Context, method string, key string) error
+}
+
+// NewStore returns a mock that delegates every call to s.
+func NewStore(s idempotency.Store) *Store {
+	return &Store{
+		ReserveIdempotencyKeyFunc:  s.ReserveIdempotencyKey,
+		CompleteIdempotencyKeyFunc: s.CompleteIdempotencyKey,
+		ReleaseIdempotencyKeyFunc:  s.ReleaseIdempotencyKey,
+	}
+}
+
+func (m *Store) ReserveIdempotencyKey(
+	ctx context.Context, record *idempotency.Record) (*idempotency.Record, error) {
+	return m.ReserveIdempotencyKeyFunc(ctx, record)
+}
+
+func (m *Store) CompleteIdempotencyKey(
+	ctx context.Context, method string, key string, response []byte, expiresAt string) error {
+	return m.CompleteIdempotencyKeyFunc(ctx, method, key, response, expiresAt)
+}
+
+func (m *Store) ReleaseIdempotencyKey(ctx context.Context, method string, key string) error {
+	return m.ReleaseIdempotencyKeyFunc(ctx, method, key)
+}
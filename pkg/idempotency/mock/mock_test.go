@@ -0,0 +1,17 @@
+package mock_test
+
+import (
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/idempotencytest"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/memory"
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency/mock"
+)
+
+// The delegating mock must be indistinguishable from the store it wraps.
+func TestStore(t *testing.T) {
+	idempotencytest.TestStore(t, func(t *testing.T) idempotency.Store {
+		return mock.NewStore(memory.NewStore())
+	})
+}
@@ -0,0 +1,195 @@
+// Package postgres keeps idempotency records in a Postgres table. Services
+// create the table in their migrations:
+//
+//	CREATE TABLE idempotency_keys (
+//		method      TEXT NOT NULL,
+//		key         TEXT NOT NULL CHECK (key <> ''),
+//		fingerprint TEXT NOT NULL,
+//		response    BYTEA,
+//		created_at  TIMESTAMPTZ NOT NULL,
+//		expires_at  TIMESTAMPTZ NOT NULL,
+//		PRIMARY KEY (method, key)
+//	);
+//
+//	CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/pkg/idempotency"
+)
+
+var _ idempotency.Store = (*Store)(nil)
+
+const columns = `method, key, fingerprint, response, created_at, expires_at`
+
+type Store struct {
+	db    *sql.DB
+	table string
+}
+
+// NewStore returns a store that keeps its records in table. The table name is
+// part of the queries, so it must not come from user input.
+func NewStore(db *sql.DB, table string) *Store {
+	return &Store{
+		db:    db,
+		table: table,
+	}
+}
+
+func (s *Store) CheckPreconditions() {
+	if s.db == nil {
+		panic("no DB provided")
+	}
+
+	if s.table == "" {
+		panic("no table provided")
+	}
+}
+
+func (s *Store) ReserveIdempotencyKey(
+	ctx context.Context, record *idempotency.Record) (*idempotency.Record, error) {
+
+	s.CheckPreconditions()
+
+	timeNow := time.Now().Truncate(time.Second)
+	record.CreatedAt = formatTime(timeNow)
+	record.Response = nil
+
+	if err := record.Validate(); err != nil {
+		return nil, err
+	}
+	expiresAt, _ := time.Parse(time.RFC3339, record.ExpiresAt)
+
+	var stored *idempotency.Record
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		// Expired records are dropped here, as nothing else would remove them.
+		_, err := tx.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE expires_at <= $1`, timeNow)
+		if err != nil {
+			return dbError(err, "delete idempotency keys")
+		}
+
+		// A concurrent reservation of the same key blocks the insert until it
+		// commits, after which the existing record is read below.
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO `+s.table+` (`+columns+`)
+			VALUES ($1, $2, $3, NULL, $4, $5)
+			ON CONFLICT (method, key) DO NOTHING`,
+			record.Method, record.Key, record.Fingerprint, timeNow, expiresAt,
+		)
+		if err != nil {
+			return dbError(err, "create idempotency key")
+		}
+
+		if n, err := result.RowsAffected(); err != nil {
+			return dbError(err, "create idempotency key")
+		} else if n == 1 {
+			return nil
+		}
+
+		stored, err = scanRecord(tx.QueryRowContext(ctx,
+			`SELECT `+columns+` FROM `+s.table+` WHERE method = $1 AND key = $2`,
+			record.Method, record.Key,
+		))
+		return dbError(err, "get idempotency key")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+func (s *Store) CompleteIdempotencyKey(
+	ctx context.Context, method string, key string, response []byte, expiresAt string) error {
+
+	s.CheckPreconditions()
+
+	if len(response) == 0 {
+		return idempotency.Errorf(idempotency.INVALID_ERROR, "response is required")
+	}
+
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return idempotency.Errorf(idempotency.INVALID_ERROR, "expires_at must be an RFC3339 timestamp")
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE `+s.table+` SET response = $3, expires_at = $4 WHERE method = $1 AND key = $2`,
+		method, key, response, t,
+	)
+	if err != nil {
+		return dbError(err, "update idempotency key")
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return dbError(err, "update idempotency key")
+	} else if n == 0 {
+		return idempotency.Errorf(idempotency.NOT_FOUND_ERROR, "idempotency key not found")
+	}
+
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, method string, key string) error {
+	s.CheckPreconditions()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE method = $1 AND key = $2`, method, key)
+	return dbError(err, "delete idempotency key")
+}
+
+// withTx runs fn in a transaction, which is committed if fn succeeds and
+// rolled back otherwise.
+func (s *Store) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return idempotency.Errorf(idempotency.INTERNAL_ERROR, "failed to begin transaction: %v", err)
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return idempotency.Errorf(idempotency.INTERNAL_ERROR, "failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+func scanRecord(row *sql.Row) (*idempotency.Record, error) {
+	var (
+		record               idempotency.Record
+		createdAt, expiresAt time.Time
+	)
+
+	if err := row.Scan(
+		&record.Method, &record.Key, &record.Fingerprint, &record.Response, &createdAt, &expiresAt,
+	); err != nil {
+		return nil, err
+	}
+
+	record.CreatedAt = formatTime(createdAt)
+	record.ExpiresAt = formatTime(expiresAt)
+
+	return &record, nil
+}
+
+// dbError converts a database error to an Error. Errors are internal, as the
+// inputs are validated before they reach the database.
+func dbError(err error, action string) error {
+	if err == nil {
+		return nil
+	}
+
+	return idempotency.Errorf(idempotency.INTERNAL_ERROR, "failed to %s: %v", action, err)
+}
+
+// formatTime formats a timestamp the same way as the other stores.
+func formatTime(t time.Time) string {
+	return t.Local().Format(time.RFC3339)
+}
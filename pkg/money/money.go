@@ -0,0 +1,219 @@
+// Package money represents amounts of money as whole numbers of the minor unit
+// of an ISO 4217 currency, so that prices never suffer from rounding.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrOverflow         = errors.New("amount out of range")
+	ErrFractional       = errors.New("amount is not a whole number of units")
+)
+
+// currencyExponents lists the supported currencies and the number of digits
+// of their minor unit, e.g. 2 for the cents of the Kenyan shilling.
+var currencyExponents = map[string]int{
+	"AED": 2,
+	"CNY": 2,
+	"ETB": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"GHS": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KES": 2,
+	"NGN": 2,
+	"RWF": 0,
+	"TZS": 2,
+	"UGX": 0,
+	"USD": 2,
+	"ZAR": 2,
+}
+
+// chargeableExponents lists the currencies that are charged in a larger unit
+// than their minor unit, and the number of digits of that unit: M-Pesa only
+// takes whole Kenyan shillings.
+var chargeableExponents = map[string]int{
+	"KES": 0,
+}
+
+// Money is an amount in the minor units of its currency: {1050, "KES"} is
+// KES 10.50. The zero Money has no currency and can be added to or subtracted
+// from an amount in any currency.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// New returns amount minor units of currency.
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Zero returns no money in currency.
+func Zero(currency string) Money {
+	return Money{Currency: currency}
+}
+
+// IsSupported reports whether currency is a supported ISO 4217 code.
+func IsSupported(currency string) bool {
+	_, ok := currencyExponents[currency]
+	return ok
+}
+
+// ChargeableUnit returns the smallest amount of currency that payments can be
+// made in, in minor units: 100 for KES, which is charged in whole shillings,
+// and 1 for currencies charged in their minor unit.
+func ChargeableUnit(currency string) int64 {
+	exponent, ok := chargeableExponents[currency]
+	if !ok {
+		return 1
+	}
+
+	return int64(math.Pow10(currencyExponents[currency] - exponent))
+}
+
+// Validate checks that m is in a supported currency. The zero Money is valid.
+func (m Money) Validate() error {
+	if m == (Money{}) {
+		return nil
+	}
+
+	if !IsSupported(m.Currency) {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, m.Currency)
+	}
+
+	return nil
+}
+
+// IsZero reports whether m is no money, whatever its currency.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether m is less than no money.
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// currency returns the currency of the result of an operation on m and o.
+func (m Money) currency(o Money) (string, error) {
+	switch {
+	case m.Currency == o.Currency:
+		return m.Currency, nil
+	case m == (Money{}):
+		return o.Currency, nil
+	case o == (Money{}):
+		return m.Currency, nil
+	}
+
+	return "", fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+}
+
+// Add returns m plus o. It fails if the currencies differ or the result does
+// not fit in an int64.
+func (m Money) Add(o Money) (Money, error) {
+	currency, err := m.currency(o)
+	if err != nil {
+		return Money{}, err
+	}
+
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrOverflow, m, o)
+	}
+
+	return Money{Amount: sum, Currency: currency}, nil
+}
+
+// Sub returns m minus o. It fails if the currencies differ or the result does
+// not fit in an int64.
+func (m Money) Sub(o Money) (Money, error) {
+	currency, err := m.currency(o)
+	if err != nil {
+		return Money{}, err
+	}
+
+	diff := m.Amount - o.Amount
+	if (o.Amount > 0 && diff > m.Amount) || (o.Amount < 0 && diff < m.Amount) {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrOverflow, m, o)
+	}
+
+	return Money{Amount: diff, Currency: currency}, nil
+}
+
+// Mul returns m times n, e.g. the price of n units. It fails if the result
+// does not fit in an int64.
+func (m Money) Mul(n uint) (Money, error) {
+	if m.Amount == 0 || n == 0 {
+		return Money{Currency: m.Currency}, nil
+	}
+
+	if uint64(n) > math.MaxInt64 {
+		return Money{}, fmt.Errorf("%w: %s * %d", ErrOverflow, m, n)
+	}
+
+	product := m.Amount * int64(n)
+	if product/int64(n) != m.Amount {
+		return Money{}, fmt.Errorf("%w: %s * %d", ErrOverflow, m, n)
+	}
+
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// Sum adds up amounts, which must all be in the same currency.
+func Sum(amounts ...Money) (Money, error) {
+	var sum Money
+	for _, amount := range amounts {
+		var err error
+		if sum, err = sum.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+
+	return sum, nil
+}
+
+// MajorUnits returns m in the major unit of its currency, e.g. whole
+// shillings. It fails if m is not a whole number of them.
+func (m Money) MajorUnits() (int64, error) {
+	exponent, ok := currencyExponents[m.Currency]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, m.Currency)
+	}
+
+	scale := int64(math.Pow10(exponent))
+	if m.Amount%scale != 0 {
+		return 0, fmt.Errorf("%w: %s", ErrFractional, m)
+	}
+
+	return m.Amount / scale, nil
+}
+
+// String formats m in its major unit, e.g. "KES 10.50". Amounts in an unknown
+// currency are shown in minor units.
+func (m Money) String() string {
+	exponent := currencyExponents[m.Currency]
+
+	sign, abs := "", uint64(m.Amount)
+	if m.Amount < 0 {
+		sign, abs = "-", uint64(-(m.Amount+1))+1
+	}
+
+	s := fmt.Sprintf("%s%d", sign, abs)
+	if exponent > 0 {
+		scale := uint64(math.Pow10(exponent))
+		s = fmt.Sprintf("%s%d.%0*d", sign, abs/scale, exponent, abs%scale)
+	}
+
+	if m.Currency == "" {
+		return s
+	}
+
+	return m.Currency + " " + s
+}
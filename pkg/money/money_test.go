@@ -0,0 +1,179 @@
+package money_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/pkg/money"
+)
+
+func TestMoney_Add(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    money.Money
+		want    money.Money
+		wantErr error
+	}{
+		{"Same Currency", money.New(150, "KES"), money.New(250, "KES"), money.New(400, "KES"), nil},
+		{"Zero Money", money.Money{}, money.New(250, "KES"), money.New(250, "KES"), nil},
+		{"To Zero Money", money.New(250, "KES"), money.Money{}, money.New(250, "KES"), nil},
+		{"Currency Mismatch", money.New(150, "KES"), money.New(250, "USD"), money.Money{}, money.ErrCurrencyMismatch},
+		{"Zero In Other Currency", money.Zero("USD"), money.New(250, "KES"), money.Money{}, money.ErrCurrencyMismatch},
+		{"Overflow", money.New(math.MaxInt64, "KES"), money.New(1, "KES"), money.Money{}, money.ErrOverflow},
+		{"Underflow", money.New(math.MinInt64, "KES"), money.New(-1, "KES"), money.Money{}, money.ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Add(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Money.Add() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Money.Add() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Sub(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    money.Money
+		want    money.Money
+		wantErr error
+	}{
+		{"Same Currency", money.New(250, "KES"), money.New(100, "KES"), money.New(150, "KES"), nil},
+		{"Negative Result", money.New(100, "KES"), money.New(250, "KES"), money.New(-150, "KES"), nil},
+		{"Currency Mismatch", money.New(250, "KES"), money.New(100, "USD"), money.Money{}, money.ErrCurrencyMismatch},
+		{"Overflow", money.New(math.MaxInt64, "KES"), money.New(-1, "KES"), money.Money{}, money.ErrOverflow},
+		{"Underflow", money.New(math.MinInt64, "KES"), money.New(1, "KES"), money.Money{}, money.ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.a.Sub(tt.b)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Money.Sub() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Money.Sub() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoney_Mul(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       money.Money
+		n       uint
+		want    money.Money
+		wantErr error
+	}{
+		{"Units", money.New(150, "KES"), 3, money.New(450, "KES"), nil},
+		{"No Units", money.New(150, "KES"), 0, money.Zero("KES"), nil},
+		{"Negative", money.New(-150, "KES"), 2, money.New(-300, "KES"), nil},
+		{"Overflow", money.New(math.MaxInt64/2+1, "KES"), 2, money.Money{}, money.ErrOverflow},
+		{"Huge Quantity", money.New(1, "KES"), math.MaxUint64, money.Money{}, money.ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.Mul(tt.n)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Money.Mul() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Money.Mul() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	got, err := money.Sum(money.New(100, "KES"), money.New(250, "KES"), money.Money{})
+	if err != nil {
+		t.Fatalf("Sum() error = %v", err)
+	}
+	if want := money.New(350, "KES"); got != want {
+		t.Errorf("Sum() = %v, want %v", got, want)
+	}
+
+	if _, err := money.Sum(money.New(100, "KES"), money.New(250, "USD")); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("Sum() error = %v, want %v", err, money.ErrCurrencyMismatch)
+	}
+}
+
+func TestMoney_MajorUnits(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       money.Money
+		want    int64
+		wantErr error
+	}{
+		{"Shillings", money.New(15000, "KES"), 150, nil},
+		{"No Minor Unit", money.New(1500, "UGX"), 1500, nil},
+		{"Cents", money.New(15050, "KES"), 0, money.ErrFractional},
+		{"Unknown Currency", money.New(15000, "XXX"), 0, money.ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.MajorUnits()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Money.MajorUnits() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Money.MajorUnits() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChargeableUnit(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     int64
+	}{
+		{"KES", 100},
+		{"USD", 1},
+		{"UGX", 1},
+		{"XXX", 1},
+	}
+	for _, tt := range tests {
+		if got := money.ChargeableUnit(tt.currency); got != tt.want {
+			t.Errorf("ChargeableUnit(%q) = %d, want %d", tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestMoney_Validate(t *testing.T) {
+	for _, m := range []money.Money{{}, money.New(100, "KES"), money.Zero("USD")} {
+		if err := m.Validate(); err != nil {
+			t.Errorf("Money.Validate(%v) error = %v", m, err)
+		}
+	}
+
+	for _, m := range []money.Money{money.New(100, ""), money.New(100, "kes"), money.Zero("XXX")} {
+		if err := m.Validate(); !errors.Is(err, money.ErrUnknownCurrency) {
+			t.Errorf("Money.Validate(%v) error = %v, want %v", m, err, money.ErrUnknownCurrency)
+		}
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		m    money.Money
+		want string
+	}{
+		{money.New(1050, "KES"), "KES 10.50"},
+		{money.New(5, "KES"), "KES 0.05"},
+		{money.New(-1050, "KES"), "KES -10.50"},
+		{money.New(1500, "UGX"), "UGX 1500"},
+		{money.New(math.MinInt64, "KES"), "KES -92233720368547758.08"},
+		{money.New(42, ""), "42"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("Money.String() = %q, want %q", got, tt.want)
+		}
+	}
+}
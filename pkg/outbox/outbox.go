@@ -0,0 +1,66 @@
+// Package outbox publishes the events that services record in a transactional
+// outbox: every change to a record stores the events it causes in the same
+// transaction, and a Relay publishes them afterwards. An event is therefore
+// published if and only if its change was stored, but it may be published
+// more than once.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Event is something that happened to a record, e.g. an order that was paid.
+type Event struct {
+	// Id is unique to the event. Events are delivered at least once, so
+	// consumers use it to drop the ones they have seen before.
+	Id string `json:"id"`
+
+	// Type tells what happened, e.g. OrderPaid.
+	Type string `json:"type"`
+
+	// AggregateType and AggregateId identify the record the event is about,
+	// e.g. an order and its id.
+	AggregateType string `json:"aggregate_type"`
+	AggregateId   string `json:"aggregate_id"`
+
+	// Payload is the JSON encoded state of the record after the change.
+	Payload json.RawMessage `json:"payload"`
+
+	CreatedAt string `json:"created_at"`
+}
+
+// NewEvent returns an event with the JSON encoding of payload. The store sets
+// its id and creation time.
+func NewEvent(eventType string, aggregateType string, aggregateId string, payload any) (*Event, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	return &Event{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateId:   aggregateId,
+		Payload:       b,
+	}, nil
+}
+
+// Store is the outbox of a service.
+type Store interface {
+	// ListOutboxEvents returns up to limit events, in the order they were
+	// recorded.
+	ListOutboxEvents(ctx context.Context, limit int) ([]*Event, error)
+
+	// DeleteOutboxEvents removes the events once they are published. Ids
+	// that are not in the outbox are ignored.
+	DeleteOutboxEvents(ctx context.Context, ids []string) error
+}
+
+// Publisher delivers events to their consumers.
+type Publisher interface {
+	// Publish delivers the event. Events that fail to publish are retried,
+	// in order, until they succeed.
+	Publish(ctx context.Context, event *Event) error
+}
@@ -0,0 +1,89 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Handler consumes an event published in-process.
+type Handler func(ctx context.Context, event *Event) error
+
+// InProcessPublisher delivers events to the handlers subscribed to it, in the
+// same process. If a handler fails, the event is published again to every
+// handler, so handlers must tolerate duplicates.
+type InProcessPublisher struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewInProcessPublisher() *InProcessPublisher {
+	return &InProcessPublisher{}
+}
+
+// Subscribe has handler called with every event published from now on.
+func (p *InProcessPublisher) Subscribe(handler Handler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers = append(p.handlers, handler)
+}
+
+func (p *InProcessPublisher) Publish(ctx context.Context, event *Event) error {
+	p.mu.RLock()
+	handlers := p.handlers
+	p.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FilePublisher appends events to a file as JSON lines, for local use and for
+// other processes to tail. Events are synced to disk before they count as
+// published.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePublisher opens the file at path for appending, creating it if
+// needed.
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open events file: %w", err)
+	}
+
+	return &FilePublisher{file: file}, nil
+}
+
+func (p *FilePublisher) Publish(ctx context.Context, event *Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %s: %w", event.Id, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write event %s: %w", event.Id, err)
+	}
+
+	if err := p.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync event %s: %w", event.Id, err)
+	}
+
+	return nil
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
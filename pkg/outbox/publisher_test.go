@@ -0,0 +1,104 @@
+package outbox_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+func TestNewEvent(t *testing.T) {
+	event, err := outbox.NewEvent("OrderCreated", "order", "order-1", map[string]string{"id": "order-1"})
+	if err != nil {
+		t.Fatalf("NewEvent() error = %v", err)
+	}
+
+	if event.Type != "OrderCreated" || event.AggregateType != "order" || event.AggregateId != "order-1" ||
+		string(event.Payload) != `{"id":"order-1"}` {
+		t.Errorf("NewEvent() = %+v", event)
+	}
+
+	if _, err := outbox.NewEvent("OrderCreated", "order", "order-1", func() {}); err == nil {
+		t.Errorf("NewEvent() of an unencodable payload error = nil, want an error")
+	}
+}
+
+func TestInProcessPublisher(t *testing.T) {
+	p := outbox.NewInProcessPublisher()
+
+	var first, second []string
+	p.Subscribe(func(ctx context.Context, event *outbox.Event) error {
+		first = append(first, event.Id)
+		return nil
+	})
+	p.Subscribe(func(ctx context.Context, event *outbox.Event) error {
+		if event.Id == "2" {
+			return errors.New("handler failed")
+		}
+
+		second = append(second, event.Id)
+		return nil
+	})
+
+	for _, id := range []string{"1", "2"} {
+		err := p.Publish(context.Background(), &outbox.Event{Id: id})
+		if (err != nil) != (id == "2") {
+			t.Errorf("InProcessPublisher.Publish(%s) error = %v", id, err)
+		}
+	}
+
+	if len(first) != 2 || len(second) != 1 {
+		t.Errorf("InProcessPublisher.Publish() delivered %v and %v, want 2 and 1 events", first, second)
+	}
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	// Events are appended to those of earlier runs.
+	for _, id := range []string{"1", "2"} {
+		p, err := outbox.NewFilePublisher(path)
+		if err != nil {
+			t.Fatalf("NewFilePublisher() error = %v", err)
+		}
+
+		event, err := outbox.NewEvent("OrderCreated", "order", "order-"+id, map[string]string{"id": id})
+		if err != nil {
+			t.Fatalf("NewEvent() error = %v", err)
+		}
+		event.Id = id
+
+		if err := p.Publish(context.Background(), event); err != nil {
+			t.Fatalf("FilePublisher.Publish() error = %v", err)
+		}
+
+		if err := p.Close(); err != nil {
+			t.Fatalf("FilePublisher.Close() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open events file: %v", err)
+	}
+	defer f.Close()
+
+	var ids []string
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var event outbox.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("failed to unmarshal event %q: %v", scanner.Text(), err)
+		}
+
+		ids = append(ids, event.Id)
+	}
+
+	if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("events file has events %v, want [1 2]", ids)
+	}
+}
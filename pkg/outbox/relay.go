@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// DefaultInterval is how long a Relay waits for new events once it has
+	// published all of them, or after it failed to.
+	DefaultInterval = time.Second
+
+	// DefaultBatchSize is how many events a Relay reads from the outbox at
+	// once.
+	DefaultBatchSize = 100
+)
+
+// Relay publishes the events in an outbox and removes them once they are
+// published. A relay that stops between publishing an event and removing it
+// publishes the event again when it restarts.
+type Relay struct {
+	store     Store
+	publisher Publisher
+
+	// Interval and BatchSize default to DefaultInterval and DefaultBatchSize.
+	Interval  time.Duration
+	BatchSize int
+}
+
+func NewRelay(store Store, publisher Publisher) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		Interval:  DefaultInterval,
+		BatchSize: DefaultBatchSize,
+	}
+}
+
+func (r *Relay) CheckPreconditions() {
+	if r.store == nil {
+		panic("no outbox store provided")
+	}
+
+	if r.publisher == nil {
+		panic("no publisher provided")
+	}
+}
+
+// Run publishes events until ctx is done. Failures are logged and retried
+// after the interval.
+func (r *Relay) Run(ctx context.Context) error {
+	r.CheckPreconditions()
+
+	ticker := time.NewTicker(r.interval())
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Publish(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("[outbox] failed to publish events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Publish publishes the events in the outbox until it is empty, and returns
+// how many it published. It stops at the first event that fails to publish,
+// so that events are published in the order they were recorded.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	r.CheckPreconditions()
+
+	published := 0
+	for {
+		events, err := r.store.ListOutboxEvents(ctx, r.batchSize())
+		if err != nil {
+			return published, err
+		}
+
+		ids := make([]string, 0, len(events))
+		var publishErr error
+		for _, event := range events {
+			if publishErr = r.publisher.Publish(ctx, event); publishErr != nil {
+				break
+			}
+
+			ids = append(ids, event.Id)
+		}
+
+		if len(ids) > 0 {
+			if err := r.store.DeleteOutboxEvents(ctx, ids); err != nil {
+				return published, err
+			}
+
+			published += len(ids)
+		}
+
+		if publishErr != nil {
+			return published, publishErr
+		}
+
+		if len(events) < r.batchSize() {
+			return published, nil
+		}
+	}
+}
+
+func (r *Relay) interval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultInterval
+	}
+
+	return r.Interval
+}
+
+func (r *Relay) batchSize() int {
+	if r.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+
+	return r.BatchSize
+}
@@ -0,0 +1,166 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Mik3y-F/order-management-system/pkg/outbox"
+)
+
+// store is an outbox that keeps its events in memory.
+type store struct {
+	mu     sync.Mutex
+	events []*outbox.Event
+}
+
+func newStore(n int) *store {
+	s := &store{}
+	for i := 0; i < n; i++ {
+		s.events = append(s.events, &outbox.Event{Id: fmt.Sprint(i), Type: "OrderCreated"})
+	}
+
+	return s
+}
+
+func (s *store) ListOutboxEvents(ctx context.Context, limit int) ([]*outbox.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if limit > len(s.events) {
+		limit = len(s.events)
+	}
+
+	return append([]*outbox.Event(nil), s.events[:limit]...), nil
+}
+
+func (s *store) DeleteOutboxEvents(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := make(map[string]bool)
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	var events []*outbox.Event
+	for _, event := range s.events {
+		if !deleted[event.Id] {
+			events = append(events, event)
+		}
+	}
+	s.events = events
+
+	return nil
+}
+
+func (s *store) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.events)
+}
+
+// publisher records the ids of the events it publishes and fails the ones in
+// fail once.
+type publisher struct {
+	mu        sync.Mutex
+	published []string
+	fail      map[string]bool
+}
+
+func (p *publisher) Publish(ctx context.Context, event *outbox.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.fail[event.Id] {
+		delete(p.fail, event.Id)
+		return errors.New("publisher unavailable")
+	}
+
+	p.published = append(p.published, event.Id)
+
+	return nil
+}
+
+func TestRelay_Publish(t *testing.T) {
+	s := newStore(5)
+	p := &publisher{}
+
+	r := outbox.NewRelay(s, p)
+	r.BatchSize = 2
+
+	n, err := r.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Relay.Publish() error = %v", err)
+	}
+	if n != 5 {
+		t.Errorf("Relay.Publish() = %d, want 5", n)
+	}
+
+	if want := []string{"0", "1", "2", "3", "4"}; !reflect.DeepEqual(p.published, want) {
+		t.Errorf("Relay.Publish() published %v, want %v", p.published, want)
+	}
+	if s.len() != 0 {
+		t.Errorf("Relay.Publish() left %d events in the outbox, want none", s.len())
+	}
+}
+
+func TestRelay_Publish_Failed(t *testing.T) {
+	s := newStore(5)
+	p := &publisher{fail: map[string]bool{"2": true}}
+
+	r := outbox.NewRelay(s, p)
+
+	// Publishing stops at the failed event, so that events stay in order.
+	n, err := r.Publish(context.Background())
+	if err == nil {
+		t.Fatalf("Relay.Publish() error = nil, want an error")
+	}
+	if n != 2 || s.len() != 3 {
+		t.Errorf("Relay.Publish() = %d with %d events left, want 2 with 3 left", n, s.len())
+	}
+
+	n, err = r.Publish(context.Background())
+	if err != nil {
+		t.Fatalf("Relay.Publish() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Relay.Publish() = %d, want 3", n)
+	}
+
+	if want := []string{"0", "1", "2", "3", "4"}; !reflect.DeepEqual(p.published, want) {
+		t.Errorf("Relay.Publish() published %v, want %v", p.published, want)
+	}
+}
+
+func TestRelay_Run(t *testing.T) {
+	s := newStore(3)
+	p := &publisher{fail: map[string]bool{"1": true}}
+
+	r := outbox.NewRelay(s, p)
+	r.Interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+
+	// The failed event is retried after the interval.
+	deadline := time.Now().Add(5 * time.Second)
+	for s.len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(r.Interval)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Relay.Run() error = %v, want %v", err, context.Canceled)
+	}
+
+	if s.len() != 0 {
+		t.Errorf("Relay.Run() left %d events in the outbox, want none", s.len())
+	}
+}